@@ -0,0 +1,302 @@
+// Package analyze summarizes streams of dxyflake IDs, such as IDs dumped
+// from logs or databases, into an issuance report: the rate over time, the
+// distribution over machine IDs and service IDs, how much of the sequence
+// space was used, gaps in issuance, and duplicates.
+package analyze
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/verify"
+)
+
+// MaxBuckets bounds the histogram of a Report, whatever the span of the
+// IDs; MaxSamples bounds the duplicates it lists.
+const (
+	DefaultBucket = time.Minute
+	MaxBuckets    = 10000
+	MaxSamples    = 20
+)
+
+// ErrUnrecognized is returned by Parse and AddString for strings that are
+// not a plausible ID in any supported encoding.
+var ErrUnrecognized = errors.New("unrecognized id")
+
+const (
+	shiftNode = dxyflake.BitLenSequence
+	shiftTime = dxyflake.BitLenMachineID + dxyflake.BitLenServiceID + dxyflake.BitLenSequence
+	maskNode  = 1<<(dxyflake.BitLenMachineID+dxyflake.BitLenServiceID) - 1
+	maxSeq    = 1<<dxyflake.BitLenSequence - 1
+)
+
+// Options configures an Analyzer:
+//
+// Epoch is the start time of the generators that issued the IDs.
+// If Epoch is 0, dxyflake.DefaultStartTime is used.
+//
+// Encoding is the encoding of the strings passed to AddString, see Parse.
+// If Encoding is "", it is detected for every string.
+//
+// Bucket is the width of the rate buckets, at least one dxyflake time unit.
+// If Bucket is 0, DefaultBucket is used. It is doubled until the IDs span at
+// most MaxBuckets buckets.
+//
+// GapThreshold is the shortest pause in issuance reported as a gap.
+// If GapThreshold is 0, Bucket is used.
+type Options struct {
+	Epoch        time.Time
+	Encoding     string
+	Bucket       time.Duration
+	GapThreshold time.Duration
+}
+
+// Bucket is the number of IDs issued in [Start, Start+width).
+type Bucket struct {
+	Start     time.Time `json:"start"`
+	Count     uint64    `json:"count"`
+	PerSecond float64   `json:"per-second"`
+}
+
+// Count is the number of IDs issued by one machine ID or service ID.
+type Count struct {
+	ID    uint16 `json:"id"`
+	Count uint64 `json:"count"`
+}
+
+// Node is the number of IDs issued by one (machine ID, service ID) pair.
+type Node struct {
+	MachineID uint16 `json:"machine-id"`
+	ServiceID uint16 `json:"service-id"`
+	Count     uint64 `json:"count"`
+}
+
+// Sequence describes how much of the sequence space the nodes used.
+//
+// Utilization is the highest sequence number of a node in one time unit
+// over the sequence numbers available. Saturated counts the time units in
+// which a node ran out of sequence numbers and had to wait. Missing counts
+// sequence numbers below the highest one of their time unit that are not
+// in the stream: IDs that were issued but not logged.
+type Sequence struct {
+	Units           uint64  `json:"units"`
+	MeanUtilization float64 `json:"mean-utilization"`
+	MaxUtilization  float64 `json:"max-utilization"`
+	Saturated       uint64  `json:"saturated"`
+	Missing         uint64  `json:"missing"`
+}
+
+// Gap is a pause in issuance between the IDs issued at Start and End.
+type Gap struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration string    `json:"duration"`
+}
+
+// Report is the summary of an analyzed stream.
+type Report struct {
+	Total        uint64            `json:"total"`
+	Invalid      uint64            `json:"invalid"`
+	Encodings    map[string]uint64 `json:"encodings"`
+	Duplicates   uint64            `json:"duplicates"`
+	DuplicateIDs []dxyflake.ID     `json:"duplicate-ids,omitempty"`
+	First        time.Time         `json:"first"`
+	Last         time.Time         `json:"last"`
+	Bucket       string            `json:"bucket"`
+	Rate         []Bucket          `json:"rate"`
+	Machines     []Count           `json:"machines"`
+	Services     []Count           `json:"services"`
+	Nodes        []Node            `json:"nodes"`
+	Sequence     Sequence          `json:"sequence"`
+	Gaps         []Gap             `json:"gaps"`
+}
+
+// unit is what an Analyzer keeps per (time, machine ID, service ID).
+type unit struct {
+	count  uint16
+	maxSeq uint16
+}
+
+// Analyzer collects IDs for a Report. It is not safe for concurrent use.
+type Analyzer struct {
+	opts       Options
+	epochTicks int64
+	seen       *verify.Bitset
+	units      map[int64]unit // keyed by id >> shiftNode
+	total      uint64
+	invalid    uint64
+	encodings  map[string]uint64
+	duplicates []dxyflake.ID
+	dupCount   uint64
+}
+
+// New returns an Analyzer.
+func New(opts Options) *Analyzer {
+	if opts.Epoch.IsZero() {
+		opts.Epoch = dxyflake.DefaultStartTime
+	}
+	if opts.Bucket <= 0 {
+		opts.Bucket = DefaultBucket
+	}
+	if opts.Bucket < 1e7 {
+		opts.Bucket = 1e7
+	}
+	if opts.GapThreshold <= 0 {
+		opts.GapThreshold = opts.Bucket
+	}
+	return &Analyzer{
+		opts:       opts,
+		epochTicks: opts.Epoch.UnixNano() / 1e7,
+		seen:       verify.NewBitset(),
+		units:      make(map[int64]unit),
+		encodings:  make(map[string]uint64),
+	}
+}
+
+// Add records id and reports whether it was seen before.
+func (a *Analyzer) Add(id dxyflake.ID) bool {
+	a.total++
+	if a.seen.TestAndSet(id) {
+		a.dupCount++
+		if len(a.duplicates) < MaxSamples {
+			a.duplicates = append(a.duplicates, id)
+		}
+		return true
+	}
+
+	key := int64(id) >> shiftNode
+	u := a.units[key]
+	u.count++
+	if seq := uint16(int64(id) & maxSeq); seq > u.maxSeq {
+		u.maxSeq = seq
+	}
+	a.units[key] = u
+	return false
+}
+
+// AddString parses s with Parse and records the ID and its encoding.
+// Strings that cannot be parsed are counted as invalid and return
+// ErrUnrecognized.
+func (a *Analyzer) AddString(s string) (bool, error) {
+	id, encoding, err := Parse(s, a.opts.Encoding, a.opts.Epoch)
+	if err != nil {
+		a.invalid++
+		return false, err
+	}
+	a.encodings[encoding]++
+	return a.Add(id), nil
+}
+
+func (a *Analyzer) tickTime(tick int64) time.Time {
+	t := a.epochTicks + tick
+	return time.Unix(t/100, t%100*1e7).UTC()
+}
+
+// Report summarizes the IDs added so far.
+func (a *Analyzer) Report() Report {
+	r := Report{
+		Total:        a.total,
+		Invalid:      a.invalid,
+		Encodings:    make(map[string]uint64, len(a.encodings)),
+		Duplicates:   a.dupCount,
+		DuplicateIDs: a.duplicates,
+		Rate:         []Bucket{},
+		Machines:     []Count{},
+		Services:     []Count{},
+		Nodes:        []Node{},
+		Gaps:         []Gap{},
+	}
+	for name, n := range a.encodings {
+		r.Encodings[name] = n
+	}
+	if len(a.units) == 0 {
+		r.Bucket = a.opts.Bucket.String()
+		return r
+	}
+
+	machines := make(map[uint16]uint64)
+	services := make(map[uint16]uint64)
+	nodes := make(map[int64]uint64)
+	ticks := make(map[int64]uint64)
+	var sumUtilization float64
+	for key, u := range a.units {
+		node := key & maskNode
+		machineID := uint16(node >> dxyflake.BitLenServiceID)
+		serviceID := uint16(node & (1<<dxyflake.BitLenServiceID - 1))
+		machines[machineID] += uint64(u.count)
+		services[serviceID] += uint64(u.count)
+		nodes[node] += uint64(u.count)
+		ticks[key>>(shiftTime-shiftNode)] += uint64(u.count)
+
+		utilization := float64(u.maxSeq+1) / (maxSeq + 1)
+		sumUtilization += utilization
+		if utilization > r.Sequence.MaxUtilization {
+			r.Sequence.MaxUtilization = utilization
+		}
+		if u.maxSeq == maxSeq {
+			r.Sequence.Saturated++
+		}
+		r.Sequence.Missing += uint64(u.maxSeq) + 1 - uint64(u.count)
+	}
+	r.Sequence.Units = uint64(len(a.units))
+	r.Sequence.MeanUtilization = sumUtilization / float64(len(a.units))
+
+	for id, n := range machines {
+		r.Machines = append(r.Machines, Count{id, n})
+	}
+	sort.Slice(r.Machines, func(i, j int) bool { return r.Machines[i].ID < r.Machines[j].ID })
+	for id, n := range services {
+		r.Services = append(r.Services, Count{id, n})
+	}
+	sort.Slice(r.Services, func(i, j int) bool { return r.Services[i].ID < r.Services[j].ID })
+	for node, n := range nodes {
+		r.Nodes = append(r.Nodes, Node{
+			MachineID: uint16(node >> dxyflake.BitLenServiceID),
+			ServiceID: uint16(node & (1<<dxyflake.BitLenServiceID - 1)),
+			Count:     n,
+		})
+	}
+	sort.Slice(r.Nodes, func(i, j int) bool {
+		if r.Nodes[i].MachineID != r.Nodes[j].MachineID {
+			return r.Nodes[i].MachineID < r.Nodes[j].MachineID
+		}
+		return r.Nodes[i].ServiceID < r.Nodes[j].ServiceID
+	})
+
+	sorted := make([]int64, 0, len(ticks))
+	for tick := range ticks {
+		sorted = append(sorted, tick)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	first, last := sorted[0], sorted[len(sorted)-1]
+	r.First, r.Last = a.tickTime(first), a.tickTime(last)
+
+	gapTicks := int64(a.opts.GapThreshold / 1e7)
+	for i := 1; i < len(sorted); i++ {
+		if d := sorted[i] - sorted[i-1]; d > 1 && d >= gapTicks {
+			start, end := a.tickTime(sorted[i-1]), a.tickTime(sorted[i])
+			r.Gaps = append(r.Gaps, Gap{start, end, end.Sub(start).String()})
+		}
+	}
+
+	bucket := a.opts.Bucket
+	for (last-first)/int64(bucket/1e7) >= MaxBuckets {
+		bucket *= 2
+	}
+	r.Bucket = bucket.String()
+	width := int64(bucket / 1e7)
+	start := (a.epochTicks + first) / width * width // aligned to the wall clock
+	r.Rate = make([]Bucket, (a.epochTicks+last-start)/width+1)
+	for i := range r.Rate {
+		r.Rate[i].Start = a.tickTime(start + int64(i)*width - a.epochTicks)
+	}
+	for tick, n := range ticks {
+		r.Rate[(a.epochTicks+tick-start)/width].Count += n
+	}
+	for i := range r.Rate {
+		r.Rate[i].PerSecond = float64(r.Rate[i].Count) / bucket.Seconds()
+	}
+	return r
+}
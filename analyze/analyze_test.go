@@ -0,0 +1,104 @@
+package analyze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+var epoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func compose(tick int64, machineID, serviceID, seq int64) dxyflake.ID {
+	return dxyflake.ID(tick<<shiftTime | machineID<<(dxyflake.BitLenServiceID+shiftNode) | serviceID<<shiftNode | seq)
+}
+
+func TestReport(t *testing.T) {
+	a := New(Options{Epoch: epoch, Bucket: time.Second})
+
+	// node 1/2: sequence 0 to 3 in tick 0 with 2 missing, saturated in tick 1
+	for _, seq := range []int64{0, 1, 3} {
+		a.Add(compose(0, 1, 2, seq))
+	}
+	a.Add(compose(1, 1, 2, maxSeq))
+	// node 3/2: one ID 5 seconds later, logged twice
+	a.Add(compose(500, 3, 2, 0))
+	if !a.Add(compose(500, 3, 2, 0)) {
+		t.Error("duplicate not reported")
+	}
+
+	r := a.Report()
+	if r.Total != 6 || r.Duplicates != 1 || len(r.DuplicateIDs) != 1 {
+		t.Errorf("unexpected counts: %+v", r)
+	}
+	if len(r.Nodes) != 2 || r.Nodes[0] != (Node{1, 2, 4}) || r.Nodes[1] != (Node{3, 2, 1}) {
+		t.Errorf("unexpected nodes: %+v", r.Nodes)
+	}
+	if len(r.Machines) != 2 || len(r.Services) != 1 || r.Services[0] != (Count{2, 5}) {
+		t.Errorf("unexpected distribution: %+v, %+v", r.Machines, r.Services)
+	}
+	if s := r.Sequence; s.Units != 3 || s.Saturated != 1 || s.Missing != 1+maxSeq || s.MaxUtilization != 1 {
+		t.Errorf("unexpected sequence: %+v", s)
+	}
+	if !r.First.Equal(epoch) || !r.Last.Equal(epoch.Add(5*time.Second)) {
+		t.Errorf("unexpected range: %s, %s", r.First, r.Last)
+	}
+	if len(r.Gaps) != 1 || r.Gaps[0].Duration != "4.99s" {
+		t.Errorf("unexpected gaps: %+v", r.Gaps)
+	}
+	if len(r.Rate) != 6 || r.Rate[0].Count != 4 || r.Rate[1].Count != 0 || r.Rate[5].Count != 1 {
+		t.Errorf("unexpected rate: %+v", r.Rate)
+	}
+}
+
+func TestReportBucketWidening(t *testing.T) {
+	a := New(Options{Epoch: epoch, Bucket: 10 * time.Millisecond})
+	a.Add(compose(0, 0, 0, 0))
+	a.Add(compose(100*MaxBuckets, 0, 0, 0))
+
+	r := a.Report()
+	if len(r.Rate) > MaxBuckets || r.Bucket != "1.28s" {
+		t.Errorf("unexpected buckets: %d of %s", len(r.Rate), r.Bucket)
+	}
+}
+
+func TestParse(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(7, 9)
+	st.StartTime = epoch
+	id, err := dxyflake.NewDxyflake(st).NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range Encodings {
+		got, name, err := Parse(e.Format(id), "", epoch)
+		if err != nil || got != id || name != e.Name {
+			t.Errorf("%s: Parse() = %d, %s, %v, want %d", e.Name, got, name, err, id)
+		}
+	}
+
+	for _, s := range []string{"", "not an id", "-1", "0001"} {
+		if _, _, err := Parse(s, "", epoch); err != ErrUnrecognized {
+			t.Errorf("Parse(%q) error = %v", s, err)
+		}
+	}
+	// a far future ID is not plausible
+	if _, _, err := Parse(dxyflake.ID(1<<62).String(), "", epoch); err != ErrUnrecognized {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, _, err := Parse(id.Base58(), "base58", epoch); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAddString(t *testing.T) {
+	a := New(Options{Epoch: epoch})
+	a.AddString(compose(1, 0, 0, 0).String())
+	if _, err := a.AddString("?"); err != ErrUnrecognized {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r := a.Report(); r.Total != 1 || r.Invalid != 1 || r.Encodings["decimal"] != 1 {
+		t.Errorf("unexpected counts: %d, %d, %v", r.Total, r.Invalid, r.Encodings)
+	}
+}
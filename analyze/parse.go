@@ -0,0 +1,63 @@
+package analyze
+
+import (
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Encoding converts between an ID and one of its string forms.
+type Encoding struct {
+	Name   string
+	Parse  func(s string) (dxyflake.ID, error)
+	Format func(id dxyflake.ID) string
+}
+
+// Encodings lists the encodings Parse detects, in the order they are tried.
+// Base62 is tried before Base58 and Base36 because their strings decode to
+// IDs far in the future when read as base62, while base62 strings read as
+// base58 still look plausible.
+var Encodings = []Encoding{
+	{"decimal", dxyflake.ParseString, dxyflake.ID.String},
+	{"base62", func(s string) (dxyflake.ID, error) { return dxyflake.ParseBase62([]byte(s)) }, dxyflake.ID.Base62},
+	{"base58", func(s string) (dxyflake.ID, error) { return dxyflake.ParseBase58([]byte(s)) }, dxyflake.ID.Base58},
+	{"base36", dxyflake.ParseBase36, dxyflake.ID.Base36},
+	{"base32", func(s string) (dxyflake.ID, error) { return dxyflake.ParseBase32([]byte(s)) }, dxyflake.ID.Base32},
+	{"crockford32", dxyflake.ParseCrockford32, dxyflake.ID.Crockford32},
+	{"sortable", dxyflake.ParseSortableString, dxyflake.ID.SortableString},
+	{"checked", dxyflake.ParseChecked, dxyflake.ID.StringChecked},
+	{"base64", dxyflake.ParseBase64, dxyflake.ID.Base64},
+	{"base2", dxyflake.ParseBase2, dxyflake.ID.Base2},
+	{"hex", dxyflake.ParseHex, dxyflake.ID.Hex},
+}
+
+// Parse parses s in the named encoding. If encoding is "", every encoding
+// of Encodings is tried in turn and the first one that yields a plausible
+// ID is used: one that encodes back to s and that a dxyflake started at
+// epoch could have issued by a day from now. Parse returns the ID and the
+// name of its encoding, or ErrUnrecognized if no encoding fits.
+//
+// Short strings are ambiguous: "junk" is a valid base62 ID issued shortly
+// after epoch. Pass encoding for dumps that may contain other tokens.
+func Parse(s, encoding string, epoch time.Time) (dxyflake.ID, string, error) {
+	l := dxyflake.DefaultLayout
+	if !epoch.IsZero() {
+		l.Epoch = epoch
+	}
+	return ParseLayout(s, encoding, l)
+}
+
+// ParseLayout is like Parse for IDs of the layout l.
+func ParseLayout(s, encoding string, l dxyflake.Layout) (dxyflake.ID, string, error) {
+	max := l.MaxIDAt(time.Now().Add(24 * time.Hour))
+	for _, e := range Encodings {
+		if encoding != "" && encoding != e.Name {
+			continue
+		}
+		id, err := e.Parse(s)
+		if err == nil && id >= 0 && id <= max && e.Format(id) == s {
+			return id, e.Name, nil
+		}
+	}
+	return 0, "", ErrUnrecognized
+}
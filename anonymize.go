@@ -0,0 +1,81 @@
+package dxyflake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+)
+
+// anonymizeRounds is the number of Feistel rounds. Four rounds with a
+// pseudorandom round function give a strong pseudorandom permutation.
+const anonymizeRounds = 4
+
+// Anonymize maps id to a pseudonym with a keyed pseudorandom permutation
+// of 64-bit values. The same id and key always give the same pseudonym and
+// distinct ids give distinct pseudonyms, so exported datasets can still be
+// joined on them, but without the key neither the id nor its timestamp,
+// machine ID or service ID can be recovered.
+func Anonymize(id ID, key []byte) uint64 {
+	left, right := uint32(uint64(id)>>32), uint32(id)
+	mac := hmac.New(sha256.New, key)
+	for round := 0; round < anonymizeRounds; round++ {
+		left, right = right, left^anonymizeRound(mac, round, right)
+	}
+	return uint64(left)<<32 | uint64(right)
+}
+
+// Deanonymize returns the ID Anonymize mapped to pseudonym with key.
+func Deanonymize(pseudonym uint64, key []byte) ID {
+	left, right := uint32(pseudonym>>32), uint32(pseudonym)
+	mac := hmac.New(sha256.New, key)
+	for round := anonymizeRounds - 1; round >= 0; round-- {
+		left, right = right^anonymizeRound(mac, round, left), left
+	}
+	return ID(uint64(left)<<32 | uint64(right))
+}
+
+func anonymizeRound(mac hash.Hash, round int, half uint32) uint32 {
+	var b [5]byte
+	b[0] = byte(round)
+	binary.BigEndian.PutUint32(b[1:], half)
+	mac.Reset()
+	mac.Write(b[:])
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+// obfuscateRound is the first round number of Obfuscate, so that its round
+// functions differ from those of Anonymize under the same key.
+const obfuscateRound = 16
+
+// Obfuscate returns the ID permuted with key by a keyed pseudorandom
+// permutation of 63-bit values, for public URLs that should not leak the
+// issuance rate or allow enumeration. Unlike Anonymize, the result is
+// again a non-negative ID, encoded like any other; it just has no order
+// and no parts. Deobfuscate reverses it. id must not be negative.
+func (f ID) Obfuscate(key []byte) ID {
+	// an unbalanced Feistel network: the halves are 31 and 32 bits wide,
+	// and swap widths every round
+	left, right := uint32(uint64(f)>>32)&(1<<31-1), uint32(f)
+	leftBits := uint(31)
+	mac := hmac.New(sha256.New, key)
+	for round := 0; round < anonymizeRounds; round++ {
+		rightBits := 63 - leftBits
+		left, right = right, left^anonymizeRound(mac, obfuscateRound+round, right)&(1<<leftBits-1)
+		leftBits = rightBits
+	}
+	return ID(uint64(left)<<32 | uint64(right))
+}
+
+// Deobfuscate returns the ID Obfuscate permuted to f with key.
+func (f ID) Deobfuscate(key []byte) ID {
+	left, right := uint32(uint64(f)>>32)&(1<<31-1), uint32(f)
+	leftBits := uint(31)
+	mac := hmac.New(sha256.New, key)
+	for round := anonymizeRounds - 1; round >= 0; round-- {
+		rightBits := 63 - leftBits
+		left, right = right^anonymizeRound(mac, obfuscateRound+round, left)&(1<<rightBits-1), left
+		leftBits = rightBits
+	}
+	return ID(uint64(left)<<32 | uint64(right))
+}
@@ -0,0 +1,73 @@
+package dxyflake
+
+import (
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	var st Settings
+	st.Init(3, 4)
+	gen := NewDxyflake(st)
+	key := []byte("export-2024")
+
+	seen := make(map[uint64]bool)
+	for i := 0; i < 200; i++ {
+		id, err := gen.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := Anonymize(id, key)
+		if p == uint64(id) || seen[p] {
+			t.Fatalf("unexpected pseudonym: %d", p)
+		}
+		seen[p] = true
+		if Anonymize(id, key) != p {
+			t.Fatal("pseudonym not consistent")
+		}
+		if got := Deanonymize(p, key); got != id {
+			t.Fatalf("unexpected id: %d, want %d", got, id)
+		}
+	}
+
+	id, err := gen.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Anonymize(id, []byte("other")) == Anonymize(id, key) {
+		t.Error("pseudonym does not depend on the key")
+	}
+	// consecutive IDs must not give close pseudonyms
+	if d := int64(Anonymize(id, key) - Anonymize(id+1, key)); d > -1<<32 && d < 1<<32 {
+		t.Errorf("pseudonyms of consecutive ids are close: %d", d)
+	}
+}
+
+func TestObfuscate(t *testing.T) {
+	key := []byte("obfuscation key")
+	seen := make(map[ID]bool)
+	var prev ID
+	ascending := 0
+	for _, id := range []ID{0, 1, 2, 3, 475370495148032, 475370495148033, 1<<63 - 1} {
+		o := id.Obfuscate(key)
+		if o < 0 {
+			t.Errorf("Obfuscate(%d) = %d, negative", id, o)
+		}
+		if seen[o] {
+			t.Errorf("Obfuscate(%d) = %d, a duplicate", id, o)
+		}
+		seen[o] = true
+		if o > prev {
+			ascending++
+		}
+		prev = o
+		if got := o.Deobfuscate(key); got != id {
+			t.Errorf("Deobfuscate(%d) = %d, want %d", o, got, id)
+		}
+		if o.Deobfuscate([]byte("other key")) == id {
+			t.Errorf("%d deobfuscated with another key", id)
+		}
+	}
+	if ascending == len(seen) {
+		t.Error("obfuscated IDs keep their order")
+	}
+}
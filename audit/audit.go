@@ -0,0 +1,263 @@
+// Package audit checks exported ID logs for the invariants of dxyflake:
+// every ID is unique across all exports, and the IDs of each (machine ID,
+// service ID) pair strictly increase within an export.
+//
+// Uniqueness is checked with an external merge sort, so memory use depends
+// on Options.ChunkSize and not on the number of IDs. Exports that are
+// already sorted, as the export of a single node usually is, are kept as
+// one run each and merged without being sorted again.
+package audit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// DefaultChunkSize keeps an audit within a modest amount of memory.
+// MaxSamples bounds the violations and duplicates a Report lists.
+const (
+	DefaultChunkSize = 1 << 23 // 64 MiB of IDs
+	MaxSamples       = 20
+)
+
+// ErrFinished is returned by Add after Finish.
+var ErrFinished = errors.New("audit finished")
+
+// Options configures an Auditor:
+//
+// TempDir is the directory for sorted runs.
+// If TempDir is "", the default directory for temporary files is used.
+//
+// ChunkSize is the number of IDs sorted in memory at once.
+// If ChunkSize is 0, DefaultChunkSize is used.
+//
+// Parse converts a line of an export into an ID.
+// If Parse is nil, dxyflake.ParseString is used.
+type Options struct {
+	TempDir   string
+	ChunkSize int
+	Parse     func(s string) (dxyflake.ID, error)
+}
+
+// Violation is an ID that broke an invariant within an export.
+type Violation struct {
+	Line  uint64      `json:"line"`
+	ID    dxyflake.ID `json:"id,omitempty"`
+	Error string      `json:"error"`
+}
+
+// Export is the result of one export.
+type Export struct {
+	Name         string      `json:"name"`
+	Count        uint64      `json:"count"`
+	Nodes        int         `json:"nodes"`
+	Min          dxyflake.ID `json:"min"`
+	Max          dxyflake.ID `json:"max"`
+	NonMonotonic uint64      `json:"non-monotonic"`
+	Invalid      uint64      `json:"invalid"`
+	Violations   []Violation `json:"violations,omitempty"`
+}
+
+// Duplicate is an ID found more than once, with the exports it was found in.
+type Duplicate struct {
+	ID      dxyflake.ID `json:"id"`
+	Exports []string    `json:"exports"`
+}
+
+// Report is the result of an audit.
+type Report struct {
+	Start        time.Time   `json:"start"`
+	End          time.Time   `json:"end"`
+	Exports      []Export    `json:"exports"`
+	Total        uint64      `json:"total"`
+	Unique       uint64      `json:"unique"`
+	Duplicates   uint64      `json:"duplicates"`
+	DuplicateIDs []Duplicate `json:"duplicate-ids,omitempty"`
+	NonMonotonic uint64      `json:"non-monotonic"`
+	Invalid      uint64      `json:"invalid"`
+	Passed       bool        `json:"passed"`
+}
+
+// Auditor audits exports. Call Add for every export, then Finish, which
+// also removes the temporary runs.
+type Auditor struct {
+	opts     Options
+	report   Report
+	runs     []*run
+	chunk    []dxyflake.ID
+	err      error
+	finished bool
+}
+
+// New returns an Auditor.
+func New(opts Options) *Auditor {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Parse == nil {
+		opts.Parse = dxyflake.ParseString
+	}
+	return &Auditor{
+		opts:   opts,
+		report: Report{Start: time.Now(), Exports: []Export{}},
+	}
+}
+
+// Add reads an export of one ID per line from r. Blank lines are skipped.
+// After an error the audit is incomplete and Finish returns the error too.
+func (a *Auditor) Add(name string, r io.Reader) error {
+	if a.finished {
+		return ErrFinished
+	}
+	if a.err != nil {
+		return a.err
+	}
+	a.err = a.add(name, r)
+	return a.err
+}
+
+func (a *Auditor) add(name string, r io.Reader) error {
+	index := len(a.report.Exports)
+	e := Export{Name: name}
+	last := make(map[int64]dxyflake.ID)
+	var current *run
+	a.chunk = a.chunk[:0]
+
+	violate := func(line uint64, id dxyflake.ID, err string) {
+		if len(e.Violations) < MaxSamples {
+			e.Violations = append(e.Violations, Violation{line, id, err})
+		}
+	}
+	flush := func() error {
+		if len(a.chunk) == 0 {
+			return nil
+		}
+		sorted := sort.SliceIsSorted(a.chunk, func(i, j int) bool { return a.chunk[i] < a.chunk[j] })
+		if !sorted {
+			sort.Slice(a.chunk, func(i, j int) bool { return a.chunk[i] < a.chunk[j] })
+		}
+		// a sorted export stays a single run
+		if current == nil || a.chunk[0] <= current.last {
+			r, err := newRun(a.opts.TempDir, index)
+			if err != nil {
+				return err
+			}
+			a.runs = append(a.runs, r)
+			current = r
+		}
+		err := current.write(a.chunk)
+		a.chunk = a.chunk[:0]
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	var line uint64
+	for scanner.Scan() {
+		line++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			continue
+		}
+		id, err := a.opts.Parse(s)
+		if err != nil {
+			e.Invalid++
+			violate(line, 0, fmt.Sprintf("invalid id %q", s))
+			continue
+		}
+
+		if e.Count == 0 || id < e.Min {
+			e.Min = id
+		}
+		if e.Count == 0 || id > e.Max {
+			e.Max = id
+		}
+		e.Count++
+
+		node := int64(id) >> dxyflake.BitLenSequence & (1<<(dxyflake.BitLenMachineID+dxyflake.BitLenServiceID) - 1)
+		if prev, ok := last[node]; ok && id <= prev {
+			e.NonMonotonic++
+			violate(line, id, fmt.Sprintf("not greater than %d", prev))
+		}
+		last[node] = id
+
+		a.chunk = append(a.chunk, id)
+		if len(a.chunk) == a.opts.ChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	e.Nodes = len(last)
+	a.report.Exports = append(a.report.Exports, e)
+	a.report.Total += e.Count
+	a.report.NonMonotonic += e.NonMonotonic
+	a.report.Invalid += e.Invalid
+	return nil
+}
+
+// AddFile adds the export in the named file.
+func (a *Auditor) AddFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return a.Add(path, f)
+}
+
+// Finish merges the exports, removes the temporary runs and returns the
+// report. The Auditor cannot be used afterwards.
+func (a *Auditor) Finish() (Report, error) {
+	if a.finished {
+		return Report{}, ErrFinished
+	}
+	a.finished = true
+	a.chunk = nil
+	defer func() {
+		for _, r := range a.runs {
+			r.remove()
+		}
+	}()
+
+	if a.err != nil {
+		return Report{}, a.err
+	}
+	r := &a.report
+	err := merge(a.runs, func(id dxyflake.ID, exports []int) {
+		r.Unique++
+		if len(exports) == 1 {
+			return
+		}
+		r.Duplicates += uint64(len(exports) - 1)
+		if len(r.DuplicateIDs) < MaxSamples {
+			d := Duplicate{ID: id}
+			for _, i := range exports {
+				d.Exports = append(d.Exports, r.Exports[i].Name)
+			}
+			r.DuplicateIDs = append(r.DuplicateIDs, d)
+		}
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	r.End = time.Now()
+	r.Passed = r.Duplicates == 0 && r.NonMonotonic == 0 && r.Invalid == 0
+	return *r, nil
+}
@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func export(ids ...int64) *strings.Reader {
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintln(&b, id)
+	}
+	return strings.NewReader(b.String())
+}
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	a := New(Options{TempDir: dir, ChunkSize: 3})
+
+	node1 := []int64{}
+	node2 := []int64{}
+	for i := int64(0); i < 10; i++ {
+		node1 = append(node1, i<<22|1<<17|i)
+		node2 = append(node2, i<<22|2<<17|i)
+	}
+	if err := a.Add("node-1", export(node1...)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Add("node-2", export(node2...)); err != nil {
+		t.Fatal(err)
+	}
+	// sorted exports are kept as one run each
+	if len(a.runs) != 2 {
+		t.Errorf("unexpected runs: %d", len(a.runs))
+	}
+
+	r, err := a.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed || r.Total != 20 || r.Unique != 20 || len(r.Exports) != 2 || r.Exports[0].Nodes != 1 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("runs not removed: %d", len(files))
+	}
+	if _, err := a.Finish(); err != ErrFinished {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAuditViolations(t *testing.T) {
+	a := New(Options{TempDir: t.TempDir(), ChunkSize: 2})
+
+	// 5 is issued by both nodes, and node-2 goes backwards
+	if err := a.Add("node-1", export(1, 3, 5, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Add("node-2", strings.NewReader("9\n5\n\n2\nx\n4\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := a.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed || r.Total != 8 || r.Unique != 7 || r.Duplicates != 1 || r.Invalid != 1 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if len(r.DuplicateIDs) != 1 || r.DuplicateIDs[0].ID != 5 ||
+		strings.Join(r.DuplicateIDs[0].Exports, ",") != "node-1,node-2" {
+		t.Errorf("unexpected duplicates: %+v", r.DuplicateIDs)
+	}
+
+	e := r.Exports[1]
+	if e.NonMonotonic != 2 || e.Min != 2 || e.Max != 9 {
+		t.Errorf("unexpected export: %+v", e)
+	}
+	if len(e.Violations) != 3 || e.Violations[0] != (Violation{2, dxyflake.ID(5), "not greater than 9"}) ||
+		e.Violations[2].Line != 5 {
+		t.Errorf("unexpected violations: %+v", e.Violations)
+	}
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// run is a sorted file of big endian IDs written by one export.
+type run struct {
+	export int
+	file   *os.File
+	w      *bufio.Writer
+	last   dxyflake.ID
+}
+
+func newRun(dir string, export int) (*run, error) {
+	f, err := os.CreateTemp(dir, "dxyflake-audit-*.run")
+	if err != nil {
+		return nil, err
+	}
+	return &run{export: export, file: f, w: bufio.NewWriterSize(f, 1<<20)}, nil
+}
+
+func (r *run) write(ids []dxyflake.ID) error {
+	var b [8]byte
+	for _, id := range ids {
+		binary.BigEndian.PutUint64(b[:], uint64(id))
+		if _, err := r.w.Write(b[:]); err != nil {
+			return err
+		}
+	}
+	r.last = ids[len(ids)-1]
+	return nil
+}
+
+func (r *run) remove() error {
+	r.file.Close()
+	return os.Remove(r.file.Name())
+}
+
+// cursor reads a run back during the merge.
+type cursor struct {
+	run *run
+	r   *bufio.Reader
+	id  dxyflake.ID
+}
+
+func (c *cursor) next() (bool, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(c.r, b[:]); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	c.id = dxyflake.ID(binary.BigEndian.Uint64(b[:]))
+	return true, nil
+}
+
+// cursors is a min-heap of cursors by their current ID.
+type cursors []*cursor
+
+func (h cursors) Len() int            { return len(h) }
+func (h cursors) Less(i, j int) bool  { return h[i].id < h[j].id }
+func (h cursors) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *cursors) Push(x interface{}) { *h = append(*h, x.(*cursor)) }
+func (h *cursors) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// merge calls visit once for every distinct ID of runs, in increasing
+// order, with the export of every occurrence of the ID.
+func merge(runs []*run, visit func(id dxyflake.ID, exports []int)) error {
+	h := make(cursors, 0, len(runs))
+	for _, r := range runs {
+		if err := r.w.Flush(); err != nil {
+			return err
+		}
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		c := &cursor{run: r, r: bufio.NewReaderSize(r.file, 1<<20)}
+		ok, err := c.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, c)
+		}
+	}
+	heap.Init(&h)
+
+	var exports []int
+	for h.Len() > 0 {
+		id := h[0].id
+		exports = exports[:0]
+		for h.Len() > 0 && h[0].id == id {
+			c := h[0]
+			exports = append(exports, c.run.export)
+			ok, err := c.next()
+			if err != nil {
+				return err
+			}
+			if ok {
+				heap.Fix(&h, 0)
+			} else {
+				heap.Pop(&h)
+			}
+		}
+		visit(id, exports)
+	}
+	return nil
+}
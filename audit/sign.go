@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+)
+
+// These errors are returned when signing or verifying reports.
+var (
+	ErrBadSignature = errors.New("report signature does not verify")
+	ErrInvalidKey   = errors.New("not an ed25519 key")
+)
+
+// Signed is a report with an ed25519 signature over its compact JSON
+// encoding, so it still verifies after being indented. PublicKey identifies
+// the signer; verifiers must compare it with a key they trust rather than
+// trust it on its own.
+type Signed struct {
+	Report    json.RawMessage `json:"report"`
+	Algorithm string          `json:"algorithm"`
+	PublicKey []byte          `json:"public-key"`
+	Signature []byte          `json:"signature"`
+}
+
+// Sign signs r with key.
+func Sign(r Report, key ed25519.PrivateKey) (*Signed, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Signed{
+		Report:    b,
+		Algorithm: "ed25519",
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, b),
+	}, nil
+}
+
+// Verify checks the signature with the trusted key pub and returns the
+// report.
+func (s *Signed) Verify(pub ed25519.PublicKey) (Report, error) {
+	var b bytes.Buffer
+	if err := json.Compact(&b, s.Report); err != nil {
+		return Report{}, err
+	}
+	if s.Algorithm != "ed25519" || !bytes.Equal(s.PublicKey, pub) ||
+		!ed25519.Verify(pub, b.Bytes(), s.Signature) {
+		return Report{}, ErrBadSignature
+	}
+	var r Report
+	err := json.Unmarshal(b.Bytes(), &r)
+	return r, err
+}
+
+func readPEM(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+	return block.Bytes, nil
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 ed25519 private key, as
+// written by "openssl genpkey -algorithm ed25519".
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX ed25519 public key, as written by
+// "openssl pkey -pubout".
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return pub, nil
+}
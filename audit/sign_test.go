@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Sign(Report{Total: 42, Passed: true}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Signed
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	r, err := decoded.Verify(pub)
+	if err != nil || r.Total != 42 || !r.Passed {
+		t.Errorf("Verify() = %+v, %v", r, err)
+	}
+
+	decoded.Report = json.RawMessage(`{"total":43,"passed":true}`)
+	if _, err := decoded.Verify(pub); err != ErrBadSignature {
+		t.Errorf("tampered report verified: %v", err)
+	}
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := s.Verify(other); err != ErrBadSignature {
+		t.Errorf("verified with another key: %v", err)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPath := filepath.Join(dir, "key.pub")
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := LoadPrivateKey(privPath); err != nil || !got.Equal(priv) {
+		t.Errorf("LoadPrivateKey() = %v", err)
+	}
+	if got, err := LoadPublicKey(pubPath); err != nil || !got.Equal(pub) {
+		t.Errorf("LoadPublicKey() = %v", err)
+	}
+	if _, err := LoadPrivateKey(pubPath); err == nil {
+		t.Error("public key loaded as private key")
+	}
+}
@@ -0,0 +1,80 @@
+package dxyflake
+
+import (
+	"sync"
+	"time"
+)
+
+// Backfill issues IDs for past times, e.g. to import legacy records with
+// IDs whose time part is their original creation time. It keeps the next
+// sequence number of every time unit it issued IDs of, so the IDs of one
+// import job are unique whatever the order of its times; memory grows
+// with the number of distinct time units.
+//
+// Backfill only issues IDs of time units before the one it was created in,
+// so a dxyflake of the same machine ID and service ID started afterwards
+// issues other IDs. Past IDs of other dxyflakes of that pair are not known
+// to it: give an import its own machine ID or service ID, or import times
+// no dxyflake of the pair issued IDs at.
+type Backfill struct {
+	df    *dxyflake
+	until int64 // the first time unit refused
+
+	mutex  sync.Mutex
+	next   map[int64]uint32 // next sequence number by time unit
+	closed bool
+}
+
+// NewBackfill returns a Backfill of IDs of the dxyflakes created with st.
+// It returns the errors of NewDxyflakeWithError; with st.Exclusive, no
+// other dxyflake of the process may share its ID space until it is closed.
+func NewBackfill(st Settings) (*Backfill, error) {
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		return nil, err
+	}
+	return &Backfill{df: df, until: df.CurrentTick(), next: make(map[int64]uint32)}, nil
+}
+
+// NextIDAt issues an ID whose time part is the time unit of t. It returns
+// ErrOutOfRange if t is before the start time, ErrFutureTimestamp if t is
+// not before the time unit the Backfill was created in,
+// ErrSequenceExhausted once the sequence numbers of the time unit are used
+// up, and ErrClosed after Close.
+func (b *Backfill) NextIDAt(t time.Time) (ID, error) {
+	l := b.df.layout
+	tick := l.Tick(t)
+	if tick < 0 {
+		return 0, ErrOutOfRange
+	}
+	if tick >= b.until {
+		return 0, ErrFutureTimestamp
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+	seq := b.next[tick]
+	if seq >= 1<<uint(l.BitLenSequence) {
+		return 0, ErrSequenceExhausted
+	}
+	b.next[tick] = seq + 1
+	return l.compose(tick, b.df.machineID, b.df.serviceID, uint16(seq)), nil
+}
+
+// Layout returns the layout of the IDs of the Backfill.
+func (b *Backfill) Layout() Layout {
+	return b.df.layout
+}
+
+// Close stops the Backfill and releases its ID space, see Close of a
+// dxyflake. Every later NextIDAt returns ErrClosed.
+func (b *Backfill) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.closed = true
+	b.next = nil
+	return b.df.Close()
+}
@@ -0,0 +1,48 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackfill(t *testing.T) {
+	var st Settings
+	st.Init(9, 10)
+	st.StartTime = time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 53, 4, 4, 2
+	b, err := NewBackfill(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	seen := make(map[ID]bool)
+	for _, t0 := range []time.Time{at, at.Add(time.Hour), at, at, at} {
+		id, err := b.NextIDAt(t0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		seen[id] = true
+		p := b.Layout().DecomposeParts(id)
+		if !p.Timestamp.Equal(t0) || p.MachineID != 9 || p.ServiceID != 10 {
+			t.Errorf("unexpected parts: %+v", p)
+		}
+	}
+	if _, err := b.NextIDAt(at); err != ErrSequenceExhausted {
+		t.Errorf("fifth ID of a time unit: %v", err)
+	}
+	if _, err := b.NextIDAt(st.StartTime.Add(-time.Hour)); err != ErrOutOfRange {
+		t.Errorf("time before the start time: %v", err)
+	}
+	if _, err := b.NextIDAt(time.Now().Add(time.Hour)); err != ErrFutureTimestamp {
+		t.Errorf("future time: %v", err)
+	}
+
+	b.Close()
+	if _, err := b.NextIDAt(at.Add(2 * time.Hour)); err != ErrClosed {
+		t.Errorf("NextIDAt() after Close = %v", err)
+	}
+}
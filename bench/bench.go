@@ -0,0 +1,306 @@
+// Package bench runs the dxyflake benchmark suite from programs, so that
+// performance CI can keep a JSON report of a baseline and fail builds whose
+// throughput regresses beyond a threshold, see Report.Check.
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+var (
+	// ErrUnknownCase is returned by Run for case names not in Cases.
+	ErrUnknownCase = errors.New("unknown benchmark case")
+	// ErrSchema is returned by ReadReport for reports of another schema.
+	ErrSchema = errors.New("unsupported report schema")
+	// ErrRegression is returned by Report.Check for regressed cases.
+	ErrRegression = errors.New("benchmark regression")
+)
+
+// Schema is the version of the JSON report format. It changes when fields
+// are removed or change their meaning.
+const Schema = 1
+
+// These are the defaults used when Config fields are left zero.
+const (
+	DefaultDuration  = time.Second
+	DefaultThreshold = 0.1
+)
+
+// A Case is one benchmark. Setup returns the operation measured, which
+// must be safe for concurrent use if the case is parallel.
+type Case struct {
+	Name     string
+	Parallel bool
+	Setup    func() (op func() error, err error)
+}
+
+func newGenerator() (dxyflake.Generator, error) {
+	var st dxyflake.Settings
+	st.Init(1, 1)
+	df := dxyflake.NewDxyflake(st)
+	if df == nil {
+		return nil, errors.New("dxyflake not created")
+	}
+	return df, nil
+}
+
+func nextID() (func() error, error) {
+	gen, err := newGenerator()
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := gen.NextID()
+		return err
+	}, nil
+}
+
+func nextIDSharded() (func() error, error) {
+	var st dxyflake.Settings
+	st.Init(1, 1)
+	gen, err := dxyflake.NewShardedDxyflake(st, runtime.GOMAXPROCS(0))
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := gen.NextID()
+		return err
+	}, nil
+}
+
+func format(f func(dxyflake.ID) string) func() (func() error, error) {
+	return func() (func() error, error) {
+		id := dxyflake.ID(475370495148032)
+		return func() error {
+			f(id)
+			return nil
+		}, nil
+	}
+}
+
+// Cases are the benchmarks of the suite.
+var Cases = []Case{
+	{"next-id", false, nextID},
+	{"next-id-parallel", true, nextID},
+	{"next-id-sharded-parallel", true, nextIDSharded},
+	{"string", false, format(dxyflake.ID.String)},
+	{"base62", false, format(dxyflake.ID.Base62)},
+	{"decompose", false, func() (func() error, error) {
+		return func() error {
+			dxyflake.Decompose(475370495148032)
+			return nil
+		}, nil
+	}},
+}
+
+// Config configures Run.
+//
+// Cases names the cases to run. If Cases is empty, every case is run.
+//
+// Duration is how long each case runs. If Duration is 0, DefaultDuration
+// is used.
+//
+// Parallelism is the number of goroutines of parallel cases. If
+// Parallelism is 0, GOMAXPROCS is used.
+type Config struct {
+	Cases       []string
+	Duration    time.Duration
+	Parallelism int
+}
+
+// Result is the measurement of one case.
+type Result struct {
+	Name        string  `json:"name"`
+	Ops         int64   `json:"ops"`
+	Errors      int64   `json:"errors"`
+	NsPerOp     float64 `json:"ns-per-op"`
+	OpsPerSec   float64 `json:"ops-per-sec"`
+	AllocsPerOp float64 `json:"allocs-per-op"`
+	BytesPerOp  float64 `json:"bytes-per-op"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Schema     int       `json:"schema"`
+	Time       time.Time `json:"time"`
+	GoVersion  string    `json:"go-version"`
+	GOOS       string    `json:"goos"`
+	GOARCH     string    `json:"goarch"`
+	NumCPU     int       `json:"num-cpu"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Results    []Result  `json:"results"`
+}
+
+// Run runs the cases of cfg one after the other and reports their
+// throughput.
+func Run(cfg Config) (Report, error) {
+	if cfg.Duration <= 0 {
+		cfg.Duration = DefaultDuration
+	}
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = runtime.GOMAXPROCS(0)
+	}
+	cases := Cases
+	if len(cfg.Cases) > 0 {
+		cases = nil
+		for _, name := range cfg.Cases {
+			c, ok := lookup(name)
+			if !ok {
+				return Report{}, fmt.Errorf("%w: %s", ErrUnknownCase, name)
+			}
+			cases = append(cases, c)
+		}
+	}
+
+	report := Report{
+		Schema:     Schema,
+		Time:       time.Now().UTC(),
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+	for _, c := range cases {
+		op, err := c.Setup()
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %w", c.Name, err)
+		}
+		n := 1
+		if c.Parallel {
+			n = cfg.Parallelism
+		}
+		r := measure(op, n, cfg.Duration)
+		r.Name = c.Name
+		report.Results = append(report.Results, r)
+	}
+	return report, nil
+}
+
+func lookup(name string) (Case, bool) {
+	for _, c := range Cases {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Case{}, false
+}
+
+// measure runs op on n goroutines for d.
+func measure(op func() error, n int, d time.Duration) Result {
+	var ops, errs int64
+	var stop int32
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var done, failed int64
+			for atomic.LoadInt32(&stop) == 0 {
+				// check the clock every 64 operations only
+				for j := 0; j < 64; j++ {
+					if op() != nil {
+						failed++
+					}
+				}
+				done += 64
+			}
+			atomic.AddInt64(&ops, done)
+			atomic.AddInt64(&errs, failed)
+		}()
+	}
+	time.Sleep(d)
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	r := Result{Ops: ops, Errors: errs}
+	if ops > 0 {
+		r.NsPerOp = float64(elapsed.Nanoseconds()) * float64(n) / float64(ops)
+		r.OpsPerSec = float64(ops) / elapsed.Seconds()
+		r.AllocsPerOp = float64(after.Mallocs-before.Mallocs) / float64(ops)
+		r.BytesPerOp = float64(after.TotalAlloc-before.TotalAlloc) / float64(ops)
+	}
+	return r
+}
+
+// Regression is a case whose throughput fell below its baseline.
+type Regression struct {
+	Name     string
+	Baseline float64 // ops per second
+	Current  float64 // ops per second
+}
+
+// Compare returns the cases of r whose throughput is more than threshold,
+// a fraction, below that of the same case in baseline. Cases missing from
+// either report are skipped.
+func (r Report) Compare(baseline Report, threshold float64) []Regression {
+	base := make(map[string]Result, len(baseline.Results))
+	for _, res := range baseline.Results {
+		base[res.Name] = res
+	}
+	var regressions []Regression
+	for _, res := range r.Results {
+		b, ok := base[res.Name]
+		if ok && res.OpsPerSec < b.OpsPerSec*(1-threshold) {
+			regressions = append(regressions, Regression{res.Name, b.OpsPerSec, res.OpsPerSec})
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool { return regressions[i].Name < regressions[j].Name })
+	return regressions
+}
+
+// Check returns ErrRegression, naming the regressed cases, if Compare finds
+// any. If threshold is 0, DefaultThreshold is used.
+func (r Report) Check(baseline Report, threshold float64) error {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	regressions := r.Compare(baseline, threshold)
+	if len(regressions) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(regressions))
+	for i, reg := range regressions {
+		msgs[i] = fmt.Sprintf("%s %.0f ops/s < %.0f ops/s (%.1f%%)",
+			reg.Name, reg.Current, reg.Baseline, 100*(reg.Current/reg.Baseline-1))
+	}
+	return fmt.Errorf("%w: %s", ErrRegression, strings.Join(msgs, ", "))
+}
+
+// WriteReport writes r as JSON to w.
+func WriteReport(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport reads a JSON report from r. It returns ErrSchema for reports
+// of another schema version.
+func ReadReport(r io.Reader) (Report, error) {
+	var report Report
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return Report{}, err
+	}
+	if report.Schema != Schema {
+		return Report{}, fmt.Errorf("%w: %d", ErrSchema, report.Schema)
+	}
+	return report, nil
+}
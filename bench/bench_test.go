@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(Config{Cases: []string{"next-id", "base62"}, Duration: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Schema != Schema || len(report.Results) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for _, r := range report.Results {
+		if r.Ops == 0 || r.Errors != 0 || r.OpsPerSec <= 0 || r.NsPerOp <= 0 {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	}
+
+	if _, err := Run(Config{Cases: []string{"next-idz"}}); !errors.Is(err, ErrUnknownCase) {
+		t.Errorf("unknown case accepted: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	baseline := Report{Schema: Schema, Results: []Result{{Name: "next-id", OpsPerSec: 1000}, {Name: "base62", OpsPerSec: 1000}}}
+	current := Report{Schema: Schema, Results: []Result{{Name: "next-id", OpsPerSec: 950}, {Name: "base62", OpsPerSec: 800}, {Name: "new", OpsPerSec: 1}}}
+
+	if err := current.Check(baseline, 0); !errors.Is(err, ErrRegression) || !strings.Contains(err.Error(), "base62") || strings.Contains(err.Error(), "next-id") {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := current.Check(baseline, 0.25); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, current); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadReport(&buf)
+	if err != nil || len(got.Results) != 3 || got.Results[1].OpsPerSec != 800 {
+		t.Errorf("ReadReport() = %+v, %v", got, err)
+	}
+	if _, err := ReadReport(strings.NewReader(`{"schema": 2}`)); !errors.Is(err, ErrSchema) {
+		t.Errorf("ReadReport() error = %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package dxyflake
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// BuildInfo describes the program a generator runs in, so that operators
+// can tell which code issued which IDs during incident forensics.
+//
+// Version is the version of the dxyflake module, or "(devel)" if it is
+// unknown. Program is the path of the main module. Commit, CommitTime and
+// Modified describe the version control state the program was built from,
+// if it was built with Go 1.18 or later from a checkout; Modified reports
+// uncommitted changes. GoVersion is the version of Go the program was
+// built with.
+type BuildInfo struct {
+	Version    string `json:"version"`
+	Program    string `json:"program,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit-time,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	GoVersion  string `json:"go-version"`
+}
+
+var build struct {
+	once sync.Once
+	info BuildInfo
+}
+
+// Build returns the BuildInfo of the program.
+func Build() BuildInfo {
+	build.once.Do(func() {
+		b := BuildInfo{Version: moduleVersion(), GoVersion: runtime.Version()}
+		if info, ok := debug.ReadBuildInfo(); ok {
+			b.Program = info.Main.Path
+			readVCS(info, &b)
+		}
+		build.info = b
+	})
+	return build.info
+}
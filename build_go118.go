@@ -0,0 +1,20 @@
+//go:build go1.18
+// +build go1.18
+
+package dxyflake
+
+import "runtime/debug"
+
+// readVCS sets the version control fields of b from info.
+func readVCS(info *debug.BuildInfo, b *BuildInfo) {
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Commit = s.Value
+		case "vcs.time":
+			b.CommitTime = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+}
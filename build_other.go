@@ -0,0 +1,10 @@
+//go:build !go1.18
+// +build !go1.18
+
+package dxyflake
+
+import "runtime/debug"
+
+// readVCS does nothing: builds before Go 1.18 carry no version control
+// information.
+func readVCS(info *debug.BuildInfo, b *BuildInfo) {}
@@ -0,0 +1,82 @@
+//go:build chaos
+// +build chaos
+
+package dxyflake
+
+// Fault injection for chaos testing, only compiled with the chaos build tag:
+//
+//	go test -tags chaos ./...
+//
+// The hooks are process wide and affect every generator. The invariants a
+// generator keeps under each fault are:
+//
+// Clock jump forward (InjectClockJump with d > 0): IDs stay unique and
+// increasing. The time part of the next ID jumps forward by d.
+//
+// Clock jump backward (InjectClockJump with d < 0): IDs stay unique and
+// increasing. The generator keeps issuing from its last time unit and, once
+// the 4096 sequence numbers of that unit are used up, moves to the next unit
+// and sleeps, holding its lock, until the clock has caught up with it. A
+// large jump therefore blocks NextID for about the size of the jump.
+//
+// Provider failure (InjectProviderFailure): NewDxyflake returns nil, as if
+// Settings.MachineID or Settings.ServiceID had failed. Generators that
+// already exist are not affected.
+//
+// Issue failure (InjectIssueFailure), e.g. a lost machine ID lease: NextID
+// returns the injected error without touching the generator state, so no ID
+// is skipped or issued twice once the fault is cleared.
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// The faults are read on every ID, so they are kept in atomics rather than
+// behind a lock.
+var chaos struct {
+	clockOffset int64        // time.Duration
+	providerErr atomic.Value // fault
+	issueErr    atomic.Value // fault
+}
+
+// fault wraps an injected error, as atomic.Value needs a consistent type.
+type fault struct{ err error }
+
+// InjectClockJump moves the clock seen by every generator by d, on top of
+// any earlier jump.
+func InjectClockJump(d time.Duration) {
+	atomic.AddInt64(&chaos.clockOffset, int64(d))
+}
+
+// InjectProviderFailure makes machine ID and service ID providers fail with
+// err. A nil err clears the fault.
+func InjectProviderFailure(err error) {
+	chaos.providerErr.Store(fault{err})
+}
+
+// InjectIssueFailure makes NextID fail with err. A nil err clears the fault.
+func InjectIssueFailure(err error) {
+	chaos.issueErr.Store(fault{err})
+}
+
+// ResetFaults clears every injected fault, including clock jumps.
+func ResetFaults() {
+	atomic.StoreInt64(&chaos.clockOffset, 0)
+	chaos.providerErr.Store(fault{})
+	chaos.issueErr.Store(fault{})
+}
+
+func clockJump() time.Duration {
+	return time.Duration(atomic.LoadInt64(&chaos.clockOffset))
+}
+
+func providerFault() error {
+	f, _ := chaos.providerErr.Load().(fault)
+	return f.err
+}
+
+func issueFault() error {
+	f, _ := chaos.issueErr.Load().(fault)
+	return f.err
+}
@@ -0,0 +1,104 @@
+//go:build chaos
+// +build chaos
+
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newChaosDxyflake(t *testing.T) *dxyflake {
+	var st Settings
+	st.Init(7, 7)
+	df := NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	return df
+}
+
+func TestChaosClockJump(t *testing.T) {
+	defer ResetFaults()
+	df := newChaosDxyflake(t)
+
+	before, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	InjectClockJump(time.Hour)
+	forward, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jumped := Decompose(forward)["time"] - Decompose(before)["time"]; jumped < 360000 {
+		t.Errorf("unexpected forward jump: %d", jumped)
+	}
+
+	// a generator that has seen the future clock would sleep for an hour
+	ResetFaults()
+	df = newChaosDxyflake(t)
+	last, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	InjectClockJump(-50 * time.Millisecond)
+	for i := 0; i < 3*(1<<BitLenSequence); i++ {
+		id, err := df.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("id %d is not greater than %d after a backward jump", id, last)
+		}
+		last = id
+	}
+}
+
+func TestChaosProviderFailure(t *testing.T) {
+	defer ResetFaults()
+	df := newChaosDxyflake(t)
+
+	InjectProviderFailure(errors.New("provider down"))
+	var st Settings
+	st.Init(1, 1)
+	if NewDxyflake(st) != nil {
+		t.Error("dxyflake created with failing providers")
+	}
+	if _, err := df.NextID(); err != nil {
+		t.Errorf("existing dxyflake affected: %s", err)
+	}
+
+	InjectProviderFailure(nil)
+	if NewDxyflake(st) == nil {
+		t.Error("dxyflake not created after the fault was cleared")
+	}
+}
+
+func TestChaosIssueFailure(t *testing.T) {
+	defer ResetFaults()
+	df := newChaosDxyflake(t)
+
+	before, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lost := errors.New("lease lost")
+	InjectIssueFailure(lost)
+	if _, err := df.NextID(); err != lost {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	InjectIssueFailure(nil)
+	after, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after <= before {
+		t.Errorf("id %d is not greater than %d", after, before)
+	}
+}
@@ -0,0 +1,254 @@
+// Package client fetches dxyflake IDs from an ID server, see package
+// server.
+//
+// Concurrent NextID calls are coalesced: the first call of a batch waits up
+// to Options.MaxWait for others to join it, and the batch is then fetched
+// with a single request. A chatty caller thus costs one request per batch
+// rather than one per ID.
+//
+// Hybrid issues IDs locally while it holds a machine ID lease and falls
+// back to a Client otherwise.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// The defaults of Options.MaxBatch and Options.MaxWait trade a millisecond
+// of latency for up to 100 IDs per request.
+const (
+	DefaultMaxBatch = 100
+	DefaultMaxWait  = time.Millisecond
+)
+
+// ErrShortBatch is returned when the server returns fewer IDs than asked
+// for.
+var ErrShortBatch = errors.New("server returned fewer IDs than requested")
+
+// Options configures a Client.
+//
+// MaxBatch is the largest number of NextID calls coalesced into one
+// request. It must not exceed the batch limit of the server.
+//
+// MaxWait is how long the first NextID call of a batch waits for others
+// to join it. A negative MaxWait disables coalescing.
+type Options struct {
+	HTTPClient *http.Client
+	MaxBatch   int
+	MaxWait    time.Duration
+}
+
+// Stats counts the NextID calls of a Client and the requests serving them.
+type Stats struct {
+	Calls    uint64
+	Requests uint64
+}
+
+// Client is a dxyflake.Generator fetching IDs from an ID server.
+type Client struct {
+	base string
+	opts Options
+
+	mutex   sync.Mutex
+	pending []chan result
+	timer   *time.Timer
+	stats   Stats
+}
+
+type result struct {
+	id  dxyflake.ID
+	err error
+}
+
+// New returns a Client of the ID server at baseURL, e.g.
+// "http://ids.internal:8080".
+func New(baseURL string, opts Options) *Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = DefaultMaxBatch
+	}
+	if opts.MaxWait == 0 {
+		opts.MaxWait = DefaultMaxWait
+	}
+	return &Client{base: strings.TrimSuffix(baseURL, "/"), opts: opts}
+}
+
+// NextID returns an ID issued by the server, fetched together with the IDs
+// of concurrent calls.
+func (c *Client) NextID() (dxyflake.ID, error) {
+	ch := make(chan result, 1)
+
+	c.mutex.Lock()
+	c.stats.Calls++
+	c.pending = append(c.pending, ch)
+	if len(c.pending) >= c.opts.MaxBatch || c.opts.MaxWait < 0 {
+		batch := c.take()
+		c.mutex.Unlock()
+		c.fetch(batch)
+	} else {
+		if len(c.pending) == 1 {
+			c.timer = time.AfterFunc(c.opts.MaxWait, c.flush)
+		}
+		c.mutex.Unlock()
+	}
+
+	r := <-ch
+	return r.id, r.err
+}
+
+// take removes the pending batch. It is called with the mutex locked.
+func (c *Client) take() []chan result {
+	batch := c.pending
+	c.pending = nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.stats.Requests++
+	return batch
+}
+
+// flush fetches the pending batch once MaxWait has passed.
+func (c *Client) flush() {
+	c.mutex.Lock()
+	if len(c.pending) == 0 {
+		c.mutex.Unlock()
+		return
+	}
+	batch := c.take()
+	c.mutex.Unlock()
+	c.fetch(batch)
+}
+
+func (c *Client) fetch(batch []chan result) {
+	ids, err := c.NextIDs(context.Background(), len(batch))
+	for i, ch := range batch {
+		if err != nil {
+			ch <- result{err: err}
+		} else {
+			ch <- result{id: ids[i]}
+		}
+	}
+}
+
+// NextIDs fetches n IDs with a single request, bypassing coalescing. Error
+// responses of the server are returned as *server.Error, whose Retryable
+// and RetryAfterMS fields tell whether and when to retry.
+func (c *Client) NextIDs(ctx context.Context, n int) ([]dxyflake.ID, error) {
+	u := c.base + "/v1/ids?" + url.Values{"count": {strconv.Itoa(n)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := new(server.Error)
+		if err := json.NewDecoder(resp.Body).Decode(e); err != nil || e.Code == "" {
+			return nil, fmt.Errorf("%s: invalid error response", resp.Status)
+		}
+		return nil, e
+	}
+	var body server.IDsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if len(body.IDs) < n {
+		return nil, ErrShortBatch
+	}
+
+	ids := make([]dxyflake.ID, n)
+	for i := range ids {
+		id, err := dxyflake.ParseString(body.IDs[i])
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// Describe fetches the description of the generator of the server. Compare
+// it with the local one before exchanging IDs:
+//
+//	d, err := c.Describe(ctx)
+//	...
+//	if err := d.Compatible(dxyflake.DefaultLayout.Describe()); err != nil {
+//		return err
+//	}
+func (c *Client) Describe(ctx context.Context) (dxyflake.Description, error) {
+	var d dxyflake.Description
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/v1/meta", nil)
+	if err != nil {
+		return d, err
+	}
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return d, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return d, fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&d)
+	return d, err
+}
+
+// ErrNoServerTime is returned by Offset for servers that do not send
+// server.TimeHeader.
+var ErrNoServerTime = errors.New("server sent no time")
+
+// Offset compares the system clock with the clock of the server, from the
+// server.TimeHeader of GET /v1/meta. It returns the server time minus the
+// system time, and the round trip time of the request, which bounds the
+// error of the offset. Its signature fits a clock.Reference.
+func (c *Client) Offset(ctx context.Context) (offset, rtt time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+"/v1/meta", nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	sent := time.Now()
+	resp, err := c.opts.HTTPClient.Do(req)
+	received := time.Now()
+	if err != nil {
+		return 0, 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	at, err := time.Parse(time.RFC3339Nano, resp.Header.Get(server.TimeHeader))
+	if err != nil {
+		return 0, 0, ErrNoServerTime
+	}
+	rtt = received.Sub(sent)
+	return at.Sub(sent.Add(rtt / 2)), rtt, nil
+}
+
+// Stats returns the numbers of NextID calls and of the requests made for
+// them.
+func (c *Client) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+func newTestServer(t *testing.T, opts server.Options) *httptest.Server {
+	var st dxyflake.Settings
+	st.Init(1, 2)
+	gen := dxyflake.NewDxyflake(st)
+	if gen == nil {
+		t.Fatal("dxyflake not created")
+	}
+	ts := httptest.NewServer(server.New(gen, opts))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestCoalescing(t *testing.T) {
+	ts := newTestServer(t, server.Options{})
+	c := New(ts.URL+"/", Options{MaxBatch: 10, MaxWait: time.Second})
+
+	const n = 100
+	ids := make(chan dxyflake.ID, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := c.NextID()
+			if err != nil {
+				t.Error(err)
+			}
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[dxyflake.ID]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		seen[id] = true
+	}
+	// every batch is full, so no call waits for MaxWait
+	if s := c.Stats(); s.Calls != n || s.Requests != n/10 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestMaxWait(t *testing.T) {
+	ts := newTestServer(t, server.Options{})
+	c := New(ts.URL, Options{MaxBatch: 10, MaxWait: 20 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := c.NextID(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("returned after %v, before MaxWait", d)
+	}
+
+	c = New(ts.URL, Options{MaxWait: -1})
+	for i := 0; i < 3; i++ {
+		if _, err := c.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := c.Stats(); s.Requests != 3 {
+		t.Errorf("coalescing not disabled: %+v", s)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := newTestServer(t, server.Options{MaxBatch: 5})
+	c := New(ts.URL, Options{MaxBatch: 10})
+
+	var e *server.Error
+	if _, err := c.NextIDs(context.Background(), 6); !errors.As(err, &e) || e.Code != server.CodeBatchTooLarge || e.Retryable {
+		t.Errorf("got %v", err)
+	}
+	ids, err := c.NextIDs(context.Background(), 5)
+	if err != nil || len(ids) != 5 {
+		t.Errorf("got %v, %v", ids, err)
+	}
+
+	c = New("http://127.0.0.1:1", Options{MaxWait: -1})
+	if _, err := c.NextID(); err == nil {
+		t.Error("no error without a server")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	ts := newTestServer(t, server.Options{})
+	c := New(ts.URL, Options{})
+
+	d, err := c.Describe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Compatible(dxyflake.DefaultLayout.Describe()); err != nil {
+		t.Error(err)
+	}
+	if d.MachineID == nil || *d.MachineID != 1 {
+		t.Errorf("unexpected description: %+v", d)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	ts := newTestServer(t, server.Options{})
+	c := New(ts.URL, Options{})
+
+	offset, rtt, err := c.Offset(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < -time.Second || offset > time.Second || rtt < 0 {
+		t.Errorf("Offset() = %v, %v", offset, rtt)
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Lease is the validity of a machine ID claim, such as a *lease.Lease.
+type Lease interface {
+	Valid() bool
+}
+
+// HybridStats counts the IDs a Hybrid issued locally and remotely.
+type HybridStats struct {
+	Local  uint64
+	Remote uint64
+}
+
+// Hybrid is a dxyflake.Generator that issues IDs with a local generator
+// while it holds a valid machine ID lease, and fetches them from the ID
+// server otherwise: before a lease is acquired, after it is lost and when
+// the local generator fails.
+//
+// IDs of the two sources are unique but not ordered with respect to each
+// other.
+type Hybrid struct {
+	remote dxyflake.Generator
+
+	mutex sync.Mutex
+	local dxyflake.Generator
+	lease Lease
+	stats HybridStats
+}
+
+// NewHybrid returns a Hybrid falling back to remote, usually a *Client.
+func NewHybrid(remote dxyflake.Generator) *Hybrid {
+	return &Hybrid{remote: remote}
+}
+
+// Attach makes h issue IDs with gen for as long as l is valid. gen must use
+// the machine ID l claims.
+func (h *Hybrid) Attach(gen dxyflake.Generator, l Lease) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.local, h.lease = gen, l
+}
+
+// Detach makes h fetch every ID from the ID server, e.g. before releasing
+// the lease.
+func (h *Hybrid) Detach() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.local, h.lease = nil, nil
+}
+
+// Local reports whether h currently issues IDs locally.
+func (h *Hybrid) Local() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.local != nil && h.lease.Valid()
+}
+
+// NextID issues an ID locally if h holds a valid lease, remotely otherwise.
+func (h *Hybrid) NextID() (dxyflake.ID, error) {
+	h.mutex.Lock()
+	local, l := h.local, h.lease
+	h.mutex.Unlock()
+
+	if local != nil && l.Valid() {
+		if id, err := local.NextID(); err == nil {
+			// the lease may have been lost while issuing; an ID is only
+			// safe if it was still valid afterwards
+			if l.Valid() {
+				h.count(&h.stats.Local)
+				return id, nil
+			}
+		}
+	}
+
+	id, err := h.remote.NextID()
+	if err == nil {
+		h.count(&h.stats.Remote)
+	}
+	return id, err
+}
+
+func (h *Hybrid) count(n *uint64) {
+	h.mutex.Lock()
+	*n++
+	h.mutex.Unlock()
+}
+
+// Stats returns the numbers of IDs issued locally and remotely.
+func (h *Hybrid) Stats() HybridStats {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.stats
+}
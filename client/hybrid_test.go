@@ -0,0 +1,78 @@
+package client
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+type testLease struct {
+	valid int32
+}
+
+func (l *testLease) Valid() bool {
+	return atomic.LoadInt32(&l.valid) != 0
+}
+
+func (l *testLease) set(valid bool) {
+	v := int32(0)
+	if valid {
+		v = 1
+	}
+	atomic.StoreInt32(&l.valid, v)
+}
+
+type failingGenerator struct{}
+
+func (failingGenerator) NextID() (dxyflake.ID, error) {
+	return 0, errors.New("failed")
+}
+
+func TestHybrid(t *testing.T) {
+	ts := newTestServer(t, server.Options{})
+	h := NewHybrid(New(ts.URL, Options{MaxWait: -1}))
+
+	machineID := func() int64 {
+		id, err := h.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return dxyflake.Decompose(id)["machine-id"]
+	}
+
+	// startup: no lease yet
+	if m := machineID(); m != 1 || h.Local() {
+		t.Errorf("got machine id %d before attaching", m)
+	}
+
+	var st dxyflake.Settings
+	st.Init(7, 2)
+	l := &testLease{}
+	l.set(true)
+	h.Attach(dxyflake.NewDxyflake(st), l)
+	if m := machineID(); m != 7 || !h.Local() {
+		t.Errorf("got machine id %d with a valid lease", m)
+	}
+
+	l.set(false)
+	if m := machineID(); m != 1 || h.Local() {
+		t.Errorf("got machine id %d after losing the lease", m)
+	}
+
+	l.set(true)
+	h.Attach(failingGenerator{}, l)
+	if m := machineID(); m != 1 {
+		t.Errorf("got machine id %d from a failing local generator", m)
+	}
+
+	h.Detach()
+	if m := machineID(); m != 1 || h.Local() {
+		t.Errorf("got machine id %d after detaching", m)
+	}
+	if s := h.Stats(); s.Local != 1 || s.Remote != 4 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// These are the defaults of Replicas.
+const (
+	DefaultRefreshInterval = 30 * time.Second
+	DefaultDownTime        = time.Second
+)
+
+// ErrNoReplica is returned by Replicas.NextID when every replica is down.
+var ErrNoReplica = errors.New("no replica available")
+
+// Replicas is a dxyflake.Generator spreading NextID calls across the
+// replicas of an ID server in proportion to the capacity they advertise in
+// their descriptions, see coordinator.Pool. A replica that fails with a
+// retryable error is left out for its retry delay, or DefaultDownTime,
+// and the call is retried at another one.
+type Replicas struct {
+	replicas []*replica
+
+	mutex     sync.Mutex
+	refreshed time.Time
+}
+
+type replica struct {
+	client   *Client
+	capacity int64     // 0 until described
+	down     time.Time // left out until then
+}
+
+// NewReplicas returns a Replicas of the ID servers at baseURLs, each
+// fetched from by a Client with opts. The capacities are refreshed in the
+// background every DefaultRefreshInterval; until then the replicas are
+// weighted equally.
+func NewReplicas(baseURLs []string, opts Options) *Replicas {
+	r := &Replicas{replicas: make([]*replica, len(baseURLs))}
+	for i, u := range baseURLs {
+		r.replicas[i] = &replica{client: New(u, opts)}
+	}
+	return r
+}
+
+// Refresh fetches the description of every replica and weights it by its
+// capacity. Replicas that cannot be described are left out for
+// DefaultDownTime. It returns the first error.
+func (r *Replicas) Refresh(ctx context.Context) error {
+	capacities := make([]int64, len(r.replicas))
+	errs := make([]error, len(r.replicas))
+	var wg sync.WaitGroup
+	for i, rep := range r.replicas {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			d, err := c.Describe(ctx)
+			capacities[i], errs[i] = d.Capacity, err
+		}(i, rep.client)
+	}
+	wg.Wait()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.refreshed = time.Now()
+	var first error
+	for i, rep := range r.replicas {
+		if errs[i] != nil {
+			rep.down = r.refreshed.Add(DefaultDownTime)
+			if first == nil {
+				first = errs[i]
+			}
+			continue
+		}
+		rep.capacity = capacities[i]
+	}
+	return first
+}
+
+// pick returns a replica that is up, other than those in tried, chosen at
+// random in proportion to its capacity.
+func (r *Replicas) pick(tried map[*replica]bool) *replica {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.refreshed) > DefaultRefreshInterval {
+		r.refreshed = now
+		go r.Refresh(context.Background())
+	}
+
+	var total int64
+	up := make([]*replica, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		if !tried[rep] && !now.Before(rep.down) {
+			up = append(up, rep)
+			total += rep.weight()
+		}
+	}
+	if len(up) == 0 {
+		return nil
+	}
+	n := rand.Int63n(total)
+	for _, rep := range up {
+		if n -= rep.weight(); n < 0 {
+			return rep
+		}
+	}
+	return up[len(up)-1]
+}
+
+// weight is the capacity of the replica, or that of a single generator if
+// it has not been described yet.
+func (rep *replica) weight() int64 {
+	if rep.capacity > 0 {
+		return rep.capacity
+	}
+	return dxyflake.DefaultLayout.Capacity()
+}
+
+// NextID returns an ID of one of the replicas.
+func (r *Replicas) NextID() (dxyflake.ID, error) {
+	tried := make(map[*replica]bool, len(r.replicas))
+	err := ErrNoReplica
+	for {
+		rep := r.pick(tried)
+		if rep == nil {
+			return 0, err
+		}
+		var id dxyflake.ID
+		if id, err = rep.client.NextID(); err == nil {
+			return id, nil
+		}
+
+		var e *server.Error
+		if errors.As(err, &e) && !e.Retryable {
+			return 0, err
+		}
+		down := DefaultDownTime
+		if e != nil && e.RetryAfterMS > 0 {
+			down = time.Duration(e.RetryAfterMS) * time.Millisecond
+		}
+		r.mutex.Lock()
+		rep.down = time.Now().Add(down)
+		r.mutex.Unlock()
+		tried[rep] = true
+	}
+}
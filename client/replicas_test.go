@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/coordinator"
+	"github.com/GiterLab/dxyflake/lease"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	b := lease.NewMemoryBackend()
+	var st dxyflake.Settings
+	st.Init(0, 2)
+
+	// replica a claims three machine IDs, replica b one
+	var urls []string
+	var servers []*httptest.Server
+	for i, n := range []int{3, 1} {
+		pool, err := coordinator.New(b, coordinator.Options{Holder: fmt.Sprint("replica-", i)}).NewPool(ctx, st, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pool.Release(ctx)
+		ts := httptest.NewServer(server.New(pool, server.Options{}))
+		defer ts.Close()
+		urls = append(urls, ts.URL)
+		servers = append(servers, ts)
+	}
+
+	r := NewReplicas(urls, Options{MaxWait: -1})
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	perReplica := make(map[bool]int)
+	for i := 0; i < 400; i++ {
+		id, err := r.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// replica a has machine IDs 0 to 2
+		perReplica[dxyflake.Decompose(id)["machine-id"] < 3]++
+	}
+	if a := perReplica[true]; a < 240 || a > 360 {
+		t.Errorf("replica a served %d of 400 calls, want about 300", a)
+	}
+
+	// calls move to the remaining replica
+	servers[0].Close()
+	for i := 0; i < 10; i++ {
+		id, err := r.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dxyflake.Decompose(id)["machine-id"] != 3 {
+			t.Errorf("ID not from replica b: %d", id)
+		}
+	}
+	servers[1].Close()
+	if _, err := r.NextID(); err == nil {
+		t.Error("NextID() succeeded without replicas")
+	}
+}
@@ -0,0 +1,44 @@
+package dxyflake
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the time source of a dxyflake. Now must be safe for concurrent
+// use. Sleep waits for d as measured by the clock; for clocks that tick at
+// the rate of the system clock, time.Sleep will do.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SystemClock is the clock of the operating system, used when
+// Settings.Clock is nil.
+var SystemClock Clock = systemClock{}
+
+// sleepContext waits for d on c, or until ctx is done. Only sleeps on the
+// system clock can be interrupted; on other clocks ctx is checked before
+// and after sleeping.
+func sleepContext(ctx context.Context, c Clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c != SystemClock {
+		c.Sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,102 @@
+// Package clock provides dxyflake clocks backed by reference time sources,
+// such as a PTP hardware clock, for deployments where IDs of different
+// machines are only ordered as well as their clocks agree.
+//
+//	phc, err := clock.OpenPHC("/dev/ptp0", 37*time.Second)
+//	...
+//	src := clock.New(phc.Sample)
+//	if _, err := src.Sample(); err != nil {
+//		// do not start issuing IDs
+//	}
+//	st.Clock = src
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned by OpenPHC on platforms without PTP hardware
+// clocks.
+var ErrUnsupported = errors.New("ptp hardware clocks are not supported on this platform")
+
+// Sampler reads the time of a reference clock.
+type Sampler func() (time.Time, error)
+
+// Source is a dxyflake.Clock that reads its time from a Sampler.
+//
+// When the sampler fails, Now falls back to the system time corrected by
+// the offset of the last good sample, so a generator keeps running on a
+// short outage; Err tells whether the clock can currently be trusted.
+type Source struct {
+	sample Sampler
+
+	mutex   sync.Mutex
+	offset  time.Duration
+	err     error
+	samples uint64
+	errors  uint64
+}
+
+// New returns a Source reading sample.
+func New(sample Sampler) *Source {
+	return &Source{sample: sample}
+}
+
+// Sample reads the reference clock, updates the offset to the system clock
+// and returns the reference time.
+func (s *Source) Sample() (time.Time, error) {
+	t, err := s.sample()
+	system := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.samples++
+	s.err = err
+	if err != nil {
+		s.errors++
+		return system.Add(s.offset), err
+	}
+	s.offset = t.Sub(system)
+	return t, nil
+}
+
+// Now returns the time of the reference clock, or the corrected system time
+// if the reference clock cannot be read.
+func (s *Source) Now() time.Time {
+	t, _ := s.Sample()
+	return t
+}
+
+// Sleep waits for d. Reference clocks are assumed to tick at the rate of the
+// system clock, only the offset differs.
+func (s *Source) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// Offset returns the reference time minus the system time at the last good
+// sample.
+func (s *Source) Offset() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.offset
+}
+
+// Err returns the error of the last sample, or nil if it succeeded.
+func (s *Source) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
+
+// Stats returns the number of samples taken and how many of them failed.
+func (s *Source) Stats() (samples, errors uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.samples, s.errors
+}
@@ -0,0 +1,74 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestSource(t *testing.T) {
+	offset := 90 * time.Millisecond
+	var fail error
+	src := New(func() (time.Time, error) {
+		if fail != nil {
+			return time.Time{}, fail
+		}
+		return time.Now().Add(offset), nil
+	})
+
+	if d := src.Now().Sub(time.Now()); d < offset-time.Millisecond || d > offset+time.Millisecond {
+		t.Errorf("unexpected time difference: %s", d)
+	}
+	if src.Err() != nil {
+		t.Errorf("unexpected error: %v", src.Err())
+	}
+
+	// the last offset is kept while the reference clock is down
+	fail = errors.New("no sync")
+	if d := src.Now().Sub(time.Now()); d < offset-time.Millisecond || d > offset+time.Millisecond {
+		t.Errorf("unexpected fallback difference: %s", d)
+	}
+	if src.Err() != fail {
+		t.Errorf("unexpected error: %v", src.Err())
+	}
+	if samples, errs := src.Stats(); samples != 2 || errs != 1 {
+		t.Errorf("unexpected stats: %d, %d", samples, errs)
+	}
+}
+
+func TestSourceGenerator(t *testing.T) {
+	// a reference clock a second behind the system clock
+	src := New(func() (time.Time, error) {
+		return time.Now().Add(-time.Second), nil
+	})
+
+	var st dxyflake.Settings
+	st.Init(1, 1)
+	st.StartTime = time.Now().Add(-time.Hour)
+	st.Clock = src
+	gen := dxyflake.NewDxyflake(st)
+	if gen == nil {
+		t.Fatal("dxyflake not created")
+	}
+	id, err := gen.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := dxyflake.Decompose(id)["time"]; elapsed > 359900 || elapsed < 359890 {
+		t.Errorf("unexpected time: %d", elapsed)
+	}
+}
+
+func TestOpenPHC(t *testing.T) {
+	phc, err := OpenPHC("/dev/ptp0", 37*time.Second)
+	if err != nil {
+		t.Skip("no PTP hardware clock:", err)
+	}
+	defer phc.Close()
+
+	if _, err := phc.Sample(); err != nil {
+		t.Error(err)
+	}
+}
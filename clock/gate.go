@@ -0,0 +1,128 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// These errors are returned by Check.
+var (
+	ErrClockDiverged = errors.New("system clock diverges from the references")
+	ErrNoReference   = errors.New("too few references answered")
+)
+
+// DefaultMaxOffset is well above the offset of an NTP-synchronized clock,
+// and DefaultTimeout leaves slow references a few round trips.
+const (
+	DefaultMaxOffset = 100 * time.Millisecond
+	DefaultTimeout   = 5 * time.Second
+)
+
+// Reference is a time source the system clock is checked against. Offset
+// returns the reference time minus the system time, and the round trip
+// time of the query. The Offset method of a client.Client, comparing with
+// a peer ID server, fits it.
+type Reference struct {
+	Name   string
+	Offset func(ctx context.Context) (offset, rtt time.Duration, err error)
+}
+
+// NTP is the Reference of an NTP server, see QueryNTP.
+func NTP(server string, timeout time.Duration) Reference {
+	return Reference{Name: server, Offset: func(ctx context.Context) (time.Duration, time.Duration, error) {
+		r, err := QueryNTP(server, timeout)
+		return r.Offset, r.RTT, err
+	}}
+}
+
+// GateOptions configures Check.
+//
+// MaxOffset is how far the system clock may be off the references.
+// If MaxOffset is 0, DefaultMaxOffset is used.
+//
+// MinReferences is the number of references that must answer.
+// If MinReferences is 0, one must.
+//
+// Timeout is the deadline of the context passed to the references, which
+// Check waits for. If Timeout is 0, DefaultTimeout is used.
+type GateOptions struct {
+	MaxOffset     time.Duration
+	MinReferences int
+	Timeout       time.Duration
+}
+
+// Result is the answer of one reference.
+type Result struct {
+	Name   string
+	Offset time.Duration
+	RTT    time.Duration
+	Err    error
+}
+
+// Report is the outcome of Check. Offset is the median offset of the
+// references that answered.
+type Report struct {
+	Results []Result
+	Offset  time.Duration
+}
+
+// Check queries refs concurrently and compares the system clock with
+// them. It returns ErrNoReference if fewer than opts.MinReferences answer,
+// and ErrClockDiverged if the median of their offsets exceeds
+// opts.MaxOffset; the median tolerates a minority of bad references.
+func Check(ctx context.Context, refs []Reference, opts GateOptions) (Report, error) {
+	if opts.MaxOffset <= 0 {
+		opts.MaxOffset = DefaultMaxOffset
+	}
+	if opts.MinReferences <= 0 {
+		opts.MinReferences = 1
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	report := Report{Results: make([]Result, len(refs))}
+	done := make(chan struct{})
+	for i, ref := range refs {
+		go func(r *Result, ref Reference) {
+			r.Name = ref.Name
+			r.Offset, r.RTT, r.Err = ref.Offset(ctx)
+			done <- struct{}{}
+		}(&report.Results[i], ref)
+	}
+	for range refs {
+		<-done
+	}
+
+	var offsets []time.Duration
+	for _, r := range report.Results {
+		if r.Err == nil {
+			offsets = append(offsets, r.Offset)
+		}
+	}
+	if len(offsets) < opts.MinReferences {
+		return report, fmt.Errorf("%w: %d of %d", ErrNoReference, len(offsets), opts.MinReferences)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	n := len(offsets)
+	report.Offset = (offsets[(n-1)/2] + offsets[n/2]) / 2
+	if report.Offset > opts.MaxOffset || report.Offset < -opts.MaxOffset {
+		return report, fmt.Errorf("%w: off by %v", ErrClockDiverged, report.Offset)
+	}
+	return report, nil
+}
+
+// Gate returns a check of the system clock against refs for
+// dxyflake.Settings.CheckClock, so that a badly clocked node never starts
+// issuing IDs.
+func Gate(refs []Reference, opts GateOptions) func() error {
+	return func() error {
+		_, err := Check(context.Background(), refs, opts)
+		return err
+	}
+}
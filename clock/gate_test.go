@@ -0,0 +1,45 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func fixed(name string, offset time.Duration, err error) Reference {
+	return Reference{Name: name, Offset: func(ctx context.Context) (time.Duration, time.Duration, error) {
+		return offset, time.Millisecond, err
+	}}
+}
+
+func TestCheck(t *testing.T) {
+	errDown := errors.New("down")
+	ctx := context.Background()
+
+	// one bad reference is outvoted
+	refs := []Reference{fixed("a", 10*time.Millisecond, nil), fixed("b", time.Hour, nil), fixed("c", -5*time.Millisecond, nil), fixed("d", 0, errDown)}
+	r, err := Check(ctx, refs, GateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Offset != 10*time.Millisecond || len(r.Results) != 4 || r.Results[3].Err != errDown {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	refs = []Reference{fixed("a", 2*time.Second, nil), fixed("b", 3*time.Second, nil)}
+	if _, err := Check(ctx, refs, GateOptions{MaxOffset: time.Second}); !errors.Is(err, ErrClockDiverged) {
+		t.Errorf("diverged clock: %v", err)
+	}
+	if err := Gate(refs, GateOptions{MaxOffset: 5 * time.Second})(); err != nil {
+		t.Errorf("Gate() = %v", err)
+	}
+
+	refs = []Reference{fixed("a", 0, nil), fixed("b", 0, errDown)}
+	if _, err := Check(ctx, refs, GateOptions{MinReferences: 2}); !errors.Is(err, ErrNoReference) {
+		t.Errorf("missing reference: %v", err)
+	}
+	if _, err := Check(ctx, nil, GateOptions{}); !errors.Is(err, ErrNoReference) {
+		t.Errorf("no references: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package clock
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrInvalidNTPResponse is returned by QueryNTP for responses that are not
+// a usable server reply.
+var ErrInvalidNTPResponse = errors.New("invalid ntp response")
+
+// DefaultNTPPort is the port QueryNTP uses for servers without one.
+const DefaultNTPPort = "123"
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1900, to the
+// Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// NTPResult is the outcome of an SNTP query.
+//
+// Offset is the server time minus the system time, and RTT the round trip
+// time of the query, which bounds the error of Offset.
+type NTPResult struct {
+	Server  string
+	Offset  time.Duration
+	RTT     time.Duration
+	Stratum int
+}
+
+// QueryNTP asks an NTP server for the time, with a single SNTP (RFC 4330)
+// request, and returns the offset of the system clock. The server is a
+// host name or a host:port pair.
+func QueryNTP(server string, timeout time.Duration) (NTPResult, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, DefaultNTPPort)
+	}
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return NTPResult{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var req [48]byte
+	req[0] = 0<<6 | 4<<3 | 3 // no leap warning, version 4, client mode
+	t1 := time.Now()
+	putNTPTime(req[40:], t1)
+	if _, err := conn.Write(req[:]); err != nil {
+		return NTPResult{}, err
+	}
+
+	var resp [48]byte
+	n, err := conn.Read(resp[:])
+	t4 := time.Now()
+	if err != nil {
+		return NTPResult{}, err
+	}
+	if n < len(resp) || resp[0]&7 != 4 || resp[1] == 0 || resp[0]>>6 == 3 ||
+		binary.BigEndian.Uint64(resp[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		// not a server reply, a kiss-o'-death, unsynchronized or not an
+		// answer to this request
+		return NTPResult{}, ErrInvalidNTPResponse
+	}
+
+	t2, t3 := ntpTime(resp[32:]), ntpTime(resp[40:])
+	return NTPResult{
+		Server:  server,
+		Offset:  (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		RTT:     t4.Sub(t1) - t3.Sub(t2),
+		Stratum: int(resp[1]),
+	}, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	sec := int64(binary.BigEndian.Uint32(b)) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(sec, frac*1e9>>32)
+}
+
+func putNTPTime(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[4:], uint32(int64(t.Nanosecond())<<32/1e9))
+}
@@ -0,0 +1,73 @@
+package clock
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeNTP answers SNTP requests with its system time moved by offset, or
+// with reply(req) if set.
+func fakeNTP(t *testing.T, offset time.Duration, reply func(req []byte) []byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("udp not available:", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var resp []byte
+			if reply != nil {
+				resp = reply(buf[:n])
+			} else {
+				resp = make([]byte, 48)
+				resp[0] = 4<<3 | 4 // version 4, server mode
+				resp[1] = 2
+				copy(resp[24:32], buf[40:48])
+				now := time.Now().Add(offset)
+				putNTPTime(resp[32:], now)
+				putNTPTime(resp[40:], now)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestQueryNTP(t *testing.T) {
+	addr := fakeNTP(t, 2*time.Second, nil)
+	r, err := QueryNTP(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := r.Offset - 2*time.Second; d < -50*time.Millisecond || d > 50*time.Millisecond {
+		t.Errorf("got offset %v, want 2s", r.Offset)
+	}
+	if r.Stratum != 2 || r.RTT < 0 || r.Server != addr {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestQueryNTPInvalid(t *testing.T) {
+	for name, reply := range map[string]func([]byte) []byte{
+		"short":       func([]byte) []byte { return make([]byte, 10) },
+		"client mode": func([]byte) []byte { b := make([]byte, 48); b[0] = 3; b[1] = 2; return b },
+		"kiss of death": func(req []byte) []byte {
+			b := make([]byte, 48)
+			b[0] = 4
+			copy(b[24:32], req[40:48])
+			return b
+		},
+		"wrong origin": func([]byte) []byte { b := make([]byte, 48); b[0] = 4; b[1] = 2; return b },
+	} {
+		if _, err := QueryNTP(fakeNTP(t, 0, reply), time.Second); err != ErrInvalidNTPResponse {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build linux
+// +build linux
+
+package clock
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// PHC is a PTP hardware clock, such as /dev/ptp0, usually kept in sync with
+// the grandmaster by ptp4l.
+type PHC struct {
+	file      *os.File
+	clockID   int32
+	utcOffset time.Duration
+}
+
+// OpenPHC opens the PTP hardware clock device. utcOffset is subtracted from
+// every reading: PHCs usually run on TAI, which is 37 seconds ahead of UTC
+// since 2017.
+func OpenPHC(device string, utcOffset time.Duration) (*PHC, error) {
+	f, err := os.Open(device)
+	if err != nil {
+		return nil, err
+	}
+	p := &PHC{
+		file:      f,
+		clockID:   int32(^f.Fd()<<3 | 3), // FD_TO_CLOCKID
+		utcOffset: utcOffset,
+	}
+	if _, err := p.Sample(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Sample reads the clock. It is a Sampler.
+func (p *PHC) Sample() (time.Time, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(p.clockID, &ts); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(ts.Unix()).Add(-p.utcOffset), nil
+}
+
+// Close closes the device.
+func (p *PHC) Close() error {
+	return p.file.Close()
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package clock
+
+import "time"
+
+// PHC is a PTP hardware clock. It is only supported on Linux.
+type PHC struct{}
+
+// OpenPHC returns ErrUnsupported.
+func OpenPHC(device string, utcOffset time.Duration) (*PHC, error) {
+	return nil, ErrUnsupported
+}
+
+// Sample returns ErrUnsupported.
+func (p *PHC) Sample() (time.Time, error) {
+	return time.Time{}, ErrUnsupported
+}
+
+// Close does nothing.
+func (p *PHC) Close() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package dxyflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ClockBackPolicy is what a dxyflake does when its clock moves back behind
+// the latest time it has read, e.g. after an NTP step or a VM migration.
+type ClockBackPolicy int
+
+// These are the clock back policies.
+const (
+	ClockBackContinue ClockBackPolicy = iota // keep issuing in the time unit of the last ID, the default
+	ClockBackWait                            // wait until the clock has caught up
+	ClockBackError                           // return ErrClockMovedBack
+)
+
+// ErrClockMovedBack is returned by NextID under ClockBackError while the
+// clock is behind the latest time the dxyflake has read by more than
+// Settings.ClockBackTolerance.
+var ErrClockMovedBack = errors.New("clock moved back")
+
+// tick returns the current dxyflake time, applying the clock back policy
+// if the clock is behind the latest time read. If wait is false,
+// ClockBackWait returns ErrClockMovedBack instead of waiting. It returns
+// ErrClockCheck while the periodic clock check fails.
+func (df *dxyflake) tick(ctx context.Context, wait bool) (int64, error) {
+	if err := df.checker.failed(); err != nil {
+		return 0, err
+	}
+	current := df.elapsed(df.now())
+	behind := df.lastTick - current
+	if behind <= 0 {
+		df.lastTick = current
+		return current, nil
+	}
+	d := time.Duration(behind) * df.layout.Unit
+	if d <= df.clockSlack || df.clockBack == ClockBackContinue {
+		return current, nil
+	}
+	if df.clockBack == ClockBackError || !wait {
+		return 0, fmt.Errorf("%w by %v", ErrClockMovedBack, d)
+	}
+	df.waits++
+	if err := df.wait(ctx, df.sleepTime(behind, df.now())); err != nil {
+		return 0, err
+	}
+	// a clock still behind is left to ClockBackContinue
+	return df.elapsed(df.now()), nil
+}
@@ -0,0 +1,66 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClockBack(t *testing.T) {
+	newDxyflake := func(policy ClockBackPolicy, tolerance time.Duration) (*dxyflake, *steppedClock) {
+		var st Settings
+		st.Init(1, 2)
+		c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		st.Clock = c
+		st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+		st.ClockBack = policy
+		st.ClockBackTolerance = tolerance
+		df, err := NewDxyflakeWithError(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := df.NextID(); err != nil {
+			t.Fatal(err)
+		}
+		c.now = c.now.Add(-time.Second)
+		return df, c
+	}
+
+	df, _ := newDxyflake(ClockBackContinue, 0)
+	if _, err := df.NextID(); err != nil {
+		t.Errorf("ClockBackContinue: %v", err)
+	}
+
+	df, c := newDxyflake(ClockBackError, 0)
+	if _, err := df.NextID(); !errors.Is(err, ErrClockMovedBack) {
+		t.Errorf("ClockBackError: NextID() error = %v, want %v", err, ErrClockMovedBack)
+	}
+	if _, err := df.NextIDs(2); !errors.Is(err, ErrClockMovedBack) {
+		t.Errorf("ClockBackError: NextIDs() error = %v, want %v", err, ErrClockMovedBack)
+	}
+	c.now = c.now.Add(time.Second)
+	if _, err := df.NextID(); err != nil {
+		t.Errorf("ClockBackError after catching up: %v", err)
+	}
+
+	df, _ = newDxyflake(ClockBackError, 2*time.Second)
+	if _, err := df.NextID(); err != nil {
+		t.Errorf("ClockBackError within the tolerance: %v", err)
+	}
+
+	df, c = newDxyflake(ClockBackWait, 0)
+	if _, err := df.TryNextID(); !errors.Is(err, ErrClockMovedBack) {
+		t.Errorf("ClockBackWait: TryNextID() error = %v, want %v", err, ErrClockMovedBack)
+	}
+	before := c.Now()
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited := c.Now().Sub(before); waited != time.Second {
+		t.Errorf("ClockBackWait waited %v, want 1s", waited)
+	}
+	if tick := df.Decompose(id)["time"]; tick != df.CurrentTick() {
+		t.Errorf("ID issued at tick %d, clock at %d", tick, df.CurrentTick())
+	}
+}
@@ -0,0 +1,76 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClockCheck is returned by NextID while the latest periodic run of
+// Settings.CheckClock failed, see Settings.CheckClockInterval.
+var ErrClockCheck = errors.New("clock check failed")
+
+// clockChecker runs Settings.CheckClock periodically. It does not refer to
+// its dxyflake, so that dropped dxyflakes are still garbage collected and
+// stop it.
+type clockChecker struct {
+	err  atomic.Value // clockError
+	stop chan struct{}
+	once sync.Once
+}
+
+type clockError struct {
+	err error
+}
+
+// startClockChecker runs check every interval until stopped. Failures are
+// passed to alert; without alert, they are returned by failed until a
+// check passes.
+func startClockChecker(check func() error, interval time.Duration, alert func(error)) *clockChecker {
+	c := &clockChecker{stop: make(chan struct{})}
+	c.err.Store(clockError{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+			}
+			err := check()
+			if err != nil && alert != nil {
+				alert(err)
+				err = nil
+			}
+			c.err.Store(clockError{err})
+		}
+	}()
+	return c
+}
+
+// failed returns the error of the latest check, nil if it passed.
+func (c *clockChecker) failed() error {
+	if c == nil {
+		return nil
+	}
+	if err := c.err.Load().(clockError).err; err != nil {
+		return fmt.Errorf("%w: %v", ErrClockCheck, err)
+	}
+	return nil
+}
+
+func (c *clockChecker) close() {
+	if c != nil {
+		c.once.Do(func() { close(c.stop) })
+	}
+}
+
+// release drops df from the live dxyflakes and stops its clock checks. It
+// is called by Drain and Close, or by the finalizer of df.
+func (df *dxyflake) release() {
+	df.unregister()
+	df.checker.close()
+}
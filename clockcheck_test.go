@@ -0,0 +1,81 @@
+package dxyflake
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errSkew = errors.New("skew")
+
+func TestCheckClockInterval(t *testing.T) {
+	var failing int32
+	var st Settings
+	st.Init(1, 2)
+	st.StartTime = time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	st.CheckClock = func() error {
+		if atomic.LoadInt32(&failing) != 0 {
+			return errSkew
+		}
+		return nil
+	}
+	st.CheckClockInterval = time.Millisecond
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Drain()
+
+	// waitFor calls NextID until its error matches want
+	waitFor := func(want error) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, err := df.NextID()
+			if (want == nil && err == nil) || (want != nil && errors.Is(err, want)) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("NextID() = %v, want %v", err, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	atomic.StoreInt32(&failing, 1)
+	waitFor(ErrClockCheck)
+	if _, err := df.NextID(); !errors.Is(err, ErrClockCheck) || !strings.Contains(err.Error(), errSkew.Error()) {
+		t.Errorf("NextID() = %v", err)
+	}
+	atomic.StoreInt32(&failing, 0)
+	waitFor(nil)
+
+	// with an alert, failures are reported and IDs keep coming
+	alerts := make(chan error, 1)
+	st.StartTime = st.StartTime.Add(time.Hour)
+	st.ClockAlert = func(err error) {
+		select {
+		case alerts <- err:
+		default:
+		}
+	}
+	alerted, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&failing, 1)
+	select {
+	case err := <-alerts:
+		if !errors.Is(err, errSkew) {
+			t.Errorf("alert %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert")
+	}
+	if _, err := alerted.NextID(); err != nil {
+		t.Errorf("NextID() with an alert: %v", err)
+	}
+	alerted.Drain()
+	atomic.StoreInt32(&failing, 0)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/analyze"
+)
+
+func runAnalyze(args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	epochFlag := fs.String("epoch", dxyflake.DefaultStartTime.Format(time.RFC3339), "start time of the generators, an epoch name or RFC 3339")
+	encoding := fs.String("encoding", "", "encoding of the IDs, detected per line if empty")
+	bucket := fs.Duration("bucket", analyze.DefaultBucket, "width of the rate buckets")
+	gap := fs.Duration("gap", 0, "shortest pause reported as a gap, the bucket width if 0")
+	out := fs.String("o", "", "write the JSON report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake analyze [flags] <file|->")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	epoch, err := dxyflake.ParseEpoch(*epochFlag)
+	if err != nil {
+		return err
+	}
+	if *encoding != "" {
+		known := false
+		for _, e := range analyze.Encodings {
+			known = known || e.Name == *encoding
+		}
+		if !known {
+			return fmt.Errorf("unknown encoding %q", *encoding)
+		}
+	}
+
+	var src io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+
+	a := analyze.New(analyze.Options{
+		Epoch:        epoch,
+		Encoding:     *encoding,
+		Bucket:       *bucket,
+		GapThreshold: *gap,
+	})
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			a.AddString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	report := a.Report()
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if *out != "" {
+		err = os.WriteFile(*out, b, 0644)
+	} else {
+		_, err = os.Stdout.Write(b)
+	}
+	if err != nil {
+		return err
+	}
+	if report.Duplicates > 0 {
+		return errors.New("duplicate IDs found")
+	}
+	return nil
+}
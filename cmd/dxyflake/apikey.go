@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// apikeyReport is the output of the apikey command. Key goes to the client,
+// Hash to the api-keys of the configuration file.
+type apikeyReport struct {
+	Name string `json:"name,omitempty"`
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+func runAPIKey(args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the client the key is for")
+	hash := fs.String("hash", "", "print the hash of this existing key instead of creating one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report := apikeyReport{Name: *name, Key: *hash, Hash: server.HashAPIKey(*hash)}
+	if *hash == "" {
+		var err error
+		if report.Key, report.Hash, err = server.NewAPIKey(); err != nil {
+			return err
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/GiterLab/dxyflake/analyze"
+	"github.com/GiterLab/dxyflake/audit"
+)
+
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	encoding := fs.String("encoding", "decimal", "encoding of the IDs in the exports")
+	chunk := fs.Int("chunk", audit.DefaultChunkSize, "number of IDs sorted in memory at once")
+	tmp := fs.String("tmp", "", "directory for sorted runs, the system default if empty")
+	keyPath := fs.String("key", "", "sign the report with this PEM ed25519 private key")
+	verifyPath := fs.String("verify", "", "verify this signed report instead of auditing")
+	pubPath := fs.String("pub", "", "PEM ed25519 public key for -verify")
+	out := fs.String("o", "", "write the JSON report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake audit [flags] <export>...")
+		fmt.Fprintln(fs.Output(), "       dxyflake audit -verify <report> -pub <key>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *verifyPath != "" {
+		return verifyAudit(*verifyPath, *pubPath)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	opts := audit.Options{TempDir: *tmp, ChunkSize: *chunk}
+	for _, e := range analyze.Encodings {
+		if e.Name == *encoding {
+			opts.Parse = e.Parse
+		}
+	}
+	if opts.Parse == nil {
+		return fmt.Errorf("unknown encoding %q", *encoding)
+	}
+
+	a := audit.New(opts)
+	for _, path := range fs.Args() {
+		if err := a.AddFile(path); err != nil {
+			a.Finish()
+			return err
+		}
+	}
+	report, err := a.Finish()
+	if err != nil {
+		return err
+	}
+
+	var v interface{} = report
+	if *keyPath != "" {
+		key, err := audit.LoadPrivateKey(*keyPath)
+		if err != nil {
+			return err
+		}
+		if v, err = audit.Sign(report, key); err != nil {
+			return err
+		}
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if *out != "" {
+		err = os.WriteFile(*out, b, 0644)
+	} else {
+		_, err = os.Stdout.Write(b)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !report.Passed {
+		return errors.New("invariants violated")
+	}
+	return nil
+}
+
+func verifyAudit(path, pubPath string) error {
+	if pubPath == "" {
+		return errors.New("-pub is required with -verify")
+	}
+	pub, err := audit.LoadPublicKey(pubPath)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var s audit.Signed
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	report, err := s.Verify(pub)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("signature OK: %d IDs in %d exports, passed: %v\n", report.Total, len(report.Exports), report.Passed)
+	return nil
+}
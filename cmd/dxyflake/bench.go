@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/GiterLab/dxyflake/bench"
+)
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	cases := fs.String("cases", "", "comma separated cases to run, all if empty")
+	duration := fs.Duration("duration", bench.DefaultDuration, "how long each case runs")
+	parallelism := fs.Int("parallelism", 0, "goroutines of parallel cases, GOMAXPROCS if 0")
+	baseline := fs.String("baseline", "", "fail if throughput regressed against this report")
+	threshold := fs.Float64("threshold", bench.DefaultThreshold, "regression threshold, a fraction of the baseline throughput")
+	out := fs.String("o", "", "write the JSON report to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := bench.Config{Duration: *duration, Parallelism: *parallelism}
+	if *cases != "" {
+		cfg.Cases = strings.Split(*cases, ",")
+	}
+	report, err := bench.Run(cfg)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := bench.WriteReport(w, report); err != nil {
+		return err
+	}
+
+	if *baseline == "" {
+		return nil
+	}
+	f, err := os.Open(*baseline)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	base, err := bench.ReadReport(f)
+	if err != nil {
+		return err
+	}
+	return report.Check(base, *threshold)
+}
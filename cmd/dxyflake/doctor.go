@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/clock"
+	"github.com/GiterLab/dxyflake/config"
+)
+
+// check is the outcome of one doctor check.
+type check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+type doctorReport struct {
+	Config    string  `json:"config,omitempty"`
+	MachineID *uint16 `json:"machine-id,omitempty"`
+	ServiceID *uint16 `json:"service-id,omitempty"`
+	Namespace string  `json:"namespace,omitempty"`
+	Epoch     string  `json:"epoch,omitempty"`
+	Checks    []check `json:"checks"`
+	Passed    bool    `json:"passed"`
+}
+
+func (r *doctorReport) add(name string, err error, detail string) {
+	c := check{Name: name, OK: err == nil, Detail: detail}
+	if err != nil {
+		c.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	configPath := fs.String("config", "", "configuration file; only the environment is used if empty")
+	ntpServer := fs.String("ntp", "pool.ntp.org", "NTP server to compare the system clock with, none if empty")
+	maxOffset := fs.Duration("max-offset", 100*time.Millisecond, "largest acceptable offset of the system clock")
+	timeout := fs.Duration("timeout", 3*time.Second, "timeout of the NTP query")
+	jsonOut := fs.Bool("json", false, "write the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake doctor [flags]")
+		fmt.Fprintln(fs.Output(), "Checks a deployment without issuing any IDs.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	r := doctor(*configPath, *ntpServer, *maxOffset, *timeout)
+	if *jsonOut {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(append(b, '\n'))
+	} else {
+		printDoctorReport(os.Stdout, r)
+	}
+	if !r.Passed {
+		return errors.New("checks failed")
+	}
+	return nil
+}
+
+func doctor(configPath, ntpServer string, maxOffset, timeout time.Duration) *doctorReport {
+	r := &doctorReport{Config: configPath}
+	defer func() {
+		r.Passed = true
+		for _, c := range r.Checks {
+			r.Passed = r.Passed && c.OK
+		}
+	}()
+
+	f, err := config.Read(configPath)
+	r.add("config", err, "read "+displayPath(configPath))
+	if err != nil {
+		return r
+	}
+	st, err := f.Settings()
+	r.add("settings", err, "valid")
+	if err != nil {
+		return r
+	}
+
+	epoch := st.StartTime
+	if epoch.IsZero() {
+		epoch = dxyflake.DefaultStartTime
+	}
+	r.Epoch = epoch.Format(time.RFC3339)
+	if name := dxyflake.EpochName(epoch); name != "" {
+		r.Epoch += " (" + name + ")"
+	}
+	now := time.Now()
+	if epoch.After(now) {
+		r.add("epoch", fmt.Errorf("epoch %s is in the future", r.Epoch), "")
+	} else {
+		limit := dxyflake.DefaultLayout
+		limit.Epoch = epoch
+		end := limit.TickTime(1<<dxyflake.BitLenTime - 1)
+		r.add("epoch", nil, fmt.Sprintf("%s, IDs run out in %d", r.Epoch, end.Year()))
+	}
+
+	machineID, err := provide(st.MachineID, st.CheckMachineID, dxyflake.BitLenMachineID)
+	r.add("machine-id", err, fmt.Sprintf("would be %d", machineID))
+	if err == nil {
+		r.MachineID = &machineID
+	}
+	serviceID, err := provide(st.ServiceID, st.CheckServiceID, dxyflake.BitLenServiceID)
+	r.add("service-id", err, fmt.Sprintf("would be %d", serviceID))
+	if err == nil {
+		r.ServiceID = &serviceID
+		r.Namespace = st.ServiceNames.Name(serviceID)
+	}
+
+	if ntpServer != "" {
+		res, err := clock.QueryNTP(ntpServer, timeout)
+		if err == nil {
+			if abs(res.Offset) > maxOffset {
+				err = fmt.Errorf("system clock is %v off %s, more than %v", res.Offset, res.Server, maxOffset)
+			}
+		}
+		r.add("clock", err, fmt.Sprintf("%v off %s (round trip %v)", res.Offset, res.Server, res.RTT))
+	}
+	return r
+}
+
+// provide runs an ID provider and validates its ID like NewDxyflake does.
+func provide(provider func() (uint16, error), valid func(uint16) bool, bits uint) (uint16, error) {
+	if provider == nil {
+		return 0, nil
+	}
+	id, err := provider()
+	if err != nil {
+		return 0, err
+	}
+	if id >= 1<<bits {
+		return id, fmt.Errorf("%d does not fit in %d bits", id, bits)
+	}
+	if valid != nil && !valid(id) {
+		return id, fmt.Errorf("%d is not allowed by the policy", id)
+	}
+	return id, nil
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "environment"
+	}
+	return path
+}
+
+func printDoctorReport(w io.Writer, r *doctorReport) {
+	for _, c := range r.Checks {
+		status := "ok"
+		if !c.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%-4s  %-10s  %s\n", status, c.Name, c.Detail)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/analyze"
+	"github.com/GiterLab/dxyflake/config"
+)
+
+func runExplain(args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	configPath := fs.String("config", "", "configuration file for the epoch and the tenant names of service IDs")
+	epochFlag := fs.String("epoch", "", "start time of the generators, an epoch name or RFC 3339; overrides -config")
+	encoding := fs.String("encoding", "", "encoding of the IDs, detected per ID if empty")
+	jsonOut := fs.Bool("json", false, "write the parts of each ID as a JSON line, see dxyflake.PartsJSONSchema")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake explain [flags] [id...]")
+		fmt.Fprintln(fs.Output(), "Without arguments, IDs are read from stdin, one per line.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	layout := dxyflake.DefaultLayout
+	var names dxyflake.ServiceNames
+	if *configPath != "" {
+		st, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		if !st.StartTime.IsZero() {
+			layout.Epoch = st.StartTime
+		}
+		names = st.ServiceNames
+	}
+	if *epochFlag != "" {
+		epoch, err := dxyflake.ParseEpoch(*epochFlag)
+		if err != nil {
+			return err
+		}
+		layout.Epoch = epoch
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	explain := func(s string) error {
+		id, _, err := analyze.Parse(s, *encoding, layout.Epoch)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s, err)
+		}
+		if *jsonOut {
+			return enc.Encode(layout.DecomposeParts(id))
+		}
+		fmt.Println(layout.DebugString(id, names))
+		return nil
+	}
+
+	return eachArg(fs, explain)
+}
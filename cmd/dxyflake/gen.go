@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// genField formats one field of a generated row.
+type genField func(id dxyflake.ID, epoch time.Time) interface{}
+
+var genFields = map[string]genField{
+	"id":          func(id dxyflake.ID, _ time.Time) interface{} { return id },
+	"int64":       func(id dxyflake.ID, _ time.Time) interface{} { return id.Int64() },
+	"base2":       func(id dxyflake.ID, _ time.Time) interface{} { return id.Base2() },
+	"base32":      func(id dxyflake.ID, _ time.Time) interface{} { return id.Base32() },
+	"crockford32": func(id dxyflake.ID, _ time.Time) interface{} { return id.Crockford32() },
+	"base36":      func(id dxyflake.ID, _ time.Time) interface{} { return id.Base36() },
+	"base58":      func(id dxyflake.ID, _ time.Time) interface{} { return id.Base58() },
+	"base62":      func(id dxyflake.ID, _ time.Time) interface{} { return id.Base62() },
+	"base64":      func(id dxyflake.ID, _ time.Time) interface{} { return id.Base64() },
+	"sortable":    func(id dxyflake.ID, _ time.Time) interface{} { return id.SortableString() },
+	"time":        genTime,
+	"machine-id":  genPart("machine-id"),
+	"service-id":  genPart("service-id"),
+	"sequence":    genPart("sequence"),
+}
+
+func genTime(id dxyflake.ID, epoch time.Time) interface{} {
+	ticks := epoch.UnixNano()/1e7 + dxyflake.Decompose(id)["time"]
+	return time.Unix(ticks/100, ticks%100*1e7).UTC().Format(time.RFC3339Nano)
+}
+
+func genPart(name string) genField {
+	return func(id dxyflake.ID, _ time.Time) interface{} {
+		return dxyflake.Decompose(id)[name]
+	}
+}
+
+func genFieldNames() string {
+	names := make([]string, 0, len(genFields))
+	for name := range genFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// genWriter writes rows in one output format.
+type genWriter interface {
+	Write(values []interface{}) error
+	Flush() error
+}
+
+type jsonlWriter struct {
+	w     *bufio.Writer
+	names []string
+	line  []byte
+}
+
+func (j *jsonlWriter) Write(values []interface{}) error {
+	j.line = append(j.line[:0], '{')
+	for i, v := range values {
+		if i > 0 {
+			j.line = append(j.line, ',')
+		}
+		j.line = strconv.AppendQuote(j.line, j.names[i])
+		j.line = append(j.line, ':')
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		j.line = append(j.line, b...)
+	}
+	j.line = append(j.line, '}', '\n')
+	_, err := j.w.Write(j.line)
+	return err
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvWriter) Write(values []interface{}) error {
+	c.record = c.record[:0]
+	for _, v := range values {
+		c.record = append(c.record, fmt.Sprint(v))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ContinueOnError)
+	count := fs.Int("count", 10, "number of IDs to generate")
+	format := fs.String("format", "jsonl", "output format, jsonl or csv")
+	fieldsFlag := fs.String("fields", "id", "comma separated fields: "+genFieldNames())
+	machineID := fs.Uint("machine-id", 0, "machine ID of the generator")
+	serviceID := fs.Uint("service-id", 0, "service ID of the generator")
+	epochFlag := fs.String("epoch", dxyflake.DefaultStartTime.Format(time.RFC3339), "start time of the generator, an epoch name or RFC 3339")
+	allowEpochChange := fs.Bool("allow-epoch-change", false, "confirm an epoch other than "+dxyflake.EpochDefault)
+	out := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *count < 0 {
+		return errors.New("count must not be negative")
+	}
+	epoch, err := dxyflake.ParseEpoch(*epochFlag)
+	if err != nil {
+		return err
+	}
+	if !epoch.Equal(dxyflake.DefaultStartTime) && !*allowEpochChange {
+		return fmt.Errorf("epoch %s differs from %s, confirm with -allow-epoch-change", *epochFlag, dxyflake.EpochDefault)
+	}
+	if *machineID >= 1<<dxyflake.BitLenMachineID || *serviceID >= 1<<dxyflake.BitLenServiceID {
+		return errors.New("machine-id or service-id out of range")
+	}
+
+	names := strings.Split(*fieldsFlag, ",")
+	fields := make([]genField, len(names))
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		f, ok := genFields[names[i]]
+		if !ok {
+			return fmt.Errorf("unknown field %q, want one of %s", names[i], genFieldNames())
+		}
+		fields[i] = f
+	}
+
+	var st dxyflake.Settings
+	st.Init(uint16(*machineID), uint16(*serviceID))
+	st.StartTime = epoch
+	gen, err := dxyflake.NewDxyflakeWithError(st)
+	if err != nil {
+		return fmt.Errorf("cannot create generator: %w", err)
+	}
+
+	var dst io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+
+	// Rows are generated and written by the same goroutine, so a slow
+	// consumer blocks generation instead of buffering rows in memory.
+	var w genWriter
+	switch *format {
+	case "jsonl":
+		w = &jsonlWriter{w: bufio.NewWriterSize(dst, 64<<10), names: names}
+	case "csv":
+		c := &csvWriter{w: csv.NewWriter(dst)}
+		if err := c.w.Write(names); err != nil {
+			return err
+		}
+		w = c
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	values := make([]interface{}, len(fields))
+	for n := 0; n < *count; n++ {
+		id, err := gen.NextID()
+		if err != nil {
+			return err
+		}
+		for i, f := range fields {
+			values[i] = f(id, epoch)
+		}
+		if err := w.Write(values); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
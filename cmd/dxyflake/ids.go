@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/analyze"
+)
+
+// encodingNames lists the names of analyze.Encodings.
+func encodingNames() string {
+	names := make([]string, len(analyze.Encodings))
+	for i, e := range analyze.Encodings {
+		names[i] = e.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// encodingByName returns the encoding of analyze.Encodings named name.
+func encodingByName(name string) (analyze.Encoding, error) {
+	for _, e := range analyze.Encodings {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+	return analyze.Encoding{}, fmt.Errorf("unknown encoding %q, want one of %s", name, encodingNames())
+}
+
+// eachArg calls f with every argument of fs, or with every non-empty line
+// of stdin if there are none.
+func eachArg(fs *flag.FlagSet, f func(s string) error) error {
+	if fs.NArg() > 0 {
+		for _, s := range fs.Args() {
+			if err := f(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if s := strings.TrimSpace(scanner.Text()); s != "" {
+			if err := f(s); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func runNew(args []string) error {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	machineID := fs.Uint("m", 0, "machine ID of the generator, its node ID with -region-bits")
+	serviceID := fs.Uint("s", 0, "service ID of the generator")
+	regionID := fs.Uint("r", 0, "region ID of the generator, with -region-bits")
+	regionBits := fs.Int("region-bits", 0, "bit length of the region ID within the machine ID")
+	count := fs.Int("n", 1, "number of IDs to generate")
+	encoding := fs.String("encoding", "decimal", "encoding of the IDs: "+encodingNames())
+	epochFlag := fs.String("epoch", dxyflake.EpochDefault, "start time of the generator, an epoch name or RFC 3339")
+	allowEpochChange := fs.Bool("allow-epoch-change", false, "confirm an epoch other than "+dxyflake.EpochDefault)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *count < 0 {
+		return errors.New("count must not be negative")
+	}
+	enc, err := encodingByName(*encoding)
+	if err != nil {
+		return err
+	}
+	epoch, err := dxyflake.ParseEpoch(*epochFlag)
+	if err != nil {
+		return err
+	}
+	if !epoch.Equal(dxyflake.DefaultStartTime) && !*allowEpochChange {
+		return fmt.Errorf("epoch %s differs from %s, confirm with -allow-epoch-change", *epochFlag, dxyflake.EpochDefault)
+	}
+	if *machineID >= 1<<dxyflake.BitLenMachineID || *serviceID >= 1<<dxyflake.BitLenServiceID ||
+		*regionID >= 1<<dxyflake.BitLenMachineID {
+		return errors.New("machine ID, service ID or region ID out of range")
+	}
+	var st dxyflake.Settings
+	st.InitRegion(uint16(*regionID), uint16(*machineID), uint16(*serviceID))
+	st.BitsRegion = *regionBits
+	st.StartTime = epoch
+	gen, err := dxyflake.NewDxyflakeWithError(st)
+	if err != nil {
+		return fmt.Errorf("cannot create generator: %w", err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for n := 0; n < *count; n++ {
+		id, err := gen.NextID()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, enc.Format(id))
+	}
+	return w.Flush()
+}
+
+func runDecompose(args []string) error {
+	fs := flag.NewFlagSet("decompose", flag.ContinueOnError)
+	encoding := fs.String("encoding", "", "encoding of the IDs, detected per ID if empty")
+	epochFlag := fs.String("epoch", dxyflake.EpochDefault, "start time of the generators, an epoch name or RFC 3339")
+	regionBits := fs.Int("region-bits", 0, "bit length of the region ID within the machine ID, to show regions and nodes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake decompose [flags] [id...]")
+		fmt.Fprintln(fs.Output(), "Without arguments, IDs are read from stdin, one per line.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	layout := dxyflake.DefaultLayout
+	epoch, err := dxyflake.ParseEpoch(*epochFlag)
+	if err != nil {
+		return err
+	}
+	layout.Epoch = epoch
+	layout.BitLenRegionID = *regionBits
+	if err := layout.Validate(); err != nil {
+		return fmt.Errorf("region-bits: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *regionBits > 0 {
+		fmt.Fprintln(w, "ID\tTIME\tMACHINE\tREGION\tNODE\tSERVICE\tSEQUENCE")
+	} else {
+		fmt.Fprintln(w, "ID\tTIME\tMACHINE\tSERVICE\tSEQUENCE")
+	}
+	err = eachArg(fs, func(s string) error {
+		id, _, err := analyze.ParseLayout(s, *encoding, layout)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s, err)
+		}
+		p := layout.DecomposeParts(id)
+		if *regionBits > 0 {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\n", p.ID, p.Timestamp.UTC().Format(time.RFC3339Nano),
+				p.MachineID, p.RegionID, p.NodeID, p.ServiceID, p.Sequence)
+			return nil
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\n", p.ID, p.Timestamp.UTC().Format(time.RFC3339Nano),
+			p.MachineID, p.ServiceID, p.Sequence)
+		return nil
+	})
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	from := fs.String("from", "", "encoding of the IDs, detected per ID if empty")
+	to := fs.String("to", "decimal", "encoding to convert to: "+encodingNames())
+	epochFlag := fs.String("epoch", dxyflake.EpochDefault, "start time of the generators, to detect encodings")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake convert [flags] [id...]")
+		fmt.Fprintln(fs.Output(), "Without arguments, IDs are read from stdin, one per line.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	enc, err := encodingByName(*to)
+	if err != nil {
+		return err
+	}
+	if *from != "" {
+		if _, err := encodingByName(*from); err != nil {
+			return err
+		}
+	}
+	epoch, err := dxyflake.ParseEpoch(*epochFlag)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = eachArg(fs, func(s string) error {
+		id, _, err := analyze.Parse(s, *from, epoch)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s, err)
+		}
+		fmt.Fprintln(w, enc.Format(id))
+		return nil
+	})
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
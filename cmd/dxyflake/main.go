@@ -0,0 +1,88 @@
+// Command dxyflake is a toolbox for working with dxyflake IDs.
+//
+// Usage:
+//
+//	dxyflake <command> [arguments]
+//
+// The commands are:
+//
+//	new                  generate IDs
+//	decompose            print the time, machine ID, service ID and sequence of IDs
+//	convert              convert IDs between encodings
+//	simulate collisions  estimate machine ID collision probabilities
+//	soak                 run generators under load and verify their invariants
+//	routes               build a routing table from a fleet description
+//	gen                  generate IDs in bulk as JSON lines or CSV
+//	analyze              report issuance rate, distribution, gaps and duplicates of ID dumps
+//	audit                verify uniqueness and monotonicity of per-node exports
+//	explain              describe IDs, naming their service IDs
+//	doctor               check configuration, ID providers and clock without issuing IDs
+//	reepoch              rewrite stored IDs to a later epoch
+//	apikey               create an API key for the ID server and its hash
+//	sql                  print SQL expressions decomposing an ID column
+//	bench                run the benchmark suite and check it against a baseline
+//	version              print the version and build information
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []*command{
+	{"new", "generate IDs", runNew},
+	{"decompose", "print the time, machine ID, service ID and sequence of IDs", runDecompose},
+	{"convert", "convert IDs between encodings", runConvert},
+	{"simulate", "simulate deployments (collisions)", runSimulate},
+	{"soak", "run generators under load and verify their invariants", runSoak},
+	{"routes", "build a routing table from a fleet description", runRoutes},
+	{"gen", "generate IDs in bulk as JSON lines or CSV", runGen},
+	{"analyze", "report issuance rate, distribution, gaps and duplicates of ID dumps", runAnalyze},
+	{"audit", "verify uniqueness and monotonicity of per-node exports", runAudit},
+	{"explain", "describe IDs, naming their service IDs", runExplain},
+	{"doctor", "check configuration, ID providers and clock without issuing IDs", runDoctor},
+	{"reepoch", "rewrite stored IDs to a later epoch", runReepoch},
+	{"apikey", "create an API key for the ID server and its hash", runAPIKey},
+	{"sql", "print SQL expressions decomposing an ID column", runSQL},
+	{"bench", "run the benchmark suite and check it against a baseline", runBench},
+	{"version", "print the version and build information", runVersion},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: dxyflake <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "The commands are:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintln(os.Stderr, "dxyflake "+c.name+":", err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "dxyflake: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
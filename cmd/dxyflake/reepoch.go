@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/reepoch"
+)
+
+func runReepoch(args []string) error {
+	fs := flag.NewFlagSet("reepoch", flag.ContinueOnError)
+	fromFlag := fs.String("from", dxyflake.EpochDefault, "epoch of the stored IDs, an epoch name or RFC 3339")
+	toFlag := fs.String("to", "", "new epoch, an epoch name or RFC 3339")
+	mappingPath := fs.String("mapping", "", "write fresh IDs for IDs before the new epoch to this CSV file; fail on them if empty")
+	machineID := fs.Uint("machine-id", 0, "machine ID issuing the fresh IDs")
+	serviceID := fs.Uint("service-id", 0, "service ID issuing the fresh IDs")
+	out := fs.String("o", "", "write the IDs to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake reepoch -to <epoch> [flags] <file|->")
+		fmt.Fprintln(fs.Output(), "Rewrites decimal IDs, one per line, to a later epoch and writes a JSON report to stderr.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *toFlag == "" {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	from, err := dxyflake.ParseEpoch(*fromFlag)
+	if err != nil {
+		return err
+	}
+	to, err := dxyflake.ParseEpoch(*toFlag)
+	if err != nil {
+		return err
+	}
+	if *machineID >= 1<<dxyflake.BitLenMachineID || *serviceID >= 1<<dxyflake.BitLenServiceID {
+		return errors.New("machine-id or service-id out of range")
+	}
+
+	var gen dxyflake.Generator
+	mapping := io.Discard
+	if *mappingPath != "" {
+		var st dxyflake.Settings
+		st.Init(uint16(*machineID), uint16(*serviceID))
+		st.StartTime = to
+		df := dxyflake.NewDxyflake(st)
+		if df == nil {
+			return errors.New("cannot create generator for the new epoch")
+		}
+		gen = df
+		f, err := os.Create(*mappingPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		mapping = f
+	}
+	r, err := reepoch.New(from, to, gen)
+	if err != nil {
+		return err
+	}
+
+	var src io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		src = f
+	}
+	var dst io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dst = f
+	}
+
+	rep, err := r.Run(src, dst, mapping)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	os.Stderr.Write(append(b, '\n'))
+	return nil
+}
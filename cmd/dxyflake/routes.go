@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/GiterLab/dxyflake/routing"
+	"gopkg.in/yaml.v3"
+)
+
+func runRoutes(args []string) error {
+	fs := flag.NewFlagSet("routes", flag.ContinueOnError)
+	fleetPath := fs.String("fleet", "", "fleet description, YAML or JSON")
+	out := fs.String("o", "", "write the routing table to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *fleetPath == "" {
+		return errors.New("-fleet is required")
+	}
+
+	b, err := os.ReadFile(*fleetPath)
+	if err != nil {
+		return err
+	}
+	var fleet routing.Fleet
+	if err := yaml.Unmarshal(b, &fleet); err != nil {
+		return err
+	}
+
+	table, err := routing.Build(fleet)
+	if err != nil {
+		return err
+	}
+	b, err = table.JSON()
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if *out != "" {
+		return os.WriteFile(*out, b, 0644)
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
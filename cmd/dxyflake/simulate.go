@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/simulate"
+)
+
+func runSimulate(args []string) error {
+	if len(args) < 1 || args[0] != "collisions" {
+		return errors.New("usage: dxyflake simulate collisions [flags]")
+	}
+	return runSimulateCollisions(args[1:])
+}
+
+func runSimulateCollisions(args []string) error {
+	fs := flag.NewFlagSet("simulate collisions", flag.ContinueOnError)
+	nodes := fs.Int("nodes", 8, "number of nodes sharing the machine ID space")
+	trials := fs.Int("trials", 10000, "number of simulated deployments")
+	bits := fs.Uint("bits", dxyflake.BitLenMachineID, "bit length of the machine ID")
+	subnet := fs.Int("subnet", simulate.DefaultSubnetSize, "addresses in the subnet for IP based strategies")
+	seed := fs.Int64("seed", 1, "random seed")
+	strategy := fs.String("strategy", "all", "machine ID assignment strategy, or all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	names := []string{*strategy}
+	if *strategy == "all" {
+		names = simulate.StrategyNames()
+	}
+
+	c := simulate.Config{
+		Nodes:      *nodes,
+		Trials:     *trials,
+		Bits:       *bits,
+		SubnetSize: *subnet,
+		Seed:       *seed,
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tNODES\tTRIALS\tP(COLLISION)\tMEAN COLLIDING NODES")
+	for _, name := range names {
+		s, err := simulate.StrategyByName(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		res, err := simulate.Collisions(s, c)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.4f\t%.2f\n", name, res.Nodes, res.Trials, res.Probability, res.MeanColliding)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nbirthday bound for %d random IDs in %d: %.4f\n",
+		*nodes, 1<<*bits, simulate.BirthdayProbability(*nodes, 1<<*bits))
+	return nil
+}
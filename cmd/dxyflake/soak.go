@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/verify"
+)
+
+// maxSamples is the number of violating IDs kept in the soak report.
+const maxSamples = 20
+
+type soakViolation struct {
+	ID    dxyflake.ID `json:"id"`
+	Error string      `json:"error"`
+}
+
+type soakReport struct {
+	Start        time.Time       `json:"start"`
+	End          time.Time       `json:"end"`
+	Duration     string          `json:"duration"`
+	Nodes        int             `json:"nodes"`
+	TargetRate   float64         `json:"target-rate"`
+	AchievedRate float64         `json:"achieved-rate"`
+	Generated    uint64          `json:"generated"`
+	Errors       uint64          `json:"errors"`
+	Stats        verify.Stats    `json:"stats"`
+	Violations   []soakViolation `json:"violations,omitempty"`
+	Passed       bool            `json:"passed"`
+}
+
+// parseRate parses a rate like 100, 100k or 1.5m IDs per second.
+func parseRate(s string) (float64, error) {
+	mul := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mul, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		mul, s = 1e6, strings.TrimSuffix(s, "m")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return f * mul, nil
+}
+
+func runSoak(args []string) error {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	duration := fs.Duration("duration", time.Minute, "how long to run")
+	rateFlag := fs.String("rate", "100k", "target IDs per second over all nodes, 0 for unlimited")
+	nodes := fs.Int("nodes", 1, "number of generators, each with its own machine ID and service ID")
+	interval := fs.Duration("interval", 10*time.Second, "progress report interval")
+	window := fs.Duration("window", time.Minute, "how long IDs are kept for the uniqueness check")
+	reportPath := fs.String("report", "", "write the JSON report to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	rate, err := parseRate(*rateFlag)
+	if err != nil {
+		return err
+	}
+	maxNodes := 1 << (dxyflake.BitLenMachineID + dxyflake.BitLenServiceID)
+	if *nodes < 1 || *nodes > maxNodes {
+		return fmt.Errorf("nodes must be between 1 and %d", maxNodes)
+	}
+
+	gens := make([]dxyflake.Generator, *nodes)
+	for i := range gens {
+		var st dxyflake.Settings
+		st.Init(uint16(i%(1<<dxyflake.BitLenMachineID)), uint16(i>>dxyflake.BitLenMachineID))
+		df := dxyflake.NewDxyflake(st)
+		if df == nil {
+			return errors.New("dxyflake not created")
+		}
+		gens[i] = df
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	v := verify.NewVerifier(*window)
+	report := soakReport{
+		Start:      time.Now(),
+		Nodes:      *nodes,
+		TargetRate: rate,
+	}
+	var generated, genErrors uint64
+	var mutex sync.Mutex
+
+	var wg sync.WaitGroup
+	for _, gen := range gens {
+		wg.Add(1)
+		go func(gen dxyflake.Generator) {
+			defer wg.Done()
+
+			issue := func() {
+				id, err := gen.NextID()
+				if err != nil {
+					atomic.AddUint64(&genErrors, 1)
+					return
+				}
+				atomic.AddUint64(&generated, 1)
+				if err := v.Add(id); err != nil {
+					mutex.Lock()
+					if len(report.Violations) < maxSamples {
+						report.Violations = append(report.Violations, soakViolation{id, err.Error()})
+					}
+					mutex.Unlock()
+				}
+			}
+
+			if rate == 0 {
+				for ctx.Err() == nil {
+					issue()
+				}
+				return
+			}
+
+			// hand out credit every 10 msec, the generator's time unit
+			const step = 10 * time.Millisecond
+			perStep := rate / float64(len(gens)) * step.Seconds()
+			ticker := time.NewTicker(step)
+			defer ticker.Stop()
+			credit := 0.0
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				for credit += perStep; credit >= 1; credit-- {
+					issue()
+				}
+			}
+		}(gen)
+	}
+
+	progress := time.NewTicker(*interval)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+loop:
+	for {
+		select {
+		case <-done:
+			break loop
+		case <-progress.C:
+			s := v.Stats()
+			elapsed := time.Since(report.Start)
+			fmt.Fprintf(os.Stderr, "%s generated %d (%.0f/s) violations %d errors %d pages %d\n",
+				elapsed.Round(time.Second), s.Total, float64(s.Total)/elapsed.Seconds(),
+				s.Violations(), atomic.LoadUint64(&genErrors), s.Pages)
+		}
+	}
+	progress.Stop()
+
+	report.End = time.Now()
+	elapsed := report.End.Sub(report.Start)
+	report.Duration = elapsed.Round(time.Millisecond).String()
+	report.Generated = atomic.LoadUint64(&generated)
+	report.Errors = atomic.LoadUint64(&genErrors)
+	report.AchievedRate = float64(report.Generated) / elapsed.Seconds()
+	report.Stats = v.Stats()
+	report.Passed = report.Stats.Violations() == 0 && report.Errors == 0
+
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if *reportPath != "" {
+		err = os.WriteFile(*reportPath, b, 0644)
+	} else {
+		_, err = os.Stdout.Write(b)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !report.Passed {
+		return errors.New("invariants violated")
+	}
+	return nil
+}
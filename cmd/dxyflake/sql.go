@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/config"
+	"github.com/GiterLab/dxyflake/sqlexpr"
+)
+
+func runSQL(args []string) error {
+	fs := flag.NewFlagSet("sql", flag.ContinueOnError)
+	dialect := fs.String("dialect", string(sqlexpr.PostgreSQL), "SQL dialect, mysql, postgresql, clickhouse or bigquery")
+	column := fs.String("column", "id", "column holding the IDs")
+	udf := fs.String("udf", "", "print the statements creating functions named after `name` instead, for clickhouse or bigquery")
+	configPath := fs.String("config", "", "configuration file for the epoch and layout")
+	epochFlag := fs.String("epoch", "", "start time of the generators, an epoch name or RFC 3339; overrides -config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	layout := dxyflake.DefaultLayout
+	if *configPath != "" {
+		st, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		if !st.StartTime.IsZero() {
+			layout.Epoch = st.StartTime
+		}
+		if st.BitsTime != 0 {
+			layout.BitLenTime, layout.BitLenMachineID = st.BitsTime, st.BitsMachineID
+			layout.BitLenServiceID, layout.BitLenSequence = st.BitsServiceID, st.BitsSequence
+		}
+	}
+	if *epochFlag != "" {
+		epoch, err := dxyflake.ParseEpoch(*epochFlag)
+		if err != nil {
+			return err
+		}
+		layout.Epoch = epoch
+	}
+
+	if *udf != "" {
+		s, err := sqlexpr.UDFs(sqlexpr.Dialect(*dialect), *udf, layout)
+		if err != nil {
+			return err
+		}
+		fmt.Print(s)
+		return nil
+	}
+
+	e, err := sqlexpr.New(sqlexpr.Dialect(*dialect), *column, layout)
+	if err != nil {
+		return err
+	}
+	fmt.Println(e.Select())
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "write the build information as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: dxyflake version [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	b := dxyflake.Build()
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	}
+	fmt.Println("dxyflake", b.Version)
+	if b.Commit != "" {
+		commit := b.Commit
+		if b.Modified {
+			commit += " (modified)"
+		}
+		fmt.Println("commit", commit, b.CommitTime)
+	}
+	fmt.Println("go", b.GoVersion)
+	return nil
+}
@@ -0,0 +1,120 @@
+// Command dxyflaked is a ready-to-run dxyflake ID server, meant for
+// evaluation and for deployments happy with its defaults:
+//
+//	docker run -p 8080:8080 giterlab/dxyflaked
+//	curl localhost:8080/v1/id
+//
+// It serves the endpoints of package server, Prometheus metrics on
+// /metrics and a health check on /healthz. It is configured by the
+// environment variables of package config and, if -config or
+// DXYFLAKED_CONFIG names one, by a configuration file. Without a machine
+// ID provider, the machine ID is derived from the host name, which is the
+// pod name under Kubernetes; pin it with DXYFLAKE_MACHINE_ID when running
+// more than a few replicas. On SIGTERM, the server drains and exits.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/config"
+	"github.com/GiterLab/dxyflake/metrics"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// These are the defaults of dxyflaked.
+const (
+	DefaultAddr         = ":8080"
+	DefaultReadTimeout  = 5 * time.Second
+	DefaultWriteTimeout = 10 * time.Second
+	DefaultDrainTimeout = 30 * time.Second
+)
+
+// EnvConfig names the configuration file when -config is not given.
+const EnvConfig = "DXYFLAKED_CONFIG"
+
+func main() {
+	configPath := flag.String("config", os.Getenv(EnvConfig), "configuration file; only the environment is used if empty")
+	drainTimeout := flag.Duration("drain-timeout", DefaultDrainTimeout, "longest time to drain on SIGTERM")
+	flag.Parse()
+
+	if err := run(*configPath, *drainTimeout); err != nil {
+		log.Fatal("dxyflaked: ", err)
+	}
+}
+
+func run(configPath string, drainTimeout time.Duration) error {
+	f, err := config.Read(configPath)
+	if err != nil {
+		return err
+	}
+	if f.MachineID.Name == "" {
+		f.MachineID.Name = "hostname"
+	}
+	if f.Server.Addr == "" {
+		f.Server.Addr = DefaultAddr
+	}
+	if f.Server.ReadTimeout.Duration == 0 {
+		f.Server.ReadTimeout.Duration = DefaultReadTimeout
+	}
+	if f.Server.WriteTimeout.Duration == 0 {
+		f.Server.WriteTimeout.Duration = DefaultWriteTimeout
+	}
+
+	st, err := f.Settings()
+	if err != nil {
+		return err
+	}
+	gen, err := dxyflake.NewDxyflakeWithError(st)
+	if err != nil {
+		return err
+	}
+	d := gen.Describe()
+	log.Printf("dxyflaked: version %s, commit %s, machine id %d, service id %d, epoch %s, listening on %s",
+		d.Version, d.Commit, *d.MachineID, *d.ServiceID, d.Epoch, f.Server.Addr)
+
+	s := server.New(gen, server.Options{ServiceNames: st.ServiceNames, MaxBatch: f.Server.MaxBatch})
+	mux := http.NewServeMux()
+	mux.Handle("/", s)
+	mux.Handle("/metrics", metrics.Handler(gen))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	srv := &http.Server{
+		Addr:         f.Server.Addr,
+		Handler:      mux,
+		ReadTimeout:  f.Server.ReadTimeout.Duration,
+		WriteTimeout: f.Server.WriteTimeout.Duration,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	sig, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	select {
+	case err := <-errc:
+		return err
+	case <-sig.Done():
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	marks, err := s.Drain(ctx, server.DrainOptions{})
+	if err != nil {
+		return err
+	}
+	for _, m := range marks {
+		log.Printf("dxyflaked: drained machine id %d, service id %d, next ID at %s", m.MachineID, m.ServiceID, m.Next.Format(time.RFC3339Nano))
+	}
+	return srv.Shutdown(ctx)
+}
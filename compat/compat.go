@@ -0,0 +1,61 @@
+// Package compat keeps the uint64 API of dxyflake for code written against
+// it, so that such code can move to newer releases of the module by
+// changing its import path alone, and to the ID type, NewDxyflakeWithError
+// and Layout at its own pace.
+//
+// IDs are the same as those of package dxyflake: a Dxyflake wraps a
+// dxyflake generator and converts its IDs at the boundary.
+package compat
+
+import "github.com/GiterLab/dxyflake"
+
+// These constants are the bit lengths of dxyflake ID parts.
+const (
+	BitLenTime      = dxyflake.BitLenTime
+	BitLenMachineID = dxyflake.BitLenMachineID
+	BitLenServiceID = dxyflake.BitLenServiceID
+	BitLenSequence  = dxyflake.BitLenSequence
+)
+
+// Settings configures dxyflake, see dxyflake.Settings.
+type Settings = dxyflake.Settings
+
+// Dxyflake is a distributed unique ID generator issuing uint64 IDs.
+type Dxyflake struct {
+	df interface {
+		NextID() (dxyflake.ID, error)
+	}
+}
+
+// NewDxyflake returns a new Dxyflake configured with the given Settings.
+// It returns nil in the cases dxyflake.NewDxyflake does.
+func NewDxyflake(st Settings) *Dxyflake {
+	df, err := dxyflake.NewDxyflakeWithError(st)
+	if err != nil {
+		return nil
+	}
+	return &Dxyflake{df: df}
+}
+
+// NextID generates a next unique ID.
+// After the dxyflake time overflows, NextID returns an error.
+func (df *Dxyflake) NextID() (uint64, error) {
+	id, err := df.df.NextID()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
+
+// Decompose returns a set of dxyflake ID parts.
+func Decompose(id uint64) map[string]uint64 {
+	parts := dxyflake.Decompose(dxyflake.ID(id))
+	m := make(map[string]uint64, len(parts))
+	for k, v := range parts {
+		m[k] = uint64(v)
+	}
+	m["id"] = id
+	m["msb"] = id >> 63
+	m["time"] = id >> (BitLenMachineID + BitLenServiceID + BitLenSequence)
+	return m
+}
@@ -0,0 +1,43 @@
+package compat
+
+import (
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestNextID(t *testing.T) {
+	var st Settings
+	st.Init(3, 4)
+	df := NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := Decompose(id)
+	want := dxyflake.Decompose(dxyflake.ID(id))
+	for k, v := range want {
+		if parts[k] != uint64(v) {
+			t.Errorf("Decompose()[%q] = %d, want %d", k, parts[k], v)
+		}
+	}
+	if parts["machine-id"] != 3 || parts["service-id"] != 4 {
+		t.Errorf("unexpected parts %v", parts)
+	}
+
+	st.Init(1<<BitLenMachineID, 0)
+	st.CheckMachineID = func(id uint16) bool { return id < 1<<BitLenMachineID }
+	if NewDxyflake(st) != nil {
+		t.Error("dxyflake created with an invalid machine ID")
+	}
+}
+
+func TestDecomposeMSB(t *testing.T) {
+	parts := Decompose(1<<63 | 5)
+	if parts["msb"] != 1 || parts["id"] != 1<<63|5 || parts["sequence"] != 5 || parts["time"] != 1<<41 {
+		t.Errorf("unexpected parts %v", parts)
+	}
+}
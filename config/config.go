@@ -0,0 +1,453 @@
+// Package config loads dxyflake Settings from YAML, TOML or JSON files with
+// environment variable overrides.
+//
+// A YAML configuration looks like:
+//
+//	epoch: "2021-10-01T00:00:00Z"
+//	machine-id:
+//	  provider: env
+//	  params:
+//	    name: POD_ORDINAL
+//	service-id:
+//	  provider: static
+//	  params:
+//	    value: 3
+//	policy:
+//	  allow-machine-ids: [0, 1, 2, 3]
+//	  tenants:
+//	    orders: 1
+//	  quotas:
+//	    orders: 10000
+//	server:
+//	  addr: ":8080"
+//	  read-timeout: 5s
+//
+// The epoch is the name of a registered epoch, see dxyflake.ParseEpoch, an
+// RFC 3339 time or a date. Any epoch but dxy-default must be confirmed with
+// "allow-epoch-change: true". An epoch-tolerance such as "50ms" lets a
+// generator start while the epoch is slightly ahead of its clock.
+//
+// The format is chosen by the file extension: .yaml, .yml, .toml or .json.
+// The environment variables listed in the Env constants override the file.
+// The policy section can be reloaded while running, see Reloader.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/GiterLab/dxyflake"
+	"gopkg.in/yaml.v3"
+)
+
+// These environment variables override the configuration file.
+const (
+	EnvEpoch             = "DXYFLAKE_EPOCH"               // start time, epoch name, RFC 3339 or YYYY-MM-DD
+	EnvAllowEpochChange  = "DXYFLAKE_ALLOW_EPOCH_CHANGE"  // "true" to allow an epoch other than dxy-default
+	EnvMachineID         = "DXYFLAKE_MACHINE_ID"          // static machine ID
+	EnvMachineIDProvider = "DXYFLAKE_MACHINE_ID_PROVIDER" // machine ID provider name
+	EnvServiceID         = "DXYFLAKE_SERVICE_ID"          // static service ID
+	EnvServiceIDProvider = "DXYFLAKE_SERVICE_ID_PROVIDER" // service ID provider name
+	EnvAllowMachineIDs   = "DXYFLAKE_ALLOW_MACHINE_IDS"   // comma separated allowlist
+	EnvAllowServiceIDs   = "DXYFLAKE_ALLOW_SERVICE_IDS"   // comma separated allowlist
+	EnvServerAddr        = "DXYFLAKE_SERVER_ADDR"         // server listen address
+)
+
+// ErrUnknownFormat is returned by Read for unsupported file extensions.
+var ErrUnknownFormat = errors.New("unknown configuration format")
+
+// ErrUnsupportedLayout is returned when the configured layout cannot be
+// used by dxyflake.Settings, see its Bits fields.
+var ErrUnsupportedLayout = errors.New("unsupported layout")
+
+// ErrUnknownEnvironment is returned by File.Settings for environments other
+// than "", "production" and "test".
+var ErrUnknownEnvironment = errors.New("unknown environment")
+
+// ErrUnknownClockBack is returned by File.Settings for clock back policies
+// other than "", "continue", "wait" and "error".
+var ErrUnknownClockBack = errors.New("unknown clock back policy")
+
+// ErrUnknownExhaustion is returned by File.Settings for exhaustion policies
+// other than "", "sleep", "spin", "error" and "borrow".
+var ErrUnknownExhaustion = errors.New("unknown exhaustion policy")
+
+// ErrUnknownRateLimit is returned by File.Settings for rate limit policies
+// other than "", "wait" and "error".
+var ErrUnknownRateLimit = errors.New("unknown rate limit policy")
+
+// ErrEpochChange is returned by File.Settings for an epoch other than the
+// default one unless the change is allowed explicitly. A wrong epoch shifts
+// the timestamp of every ID, so it must never be picked up by accident.
+var ErrEpochChange = errors.New("epoch differs from the default, set allow-epoch-change to confirm")
+
+// Duration is a time.Duration written as a string such as "5s".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a duration string.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText returns the duration string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// Layout is the bit layout of IDs. The bit lengths must sum to 63 bits, or
+// to dxyflake.JSSafeBits for IDs that are exact JavaScript numbers, or the
+// configuration is refused instead of silently producing incompatible
+// IDs. If Layout is nil, the default layout of the dxyflake package is used.
+// Unit is the time unit, see dxyflake.Settings.TimeUnit.
+type Layout struct {
+	Time      int      `yaml:"time" toml:"time" json:"time"`
+	MachineID int      `yaml:"machine-id" toml:"machine-id" json:"machine-id"`
+	ServiceID int      `yaml:"service-id" toml:"service-id" json:"service-id"`
+	Sequence  int      `yaml:"sequence" toml:"sequence" json:"sequence"`
+	Unit      Duration `yaml:"unit" toml:"unit" json:"unit"`
+}
+
+// Provider selects a registered ID provider by name.
+type Provider struct {
+	Name   string `yaml:"provider" toml:"provider" json:"provider"`
+	Params Params `yaml:"params" toml:"params" json:"params"`
+}
+
+// Policy holds the ID assignment rules. Unlike the rest of the file, the
+// policy can be changed at runtime, see Reloader.
+//
+// AllowMachineIDs and AllowServiceIDs restrict the IDs a generator may use.
+// An empty list allows every ID.
+//
+// Tenants maps tenant names to the service ID their IDs are issued with.
+//
+// Quotas caps the number of IDs per second each tenant may request.
+// Tenants without a quota are not limited.
+//
+// APIKeys are the clients of the ID server, see server.APIKeyAuth. Keys
+// are rotated by adding the new key, reloading, moving the clients over
+// and removing the old key.
+type Policy struct {
+	AllowMachineIDs []uint16          `yaml:"allow-machine-ids" toml:"allow-machine-ids" json:"allow-machine-ids"`
+	AllowServiceIDs []uint16          `yaml:"allow-service-ids" toml:"allow-service-ids" json:"allow-service-ids"`
+	Tenants         map[string]uint16 `yaml:"tenants" toml:"tenants" json:"tenants"`
+	Quotas          map[string]int    `yaml:"quotas" toml:"quotas" json:"quotas"`
+	APIKeys         []APIKey          `yaml:"api-keys" toml:"api-keys" json:"api-keys"`
+}
+
+// APIKey is a client of the ID server, see server.APIKey. Hash is the
+// SHA-256 hash of the key as printed by "dxyflake apikey".
+type APIKey struct {
+	Name       string   `yaml:"name" toml:"name" json:"name"`
+	Hash       string   `yaml:"hash" toml:"hash" json:"hash"`
+	Tenants    []string `yaml:"tenants" toml:"tenants" json:"tenants"`
+	ServiceIDs []uint16 `yaml:"service-ids" toml:"service-ids" json:"service-ids"`
+	Quota      int      `yaml:"quota" toml:"quota" json:"quota"`
+}
+
+// ServiceNames returns the tenants by service ID. Tenants sharing a
+// service ID are joined by commas in sorted order.
+func (p *Policy) ServiceNames() dxyflake.ServiceNames {
+	if len(p.Tenants) == 0 {
+		return nil
+	}
+	tenants := make([]string, 0, len(p.Tenants))
+	for name := range p.Tenants {
+		tenants = append(tenants, name)
+	}
+	sort.Strings(tenants)
+	names := make(dxyflake.ServiceNames, len(p.Tenants))
+	for _, name := range tenants {
+		id := p.Tenants[name]
+		if names[id] != "" {
+			name = names[id] + "," + name
+		}
+		names[id] = name
+	}
+	return names
+}
+
+// Server holds the options of an ID server.
+type Server struct {
+	Addr         string   `yaml:"addr" toml:"addr" json:"addr"`
+	ReadTimeout  Duration `yaml:"read-timeout" toml:"read-timeout" json:"read-timeout"`
+	WriteTimeout Duration `yaml:"write-timeout" toml:"write-timeout" json:"write-timeout"`
+	MaxBatch     int      `yaml:"max-batch" toml:"max-batch" json:"max-batch"`
+}
+
+// File is the content of a configuration file.
+type File struct {
+	Epoch            string   `yaml:"epoch" toml:"epoch" json:"epoch"`
+	AllowEpochChange bool     `yaml:"allow-epoch-change" toml:"allow-epoch-change" json:"allow-epoch-change"`
+	EpochTolerance   Duration `yaml:"epoch-tolerance" toml:"epoch-tolerance" json:"epoch-tolerance"`
+	Layout           *Layout  `yaml:"layout" toml:"layout" json:"layout"`
+	Environment      string   `yaml:"environment" toml:"environment" json:"environment"`
+	ClockBack        string   `yaml:"clock-back" toml:"clock-back" json:"clock-back"`
+	ClockTolerance   Duration `yaml:"clock-back-tolerance" toml:"clock-back-tolerance" json:"clock-back-tolerance"`
+	Exhaustion       string   `yaml:"exhaustion" toml:"exhaustion" json:"exhaustion"`
+	MaxBorrow        Duration `yaml:"max-borrow" toml:"max-borrow" json:"max-borrow"`
+	RandomSequence   bool     `yaml:"random-sequence" toml:"random-sequence" json:"random-sequence"`
+	RateLimit        float64  `yaml:"rate-limit" toml:"rate-limit" json:"rate-limit"`
+	RateBurst        int      `yaml:"rate-burst" toml:"rate-burst" json:"rate-burst"`
+	RateLimited      string   `yaml:"rate-limited" toml:"rate-limited" json:"rate-limited"`
+	MachineID        Provider `yaml:"machine-id" toml:"machine-id" json:"machine-id"`
+	ServiceID        Provider `yaml:"service-id" toml:"service-id" json:"service-id"`
+	Policy           Policy   `yaml:"policy" toml:"policy" json:"policy"`
+	Server           Server   `yaml:"server" toml:"server" json:"server"`
+}
+
+// Load reads the configuration file at path, applies the environment
+// overrides and returns the resulting Settings. If path is empty, only the
+// environment is used.
+func Load(path string) (dxyflake.Settings, error) {
+	f, err := Read(path)
+	if err != nil {
+		return dxyflake.Settings{}, err
+	}
+	return f.Settings()
+}
+
+// Read reads the configuration file at path and applies the environment
+// overrides. If path is empty, only the environment is used.
+func Read(path string) (*File, error) {
+	f := new(File)
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := decode(path, b, f); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := f.applyEnv(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func decode(path string, b []byte, f *File) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(f); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	case ".toml":
+		md, err := toml.Decode(string(b), f)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown field %q", undecoded[0].String())
+		}
+		return nil
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		return dec.Decode(f)
+	}
+	return ErrUnknownFormat
+}
+
+func (f *File) applyEnv() error {
+	if v, ok := os.LookupEnv(EnvEpoch); ok {
+		f.Epoch = v
+	}
+	if v, ok := os.LookupEnv(EnvAllowEpochChange); ok {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvAllowEpochChange, err)
+		}
+		f.AllowEpochChange = allow
+	}
+	if v, ok := os.LookupEnv(EnvMachineIDProvider); ok {
+		f.MachineID = Provider{Name: v, Params: f.MachineID.Params}
+	}
+	if v, ok := os.LookupEnv(EnvMachineID); ok {
+		f.MachineID = Provider{Name: "static", Params: Params{"value": v}}
+	}
+	if v, ok := os.LookupEnv(EnvServiceIDProvider); ok {
+		f.ServiceID = Provider{Name: v, Params: f.ServiceID.Params}
+	}
+	if v, ok := os.LookupEnv(EnvServiceID); ok {
+		f.ServiceID = Provider{Name: "static", Params: Params{"value": v}}
+	}
+	if v, ok := os.LookupEnv(EnvAllowMachineIDs); ok {
+		ids, err := parseIDList(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvAllowMachineIDs, err)
+		}
+		f.Policy.AllowMachineIDs = ids
+	}
+	if v, ok := os.LookupEnv(EnvAllowServiceIDs); ok {
+		ids, err := parseIDList(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvAllowServiceIDs, err)
+		}
+		f.Policy.AllowServiceIDs = ids
+	}
+	if v, ok := os.LookupEnv(EnvServerAddr); ok {
+		f.Server.Addr = v
+	}
+	return nil
+}
+
+func parseIDList(s string) ([]uint16, error) {
+	var ids []uint16
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint16(id))
+	}
+	return ids, nil
+}
+
+func allowlist(ids []uint16) func(uint16) bool {
+	if len(ids) == 0 {
+		return nil
+	}
+	allowed := make(map[uint16]bool, len(ids))
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	return func(id uint16) bool {
+		return allowed[id]
+	}
+}
+
+// bits returns the bit lengths of the machine ID and the service ID of the
+// IDs of the file.
+func (f *File) bits() (machine, service uint) {
+	if f.Layout == nil {
+		return dxyflake.BitLenMachineID, dxyflake.BitLenServiceID
+	}
+	return uint(f.Layout.MachineID), uint(f.Layout.ServiceID)
+}
+
+// Settings returns the dxyflake Settings described by the file.
+func (f *File) Settings() (dxyflake.Settings, error) {
+	var st dxyflake.Settings
+
+	if f.Layout != nil {
+		l := *f.Layout
+		width := l.Time + l.MachineID + l.ServiceID + l.Sequence
+		if l.Time <= 0 || (width != 63 && width != dxyflake.JSSafeBits) ||
+			l.MachineID < 0 || l.MachineID > 16 ||
+			l.ServiceID < 0 || l.ServiceID > 16 ||
+			l.Sequence < 0 || l.Sequence > 16 {
+			return st, ErrUnsupportedLayout
+		}
+		if u := l.Unit.Duration; u != 0 && (dxyflake.Layout{BitLenTime: l.Time, Unit: u}).Validate() != nil {
+			return st, ErrUnsupportedLayout
+		}
+		st.BitsTime, st.BitsMachineID = l.Time, l.MachineID
+		st.BitsServiceID, st.BitsSequence = l.ServiceID, l.Sequence
+		st.TimeUnit = l.Unit.Duration
+	}
+
+	if f.Epoch != "" {
+		t, err := dxyflake.ParseEpoch(f.Epoch)
+		if err != nil {
+			return st, fmt.Errorf("epoch: %w", err)
+		}
+		if !t.Equal(dxyflake.DefaultStartTime) && !f.AllowEpochChange {
+			return st, fmt.Errorf("epoch %s: %w", f.Epoch, ErrEpochChange)
+		}
+		st.StartTimeSet(t)
+	}
+	st.StartTimeTolerance = f.EpochTolerance.Duration
+
+	switch f.Environment {
+	case "":
+	case "production":
+		st.Environment = dxyflake.EnvProduction
+	case "test":
+		st.Environment = dxyflake.EnvTest
+	default:
+		return st, fmt.Errorf("%w: %s", ErrUnknownEnvironment, f.Environment)
+	}
+
+	switch f.ClockBack {
+	case "", "continue":
+	case "wait":
+		st.ClockBack = dxyflake.ClockBackWait
+	case "error":
+		st.ClockBack = dxyflake.ClockBackError
+	default:
+		return st, fmt.Errorf("%w: %s", ErrUnknownClockBack, f.ClockBack)
+	}
+	st.ClockBackTolerance = f.ClockTolerance.Duration
+
+	switch f.Exhaustion {
+	case "", "sleep":
+	case "spin":
+		st.Exhaustion = dxyflake.ExhaustionSpin
+	case "error":
+		st.Exhaustion = dxyflake.ExhaustionError
+	case "borrow":
+		st.Exhaustion = dxyflake.ExhaustionBorrow
+	default:
+		return st, fmt.Errorf("%w: %s", ErrUnknownExhaustion, f.Exhaustion)
+	}
+	st.MaxBorrow = f.MaxBorrow.Duration
+	st.RandomSequence = f.RandomSequence
+
+	switch f.RateLimited {
+	case "", "wait":
+	case "error":
+		st.RateLimited = dxyflake.RateLimitError
+	default:
+		return st, fmt.Errorf("%w: %s", ErrUnknownRateLimit, f.RateLimited)
+	}
+	st.RateLimit, st.RateBurst = f.RateLimit, f.RateBurst
+
+	if err := f.Policy.validate(f.bits()); err != nil {
+		return st, err
+	}
+
+	machineBits, serviceBits := f.bits()
+	var err error
+	if f.MachineID.Name != "" {
+		st.MachineID, err = newProvider(f.MachineID, machineBits)
+		if err != nil {
+			return st, fmt.Errorf("machine-id: %w", err)
+		}
+	}
+	if f.ServiceID.Name != "" {
+		st.ServiceID, err = newProvider(f.ServiceID, serviceBits)
+		if err != nil {
+			return st, fmt.Errorf("service-id: %w", err)
+		}
+	}
+
+	st.CheckMachineID = allowlist(f.Policy.AllowMachineIDs)
+	st.CheckServiceID = allowlist(f.Policy.AllowServiceIDs)
+	st.ServiceNames = f.Policy.ServiceNames()
+	return st, nil
+}
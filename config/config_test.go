@@ -0,0 +1,317 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+const yamlConfig = `
+epoch: "2022-01-01T00:00:00Z"
+allow-epoch-change: true
+epoch-tolerance: 50ms
+clock-back: wait
+clock-back-tolerance: 20ms
+exhaustion: borrow
+max-borrow: 30ms
+random-sequence: true
+rate-limit: 5000
+rate-limited: error
+layout:
+  time: 41
+  machine-id: 5
+  service-id: 5
+  sequence: 12
+  unit: 1ms
+machine-id:
+  provider: static
+  params:
+    value: 3
+service-id:
+  provider: env
+  params:
+    name: TEST_DXYFLAKE_SERVICE
+policy:
+  allow-machine-ids: [1, 2, 3]
+  tenants:
+    orders: 9
+server:
+  addr: ":8080"
+  read-timeout: 5s
+`
+
+const tomlConfig = `
+epoch = "2022-01-01"
+allow-epoch-change = true
+
+[machine-id]
+provider = "static"
+params = { value = 3 }
+
+[service-id]
+provider = "hostname"
+params = { hostname = "node-a" }
+
+[server]
+addr = ":9090"
+write-timeout = "2s"
+`
+
+const jsonConfig = `{
+  "machine-id": {"provider": "static", "params": {"value": 3}},
+  "policy": {"allow-service-ids": [7]}
+}`
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	t.Setenv("TEST_DXYFLAKE_SERVICE", "9")
+	path := writeConfig(t, "dxyflake.yaml", yamlConfig)
+
+	f, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Server.Addr != ":8080" || f.Server.ReadTimeout.Duration != 5*time.Second {
+		t.Errorf("unexpected server options: %+v", f.Server)
+	}
+
+	st, err := f.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.StartTime.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start time: %s", st.StartTime)
+	}
+	if st.StartTimeTolerance != 50*time.Millisecond {
+		t.Errorf("unexpected start time tolerance: %v", st.StartTimeTolerance)
+	}
+	if st.TimeUnit != time.Millisecond {
+		t.Errorf("unexpected time unit: %v", st.TimeUnit)
+	}
+	if st.ClockBack != dxyflake.ClockBackWait || st.ClockBackTolerance != 20*time.Millisecond {
+		t.Errorf("unexpected clock back policy %v, %v", st.ClockBack, st.ClockBackTolerance)
+	}
+	if st.Exhaustion != dxyflake.ExhaustionBorrow || st.MaxBorrow != 30*time.Millisecond || !st.RandomSequence {
+		t.Errorf("unexpected exhaustion policy %v, %v", st.Exhaustion, st.MaxBorrow)
+	}
+	if st.RateLimit != 5000 || st.RateBurst != 0 || st.RateLimited != dxyflake.RateLimitError {
+		t.Errorf("unexpected rate limit %v, %d, %v", st.RateLimit, st.RateBurst, st.RateLimited)
+	}
+	if id, err := st.MachineID(); err != nil || id != 3 {
+		t.Errorf("unexpected machine id: %d, %v", id, err)
+	}
+	if id, err := st.ServiceID(); err != nil || id != 9 {
+		t.Errorf("unexpected service id: %d, %v", id, err)
+	}
+	if !st.CheckMachineID(3) || st.CheckMachineID(4) {
+		t.Error("machine id allowlist not applied")
+	}
+	if st.CheckServiceID != nil {
+		t.Error("unexpected service id check")
+	}
+
+	df := dxyflake.NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := dxyflake.Decompose(id); parts["machine-id"] != 3 || parts["service-id"] != 9 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+	if ns := df.Namespace(); ns != "orders" {
+		t.Errorf("unexpected namespace %q", ns)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfig(t, "dxyflake.toml", tomlConfig)
+
+	f, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Server.Addr != ":9090" || f.Server.WriteTimeout.Duration != 2*time.Second {
+		t.Errorf("unexpected server options: %+v", f.Server)
+	}
+
+	st, err := f.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.StartTime.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start time: %s", st.StartTime)
+	}
+	first, _ := st.ServiceID()
+	second, _ := st.ServiceID()
+	if first != second || first >= 1<<dxyflake.BitLenServiceID {
+		t.Errorf("unexpected hostname service id: %d, %d", first, second)
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	path := writeConfig(t, "dxyflake.json", jsonConfig)
+	t.Setenv(EnvMachineID, "5")
+	t.Setenv(EnvServiceID, "7")
+	t.Setenv(EnvEpoch, "2021-12-01")
+	t.Setenv(EnvAllowEpochChange, "true")
+	t.Setenv(EnvServerAddr, ":1234")
+
+	f, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Server.Addr != ":1234" {
+		t.Errorf("unexpected addr: %s", f.Server.Addr)
+	}
+
+	st, err := f.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := st.MachineID(); id != 5 {
+		t.Errorf("unexpected machine id: %d", id)
+	}
+	if id, _ := st.ServiceID(); id != 7 || !st.CheckServiceID(id) {
+		t.Errorf("unexpected service id: %d", id)
+	}
+	if dxyflake.NewDxyflake(st) == nil {
+		t.Error("dxyflake not created")
+	}
+}
+
+func TestLoadEpochName(t *testing.T) {
+	st, err := Load(writeConfig(t, "dxyflake.yaml", "epoch: twitter\nallow-epoch-change: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := dxyflake.LookupEpoch(dxyflake.EpochTwitter); !st.StartTime.Equal(want) {
+		t.Errorf("unexpected epoch: %v", st.StartTime)
+	}
+	if _, err := Load(writeConfig(t, "dxyflake.yaml", "epoch: dxy-default\n")); err != nil {
+		t.Errorf("default epoch refused: %v", err)
+	}
+}
+
+func TestLoadLayout(t *testing.T) {
+	content := "layout:\n  time: 39\n  machine-id: 8\n  service-id: 4\n  sequence: 12\n" +
+		"machine-id:\n  provider: static\n  params:\n    value: 200\n" +
+		"policy:\n  allow-machine-ids: [100, 200]\n"
+	st, err := Load(writeConfig(t, "dxyflake.yaml", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	df := dxyflake.NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := df.Decompose(id); parts["machine-id"] != 200 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+
+	st, err = Load(writeConfig(t, "dxyflake.yaml", "layout:\n  time: 38\n  machine-id: 5\n  service-id: 2\n  sequence: 8\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if df := dxyflake.NewDxyflake(st); df == nil || !df.Layout().JSSafe() {
+		t.Error("53-bit layout refused")
+	}
+
+	if _, err := Load(writeConfig(t, "dxyflake.yaml", "layout:\n  time: 39\n  machine-id: 8\n  service-id: 4\n  sequence: 12\npolicy:\n  allow-service-ids: [16]\n")); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("Load() error = %v, want %v", err, ErrInvalidPolicy)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    error
+	}{
+		{"unknown format", "dxyflake.ini", "", ErrUnknownFormat},
+		{"unknown provider", "dxyflake.yaml", "machine-id:\n  provider: dice\n", ErrUnknownProvider},
+		{"layout", "dxyflake.yaml", "layout:\n  time: 39\n  machine-id: 8\n  service-id: 4\n  sequence: 8\n", ErrUnsupportedLayout},
+		{"epoch change", "dxyflake.yaml", "epoch: twitter\n", ErrEpochChange},
+		{"environment", "dxyflake.yaml", "environment: qa\n", ErrUnknownEnvironment},
+		{"clock back", "dxyflake.yaml", "clock-back: jump\n", ErrUnknownClockBack},
+		{"exhaustion", "dxyflake.yaml", "exhaustion: drop\n", ErrUnknownExhaustion},
+		{"rate limit", "dxyflake.yaml", "rate-limited: drop\n", ErrUnknownRateLimit},
+		{"unit", "dxyflake.yaml", "layout:\n  time: 41\n  machine-id: 5\n  service-id: 5\n  sequence: 12\n  unit: 3ms\n", ErrUnsupportedLayout},
+		{"unknown epoch", "dxyflake.yaml", "epoch: nowhere\n", dxyflake.ErrUnknownEpoch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.file, tt.content))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Load() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(writeConfig(t, "dxyflake.yaml", "machine-ids: 3\n")); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if _, err := Load(writeConfig(t, "dxyflake.toml", "machine-ids = 3\n")); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if _, err := Load(writeConfig(t, "dxyflake.yaml", "machine-id:\n  provider: static\n  params:\n    value: 32\n")); err == nil {
+		t.Error("machine id overflow accepted")
+	}
+}
+
+func TestIPProviders(t *testing.T) {
+	if id := lowBits(net.ParseIP("10.0.3.200"), 8); id != 200 {
+		t.Errorf("lowBits() = %d", id)
+	}
+	if _, err := Load(writeConfig(t, "dxyflake.yaml", "machine-id:\n  provider: interface\n")); err == nil {
+		t.Error("interface provider without a name accepted")
+	}
+	st, err := Load(writeConfig(t, "dxyflake.yaml", "machine-id:\n  provider: interface\n  params:\n    name: no-such-interface0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.MachineID(); err == nil {
+		t.Error("machine ID of a missing interface")
+	}
+	if st, err = Load(writeConfig(t, "dxyflake.yaml", "machine-id:\n  provider: private-ip\n")); err != nil || st.MachineID == nil {
+		t.Errorf("private-ip provider: %v", err)
+	}
+}
+
+func TestPodProviders(t *testing.T) {
+	t.Setenv(dxyflake.EnvPodName, "ids-7")
+	t.Setenv(dxyflake.EnvPodNamespace, "orders")
+	st, err := Load(writeConfig(t, "dxyflake.yaml", "machine-id:\n  provider: pod-ordinal\n  params:\n    offset: 16\nservice-id:\n  provider: pod\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := st.MachineID(); err != nil || id != 23 {
+		t.Errorf("pod-ordinal machine id = %d, %v", id, err)
+	}
+	if id, err := st.ServiceID(); err != nil || id != foldHash("orders/ids-7", dxyflake.BitLenServiceID) {
+		t.Errorf("pod service id = %d, %v", id, err)
+	}
+	t.Setenv(dxyflake.EnvPodName, "ids-16")
+	if _, err := st.MachineID(); err == nil {
+		t.Error("ordinal beyond the machine id bits accepted")
+	}
+}
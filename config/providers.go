@@ -0,0 +1,207 @@
+package config
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrUnknownProvider is returned for provider names that are not registered.
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// Params are the parameters of a provider as written in the configuration.
+type Params map[string]interface{}
+
+// String returns the parameter as a string, or def if it is not set.
+func (p Params) String(name, def string) string {
+	v, ok := p[name]
+	if !ok || v == nil {
+		return def
+	}
+	return fmt.Sprint(v)
+}
+
+// Uint16 returns the parameter as a uint16, or def if it is not set.
+func (p Params) Uint16(name string, def uint16) (uint16, error) {
+	s := p.String(name, "")
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", name, err)
+	}
+	return uint16(v), nil
+}
+
+// ProviderFactory builds an ID provider from its parameters. bits is the bit
+// length of the field the provider fills.
+type ProviderFactory func(params Params, bits uint) (func() (uint16, error), error)
+
+var providers = struct {
+	sync.RWMutex
+	m map[string]ProviderFactory
+}{m: map[string]ProviderFactory{
+	"static":      staticProvider,
+	"env":         envProvider,
+	"hostname":    hostnameProvider,
+	"private-ip":  privateIPProvider,
+	"interface":   interfaceProvider,
+	"pod-ordinal": podOrdinalProvider,
+	"pod":         podProvider,
+}}
+
+// RegisterProvider makes a provider available by name in configuration
+// files. Registering a name twice replaces the earlier provider.
+func RegisterProvider(name string, f ProviderFactory) {
+	providers.Lock()
+	defer providers.Unlock()
+
+	providers.m[name] = f
+}
+
+func newProvider(p Provider, bits uint) (func() (uint16, error), error) {
+	providers.RLock()
+	f, ok := providers.m[p.Name]
+	providers.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, p.Name)
+	}
+	return f(p.Params, bits)
+}
+
+// staticProvider returns the "value" parameter.
+func staticProvider(params Params, bits uint) (func() (uint16, error), error) {
+	v, err := params.Uint16("value", 0)
+	if err != nil {
+		return nil, err
+	}
+	if v >= 1<<bits {
+		return nil, fmt.Errorf("value %d does not fit in %d bits", v, bits)
+	}
+	return func() (uint16, error) {
+		return v, nil
+	}, nil
+}
+
+// envProvider reads the ID from the environment variable named by "name"
+// when the generator is created.
+func envProvider(params Params, bits uint) (func() (uint16, error), error) {
+	name := params.String("name", "")
+	if name == "" {
+		return nil, errors.New("param name is required")
+	}
+	return func() (uint16, error) {
+		v, err := strconv.ParseUint(os.Getenv(name), 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		if v >= 1<<bits {
+			return 0, fmt.Errorf("%s: %d does not fit in %d bits", name, v, bits)
+		}
+		return uint16(v), nil
+	}, nil
+}
+
+// hostnameProvider hashes the host name, or the "hostname" parameter, into
+// the field. Distinct hosts may collide, so pair it with an allowlist or a
+// coordinator when the fleet is large.
+func hostnameProvider(params Params, bits uint) (func() (uint16, error), error) {
+	fixed := params.String("hostname", "")
+	return func() (uint16, error) {
+		name := fixed
+		if name == "" {
+			var err error
+			if name, err = os.Hostname(); err != nil {
+				return 0, err
+			}
+		}
+		return foldHash(name, bits), nil
+	}, nil
+}
+
+// foldHash folds the FNV-1a hash of s into bits bits.
+func foldHash(s string, bits uint) uint16 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	sum := h.Sum32()
+	sum ^= sum >> 16
+	return uint16(sum & (1<<bits - 1))
+}
+
+// privateIPProvider takes the ID from the lowest bits of the private IPv4
+// address of the host, see dxyflake.PrivateIPv4. Hosts only get distinct
+// IDs within one block of 2^bits addresses.
+func privateIPProvider(params Params, bits uint) (func() (uint16, error), error) {
+	return func() (uint16, error) {
+		ip, err := dxyflake.PrivateIPv4()
+		if err != nil {
+			return 0, err
+		}
+		return lowBits(ip, bits), nil
+	}, nil
+}
+
+// interfaceProvider takes the ID from the lowest bits of the IPv4 address
+// of the network interface named by "name".
+func interfaceProvider(params Params, bits uint) (func() (uint16, error), error) {
+	name := params.String("name", "")
+	if name == "" {
+		return nil, errors.New("param name is required")
+	}
+	return func() (uint16, error) {
+		ip, err := dxyflake.InterfaceIPv4(name)
+		if err != nil {
+			return 0, err
+		}
+		return lowBits(ip, bits), nil
+	}, nil
+}
+
+func lowBits(ip net.IP, bits uint) uint16 {
+	return uint16(binary.BigEndian.Uint32(ip.To4()) & (1<<bits - 1))
+}
+
+// podOrdinalProvider takes the ID from the ordinal of the StatefulSet pod,
+// see dxyflake.PodOrdinal, plus the "offset" parameter, so that several
+// StatefulSets can share the ID space.
+func podOrdinalProvider(params Params, bits uint) (func() (uint16, error), error) {
+	offset, err := params.Uint16("offset", 0)
+	if err != nil {
+		return nil, err
+	}
+	return func() (uint16, error) {
+		name, err := dxyflake.PodName()
+		if err != nil {
+			return 0, err
+		}
+		n, err := dxyflake.PodOrdinal(name)
+		if err != nil {
+			return 0, err
+		}
+		v := n + int(offset)
+		if v >= 1<<bits {
+			return 0, fmt.Errorf("ordinal %d of %s plus offset %d does not fit in %d bits", n, name, offset, bits)
+		}
+		return uint16(v), nil
+	}, nil
+}
+
+// podProvider hashes the namespace and name of the pod into the field,
+// see dxyflake.MachineIDFromPod.
+func podProvider(params Params, bits uint) (func() (uint16, error), error) {
+	return func() (uint16, error) {
+		name, err := dxyflake.PodName()
+		if err != nil {
+			return 0, err
+		}
+		return foldHash(os.Getenv(dxyflake.EnvPodNamespace)+"/"+name, bits), nil
+	}, nil
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrInvalidPolicy is returned when a Policy does not validate.
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// ErrRestartRequired is returned by Reload when anything but the policy
+// changed. Those changes only take effect after a restart, so the reload is
+// refused rather than applied halfway.
+var ErrRestartRequired = errors.New("configuration change requires a restart")
+
+// reloadDelay collapses the burst of events an editor or a configmap
+// update produces into a single reload.
+const reloadDelay = 100 * time.Millisecond
+
+// Validate checks that every ID fits its field, that every tenant is mapped
+// to an allowed service ID, that quotas are not negative and that API keys
+// are named uniquely and hashed. IDs are checked against the default
+// layout.
+func (p *Policy) Validate() error {
+	return p.validate(dxyflake.BitLenMachineID, dxyflake.BitLenServiceID)
+}
+
+// validate is Validate for machine IDs and service IDs of the given bit
+// lengths.
+func (p *Policy) validate(machineBits, serviceBits uint) error {
+	for _, id := range p.AllowMachineIDs {
+		if id >= 1<<machineBits {
+			return fmt.Errorf("%w: machine id %d does not fit in %d bits", ErrInvalidPolicy, id, machineBits)
+		}
+	}
+	for _, id := range p.AllowServiceIDs {
+		if id >= 1<<serviceBits {
+			return fmt.Errorf("%w: service id %d does not fit in %d bits", ErrInvalidPolicy, id, serviceBits)
+		}
+	}
+	check := allowlist(p.AllowServiceIDs)
+	for tenant, id := range p.Tenants {
+		if tenant == "" {
+			return fmt.Errorf("%w: empty tenant name", ErrInvalidPolicy)
+		}
+		if id >= 1<<serviceBits {
+			return fmt.Errorf("%w: tenant %s: service id %d does not fit in %d bits", ErrInvalidPolicy, tenant, id, serviceBits)
+		}
+		if check != nil && !check(id) {
+			return fmt.Errorf("%w: tenant %s: service id %d is not allowed", ErrInvalidPolicy, tenant, id)
+		}
+	}
+	for tenant, quota := range p.Quotas {
+		if _, ok := p.Tenants[tenant]; !ok {
+			return fmt.Errorf("%w: quota for unknown tenant %s", ErrInvalidPolicy, tenant)
+		}
+		if quota < 0 {
+			return fmt.Errorf("%w: tenant %s: negative quota", ErrInvalidPolicy, tenant)
+		}
+	}
+	names := make(map[string]bool, len(p.APIKeys))
+	for _, k := range p.APIKeys {
+		if k.Name == "" || names[k.Name] {
+			return fmt.Errorf("%w: API key names must be unique and not empty", ErrInvalidPolicy)
+		}
+		names[k.Name] = true
+		if b, err := hex.DecodeString(k.Hash); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("%w: API key %s: hash is not a hex SHA-256 hash", ErrInvalidPolicy, k.Name)
+		}
+		if k.Quota < 0 {
+			return fmt.Errorf("%w: API key %s: negative quota", ErrInvalidPolicy, k.Name)
+		}
+	}
+	return nil
+}
+
+// Reloader keeps the policy of a configuration file up to date while the
+// rest of the configuration stays as it was first loaded.
+//
+// The policy is swapped atomically, so the Check functions of the Settings
+// returned by Reloader.Settings always see one consistent policy.
+type Reloader struct {
+	path   string
+	file   *File
+	policy atomic.Value // *Policy
+}
+
+// NewReloader reads the configuration file at path, applies the environment
+// overrides and validates it.
+func NewReloader(path string) (*Reloader, error) {
+	f, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Policy.validate(f.bits()); err != nil {
+		return nil, err
+	}
+
+	r := &Reloader{path: path, file: f}
+	r.policy.Store(&f.Policy)
+	return r, nil
+}
+
+// File returns the configuration as first loaded. Its Policy field is not
+// updated by reloads, use Policy for that.
+func (r *Reloader) File() *File {
+	return r.file
+}
+
+// Policy returns the current policy. It must not be modified.
+func (r *Reloader) Policy() *Policy {
+	return r.policy.Load().(*Policy)
+}
+
+// Settings returns the Settings of the configuration file, with
+// CheckMachineID and CheckServiceID following the current policy.
+func (r *Reloader) Settings() (dxyflake.Settings, error) {
+	st, err := r.file.Settings()
+	if err != nil {
+		return st, err
+	}
+	st.CheckMachineID = r.CheckMachineID
+	st.CheckServiceID = r.CheckServiceID
+	return st, nil
+}
+
+// CheckMachineID reports whether the current policy allows the machine ID.
+func (r *Reloader) CheckMachineID(id uint16) bool {
+	check := allowlist(r.Policy().AllowMachineIDs)
+	return check == nil || check(id)
+}
+
+// CheckServiceID reports whether the current policy allows the service ID.
+func (r *Reloader) CheckServiceID(id uint16) bool {
+	check := allowlist(r.Policy().AllowServiceIDs)
+	return check == nil || check(id)
+}
+
+// ServiceID returns the service ID of a tenant under the current policy.
+func (r *Reloader) ServiceID(tenant string) (uint16, bool) {
+	id, ok := r.Policy().Tenants[tenant]
+	return id, ok
+}
+
+// Quota returns the quota of a tenant under the current policy.
+// ok is false if the tenant is not limited.
+func (r *Reloader) Quota(tenant string) (quota int, ok bool) {
+	quota, ok = r.Policy().Quotas[tenant]
+	return
+}
+
+// APIKeys returns the API keys of the current policy, for
+// server.NewAPIKeyAuth.
+func (r *Reloader) APIKeys() []server.APIKey {
+	keys := r.Policy().APIKeys
+	out := make([]server.APIKey, len(keys))
+	for i, k := range keys {
+		out[i] = server.APIKey(k)
+	}
+	return out
+}
+
+// Reload reads the configuration file again and swaps in its policy. The
+// current policy is kept if the file does not validate or if anything
+// besides the policy changed.
+func (r *Reloader) Reload() error {
+	f, err := Read(r.path)
+	if err != nil {
+		return err
+	}
+	if err := f.Policy.validate(f.bits()); err != nil {
+		return err
+	}
+
+	fixed, next := *r.file, *f
+	fixed.Policy, next.Policy = Policy{}, Policy{}
+	if !reflect.DeepEqual(fixed, next) {
+		return ErrRestartRequired
+	}
+
+	r.policy.Store(&f.Policy)
+	return nil
+}
+
+// Watch reloads the policy whenever the configuration file changes or the
+// process receives SIGHUP, until ctx is done. report, if not nil, is called
+// after every reload attempt with its outcome.
+func (r *Reloader) Watch(ctx context.Context, report func(error)) error {
+	if report == nil {
+		report = func(error) {}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// watch the directory, editors and configmaps replace the file itself
+	if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+		return err
+	}
+	name := filepath.Clean(r.path)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-watcher.Errors:
+			report(err)
+		case ev := <-watcher.Events:
+			if filepath.Clean(ev.Name) == name {
+				timer.Reset(reloadDelay)
+			}
+		case <-hup:
+			report(r.Reload())
+		case <-timer.C:
+			report(r.Reload())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake/server"
+)
+
+const policyConfig = `
+machine-id:
+  provider: static
+  params:
+    value: 1
+policy:
+  allow-machine-ids: [1, 2]
+  allow-service-ids: [1, 2, 3]
+  tenants:
+    orders: 1
+    billing: 2
+  quotas:
+    orders: 1000
+`
+
+const updatedPolicyConfig = `
+machine-id:
+  provider: static
+  params:
+    value: 1
+policy:
+  allow-machine-ids: [1, 2, 3]
+  allow-service-ids: [1, 2, 3]
+  tenants:
+    orders: 1
+    billing: 2
+    audit: 3
+`
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+	}{
+		{"machine id overflow", Policy{AllowMachineIDs: []uint16{32}}},
+		{"service id overflow", Policy{AllowServiceIDs: []uint16{32}}},
+		{"tenant not allowed", Policy{AllowServiceIDs: []uint16{1}, Tenants: map[string]uint16{"a": 2}}},
+		{"empty tenant", Policy{Tenants: map[string]uint16{"": 2}}},
+		{"quota of unknown tenant", Policy{Quotas: map[string]int{"a": 1}}},
+		{"negative quota", Policy{Tenants: map[string]uint16{"a": 2}, Quotas: map[string]int{"a": -1}}},
+		{"unnamed API key", Policy{APIKeys: []APIKey{{Hash: server.HashAPIKey("k")}}}},
+		{"duplicate API key", Policy{APIKeys: []APIKey{{Name: "a", Hash: server.HashAPIKey("k")}, {Name: "a", Hash: server.HashAPIKey("l")}}}},
+		{"plain API key", Policy{APIKeys: []APIKey{{Name: "a", Hash: "secret"}}}},
+		{"negative API key quota", Policy{APIKeys: []APIKey{{Name: "a", Hash: server.HashAPIKey("k"), Quota: -1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := writeConfig(t, "dxyflake.yaml", policyConfig)
+	r, err := NewReloader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := r.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.CheckMachineID(3) {
+		t.Error("machine id 3 allowed")
+	}
+	if _, ok := r.ServiceID("audit"); ok {
+		t.Error("unexpected tenant audit")
+	}
+	if q, ok := r.Quota("orders"); !ok || q != 1000 {
+		t.Errorf("unexpected quota: %d", q)
+	}
+
+	if err := os.WriteFile(path, []byte(updatedPolicyConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !st.CheckMachineID(3) {
+		t.Error("machine id 3 not allowed after reload")
+	}
+	if id, ok := r.ServiceID("audit"); !ok || id != 3 {
+		t.Errorf("unexpected audit service id: %d", id)
+	}
+	if _, ok := r.Quota("orders"); ok {
+		t.Error("quota not removed")
+	}
+
+	// invalid and unsafe changes keep the current policy
+	invalid := updatedPolicyConfig + "  quotas:\n    nobody: 1\n"
+	if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("Reload() error = %v", err)
+	}
+	unsafe := "epoch: 2022-01-01\n" + updatedPolicyConfig
+	if err := os.WriteFile(path, []byte(unsafe), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != ErrRestartRequired {
+		t.Errorf("Reload() error = %v", err)
+	}
+	if _, ok := r.ServiceID("audit"); !ok {
+		t.Error("policy lost after a refused reload")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, "dxyflake.yaml", policyConfig)
+	r, err := NewReloader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan error, 10)
+	go r.Watch(ctx, func(err error) { reloaded <- err })
+
+	// give the watcher time to start before touching the file
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(path, []byte(updatedPolicyConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("file change not picked up")
+	}
+	if _, ok := r.ServiceID("audit"); !ok {
+		t.Error("policy not reloaded")
+	}
+}
+
+func TestReloadAPIKeys(t *testing.T) {
+	config := policyConfig + "  api-keys:\n    - name: shop\n      hash: " + server.HashAPIKey("old") + "\n      quota: 10\n"
+	path := writeConfig(t, "dxyflake.yaml", config)
+	r, err := NewReloader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := r.APIKeys()
+	if len(keys) != 1 || keys[0].Name != "shop" || keys[0].Quota != 10 {
+		t.Fatalf("unexpected keys: %+v", keys)
+	}
+
+	// rotate the key
+	config = policyConfig + "  api-keys:\n    - name: shop\n      hash: " + server.HashAPIKey("new") + "\n"
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := r.APIKeys(); len(keys) != 1 || keys[0].Hash != server.HashAPIKey("new") {
+		t.Errorf("key not rotated: %+v", keys)
+	}
+}
@@ -0,0 +1,67 @@
+package dxyflake
+
+import "context"
+
+// IDKind names the role of an ID carried in a context, so that a request ID
+// and the ID of the entity the request works on travel side by side.
+type IDKind string
+
+// These are the ID kinds of the package. Other kinds may be used freely.
+const (
+	EntityID  IDKind = "entity-id"
+	RequestID IDKind = "request-id"
+)
+
+type idsKey struct{}
+
+// contextIDs is the list of IDs of a context, newest first. A context only
+// refers to its head, so IDs added to a child never leak into its parent.
+type contextIDs struct {
+	kind IDKind
+	id   ID
+	next *contextIDs
+}
+
+// ContextWithID returns a copy of ctx carrying id as its EntityID.
+func ContextWithID(ctx context.Context, id ID) context.Context {
+	return ContextWithIDKind(ctx, EntityID, id)
+}
+
+// IDFromContext returns the EntityID of ctx, and false if it has none.
+func IDFromContext(ctx context.Context) (ID, bool) {
+	return IDKindFromContext(ctx, EntityID)
+}
+
+// ContextWithIDKind returns a copy of ctx carrying id as its ID of kind,
+// replacing any ID of that kind ctx carries.
+func ContextWithIDKind(ctx context.Context, kind IDKind, id ID) context.Context {
+	head, _ := ctx.Value(idsKey{}).(*contextIDs)
+	return context.WithValue(ctx, idsKey{}, &contextIDs{kind: kind, id: id, next: head})
+}
+
+// IDKindFromContext returns the ID of kind of ctx, and false if it has none.
+func IDKindFromContext(ctx context.Context, kind IDKind) (ID, bool) {
+	head, _ := ctx.Value(idsKey{}).(*contextIDs)
+	for n := head; n != nil; n = n.next {
+		if n.kind == kind {
+			return n.id, true
+		}
+	}
+	return 0, false
+}
+
+// IDsFromContext returns every ID ctx carries by its kind, e.g. for loggers
+// adding them to each line. It returns nil if ctx carries none.
+func IDsFromContext(ctx context.Context) map[IDKind]ID {
+	head, _ := ctx.Value(idsKey{}).(*contextIDs)
+	if head == nil {
+		return nil
+	}
+	ids := make(map[IDKind]ID)
+	for n := head; n != nil; n = n.next {
+		if _, ok := ids[n.kind]; !ok {
+			ids[n.kind] = n.id
+		}
+	}
+	return ids
+}
@@ -0,0 +1,37 @@
+package dxyflake
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextIDs(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := IDFromContext(ctx); ok {
+		t.Error("ID found in an empty context")
+	}
+	if ids := IDsFromContext(ctx); ids != nil {
+		t.Errorf("unexpected IDs: %v", ids)
+	}
+
+	parent := ContextWithIDKind(ctx, RequestID, 1)
+	child := ContextWithID(parent, 2)
+	child = ContextWithIDKind(child, RequestID, 3)
+
+	if id, ok := IDFromContext(child); !ok || id != 2 {
+		t.Errorf("IDFromContext() = %d, %v", id, ok)
+	}
+	if id, ok := IDKindFromContext(child, RequestID); !ok || id != 3 {
+		t.Errorf("IDKindFromContext() = %d, %v", id, ok)
+	}
+	if id, ok := IDKindFromContext(parent, RequestID); !ok || id != 1 {
+		t.Errorf("parent changed: %d, %v", id, ok)
+	}
+	if _, ok := IDFromContext(parent); ok {
+		t.Error("entity ID leaked into the parent")
+	}
+	ids := IDsFromContext(child)
+	if len(ids) != 2 || ids[EntityID] != 2 || ids[RequestID] != 3 {
+		t.Errorf("unexpected IDs: %v", ids)
+	}
+}
@@ -0,0 +1,199 @@
+// Package convert re-bases IDs issued by another snowflake-style deployment
+// into the dxyflake layout.
+//
+// A foreign ID is decomposed with its own Layout, its timestamp is moved to
+// the dxyflake start time and time unit, and its node and sequence are
+// mapped onto the dxyflake machine ID, service ID and sequence fields. IDs
+// that cannot be represented are flagged instead of being silently mangled.
+//
+// Layouts also decompose foreign IDs as they are, and Eras tell the
+// Twitter Snowflake, Sonyflake and dxyflake IDs of one table apart.
+package convert
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These errors flag IDs that cannot be converted.
+var (
+	ErrNegative         = errors.New("negative source id")
+	ErrBeforeStartTime  = errors.New("timestamp is before the dxyflake start time")
+	ErrTimeOverflow     = errors.New("timestamp is over the dxyflake time limit")
+	ErrNodeOverflow     = errors.New("node does not fit the machine id and service id")
+	ErrSequenceOverflow = errors.New("sequence does not fit the dxyflake sequence")
+	ErrCollision        = errors.New("converted id collides with an earlier one")
+)
+
+// ErrInvalidLayout is returned by NewConverter when the source Layout is unusable.
+var ErrInvalidLayout = errors.New("invalid source layout")
+
+// Layout describes a snowflake-style ID made of time, node and sequence
+// fields, from the most significant bits to the least, or with the node
+// below the sequence if NodeLast is set.
+type Layout struct {
+	Epoch          time.Time     // time of tick 0
+	Unit           time.Duration // length of one tick
+	BitLenTime     uint
+	BitLenNode     uint
+	BitLenSequence uint
+	NodeLast       bool // the node is in the lowest bits, as in Sonyflake
+}
+
+// Twitter is the layout used by Twitter's Snowflake and bwmarrin/snowflake.
+var Twitter = Layout{
+	Epoch:          time.Unix(1288834974, 657000000).UTC(),
+	Unit:           time.Millisecond,
+	BitLenTime:     41,
+	BitLenNode:     10,
+	BitLenSequence: 12,
+}
+
+// Sonyflake is the default layout of sony/sonyflake: 10 msec ticks, an
+// 8-bit sequence and the 16-bit machine ID as the node, in the lowest bits.
+// Set Epoch to the StartTime of the Sonyflake settings if it had one.
+var Sonyflake = Layout{
+	Epoch:          time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC),
+	Unit:           10 * time.Millisecond,
+	BitLenTime:     39,
+	BitLenNode:     16,
+	BitLenSequence: 8,
+	NodeLast:       true,
+}
+
+func (l Layout) valid() bool {
+	return l.Unit > 0 && l.BitLenTime > 0 &&
+		l.BitLenTime+l.BitLenNode+l.BitLenSequence <= 63
+}
+
+func (l Layout) decompose(id int64) (tick, node, sequence int64) {
+	if l.NodeLast {
+		node = id & (1<<l.BitLenNode - 1)
+		sequence = (id >> l.BitLenNode) & (1<<l.BitLenSequence - 1)
+	} else {
+		sequence = id & (1<<l.BitLenSequence - 1)
+		node = (id >> l.BitLenSequence) & (1<<l.BitLenNode - 1)
+	}
+	tick = id >> (l.BitLenNode + l.BitLenSequence)
+	return
+}
+
+// NodeMapper maps a foreign node ID onto a dxyflake machine ID and service ID.
+type NodeMapper func(node int64) (machineID, serviceID uint16, err error)
+
+// SplitNode is the default NodeMapper. It uses the high bits of the node
+// as the machine ID and the low bits as the service ID, which matches the
+// datacenter/worker split of Twitter's Snowflake.
+func SplitNode(node int64) (uint16, uint16, error) {
+	if node < 0 || node >= 1<<(dxyflake.BitLenMachineID+dxyflake.BitLenServiceID) {
+		return 0, 0, ErrNodeOverflow
+	}
+	return uint16(node >> dxyflake.BitLenServiceID), uint16(node & (1<<dxyflake.BitLenServiceID - 1)), nil
+}
+
+// Converter converts foreign IDs into dxyflake IDs.
+//
+// When the source time unit is finer than the dxyflake time unit, several
+// source ticks fold into one dxyflake tick and two IDs could convert to the
+// same value. The Converter remembers the IDs it has produced in that case
+// and flags the later one with ErrCollision.
+type Converter struct {
+	from      Layout
+	startTick int64
+	mapNode   NodeMapper
+
+	mutex sync.Mutex
+	lossy bool
+	seen  map[dxyflake.ID]int64
+}
+
+// NewConverter returns a Converter from the given source Layout to dxyflake
+// IDs based on startTime. If startTime is 0, dxyflake.DefaultStartTime is
+// used. If mapNode is nil, SplitNode is used.
+func NewConverter(from Layout, startTime time.Time, mapNode NodeMapper) (*Converter, error) {
+	if !from.valid() {
+		return nil, ErrInvalidLayout
+	}
+	if startTime.IsZero() {
+		startTime = dxyflake.DefaultStartTime
+	}
+	if mapNode == nil {
+		mapNode = SplitNode
+	}
+
+	c := &Converter{
+		from:      from,
+		startTick: toTick(startTime),
+		mapNode:   mapNode,
+		lossy:     from.Unit < dxyflakeTimeUnit,
+	}
+	if c.lossy {
+		c.seen = make(map[dxyflake.ID]int64)
+	}
+	return c, nil
+}
+
+const dxyflakeTimeUnit = 10 * time.Millisecond
+
+// toTick returns t in dxyflake ticks since the Unix epoch without going
+// through UnixNano, which overflows for times far in the future.
+func toTick(t time.Time) int64 {
+	return t.Unix()*int64(time.Second/dxyflakeTimeUnit) + int64(t.Nanosecond())/int64(dxyflakeTimeUnit)
+}
+
+// Convert returns the dxyflake ID for a foreign ID.
+func (c *Converter) Convert(src int64) (dxyflake.ID, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.convert(src, c.seen)
+}
+
+func (c *Converter) convert(src int64, seen map[dxyflake.ID]int64) (dxyflake.ID, error) {
+	if src < 0 {
+		return 0, ErrNegative
+	}
+
+	tick, node, sequence := c.from.decompose(src)
+
+	hi, lo := bits.Mul64(uint64(tick), uint64(c.from.Unit))
+	if hi != 0 || lo > math.MaxInt64 {
+		return 0, ErrTimeOverflow
+	}
+	elapsed := toTick(c.from.Epoch.Add(time.Duration(lo))) - c.startTick
+	if elapsed < 0 {
+		return 0, ErrBeforeStartTime
+	}
+	if elapsed >= 1<<dxyflake.BitLenTime {
+		return 0, ErrTimeOverflow
+	}
+
+	machineID, serviceID, err := c.mapNode(node)
+	if err != nil {
+		return 0, err
+	}
+	if machineID >= 1<<dxyflake.BitLenMachineID || serviceID >= 1<<dxyflake.BitLenServiceID {
+		return 0, ErrNodeOverflow
+	}
+	if sequence >= 1<<dxyflake.BitLenSequence {
+		return 0, ErrSequenceOverflow
+	}
+
+	id := dxyflake.ID(elapsed<<(dxyflake.BitLenMachineID+dxyflake.BitLenServiceID+dxyflake.BitLenSequence) |
+		int64(machineID)<<(dxyflake.BitLenServiceID+dxyflake.BitLenSequence) |
+		int64(serviceID)<<dxyflake.BitLenSequence |
+		sequence)
+
+	if seen != nil {
+		if prev, ok := seen[id]; ok && prev != src {
+			return 0, ErrCollision
+		}
+		seen[id] = src
+	}
+	return id, nil
+}
@@ -0,0 +1,130 @@
+package convert
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func twitterID(t time.Time, node, sequence int64) int64 {
+	tick := int64(t.Sub(Twitter.Epoch) / time.Millisecond)
+	return tick<<22 | node<<12 | sequence
+}
+
+func TestConvertTwitter(t *testing.T) {
+	c, err := NewConverter(Twitter, time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2022, 3, 4, 5, 6, 7, 80000000, time.UTC)
+	id, err := c.Convert(twitterID(at, 3<<5|7, 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := dxyflake.Decompose(id)
+	wantTime := at.Sub(dxyflake.DefaultStartTime) / (10 * time.Millisecond)
+	if parts["time"] != int64(wantTime) {
+		t.Errorf("unexpected time: %d, want %d", parts["time"], wantTime)
+	}
+	if parts["machine-id"] != 3 {
+		t.Errorf("unexpected machine id: %d", parts["machine-id"])
+	}
+	if parts["service-id"] != 7 {
+		t.Errorf("unexpected service id: %d", parts["service-id"])
+	}
+	if parts["sequence"] != 42 {
+		t.Errorf("unexpected sequence: %d", parts["sequence"])
+	}
+}
+
+func TestConvertRejects(t *testing.T) {
+	c, err := NewConverter(Twitter, time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		name string
+		src  int64
+		want error
+	}{
+		{"negative", -1, ErrNegative},
+		{"before start time", twitterID(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 0, 0), ErrBeforeStartTime},
+		{"collision", twitterID(at.Add(time.Millisecond), 0, 0), ErrCollision},
+	}
+
+	if _, err := c.Convert(twitterID(at, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Convert(tt.src); err != tt.want {
+				t.Errorf("Convert() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertNodeOverflow(t *testing.T) {
+	wide := Layout{
+		Epoch:          Twitter.Epoch,
+		Unit:           10 * time.Millisecond,
+		BitLenTime:     39,
+		BitLenNode:     12,
+		BitLenSequence: 12,
+	}
+	c, err := NewConverter(wide, time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tick := int64(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Sub(wide.Epoch) / wide.Unit)
+	if _, err := c.Convert(tick<<24 | 1<<10<<12); err != ErrNodeOverflow {
+		t.Errorf("Convert() error = %v, want %v", err, ErrNodeOverflow)
+	}
+	if _, err := c.Convert(tick<<24 | 1023<<12); err != nil {
+		t.Errorf("Convert() error = %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	c, err := NewConverter(Twitter, time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	src := []int64{
+		twitterID(at, 1, 0),
+		twitterID(at, 1, 1),
+		twitterID(at.Add(time.Millisecond), 1, 1),
+		-5,
+	}
+
+	r := c.DryRun(src)
+	if r.Total != 4 || r.Converted != 2 {
+		t.Fatalf("unexpected report: total %d, converted %d", r.Total, r.Converted)
+	}
+	if len(r.Rejected()) != 2 {
+		t.Errorf("unexpected rejected: %v", r.Rejected())
+	}
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), ErrCollision.Error()) {
+		t.Errorf("report misses collisions:\n%s", buf.String())
+	}
+
+	// a dry run must not leave converted ids behind
+	if _, err := c.Convert(src[0]); err != nil {
+		t.Errorf("Convert() after DryRun error = %v", err)
+	}
+}
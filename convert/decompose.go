@@ -0,0 +1,96 @@
+package convert
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrUnknownEra is returned by Eras.Decompose for IDs outside every era.
+var ErrUnknownEra = errors.New("id outside every era")
+
+// Parts are the parts of an ID of a Layout. Time is the time at which its
+// tick begins.
+type Parts struct {
+	ID       int64
+	Time     time.Time
+	Tick     int64
+	Node     int64
+	Sequence int64
+}
+
+// FromDxyflake returns the Layout of the IDs of l, whose node is the
+// machine ID and service ID, node>>BitLenServiceID being the machine ID.
+func FromDxyflake(l dxyflake.Layout) Layout {
+	return Layout{
+		Epoch:          l.Epoch,
+		Unit:           l.Unit,
+		BitLenTime:     uint(l.BitLenTime),
+		BitLenNode:     uint(l.BitLenMachineID + l.BitLenServiceID),
+		BitLenSequence: uint(l.BitLenSequence),
+	}
+}
+
+// Decompose returns the parts of id, read with the layout as the foreign
+// generator wrote it.
+func (l Layout) Decompose(id int64) (Parts, error) {
+	if !l.valid() {
+		return Parts{}, ErrInvalidLayout
+	}
+	if id < 0 {
+		return Parts{}, ErrNegative
+	}
+	tick, node, sequence := l.decompose(id)
+	hi, lo := bits.Mul64(uint64(tick), uint64(l.Unit))
+	if hi != 0 || lo > math.MaxInt64 {
+		return Parts{}, ErrTimeOverflow
+	}
+	return Parts{
+		ID:       id,
+		Time:     l.Epoch.Add(time.Duration(lo)),
+		Tick:     tick,
+		Node:     node,
+		Sequence: sequence,
+	}, nil
+}
+
+// Era is the range of IDs, Min to Max inclusive, a table holds of one
+// generation of generators.
+type Era struct {
+	Name   string
+	Layout Layout
+	Min    int64
+	Max    int64
+}
+
+// Eras are the eras of the IDs of a table, such as Twitter Snowflake IDs
+// issued before a migration next to the dxyflake IDs issued since, so that
+// one tool reads them all. The IDs of the eras must not overlap: dxyflake
+// IDs are far smaller than Snowflake IDs of the same time, so Max of the
+// dxyflake era is the ID of the migration and Min of the Snowflake era the
+// first Snowflake ID.
+type Eras []Era
+
+// Era returns the first era holding id, and false if none does.
+func (e Eras) Era(id int64) (Era, bool) {
+	for _, era := range e {
+		if id >= era.Min && id <= era.Max {
+			return era, true
+		}
+	}
+	return Era{}, false
+}
+
+// Decompose returns the name of the era of id and its parts, or
+// ErrUnknownEra.
+func (e Eras) Decompose(id int64) (string, Parts, error) {
+	era, ok := e.Era(id)
+	if !ok {
+		return "", Parts{}, ErrUnknownEra
+	}
+	p, err := era.Layout.Decompose(id)
+	return era.Name, p, err
+}
@@ -0,0 +1,78 @@
+package convert
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func sonyflakeID(t time.Time, machineID, sequence int64) int64 {
+	tick := int64(t.Sub(Sonyflake.Epoch) / (10 * time.Millisecond))
+	return tick<<24 | sequence<<16 | machineID
+}
+
+func TestDecompose(t *testing.T) {
+	at := time.Date(2022, 3, 4, 5, 6, 7, 80000000, time.UTC)
+
+	p, err := Sonyflake.Decompose(sonyflakeID(at, 0xbeef, 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Time.Equal(at) || p.Node != 0xbeef || p.Sequence != 42 {
+		t.Errorf("Sonyflake parts %+v", p)
+	}
+	p, err = Twitter.Decompose(twitterID(at, 3<<5|7, 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Time.Equal(at) || p.Node != 3<<5|7 || p.Sequence != 42 {
+		t.Errorf("Twitter parts %+v", p)
+	}
+	if _, err := Twitter.Decompose(-1); !errors.Is(err, ErrNegative) {
+		t.Errorf("negative id: %v", err)
+	}
+
+	// Sonyflake IDs convert with the node as the machine ID
+	c, err := NewConverter(Sonyflake, time.Time{}, func(node int64) (uint16, uint16, error) {
+		return uint16(node % 32), 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.Convert(sonyflakeID(at, 7, 42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := dxyflake.DefaultLayout.Compose(at, 7, 1, 42)
+	if id != want {
+		t.Errorf("converted %d, want %d", id, want)
+	}
+}
+
+func TestEras(t *testing.T) {
+	at := time.Date(2022, 3, 4, 5, 6, 7, 80000000, time.UTC)
+	migration := dxyflake.DefaultLayout.MaxIDAt(at)
+	eras := Eras{
+		{"dxyflake", FromDxyflake(dxyflake.DefaultLayout), 0, int64(migration)},
+		{"snowflake", Twitter, twitterID(dxyflake.DefaultStartTime, 0, 0), math.MaxInt64},
+	}
+
+	old := twitterID(at.Add(-time.Hour), 5, 1)
+	if name, p, err := eras.Decompose(old); err != nil || name != "snowflake" || !p.Time.Equal(at.Add(-time.Hour)) {
+		t.Errorf("snowflake ID: %s %+v %v", name, p, err)
+	}
+	id, _ := dxyflake.DefaultLayout.Compose(at, 3, 7, 42)
+	name, p, err := eras.Decompose(int64(id))
+	if err != nil || name != "dxyflake" {
+		t.Fatalf("dxyflake ID: %s %v", name, err)
+	}
+	if !p.Time.Equal(at) || p.Node != 3<<dxyflake.BitLenServiceID|7 || p.Sequence != 42 {
+		t.Errorf("dxyflake parts %+v", p)
+	}
+	if _, _, err := eras.Decompose(int64(migration) + 1); !errors.Is(err, ErrUnknownEra) {
+		t.Errorf("ID between eras: %v", err)
+	}
+}
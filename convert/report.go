@@ -0,0 +1,103 @@
+package convert
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Result is the outcome of converting one foreign ID.
+type Result struct {
+	Source int64
+	ID     dxyflake.ID
+	Err    error
+}
+
+// Report summarizes the conversion of a set of foreign IDs.
+type Report struct {
+	Total     int
+	Converted int
+	Failed    map[string]int // number of failures per error message
+	Results   []Result
+}
+
+// Rejected returns the results that could not be converted.
+func (r *Report) Rejected() []Result {
+	var rejected []Result
+	for _, res := range r.Results {
+		if res.Err != nil {
+			rejected = append(rejected, res)
+		}
+	}
+	return rejected
+}
+
+// WriteTo writes a human readable summary of the report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	write := func(format string, a ...interface{}) error {
+		m, err := fmt.Fprintf(w, format, a...)
+		n += int64(m)
+		return err
+	}
+
+	if err := write("total: %d\nconverted: %d\nrejected: %d\n", r.Total, r.Converted, r.Total-r.Converted); err != nil {
+		return n, err
+	}
+
+	reasons := make([]string, 0, len(r.Failed))
+	for reason := range r.Failed {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		if err := write("  %s: %d\n", reason, r.Failed[reason]); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// ConvertAll converts every foreign ID and reports the outcome.
+func (c *Converter) ConvertAll(src []int64) *Report {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.run(src, c.seen)
+}
+
+// DryRun reports what ConvertAll would do without recording the converted
+// IDs, so a later Convert or ConvertAll does not see them as collisions.
+func (c *Converter) DryRun(src []int64) *Report {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var seen map[dxyflake.ID]int64
+	if c.lossy {
+		seen = make(map[dxyflake.ID]int64, len(c.seen)+len(src))
+		for id, s := range c.seen {
+			seen[id] = s
+		}
+	}
+	return c.run(src, seen)
+}
+
+func (c *Converter) run(src []int64, seen map[dxyflake.ID]int64) *Report {
+	r := &Report{
+		Total:   len(src),
+		Failed:  make(map[string]int),
+		Results: make([]Result, 0, len(src)),
+	}
+	for _, s := range src {
+		id, err := c.convert(s, seen)
+		if err != nil {
+			r.Failed[err.Error()]++
+		} else {
+			r.Converted++
+		}
+		r.Results = append(r.Results, Result{Source: s, ID: id, Err: err})
+	}
+	return r
+}
@@ -0,0 +1,163 @@
+// Package coordinator assigns machine IDs to generators by leasing them
+// from a shared lease.Backend, so that no two running generators share a
+// machine ID.
+//
+// A Coordinator tries the machine IDs of its pool in turn and keeps the
+// first one it can lease; the lease is renewed in the background. A Claim
+// plugs into the dxyflake Settings:
+//
+//	c := coordinator.New(backend, coordinator.Options{})
+//	claim, err := c.Claim(ctx)
+//	...
+//	defer claim.Release(ctx)
+//	st.MachineID = claim.Provide
+//	st.CheckMachineID = claim.Check
+//
+// Close the generator before releasing the claim, so that it issues no ID
+// once another generator may lease the machine ID.
+//
+// A Pool claims several machine IDs and issues IDs with all of them, so
+// that replicas of an ID server partition the machine ID space.
+//
+// lease.NewRedisBackend and lease.NewEtcdBackend lease machine IDs in Redis
+// and etcd; lease.NewMemoryBackend serves tests and local development with
+// the same code paths as a networked backend.
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+// ErrNoFreeMachineID is returned by Claim when every machine ID of the
+// pool is leased by another holder.
+var ErrNoFreeMachineID = errors.New("no free machine id")
+
+// DefaultPrefix is the key prefix used when Options.Prefix is empty.
+const DefaultPrefix = "dxyflake"
+
+// Options configures a Coordinator:
+//
+// Prefix is the prefix of the lease keys, "<prefix>/machine-id/<id>", which
+// separates deployments sharing a backend.
+// If Prefix is empty, DefaultPrefix is used.
+//
+// Holder names the claimer in the backend.
+// If Holder is empty, lease.DefaultHolder() is used.
+//
+// MachineIDs is the pool of machine IDs to claim from, in order of
+// preference. If MachineIDs is empty, all machine IDs are used.
+//
+// Lease configures the leases of the claims.
+type Options struct {
+	Prefix     string
+	Holder     string
+	MachineIDs []uint16
+	Lease      lease.Options
+}
+
+// Coordinator claims machine IDs from a lease.Backend.
+type Coordinator struct {
+	backend lease.Backend
+	opts    Options
+
+	mutex   sync.Mutex
+	claimed map[uint16]bool // by this coordinator, not yet released
+}
+
+// New returns a Coordinator claiming machine IDs from b.
+func New(b lease.Backend, opts Options) *Coordinator {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.Holder == "" {
+		opts.Holder = lease.DefaultHolder()
+	}
+	if len(opts.MachineIDs) == 0 {
+		for id := uint16(0); id < 1<<dxyflake.BitLenMachineID; id++ {
+			opts.MachineIDs = append(opts.MachineIDs, id)
+		}
+	}
+	return &Coordinator{backend: b, opts: opts, claimed: make(map[uint16]bool)}
+}
+
+// Key returns the lease key of machineID.
+func (c *Coordinator) Key(machineID uint16) string {
+	return fmt.Sprintf("%s/machine-id/%d", c.opts.Prefix, machineID)
+}
+
+// Claim leases the first free machine ID of the pool that this
+// Coordinator has not claimed yet. Errors of the backend other than
+// lease.ErrHeld are returned at once.
+func (c *Coordinator) Claim(ctx context.Context) (*Claim, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, id := range c.opts.MachineIDs {
+		if c.claimed[id] {
+			continue
+		}
+		l, err := lease.Acquire(ctx, c.backend, c.Key(id), c.opts.Holder, c.opts.Lease)
+		if errors.Is(err, lease.ErrHeld) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.claimed[id] = true
+		return &Claim{c: c, id: id, lease: l}, nil
+	}
+	return nil, ErrNoFreeMachineID
+}
+
+// Claim is a leased machine ID.
+type Claim struct {
+	c     *Coordinator
+	id    uint16
+	lease *lease.Lease
+}
+
+// MachineID returns the claimed machine ID.
+func (c *Claim) MachineID() uint16 {
+	return c.id
+}
+
+// Lease returns the lease of the claim.
+func (c *Claim) Lease() *lease.Lease {
+	return c.lease
+}
+
+// Provide returns the machine ID while the lease is held, for
+// Settings.MachineID.
+func (c *Claim) Provide() (uint16, error) {
+	if err := c.lease.Err(); err != nil {
+		return 0, err
+	}
+	return c.id, nil
+}
+
+// Check reports whether id is the claimed machine ID and its lease is
+// held, for Settings.CheckMachineID.
+func (c *Claim) Check(id uint16) bool {
+	return id == c.id && c.lease.Valid()
+}
+
+// Valid reports whether the lease is still held, so that a Claim can be
+// attached to a client.Hybrid.
+func (c *Claim) Valid() bool {
+	return c.lease.Valid()
+}
+
+// Release gives the machine ID back.
+func (c *Claim) Release(ctx context.Context) error {
+	err := c.lease.Release(ctx)
+	c.c.mutex.Lock()
+	delete(c.c.claimed, c.id)
+	c.c.mutex.Unlock()
+	return err
+}
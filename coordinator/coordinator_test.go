@@ -0,0 +1,89 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+func TestClaim(t *testing.T) {
+	b := lease.NewMemoryBackend()
+	ctx := context.Background()
+	opts := Options{MachineIDs: []uint16{3, 4}, Lease: lease.Options{TTL: 30 * time.Millisecond}}
+
+	a := New(b, Options{Holder: "a", MachineIDs: opts.MachineIDs, Lease: opts.Lease})
+	first, err := a.Claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(b, Options{Holder: "b", MachineIDs: opts.MachineIDs, Lease: opts.Lease}).Claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.MachineID() != 3 || second.MachineID() != 4 {
+		t.Errorf("unexpected machine ids: %d, %d", first.MachineID(), second.MachineID())
+	}
+	if _, err := New(b, Options{Holder: "c", MachineIDs: opts.MachineIDs}).Claim(ctx); err != ErrNoFreeMachineID {
+		t.Errorf("Claim() error = %v, want %v", err, ErrNoFreeMachineID)
+	}
+
+	// claims are renewed
+	time.Sleep(100 * time.Millisecond)
+	if holder, _, _ := b.Holder(ctx, a.Key(3)); holder != "a" || !first.Valid() {
+		t.Errorf("claim not renewed: %q, %v", holder, first.Lease().Err())
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	third, err := New(b, Options{Holder: "c", MachineIDs: opts.MachineIDs}).Claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.MachineID() != 3 {
+		t.Errorf("released machine id not reclaimed: %d", third.MachineID())
+	}
+	third.Release(ctx)
+	second.Release(ctx)
+}
+
+func TestClaimSettings(t *testing.T) {
+	b := lease.NewMemoryBackend()
+	c := New(b, Options{Prefix: "test", MachineIDs: []uint16{7}, Lease: lease.Options{TTL: 30 * time.Millisecond}})
+	claim, err := c.Claim(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var st dxyflake.Settings
+	st.Init(0, 1)
+	st.MachineID = claim.Provide
+	st.CheckMachineID = claim.Check
+	df := dxyflake.NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := dxyflake.Decompose(id); parts["machine-id"] != 7 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+	if claim.Check(8) {
+		t.Error("foreign machine id accepted")
+	}
+
+	// a lost lease invalidates the claim
+	b.Revoke(c.Key(7))
+	<-claim.Lease().Done()
+	if claim.Check(7) {
+		t.Error("machine id accepted after the lease was lost")
+	}
+	if _, err := claim.Provide(); err != lease.ErrExpired {
+		t.Errorf("Provide() error = %v, want %v", err, lease.ErrExpired)
+	}
+}
@@ -0,0 +1,131 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+// ErrNoGenerator is returned by NewPool when a generator cannot be created
+// from the settings.
+var ErrNoGenerator = errors.New("cannot create generator")
+
+// Pool is a generator issuing IDs with every machine ID it claimed, in
+// turn. Replicas of an ID server each run a Pool on a shared Coordinator:
+// the machine ID space is partitioned among them without any further
+// coordination, and a replica claiming n machine IDs can issue n times the
+// IDs of one generator, see Describe.
+type Pool struct {
+	claims []*Claim
+	gens   []dxyflake.Generator
+	next   uint32
+	closed uint32 // atomic
+}
+
+// NewPool claims n machine IDs and creates a generator for each with st,
+// whose MachineID is replaced by the claimed one. If n is less than 1, one
+// machine ID is claimed. The claims made so far are released if one fails.
+func (c *Coordinator) NewPool(ctx context.Context, st dxyflake.Settings, n int) (*Pool, error) {
+	if n < 1 {
+		n = 1
+	}
+	p := new(Pool)
+	for i := 0; i < n; i++ {
+		claim, err := c.Claim(ctx)
+		if err != nil {
+			p.Release(ctx)
+			return nil, err
+		}
+		p.claims = append(p.claims, claim)
+
+		gst := st
+		gst.MachineID = claim.Provide
+		gst.CheckMachineID = claim.Check
+		if st.CheckMachineID != nil {
+			gst.CheckMachineID = func(id uint16) bool {
+				return claim.Check(id) && st.CheckMachineID(id)
+			}
+		}
+		gen := dxyflake.NewDxyflake(gst)
+		if gen == nil {
+			p.Release(ctx)
+			return nil, ErrNoGenerator
+		}
+		p.gens = append(p.gens, gen)
+	}
+	return p, nil
+}
+
+// Claims returns the claims of the pool.
+func (p *Pool) Claims() []*Claim {
+	return p.claims
+}
+
+// NextID returns an ID of the next generator whose claim is held. It
+// returns lease.ErrExpired if none is, and dxyflake.ErrClosed once the pool
+// is closed.
+func (p *Pool) NextID() (dxyflake.ID, error) {
+	if atomic.LoadUint32(&p.closed) != 0 {
+		return 0, dxyflake.ErrClosed
+	}
+	start := int(atomic.AddUint32(&p.next, 1))
+	err := lease.ErrExpired
+	for i := range p.gens {
+		j := (start + i) % len(p.gens)
+		if !p.claims[j].Valid() {
+			continue
+		}
+		var id dxyflake.ID
+		if id, err = p.gens[j].NextID(); err == nil {
+			return id, nil
+		}
+	}
+	return 0, err
+}
+
+// Describe returns the Description of the generators of the pool, without
+// a machine ID and with the capacity of those whose claims are held.
+func (p *Pool) Describe() dxyflake.Description {
+	var d dxyflake.Description
+	var capacity int64
+	for i, gen := range p.gens {
+		g, ok := gen.(interface{ Describe() dxyflake.Description })
+		if !ok {
+			continue
+		}
+		d = g.Describe()
+		if p.claims[i].Valid() {
+			capacity += d.Capacity
+		}
+	}
+	d.MachineID = nil
+	d.Capacity = capacity
+	return d
+}
+
+// Close closes the generators of the pool and then gives their machine IDs
+// back, so that no ID is issued with a machine ID another replica may
+// already hold. Every later NextID returns dxyflake.ErrClosed.
+func (p *Pool) Close(ctx context.Context) error {
+	atomic.StoreUint32(&p.closed, 1)
+	for _, gen := range p.gens {
+		if c, ok := gen.(interface{ Close() error }); ok {
+			c.Close()
+		}
+	}
+	return p.Release(ctx)
+}
+
+// Release gives all machine IDs of the pool back.
+func (p *Pool) Release(ctx context.Context) error {
+	var first error
+	for _, claim := range p.claims {
+		if err := claim.Release(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,83 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+func TestPool(t *testing.T) {
+	b := lease.NewMemoryBackend()
+	ctx := context.Background()
+	c := New(b, Options{MachineIDs: []uint16{1, 2, 3}, Lease: lease.Options{TTL: 30 * time.Millisecond}})
+
+	var st dxyflake.Settings
+	st.Init(0, 4)
+	p, err := c.NewPool(ctx, st, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Release(ctx)
+
+	seen := make(map[int64]int)
+	for i := 0; i < 10; i++ {
+		id, err := p.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[dxyflake.Decompose(id)["machine-id"]]++
+	}
+	if len(seen) != 2 || seen[1] != 5 || seen[2] != 5 {
+		t.Errorf("unexpected spread over machine ids: %v", seen)
+	}
+	if d := p.Describe(); d.Capacity != 2*dxyflake.DefaultLayout.Capacity() || d.MachineID != nil || *d.ServiceID != 4 {
+		t.Errorf("unexpected description: %+v", d)
+	}
+
+	// another replica cannot claim more than is left
+	if _, err := New(b, Options{Holder: "other", MachineIDs: []uint16{1, 2, 3}}).NewPool(ctx, st, 2); err != ErrNoFreeMachineID {
+		t.Errorf("NewPool() error = %v, want %v", err, ErrNoFreeMachineID)
+	}
+	if _, ok, _ := b.Holder(ctx, c.Key(3)); ok {
+		t.Error("claim of a failed pool not released")
+	}
+
+	// a lost claim takes its generator out of the pool
+	b.Revoke(c.Key(1))
+	<-p.Claims()[0].Lease().Done()
+	for i := 0; i < 4; i++ {
+		id, err := p.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := dxyflake.Decompose(id)["machine-id"]; m != 2 {
+			t.Errorf("ID issued with lost machine id %d", m)
+		}
+	}
+	if d := p.Describe(); d.Capacity != dxyflake.DefaultLayout.Capacity() {
+		t.Errorf("unexpected capacity: %d", d.Capacity)
+	}
+	b.Revoke(c.Key(2))
+	<-p.Claims()[1].Lease().Done()
+	if _, err := p.NextID(); err != lease.ErrExpired {
+		t.Errorf("NextID() error = %v, want %v", err, lease.ErrExpired)
+	}
+
+	// a closed pool issues no ID and gives its machine IDs back
+	q, err := c.NewPool(ctx, st, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(ctx); err != nil {
+		t.Error(err)
+	}
+	if _, err := q.NextID(); err != dxyflake.ErrClosed {
+		t.Errorf("NextID() after Close = %v", err)
+	}
+	if _, ok, _ := b.Holder(ctx, c.Key(3)); ok {
+		t.Error("machine id held after Close")
+	}
+}
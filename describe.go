@@ -0,0 +1,115 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ErrIncompatible is returned by Description.Compatible for IDs of
+// different layouts.
+var ErrIncompatible = errors.New("incompatible id layouts")
+
+// modulePath is the path of the dxyflake module, to find its version in
+// the build information.
+const modulePath = "github.com/GiterLab/dxyflake"
+
+// Description describes a generator or the IDs of a layout for other
+// services, so that they can check that they read IDs the same way before
+// exchanging them, see Compatible. Version, Commit and GoVersion come from
+// the BuildInfo of the program. The machine ID, service ID, namespace
+// and capacity are only set for generators. Capacity is the number of IDs
+// per second the generator can issue at most, for load balancers spreading
+// requests across ID servers.
+type Description struct {
+	Version         string    `json:"version"`
+	Commit          string    `json:"commit,omitempty"`
+	GoVersion       string    `json:"go-version,omitempty"`
+	Epoch           time.Time `json:"epoch"`
+	Unit            string    `json:"unit"`
+	BitLenTime      int       `json:"bit-len-time"`
+	BitLenMachineID int       `json:"bit-len-machine-id"`
+	BitLenServiceID int       `json:"bit-len-service-id"`
+	BitLenSequence  int       `json:"bit-len-sequence"`
+	BitLenRegionID  int       `json:"bit-len-region-id,omitempty"`
+	MachineID       *uint16   `json:"machine-id,omitempty"`
+	ServiceID       *uint16   `json:"service-id,omitempty"`
+	Namespace       string    `json:"namespace,omitempty"`
+	Capacity        int64     `json:"capacity,omitempty"`
+}
+
+// Describe returns the Description of the layout.
+func (l Layout) Describe() Description {
+	b := Build()
+	return Description{
+		Version:         b.Version,
+		Commit:          b.Commit,
+		GoVersion:       b.GoVersion,
+		Epoch:           l.epoch().UTC(),
+		Unit:            l.Unit.String(),
+		BitLenTime:      l.BitLenTime,
+		BitLenMachineID: l.BitLenMachineID,
+		BitLenServiceID: l.BitLenServiceID,
+		BitLenSequence:  l.BitLenSequence,
+		BitLenRegionID:  l.BitLenRegionID,
+	}
+}
+
+// Describe returns the Description of the dxyflake.
+func (df *dxyflake) Describe() Description {
+	d := df.layout.Describe()
+	machineID, serviceID := df.machineID, df.serviceID
+	d.MachineID, d.ServiceID = &machineID, &serviceID
+	d.Namespace = df.Namespace()
+	d.Capacity = df.layout.Capacity()
+	return d
+}
+
+// Compatible returns ErrIncompatible, naming the differences, if IDs of
+// the two descriptions are not read the same way: their epochs, units or
+// bit lengths differ. Versions and node IDs do not matter.
+func (d Description) Compatible(o Description) error {
+	var diffs []string
+	if !d.Epoch.Equal(o.Epoch) {
+		diffs = append(diffs, fmt.Sprintf("epoch %s != %s", d.Epoch.Format(time.RFC3339Nano), o.Epoch.Format(time.RFC3339Nano)))
+	}
+	if d.Unit != o.Unit {
+		diffs = append(diffs, fmt.Sprintf("unit %s != %s", d.Unit, o.Unit))
+	}
+	if d.BitLenTime != o.BitLenTime || d.BitLenMachineID != o.BitLenMachineID ||
+		d.BitLenServiceID != o.BitLenServiceID || d.BitLenSequence != o.BitLenSequence {
+		diffs = append(diffs, fmt.Sprintf("bits %d/%d/%d/%d != %d/%d/%d/%d",
+			d.BitLenTime, d.BitLenMachineID, d.BitLenServiceID, d.BitLenSequence,
+			o.BitLenTime, o.BitLenMachineID, o.BitLenServiceID, o.BitLenSequence))
+	}
+	if d.BitLenRegionID != o.BitLenRegionID {
+		diffs = append(diffs, fmt.Sprintf("region bits %d != %d", d.BitLenRegionID, o.BitLenRegionID))
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w: %s", ErrIncompatible, strings.Join(diffs, ", "))
+	}
+	return nil
+}
+
+// moduleVersion returns the version of the dxyflake module the program was
+// built with, or "(devel)" if it is unknown.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
@@ -0,0 +1,43 @@
+package dxyflake
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	var st Settings
+	st.Init(3, 4)
+	st.Namespace = "orders"
+	gen := NewDxyflake(st)
+
+	d := gen.Describe()
+	if !d.Epoch.Equal(DefaultStartTime) || d.Unit != "10ms" || d.BitLenTime != BitLenTime ||
+		d.BitLenSequence != BitLenSequence || d.Version == "" || d.GoVersion != runtime.Version() {
+		t.Errorf("unexpected description: %+v", d)
+	}
+	if d.MachineID == nil || *d.MachineID != 3 || d.ServiceID == nil || *d.ServiceID != 4 || d.Namespace != "orders" || d.Capacity != 409600 {
+		t.Errorf("unexpected node: %+v", d)
+	}
+
+	if err := d.Compatible(DefaultLayout.Describe()); err != nil {
+		t.Errorf("default layout incompatible: %v", err)
+	}
+	other := DefaultLayout
+	other.Epoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := d.Compatible(other.Describe()); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("other epoch: got %v", err)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	b := Build()
+	if b.Version == "" || b.GoVersion != runtime.Version() {
+		t.Errorf("unexpected build info: %+v", b)
+	}
+	if b.Commit != DefaultLayout.Describe().Commit {
+		t.Errorf("commit not described: %+v", b)
+	}
+}
@@ -0,0 +1,70 @@
+package dxyflake
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrDuplicate is returned by NewDxyflakeWithError when another live
+// dxyflake of the process has the same machine ID, service ID and start
+// time, and either of them is exclusive, see Settings.Exclusive.
+var ErrDuplicate = errors.New("duplicate machine id and service id in process")
+
+// pairKey identifies the ID space of a dxyflake.
+type pairKey struct {
+	machineID uint16
+	serviceID uint16
+	startTime int64
+}
+
+type pair struct {
+	live      int
+	exclusive bool
+}
+
+// pairs holds the live dxyflakes of the process by their ID space.
+var pairs = struct {
+	sync.Mutex
+	m map[pairKey]*pair
+}{m: make(map[pairKey]*pair)}
+
+// register records df as live, and returns ErrDuplicate if it may not
+// share its ID space with a live dxyflake. df stays live until it is
+// drained or garbage collected.
+func (df *dxyflake) register(exclusive bool) error {
+	key := pairKey{df.machineID, df.serviceID, df.startTime}
+
+	pairs.Lock()
+	defer pairs.Unlock()
+	p := pairs.m[key]
+	if p != nil && (exclusive || p.exclusive) {
+		return ErrDuplicate
+	}
+	if p == nil {
+		p = &pair{exclusive: exclusive}
+		pairs.m[key] = p
+	}
+	p.live++
+	df.registered = true
+	runtime.SetFinalizer(df, (*dxyflake).release)
+	return nil
+}
+
+// unregister drops df from the live dxyflakes. It is called with df.mutex
+// held, or by the finalizer of df, see release.
+func (df *dxyflake) unregister() {
+	if !df.registered {
+		return
+	}
+	df.registered = false
+	key := pairKey{df.machineID, df.serviceID, df.startTime}
+
+	pairs.Lock()
+	defer pairs.Unlock()
+	if p := pairs.m[key]; p != nil {
+		if p.live--; p.live == 0 {
+			delete(pairs.m, key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package dxyflake
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestExclusive(t *testing.T) {
+	var st Settings
+	st.Init(29, 30)
+	st.Exclusive = true
+	first, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDxyflakeWithError(st); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second exclusive dxyflake: %v", err)
+	}
+	shared := st
+	shared.Exclusive = false
+	if _, err := NewDxyflakeWithError(shared); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("dxyflake next to an exclusive one: %v", err)
+	}
+	other := st
+	other.StartTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := NewDxyflakeWithError(other); err != nil {
+		t.Errorf("dxyflake of another epoch: %v", err)
+	}
+
+	first.Drain()
+	first.Drain()
+	second, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatalf("dxyflake after drain: %v", err)
+	}
+	runtime.KeepAlive(second)
+
+	// dropped dxyflakes are released once garbage collected
+	second = nil
+	var third *dxyflake
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		if third, err = NewDxyflakeWithError(st); !errors.Is(err, ErrDuplicate) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("dxyflake after garbage collection: %v", err)
+	}
+	third.Drain()
+
+	// duplicates are allowed unless one of them is exclusive
+	shared.Init(28, 30)
+	a, b := NewDxyflake(shared), NewDxyflake(shared)
+	if a == nil || b == nil {
+		t.Error("duplicate dxyflakes refused")
+	}
+	runtime.KeepAlive(a)
+	runtime.KeepAlive(b)
+}
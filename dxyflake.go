@@ -11,8 +11,14 @@
 package dxyflake
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,11 +30,22 @@ const (
 	BitLenSequence  = 12 // bit length of sequence number
 )
 
+// DefaultStartTime is the start time used when Settings.StartTime is 0.
+var DefaultStartTime = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
 // Settings configures dxyflake:
 //
 // StartTime is the time since which the dxyflake time is defined as the elapsed time.
 // If StartTime is 0, the start time of the dxyflake is set to "2021-10-01 00:00:00 +0000 UTC".
-// If StartTime is ahead of the current time, dxyflake is not created.
+// If StartTime is ahead of the current time by more than StartTimeTolerance,
+// dxyflake is not created.
+//
+// StartTimeTolerance is how far StartTime may be ahead of the current time,
+// as when the clock of a freshly booted machine is slightly behind the one
+// that wrote the configuration. Within it, NewDxyflake logs a warning and
+// waits with Waiter until the first time unit after StartTime has begun.
+//
+// Logf logs warnings. If Logf is nil, log.Printf is used.
 //
 // MachineID returns the unique ID of the dxyflake instance.
 // If MachineID returns an error, dxyflake is not created.
@@ -45,12 +62,152 @@ const (
 // CheckServiceID validates the uniqueness of the service ID.
 // If CheckServiceID returns false, dxyflake is not created.
 // If CheckServiceID is nil, no validation is done.
+// Both can be replaced on the live dxyflake, see SetValidators.
+//
+// Clock is the time source of the dxyflake.
+// If Clock is nil, SystemClock is used.
+//
+// Waiter waits for the next time unit when the sequence numbers of one are
+// used up. If Waiter is nil, SleepWaiter is used.
+//
+// Namespace is the human-readable name of the service of the dxyflake.
+// If Namespace is "", it is looked up by the service ID in ServiceNames.
+//
+// ServiceNames names the service IDs in debug output.
+//
+// BitsTime, BitsMachineID, BitsServiceID and BitsSequence are the bit
+// lengths of the ID parts. If they are all 0, BitLenTime, BitLenMachineID,
+// BitLenServiceID and BitLenSequence are used. Otherwise they must sum to
+// 63 bits, or to JSSafeBits for IDs that are exact JavaScript numbers, see
+// InitJSSafe, with BitsTime above 0 and the others at most 16 bits, or
+// dxyflake is not created.
+//
+// Exclusive refuses to create the dxyflake while another live dxyflake of
+// the process has the same machine ID, service ID and start time, and
+// refuses to create such a dxyflake while this one is live. A dxyflake is
+// live until it is drained, closed or garbage collected, so drain a
+// dxyflake before replacing it. If Exclusive is false, duplicates are only refused
+// next to exclusive dxyflakes.
+//
+// CheckClock validates the clock before any ID is issued, e.g. against
+// reference clocks, see clock.Gate and clock.NTP.
+// If CheckClock returns an error, dxyflake is not created.
+// If CheckClock is nil, no validation is done.
+//
+// CheckClockInterval, if positive, runs CheckClock again at that interval
+// until the dxyflake is drained or closed. While the latest run failed, NextID
+// returns ErrClockCheck, unless ClockAlert is set: then the failure is
+// passed to ClockAlert, e.g. to page someone, and IDs keep coming.
+//
+// Environment marks the IDs of the dxyflake, see Environment. In EnvTest,
+// the service ID is the test service ID of the layout, whatever ServiceID
+// returns.
+//
+// Rand is the source of randomness of the randomized options, e.g. a
+// DRBG for FIPS-constrained deployments or a seeded source for
+// deterministic tests. It is only read with the dxyflake locked.
+// If Rand is nil, crypto/rand.Reader is used.
+//
+// TimeUnit is the unit of the time part of the IDs. It must divide a
+// second or be a whole number of seconds, or dxyflake is not created.
+// time.Millisecond gives the granularity of Twitter's Snowflake and ten
+// times the IDs per second, at the cost of a tenth of the lifespan: 41
+// bits of milliseconds last 69 years.
+// If TimeUnit is 0, 10 msec is used.
+//
+// Metrics is notified of every ID issued and every wait, see Metrics.
+// If Metrics is nil, only Stats keeps count.
+//
+// ClockBack is what the dxyflake does when its clock moves back, see
+// ClockBackPolicy. If ClockBack is ClockBackContinue, IDs keep coming from
+// the time unit of the last ID, and NextID waits for the clock once its
+// sequence numbers are used up.
+//
+// ClockBackTolerance is how far the clock may move back before ClockBack
+// applies. Within it, the dxyflake continues as under ClockBackContinue.
+//
+// Exhaustion is what NextID does when the sequence numbers of a time unit
+// are used up, see ExhaustionPolicy. Under ExhaustionSleep, the default, it
+// waits for the next time unit with Waiter, holding the lock of the
+// dxyflake; under ExhaustionSpin it busy-waits instead, whatever Waiter is.
+//
+// MaxBorrow is how far ahead of the clock the time units of IDs issued
+// under ExhaustionBorrow may begin. Beyond it, NextID waits as under
+// ExhaustionSleep. Borrowed IDs carry a time ahead of their issuing; a
+// generator replacing the dxyflake with the same machine ID and service ID
+// must start after the time of its last ID, see Drain.
+//
+// RandomSequence starts the sequence of every time unit at a random number
+// in the lower half of the sequence numbers instead of at 0, so that the
+// low bits of the IDs of a lightly loaded dxyflake spread evenly, e.g. for
+// sharding by id % n. IDs stay unique and increasing, but a time unit
+// holds as few as half as many IDs.
+//
+// Transform post-processes every ID before NextID, TryNextID and NextIDs
+// return it, e.g. AppendCheckDigit, see Chain. Decompose and the other
+// methods reading IDs expect them untransformed. If Transform returns an
+// error, NextID returns it and the sequence number of the ID is spent.
+// If Transform is nil, IDs are returned as issued.
+//
+// ExpiryAlert, if set, is passed the time left until the time part of the
+// IDs overflows, see ExpiresAt, once the dxyflake is created or issues an
+// ID within ExpiryWarning of it, e.g. to page someone years before NextID
+// fails. It is called once, in a goroutine of its own.
+// If ExpiryWarning is 0, DefaultExpiryWarning is used.
+//
+// BitsRegion splits the machine ID into a region ID and a node ID, see
+// Layout.BitLenRegionID, so that regions issue unique IDs without
+// coordinating with each other. MachineID then returns the node ID, which
+// must fit the remaining machine ID bits, and RegionID the region ID; see
+// InitRegion. CheckMachineID is passed the whole machine ID.
+// If RegionID is nil, region 0 is used.
+//
+// RateLimit caps the IDs issued to RateLimit per second, e.g. to protect
+// a downstream system consuming them, with bursts of up to RateBurst IDs
+// after idle time. RateLimited is what NextID does beyond it, see
+// RateLimitPolicy: under RateLimitWait, the default, it waits with Waiter,
+// holding the lock of the dxyflake. TryNextID returns ErrRateLimited
+// instead of waiting. Calls failing before taking a sequence number, e.g.
+// with ErrSequenceExhausted, do not count against the limit. If RateLimit is
+// 0, IDs are not limited.
+// If RateBurst is 0, a second's worth of IDs is used, at least 1.
 type Settings struct {
-	StartTime      time.Time
-	MachineID      func() (uint16, error)
-	ServiceID      func() (uint16, error)
-	CheckMachineID func(uint16) bool
-	CheckServiceID func(uint16) bool
+	StartTime          time.Time
+	StartTimeTolerance time.Duration
+	Logf               func(format string, args ...interface{})
+	MachineID          func() (uint16, error)
+	ServiceID          func() (uint16, error)
+	CheckMachineID     func(uint16) bool
+	CheckServiceID     func(uint16) bool
+	Clock              Clock
+	Waiter             Waiter
+	Namespace          string
+	ServiceNames       ServiceNames
+	BitsTime           int
+	BitsMachineID      int
+	BitsServiceID      int
+	BitsSequence       int
+	Exclusive          bool
+	Environment        Environment
+	CheckClock         func() error
+	Rand               io.Reader
+	ClockBack          ClockBackPolicy
+	ClockBackTolerance time.Duration
+	TimeUnit           time.Duration
+	Metrics            Metrics
+	Exhaustion         ExhaustionPolicy
+	MaxBorrow          time.Duration
+	RandomSequence     bool
+	CheckClockInterval time.Duration
+	ClockAlert         func(error)
+	Transform          Transform
+	ExpiryWarning      time.Duration
+	ExpiryAlert        func(remaining time.Duration)
+	RegionID           func() (uint16, error)
+	BitsRegion         int
+	RateLimit          float64
+	RateBurst          int
+	RateLimited        RateLimitPolicy
 }
 
 // Init set default MachineID & ServiceID
@@ -65,6 +222,68 @@ func (s *Settings) Init(mID, sID uint16) {
 	}
 }
 
+// These errors are returned by Settings.InitChecked and
+// NewDxyflakeWithError for IDs that do not fit their bit length.
+var (
+	ErrInvalidMachineID = errors.New("machine id out of range")
+	ErrInvalidServiceID = errors.New("service id out of range")
+)
+
+// InitChecked is like Init but returns an error if mID or sID does not fit
+// the machine ID or service ID bit length of s, BitLenMachineID and
+// BitLenServiceID by default. It also sets CheckMachineID and
+// CheckServiceID, if they are nil, to the same range checks.
+func (s *Settings) InitChecked(mID, sID uint16) error {
+	l, _ := s.bits()
+	validMachineID := func(id uint16) bool { return id < 1<<uint(l.BitLenMachineID) }
+	validServiceID := func(id uint16) bool { return id < 1<<uint(l.BitLenServiceID) }
+	if !validMachineID(mID) {
+		return ErrInvalidMachineID
+	}
+	if !validServiceID(sID) {
+		return ErrInvalidServiceID
+	}
+	s.Init(mID, sID)
+	if s.CheckMachineID == nil {
+		s.CheckMachineID = validMachineID
+	}
+	if s.CheckServiceID == nil {
+		s.CheckServiceID = validServiceID
+	}
+	return nil
+}
+
+// bits returns the layout of the IDs of a dxyflake created with s, and
+// false if its bit lengths are invalid.
+func (s *Settings) bits() (Layout, bool) {
+	l := layoutAt(s.StartTime)
+	if s.TimeUnit != 0 {
+		l.Unit = s.TimeUnit
+		if l.Validate() != nil {
+			return l, false
+		}
+	}
+	if s.BitsRegion < 0 {
+		return l, false
+	}
+	l.BitLenRegionID = s.BitsRegion
+	if s.BitsTime == 0 && s.BitsMachineID == 0 && s.BitsServiceID == 0 && s.BitsSequence == 0 {
+		return l, s.BitsRegion <= l.BitLenMachineID
+	}
+	width := s.BitsTime + s.BitsMachineID + s.BitsServiceID + s.BitsSequence
+	if s.BitsTime <= 0 || (width != 63 && width != JSSafeBits) ||
+		s.BitsMachineID < 0 || s.BitsMachineID > 16 ||
+		s.BitsServiceID < 0 || s.BitsServiceID > 16 ||
+		s.BitsSequence < 0 || s.BitsSequence > 16 || s.BitsRegion > s.BitsMachineID {
+		return l, false
+	}
+	l.BitLenTime = s.BitsTime
+	l.BitLenMachineID = s.BitsMachineID
+	l.BitLenServiceID = s.BitsServiceID
+	l.BitLenSequence = s.BitsSequence
+	return l, true
+}
+
 // StartTimeSet set start time
 func (s *Settings) StartTimeSet(t time.Time) {
 	if s != nil {
@@ -72,124 +291,503 @@ func (s *Settings) StartTimeSet(t time.Time) {
 	}
 }
 
+// Generator is implemented by anything that issues dxyflake IDs.
+type Generator interface {
+	NextID() (ID, error)
+}
+
 // dxyflake is a distributed unique ID generator.
 type dxyflake struct {
 	mutex       *sync.Mutex
 	startTime   int64
 	elapsedTime int64
+	lastTick    int64 // latest time read from the clock
 	machineID   uint16
 	serviceID   uint16
 	sequence    uint16
+	firstSeq    uint16 // sequence numbers of the dxyflake, all unless sharded
+	lastSeq     uint16
+	startSeq    uint16 // first sequence number of the current time unit
+	randomSeq   bool   // Settings.RandomSequence
+	checker     *clockChecker
+	stopped     error // ErrDrained or ErrClosed, once stopped
+	registered  bool
+	issued      uint64
+	waits       uint64
+	waitTime    time.Duration
+	highWater   uint16  // highest sequence number issued
+	pressure    float64 // moving average of the use of past time units
+	clock       Clock
+	waiter      Waiter
+	rand        io.Reader
+	metrics     Metrics
+	clockBack   ClockBackPolicy
+	clockSlack  time.Duration // Settings.ClockBackTolerance
+	exhaustion  ExhaustionPolicy
+	maxBorrow   time.Duration
+	validators  atomic.Value // validators
+	layout      Layout
+	names       ServiceNames
+	transform   Transform
+	warnTick    int64 // time unit from which expiryAlert is due
+	expiryAlert func(time.Duration)
+	limiter     *limiter // Settings.RateLimit, nil if unlimited
 }
 
 // NewDxyflake returns a new dxyflake configured with the given Settings.
 // NewDxyflake returns nil in the following cases:
-// - Settings.StartTime is ahead of the current time.
+// - The bit lengths or the time unit of Settings are invalid.
+// - Settings.StartTime is ahead of the current time by more than StartTimeTolerance.
 // - Settings.MachineID returns an error.
 // - Settings.ServiceID returns an error.
+// - Settings.RegionID returns an error.
 // - Settings.CheckMachineID returns false.
 // - Settings.CheckServiceID returns false.
+// - The machine ID, the service ID or the region ID does not fit its bit length.
+// - The service ID is reserved for tests in EnvProduction.
+// - Settings.CheckClock returns an error.
+// NewDxyflakeWithError tells these cases apart.
 func NewDxyflake(st Settings) *dxyflake {
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		return nil
+	}
+	return df
+}
+
+// ErrStartTimeAhead is returned by NewDxyflakeWithError when
+// Settings.StartTime is ahead of the current time by more than
+// StartTimeTolerance.
+var ErrStartTimeAhead = errors.New("start time ahead of the current time")
+
+// NewDxyflakeWithError is like NewDxyflake but returns the reason the
+// dxyflake is not created: ErrInvalidLayout for invalid bit lengths or an
+// invalid time unit, ErrStartTimeAhead, the error of Settings.MachineID,
+// Settings.ServiceID or Settings.RegionID, or ErrInvalidMachineID,
+// ErrInvalidServiceID or ErrInvalidRegionID for IDs that do not fit their
+// bit length or fail their check.
+func NewDxyflakeWithError(st Settings) (*dxyflake, error) {
 	df := new(dxyflake)
 	df.mutex = new(sync.Mutex)
-	df.sequence = uint16(1<<BitLenSequence - 1)
 
-	if st.StartTime.After(time.Now()) {
-		return nil
+	var ok bool
+	if df.layout, ok = st.bits(); !ok {
+		return nil, ErrInvalidLayout
 	}
-	if st.StartTime.IsZero() {
-		df.startTime = toDxyflakeTime(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC))
-	} else {
-		df.startTime = toDxyflakeTime(st.StartTime)
+	df.lastSeq = uint16(1<<uint(df.layout.BitLenSequence) - 1)
+	df.sequence = df.lastSeq
+
+	df.clock = st.Clock
+	if df.clock == nil {
+		df.clock = SystemClock
+	}
+	df.waiter = st.Waiter
+	if df.waiter == nil {
+		df.waiter = SleepWaiter
+	}
+	df.rand = st.Rand
+	if df.rand == nil {
+		df.rand = rand.Reader
+	}
+	df.metrics = st.Metrics
+	df.clockBack = st.ClockBack
+	df.clockSlack = st.ClockBackTolerance
+	df.randomSeq = st.RandomSequence
+	df.exhaustion = st.Exhaustion
+	df.maxBorrow = st.MaxBorrow
+	df.transform = st.Transform
+	df.limiter = newLimiter(st, df.clock.Now())
+	if df.exhaustion == ExhaustionSpin {
+		df.waiter = SpinWaiter{Spin: df.layout.Unit}
+	}
+
+	if ahead := st.StartTime.Sub(df.now()); ahead > 0 {
+		if ahead > st.StartTimeTolerance {
+			return nil, fmt.Errorf("%w: %v", ErrStartTimeAhead, ahead)
+		}
+		logf := st.Logf
+		if logf == nil {
+			logf = log.Printf
+		}
+		logf("dxyflake: start time %s is %v ahead of the clock, waiting for it", st.StartTime.Format(time.RFC3339Nano), ahead)
+	}
+	df.startTime = df.layout.units(df.layout.epoch())
+
+	if err := providerFault(); err != nil {
+		return nil, err
+	}
+	if st.CheckClock != nil {
+		if err := st.CheckClock(); err != nil {
+			return nil, fmt.Errorf("clock: %w", err)
+		}
 	}
 
 	var err error
-	if st.MachineID == nil {
-		df.machineID = 0
-	} else {
-		df.machineID, err = st.MachineID()
+	if st.MachineID != nil {
+		if df.machineID, err = st.MachineID(); err != nil {
+			return nil, fmt.Errorf("machine id: %w", err)
+		}
 	}
-	if st.ServiceID == nil {
-		df.serviceID = 0
-	} else {
-		df.serviceID, err = st.ServiceID()
+	if df.layout.BitLenRegionID > 0 || st.RegionID != nil {
+		if df.machineID, err = df.layout.regionMachineID(st.RegionID, df.machineID); err != nil {
+			return nil, err
+		}
+	}
+	if st.Environment == EnvTest {
+		df.serviceID = df.layout.TestServiceID()
+	} else if st.ServiceID != nil {
+		if df.serviceID, err = st.ServiceID(); err != nil {
+			return nil, fmt.Errorf("service id: %w", err)
+		}
+	}
+	if df.machineID >= 1<<uint(df.layout.BitLenMachineID) ||
+		(st.CheckMachineID != nil && !st.CheckMachineID(df.machineID)) {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMachineID, df.machineID)
 	}
-	if err != nil ||
-		(st.CheckMachineID != nil && !st.CheckMachineID(df.machineID)) ||
+	if df.serviceID >= 1<<uint(df.layout.BitLenServiceID) ||
+		(st.Environment == EnvProduction && df.serviceID == df.layout.TestServiceID()) ||
 		(st.CheckServiceID != nil && !st.CheckServiceID(df.serviceID)) {
-		return nil
+		return nil, fmt.Errorf("%w: %d", ErrInvalidServiceID, df.serviceID)
 	}
 
-	return df
+	df.validators.Store(validators{st.CheckMachineID, st.CheckServiceID})
+
+	df.names = make(ServiceNames, len(st.ServiceNames)+1)
+	for id, name := range st.ServiceNames {
+		df.names[id] = name
+	}
+	if st.Namespace != "" {
+		df.names[df.serviceID] = st.Namespace
+	}
+
+	// IDs cannot encode a time before StartTime, and a new dxyflake takes
+	// the time unit of StartTime itself as used up
+	if ahead := st.StartTime.Sub(df.now()); ahead > 0 {
+		df.waiter.Wait(df.clock, ahead+df.layout.Unit)
+	}
+
+	if err := df.register(st.Exclusive); err != nil {
+		return nil, err
+	}
+	if st.CheckClock != nil && st.CheckClockInterval > 0 {
+		df.checker = startClockChecker(st.CheckClock, st.CheckClockInterval, st.ClockAlert)
+	}
+	if st.ExpiryAlert != nil {
+		df.warnExpiry(st.ExpiryWarning, st.ExpiryAlert)
+		df.checkExpiry(df.CurrentTick())
+	}
+	return df, nil
 }
 
 // NextID generates a next unique ID.
 // After the dxyflake time overflows, NextID returns an error.
 func (df *dxyflake) NextID() (ID, error) {
-	const maskSequence = uint16(1<<BitLenSequence - 1)
+	return df.NextIDContext(context.Background())
+}
+
+// NextIDContext is like NextID, but when the sequence numbers of the time
+// unit are used up it stops waiting for the next one once ctx is done, and
+// returns ctx.Err() without issuing an ID. Waits of Settings.Waiters other
+// than SleepWaiter are not interrupted, ctx is checked after them.
+func (df *dxyflake) NextIDContext(ctx context.Context) (id ID, err error) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if df.stopped != nil {
+		return 0, df.stopped
+	}
+	if err := issueFault(); err != nil {
+		return 0, err
+	}
+	if err := df.throttle(ctx, df.limiter, 1, true); err != nil {
+		return 0, err
+	}
+	defer func() { df.limiter.refund(1, err) }()
 
+	current, err := df.tick(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+	if df.elapsedTime < current {
+		df.endTick(df.used(), current-df.elapsedTime-1)
+		df.elapsedTime = current
+		if err := df.startSequence(); err != nil {
+			return 0, err
+		}
+	} else if df.sequence != df.lastSeq { // df.elapsedTime >= current
+		df.sequence++
+	} else { // overflow
+		if df.exhaustion == ExhaustionError {
+			return 0, ErrSequenceExhausted
+		}
+		df.endTick(1, 0)
+		df.elapsedTime++
+		if err := df.startSequence(); err != nil {
+			df.elapsedTime--
+			return 0, err
+		}
+		d := df.sleepTime(df.elapsedTime-current, df.now())
+		if df.borrow(d) {
+			return df.toID()
+		}
+		df.waits++
+		if err := df.wait(ctx, d); err != nil {
+			// give the time unit back, so the next call waits again
+			df.elapsedTime--
+			df.sequence = df.lastSeq
+			return 0, err
+		}
+	}
+
+	return df.toID()
+}
+
+// ErrSequenceExhausted is returned by TryNextID, and by NextID under
+// ExhaustionError, when the sequence numbers of the current time unit are
+// used up.
+var ErrSequenceExhausted = errors.New("sequence exhausted")
+
+// TryNextID is like NextID, but returns ErrSequenceExhausted instead of
+// waiting for the next time unit when the sequence numbers of the current
+// one are used up, e.g. to fall back to another generator. Under
+// ClockBackWait it returns ErrClockMovedBack instead of waiting for the
+// clock, and beyond Settings.RateLimit it returns ErrRateLimited.
+func (df *dxyflake) TryNextID() (id ID, err error) {
 	df.mutex.Lock()
 	defer df.mutex.Unlock()
 
-	current := currentElapsedTime(df.startTime)
+	if df.stopped != nil {
+		return 0, df.stopped
+	}
+	if err := issueFault(); err != nil {
+		return 0, err
+	}
+	if err := df.throttle(context.Background(), df.limiter, 1, false); err != nil {
+		return 0, err
+	}
+	defer func() { df.limiter.refund(1, err) }()
+
+	current, err := df.tick(context.Background(), false)
+	if err != nil {
+		return 0, err
+	}
 	if df.elapsedTime < current {
+		df.endTick(df.used(), current-df.elapsedTime-1)
 		df.elapsedTime = current
-		df.sequence = 0
-	} else { // df.elapsedTime >= current
-		df.sequence = (df.sequence + 1) & maskSequence
-		if df.sequence == 0 { // overflow
-			df.elapsedTime++
-			overtime := df.elapsedTime - current
-			time.Sleep(sleepTime((overtime)))
+		if err := df.startSequence(); err != nil {
+			return 0, err
 		}
+	} else if df.sequence == df.lastSeq {
+		return 0, ErrSequenceExhausted
+	} else {
+		df.sequence++
 	}
 
 	return df.toID()
 }
 
+// wait waits for d with the Waiter of the dxyflake, or until ctx is done.
+func (df *dxyflake) wait(ctx context.Context, d time.Duration) error {
+	defer df.waited(df.clock.Now())
+	return df.sleep(ctx, d)
+}
+
+// sleep waits for d like wait, without counting it as a wait for the next
+// time unit.
+func (df *dxyflake) sleep(ctx context.Context, d time.Duration) error {
+	if ctx.Done() == nil {
+		df.waiter.Wait(df.clock, d)
+		return nil
+	}
+	if df.waiter == SleepWaiter {
+		return sleepContext(ctx, df.clock, d)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	df.waiter.Wait(df.clock, d)
+	return ctx.Err()
+}
+
+// ErrInvalidCount is returned by NextIDs for counts below 1.
+var ErrInvalidCount = errors.New("count must be positive")
+
+// NextIDs generates n consecutive IDs under a single lock, spanning as many
+// time units as n needs. It returns once the time unit of the last ID has
+// begun, so none of the IDs is from the future. NextIDs returns no IDs on
+// error. Under Settings.RateLimit, it first waits until all n IDs are due.
+func (df *dxyflake) NextIDs(n int) (ids []ID, err error) {
+	if n <= 0 {
+		return nil, ErrInvalidCount
+	}
+
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if df.stopped != nil {
+		return nil, df.stopped
+	}
+	if err := issueFault(); err != nil {
+		return nil, err
+	}
+	if err := df.throttle(context.Background(), df.limiter, n, true); err != nil {
+		return nil, err
+	}
+	defer func() { df.limiter.refund(n, err) }()
+
+	current, err := df.tick(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+	ids = make([]ID, n)
+	for i := range ids {
+		if df.elapsedTime < current {
+			df.endTick(df.used(), current-df.elapsedTime-1)
+			df.elapsedTime = current
+			err = df.startSequence()
+		} else if df.sequence != df.lastSeq {
+			df.sequence++
+		} else {
+			df.endTick(1, 0)
+			df.elapsedTime++
+			err = df.startSequence()
+		}
+		if err != nil {
+			return nil, err
+		}
+		id, err := df.toID()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	if overtime := df.elapsedTime - current; overtime > 0 {
+		df.waits++
+		df.wait(context.Background(), df.sleepTime(overtime, df.now()))
+	}
+	return ids, nil
+}
+
+// ErrDrained is returned by NextID after the dxyflake has been drained.
+var ErrDrained = errors.New("dxyflake drained")
+
+// ErrClosed is returned by NextID after the dxyflake has been closed.
+var ErrClosed = errors.New("dxyflake closed")
+
+// ErrOverTimeLimit is returned by NextID once the dxyflake time no longer
+// fits BitLenTime bits.
+var ErrOverTimeLimit = errors.New("over the time limit")
+
+// Mark is the high-water mark of a drained dxyflake: another dxyflake with
+// the same MachineID and ServiceID must not issue IDs before Next.
+type Mark struct {
+	MachineID uint16
+	ServiceID uint16
+	Next      time.Time
+}
+
+// Drain stops the dxyflake and returns its high-water mark. Every later
+// NextID returns ErrDrained, so no ID can be issued past the mark.
+func (df *dxyflake) Drain() Mark {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if df.stopped == nil {
+		df.stopped = ErrDrained
+	}
+	df.release()
+	return Mark{
+		MachineID: df.machineID,
+		ServiceID: df.serviceID,
+		Next:      df.layout.fromUnits(df.startTime + df.elapsedTime + 1),
+	}
+}
+
+// Close stops the dxyflake for good, e.g. before the lease of its machine
+// ID is released: once Close returns, no ID is being issued and every later
+// NextID returns ErrClosed. Close may be called more than once.
+func (df *dxyflake) Close() error {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	df.stopped = ErrClosed
+	df.release()
+	return nil
+}
+
+// CurrentTick returns the current dxyflake time: the number of time units
+// elapsed since the start time, by the dxyflake's clock.
+func (df *dxyflake) CurrentTick() int64 {
+	return df.elapsed(df.now())
+}
+
+// Layout returns the layout of the IDs the dxyflake issues, with its start
+// time as the epoch.
+func (df *dxyflake) Layout() Layout {
+	return df.layout
+}
+
+// Clock returns the time source of the dxyflake, see Settings.Clock.
+func (df *dxyflake) Clock() Clock {
+	return df.clock
+}
+
+// TickTime returns the time at which the dxyflake time tick begins, e.g.
+// for the "time" part of a decomposed ID.
+func (df *dxyflake) TickTime(tick int64) time.Time {
+	return df.layout.fromUnits(df.startTime + tick)
+}
+
 const dxyflakeTimeUnit = 1e7 // nsec, i.e. 10 msec
 
 func toDxyflakeTime(t time.Time) int64 {
 	return t.UTC().UnixNano() / dxyflakeTimeUnit
 }
 
-func currentElapsedTime(startTime int64) int64 {
-	return toDxyflakeTime(time.Now()) - startTime
+// elapsed returns the dxyflake time at now.
+func (df *dxyflake) elapsed(now time.Time) int64 {
+	return df.layout.units(now) - df.startTime
 }
 
-func sleepTime(overtime int64) time.Duration {
-	return time.Duration(overtime)*10*time.Millisecond -
-		time.Duration(time.Now().UTC().UnixNano()%dxyflakeTimeUnit)*time.Nanosecond
+// sleepTime returns the time from now until overtime units after the
+// beginning of the unit of now.
+func (df *dxyflake) sleepTime(overtime int64, now time.Time) time.Duration {
+	return time.Duration(overtime)*df.layout.Unit - now.Sub(df.layout.fromUnits(df.layout.units(now)))
+}
+
+// now returns the time of the dxyflake's clock, moved by any injected clock
+// jump.
+func (df *dxyflake) now() time.Time {
+	return df.clock.Now().Add(clockJump())
 }
 
 func (df *dxyflake) toID() (ID, error) {
-	if df.elapsedTime >= 1<<BitLenTime {
-		return 0, errors.New("over the time limit")
+	if df.elapsedTime >= 1<<uint(df.layout.BitLenTime) {
+		return 0, ErrOverTimeLimit
 	}
 
-	return ID(int64(df.elapsedTime)<<(BitLenMachineID+BitLenServiceID+BitLenSequence) |
-		int64(df.machineID)<<(BitLenServiceID+BitLenSequence) |
-		int64(df.serviceID)<<BitLenSequence |
-		int64(df.sequence)), nil
+	df.issued++
+	df.checkExpiry(df.elapsedTime)
+	if df.sequence > df.highWater {
+		df.highWater = df.sequence
+	}
+	if df.metrics != nil {
+		df.metrics.Issued(df.sequence)
+	}
+	id := df.layout.compose(df.elapsedTime, df.machineID, df.serviceID, df.sequence)
+	if df.transform != nil {
+		return df.transform(id)
+	}
+	return id, nil
 }
 
-// Decompose returns a set of dxyflake ID parts.
+// Decompose returns a set of dxyflake ID parts. It allocates the map on
+// every call; DecomposeParts does not.
 func Decompose(id ID) map[string]int64 {
-	const maskMachineID = int64((1<<BitLenMachineID - 1) << (BitLenServiceID + BitLenSequence))
-	const maskServiceID = int64((1<<BitLenServiceID - 1) << BitLenSequence)
-	const maskSequence = int64(1<<BitLenSequence - 1)
-
-	msb := int64(id) >> 63
-	time := int64(id) >> (BitLenMachineID + BitLenServiceID + BitLenSequence)
-	machineID := (int64(id) & maskMachineID) >> (BitLenServiceID + BitLenSequence)
-	serviceID := (int64(id) & maskServiceID) >> BitLenSequence
-	sequence := (int64(id) & maskSequence)
-	return map[string]int64{
-		"id":         int64(id),
-		"msb":        msb,
-		"time":       time,
-		"machine-id": machineID,
-		"service-id": serviceID,
-		"sequence":   sequence,
-	}
+	return DefaultLayout.Decompose(id)
+}
+
+// Decompose returns a set of the parts of an ID of the dxyflake's layout.
+func (df *dxyflake) Decompose(id ID) map[string]int64 {
+	return df.layout.Decompose(id)
 }
@@ -1,6 +1,7 @@
 package dxyflake
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"testing"
@@ -47,10 +48,25 @@ func testReset(t *testing.T) {
 }
 
 func TestDxyflakeOnce(t *testing.T) {
+	start := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	clock := &steppedClock{now: start}
+	var st Settings
+	st.Init(uint16(machineID), uint16(serviceID))
+	st.StartTime = start
+	st.Clock = clock
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
 	sleepTime := int64(50)
-	time.Sleep(time.Duration(sleepTime) * 10 * time.Millisecond)
+	clock.Sleep(time.Duration(sleepTime) * 10 * time.Millisecond)
 
-	id := nextID(t)
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal("id not generated:", err)
+	}
 	parts := Decompose(id)
 
 	actualMSB := parts["msb"]
@@ -210,18 +226,339 @@ func TestNildxyflake(t *testing.T) {
 	}
 }
 
-func pseudoSleep(period time.Duration) {
-	df.startTime -= int64(period)
+func TestNewDxyflakeWithError(t *testing.T) {
+	errNoID := errors.New("no id")
+	ok := func() (uint16, error) { return 1, nil }
+	fail := func() (uint16, error) { return 0, errNoID }
+	tests := []struct {
+		name string
+		st   Settings
+		want error
+	}{
+		{"bits", Settings{BitsTime: 40}, ErrInvalidLayout},
+		{"start time", Settings{StartTime: time.Now().Add(time.Minute)}, ErrStartTimeAhead},
+		// a valid service ID must not hide the machine ID error
+		{"machine id provider", Settings{MachineID: fail, ServiceID: ok}, errNoID},
+		{"service id provider", Settings{MachineID: ok, ServiceID: fail}, errNoID},
+		{"machine id range", Settings{MachineID: func() (uint16, error) { return 32, nil }}, ErrInvalidMachineID},
+		{"machine id check", Settings{CheckMachineID: func(uint16) bool { return false }}, ErrInvalidMachineID},
+		{"service id check", Settings{CheckServiceID: func(uint16) bool { return false }}, ErrInvalidServiceID},
+		{"clock", Settings{CheckClock: func() error { return errNoID }}, errNoID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df, err := NewDxyflakeWithError(tt.st)
+			if !errors.Is(err, tt.want) || df != nil {
+				t.Errorf("NewDxyflakeWithError() = %v, %v, want %v", df, err, tt.want)
+			}
+			if NewDxyflake(tt.st) != nil {
+				t.Error("NewDxyflake() != nil")
+			}
+		})
+	}
+
+	if df, err := NewDxyflakeWithError(Settings{}); err != nil || df == nil {
+		t.Errorf("NewDxyflakeWithError() = %v, %v", df, err)
+	}
+}
+
+func TestNextIDs(t *testing.T) {
+	var waits int
+	var st Settings
+	st.Init(1, 2)
+	st.Clock = frozenClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { waits++ })
+	gen := NewDxyflake(st)
+
+	if _, err := gen.NextIDs(0); err != ErrInvalidCount {
+		t.Errorf("unexpected error: %v", err)
+	}
+	n := 2<<BitLenSequence + 10
+	ids, err := gen.NextIDs(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != n || waits != 1 {
+		t.Fatalf("got %d IDs after %d waits", len(ids), waits)
+	}
+	for i := 1; i < n; i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("ID %d not increasing: %d <= %d", i, ids[i], ids[i-1])
+		}
+	}
+	first, last := Decompose(ids[0]), Decompose(ids[n-1])
+	if last["time"]-first["time"] != 2 || last["sequence"] != 9 {
+		t.Errorf("unexpected parts: %v %v", first, last)
+	}
+	if id, err := gen.NextID(); err != nil || id <= ids[n-1] {
+		t.Errorf("NextID() = %d, %v after %d", id, err, ids[n-1])
+	}
+
+	gen.Drain()
+	if _, err := gen.NextIDs(1); err != ErrDrained {
+		t.Errorf("unexpected error: %v", err)
+	}
 }
 
 func TestNextIDError(t *testing.T) {
-	year := time.Duration(365*24) * time.Hour / dxyflakeTimeUnit
-	pseudoSleep(time.Duration(697) * year)
-	nextID(t)
-
-	pseudoSleep(time.Duration(1) * year)
-	_, err := df.NextID()
-	if err == nil {
-		t.Errorf("time is not over")
+	// 33 bits of time run out within 3 years
+	var st Settings
+	st.Init(1, 2)
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 33, 8, 8, 14
+	c := &steppedClock{now: DefaultLayout.TickTime(1<<33 - 1)}
+	st.Clock = c
+	gen := NewDxyflake(st)
+
+	if _, err := gen.NextID(); err != nil {
+		t.Fatal(err)
+	}
+	c.Sleep(dxyflakeTimeUnit)
+	if _, err := gen.NextID(); err != ErrOverTimeLimit {
+		t.Errorf("NextID() error = %v, want %v", err, ErrOverTimeLimit)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	var st Settings
+	st.Init(3, 4)
+	d := NewDxyflake(st)
+
+	id, err := d.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mark := d.Drain()
+	if mark.MachineID != 3 || mark.ServiceID != 4 {
+		t.Errorf("unexpected mark: %+v", mark)
+	}
+	next := toDxyflakeTime(mark.Next) - d.startTime
+	if next != Decompose(id)["time"]+1 {
+		t.Errorf("unexpected mark time: %d", next)
+	}
+
+	if _, err := d.NextID(); err != ErrDrained {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var st Settings
+	st.Init(3, 5)
+	st.Exclusive = true
+	d := NewDxyflake(st)
+	if _, err := d.NextID(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	d.Drain()
+	for _, err := range []error{
+		func() error { _, err := d.NextID(); return err }(),
+		func() error { _, err := d.TryNextID(); return err }(),
+		func() error { _, err := d.NextIDs(2); return err }(),
+	} {
+		if err != ErrClosed {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	// a closed dxyflake no longer holds its ID space
+	next, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatalf("dxyflake after close: %v", err)
+	}
+	next.Close()
+}
+
+func TestInitChecked(t *testing.T) {
+	var st Settings
+	if err := st.InitChecked(32, 0); err != ErrInvalidMachineID {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := st.InitChecked(0, 32); err != ErrInvalidServiceID {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if st.MachineID != nil {
+		t.Error("settings changed on error")
+	}
+
+	if err := st.InitChecked(31, 31); err != nil {
+		t.Fatal(err)
+	}
+	if st.CheckMachineID == nil || st.CheckServiceID == nil {
+		t.Fatal("check functions not set")
+	}
+	id, err := NewDxyflake(st).NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := Decompose(id); parts["machine-id"] != 31 || parts["service-id"] != 31 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+
+	// the checks also catch providers that return invalid IDs
+	st.ServiceID = func() (uint16, error) { return 40, nil }
+	if NewDxyflake(st) != nil {
+		t.Error("dxyflake created with an invalid service id")
+	}
+}
+
+func TestSettingsBits(t *testing.T) {
+	var st Settings
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 39, 8, 4, 12
+	if err := st.InitChecked(200, 3); err != nil {
+		t.Fatal(err)
+	}
+	df := NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	if l := df.Layout(); l.BitLenTime != 39 || l.BitLenMachineID != 8 || l.BitLenServiceID != 4 || l.BitLenSequence != 12 {
+		t.Errorf("unexpected layout: %+v", l)
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := df.Decompose(id); parts["machine-id"] != 200 || parts["service-id"] != 3 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+
+	st.ServiceID = func() (uint16, error) { return 16, nil }
+	if NewDxyflake(st) != nil {
+		t.Error("dxyflake created with a service id out of its bits")
+	}
+
+	for _, bits := range [][4]int{
+		{41, 5, 5, 11}, // 62 bits
+		{0, 20, 20, 23},
+		{39, 17, 0, 7},
+		{41, -1, 6, 17},
+	} {
+		var st Settings
+		st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = bits[0], bits[1], bits[2], bits[3]
+		if NewDxyflake(st) != nil {
+			t.Errorf("dxyflake created with bits %v", bits)
+		}
+	}
+}
+
+func TestTicks(t *testing.T) {
+	var st Settings
+	st.Init(1, 1)
+	st.StartTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	gen := NewDxyflake(st)
+
+	before := gen.CurrentTick()
+	id, err := gen.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tick := Decompose(id)["time"]
+	if tick < before || tick > gen.CurrentTick() {
+		t.Errorf("unexpected tick: %d", tick)
+	}
+
+	if got := gen.TickTime(tick); time.Since(got) < 0 || time.Since(got) > time.Second {
+		t.Errorf("unexpected tick time: %s", got)
+	}
+	if got := gen.TickTime(150); !got.Equal(st.StartTime.Add(1500 * time.Millisecond)) {
+		t.Errorf("unexpected tick time: %s", got)
+	}
+}
+
+func TestStartTimeTolerance(t *testing.T) {
+	clock := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var warnings []string
+	var st Settings
+	st.Init(1, 2)
+	st.Clock = clock
+	st.StartTimeSet(clock.now.Add(3 * time.Millisecond))
+	st.Logf = func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if NewDxyflake(st) != nil {
+		t.Fatal("dxyflake created with a start time ahead of the clock")
+	}
+
+	st.StartTimeTolerance = 5 * time.Millisecond
+	gen := NewDxyflake(st)
+	if gen == nil {
+		t.Fatal("dxyflake not created within the tolerance")
+	}
+	if len(warnings) != 1 {
+		t.Errorf("unexpected warnings: %q", warnings)
+	}
+	id, err := gen.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.Layout().TimeOf(id).Before(st.StartTime) || clock.Now().Before(st.StartTime) {
+		t.Errorf("id issued before the start time: %v at %v", gen.Layout().TimeOf(id), clock.Now())
+	}
+
+	// the clock never moved back, nor was a sequence used up
+	st.Init(1, 3)
+	st.StartTimeSet(clock.Now().Add(3 * time.Millisecond))
+	st.ClockBack = ClockBackError
+	st.Exhaustion = ExhaustionError
+	gen = NewDxyflake(st)
+	if gen == nil {
+		t.Fatal("dxyflake not created within the tolerance")
+	}
+	if clock.Now().Before(st.StartTime) {
+		t.Errorf("dxyflake created at %v before the start time", clock.Now())
+	}
+	if _, err := gen.TryNextID(); err != nil {
+		t.Errorf("TryNextID() = %v", err)
+	}
+	if _, err := gen.NextID(); err != nil {
+		t.Errorf("NextID() = %v", err)
+	}
+}
+
+func TestTimeUnit(t *testing.T) {
+	for _, unit := range []time.Duration{time.Millisecond, time.Second} {
+		var st Settings
+		st.Init(1, 2)
+		st.TimeUnit = unit
+		c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		st.Clock = c
+		st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+		gen, err := NewDxyflakeWithError(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gen.Layout().Unit != unit {
+			t.Errorf("layout unit %v, want %v", gen.Layout().Unit, unit)
+		}
+
+		id, _ := gen.NextID()
+		tick := gen.Decompose(id)["time"]
+		if want := c.now.Sub(DefaultStartTime) / unit; tick != int64(want) {
+			t.Errorf("%v: time part %d, want %d", unit, tick, want)
+		}
+		if at := gen.Layout().TimeOf(id); !at.Equal(c.now) {
+			t.Errorf("%v: TimeOf() = %s, want %s", unit, at, c.now)
+		}
+		for i := 0; i < 1<<BitLenSequence; i++ {
+			id, _ = gen.NextID()
+		}
+		if waited := c.now.Sub(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); waited != unit {
+			t.Errorf("%v: waited %v for the next unit", unit, waited)
+		}
+		if next := gen.Decompose(id)["time"]; next != tick+1 {
+			t.Errorf("%v: time part %d after overflow, want %d", unit, next, tick+1)
+		}
+	}
+
+	var st Settings
+	st.TimeUnit = 3 * time.Millisecond
+	if _, err := NewDxyflakeWithError(st); err != ErrInvalidLayout {
+		t.Errorf("NewDxyflakeWithError() error = %v, want %v", err, ErrInvalidLayout)
 	}
 }
@@ -0,0 +1,46 @@
+package dxyflaketest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a dxyflake.Clock that only moves when told to. Sleep advances
+// it by d instead of waiting, so a generator that has used up a time unit
+// moves on at once, and tests of sequence overflow, rollover and clock
+// steps run without sleeping and always alike.
+type Clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewClock returns a Clock showing t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d.
+func (c *Clock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// Advance moves the clock by d, back if d is negative.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set sets the clock to t.
+func (c *Clock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = t
+}
@@ -0,0 +1,45 @@
+package dxyflaketest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestClock(t *testing.T) {
+	c := NewClock(Start)
+	var st dxyflake.Settings
+	st.Init(1, 2)
+	st.Clock = c
+	df := dxyflake.NewDxyflake(st)
+
+	// the 4097th ID overflows into the next time unit without sleeping
+	var last dxyflake.ID
+	for i := 0; i <= 1<<dxyflake.BitLenSequence; i++ {
+		id, err := df.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= last {
+			t.Fatalf("ID %d not increasing", i)
+		}
+		last = id
+	}
+	if got := c.Now().Sub(Start); got != 10*time.Millisecond {
+		t.Errorf("clock advanced by %v, want 10ms", got)
+	}
+	parts := dxyflake.Decompose(last)
+	if parts["sequence"] != 0 || df.TickTime(parts["time"]) != c.Now() {
+		t.Errorf("unexpected parts %v", parts)
+	}
+
+	c.Advance(-time.Second)
+	if id, err := df.NextID(); err != nil || id <= last {
+		t.Errorf("NextID() = %d, %v after a clock step back", id, err)
+	}
+	c.Set(Start.Add(time.Hour))
+	if df.CurrentTick() != parts["time"]+360000-1 {
+		t.Errorf("unexpected tick %d", df.CurrentTick())
+	}
+}
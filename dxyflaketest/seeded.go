@@ -0,0 +1,85 @@
+// Package dxyflaketest provides helpers for tests that use dxyflake IDs.
+package dxyflaketest
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Start is the time the streams of Seeded generators start at.
+var Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// nodes is the number of (machine ID, service ID) pairs a Seeded generator
+// issues IDs for.
+const nodes = 4
+
+// Generator is a deterministic pseudo-generator of valid looking IDs, as if
+// issued by a few nodes under moderate load: time advances in irregular
+// steps, several nodes issue in the same time unit and sequence numbers
+// count up within it.
+//
+// The IDs are unique within one Generator only. Two Generators with the same
+// seed return the same IDs, in any process, so they must never be used
+// outside of tests and fixtures.
+type Generator struct {
+	r     *rand.Rand
+	tick  int64
+	left  int // IDs left in the current time unit
+	nodes [nodes]struct {
+		machineID, serviceID int64
+		sequence             int64
+	}
+}
+
+// Seeded returns a Generator whose IDs only depend on seed.
+func Seeded(seed int64) *Generator {
+	g := &Generator{r: rand.New(rand.NewSource(seed))}
+	g.tick = (Start.UnixNano() - dxyflake.DefaultStartTime.UnixNano()) / 1e7
+	for i := range g.nodes {
+		g.nodes[i].machineID = int64(g.r.Intn(1 << dxyflake.BitLenMachineID))
+		g.nodes[i].serviceID = int64(i)
+	}
+	g.advance()
+	return g
+}
+
+// advance moves to a later time unit with a new number of IDs.
+func (g *Generator) advance() {
+	g.tick += 1 + int64(g.r.ExpFloat64()*5)
+	g.left = 1 + g.r.Intn(8)
+	for i := range g.nodes {
+		g.nodes[i].sequence = 0
+	}
+}
+
+// NextID returns the next ID of the stream. It never fails.
+func (g *Generator) NextID() (dxyflake.ID, error) {
+	if g.left == 0 {
+		g.advance()
+	}
+	g.left--
+
+	n := &g.nodes[g.r.Intn(nodes)]
+	id := g.tick<<(dxyflake.BitLenMachineID+dxyflake.BitLenServiceID+dxyflake.BitLenSequence) |
+		n.machineID<<(dxyflake.BitLenServiceID+dxyflake.BitLenSequence) |
+		n.serviceID<<dxyflake.BitLenSequence |
+		n.sequence
+	n.sequence++
+	return dxyflake.ID(id), nil
+}
+
+// IDs returns the next n IDs of the stream.
+func (g *Generator) IDs(n int) []dxyflake.ID {
+	ids := make([]dxyflake.ID, n)
+	for i := range ids {
+		ids[i], _ = g.NextID()
+	}
+	return ids
+}
+
+// Time returns the time of the current time unit of the stream.
+func (g *Generator) Time() time.Time {
+	return dxyflake.DefaultStartTime.Add(time.Duration(g.tick) * 1e7)
+}
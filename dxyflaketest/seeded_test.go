@@ -0,0 +1,53 @@
+package dxyflaketest
+
+import (
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestSeededGolden(t *testing.T) {
+	// fixtures rely on these never changing
+	want := []dxyflake.ID{29788282558484480, 29788282556645376, 29788282556645377, 29788282556645378, 29788282557440000}
+	got := Seeded(1).IDs(len(want))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected ids: %v", got)
+		}
+	}
+}
+
+func TestSeeded(t *testing.T) {
+	g := Seeded(42)
+	if other := Seeded(43).IDs(10); other[0] == g.IDs(1)[0] {
+		t.Error("seed ignored")
+	}
+
+	seen := make(map[dxyflake.ID]bool)
+	last := make(map[int64]dxyflake.ID)
+	lastTime := int64(0)
+	for _, id := range g.IDs(10000) {
+		if seen[id] {
+			t.Fatalf("duplicate id: %d", id)
+		}
+		seen[id] = true
+
+		parts := dxyflake.Decompose(id)
+		if parts["time"] < lastTime {
+			t.Fatalf("time went backwards: %d", id)
+		}
+		lastTime = parts["time"]
+		node := parts["machine-id"]<<dxyflake.BitLenServiceID | parts["service-id"]
+		if id <= last[node] {
+			t.Fatalf("not increasing per node: %d", id)
+		}
+		last[node] = id
+	}
+
+	if got := dxyflake.Decompose(g.IDs(1)[0])["time"]; got > (g.Time().UnixNano()-dxyflake.DefaultStartTime.UnixNano())/1e7 {
+		t.Errorf("id later than Time: %d", got)
+	}
+	if !g.Time().After(Start) {
+		t.Errorf("unexpected time: %s", g.Time())
+	}
+}
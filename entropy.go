@@ -0,0 +1,35 @@
+package dxyflake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// randomBits returns n random bits, n at most 64, read from the Rand of
+// the settings of df. It is called with df.mutex held.
+func (df *dxyflake) randomBits(n uint) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(df.rand, b[:(n+7)/8]); err != nil {
+		return 0, fmt.Errorf("rand: %w", err)
+	}
+	return binary.LittleEndian.Uint64(b[:]) & (1<<n - 1), nil
+}
+
+// startSequence starts a time unit at the first sequence number of df, or
+// at a random one in the lower half of them under Settings.RandomSequence.
+// The sequence is left as it was on error. It is called with df.mutex held.
+func (df *dxyflake) startSequence() error {
+	start := df.firstSeq
+	if df.randomSeq {
+		r, err := df.randomBits(32)
+		if err != nil {
+			return err
+		}
+		if half := (uint64(df.lastSeq) - uint64(df.firstSeq) + 1) / 2; half > 0 {
+			start += uint16(r % half)
+		}
+	}
+	df.sequence, df.startSeq = start, start
+	return nil
+}
@@ -0,0 +1,95 @@
+package dxyflake
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRand(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	st.Rand = bytes.NewReader([]byte{0xff, 0x12, 0x34})
+	df := NewDxyflake(st)
+
+	if v, err := df.randomBits(4); err != nil || v != 0xf {
+		t.Errorf("randomBits(4) = %#x, %v", v, err)
+	}
+	if v, err := df.randomBits(12); err != nil || v != 0x412 {
+		t.Errorf("randomBits(12) = %#x, %v", v, err)
+	}
+	if _, err := df.randomBits(8); !errors.Is(err, io.EOF) {
+		t.Errorf("exhausted Rand: %v", err)
+	}
+
+	st.Rand = nil
+	if df := NewDxyflake(st); df.rand == nil {
+		t.Error("no default Rand")
+	} else if _, err := df.randomBits(64); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRandomSequence(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	st.StartTime = time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.Clock = c
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+	st.RandomSequence = true
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one ID per time unit
+	shards := make(map[int64]int)
+	var prev ID
+	for i := 0; i < 400; i++ {
+		id, err := df.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= prev {
+			t.Fatalf("%d not after %d", id, prev)
+		}
+		prev = id
+		if seq := SequenceBits(id); seq >= 1<<(BitLenSequence-1) {
+			t.Fatalf("sequence %d in the upper half", seq)
+		}
+		shards[int64(id)%4]++
+		c.Sleep(10 * time.Millisecond)
+	}
+	for shard := int64(0); shard < 4; shard++ {
+		if shards[shard] < 50 {
+			t.Errorf("uneven shards: %v", shards)
+		}
+	}
+
+	// a time unit still holds at least half the sequence numbers
+	for i := 0; i < 1<<(BitLenSequence-1); i++ {
+		id, err := df.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= prev {
+			t.Fatalf("%d not after %d", id, prev)
+		}
+		prev = id
+	}
+	if s := df.Stats(); s.Waits != 0 {
+		t.Errorf("%d waits within half the sequence numbers", s.Waits)
+	}
+
+	st.Rand = bytes.NewReader(nil)
+	df, err = NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := df.NextID(); !errors.Is(err, io.EOF) {
+		t.Errorf("exhausted Rand: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package dxyflake
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// An envelope is an ID in base62 behind a prefix naming the version of the
+// layout it was issued with, e.g. "dxy1_2AzAAye6i". IDs stored as
+// envelopes stay decodable after the layout or the epoch changes, as every
+// new layout gets a new version.
+
+// These errors are returned by ParseEnvelope.
+var (
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+	ErrUnknownVersion  = errors.New("unknown envelope version")
+)
+
+// EnvelopeVersion is the version Envelope writes.
+const EnvelopeVersion = 1
+
+const envelopePrefix = "dxy"
+
+// envelopeLayouts are the layouts of the known envelope versions. Versions
+// are never reused or changed once released.
+var envelopeLayouts = map[int]Layout{
+	1: DefaultLayout,
+	2: LayoutV2,
+}
+
+// EnvelopeLayout returns the layout of an envelope version.
+func EnvelopeLayout(version int) (Layout, error) {
+	l, ok := envelopeLayouts[version]
+	if !ok {
+		return Layout{}, ErrUnknownVersion
+	}
+	return l, nil
+}
+
+// Envelope returns the ID as an envelope of EnvelopeVersion.
+func (f ID) Envelope() string {
+	return envelopePrefix + strconv.Itoa(EnvelopeVersion) + "_" + f.Base62()
+}
+
+// ParseEnvelope parses an envelope into an ID and the layout of its
+// version. It is strict: it returns ErrUnknownVersion for versions it does
+// not know, rather than guessing a layout, and ErrInvalidEnvelope for
+// anything Envelope would not have written, such as leading zeros.
+func ParseEnvelope(s string) (ID, Layout, error) {
+	if !strings.HasPrefix(s, envelopePrefix) {
+		return -1, Layout{}, ErrInvalidEnvelope
+	}
+	i := strings.IndexByte(s, '_')
+	if i < 0 {
+		return -1, Layout{}, ErrInvalidEnvelope
+	}
+	v, body := s[len(envelopePrefix):i], s[i+1:]
+	if v == "" || v[0] == '0' || len(body) == 0 || len(body) > 11 {
+		return -1, Layout{}, ErrInvalidEnvelope
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || strconv.Itoa(version) != v {
+		return -1, Layout{}, ErrInvalidEnvelope
+	}
+
+	id, err := ParseBase62([]byte(body))
+	if err != nil || id < 0 || id.Base62() != body {
+		return -1, Layout{}, ErrInvalidEnvelope
+	}
+	l, err := EnvelopeLayout(version)
+	if err != nil {
+		return -1, Layout{}, err
+	}
+	return id, l, nil
+}
@@ -0,0 +1,42 @@
+package dxyflake
+
+import "testing"
+
+func TestEnvelope(t *testing.T) {
+	for _, id := range []ID{0, 475370495148032, 1<<63 - 1} {
+		s := id.Envelope()
+		got, l, err := ParseEnvelope(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if got != id || l != DefaultLayout {
+			t.Errorf("%s: got %d, %+v", s, got, l)
+		}
+	}
+	if s := ID(475370495148032).Envelope(); s != "dxy1_2AzAAye6i" {
+		t.Errorf("got %s", s)
+	}
+}
+
+func TestParseEnvelopeStrict(t *testing.T) {
+	for s, want := range map[string]error{
+		"dxy3_2AzAAye6i":    ErrUnknownVersion,
+		"dxy99_2AzAAye6i":   ErrUnknownVersion,
+		"2AzAAye6i":         ErrInvalidEnvelope,
+		"dxy_2AzAAye6i":     ErrInvalidEnvelope,
+		"dxy01_2AzAAye6i":   ErrInvalidEnvelope,
+		"dxy+1_2AzAAye6i":   ErrInvalidEnvelope,
+		"dxy1_":             ErrInvalidEnvelope,
+		"dxy1_02AzAAye6i":   ErrInvalidEnvelope,
+		"dxy1_2AzAAye6i!":   ErrInvalidEnvelope,
+		"dxy1_AzL8n0Y58m8":  ErrInvalidEnvelope, // overflows int64
+		"dxy1_zzzzzzzzzzzz": ErrInvalidEnvelope,
+		"DXY1_2AzAAye6i":    ErrInvalidEnvelope,
+		"dxy1-2AzAAye6i":    ErrInvalidEnvelope,
+		"dxy1_2AzA_Aye6i":   ErrInvalidEnvelope,
+	} {
+		if _, _, err := ParseEnvelope(s); err != want {
+			t.Errorf("%s: got %v, want %v", s, err, want)
+		}
+	}
+}
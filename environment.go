@@ -0,0 +1,39 @@
+package dxyflake
+
+// Environment marks the IDs of a dxyflake as coming from production or
+// from a test or staging environment, so that test IDs leaking into
+// production databases can be found and filtered.
+//
+// Test IDs carry the largest service ID of their layout, TestServiceID in
+// the default layout. Production dxyflakes refuse that service ID, so no
+// production ID is ever taken for a test one.
+type Environment int
+
+// These are the environments.
+const (
+	EnvUnmarked   Environment = iota // IDs are not marked, the default
+	EnvProduction                    // the test service ID is refused
+	EnvTest                          // the service ID is the test service ID
+)
+
+// TestServiceID is the service ID of the IDs of EnvTest dxyflakes of the
+// default layout.
+const TestServiceID = 1<<BitLenServiceID - 1
+
+// TestServiceID returns the service ID of the IDs of EnvTest dxyflakes of
+// layout l.
+func (l Layout) TestServiceID() uint16 {
+	return uint16(1<<uint(l.BitLenServiceID) - 1)
+}
+
+// IsTestEnvironment reports whether id of layout l was issued by an
+// EnvTest dxyflake, by its service ID.
+func (l Layout) IsTestEnvironment(id ID) bool {
+	return uint16(l.Masks().ServiceBits(id)) == l.TestServiceID()
+}
+
+// IsTestEnvironment reports whether id was issued by an EnvTest dxyflake of
+// the default layout.
+func IsTestEnvironment(id ID) bool {
+	return ServiceBits(id) == TestServiceID
+}
@@ -0,0 +1,42 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	st.Environment = EnvTest
+	id, err := NewDxyflake(st).NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsTestEnvironment(id) || ServiceBits(id) != TestServiceID {
+		t.Errorf("test ID %d not marked", id)
+	}
+
+	st.Environment = EnvProduction
+	id, err = NewDxyflake(st).NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsTestEnvironment(id) {
+		t.Errorf("production ID %d marked", id)
+	}
+	st.Init(1, TestServiceID)
+	if _, err := NewDxyflakeWithError(st); !errors.Is(err, ErrInvalidServiceID) {
+		t.Errorf("production dxyflake with the test service id: %v", err)
+	}
+
+	st = Settings{BitsTime: 39, BitsMachineID: 8, BitsServiceID: 4, BitsSequence: 12, Environment: EnvTest}
+	df := NewDxyflake(st)
+	id, err = df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := df.Layout(); !l.IsTestEnvironment(id) || l.TestServiceID() != 15 {
+		t.Errorf("test ID %d not marked in %+v", id, l)
+	}
+}
@@ -0,0 +1,118 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// These errors are returned by the epoch registry.
+var (
+	ErrUnknownEpoch     = errors.New("unknown epoch")
+	ErrInvalidEpochName = errors.New("invalid epoch name")
+	ErrEpochRegistered  = errors.New("epoch name already registered")
+)
+
+// These are the names of the preset epochs.
+const (
+	EpochDefault = "dxy-default" // DefaultStartTime
+	EpochTwitter = "twitter"     // the Twitter Snowflake epoch, 2010-11-04T01:42:54.657Z
+	EpochUnix    = "unix"        // 1970-01-01T00:00:00Z
+)
+
+var epochs = struct {
+	sync.RWMutex
+	m map[string]time.Time
+}{m: map[string]time.Time{
+	EpochDefault: DefaultStartTime,
+	EpochTwitter: time.Unix(0, 1288834974657*int64(time.Millisecond)).UTC(),
+	EpochUnix:    time.Unix(0, 0).UTC(),
+}}
+
+// RegisterEpoch registers a custom epoch under name, so that configuration
+// files and the command can refer to it. Names are made of lower case
+// letters, digits and hyphens and start with a letter, so they never read
+// as a date. A name cannot be registered twice.
+func RegisterEpoch(name string, t time.Time) error {
+	if !validEpochName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidEpochName, name)
+	}
+	epochs.Lock()
+	defer epochs.Unlock()
+
+	if _, ok := epochs.m[name]; ok {
+		return fmt.Errorf("%w: %q", ErrEpochRegistered, name)
+	}
+	epochs.m[name] = t
+	return nil
+}
+
+func validEpochName(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupEpoch returns the epoch registered under name.
+func LookupEpoch(name string) (time.Time, bool) {
+	epochs.RLock()
+	defer epochs.RUnlock()
+
+	t, ok := epochs.m[name]
+	return t, ok
+}
+
+// EpochName returns the name of the registered epoch equal to t, or "" if
+// there is none. Preset names win over custom ones.
+func EpochName(t time.Time) string {
+	for _, name := range []string{EpochDefault, EpochTwitter, EpochUnix} {
+		if e, _ := LookupEpoch(name); e.Equal(t) {
+			return name
+		}
+	}
+	for _, name := range EpochNames() {
+		if e, _ := LookupEpoch(name); e.Equal(t) {
+			return name
+		}
+	}
+	return ""
+}
+
+// EpochNames returns the names of the registered epochs, sorted.
+func EpochNames() []string {
+	epochs.RLock()
+	defer epochs.RUnlock()
+
+	names := make([]string, 0, len(epochs.m))
+	for name := range epochs.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseEpoch parses an epoch given by the name of a registered epoch, as an
+// RFC 3339 time or as a date, YYYY-MM-DD, in UTC.
+func ParseEpoch(s string) (time.Time, error) {
+	if t, ok := LookupEpoch(s); ok {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if validEpochName(s) {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrUnknownEpoch, s)
+	}
+	return time.Time{}, fmt.Errorf("invalid epoch %q: not a registered name, RFC 3339 time or date", s)
+}
@@ -0,0 +1,55 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseEpoch(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"dxy-default", DefaultStartTime},
+		{"twitter", time.Date(2010, 11, 4, 1, 42, 54, 657e6, time.UTC)},
+		{"unix", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2022-01-01T00:00:00Z", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2022-01-01", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseEpoch(tt.s)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseEpoch(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseEpoch("nowhere"); !errors.Is(err, ErrUnknownEpoch) {
+		t.Errorf("unknown name: got %v", err)
+	}
+	if _, err := ParseEpoch("01/02/2022"); err == nil {
+		t.Error("no error for an invalid epoch")
+	}
+}
+
+func TestRegisterEpoch(t *testing.T) {
+	legacy := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := RegisterEpoch("test-legacy", legacy); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ParseEpoch("test-legacy"); err != nil || !got.Equal(legacy) {
+		t.Errorf("unexpected epoch: %v, %v", got, err)
+	}
+	if EpochName(legacy) != "test-legacy" || EpochName(DefaultStartTime) != EpochDefault {
+		t.Errorf("unexpected names: %q, %q", EpochName(legacy), EpochName(DefaultStartTime))
+	}
+
+	if err := RegisterEpoch("test-legacy", legacy); !errors.Is(err, ErrEpochRegistered) {
+		t.Errorf("registered twice: got %v", err)
+	}
+	for _, name := range []string{"", "2019-03-01", "Legacy", "legacy epoch"} {
+		if err := RegisterEpoch(name, legacy); !errors.Is(err, ErrInvalidEpochName) {
+			t.Errorf("RegisterEpoch(%q) = %v", name, err)
+		}
+	}
+}
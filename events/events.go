@@ -0,0 +1,380 @@
+// Package events publishes generator lifecycle events as CloudEvents, so
+// that an event bus can drive automation off them: leases acquired and
+// lost, clock anomalies and sequence saturation.
+//
+// An Emitter hooks into the extension points of the generator and the
+// lease package and hands the events to a Sink, such as an HTTP endpoint
+// or a function producing to Kafka:
+//
+//	e := events.NewEmitter(events.HTTPSink("http://bus/events", nil), events.Options{})
+//	defer e.Close(context.Background())
+//	st.Clock = e.Clock(dxyflake.SystemClock)
+//	st.Waiter = e.Waiter(nil)
+//	opts := lease.Options{OnExpired: e.OnExpired(nil)}
+//
+// Events are queued and sent from a goroutine, so a slow sink never holds
+// up the generator; when the queue is full, events are dropped and counted.
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+	"github.com/GiterLab/dxyflake/supervise"
+)
+
+// These are the types of the events of an Emitter.
+const (
+	TypeLeaseAcquired     = "com.giterlab.dxyflake.lease.acquired"
+	TypeLeaseLost         = "com.giterlab.dxyflake.lease.lost"
+	TypeClockAnomaly      = "com.giterlab.dxyflake.clock.anomaly"
+	TypeSequenceSaturated = "com.giterlab.dxyflake.sequence.saturated"
+)
+
+// SpecVersion is the CloudEvents specification version of the events.
+const SpecVersion = "1.0"
+
+// ContentType is the media type of a CloudEvent in structured mode.
+const ContentType = "application/cloudevents+json"
+
+// DefaultSaturationInterval sends at most one saturation event a second.
+const (
+	DefaultBuffer             = 64
+	DefaultSaturationInterval = time.Second
+)
+
+// Event is a CloudEvent in its JSON format.
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype,omitempty"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+// LeaseData is the data of lease events.
+type LeaseData struct {
+	Key      string    `json:"key"`
+	Holder   string    `json:"holder,omitempty"`
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+// ClockData is the data of clock anomaly events. Step is how far the clock
+// moved back, or the offset to a reference clock.
+type ClockData struct {
+	Step   string `json:"step"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SaturationData is the data of sequence saturation events: the number of
+// time units whose sequence numbers ran out since the last event, and the
+// time waited for the next units.
+type SaturationData struct {
+	Units  uint64 `json:"units"`
+	Waited string `json:"waited"`
+}
+
+// Sink publishes an event.
+type Sink func(ctx context.Context, e Event) error
+
+// HTTPSink returns a Sink posting events in structured mode to url. If
+// client is nil, http.DefaultClient is used.
+func HTTPSink(url string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context, e Event) error {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", ContentType)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("events: %s: %s", url, resp.Status)
+		}
+		return nil
+	}
+}
+
+// Options configures an Emitter:
+//
+// Source is the source of the events.
+// If Source is empty, "dxyflake/" and lease.DefaultHolder() is used.
+//
+// Subject is the subject of the events, such as the namespace of the
+// generator.
+//
+// Buffer is the number of events queued for the sink.
+// If Buffer is 0, DefaultBuffer is used.
+//
+// SaturationInterval is the shortest time between saturation events, which
+// would otherwise be sent for every time unit of a busy generator.
+// If SaturationInterval is 0, DefaultSaturationInterval is used.
+//
+// Timeout limits every call to the sink; 0 means no limit.
+//
+// OnError is called, from the sending goroutine, with the errors of the
+// sink.
+//
+// Rand is the source of the random event IDs.
+// If Rand is nil, crypto/rand.Reader is used.
+//
+// Supervisor, if set, runs the sending as its task "events" instead of a
+// goroutine of its own, restarted if the sink panics. The queued events are
+// sent and the emitter closed when the supervisor stops.
+type Options struct {
+	Source             string
+	Subject            string
+	Buffer             int
+	SaturationInterval time.Duration
+	Timeout            time.Duration
+	OnError            func(e Event, err error)
+	Rand               io.Reader
+	Supervisor         *supervise.Supervisor
+}
+
+func (o *Options) setDefaults() {
+	if o.Source == "" {
+		o.Source = "dxyflake/" + lease.DefaultHolder()
+	}
+	if o.Buffer <= 0 {
+		o.Buffer = DefaultBuffer
+	}
+	if o.SaturationInterval <= 0 {
+		o.SaturationInterval = DefaultSaturationInterval
+	}
+	if o.Rand == nil {
+		o.Rand = rand.Reader
+	}
+}
+
+// Emitter converts generator events to CloudEvents and sends them to a
+// sink. It is safe for concurrent use.
+type Emitter struct {
+	sink  Sink
+	opts  Options
+	queue chan Event
+	done  chan struct{}
+
+	mutex     sync.Mutex
+	closed    bool
+	dropped   uint64
+	saturated uint64
+	waited    time.Duration
+	lastSent  time.Time
+}
+
+// NewEmitter returns an Emitter sending to sink. Call Close to flush and
+// stop it.
+func NewEmitter(sink Sink, opts Options) *Emitter {
+	opts.setDefaults()
+	e := &Emitter{
+		sink:  sink,
+		opts:  opts,
+		queue: make(chan Event, opts.Buffer),
+		done:  make(chan struct{}),
+	}
+	if opts.Supervisor == nil {
+		go e.send(context.Background())
+	} else if err := opts.Supervisor.Go(supervise.Spec{Name: "events", Run: e.send, Restart: supervise.OnFailure}); err != nil {
+		e.closed = true
+		close(e.queue)
+		close(e.done)
+	}
+	return e
+}
+
+// send sends the queued events until the queue is closed, or until ctx is
+// done, which closes it.
+func (e *Emitter) send(ctx context.Context) error {
+	for {
+		select {
+		case ev, ok := <-e.queue:
+			if !ok {
+				close(e.done)
+				return nil
+			}
+			e.deliver(ev)
+		case <-ctx.Done():
+			e.closeQueue()
+			for ev := range e.queue {
+				e.deliver(ev)
+			}
+			close(e.done)
+			return nil
+		}
+	}
+}
+
+func (e *Emitter) deliver(ev Event) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if e.opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, e.opts.Timeout)
+	}
+	defer cancel()
+	if err := e.sink(ctx, ev); err != nil && e.opts.OnError != nil {
+		e.opts.OnError(ev, err)
+	}
+}
+
+func (e *Emitter) closeQueue() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+}
+
+// Emit queues an event of type typ with data. It does not block; if the
+// queue is full or the emitter closed, the event is dropped.
+func (e *Emitter) Emit(typ string, data interface{}) {
+	ev := Event{
+		SpecVersion:     SpecVersion,
+		Source:          e.opts.Source,
+		Type:            typ,
+		Subject:         e.opts.Subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.closed {
+		e.dropped++
+		return
+	}
+	ev.ID = newEventID(e.opts.Rand)
+	select {
+	case e.queue <- ev:
+	default:
+		e.dropped++
+	}
+}
+
+// Dropped returns the number of events dropped so far.
+func (e *Emitter) Dropped() uint64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.dropped
+}
+
+// Close stops accepting events and waits until the queued ones are sent or
+// ctx is done.
+func (e *Emitter) Close(ctx context.Context) error {
+	e.closeQueue()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LeaseAcquired emits a lease acquired event for l.
+func (e *Emitter) LeaseAcquired(l *lease.Lease) {
+	e.Emit(TypeLeaseAcquired, LeaseData{Key: l.Key(), Holder: l.Holder(), Deadline: l.Deadline()})
+}
+
+// OnExpired returns a lease.Options.OnExpired function emitting a lease
+// lost event and then calling next, if not nil.
+func (e *Emitter) OnExpired(next func(key string)) func(key string) {
+	return func(key string) {
+		e.Emit(TypeLeaseLost, LeaseData{Key: key})
+		if next != nil {
+			next(key)
+		}
+	}
+}
+
+// ClockAnomaly emits a clock anomaly event, for anomalies found by other
+// means than Clock, such as a large offset to an NTP server.
+func (e *Emitter) ClockAnomaly(step time.Duration, detail string) {
+	e.Emit(TypeClockAnomaly, ClockData{Step: step.String(), Detail: detail})
+}
+
+// Clock returns c emitting a clock anomaly event whenever its time moves
+// back.
+func (e *Emitter) Clock(c dxyflake.Clock) dxyflake.Clock {
+	return &clock{Clock: c, e: e}
+}
+
+type clock struct {
+	dxyflake.Clock
+	e *Emitter
+
+	mutex sync.Mutex
+	last  time.Time
+}
+
+func (c *clock) Now() time.Time {
+	t := c.Clock.Now()
+
+	c.mutex.Lock()
+	step := c.last.Sub(t)
+	if step <= 0 {
+		c.last = t
+	}
+	c.mutex.Unlock()
+
+	if step > 0 {
+		c.e.ClockAnomaly(step, "clock moved back")
+	}
+	return t
+}
+
+// Waiter returns w emitting sequence saturation events, at most one per
+// SaturationInterval. If w is nil, dxyflake.SleepWaiter is used.
+func (e *Emitter) Waiter(w dxyflake.Waiter) dxyflake.Waiter {
+	if w == nil {
+		w = dxyflake.SleepWaiter
+	}
+	return dxyflake.WaiterFunc(func(c dxyflake.Clock, d time.Duration) {
+		e.mutex.Lock()
+		e.saturated++
+		e.waited += d
+		var data *SaturationData
+		if now := time.Now(); now.Sub(e.lastSent) >= e.opts.SaturationInterval {
+			data = &SaturationData{Units: e.saturated, Waited: e.waited.String()}
+			e.saturated, e.waited, e.lastSent = 0, 0, now
+		}
+		e.mutex.Unlock()
+
+		if data != nil {
+			e.Emit(TypeSequenceSaturated, *data)
+		}
+		w.Wait(c, d)
+	})
+}
+
+func newEventID(r io.Reader) string {
+	var b [16]byte
+	io.ReadFull(r, b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,177 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/supervise"
+)
+
+type recorder struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (r *recorder) sink(ctx context.Context, e Event) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recorder) types() map[string]int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	types := make(map[string]int)
+	for _, e := range r.events {
+		types[e.Type]++
+	}
+	return types
+}
+
+type backClock struct {
+	dxyflake.Clock
+	mutex sync.Mutex
+	calls int
+}
+
+// Now moves back by a second on its third call.
+func (c *backClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.calls++
+	if c.calls >= 3 {
+		return time.Now().Add(-time.Second)
+	}
+	return time.Now()
+}
+
+func TestEmitter(t *testing.T) {
+	var r recorder
+	e := NewEmitter(r.sink, Options{Source: "test", Subject: "orders"})
+
+	var st dxyflake.Settings
+	st.Init(1, 2)
+	st.Waiter = e.Waiter(nil)
+	gen := dxyflake.NewDxyflake(st)
+	for i := 0; i < 3*4096; i++ {
+		if _, err := gen.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := e.Clock(&backClock{Clock: dxyflake.SystemClock})
+	for i := 0; i < 3; i++ {
+		c.Now()
+	}
+	e.OnExpired(nil)("machine/1")
+
+	if err := e.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	types := r.types()
+	if types[TypeSequenceSaturated] != 1 || types[TypeClockAnomaly] != 1 || types[TypeLeaseLost] != 1 {
+		t.Errorf("unexpected events: %v", types)
+	}
+	for _, ev := range r.events {
+		if ev.SpecVersion != SpecVersion || ev.Source != "test" || ev.Subject != "orders" || ev.ID == "" {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	}
+
+	e.Emit(TypeLeaseLost, nil)
+	if e.Dropped() != 1 {
+		t.Errorf("event after close not dropped: %d", e.Dropped())
+	}
+}
+
+func TestRand(t *testing.T) {
+	var r recorder
+	e := NewEmitter(r.sink, Options{Rand: bytes.NewReader(make([]byte, 32))})
+	e.Emit(TypeLeaseLost, nil)
+	e.Emit(TypeLeaseLost, nil)
+	if err := e.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range r.events {
+		if ev.ID != "00000000000000000000000000000000" {
+			t.Errorf("event ID not read from Rand: %q", ev.ID)
+		}
+	}
+	if len(r.events) != 2 {
+		t.Errorf("%d events sent", len(r.events))
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != ContentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sink := HTTPSink(srv.URL, nil)
+	ev := Event{SpecVersion: SpecVersion, ID: "1", Source: "test", Type: TypeLeaseLost, Time: time.Now()}
+	if err := sink(context.Background(), ev); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "1" || got.Type != TypeLeaseLost {
+		t.Errorf("unexpected event: %+v", got)
+	}
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+	if err := HTTPSink(failing.URL, nil)(context.Background(), ev); err == nil {
+		t.Error("no error for a failed request")
+	}
+}
+
+func TestSupervised(t *testing.T) {
+	var r recorder
+	panicked := false
+	sink := func(ctx context.Context, e Event) error {
+		if !panicked {
+			panicked = true
+			panic("sink failed")
+		}
+		return r.sink(ctx, e)
+	}
+	sup := supervise.New(context.Background(), supervise.Options{})
+	e := NewEmitter(sink, Options{Supervisor: sup})
+
+	e.Emit(TypeLeaseLost, nil) // lost with the panic
+	for len(r.types()) == 0 {
+		e.Emit(TypeLeaseLost, nil)
+		time.Sleep(time.Millisecond)
+	}
+	if st := sup.Status(); len(st) != 1 || st[0].Restarts != 1 {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	// stopping the supervisor sends the queued events and closes the emitter
+	e.Emit(TypeClockAnomaly, nil)
+	if err := sup.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if r.types()[TypeClockAnomaly] != 1 {
+		t.Errorf("queued event not sent: %v", r.types())
+	}
+	if err := e.Close(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
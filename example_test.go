@@ -0,0 +1,147 @@
+package dxyflake_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// fixedClock stops at one time, so that the examples issue the same IDs
+// on every run.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time        { return time.Time(c) }
+func (c fixedClock) Sleep(d time.Duration) {}
+
+func ExampleNewDxyflake() {
+	var st dxyflake.Settings
+	st.Init(3, 4) // machine ID 3, service ID 4
+	st.Clock = fixedClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	gen := dxyflake.NewDxyflake(st)
+
+	for i := 0; i < 2; i++ {
+		id, err := gen.NextID()
+		if err != nil {
+			panic(err)
+		}
+		p := dxyflake.DecomposeParts(id)
+		fmt.Println(id, p.Timestamp.UTC(), p.MachineID, p.ServiceID, p.Sequence)
+	}
+	// Output:
+	// 29829153948057600 2024-01-02 03:04:05 +0000 UTC 3 4 0
+	// 29829153948057601 2024-01-02 03:04:05 +0000 UTC 3 4 1
+}
+
+func ExampleSettings_transform() {
+	var st dxyflake.Settings
+	st.Init(3, 4)
+	st.Clock = fixedClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	// every ID ends with a Luhn check digit, in every encoding of it
+	st.Transform = dxyflake.Chain(dxyflake.AppendCheckDigit)
+	gen := dxyflake.NewDxyflake(st)
+
+	id, err := gen.NextID()
+	if err != nil {
+		panic(err)
+	}
+	orig, err := dxyflake.StripCheckDigit(id)
+	fmt.Println(id, orig, err)
+	_, err = dxyflake.StripCheckDigit(id + 1)
+	fmt.Println(err)
+	// Output:
+	// 298291539480576003 29829153948057600 <nil>
+	// check digit mismatch
+}
+
+func ExampleSettings_InitRegion() {
+	// 4 regions of 8 nodes share the 5 machine ID bits
+	var st dxyflake.Settings
+	st.InitRegion(2, 5, 4)
+	st.BitsRegion = 2
+	st.Clock = fixedClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	gen := dxyflake.NewDxyflake(st)
+
+	id, err := gen.NextID()
+	if err != nil {
+		panic(err)
+	}
+	p := gen.DecomposeParts(id)
+	fmt.Println(id, p.MachineID, p.RegionID, p.NodeID)
+	// Output:
+	// 29829153950416896 21 2 5
+}
+
+func ExampleSettings_customEpoch() {
+	var st dxyflake.Settings
+	st.Init(1, 0)
+	st.StartTimeSet(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	st.Clock = fixedClock(time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC))
+	gen := dxyflake.NewDxyflake(st)
+
+	id, err := gen.NextID()
+	if err != nil {
+		panic(err)
+	}
+	// IDs of another epoch must be read with the generator, or a Layout
+	// of the same epoch.
+	fmt.Println(id, gen.DecomposeParts(id).Time, gen.TimeOf(id).UTC())
+	// Output:
+	// 419561472 100 2024-01-01 00:00:01 +0000 UTC
+}
+
+func ExampleMachineIDFromPodOrdinal() {
+	// Set by Kubernetes for the pod "ids-7" of a StatefulSet, from the
+	// Downward API.
+	os.Setenv(dxyflake.EnvPodName, "ids-7")
+	defer os.Unsetenv(dxyflake.EnvPodName)
+
+	var st dxyflake.Settings
+	st.MachineID = dxyflake.MachineIDFromPodOrdinal()
+	gen, err := dxyflake.NewDxyflakeWithError(st)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(*gen.Describe().MachineID)
+	// Output:
+	// 7
+}
+
+func ExampleLayout_MinIDAt() {
+	// Rows keyed by ID are selected by creation time without an index on
+	// a timestamp column:
+	//
+	//	SELECT * FROM orders WHERE id BETWEEN ? AND ?
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24*time.Hour - time.Nanosecond)
+	fmt.Println(dxyflake.DefaultLayout.MinIDAt(from), dxyflake.DefaultLayout.MaxIDAt(to))
+	// Output:
+	// 29824521338880000 29860760125439999
+}
+
+func ExampleID_Scan() {
+	// IDs are written to and read from BIGINT columns with database/sql;
+	// Value and Scan are what the driver calls.
+	id := dxyflake.ID(29829153948057600)
+	v, _ := id.Value()
+
+	var back dxyflake.ID
+	if err := back.Scan(v); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%T %v\n", v, back)
+	// Output:
+	// int64 29829153948057600
+}
+
+func ExampleID_Crockford32() {
+	id := dxyflake.ID(29829153948057600)
+	s := id.Crockford32()
+	// case and hyphens do not matter when reading
+	back, _ := dxyflake.ParseCrockford32(strings.ToLower(s[:4]) + "-" + s[4:])
+	fmt.Println(s, id.SortableString(), back == id)
+	// Output:
+	// TFSEMEGCG00 00TFSEMEGCG00 true
+}
@@ -0,0 +1,21 @@
+package dxyflake
+
+import "time"
+
+// ExhaustionPolicy is what NextID and NextIDContext do when the sequence
+// numbers of the current time unit are used up.
+type ExhaustionPolicy int
+
+// These are the exhaustion policies.
+const (
+	ExhaustionSleep  ExhaustionPolicy = iota // wait for the next time unit with Settings.Waiter, the default
+	ExhaustionSpin                           // busy-wait for the next time unit, see SpinWaiter
+	ExhaustionError                          // return ErrSequenceExhausted, like TryNextID
+	ExhaustionBorrow                         // issue from time units up to Settings.MaxBorrow ahead
+)
+
+// borrow reports whether the dxyflake issues IDs of a time unit beginning
+// in d instead of waiting for it.
+func (df *dxyflake) borrow(d time.Duration) bool {
+	return df.exhaustion == ExhaustionBorrow && d <= df.maxBorrow
+}
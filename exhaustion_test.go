@@ -0,0 +1,75 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExhaustion(t *testing.T) {
+	newDxyflake := func(policy ExhaustionPolicy, maxBorrow time.Duration) (*dxyflake, *steppedClock) {
+		var st Settings
+		st.Init(1, 2)
+		st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 41, 7, 7, 8
+		c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		st.Clock = c
+		st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+		st.Exhaustion = policy
+		st.MaxBorrow = maxBorrow
+		df, err := NewDxyflakeWithError(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return df, c
+	}
+	issue := func(df *dxyflake, n int) error {
+		var prev ID
+		for i := 0; i < n; i++ {
+			id, err := df.NextID()
+			if err != nil {
+				return err
+			}
+			if id <= prev {
+				t.Fatalf("%d not after %d", id, prev)
+			}
+			prev = id
+		}
+		return nil
+	}
+
+	df, c := newDxyflake(ExhaustionError, 0)
+	if err := issue(df, 256); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := df.NextID(); !errors.Is(err, ErrSequenceExhausted) {
+		t.Errorf("ExhaustionError: %v", err)
+	}
+	c.Sleep(10 * time.Millisecond)
+	if _, err := df.NextID(); err != nil {
+		t.Errorf("ExhaustionError in the next time unit: %v", err)
+	}
+
+	df, c = newDxyflake(ExhaustionBorrow, 20*time.Millisecond)
+	start := c.Now()
+	if err := issue(df, 3*256); err != nil {
+		t.Fatal(err)
+	}
+	if s := df.Stats(); s.Waits != 0 || !c.Now().Equal(start) {
+		t.Errorf("borrowing within MaxBorrow waited: %+v", s)
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := df.Stats(); s.Waits != 1 || !c.Now().Equal(start.Add(30*time.Millisecond)) {
+		t.Errorf("borrowing beyond MaxBorrow did not wait: %+v, %v", s, c.Now())
+	}
+	if got := df.DecomposeParts(id).Timestamp; !got.Equal(c.Now()) {
+		t.Errorf("time %v, want %v", got, c.Now())
+	}
+
+	df, _ = newDxyflake(ExhaustionSpin, 0)
+	if w, ok := df.waiter.(SpinWaiter); !ok || w.Spin != 10*time.Millisecond {
+		t.Errorf("ExhaustionSpin waiter %#v", df.waiter)
+	}
+}
@@ -0,0 +1,50 @@
+package dxyflake
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultExpiryWarning is the ExpiryWarning used when Settings.ExpiryWarning
+// is 0.
+const DefaultExpiryWarning = 365 * 24 * time.Hour
+
+// ExpiresAt returns the time at which the time part of the IDs of layout l
+// overflows: no ID can be issued from then on.
+func (l Layout) ExpiresAt() time.Time {
+	return l.TickTime(1 << uint(l.BitLenTime))
+}
+
+// ExpiresAt returns the time from which NextID returns ErrOverTimeLimit, as
+// the time part of the IDs of the dxyflake overflows.
+func (df *dxyflake) ExpiresAt() time.Time {
+	return df.layout.ExpiresAt()
+}
+
+// TimeRemaining returns the time left until ExpiresAt by the clock of the
+// dxyflake, e.g. for dashboards. It is capped at the largest
+// time.Duration, about 292 years, and negative once the dxyflake expired.
+func (df *dxyflake) TimeRemaining() time.Duration {
+	return df.ExpiresAt().Sub(df.now())
+}
+
+// warnExpiry sets the time unit from which the IDs of the dxyflake are
+// within warning of ExpiresAt, see Settings.ExpiryAlert.
+func (df *dxyflake) warnExpiry(warning time.Duration, alert func(time.Duration)) {
+	if warning == 0 {
+		warning = DefaultExpiryWarning
+	}
+	df.expiryAlert = alert
+	df.warnTick = df.layout.Tick(df.ExpiresAt().Add(-warning))
+}
+
+// checkExpiry passes the remaining time to the expiry alert once tick is
+// within the warning, the first time only. It is called with df.mutex held
+// or before df is shared.
+func (df *dxyflake) checkExpiry(tick int64) {
+	if df.expiryAlert == nil || tick < df.warnTick {
+		return
+	}
+	df.warnTick = math.MaxInt64
+	go df.expiryAlert(df.TimeRemaining())
+}
@@ -0,0 +1,59 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+	var st Settings
+	st.Init(11, 12)
+	st.StartTime = time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 33, 8, 8, 14
+	expires := st.StartTime.Add(1 << 33 * dxyflakeTimeUnit)
+	c := &steppedClock{now: expires.Add(-2 * DefaultExpiryWarning)}
+	st.Clock = c
+	alerts := make(chan time.Duration, 2)
+	st.ExpiryAlert = func(remaining time.Duration) { alerts <- remaining }
+
+	df := NewDxyflake(st)
+	defer df.Close()
+	if !df.ExpiresAt().Equal(expires) || !df.Layout().ExpiresAt().Equal(expires) {
+		t.Errorf("ExpiresAt() = %s, want %s", df.ExpiresAt(), expires)
+	}
+	if r := df.TimeRemaining(); r != 2*DefaultExpiryWarning {
+		t.Errorf("TimeRemaining() = %v", r)
+	}
+	if _, err := df.NextID(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-alerts:
+		t.Fatalf("alert %v ahead of the warning", r)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	c.Sleep(DefaultExpiryWarning + time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, err := df.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := <-alerts; r != DefaultExpiryWarning-time.Hour {
+		t.Errorf("alerted with %v remaining", r)
+	}
+	select {
+	case r := <-alerts:
+		t.Errorf("second alert: %v", r)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// a dxyflake created within the warning alerts right away
+	st.ExpiryWarning = 3 * DefaultExpiryWarning
+	st.StartTime = st.StartTime.Add(time.Hour)
+	late := NewDxyflake(st)
+	defer late.Close()
+	if r := <-alerts; r > 3*DefaultExpiryWarning {
+		t.Errorf("alerted with %v remaining", r)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !chaos
+// +build !chaos
+
+package dxyflake
+
+import "time"
+
+// Without the chaos build tag the fault hooks are no-ops the compiler inlines.
+
+func clockJump() time.Duration {
+	return 0
+}
+
+func providerFault() error {
+	return nil
+}
+
+func issueFault() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package dxyflake
+
+// These constants extract the parts of IDs of DefaultLayout: a part is
+// (id >> Shift) & Mask, e.g. (id >> ShiftMachineID) & MaskMachineID, so
+// log filters and database computed columns can read one part without
+// Decompose.
+const (
+	ShiftTime      = BitLenMachineID + BitLenServiceID + BitLenSequence
+	ShiftMachineID = BitLenServiceID + BitLenSequence
+	ShiftServiceID = BitLenSequence
+	ShiftSequence  = 0
+
+	MaskTime      = 1<<BitLenTime - 1
+	MaskMachineID = 1<<BitLenMachineID - 1
+	MaskServiceID = 1<<BitLenServiceID - 1
+	MaskSequence  = 1<<BitLenSequence - 1
+)
+
+// TimeBits returns the time part of an ID of DefaultLayout.
+func TimeBits(id ID) int64 {
+	return int64(id) >> ShiftTime & MaskTime
+}
+
+// MachineBits returns the machine ID of an ID of DefaultLayout.
+func MachineBits(id ID) uint16 {
+	return uint16(int64(id) >> ShiftMachineID & MaskMachineID)
+}
+
+// ServiceBits returns the service ID of an ID of DefaultLayout.
+func ServiceBits(id ID) uint16 {
+	return uint16(int64(id) >> ShiftServiceID & MaskServiceID)
+}
+
+// SequenceBits returns the sequence number of an ID of DefaultLayout.
+func SequenceBits(id ID) uint16 {
+	return uint16(int64(id) & MaskSequence)
+}
+
+// Masks are the shifts and masks of the parts of the IDs of a layout, like
+// the Shift and Mask constants are for DefaultLayout.
+type Masks struct {
+	ShiftTime, ShiftMachineID, ShiftServiceID, ShiftSequence uint
+	MaskTime, MaskMachineID, MaskServiceID, MaskSequence     int64
+}
+
+// Masks returns the shifts and masks of the parts of the IDs of the layout.
+func (l Layout) Masks() Masks {
+	return Masks{
+		ShiftTime:      l.shiftTime(),
+		ShiftMachineID: l.shiftMachineID(),
+		ShiftServiceID: uint(l.BitLenSequence),
+		MaskTime:       1<<uint(l.BitLenTime) - 1,
+		MaskMachineID:  1<<uint(l.BitLenMachineID) - 1,
+		MaskServiceID:  1<<uint(l.BitLenServiceID) - 1,
+		MaskSequence:   1<<uint(l.BitLenSequence) - 1,
+	}
+}
+
+// TimeBits returns the time part of id.
+func (m Masks) TimeBits(id ID) int64 {
+	return int64(id) >> m.ShiftTime & m.MaskTime
+}
+
+// MachineBits returns the machine ID of id.
+func (m Masks) MachineBits(id ID) int64 {
+	return int64(id) >> m.ShiftMachineID & m.MaskMachineID
+}
+
+// ServiceBits returns the service ID of id.
+func (m Masks) ServiceBits(id ID) int64 {
+	return int64(id) >> m.ShiftServiceID & m.MaskServiceID
+}
+
+// SequenceBits returns the sequence number of id.
+func (m Masks) SequenceBits(id ID) int64 {
+	return int64(id) >> m.ShiftSequence & m.MaskSequence
+}
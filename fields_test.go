@@ -0,0 +1,33 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFieldBits(t *testing.T) {
+	at := DefaultStartTime.Add(1234*time.Hour + 5678*time.Millisecond)
+	id, err := DefaultLayout.Compose(at, 3, 5, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := Decompose(id)
+	if TimeBits(id) != parts["time"] || MachineBits(id) != 3 || ServiceBits(id) != 5 || SequenceBits(id) != 7 {
+		t.Errorf("unexpected bits: %d %d %d %d", TimeBits(id), MachineBits(id), ServiceBits(id), SequenceBits(id))
+	}
+
+	m := DefaultLayout.Masks()
+	if m != (Masks{ShiftTime, ShiftMachineID, ShiftServiceID, ShiftSequence, MaskTime, MaskMachineID, MaskServiceID, MaskSequence}) {
+		t.Errorf("masks differ from the constants: %+v", m)
+	}
+
+	l := Layout{Unit: 10 * time.Millisecond, BitLenTime: 39, BitLenMachineID: 16, BitLenSequence: 8}
+	id, err = l.Compose(at, 0xbeef, 0, 0xaa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = l.Masks()
+	if m.TimeBits(id) != l.Tick(at) || m.MachineBits(id) != 0xbeef || m.ServiceBits(id) != 0 || m.SequenceBits(id) != 0xaa {
+		t.Errorf("unexpected bits: %d %d %d %d", m.TimeBits(id), m.MachineBits(id), m.ServiceBits(id), m.SequenceBits(id))
+	}
+}
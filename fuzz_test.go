@@ -0,0 +1,119 @@
+//go:build go1.18
+// +build go1.18
+
+package dxyflake
+
+import (
+	"strings"
+	"testing"
+)
+
+// stringParsers are the parsers of the string encodings of IDs, with the
+// encoding that writes what they read.
+var stringParsers = []struct {
+	name   string
+	parse  func(string) (ID, error)
+	format func(ID) string
+	strict bool // refuses IDs with the sign bit set
+}{
+	{"decimal", ParseString, ID.String, false},
+	{"base2", ParseBase2, ID.Base2, false},
+	{"base32", func(s string) (ID, error) { return ParseBase32([]byte(s)) }, ID.Base32, true},
+	{"crockford32", ParseCrockford32, ID.Crockford32, true},
+	{"checked", ParseChecked, ID.StringChecked, true},
+	{"sortable", ParseSortableString, ID.SortableString, true},
+	{"base36", ParseBase36, ID.Base36, false},
+	{"base58", func(s string) (ID, error) { return ParseBase58([]byte(s)) }, ID.Base58, true},
+	{"base62", func(s string) (ID, error) { return ParseBase62([]byte(s)) }, ID.Base62, true},
+	{"hex", ParseHex, ID.Hex, true},
+	{"base64", ParseBase64, ID.Base64, true},
+}
+
+// FuzzParse checks that no parser panics, that strict parsers never return
+// IDs with the sign bit set, and that every ID a parser returns encodes
+// to a string it parses back to the same ID.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"", "0", "-1", "29829153948057600", "7ZZZZZZZZZZZZ", "FZZZZZZZZZZZZ",
+		"ffffffffffffffff", "LTE=", "Mjk4MjkxNTM5NDgwNTc2MDA=", "zzzzzzzzzzzzzzzzz", "1-4u", "a-b"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, p := range stringParsers {
+			id, err := p.parse(s)
+			if err != nil {
+				continue
+			}
+			if p.strict && id < 0 {
+				t.Fatalf("%s: %q parsed to %d", p.name, s, id)
+			}
+			if back, err := p.parse(p.format(id)); err != nil || back != id {
+				t.Fatalf("%s: %q parsed to %d, which parses back to %d, %v", p.name, s, id, back, err)
+			}
+		}
+	})
+}
+
+// FuzzParseEnvelope checks that envelopes and signed tokens parse only in
+// the form they are written in.
+func FuzzParseEnvelope(f *testing.F) {
+	key := []byte("fuzz key")
+	f.Add(ID(29829153948057600).Envelope())
+	f.Add(ID(29829153948057600).SignedToken(key))
+	f.Add("dxy1_0")
+	f.Fuzz(func(t *testing.T, s string) {
+		if id, l, err := ParseEnvelope(s); err == nil {
+			if env, err := l.Envelope(id); err != nil || env != s {
+				t.Fatalf("%q parsed to %d, written as %q, %v", s, id, env, err)
+			}
+		}
+		if id, err := ParseSignedToken(s, key); err == nil && id.SignedToken(key) != s {
+			t.Fatalf("token %q parsed to %d", s, id)
+		}
+	})
+}
+
+// FuzzParseUUID checks that UUIDs parse only in their canonical form, in
+// either case, and that FromUUID refuses what it did not write.
+func FuzzParseUUID(f *testing.F) {
+	f.Add(ID(29829153948057600).UUIDv7().String())
+	f.Add(ID(29829153948057600).UUIDv8().String())
+	f.Add("00000000-0000-0000-0000-000000000000")
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := ParseUUID(s)
+		if err != nil {
+			return
+		}
+		if u.String() != strings.ToLower(s) {
+			t.Fatalf("%q parsed to %s", s, u)
+		}
+		if id, err := FromUUID(u); err == nil && id < 0 {
+			t.Fatalf("%s converted to %d", u, id)
+		}
+	})
+}
+
+// FuzzUnmarshal checks the decoders of the JSON, text and binary forms.
+func FuzzUnmarshal(f *testing.F) {
+	f.Add([]byte(`"29829153948057600"`))
+	f.Add([]byte("29829153948057600"))
+	f.Add([]byte{0, 0x69, 0xf9, 0x75, 0x1d, 6, 0x40, 0})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var id ID
+		if err := id.UnmarshalJSON(b); err == nil {
+			var back ID
+			if j, _ := id.MarshalJSON(); back.UnmarshalJSON(j) != nil || back != id {
+				t.Fatalf("JSON %q decoded to %d", b, id)
+			}
+		}
+		if err := id.UnmarshalText(b); err == nil {
+			if back, err := ParseString(string(b)); err != nil || back != id {
+				t.Fatalf("text %q decoded to %d", b, id)
+			}
+		}
+		if err := id.UnmarshalBinary(b); err == nil {
+			if back, _ := id.MarshalBinary(); string(back) != string(b) {
+				t.Fatalf("binary %x decoded to %d", b, id)
+			}
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package dxyflake
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotSorted is returned by GapDetector.Add for an ID not greater than
+// the one before.
+var ErrNotSorted = errors.New("ids are not sorted")
+
+// Gap is a range of sequence numbers, First to Last inclusive, missing in
+// the IDs of one time unit.
+type Gap struct {
+	Tick  int64
+	Time  time.Time
+	First uint16
+	Last  uint16
+}
+
+// Len returns the number of missing sequence numbers.
+func (g Gap) Len() int {
+	return int(g.Last) - int(g.First) + 1
+}
+
+// GapDetector finds missing sequence numbers in a sorted stream of IDs of
+// one node, the same machine ID and service ID. A node numbers the IDs of
+// every time unit from 0 up, so all numbers below the largest one seen in
+// a unit must be there, as when every issued ID should be a persisted row.
+// Missing IDs after the last one seen in a unit cannot be detected, nor
+// can units without any ID.
+type GapDetector struct {
+	layout  Layout
+	started bool
+	last    ID
+	parts   map[string]int64
+	missing int
+}
+
+// NewGapDetector returns a GapDetector for IDs of a dxyflake started at
+// epoch. If epoch is 0, DefaultStartTime is used.
+func NewGapDetector(epoch time.Time) *GapDetector {
+	return &GapDetector{layout: layoutAt(epoch)}
+}
+
+// Add adds the next ID of the stream and returns the gaps before it. It
+// returns ErrNotSorted if id is not greater than the ID before and
+// ErrDifferentNodes if it is from another node; id is not added then.
+func (g *GapDetector) Add(id ID) ([]Gap, error) {
+	p := g.layout.Decompose(id)
+	if g.started {
+		if id <= g.last {
+			return nil, ErrNotSorted
+		}
+		if p["machine-id"] != g.parts["machine-id"] || p["service-id"] != g.parts["service-id"] {
+			return nil, ErrDifferentNodes
+		}
+	}
+
+	first := int64(0)
+	if g.started && p["time"] == g.parts["time"] {
+		first = g.parts["sequence"] + 1
+	}
+	g.started, g.last, g.parts = true, id, p
+	if first == p["sequence"] {
+		return nil, nil
+	}
+	gap := Gap{
+		Tick:  p["time"],
+		Time:  g.layout.TickTime(p["time"]),
+		First: uint16(first),
+		Last:  uint16(p["sequence"] - 1),
+	}
+	g.missing += gap.Len()
+	return []Gap{gap}, nil
+}
+
+// Missing returns the number of missing sequence numbers found so far.
+func (g *GapDetector) Missing() int {
+	return g.missing
+}
+
+// FindGaps returns the gaps in sorted IDs of one node, see GapDetector.
+func FindGaps(ids []ID, epoch time.Time) ([]Gap, error) {
+	g := NewGapDetector(epoch)
+	var gaps []Gap
+	for _, id := range ids {
+		found, err := g.Add(id)
+		if err != nil {
+			return gaps, err
+		}
+		gaps = append(gaps, found...)
+	}
+	return gaps, nil
+}
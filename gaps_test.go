@@ -0,0 +1,83 @@
+package dxyflake
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindGaps(t *testing.T) {
+	at := time.Now().Add(-time.Minute)
+	compose := func(d time.Duration, m, s, seq uint16) ID {
+		id, err := DefaultLayout.Compose(at.Add(d), m, s, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	tick := DefaultLayout.Tick(at)
+
+	ids := []ID{
+		compose(0, 1, 2, 0),
+		compose(0, 1, 2, 1),
+		compose(0, 1, 2, 4), // 2 and 3 missing
+		compose(0, 1, 2, 5),
+		compose(10*time.Millisecond, 1, 2, 2), // 0 and 1 missing
+		compose(30*time.Millisecond, 1, 2, 0),
+		compose(30*time.Millisecond, 1, 2, 2), // 1 missing
+	}
+	gaps, err := FindGaps(ids, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Gap{
+		{Tick: tick, First: 2, Last: 3},
+		{Tick: tick + 1, First: 0, Last: 1},
+		{Tick: tick + 3, First: 1, Last: 1},
+	}
+	for i := range gaps {
+		gaps[i].Time = time.Time{}
+	}
+	if !reflect.DeepEqual(gaps, want) {
+		t.Errorf("unexpected gaps: %+v", gaps)
+	}
+
+	g := NewGapDetector(time.Time{})
+	if _, err := g.Add(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Add(ids[0]); err != ErrNotSorted {
+		t.Errorf("unsorted: got %v", err)
+	}
+	if _, err := g.Add(compose(time.Second, 1, 3, 0)); err != ErrDifferentNodes {
+		t.Errorf("different nodes: got %v", err)
+	}
+	if g.Missing() != 1 {
+		t.Errorf("unexpected missing count: %d", g.Missing())
+	}
+}
+
+func TestFindGapsGenerated(t *testing.T) {
+	var st Settings
+	st.Init(5, 6)
+	gen := NewDxyflake(st)
+	ids := make([]ID, 1000)
+	for i := range ids {
+		id, err := gen.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	if gaps, err := FindGaps(ids, time.Time{}); err != nil || len(gaps) != 0 {
+		t.Errorf("unexpected gaps: %v, %v", gaps, err)
+	}
+	// drop an ID followed by one of the same time unit
+	i := 1
+	for ; i < len(ids)-1 && Decompose(ids[i])["time"] != Decompose(ids[i+1])["time"]; i++ {
+	}
+	gaps, err := FindGaps(append(ids[:i:i], ids[i+1:]...), time.Time{})
+	if err != nil || len(gaps) != 1 || gaps[0].Len() != 1 {
+		t.Errorf("unexpected gaps: %v, %v", gaps, err)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build go1.18
+// +build go1.18
+
+package dxyflake
+
+// Map returns f of every ID of ids.
+func Map[T any](ids []ID, f func(ID) T) []T {
+	out := make([]T, len(ids))
+	for i, id := range ids {
+		out[i] = f(id)
+	}
+	return out
+}
+
+// Filter returns the IDs of ids that keep returns true for, in order.
+func Filter[S ~[]ID](ids S, keep func(ID) bool) S {
+	var out S
+	for _, id := range ids {
+		if keep(id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// MinMax returns the smallest and the largest ID of ids, which are the
+// oldest and the newest for IDs of one node. ok is false if ids is empty.
+func MinMax[S ~[]ID](ids S) (min, max ID, ok bool) {
+	if len(ids) == 0 {
+		return 0, 0, false
+	}
+	min, max = ids[0], ids[0]
+	for _, id := range ids[1:] {
+		if id < min {
+			min = id
+		}
+		if id > max {
+			max = id
+		}
+	}
+	return min, max, true
+}
+
+// The sequence adapters below take and return functions of the shape of
+// iter.Seq, so that with Go 1.23 or later they can be ranged over and
+// passed to the iter and slices packages:
+//
+//	for s := range dxyflake.MapSeq(dxyflake.Values(ids), dxyflake.ID.Base62) {
+//		...
+//	}
+
+// Values returns a function iterating over ids.
+func Values[S ~[]ID](ids S) func(yield func(ID) bool) {
+	return func(yield func(ID) bool) {
+		for _, id := range ids {
+			if !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// MapSeq returns a function iterating over f of every ID of seq.
+func MapSeq[T any](seq func(yield func(ID) bool), f func(ID) T) func(yield func(T) bool) {
+	return func(yield func(T) bool) {
+		seq(func(id ID) bool { return yield(f(id)) })
+	}
+}
+
+// FilterSeq returns a function iterating over the IDs of seq that keep
+// returns true for.
+func FilterSeq(seq func(yield func(ID) bool), keep func(ID) bool) func(yield func(ID) bool) {
+	return func(yield func(ID) bool) {
+		seq(func(id ID) bool { return !keep(id) || yield(id) })
+	}
+}
+
+// Collect returns the IDs of seq.
+func Collect(seq func(yield func(ID) bool)) []ID {
+	var ids []ID
+	seq(func(id ID) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids
+}
@@ -0,0 +1,39 @@
+//go:build go1.18
+// +build go1.18
+
+package dxyflake
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGeneric(t *testing.T) {
+	ids := []ID{5, 3, 9, 1, 7}
+	if got := Map(ids, ID.Int64); !reflect.DeepEqual(got, []int64{5, 3, 9, 1, 7}) {
+		t.Errorf("Map() = %v", got)
+	}
+	odd := func(id ID) bool { return id%2 == 1 }
+	big := func(id ID) bool { return id > 4 }
+	if got := Filter(ids, big); !reflect.DeepEqual(got, []ID{5, 9, 7}) {
+		t.Errorf("Filter() = %v", got)
+	}
+	if min, max, ok := MinMax(ids); !ok || min != 1 || max != 9 {
+		t.Errorf("MinMax() = %d, %d, %v", min, max, ok)
+	}
+	if _, _, ok := MinMax([]ID(nil)); ok {
+		t.Error("MinMax() of no IDs")
+	}
+
+	if got := Collect(FilterSeq(Values(ids), big)); !reflect.DeepEqual(got, []ID{5, 9, 7}) {
+		t.Errorf("FilterSeq() = %v", got)
+	}
+	var strs []string
+	MapSeq(FilterSeq(Values(ids), odd), ID.String)(func(s string) bool {
+		strs = append(strs, s)
+		return len(strs) < 2
+	})
+	if !reflect.DeepEqual(strs, []string{"5", "3"}) {
+		t.Errorf("MapSeq() did not stop early: %v", strs)
+	}
+}
@@ -1,3 +1,10 @@
 module github.com/GiterLab/dxyflake
 
 go 1.17
+
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/fsnotify/fsnotify v1.7.0
+	golang.org/x/sys v0.4.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,28 @@
+package gormid_test
+
+import (
+	"fmt"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/gormid"
+)
+
+type User struct {
+	ID   dxyflake.ID `gorm:"primaryKey"`
+	Name string
+}
+
+func ExampleFiller_Fill() {
+	var st dxyflake.Settings
+	st.Init(3, 4)
+	f := gormid.New(dxyflake.NewDxyflake(st))
+
+	users := []User{{Name: "ada"}, {Name: "grace", ID: 42}}
+	if err := f.Fill(&users); err != nil {
+		panic(err)
+	}
+	// the new user got an ID, the one with an ID kept it
+	fmt.Println(users[0].ID > 0, users[1].ID)
+	// Output:
+	// true 42
+}
@@ -0,0 +1,142 @@
+// Package gormid fills the primary keys of type dxyflake.ID of records
+// before they are inserted, as GORM fills autoincrement keys, so that
+// models need no BeforeCreate hooks.
+//
+// The package does not depend on GORM; a plugin of a few lines registers a
+// Filler as a create callback:
+//
+//	type plugin struct{ f *gormid.Filler }
+//
+//	func (plugin) Name() string { return "dxyflake" }
+//
+//	func (p plugin) Initialize(db *gorm.DB) error {
+//		return db.Callback().Create().Before("gorm:create").Register("dxyflake:id", func(tx *gorm.DB) {
+//			if err := p.f.FillValue(tx.Statement.ReflectValue); err != nil {
+//				tx.AddError(err)
+//			}
+//		})
+//	}
+//
+//	db.Use(plugin{gormid.New(gen)})
+//
+// With sqlx or database/sql, call Fill on the records before the insert;
+// dxyflake.ID implements driver.Valuer and sql.Scanner, so the keys need
+// no other type.
+//
+// A field is filled if it has the type dxyflake.ID, is 0, and is tagged
+// `dxyflake:"auto"`, is a GORM primary key, tagged `gorm:"primaryKey"`,
+// or is named ID. `dxyflake:"-"` leaves a field alone. Fields of embedded
+// structs are filled like those of the record.
+package gormid
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrNotAddressable is returned by Fill for records passed by value, whose
+// fields cannot be set.
+var ErrNotAddressable = errors.New("record is not addressable, pass a pointer")
+
+var idType = reflect.TypeOf(dxyflake.ID(0))
+
+// Filler fills the dxyflake.ID keys of records with IDs of a generator.
+type Filler struct {
+	gen    dxyflake.Generator
+	fields sync.Map // reflect.Type to [][]int, the indexes of its key fields
+}
+
+// New returns a Filler issuing IDs with gen.
+func New(gen dxyflake.Generator) *Filler {
+	return &Filler{gen: gen}
+}
+
+// Fill fills the keys of v: a pointer to a struct, or to a slice or an
+// array of structs or of pointers to them, e.g. for batch inserts. Keys
+// that are set already are kept. It returns the error of the generator,
+// leaving the keys filled so far, and ErrNotAddressable if v is not a
+// pointer.
+func (f *Filler) Fill(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return ErrNotAddressable
+	}
+	return f.FillValue(rv)
+}
+
+// FillValue is like Fill for the reflect.Value of a record, e.g. the
+// Statement.ReflectValue of a GORM callback.
+func (f *Filler) FillValue(rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := f.FillValue(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if !rv.CanSet() {
+			return ErrNotAddressable
+		}
+		for _, index := range f.keys(rv.Type()) {
+			field := rv.FieldByIndex(index)
+			if field.Int() != 0 {
+				continue
+			}
+			id, err := f.gen.NextID()
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(id))
+		}
+	}
+	return nil
+}
+
+// keys returns the indexes of the key fields of the struct type t.
+func (f *Filler) keys(t reflect.Type) [][]int {
+	if keys, ok := f.fields.Load(t); ok {
+		return keys.([][]int)
+	}
+	keys := keyFields(t, nil)
+	f.fields.Store(t, keys)
+	return keys
+}
+
+func keyFields(t reflect.Type, prefix []int) [][]int {
+	var keys [][]int
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int(nil), prefix...), i)
+		tag := sf.Tag.Get("dxyflake")
+		switch {
+		case tag == "-" || sf.PkgPath != "" && !sf.Anonymous:
+		case sf.Anonymous && sf.Type.Kind() == reflect.Struct:
+			keys = append(keys, keyFields(sf.Type, index)...)
+		case sf.Type == idType && (tag == "auto" || sf.Name == "ID" || isPrimaryKey(sf.Tag.Get("gorm"))):
+			keys = append(keys, index)
+		}
+	}
+	return keys
+}
+
+// isPrimaryKey reports whether a gorm tag declares a primary key.
+func isPrimaryKey(tag string) bool {
+	for _, setting := range strings.Split(tag, ";") {
+		switch strings.ToLower(strings.TrimSpace(setting)) {
+		case "primarykey", "primary_key":
+			return true
+		}
+	}
+	return false
+}
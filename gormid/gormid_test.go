@@ -0,0 +1,67 @@
+package gormid
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+type counter struct {
+	next dxyflake.ID
+	err  error
+}
+
+func (c *counter) NextID() (dxyflake.ID, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.next++
+	return c.next, nil
+}
+
+type base struct {
+	ID dxyflake.ID
+}
+
+type order struct {
+	base
+	Key      dxyflake.ID `gorm:"column:key;primaryKey"`
+	Ref      dxyflake.ID `dxyflake:"auto"`
+	Parent   dxyflake.ID
+	Customer dxyflake.ID `gorm:"index"`
+	Skipped  dxyflake.ID `dxyflake:"-" gorm:"primary_key"`
+	Name     string
+}
+
+func TestFill(t *testing.T) {
+	f := New(&counter{})
+	o := order{Parent: 7}
+	o.Ref = 100
+	if err := f.Fill(&o); err != nil {
+		t.Fatal(err)
+	}
+	if o.base.ID != 1 || o.Key != 2 || o.Ref != 100 || o.Parent != 7 || o.Customer != 0 || o.Skipped != 0 {
+		t.Errorf("unexpected keys: %+v", o)
+	}
+
+	batch := []*order{{}, nil, {}}
+	if err := f.Fill(&batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].ID != 3 || batch[2].Key != 7 {
+		t.Errorf("unexpected keys: %+v, %+v", batch[0], batch[2])
+	}
+	rows := [2]order{}
+	if err := f.Fill(&rows); err != nil || rows[1].ID != 12 {
+		t.Errorf("array: %+v, %v", rows, err)
+	}
+
+	if err := f.Fill(order{}); err != ErrNotAddressable {
+		t.Errorf("Fill() of a value = %v", err)
+	}
+	errGen := errors.New("generator failed")
+	if err := New(&counter{err: errGen}).Fill(&order{}); err != errGen {
+		t.Errorf("Fill() error = %v, want %v", err, errGen)
+	}
+}
@@ -0,0 +1,108 @@
+package grpcid
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// maxResponse is the largest response message the client reads, enough for
+// batches of many thousand IDs.
+const maxResponse = 4 << 20
+
+// Client calls an IDService.
+type Client struct {
+	target string
+	client *http.Client
+}
+
+// NewClient returns a client of the IDService at target, a base URL such
+// as "https://ids.example.com". The HTTP client must speak HTTP/2 to it;
+// http.DefaultClient does over TLS.
+func NewClient(target string, c *http.Client) *Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return &Client{target: strings.TrimSuffix(target, "/"), client: c}
+}
+
+// GenerateID issues an ID.
+func (c *Client) GenerateID(ctx context.Context) (dxyflake.ID, error) {
+	resp, err := c.call(ctx, "GenerateID", nil)
+	if err != nil {
+		return 0, err
+	}
+	return decodeID(resp)
+}
+
+// GenerateIDs issues n IDs.
+func (c *Client) GenerateIDs(ctx context.Context, n int) ([]dxyflake.ID, error) {
+	resp, err := c.call(ctx, "GenerateIDs", encodeCount(n))
+	if err != nil {
+		return nil, err
+	}
+	return decodeIDs(resp)
+}
+
+// Decompose returns the parts of id, of the layout of the service.
+func (c *Client) Decompose(ctx context.Context, id dxyflake.ID) (dxyflake.Parts, error) {
+	resp, err := c.call(ctx, "Decompose", encodeID(id))
+	if err != nil {
+		return dxyflake.Parts{}, err
+	}
+	return decodeParts(resp)
+}
+
+// call makes a unary call with the deadline of ctx as its grpc-timeout.
+// A failed call returns a *Status.
+func (c *Client) call(ctx context.Context, method string, msg []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.target+"/"+ServiceName+"/"+method, bytes.NewReader(frame(msg)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", formatTimeout(time.Until(deadline)))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Status{Unknown, "http status " + resp.Status}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5+maxResponse))
+	if err != nil {
+		return nil, err
+	}
+	// the status is in the trailers, or in the headers of a response
+	// without a body
+	code := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	n, err := strconv.ParseUint(code, 10, 32)
+	if err != nil {
+		return nil, &Status{Unknown, fmt.Sprintf("invalid grpc-status %q", code)}
+	}
+	if Code(n) != OK {
+		if m, err := url.PathUnescape(message); err == nil {
+			message = m
+		}
+		return nil, &Status{Code(n), message}
+	}
+	return readMessage(bytes.NewReader(body), maxResponse)
+}
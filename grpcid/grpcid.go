@@ -0,0 +1,104 @@
+// Package grpcid serves dxyflake IDs over gRPC, see dxyflake.proto, and
+// calls such a service.
+//
+// The service IDService has the methods GenerateID, GenerateIDs and
+// Decompose. Server implements it on top of net/http, so that it needs no
+// gRPC runtime: register it at "/" or at "/dxyflake.v1.IDService/" of an
+// http.Server serving HTTP/2, over TLS or, from Go 1.24, over cleartext
+// with http.Server.Protocols enabling unencrypted HTTP/2. Clients
+// generated from dxyflake.proto by protoc call it like any gRPC service.
+//
+// The grpc-timeout of a call is the deadline of the context the
+// generator waits with, so a call never waits for an ID longer than its
+// client does.
+package grpcid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// ServiceName is the full name of the service of dxyflake.proto.
+const ServiceName = "dxyflake.v1.IDService"
+
+// Code is a gRPC status code.
+type Code uint32
+
+// These are the status codes returned by Server.
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	Unauthenticated    Code = 16
+)
+
+// Status is the error of a failed call.
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("grpc status %d: %s", s.Code, s.Message)
+}
+
+// codes maps the error codes of package server to gRPC status codes.
+var codes = map[server.Code]Code{
+	server.CodeInvalidRequest:    InvalidArgument,
+	server.CodeUnauthorized:      Unauthenticated,
+	server.CodeForbidden:         PermissionDenied,
+	server.CodeUnknownTenant:     NotFound,
+	server.CodeMethodNotAllowed:  Unimplemented,
+	server.CodeBatchTooLarge:     OutOfRange,
+	server.CodeQuotaExceeded:     ResourceExhausted,
+	server.CodeSequenceExhausted: Unavailable,
+	server.CodeLeaseLost:         Unavailable,
+	server.CodeClockAnomaly:      Unavailable,
+	server.CodeUnavailable:       Unavailable,
+	server.CodeTimeExhausted:     FailedPrecondition,
+	server.CodeInternal:          Internal,
+}
+
+// statusOf returns the Status of an error of a generator, classified like
+// package server does.
+func statusOf(err error) *Status {
+	var s *Status
+	switch {
+	case errors.As(err, &s):
+		return s
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Status{DeadlineExceeded, err.Error()}
+	case errors.Is(err, context.Canceled):
+		return &Status{Canceled, err.Error()}
+	case errors.Is(err, dxyflake.ErrSequenceExhausted):
+		return &Status{Unavailable, err.Error()}
+	}
+	e := server.Classify(err)
+	code, ok := codes[e.Code]
+	if !ok {
+		code = Unknown
+	}
+	return &Status{code, e.Message}
+}
+
+// layoutOf returns the layout of the IDs of gen.
+func layoutOf(gen dxyflake.Generator) dxyflake.Layout {
+	if l, ok := gen.(interface{ Layout() dxyflake.Layout }); ok {
+		return l.Layout()
+	}
+	return dxyflake.DefaultLayout
+}
@@ -0,0 +1,146 @@
+package grpcid
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func newTestClient(t *testing.T, gen dxyflake.Generator, opts Options) *Client {
+	ts := httptest.NewUnstartedServer(NewServer(gen, opts))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return NewClient(ts.URL, ts.Client())
+}
+
+func TestService(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(1, 3)
+	gen := dxyflake.NewDxyflake(st)
+	if gen == nil {
+		t.Fatal("dxyflake not created")
+	}
+	c := newTestClient(t, gen, Options{MaxBatch: 10})
+	ctx := context.Background()
+
+	id, err := c.GenerateID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= 0 {
+		t.Errorf("GenerateID() = %d", id)
+	}
+	ids, err := c.GenerateIDs(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 10 || ids[0] <= id {
+		t.Errorf("GenerateIDs() = %v", ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("IDs out of order: %v", ids)
+		}
+	}
+
+	parts, err := c.Decompose(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := dxyflake.DecomposeParts(id)
+	if !parts.Timestamp.Equal(want.Timestamp) {
+		t.Errorf("time %v, want %v", parts.Timestamp, want.Timestamp)
+	}
+	parts.Timestamp = want.Timestamp
+	if parts != want {
+		t.Errorf("Decompose() = %+v, want %+v", parts, want)
+	}
+
+	var s *Status
+	if _, err := c.GenerateIDs(ctx, 11); !errors.As(err, &s) || s.Code != OutOfRange {
+		t.Errorf("batch over the limit: %v", err)
+	}
+	if _, err := c.GenerateIDs(ctx, 0); !errors.As(err, &s) || s.Code != InvalidArgument {
+		t.Errorf("empty batch: %v", err)
+	}
+	if _, err := c.call(ctx, "Unknown", nil); !errors.As(err, &s) || s.Code != Unimplemented {
+		t.Errorf("unknown method: %v", err)
+	}
+}
+
+// deadlineGenerator records the deadline it is called with.
+type deadlineGenerator struct {
+	deadline time.Time
+	err      error
+}
+
+func (g *deadlineGenerator) NextID() (dxyflake.ID, error) {
+	return 1, nil
+}
+
+func (g *deadlineGenerator) NextIDContext(ctx context.Context) (dxyflake.ID, error) {
+	g.deadline, _ = ctx.Deadline()
+	return 1, g.err
+}
+
+func TestDeadline(t *testing.T) {
+	gen := &deadlineGenerator{}
+	c := newTestClient(t, gen, Options{})
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if _, err := c.GenerateID(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := gen.deadline.Sub(deadline); d < -time.Second || d > time.Second {
+		t.Errorf("deadline %v, want about %v", gen.deadline, deadline)
+	}
+
+	var s *Status
+	gen.err = context.DeadlineExceeded
+	if _, err := c.GenerateID(ctx); !errors.As(err, &s) || s.Code != DeadlineExceeded {
+		t.Errorf("expired call: %v", err)
+	}
+	gen.err = dxyflake.ErrSequenceExhausted
+	if _, err := c.GenerateID(ctx); !errors.As(err, &s) || s.Code != Unavailable {
+		t.Errorf("exhausted sequence: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	for _, d := range []time.Duration{time.Nanosecond, 1500 * time.Millisecond, 30 * time.Hour} {
+		got, ok := parseTimeout(formatTimeout(d))
+		if !ok || got < d || got > d+d/1e7 {
+			t.Errorf("timeout %v: got %v (%s)", d, got, formatTimeout(d))
+		}
+	}
+	for _, v := range []string{"", "1", "1x", "123456789S", "-1S"} {
+		if _, ok := parseTimeout(v); ok {
+			t.Errorf("parseTimeout(%q) accepted", v)
+		}
+	}
+}
+
+func TestPartsRegion(t *testing.T) {
+	for _, p := range []dxyflake.Parts{
+		{ID: 1 << 20, MachineID: 16, RegionID: 2, NodeID: 0},
+		{ID: 1 << 20, MachineID: 21, RegionID: 2, NodeID: 5},
+		{ID: 1 << 20, MachineID: 5, NodeID: 5},
+	} {
+		p.Timestamp = dxyflake.DefaultStartTime
+		got, err := decodeParts(encodeParts(p))
+		if err != nil || !got.Timestamp.Equal(p.Timestamp) {
+			t.Fatalf("decodeParts() = %+v, %v", got, err)
+		}
+		got.Timestamp = p.Timestamp
+		if got != p {
+			t.Errorf("decodeParts() = %+v, want %+v", got, p)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package grpcid
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// maxRequest is the largest request message the server reads. Requests
+// carry one number, so anything larger is not a request of the service.
+const maxRequest = 1 << 10
+
+// Options configures a Server.
+//
+// MaxBatch is the largest count of a GenerateIDs call,
+// server.DefaultMaxBatch if 0.
+type Options struct {
+	MaxBatch int
+}
+
+// Server is the IDService of a generator, an http.Handler.
+type Server struct {
+	gen    dxyflake.Generator
+	layout dxyflake.Layout
+	opts   Options
+}
+
+// NewServer returns the IDService of gen. Decompose uses the layout of gen
+// if it has one, DefaultLayout otherwise.
+func NewServer(gen dxyflake.Generator, opts Options) *Server {
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = server.DefaultMaxBatch
+	}
+	return &Server{gen: gen, layout: layoutOf(gen), opts: opts}
+}
+
+// ServeHTTP serves a unary call of the service.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "not a gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	ctx := r.Context()
+	if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := readMessage(r.Body, maxRequest)
+	if err != nil {
+		writeStatus(w, &Status{InvalidArgument, err.Error()})
+		return
+	}
+	var resp []byte
+	switch strings.TrimPrefix(r.URL.Path, "/"+ServiceName+"/") {
+	case "GenerateID":
+		resp, err = s.generateID(ctx)
+	case "GenerateIDs":
+		resp, err = s.generateIDs(ctx, req)
+	case "Decompose":
+		resp, err = s.decompose(req)
+	default:
+		err = &Status{Unimplemented, "unknown method " + r.URL.Path}
+	}
+	if err != nil {
+		writeStatus(w, statusOf(err))
+		return
+	}
+	w.Write(frame(resp))
+	writeStatus(w, &Status{Code: OK})
+}
+
+func (s *Server) generateID(ctx context.Context) ([]byte, error) {
+	id, err := s.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return encodeID(id), nil
+}
+
+func (s *Server) generateIDs(ctx context.Context, req []byte) ([]byte, error) {
+	count, err := decodeCount(req)
+	if err != nil {
+		return nil, &Status{InvalidArgument, err.Error()}
+	}
+	if count <= 0 {
+		return nil, &Status{InvalidArgument, "count must be positive"}
+	}
+	if count > s.opts.MaxBatch {
+		return nil, &Status{OutOfRange,
+			fmt.Sprintf("%d IDs exceed the batch limit of %d", count, s.opts.MaxBatch)}
+	}
+	if b, ok := s.gen.(interface {
+		NextIDs(n int) ([]dxyflake.ID, error)
+	}); ok {
+		ids, err := b.NextIDs(count)
+		if err != nil {
+			return nil, err
+		}
+		return encodeIDs(ids), nil
+	}
+	ids := make([]dxyflake.ID, count)
+	for i := range ids {
+		if ids[i], err = s.next(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return encodeIDs(ids), nil
+}
+
+func (s *Server) decompose(req []byte) ([]byte, error) {
+	id, err := decodeID(req)
+	if err != nil {
+		return nil, &Status{InvalidArgument, err.Error()}
+	}
+	if id < 0 {
+		return nil, &Status{InvalidArgument, "negative id"}
+	}
+	return encodeParts(s.layout.DecomposeParts(id)), nil
+}
+
+// next issues an ID, waiting no longer than ctx allows if the generator
+// takes a context.
+func (s *Server) next(ctx context.Context) (dxyflake.ID, error) {
+	if c, ok := s.gen.(interface {
+		NextIDContext(ctx context.Context) (dxyflake.ID, error)
+	}); ok {
+		return c.NextIDContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.gen.NextID()
+}
+
+// writeStatus ends a call with the status trailers.
+func writeStatus(w http.ResponseWriter, s *Status) {
+	w.Header().Set("Grpc-Status", strconv.FormatUint(uint64(s.Code), 10))
+	if s.Message != "" {
+		w.Header().Set("Grpc-Message", encodeMessage(s.Message))
+	}
+}
+
+// frame returns the length-prefixed message of a gRPC stream, never
+// compressed.
+func frame(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// readMessage reads the length-prefixed message of a unary call, of at
+// most max bytes.
+func readMessage(r io.Reader, max int) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	if prefix[0] != 0 {
+		return nil, &Status{Unimplemented, "compressed messages are not supported"}
+	}
+	n := binary.BigEndian.Uint32(prefix[1:])
+	if n > uint32(max) {
+		return nil, &Status{ResourceExhausted, fmt.Sprintf("message of %d bytes exceeds %d", n, max)}
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("reading message: %w", err)
+	}
+	return msg, nil
+}
+
+// timeoutUnits are the units of the grpc-timeout header.
+var timeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseTimeout parses a grpc-timeout header, at most 8 digits and a unit.
+func parseTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+	unit, ok := timeoutUnits[v[len(v)-1]]
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// formatTimeout returns the grpc-timeout header of d, rounded up to the
+// unit that keeps it in 8 digits.
+func formatTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "0n"
+	}
+	for _, u := range []struct {
+		unit time.Duration
+		name string
+	}{{time.Nanosecond, "n"}, {time.Microsecond, "u"}, {time.Millisecond, "m"},
+		{time.Second, "S"}, {time.Minute, "M"}} {
+		if n := (d + u.unit - 1) / u.unit; n < 1e8 {
+			return strconv.FormatInt(int64(n), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64((d+time.Hour-1)/time.Hour), 10) + "H"
+}
+
+// encodeMessage percent-encodes a grpc-message header.
+func encodeMessage(m string) string {
+	var b strings.Builder
+	for i := 0; i < len(m); i++ {
+		if c := m[i]; c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package grpcid
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// errMessage is returned for protobuf messages that cannot be decoded.
+var errMessage = errors.New("invalid protobuf message")
+
+// The messages of dxyflake.proto are encoded and decoded by hand, as the
+// package does not depend on the protobuf runtime. Unknown fields are
+// skipped, like protobuf decoders do.
+
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b // the default value is not written
+	}
+	b = appendUvarint(b, uint64(field)<<3|wireVarint)
+	return appendUvarint(b, v)
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendUvarint(b, uint64(field)<<3|wireBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// fields calls f for every field of the message b, with the value of
+// varint fields and the content of length-delimited ones.
+func fields(b []byte, f func(field int, typ int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMessage
+		}
+		b = b[n:]
+		field, typ := int(key>>3), int(key&7)
+		var v uint64
+		var data []byte
+		switch typ {
+		case wireVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errMessage
+			}
+			b = b[n:]
+		case wireI64, wireI32:
+			size := 8
+			if typ == wireI32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errMessage
+			}
+			b = b[size:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errMessage
+			}
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return errMessage
+		}
+		if err := f(field, typ, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varints decodes the packed varints of a repeated field.
+func varints(data []byte, f func(uint64)) error {
+	for len(data) > 0 {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errMessage
+		}
+		f(v)
+		data = data[n:]
+	}
+	return nil
+}
+
+func encodeID(id dxyflake.ID) []byte {
+	return appendVarint(nil, 1, uint64(id))
+}
+
+func decodeID(b []byte) (dxyflake.ID, error) {
+	var id dxyflake.ID
+	err := fields(b, func(field, typ int, v uint64, data []byte) error {
+		if field == 1 && typ == wireVarint {
+			id = dxyflake.ID(v)
+		}
+		return nil
+	})
+	return id, err
+}
+
+func encodeCount(count int) []byte {
+	return appendVarint(nil, 1, uint64(int64(count)))
+}
+
+func decodeCount(b []byte) (int, error) {
+	var count int32
+	err := fields(b, func(field, typ int, v uint64, data []byte) error {
+		if field == 1 && typ == wireVarint {
+			count = int32(v)
+		}
+		return nil
+	})
+	return int(count), err
+}
+
+func encodeIDs(ids []dxyflake.ID) []byte {
+	var packed []byte
+	for _, id := range ids {
+		packed = appendUvarint(packed, uint64(id))
+	}
+	return appendBytes(nil, 1, packed)
+}
+
+func decodeIDs(b []byte) ([]dxyflake.ID, error) {
+	var ids []dxyflake.ID
+	err := fields(b, func(field, typ int, v uint64, data []byte) error {
+		if field != 1 {
+			return nil
+		}
+		if typ == wireVarint { // not packed
+			ids = append(ids, dxyflake.ID(v))
+			return nil
+		}
+		return varints(data, func(v uint64) { ids = append(ids, dxyflake.ID(v)) })
+	})
+	return ids, err
+}
+
+func encodeParts(p dxyflake.Parts) []byte {
+	b := appendVarint(nil, 1, uint64(p.ID))
+	b = appendBytes(b, 2, []byte(p.Timestamp.UTC().Format(time.RFC3339Nano)))
+	b = appendVarint(b, 3, uint64(p.Time))
+	b = appendVarint(b, 4, uint64(p.MachineID))
+	b = appendVarint(b, 5, uint64(p.ServiceID))
+	b = appendVarint(b, 6, uint64(p.Sequence))
+	if p.RegionID != 0 || p.NodeID != p.MachineID {
+		b = appendVarint(b, 7, uint64(p.RegionID))
+		b = appendUvarint(b, 8<<3|wireVarint) // written even if 0, see node_id
+		b = appendUvarint(b, uint64(p.NodeID))
+	}
+	return b
+}
+
+func decodeParts(b []byte) (dxyflake.Parts, error) {
+	var p dxyflake.Parts
+	nodeID := -1
+	err := fields(b, func(field, typ int, v uint64, data []byte) error {
+		var err error
+		switch field {
+		case 1:
+			p.ID = dxyflake.ID(v)
+		case 2:
+			p.Timestamp, err = time.Parse(time.RFC3339Nano, string(data))
+		case 3:
+			p.Time = int64(v)
+		case 4:
+			p.MachineID = uint16(v)
+		case 5:
+			p.ServiceID = uint16(v)
+		case 6:
+			p.Sequence = uint16(v)
+		case 7:
+			p.RegionID = uint16(v)
+		case 8:
+			nodeID = int(uint16(v))
+		}
+		return err
+	})
+	p.NodeID = p.MachineID
+	if nodeID >= 0 {
+		p.NodeID = uint16(nodeID)
+	}
+	return p, err
+}
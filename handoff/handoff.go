@@ -0,0 +1,61 @@
+// Package handoff passes a machine ID from a draining dxyflake to its
+// successor without the two ever issuing from the same time unit.
+//
+// During a blue/green or rolling deploy the old instance calls Release: its
+// generator is drained, the high-water mark is persisted, and only then is
+// the machine ID given back. The new instance calls Acquire before creating
+// its generator, which waits until the persisted mark has passed.
+package handoff
+
+import (
+	"context"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Drainer is implemented by generators that can be stopped for a handoff.
+type Drainer interface {
+	Drain() dxyflake.Mark
+}
+
+// Release drains gen, saves its high-water mark to store and then calls
+// release, if not nil, to give the machine ID back, e.g. by releasing a
+// lease. release is not called when saving the mark fails, so the machine
+// ID stays blocked rather than being reused too early.
+func Release(ctx context.Context, gen Drainer, store Store, release func() error) (dxyflake.Mark, error) {
+	mark := gen.Drain()
+	if err := store.SaveMark(ctx, mark); err != nil {
+		return mark, err
+	}
+	if release != nil {
+		if err := release(); err != nil {
+			return mark, err
+		}
+	}
+	return mark, nil
+}
+
+// Acquire waits until the high-water mark saved for machineID and serviceID,
+// plus margin, has passed. margin covers the clock skew between the old and
+// the new host. Acquire returns at once if no mark was saved.
+func Acquire(ctx context.Context, store Store, machineID, serviceID uint16, margin time.Duration) error {
+	mark, ok, err := store.LoadMark(ctx, machineID, serviceID)
+	if err != nil || !ok {
+		return err
+	}
+
+	wait := time.Until(mark.Next.Add(margin))
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package handoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func newDxyflake(t *testing.T) dxyflake.Generator {
+	var st dxyflake.Settings
+	st.Init(4, 5)
+	df := dxyflake.NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	return df
+}
+
+func testHandoff(t *testing.T, store Store) {
+	old := newDxyflake(t)
+	var last dxyflake.ID
+	for i := 0; i < 3*4096; i++ {
+		id, err := old.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = id
+	}
+
+	released := false
+	mark, err := Release(context.Background(), old.(Drainer), store, func() error {
+		released = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !released {
+		t.Error("machine id not released")
+	}
+	if _, err := old.NextID(); err != dxyflake.ErrDrained {
+		t.Errorf("drained dxyflake issued an id: %v", err)
+	}
+
+	if err := Acquire(context.Background(), store, 4, 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if time.Now().Before(mark.Next) {
+		t.Error("Acquire returned before the mark")
+	}
+
+	successor := newDxyflake(t)
+	id, err := successor.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= last {
+		t.Errorf("successor id %d is not greater than %d", id, last)
+	}
+}
+
+func TestHandoffMemory(t *testing.T) {
+	testHandoff(t, NewMemoryStore())
+}
+
+func TestHandoffFile(t *testing.T) {
+	testHandoff(t, FileStore{Dir: t.TempDir()})
+}
+
+func TestAcquireCancel(t *testing.T) {
+	store := NewMemoryStore()
+	store.SaveMark(context.Background(), dxyflake.Mark{MachineID: 1, Next: time.Now().Add(time.Hour)})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Acquire(ctx, store, 1, 0, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() error = %v", err)
+	}
+	if err := Acquire(context.Background(), store, 2, 0, 0); err != nil {
+		t.Errorf("Acquire() without a mark error = %v", err)
+	}
+}
+
+type failingStore struct {
+	Store
+}
+
+func (failingStore) SaveMark(ctx context.Context, mark dxyflake.Mark) error {
+	return errors.New("store down")
+}
+
+func TestReleaseKeepsMachineIDOnFailure(t *testing.T) {
+	released := false
+	_, err := Release(context.Background(), newDxyflake(t).(Drainer), failingStore{}, func() error {
+		released = true
+		return nil
+	})
+	if err == nil || released {
+		t.Errorf("machine id released after a failed save: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package handoff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Store persists high-water marks per (machine ID, service ID).
+type Store interface {
+	SaveMark(ctx context.Context, mark dxyflake.Mark) error
+	LoadMark(ctx context.Context, machineID, serviceID uint16) (dxyflake.Mark, bool, error)
+}
+
+type pair struct {
+	machineID uint16
+	serviceID uint16
+}
+
+// MemoryStore keeps marks in memory, for tests and for handoffs between
+// generators of one process.
+type MemoryStore struct {
+	mutex sync.Mutex
+	marks map[pair]dxyflake.Mark
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{marks: make(map[pair]dxyflake.Mark)}
+}
+
+// SaveMark stores mark, keeping the later one if a mark is already stored.
+func (s *MemoryStore) SaveMark(ctx context.Context, mark dxyflake.Mark) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := pair{mark.MachineID, mark.ServiceID}
+	if prev, ok := s.marks[key]; !ok || mark.Next.After(prev.Next) {
+		s.marks[key] = mark
+	}
+	return nil
+}
+
+// LoadMark returns the mark stored for machineID and serviceID.
+func (s *MemoryStore) LoadMark(ctx context.Context, machineID, serviceID uint16) (dxyflake.Mark, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	mark, ok := s.marks[pair{machineID, serviceID}]
+	return mark, ok, nil
+}
+
+// FileStore keeps one JSON file per mark in Dir, for instances sharing a
+// host or a volume.
+type FileStore struct {
+	Dir string
+}
+
+func (s FileStore) path(machineID, serviceID uint16) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("mark-%d-%d.json", machineID, serviceID))
+}
+
+// SaveMark writes mark atomically, keeping the later one if a mark is
+// already stored.
+func (s FileStore) SaveMark(ctx context.Context, mark dxyflake.Mark) error {
+	prev, ok, err := s.LoadMark(ctx, mark.MachineID, mark.ServiceID)
+	if err != nil {
+		return err
+	}
+	if ok && !mark.Next.After(prev.Next) {
+		return nil
+	}
+
+	b, err := json.Marshal(mark)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, "mark-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(mark.MachineID, mark.ServiceID))
+}
+
+// LoadMark reads the mark stored for machineID and serviceID.
+func (s FileStore) LoadMark(ctx context.Context, machineID, serviceID uint16) (dxyflake.Mark, bool, error) {
+	var mark dxyflake.Mark
+
+	b, err := os.ReadFile(s.path(machineID, serviceID))
+	if errors.Is(err, os.ErrNotExist) {
+		return mark, false, nil
+	}
+	if err != nil {
+		return mark, false, err
+	}
+	if err := json.Unmarshal(b, &mark); err != nil {
+		return mark, false, err
+	}
+	return mark, true, nil
+}
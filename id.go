@@ -3,18 +3,25 @@ package dxyflake
 import (
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 const (
-	encodeBase32Map = "ybndrfg8ejkmcpqxot1uwisza345h769"
-	encodeBase58Map = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	encodeBase32Map    = "ybndrfg8ejkmcpqxot1uwisza345h769"
+	encodeCrockfordMap = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	encodeBase58Map    = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	encodeBase62Map    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 )
 
 var decodeBase32Map [256]byte
+var decodeCrockfordMap [256]byte
 var decodeBase58Map [256]byte
+var decodeBase62Map [256]byte
 
 // A JSONSyntaxError is returned from UnmarshalJSON if an invalid ID is provided.
 type JSONSyntaxError struct {
@@ -31,7 +38,29 @@ var ErrInvalidBase58 = errors.New("invalid base58")
 // ErrInvalidBase32 is returned by ParseBase32 when given an invalid []byte
 var ErrInvalidBase32 = errors.New("invalid base32")
 
-// Create maps for decoding Base58/Base32.
+// ErrInvalidCrockford32 is returned by ParseCrockford32 when given an invalid string
+var ErrInvalidCrockford32 = errors.New("invalid crockford base32")
+
+// ErrChecksum is returned by ParseChecked when the check symbol does not
+// match the ID, e.g. for a mistyped or transposed character.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// ErrInvalidSortable is returned by ParseSortableString when given an invalid string
+var ErrInvalidSortable = errors.New("invalid sortable string")
+
+// ErrInvalidBase62 is returned by ParseBase62 when given an invalid []byte
+var ErrInvalidBase62 = errors.New("invalid base62")
+
+// ErrInvalidBase64 is returned by ParseBase64 when given an invalid string
+var ErrInvalidBase64 = errors.New("invalid base64")
+
+// ErrInvalidHex is returned by ParseHex when given an invalid string
+var ErrInvalidHex = errors.New("invalid hex")
+
+// ErrInvalidBinary is returned by UnmarshalBinary when given other than 8 bytes
+var ErrInvalidBinary = errors.New("invalid binary id")
+
+// Create maps for decoding Base58/Base32/Base62.
 // This speeds up the process tremendously.
 func init() {
 	for i := 0; i < len(decodeBase58Map); i++ {
@@ -49,6 +78,29 @@ func init() {
 	for i := 0; i < len(encodeBase32Map); i++ {
 		decodeBase32Map[encodeBase32Map[i]] = byte(i)
 	}
+
+	for i := 0; i < len(decodeCrockfordMap); i++ {
+		decodeCrockfordMap[i] = 0xFF
+	}
+
+	for i := 0; i < len(encodeCrockfordMap); i++ {
+		decodeCrockfordMap[encodeCrockfordMap[i]] = byte(i)
+		decodeCrockfordMap[encodeCrockfordMap[i]|0x20] = byte(i)
+	}
+	for _, c := range "oO" {
+		decodeCrockfordMap[c] = 0
+	}
+	for _, c := range "iIlL" {
+		decodeCrockfordMap[c] = 1
+	}
+
+	for i := 0; i < len(decodeBase62Map); i++ {
+		decodeBase62Map[i] = 0xFF
+	}
+
+	for i := 0; i < len(encodeBase62Map); i++ {
+		decodeBase62Map[encodeBase62Map[i]] = byte(i)
+	}
 }
 
 // An ID is a custom type used for a dxyflake ID.  This is used so we can
@@ -121,18 +173,144 @@ func (f ID) Base32() string {
 // NOTE: There are many different base32 implementations so becareful when
 // doing any interoperation.
 func ParseBase32(b []byte) (ID, error) {
-	var id int64
+	return parseBase(b, 32, &decodeBase32Map, ErrInvalidBase32)
+}
 
+// parseBase parses b in the digits of decode. It returns errInvalid for
+// empty strings, invalid digits and values beyond 63 bits.
+func parseBase(b []byte, base int64, decode *[256]byte, errInvalid error) (ID, error) {
+	if len(b) == 0 {
+		return -1, errInvalid
+	}
+	var id int64
 	for i := range b {
-		if decodeBase32Map[b[i]] == 0xFF {
-			return -1, ErrInvalidBase32
+		d := int64(decode[b[i]])
+		if d == 0xFF || id > (math.MaxInt64-d)/base {
+			return -1, errInvalid
 		}
-		id = id*32 + int64(decodeBase32Map[b[i]])
+		id = id*base + d
 	}
-
 	return ID(id), nil
 }
 
+// Crockford32 returns the dxyflake ID in Crockford's base32, using the
+// digits and the uppercase letters but I, L, O and U. Its strings are
+// URL-safe and easy to read out and type, e.g. for customer-facing URLs.
+func (f ID) Crockford32() string {
+	u := uint64(f)
+	var b [13]byte
+	i := len(b)
+	for {
+		i--
+		b[i] = encodeCrockfordMap[u%32]
+		u /= 32
+		if u == 0 {
+			break
+		}
+	}
+	return string(b[i:])
+}
+
+// ParseCrockford32 parses a Crockford base32 string into a dxyflake ID. It
+// ignores case and hyphens, and reads I and L as 1 and O as 0, as the
+// encoding asks for. Values beyond 63 bits are invalid.
+func ParseCrockford32(id string) (ID, error) {
+	var u uint64
+	n := 0
+	for i := 0; i < len(id); i++ {
+		if id[i] == '-' {
+			continue
+		}
+		d := decodeCrockfordMap[id[i]]
+		if d == 0xFF || u > math.MaxInt64>>5 {
+			return -1, ErrInvalidCrockford32
+		}
+		u = u<<5 | uint64(d)
+		n++
+	}
+	if n == 0 {
+		return -1, ErrInvalidCrockford32
+	}
+	return ID(u), nil
+}
+
+// checkSymbols are the check symbols of Crockford's base32: the 32 digits
+// and five more for the remainders 32 to 36.
+const checkSymbols = encodeCrockfordMap + "*~$=U"
+
+// StringChecked returns the dxyflake ID in Crockford's base32 followed by
+// its check symbol, the ID modulo 37, for IDs read out or typed by hand.
+// ParseChecked detects any single wrong character and any transposition
+// of two adjacent characters.
+func (f ID) StringChecked() string {
+	return f.Crockford32() + string(checkSymbols[uint64(f)%37])
+}
+
+// ParseChecked parses a string of StringChecked into a dxyflake ID, read
+// like ParseCrockford32. It returns ErrInvalidCrockford32 for invalid
+// strings and ErrChecksum if the check symbol does not match.
+func ParseChecked(id string) (ID, error) {
+	id = strings.TrimRight(id, "-")
+	if len(id) < 2 {
+		return -1, ErrInvalidCrockford32
+	}
+	c := id[len(id)-1]
+	check := int(decodeCrockfordMap[c])
+	if check == 0xFF {
+		if c == 'u' {
+			c = 'U'
+		}
+		check = strings.IndexByte(checkSymbols, c)
+	}
+	if check < 0 {
+		return -1, ErrInvalidCrockford32
+	}
+	f, err := ParseCrockford32(id[:len(id)-1])
+	if err != nil {
+		return -1, err
+	}
+	if uint64(f)%37 != uint64(check) {
+		return -1, ErrChecksum
+	}
+	return f, nil
+}
+
+// sortableLen is the length of the strings of SortableString.
+const sortableLen = 13
+
+// SortableString returns the dxyflake ID as 13 characters of Crockford's
+// base32, zero-padded and in uppercase. As its characters are in ASCII
+// order and the strings have a fixed length, the strings sort like the
+// IDs, e.g. as string keys in DynamoDB, for every ID from 0 to the largest
+// 63-bit one.
+func (f ID) SortableString() string {
+	u := uint64(f)
+	var b [sortableLen]byte
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = encodeCrockfordMap[u%32]
+		u /= 32
+	}
+	return string(b[:])
+}
+
+// ParseSortableString parses a string of SortableString into a dxyflake
+// ID. Unlike ParseCrockford32, it only accepts the exact form that sorts,
+// 13 uppercase characters.
+func ParseSortableString(id string) (ID, error) {
+	if len(id) != sortableLen || id[0] > '7' {
+		return -1, ErrInvalidSortable
+	}
+	var u uint64
+	for i := 0; i < len(id); i++ {
+		d := decodeCrockfordMap[id[i]]
+		if d == 0xFF || encodeCrockfordMap[d] != id[i] {
+			return -1, ErrInvalidSortable
+		}
+		u = u<<5 | uint64(d)
+	}
+	return ID(u), nil
+}
+
 // Base36 returns a base36 string of the dxyflake ID
 func (f ID) Base36() string {
 	return strconv.FormatInt(int64(f), 36)
@@ -166,31 +344,99 @@ func (f ID) Base58() string {
 
 // ParseBase58 parses a base58 []byte into a dxyflake ID
 func ParseBase58(b []byte) (ID, error) {
-	var id int64
+	return parseBase(b, 58, &decodeBase58Map, ErrInvalidBase58)
+}
 
-	for i := range b {
-		if decodeBase58Map[b[i]] == 0xFF {
-			return -1, ErrInvalidBase58
-		}
-		id = id*58 + int64(decodeBase58Map[b[i]])
+// Base62 returns a base62 string of the dxyflake ID, using the characters
+// 0-9, A-Z and a-z. Unlike Base58 and Base32, its strings sort like the IDs
+// when padded to the same length.
+func (f ID) Base62() string {
+	if f < 62 {
+		return string(encodeBase62Map[f])
 	}
 
-	return ID(id), nil
+	b := make([]byte, 0, 11)
+	for f >= 62 {
+		b = append(b, encodeBase62Map[f%62])
+		f /= 62
+	}
+	b = append(b, encodeBase62Map[f])
+
+	for x, y := 0, len(b)-1; x < y; x, y = x+1, y-1 {
+		b[x], b[y] = b[y], b[x]
+	}
+
+	return string(b)
+}
+
+// ParseBase62 parses a base62 []byte into a dxyflake ID
+func ParseBase62(b []byte) (ID, error) {
+	return parseBase(b, 62, &decodeBase62Map, ErrInvalidBase62)
+}
+
+// Hex returns the dxyflake ID as 16 lowercase hexadecimal digits. As the
+// strings have a fixed length, they sort like the IDs.
+func (f ID) Hex() string {
+	b := f.IntBytes()
+	return hex.EncodeToString(b[:])
+}
+
+// ParseHex parses 16 hexadecimal digits into a dxyflake ID. IDs with the
+// sign bit set are invalid.
+func ParseHex(id string) (ID, error) {
+	var b [8]byte
+	if len(id) != 2*len(b) {
+		return -1, ErrInvalidHex
+	}
+	if _, err := hex.Decode(b[:], []byte(id)); err != nil || b[0]&0x80 != 0 {
+		return -1, ErrInvalidHex
+	}
+	return ParseIntBytes(b), nil
 }
 
 // Base64 returns a base64 string of the dxyflake ID
 func (f ID) Base64() string {
-	return base64.StdEncoding.EncodeToString(f.Bytes())
+	var b [28]byte
+	return string(f.AppendBase64(b[:0]))
 }
 
-// ParseBase64 converts a base64 string into a dxyflake ID
+// AppendBase64 appends the Base64 string of the dxyflake ID to b and
+// returns the extended buffer, without allocating if b has room for it,
+// e.g. for log lines.
+func (f ID) AppendBase64(b []byte) []byte {
+	var digits [20]byte
+	d := strconv.AppendInt(digits[:0], int64(f), 10)
+	n := base64.StdEncoding.EncodedLen(len(d))
+	if cap(b)-len(b) < n {
+		grown := make([]byte, len(b), len(b)+n)
+		copy(grown, b)
+		b = grown
+	}
+	base64.StdEncoding.Encode(b[len(b):len(b)+n], d)
+	return b[:len(b)+n]
+}
+
+// base64Len is the length of the longest Base64 string of an ID, of the
+// 19 digits of the largest one.
+const base64Len = 28
+
+// ParseBase64 converts a base64 string into a dxyflake ID. It only takes
+// the strings Base64 returns for IDs without the sign bit, and returns
+// ErrInvalidBase64 for anything else.
 func ParseBase64(id string) (ID, error) {
-	b, err := base64.StdEncoding.DecodeString(id)
+	var b [base64Len]byte
+	if len(id) > len(b) {
+		return -1, ErrInvalidBase64
+	}
+	n, err := base64.StdEncoding.Decode(b[:], []byte(id))
 	if err != nil {
-		return -1, err
+		return -1, ErrInvalidBase64
 	}
-	return ParseBytes(b)
-
+	f, ok := parseDecimal(b[:n])
+	if !ok || f < 0 || string(f.AppendBase64(b[:0])) != id {
+		return -1, ErrInvalidBase64
+	}
+	return f, nil
 }
 
 // Bytes returns a byte slice of the dxyflake ID
@@ -200,10 +446,40 @@ func (f ID) Bytes() []byte {
 
 // ParseBytes converts a byte slice into a dxyflake ID
 func ParseBytes(id []byte) (ID, error) {
+	if f, ok := parseDecimal(id); ok {
+		return f, nil
+	}
 	i, err := strconv.ParseInt(string(id), 10, 64)
 	return ID(i), err
 }
 
+// parseDecimal parses the decimal ID b without allocating, and returns
+// false for anything it does not take, which strconv.ParseInt then parses
+// or refuses with its error.
+func parseDecimal(b []byte) (ID, bool) {
+	if len(b) == 0 || len(b) > 20 {
+		return 0, false
+	}
+	neg := b[0] == '-'
+	if neg {
+		if b = b[1:]; len(b) == 0 {
+			return 0, false
+		}
+	}
+	var n int64
+	for _, c := range b {
+		d := int64(c - '0')
+		if c < '0' || c > '9' || n > (math.MaxInt64-d)/10 {
+			return 0, false
+		}
+		n = n*10 + d
+	}
+	if neg {
+		n = -n
+	}
+	return ID(n), true
+}
+
 // IntBytes returns an array of bytes of the dxyflake ID, encoded as a
 // big endian integer.
 func (f ID) IntBytes() [8]byte {
@@ -237,11 +513,74 @@ func (f *ID) UnmarshalJSON(b []byte) error {
 		return JSONSyntaxError{b}
 	}
 
-	i, err := strconv.ParseInt(string(b[1:len(b)-1]), 10, 64)
+	i, err := ParseBytes(b[1 : len(b)-1])
+	if err != nil {
+		return err
+	}
+
+	*f = i
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the ID in
+// decimal, e.g. for map keys in encoding/json, YAML and TOML.
+func (f ID) MarshalText() ([]byte, error) {
+	return f.AppendText(nil)
+}
+
+// AppendText appends the decimal ID to b and returns the extended buffer,
+// without allocating if b has room for it, as encoding.TextAppender asks.
+func (f ID) AppendText(b []byte) ([]byte, error) {
+	return strconv.AppendInt(b, int64(f), 10), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, reading a decimal ID.
+func (f *ID) UnmarshalText(b []byte) error {
+	i, err := ParseBytes(b)
 	if err != nil {
 		return err
 	}
+	*f = i
+	return nil
+}
 
-	*f = ID(i)
+// MarshalBinary implements encoding.BinaryMarshaler, returning the ID as a
+// big endian integer, see IntBytes. encoding/gob uses it.
+func (f ID) MarshalBinary() ([]byte, error) {
+	b := f.IntBytes()
+	return b[:], nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, reading an ID
+// encoded as a big endian integer.
+func (f *ID) UnmarshalBinary(b []byte) error {
+	if len(b) != 8 {
+		return ErrInvalidBinary
+	}
+	*f = ID(int64(binary.BigEndian.Uint64(b)))
 	return nil
 }
+
+// AddTicks returns the ID with n added to its time part, keeping the
+// machine ID, service ID and sequence. It returns ErrOutOfRange if the time
+// part would leave [0, 2^BitLenTime).
+func (f ID) AddTicks(n int64) (ID, error) {
+	const shift = BitLenMachineID + BitLenServiceID + BitLenSequence
+	if f < 0 {
+		return 0, ErrOutOfRange
+	}
+	t := int64(f) >> shift
+	if (n > 0 && t > 1<<BitLenTime-1-n) || t+n < 0 {
+		return 0, ErrOutOfRange
+	}
+	return ID((t+n)<<shift | int64(f)&(1<<shift-1)), nil
+}
+
+// WithSequence returns the ID with its sequence number replaced by seq. It
+// returns ErrOutOfRange if seq does not fit BitLenSequence bits.
+func (f ID) WithSequence(seq uint16) (ID, error) {
+	if f < 0 || seq >= 1<<BitLenSequence {
+		return 0, ErrOutOfRange
+	}
+	return ID(int64(f)&^(1<<BitLenSequence-1) | int64(seq)), nil
+}
@@ -2,6 +2,9 @@ package dxyflake
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -127,6 +130,124 @@ func TestBase32(t *testing.T) {
 	}
 }
 
+func TestCrockford32(t *testing.T) {
+	testReset(t)
+
+	for i := 0; i < 100; i++ {
+		df := nextID(t)
+		pdf, err := ParseCrockford32(df.Crockford32())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if df != pdf {
+			t.Fatal("Parsed does not match String.")
+		}
+	}
+
+	tt := []struct {
+		id  ID
+		enc string
+	}{
+		{0, "0"},
+		{31, "Z"},
+		{32, "10"},
+		{-1, "FZZZZZZZZZZZZ"},
+	}
+	for _, tc := range tt {
+		if got := tc.id.Crockford32(); got != tc.enc {
+			t.Errorf("Crockford32(%d) = %q, expected %q", tc.id, got, tc.enc)
+		}
+	}
+
+	for s, expected := range map[string]ID{"1o": 32, "ilL": 1<<10 | 1<<5 | 1, "a-b": 10<<5 | 11, "0z": 31} {
+		if id, err := ParseCrockford32(s); err != nil || id != expected {
+			t.Errorf("ParseCrockford32(%q) = %d, %v", s, id, err)
+		}
+	}
+	for _, s := range []string{"", "-", "U", "1*", "G0000000000000"} {
+		if _, err := ParseCrockford32(s); err != ErrInvalidCrockford32 {
+			t.Errorf("ParseCrockford32(%q) error = %v", s, err)
+		}
+	}
+}
+
+func TestStringChecked(t *testing.T) {
+	for _, tc := range []struct {
+		id  ID
+		enc string
+	}{
+		{0, "00"},
+		{36, "14U"},
+		{32, "10*"},
+		{1234, "16JD"},
+	} {
+		if got := tc.id.StringChecked(); got != tc.enc {
+			t.Errorf("StringChecked(%d) = %q, expected %q", tc.id, got, tc.enc)
+		}
+		if id, err := ParseChecked(tc.enc); err != nil || id != tc.id {
+			t.Errorf("ParseChecked(%q) = %d, %v", tc.enc, id, err)
+		}
+	}
+	if id, err := ParseChecked("1-4u"); err != nil || id != 36 {
+		t.Errorf("ParseChecked() of lowercase = %d, %v", id, err)
+	}
+
+	// every single wrong character and adjacent transposition is caught
+	s := ID(29829153948057600).StringChecked()
+	for i := 0; i < len(s); i++ {
+		for _, c := range checkSymbols {
+			b := []byte(s)
+			if b[i] == byte(c) {
+				continue
+			}
+			b[i] = byte(c)
+			if _, err := ParseChecked(string(b)); err == nil {
+				t.Errorf("mistyped %q accepted", b)
+			}
+		}
+		if i+1 < len(s) && s[i] != s[i+1] {
+			b := []byte(s)
+			b[i], b[i+1] = b[i+1], b[i]
+			if _, err := ParseChecked(string(b)); err == nil {
+				t.Errorf("transposed %q accepted", b)
+			}
+		}
+	}
+	if _, err := ParseChecked("16J5"); err != ErrChecksum {
+		t.Errorf("ParseChecked() of a wrong check symbol = %v", err)
+	}
+	for _, s := range []string{"", "1", "1#", "*0"} {
+		if _, err := ParseChecked(s); err != ErrInvalidCrockford32 {
+			t.Errorf("ParseChecked(%q) error = %v", s, err)
+		}
+	}
+}
+
+func TestSortableString(t *testing.T) {
+	ids := []ID{0, 1, 31, 32, 1<<40 - 1, 1 << 40, 1<<62 + 5, 1<<63 - 1}
+	for i, id := range ids {
+		s := id.SortableString()
+		if len(s) != 13 {
+			t.Errorf("SortableString(%d) = %q", id, s)
+		}
+		if i > 0 && ids[i-1].SortableString() >= s {
+			t.Errorf("%q does not sort before %q", ids[i-1].SortableString(), s)
+		}
+		if pid, err := ParseSortableString(s); err != nil || pid != id {
+			t.Errorf("ParseSortableString(%q) = %d, %v", s, pid, err)
+		}
+	}
+	if s := ID(1<<63 - 1).SortableString(); s != "7ZZZZZZZZZZZZ" {
+		t.Errorf("largest ID = %q", s)
+	}
+
+	for _, s := range []string{"", "000000000000", "0000000000000z", "000000000000z", "000000000000O", "G000000000000"} {
+		if _, err := ParseSortableString(s); err != ErrInvalidSortable {
+			t.Errorf("ParseSortableString(%q) error = %v", s, err)
+		}
+	}
+}
+
 func TestBase36(t *testing.T) {
 	testReset(t)
 	oID := nextID(t)
@@ -169,6 +290,26 @@ func TestBase58(t *testing.T) {
 	}
 }
 
+func TestBase62(t *testing.T) {
+	testReset(t)
+
+	for i := 0; i < 10; i++ {
+		df := nextID(t)
+		b62 := df.Base62()
+		pdf, err := ParseBase62([]byte(b62))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if df != pdf {
+			t.Fatal("Parsed does not match String.")
+		}
+	}
+
+	if _, err := ParseBase62([]byte("abc-")); err != ErrInvalidBase62 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestBase64(t *testing.T) {
 	testReset(t)
 	oID := nextID(t)
@@ -288,6 +429,110 @@ func TestUnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestMarshalText(t *testing.T) {
+	m := map[ID]ID{13587: -1}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"13587":"-1"}` {
+		t.Fatalf("Got %s", b)
+	}
+	var back map[ID]ID
+	if err := json.Unmarshal(b, &back); err != nil || !reflect.DeepEqual(back, m) {
+		t.Fatalf("Got %v, %v", back, err)
+	}
+
+	var id ID
+	if err := id.UnmarshalText([]byte("invalid")); err == nil {
+		t.Fatal("Expected an error decoding invalid text")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	for _, id := range []ID{0, 7, -1, 29829153948057600, 1<<63 - 1, -1 << 63} {
+		if b, _ := id.AppendText([]byte("id=")); string(b) != "id="+id.String() {
+			t.Errorf("AppendText(%d) = %q", id, b)
+		}
+		if b := id.AppendBase64([]byte("id=")); string(b) != "id="+base64.StdEncoding.EncodeToString(id.Bytes()) {
+			t.Errorf("AppendBase64(%d) = %q", id, b)
+		}
+		if back, err := ParseBase64(id.Base64()); id >= 0 && (err != nil || back != id) || id < 0 && err != ErrInvalidBase64 {
+			t.Errorf("ParseBase64(%q) = %d, %v", id.Base64(), back, err)
+		}
+		var back ID
+		if err := back.UnmarshalText([]byte(id.String())); err != nil || back != id {
+			t.Errorf("UnmarshalText(%q) = %d, %v", id.String(), back, err)
+		}
+	}
+	for _, s := range []string{"", "-", "1a", "9223372036854775808", "-9223372036854775809"} {
+		if _, err := ParseBytes([]byte(s)); err == nil {
+			t.Errorf("ParseBytes(%q) accepted", s)
+		}
+	}
+
+	id := ID(29829153948057600)
+	buf := make([]byte, 0, 64)
+	enc, quoted := id.Base64(), []byte(`"29829153948057600"`)
+	for name, f := range map[string]func(){
+		"AppendText":     func() { id.AppendText(buf[:0]) },
+		"AppendBase64":   func() { id.AppendBase64(buf[:0]) },
+		"ParseBase64":    func() { ParseBase64(enc) },
+		"DecomposeParts": func() { DecomposeParts(id) },
+		"UnmarshalJSON":  func() { id.UnmarshalJSON(quoted) },
+	} {
+		if n := testing.AllocsPerRun(100, f); n != 0 {
+			t.Errorf("%s: %v allocations", name, n)
+		}
+	}
+}
+
+func BenchmarkBase64(b *testing.B) {
+	id := ID(29829153948057600)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = id.Base64()
+	}
+}
+
+func BenchmarkAppendBase64(b *testing.B) {
+	id := ID(29829153948057600)
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = id.AppendBase64(buf[:0])
+	}
+}
+
+func BenchmarkParseBase64(b *testing.B) {
+	s := ID(29829153948057600).Base64()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseBase64(s)
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	type record struct {
+		ID  ID
+		IDs []ID
+	}
+	in := record{ID: 13587, IDs: []ID{1, -1}}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out record
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("Got %v, %v", out, err)
+	}
+
+	var id ID
+	if err := id.UnmarshalBinary([]byte{1, 2}); err != ErrInvalidBinary {
+		t.Fatalf("Expected ErrInvalidBinary, got %v", err)
+	}
+}
+
 func TestParseBase32(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -391,3 +636,91 @@ func TestParseBase58(t *testing.T) {
 		})
 	}
 }
+
+func TestAddTicks(t *testing.T) {
+	id := ID(100<<22 | 3<<17 | 4<<12 | 5)
+
+	got, err := id.AddTicks(-40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := Decompose(got); parts["time"] != 60 || parts["machine-id"] != 3 || parts["service-id"] != 4 || parts["sequence"] != 5 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+
+	max := int64(1<<BitLenTime - 1)
+	if got, err := id.AddTicks(max - 100); err != nil || Decompose(got)["time"] != max {
+		t.Errorf("AddTicks() = %d, %v", got, err)
+	}
+	for _, n := range []int64{-101, max - 99, 1 << 62} {
+		if _, err := id.AddTicks(n); err != ErrOutOfRange {
+			t.Errorf("AddTicks(%d) error = %v", n, err)
+		}
+	}
+}
+
+func TestWithSequence(t *testing.T) {
+	id := ID(100<<22 | 3<<17 | 4<<12 | 5)
+
+	got, err := id.WithSequence(4095)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != id+4090 {
+		t.Errorf("unexpected id: %d", got)
+	}
+	if got, _ := id.WithSequence(0); got != id-5 {
+		t.Errorf("unexpected id: %d", got)
+	}
+	if _, err := id.WithSequence(4096); err != ErrOutOfRange {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHex(t *testing.T) {
+	for _, id := range []ID{0, 475370495148032, 1<<63 - 1} {
+		s := id.Hex()
+		if len(s) != 16 {
+			t.Errorf("%d: got %q", id, s)
+		}
+		got, err := ParseHex(s)
+		if err != nil || got != id {
+			t.Errorf("ParseHex(%q) = %d, %v", s, got, err)
+		}
+	}
+	if s := ID(475370495148032).Hex(); s != "0001b058d1800000" {
+		t.Errorf("got %q", s)
+	}
+	for _, s := range []string{"", "1b05e20000000", "0001b05e2000000g", "0001b05e200000000"} {
+		if _, err := ParseHex(s); err != ErrInvalidHex {
+			t.Errorf("ParseHex(%q) error = %v", s, err)
+		}
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	for _, c := range []struct {
+		parse func(string) (ID, error)
+		in    string
+		err   error
+	}{
+		{func(s string) (ID, error) { return ParseBase32([]byte(s)) }, "", ErrInvalidBase32},
+		{func(s string) (ID, error) { return ParseBase32([]byte(s)) }, "99999999999999", ErrInvalidBase32},
+		{func(s string) (ID, error) { return ParseBase58([]byte(s)) }, "zzzzzzzzzzzz", ErrInvalidBase58},
+		{func(s string) (ID, error) { return ParseBase62([]byte(s)) }, "zzzzzzzzzzzz", ErrInvalidBase62},
+		{func(s string) (ID, error) { return ParseBase62([]byte(s)) }, "ab-c", ErrInvalidBase62},
+		{ParseCrockford32, "FZZZZZZZZZZZZ", ErrInvalidCrockford32},
+		{ParseSortableString, "8000000000000", ErrInvalidSortable},
+		{ParseHex, "8000000000000000", ErrInvalidHex},
+		{ParseBase64, "MQ", ErrInvalidBase64},
+		{ParseBase64, "KzE=", ErrInvalidBase64},
+		{ParseBase64, "LTE=", ErrInvalidBase64},
+		{ParseBase64, "OTIyMzM3MjAzNjg1NDc3NTgwOA==", ErrInvalidBase64},
+		{ParseBase64, "MDAwMDAwMDAwMDAwMDAwMDAwMDAx", ErrInvalidBase64},
+		{ParseBase64, "Mjk4MjkxNTM5NDgwNTc2MDA=AAAAAAAA", ErrInvalidBase64},
+	} {
+		if id, err := c.parse(c.in); err != c.err {
+			t.Errorf("%q parsed to %d, %v; want %v", c.in, id, err, c.err)
+		}
+	}
+}
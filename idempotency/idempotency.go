@@ -0,0 +1,233 @@
+// Package idempotency makes HTTP handlers idempotent by the dxyflake ID a
+// client sends with a request. The first request with an ID and
+// operation runs the handler and stores its response; later ones get the
+// stored response without running it again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/analyze"
+)
+
+// ErrInProgress is returned by Store.Reserve for keys reserved by a
+// request that has not finished.
+var ErrInProgress = errors.New("request in progress")
+
+// DefaultHeader is the header of the IETF idempotency key draft.
+const (
+	DefaultHeader = "Idempotency-Key"
+	DefaultTTL    = 24 * time.Hour
+)
+
+// ReplayedHeader is set to "true" on stored responses sent again.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Key returns the idempotency key of the operation op of the request with
+// ID id.
+func Key(op string, id dxyflake.ID) string {
+	return op + "/" + id.String()
+}
+
+// Response is a stored response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Store keeps idempotency keys and the responses of their requests.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Reserve reserves key for ttl. It returns the stored response of key
+	// if there is one, and ErrInProgress if key is reserved without one.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (*Response, error)
+	// Save stores the response of a reserved key for ttl.
+	Save(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+	// Release drops a reserved key, so that the request may be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type entry struct {
+	resp    *Response
+	expires time.Time
+}
+
+// MemoryStore is a Store in memory, for tests and single instances.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	entries map[string]entry
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]entry)}
+}
+
+// Reserve implements Store.
+func (s *MemoryStore) Reserve(ctx context.Context, key string, ttl time.Duration) (*Response, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		if e.resp == nil {
+			return nil, ErrInProgress
+		}
+		return e.resp, nil
+	}
+	s.entries[key] = entry{expires: now.Add(ttl)}
+	return nil, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(ctx context.Context, key string, resp *Response, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[key] = entry{resp: resp, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Options configures Middleware.
+//
+// Header is the request header carrying the ID, in any encoding.
+// If Header is empty, DefaultHeader is used.
+//
+// Operation names the operation of a request. If Operation is nil, the
+// method and path of the request are used.
+//
+// TTL is how long responses are stored. IDs issued longer than TTL ago
+// are refused, as their keys may have expired already.
+// If TTL is 0, DefaultTTL is used.
+//
+// Layout is the layout of the IDs. If Layout.Unit is 0, DefaultLayout is
+// used.
+//
+// Required refuses requests without an ID instead of passing them on.
+type Options struct {
+	Header    string
+	Operation func(r *http.Request) string
+	TTL       time.Duration
+	Layout    dxyflake.Layout
+	Required  bool
+}
+
+// Middleware returns middleware that runs a handler once per ID and
+// operation and replays its response to later requests, with
+// ReplayedHeader set. Requests arriving while the first one runs are
+// refused with 409 Conflict. Responses with a 5xx status are not stored,
+// so that the request may be retried. The handler finds the ID in the
+// request context as its dxyflake.RequestID.
+func Middleware(store Store, opts Options) func(http.Handler) http.Handler {
+	if opts.Header == "" {
+		opts.Header = DefaultHeader
+	}
+	if opts.Operation == nil {
+		opts.Operation = func(r *http.Request) string { return r.Method + " " + r.URL.Path }
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Layout.Unit == 0 {
+		opts.Layout = dxyflake.DefaultLayout
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := r.Header.Get(opts.Header)
+			if s == "" {
+				if opts.Required {
+					http.Error(w, "missing "+opts.Header+" header", http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, _, err := analyze.ParseLayout(s, "", opts.Layout)
+			if err != nil {
+				http.Error(w, "invalid "+opts.Header+" header: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if time.Since(opts.Layout.TimeOf(id)) > opts.TTL {
+				http.Error(w, opts.Header+" too old", http.StatusUnprocessableEntity)
+				return
+			}
+
+			key := Key(opts.Operation(r), id)
+			resp, err := store.Reserve(r.Context(), key, opts.TTL)
+			switch {
+			case err == ErrInProgress:
+				http.Error(w, "request with the same "+opts.Header+" in progress", http.StatusConflict)
+				return
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			case resp != nil:
+				replay(w, resp)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			saved := false
+			defer func() {
+				if !saved {
+					store.Release(context.Background(), key)
+				}
+			}()
+			next.ServeHTTP(rec, r.WithContext(dxyflake.ContextWithIDKind(r.Context(), dxyflake.RequestID, id)))
+			if rec.status < 500 {
+				saved = store.Save(r.Context(), key, &Response{
+					Status: rec.status,
+					Header: w.Header().Clone(),
+					Body:   rec.body.Bytes(),
+				}, opts.TTL) == nil
+			}
+		})
+	}
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder passes a response on and keeps a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
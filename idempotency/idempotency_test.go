@@ -0,0 +1,102 @@
+package idempotency
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func newTestID(t *testing.T, at time.Time) dxyflake.ID {
+	id, err := dxyflake.DefaultLayout.Compose(at, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls int32
+	gate := make(chan struct{})
+	h := Middleware(NewMemoryStore(), Options{TTL: time.Hour})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if _, ok := dxyflake.IDKindFromContext(r.Context(), dxyflake.RequestID); !ok && r.Header.Get(DefaultHeader) != "" {
+			t.Error("request ID missing from the context")
+		}
+		if r.URL.Path == "/slow" {
+			<-gate
+		}
+		if r.URL.Path == "/fail" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Call", fmt.Sprint(n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "charged")
+	}))
+	serve := func(method, path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			r.Header.Set(DefaultHeader, key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	id := newTestID(t, time.Now().Add(-time.Minute))
+	first := serve("POST", "/payments", id.String())
+	if first.Code != http.StatusCreated || first.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("unexpected first response: %d %v", first.Code, first.Header())
+	}
+	// the same ID in another encoding is the same request
+	again := serve("POST", "/payments", id.Base62())
+	if again.Code != http.StatusCreated || again.Body.String() != "charged" ||
+		again.Header().Get("X-Call") != "1" || again.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("unexpected replay: %d %q %v", again.Code, again.Body, again.Header())
+	}
+	if w := serve("POST", "/refunds", id.String()); w.Header().Get(ReplayedHeader) != "" {
+		t.Error("other operation replayed")
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("handler called %d times, want 2", n)
+	}
+
+	// 5xx responses are not stored
+	if w := serve("POST", "/fail", id.String()); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected response: %d", w.Code)
+	}
+	if w := serve("POST", "/fail", id.String()); w.Header().Get(ReplayedHeader) != "" {
+		t.Error("failed response replayed")
+	}
+
+	// concurrent duplicates are refused
+	done := make(chan int)
+	go func() { done <- serve("POST", "/slow", id.String()).Code }()
+	for atomic.LoadInt32(&calls) < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if w := serve("POST", "/slow", id.String()); w.Code != http.StatusConflict {
+		t.Errorf("duplicate in progress: %d", w.Code)
+	}
+	close(gate)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("unexpected slow response: %d", code)
+	}
+
+	if w := serve("POST", "/payments", ""); w.Code != http.StatusCreated {
+		t.Errorf("request without key refused: %d", w.Code)
+	}
+	if w := serve("POST", "/payments", "not an id!"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid key accepted: %d", w.Code)
+	}
+	old := newTestID(t, time.Now().Add(-2*time.Hour))
+	if w := serve("POST", "/payments", old.String()); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "too old") {
+		t.Errorf("expired key accepted: %d", w.Code)
+	}
+}
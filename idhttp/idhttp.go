@@ -0,0 +1,170 @@
+// Package idhttp reads IDs from HTTP requests in the public formats an API
+// hands them out in, such as signed tokens or anonymized pseudonyms, so
+// that handlers share one parser and one mapping of bad IDs to status
+// codes instead of each parsing IDs its own way.
+package idhttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These errors are wrapped by the *Error of a Parser.
+var (
+	ErrMissing = errors.New("missing id")
+	ErrInvalid = errors.New("invalid id")
+)
+
+// Error is a request ID that cannot be read, with the status to respond
+// with: 404 Not Found for invalid path values, as no resource has such an
+// ID, and 400 Bad Request otherwise.
+type Error struct {
+	Name   string // the path value or query parameter
+	Status int
+	Err    error // ErrMissing or ErrInvalid
+}
+
+func (e *Error) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns e.Err.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WriteError responds to a request with the status of err if it is an
+// *Error, and with 500 Internal Server Error otherwise.
+func WriteError(w http.ResponseWriter, err error) {
+	var e *Error
+	if errors.As(err, &e) {
+		http.Error(w, e.Error(), e.Status)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// Format is a public string form of IDs.
+type Format struct {
+	Name  string
+	Parse func(s string) (dxyflake.ID, error)
+}
+
+// These are the formats of the dxyflake package that need no key.
+var (
+	Decimal  = Format{"decimal", dxyflake.ParseString}
+	Base62   = Format{"base62", parseBase62}
+	Envelope = Format{"envelope", parseEnvelope}
+)
+
+func parseBase62(s string) (dxyflake.ID, error) {
+	id, err := dxyflake.ParseBase62([]byte(s))
+	if err != nil || id.Base62() != s {
+		return 0, ErrInvalid
+	}
+	return id, nil
+}
+
+func parseEnvelope(s string) (dxyflake.ID, error) {
+	id, _, err := dxyflake.ParseEnvelope(s)
+	return id, err
+}
+
+// SignedToken is the format of dxyflake.ID.SignedToken with key.
+func SignedToken(key []byte) Format {
+	return Format{"signed-token", func(s string) (dxyflake.ID, error) {
+		return dxyflake.ParseSignedToken(s, key)
+	}}
+}
+
+// Anonymized is the format of dxyflake.Anonymize pseudonyms with key,
+// written in decimal.
+func Anonymized(key []byte) Format {
+	return Format{"anonymized", func(s string) (dxyflake.ID, error) {
+		pseudonym, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return dxyflake.Deanonymize(pseudonym, key), nil
+	}}
+}
+
+// Parser reads IDs from requests.
+//
+// Formats are the formats IDs are accepted in, tried in turn. If Formats is
+// empty, Base62 is used.
+//
+// Layout is the layout of the IDs: IDs a dxyflake of it could not have
+// issued by a day from now are invalid. If Layout.Unit is 0, DefaultLayout
+// is used.
+type Parser struct {
+	Formats []Format
+	Layout  dxyflake.Layout
+}
+
+// DefaultParser is the Parser used by PathValue and Query.
+var DefaultParser = &Parser{}
+
+// Parse returns the ID s holds in one of the formats of p, and ErrInvalid if
+// it holds none.
+func (p *Parser) Parse(s string) (dxyflake.ID, error) {
+	formats := p.Formats
+	if len(formats) == 0 {
+		formats = []Format{Base62}
+	}
+	l := p.Layout
+	if l.Unit == 0 {
+		l = dxyflake.DefaultLayout
+	}
+	max := l.MaxIDAt(time.Now().Add(24 * time.Hour))
+	for _, f := range formats {
+		id, err := f.Parse(s)
+		if err == nil && id >= 0 && id <= max {
+			return id, nil
+		}
+	}
+	return 0, ErrInvalid
+}
+
+// PathValue returns the ID in the path value name of r, as matched by the
+// pattern of an http.ServeMux, or ErrMissing if the pattern has no such
+// wildcard. Before Go 1.22, and from Go 1.23 for requests not routed by a
+// pattern, it uses the last segment of the path instead.
+func (p *Parser) PathValue(r *http.Request, name string) (dxyflake.ID, error) {
+	return p.read(name, pathValue(r, name), http.StatusNotFound)
+}
+
+func lastSegment(r *http.Request) string {
+	return r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+}
+
+// Query returns the ID in the query parameter name of r.
+func (p *Parser) Query(r *http.Request, name string) (dxyflake.ID, error) {
+	return p.read(name, r.URL.Query().Get(name), http.StatusBadRequest)
+}
+
+func (p *Parser) read(name, s string, status int) (dxyflake.ID, error) {
+	if s == "" {
+		return 0, &Error{Name: name, Status: http.StatusBadRequest, Err: ErrMissing}
+	}
+	id, err := p.Parse(s)
+	if err != nil {
+		return 0, &Error{Name: name, Status: status, Err: err}
+	}
+	return id, nil
+}
+
+// PathValue is DefaultParser.PathValue.
+func PathValue(r *http.Request, name string) (dxyflake.ID, error) {
+	return DefaultParser.PathValue(r, name)
+}
+
+// Query is DefaultParser.Query.
+func Query(r *http.Request, name string) (dxyflake.ID, error) {
+	return DefaultParser.Query(r, name)
+}
@@ -0,0 +1,56 @@
+package idhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestQuery(t *testing.T) {
+	key := []byte("secret")
+	id, err := dxyflake.DefaultLayout.Compose(time.Now(), 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Parser{Formats: []Format{SignedToken(key), Anonymized(key), Base62}}
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"id=" + id.SignedToken(key), 0},
+		{"id=" + strconv.FormatUint(dxyflake.Anonymize(id, key), 10), 0},
+		{"id=" + id.Base62(), 0},
+		{"id=" + id.SignedToken([]byte("other")), http.StatusBadRequest},
+		{"id=" + dxyflake.ID(1<<62).Base62(), http.StatusBadRequest}, // far in the future
+		{"", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/orders?"+tt.query, nil)
+		got, err := p.Query(r, "id")
+		if tt.status == 0 {
+			if err != nil || got != id {
+				t.Errorf("%s: Query() = %d, %v", tt.query, got, err)
+			}
+			continue
+		}
+		var e *Error
+		if !errors.As(err, &e) || e.Status != tt.status {
+			t.Errorf("%s: Query() error = %v", tt.query, err)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/orders", nil)
+	if _, err := Query(r, "id"); !errors.Is(err, ErrMissing) {
+		t.Errorf("missing id: %v", err)
+	}
+	w := httptest.NewRecorder()
+	WriteError(w, &Error{Name: "id", Status: http.StatusNotFound, Err: ErrInvalid})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("WriteError() status = %d", w.Code)
+	}
+}
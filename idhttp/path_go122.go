@@ -0,0 +1,12 @@
+//go:build go1.22 && !go1.23
+// +build go1.22,!go1.23
+
+package idhttp
+
+import "net/http"
+
+// pathValue does not fall back to the last segment: without Request.Pattern
+// a request routed by a pattern lacking name cannot be told apart.
+func pathValue(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
@@ -0,0 +1,45 @@
+//go:build go1.22
+// +build go1.22
+
+package idhttp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestPathValue(t *testing.T) {
+	id, err := dxyflake.DefaultLayout.Compose(time.Now(), 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := PathValue(r, "id")
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		fmt.Fprint(w, id)
+	})
+	serve := func(path, value string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if value != "" {
+			r.SetPathValue("id", value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve("/orders/"+id.Base62()+"/items", id.Base62()); w.Code != http.StatusOK || w.Body.String() != id.String() {
+		t.Errorf("unexpected response: %d %q", w.Code, w.Body)
+	}
+	if w := serve("/orders/not-an-id/items", "not-an-id"); w.Code != http.StatusNotFound {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+}
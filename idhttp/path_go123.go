@@ -0,0 +1,13 @@
+//go:build go1.23
+// +build go1.23
+
+package idhttp
+
+import "net/http"
+
+func pathValue(r *http.Request, name string) string {
+	if v := r.PathValue(name); v != "" || r.Pattern != "" {
+		return v
+	}
+	return lastSegment(r)
+}
@@ -0,0 +1,43 @@
+//go:build go1.23
+// +build go1.23
+
+// The module's go version selects the ServeMux of Go 1.21, without patterns.
+//go:debug httpmuxgo121=0
+
+package idhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestPathValuePattern(t *testing.T) {
+	id, err := dxyflake.DefaultLayout.Compose(time.Now(), 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, got = PathValue(r, "id")
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/orders/{order}/items", h)
+	mux.Handle("/orders/", h)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/"+id.Base62(), nil))
+	if got != nil {
+		t.Errorf("last segment of an unrouted request: %v", got)
+	}
+	for _, path := range []string{"/orders/" + id.Base62() + "/items", "/orders/" + id.Base62()} {
+		got = nil
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		if !errors.Is(got, ErrMissing) {
+			t.Errorf("%s: %v", path, got)
+		}
+	}
+}
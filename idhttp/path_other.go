@@ -0,0 +1,10 @@
+//go:build !go1.22
+// +build !go1.22
+
+package idhttp
+
+import "net/http"
+
+func pathValue(r *http.Request, name string) string {
+	return lastSegment(r)
+}
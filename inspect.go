@@ -0,0 +1,89 @@
+package dxyflake
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNoPlausibleEpoch is returned by DecodeAnyEpoch when an ID is
+// impossible under every epoch.
+var ErrNoPlausibleEpoch = errors.New("no plausible epoch")
+
+// Interpretation is the reading of an ID under one candidate layout, see
+// Inspect. Index is the position of the layout among the candidates.
+// Score ranks how plausible the reading is, from 0, impossible, to 1;
+// Reasons explains every deduction.
+type Interpretation struct {
+	Index   int
+	Layout  Layout
+	Parts   map[string]int64
+	Time    time.Time
+	Score   float64
+	Reasons []string
+}
+
+// Inspect decodes id under every candidate layout and returns the
+// interpretations, the most plausible first. An interpretation is
+// impossible if id has bits set that the layout does not use or a time
+// more than a day in the future. It is less plausible if its time is
+// within a day after the epoch, as when a layout with a later epoch or a
+// coarser unit reads a foreign ID, and the older its time is, as IDs under
+// inspection are mostly recent.
+func Inspect(id ID, candidates []Layout) []Interpretation {
+	now := time.Now()
+	result := make([]Interpretation, len(candidates))
+	for i, l := range candidates {
+		in := Interpretation{Index: i, Layout: l, Score: 1}
+		if l.Validate() != nil {
+			in.Score = 0
+			in.Reasons = append(in.Reasons, "invalid layout")
+			result[i] = in
+			continue
+		}
+		in.Parts = l.Decompose(id)
+		in.Time = l.TimeOf(id)
+
+		switch {
+		case !l.Fits(id):
+			in.Score = 0
+			in.Reasons = append(in.Reasons, "bits beyond the layout are set")
+		case in.Time.After(now.Add(24 * time.Hour)):
+			in.Score = 0
+			in.Reasons = append(in.Reasons, "time is in the future")
+		default:
+			if in.Time.Before(l.epoch().Add(24 * time.Hour)) {
+				in.Score *= 0.5
+				in.Reasons = append(in.Reasons, "time is within a day after the epoch")
+			}
+			if in.Time.Before(now.AddDate(-20, 0, 0)) {
+				in.Score *= 0.5
+				in.Reasons = append(in.Reasons, "time is more than 20 years ago")
+			} else if in.Time.Before(now.AddDate(-1, 0, 0)) {
+				in.Score *= 0.8
+				in.Reasons = append(in.Reasons, "time is more than a year ago")
+			}
+		}
+		result[i] = in
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result
+}
+
+// DecodeAnyEpoch decodes an ID of DefaultLayout stored while IDs of several
+// epochs coexist, as during a migration to a new epoch. It returns the most
+// plausible interpretation by Inspect, whose Index is the position of the
+// chosen epoch; ties go to the epoch listed first. It returns
+// ErrNoPlausibleEpoch if id is impossible under every epoch.
+func DecodeAnyEpoch(id ID, epochs []time.Time) (Interpretation, error) {
+	candidates := make([]Layout, len(epochs))
+	for i, epoch := range epochs {
+		candidates[i] = DefaultLayout
+		candidates[i].Epoch = epoch
+	}
+	result := Inspect(id, candidates)
+	if len(result) == 0 || result[0].Score == 0 {
+		return Interpretation{}, ErrNoPlausibleEpoch
+	}
+	return result[0], nil
+}
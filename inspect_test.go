@@ -0,0 +1,77 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	twitter := Layout{
+		Epoch:           time.UnixMilli(1288834974657),
+		Unit:            time.Millisecond,
+		BitLenTime:      41,
+		BitLenMachineID: 10,
+		BitLenSequence:  12,
+	}
+	sonyflake := Layout{
+		Epoch:           time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC),
+		Unit:            10 * time.Millisecond,
+		BitLenTime:      39,
+		BitLenMachineID: 16,
+		BitLenSequence:  8,
+	}
+	candidates := []Layout{twitter, DefaultLayout, sonyflake, {}}
+
+	at := time.Now().Add(-time.Hour).Truncate(10 * time.Millisecond)
+	id, err := DefaultLayout.Compose(at, 3, 4, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Inspect(id, candidates)
+	if len(result) != len(candidates) {
+		t.Fatalf("got %d interpretations", len(result))
+	}
+	best := result[0]
+	if best.Index != 1 || best.Score != 1 || !best.Time.Equal(at) || best.Parts["service-id"] != 4 {
+		t.Errorf("unexpected best interpretation %+v", best)
+	}
+	if last := result[len(result)-1]; last.Index != 3 || last.Score != 0 {
+		t.Errorf("invalid layout not ranked last: %+v", last)
+	}
+	for _, in := range result[1:] {
+		if in.Score >= 1 || len(in.Reasons) == 0 {
+			t.Errorf("implausible interpretation not penalized: %+v", in)
+		}
+	}
+
+	// a Twitter ID of now reads as far in the future under the default
+	// layout
+	tid := ID(twitter.Tick(time.Now()) << 22)
+	if best := Inspect(tid, candidates)[0]; best.Index != 0 {
+		t.Errorf("Twitter ID read as layout %d", best.Index)
+	}
+}
+
+func TestDecodeAnyEpoch(t *testing.T) {
+	epochs := []time.Time{DefaultStartTime, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	at := time.Now().Add(-time.Hour).Truncate(10 * time.Millisecond)
+	for i, epoch := range epochs {
+		l := DefaultLayout
+		l.Epoch = epoch
+		id, err := l.Compose(at, 3, 4, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in, err := DecodeAnyEpoch(id, epochs)
+		if err != nil || in.Index != i || !in.Time.Equal(at) {
+			t.Errorf("epoch %d: got %+v, %v", i, in, err)
+		}
+	}
+
+	if _, err := DecodeAnyEpoch(-1, epochs); err != ErrNoPlausibleEpoch {
+		t.Errorf("impossible id decoded: %v", err)
+	}
+	if _, err := DecodeAnyEpoch(1, nil); err != ErrNoPlausibleEpoch {
+		t.Errorf("decoded without epochs: %v", err)
+	}
+}
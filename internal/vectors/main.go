@@ -0,0 +1,144 @@
+// Command vectors writes the cross-language test vectors for dxyflake IDs.
+//
+// Run it from the module root to refresh the golden data:
+//
+//	go run ./internal/vectors
+//
+// Every vector holds an ID, its decomposed parts, the timestamp based on
+// dxyflake.DefaultStartTime and every string encoding offered by the ID type.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Layout describes the bit layout the vectors were generated with.
+type Layout struct {
+	BitLenTime      int    `json:"bit-len-time"`
+	BitLenMachineID int    `json:"bit-len-machine-id"`
+	BitLenServiceID int    `json:"bit-len-service-id"`
+	BitLenSequence  int    `json:"bit-len-sequence"`
+	TimeUnitNanos   int64  `json:"time-unit-ns"`
+	StartTime       string `json:"start-time"`
+}
+
+// Vector is a single ID with everything derived from it.
+type Vector struct {
+	ID        string            `json:"id"`
+	Parts     map[string]int64  `json:"parts"`
+	Timestamp string            `json:"timestamp"`
+	Encodings map[string]string `json:"encodings"`
+}
+
+// File is the document written to disk.
+type File struct {
+	Layout  Layout   `json:"layout"`
+	Vectors []Vector `json:"vectors"`
+}
+
+const shiftTime = dxyflake.BitLenMachineID + dxyflake.BitLenServiceID + dxyflake.BitLenSequence
+
+func compose(elapsed, machineID, serviceID, sequence int64) dxyflake.ID {
+	return dxyflake.ID(elapsed<<shiftTime |
+		machineID<<(dxyflake.BitLenServiceID+dxyflake.BitLenSequence) |
+		serviceID<<dxyflake.BitLenSequence |
+		sequence)
+}
+
+func ids() []dxyflake.ID {
+	const (
+		maxTime      = 1<<dxyflake.BitLenTime - 1
+		maxMachineID = 1<<dxyflake.BitLenMachineID - 1
+		maxServiceID = 1<<dxyflake.BitLenServiceID - 1
+		maxSequence  = 1<<dxyflake.BitLenSequence - 1
+	)
+
+	list := []dxyflake.ID{
+		0,
+		1,
+		compose(0, 0, 0, maxSequence),
+		compose(0, 0, 1, 0),
+		compose(0, 1, 0, 0),
+		compose(1, 0, 0, 0),
+		compose(1, maxMachineID, maxServiceID, maxSequence),
+		compose(113337158, 0, 0, 0),
+		compose(maxTime, 0, 0, 0),
+		compose(maxTime, maxMachineID, maxServiceID, maxSequence),
+	}
+
+	r := rand.New(rand.NewSource(20211001))
+	for i := 0; i < 40; i++ {
+		list = append(list, compose(
+			r.Int63n(maxTime+1),
+			r.Int63n(maxMachineID+1),
+			r.Int63n(maxServiceID+1),
+			r.Int63n(maxSequence+1)))
+	}
+	return list
+}
+
+func vector(id dxyflake.ID) Vector {
+	parts := dxyflake.Decompose(id)
+	// 697 years do not fit in a time.Duration, so go through Unix seconds
+	ticks := parts["time"]
+	ts := time.Unix(dxyflake.DefaultStartTime.Unix()+ticks/100, ticks%100*int64(10*time.Millisecond))
+	intBytes := id.IntBytes()
+
+	return Vector{
+		ID:        id.String(),
+		Parts:     parts,
+		Timestamp: ts.UTC().Format(time.RFC3339Nano),
+		Encodings: map[string]string{
+			"decimal":     id.String(),
+			"base2":       id.Base2(),
+			"base32":      id.Base32(),
+			"crockford32": id.Crockford32(),
+			"sortable":    id.SortableString(),
+			"checked":     id.StringChecked(),
+			"base36":      id.Base36(),
+			"base58":      id.Base58(),
+			"base62":      id.Base62(),
+			"base64":      id.Base64(),
+			"hex":         id.Hex(),
+			"int-bytes":   hex.EncodeToString(intBytes[:]),
+		},
+	}
+}
+
+func main() {
+	out := flag.String("o", "testdata/vectors.json", "output file")
+	flag.Parse()
+
+	f := File{
+		Layout: Layout{
+			BitLenTime:      dxyflake.BitLenTime,
+			BitLenMachineID: dxyflake.BitLenMachineID,
+			BitLenServiceID: dxyflake.BitLenServiceID,
+			BitLenSequence:  dxyflake.BitLenSequence,
+			TimeUnitNanos:   int64(10 * time.Millisecond),
+			StartTime:       dxyflake.DefaultStartTime.Format(time.RFC3339),
+		},
+	}
+	for _, id := range ids() {
+		f.Vectors = append(f.Vectors, vector(id))
+	}
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	b = append(b, '\n')
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,76 @@
+package dxyflake
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+// Every dxyflake ID fits a signed 64-bit integer, as the most significant
+// bit is unused, so Java long, SQL BIGINT and Go int64 hold IDs as they are.
+// Consumers that store numbers as IEEE 754 doubles, such as JavaScript and
+// JSON parsers like the one of Elasticsearch, only hold integers up to
+// MaxSafeInteger exactly; a current ID is far larger, so pass IDs to them as
+// strings.
+
+// MaxSafeInteger is the largest integer n such that n and n+1 are both
+// exactly representable as a float64 (2^53 - 1).
+const MaxSafeInteger = 1<<53 - 1
+
+// These errors are returned by the conversion helpers.
+var (
+	ErrOutOfRange = errors.New("value out of the range of dxyflake IDs")
+	ErrLossy      = errors.New("conversion loses precision")
+)
+
+// BigInt returns the dxyflake ID as a big.Int.
+func (f ID) BigInt() *big.Int {
+	return big.NewInt(int64(f))
+}
+
+// FromBigInt converts a big.Int into a dxyflake ID. It returns
+// ErrOutOfRange if b is negative or does not fit 63 bits.
+func FromBigInt(b *big.Int) (ID, error) {
+	if b.Sign() < 0 || !b.IsInt64() {
+		return 0, ErrOutOfRange
+	}
+	return ID(b.Int64()), nil
+}
+
+// Uint64 returns the dxyflake ID as a uint64.
+func (f ID) Uint64() uint64 {
+	return uint64(f)
+}
+
+// FromUint64 converts a uint64 into a dxyflake ID. It returns
+// ErrOutOfRange if the most significant bit of u is set.
+func FromUint64(u uint64) (ID, error) {
+	if u > math.MaxInt64 {
+		return 0, ErrOutOfRange
+	}
+	return ID(u), nil
+}
+
+// Float64 returns the dxyflake ID as a float64. It returns ErrLossy if the
+// ID is larger than MaxSafeInteger, as the float64 would then also stand for
+// neighbouring IDs.
+func (f ID) Float64() (float64, error) {
+	if f < 0 || f > MaxSafeInteger {
+		return 0, ErrLossy
+	}
+	return float64(f), nil
+}
+
+// FromFloat64 converts a float64, e.g. a number decoded from JSON, into a
+// dxyflake ID. It returns ErrLossy if v is not an integer or is larger than
+// MaxSafeInteger, as the ID it was converted from cannot be told apart from
+// its neighbours, and ErrOutOfRange if v is negative.
+func FromFloat64(v float64) (ID, error) {
+	switch {
+	case v < 0:
+		return 0, ErrOutOfRange
+	case v > MaxSafeInteger || v != math.Trunc(v):
+		return 0, ErrLossy
+	}
+	return ID(v), nil
+}
@@ -0,0 +1,68 @@
+package dxyflake
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestBigInt(t *testing.T) {
+	for _, id := range []ID{0, 1, 475370495148032, math.MaxInt64} {
+		got, err := FromBigInt(id.BigInt())
+		if err != nil || got != id {
+			t.Errorf("FromBigInt(%d) = %d, %v", id, got, err)
+		}
+	}
+
+	tooLarge := new(big.Int).Lsh(big.NewInt(1), 63)
+	for _, b := range []*big.Int{big.NewInt(-1), tooLarge} {
+		if _, err := FromBigInt(b); err != ErrOutOfRange {
+			t.Errorf("FromBigInt(%s) error = %v", b, err)
+		}
+	}
+}
+
+func TestUint64(t *testing.T) {
+	id := ID(475370495148032)
+	if got, err := FromUint64(id.Uint64()); err != nil || got != id {
+		t.Errorf("FromUint64() = %d, %v", got, err)
+	}
+	if _, err := FromUint64(1 << 63); err != ErrOutOfRange {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFloat64(t *testing.T) {
+	tests := []struct {
+		id  ID
+		err error
+	}{
+		{0, nil},
+		{MaxSafeInteger, nil},
+		{MaxSafeInteger + 1, ErrLossy},
+		{475370495148032 << 10, ErrLossy},
+	}
+	for _, tt := range tests {
+		f, err := tt.id.Float64()
+		if err != tt.err {
+			t.Errorf("Float64(%d) error = %v, want %v", tt.id, err, tt.err)
+			continue
+		}
+		if err == nil {
+			if got, err := FromFloat64(f); err != nil || got != tt.id {
+				t.Errorf("FromFloat64(%f) = %d, %v", f, got, err)
+			}
+		}
+	}
+
+	for v, want := range map[float64]error{
+		-1:                 ErrOutOfRange,
+		1.5:                ErrLossy,
+		MaxSafeInteger + 1: ErrLossy,
+		math.NaN():         ErrLossy,
+	} {
+		if _, err := FromFloat64(v); err != want {
+			t.Errorf("FromFloat64(%f) error = %v, want %v", v, err, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package dxyflake
+
+import (
+	"context"
+	"sync"
+)
+
+// Iterator issues IDs from a Generator until its context is done or the
+// generator fails. See NewIterator.
+type Iterator struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	gen    Generator
+	ch     chan iterResult // nil without prefetch
+
+	mutex sync.Mutex
+	err   error
+}
+
+type iterResult struct {
+	id  ID
+	err error
+}
+
+// NewIterator returns an Iterator issuing IDs with gen until ctx is done.
+//
+// If prefetch is positive, a goroutine issues up to prefetch IDs ahead of
+// Next, so that consumers of many IDs do not wait for a sequence overflow
+// in their loop. The timestamps of prefetched IDs are those of the time
+// they were issued, not consumed. Call Stop, or cancel ctx, to stop the
+// goroutine.
+func NewIterator(ctx context.Context, gen Generator, prefetch int) *Iterator {
+	ctx, cancel := context.WithCancel(ctx)
+	it := &Iterator{ctx: ctx, cancel: cancel, gen: gen}
+	if prefetch > 0 {
+		it.ch = make(chan iterResult, prefetch)
+		go it.prefetch()
+	}
+	return it
+}
+
+// Iter returns an Iterator over the IDs of the dxyflake, without prefetch.
+func (df *dxyflake) Iter(ctx context.Context) *Iterator {
+	return NewIterator(ctx, df, 0)
+}
+
+func (it *Iterator) prefetch() {
+	defer close(it.ch)
+	for it.ctx.Err() == nil {
+		id, err := it.gen.NextID()
+		select {
+		case it.ch <- iterResult{id, err}:
+		case <-it.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Next returns the next ID. Once the context is done or the generator has
+// failed, Next keeps returning the same error.
+func (it *Iterator) Next() (ID, error) {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+
+	if it.err != nil {
+		return 0, it.err
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return 0, err
+	}
+
+	var r iterResult
+	if it.ch == nil {
+		r.id, r.err = it.gen.NextID()
+	} else {
+		select {
+		case res, ok := <-it.ch:
+			if !ok {
+				r.err = it.ctx.Err()
+			} else {
+				r = res
+			}
+		case <-it.ctx.Done():
+			r.err = it.ctx.Err()
+		}
+	}
+	if r.err != nil {
+		it.err = r.err
+		return 0, r.err
+	}
+	return r.id, nil
+}
+
+// Stop stops the iterator and its prefetching goroutine. Prefetched IDs
+// that were not consumed are discarded; they are unique but never used.
+func (it *Iterator) Stop() {
+	it.cancel()
+}
+
+// All returns a function iterating over the IDs of Next, which stops the
+// iterator when the loop ends. With Go 1.23 or later it can be ranged over:
+//
+//	for id, err := range gen.Iter(ctx).All() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The loop gets the error, if any, as its last iteration.
+func (it *Iterator) All() func(yield func(ID, error) bool) {
+	return func(yield func(ID, error) bool) {
+		defer it.Stop()
+		for {
+			id, err := it.Next()
+			if !yield(id, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package dxyflake
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newIterTestDxyflake(t *testing.T) *dxyflake {
+	var st Settings
+	st.Init(1, 2)
+	df := NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	return df
+}
+
+func TestIter(t *testing.T) {
+	for _, prefetch := range []int{0, 1, 100} {
+		ctx, cancel := context.WithCancel(context.Background())
+		it := NewIterator(ctx, newIterTestDxyflake(t), prefetch)
+
+		var prev ID
+		for i := 0; i < 10000; i++ {
+			id, err := it.Next()
+			if err != nil {
+				t.Fatalf("prefetch %d: %v", prefetch, err)
+			}
+			if id <= prev {
+				t.Fatalf("prefetch %d: %d not after %d", prefetch, id, prev)
+			}
+			prev = id
+		}
+
+		cancel()
+		for i := 0; i < 2; i++ {
+			if _, err := it.Next(); err != context.Canceled {
+				t.Errorf("prefetch %d: got %v after cancel", prefetch, err)
+			}
+		}
+	}
+}
+
+type failAfter struct {
+	gen Generator
+	n   int
+}
+
+var errIterTest = errors.New("test failure")
+
+func (f *failAfter) NextID() (ID, error) {
+	if f.n == 0 {
+		return 0, errIterTest
+	}
+	f.n--
+	return f.gen.NextID()
+}
+
+func TestIterAll(t *testing.T) {
+	for _, prefetch := range []int{0, 3} {
+		it := NewIterator(context.Background(), &failAfter{newIterTestDxyflake(t), 5}, prefetch)
+		var ids int
+		var last error
+		it.All()(func(id ID, err error) bool {
+			if err != nil {
+				last = err
+			} else {
+				ids++
+			}
+			return true
+		})
+		if ids != 5 || last != errIterTest {
+			t.Errorf("prefetch %d: got %d IDs, error %v", prefetch, ids, last)
+		}
+		if _, err := it.Next(); err != errIterTest {
+			t.Errorf("prefetch %d: got %v after failure", prefetch, err)
+		}
+	}
+
+	// breaking out of the loop stops the iterator
+	it := newIterTestDxyflake(t).Iter(context.Background())
+	n := 0
+	it.All()(func(ID, error) bool {
+		n++
+		return n < 3
+	})
+	if _, err := it.Next(); err != context.Canceled || n != 3 {
+		t.Errorf("got %d IDs, then %v", n, err)
+	}
+}
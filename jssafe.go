@@ -0,0 +1,48 @@
+package dxyflake
+
+// JSSafeBits is the bit length of the IDs of JSSafeLayout. IDs below
+// 1<<JSSafeBits are exact JavaScript numbers, up to Number.MAX_SAFE_INTEGER,
+// so browsers and JSON decoders of other languages read them without
+// rounding.
+const JSSafeBits = 53
+
+// JSSafeLayout is the layout of short IDs for URLs and JavaScript clients:
+// 38 bits of 10 ms time units, about 87 years from DefaultStartTime, 32
+// machine IDs, 4 service IDs and 256 sequence numbers per time unit. The
+// upper 10 bits of its IDs are always 0.
+var JSSafeLayout = Layout{
+	Epoch:           DefaultStartTime,
+	Unit:            dxyflakeTimeUnit,
+	BitLenTime:      38,
+	BitLenMachineID: BitLenMachineID,
+	BitLenServiceID: 2,
+	BitLenSequence:  8,
+}
+
+// InitJSSafe sets the MachineID and ServiceID like Init, and the bit
+// lengths of JSSafeLayout.
+func (s *Settings) InitJSSafe(mID, sID uint16) {
+	if s != nil {
+		s.Init(mID, sID)
+		s.BitsTime, s.BitsMachineID = JSSafeLayout.BitLenTime, JSSafeLayout.BitLenMachineID
+		s.BitsServiceID, s.BitsSequence = JSSafeLayout.BitLenServiceID, JSSafeLayout.BitLenSequence
+	}
+}
+
+// Width returns the number of bits the IDs of layout l may use.
+func (l Layout) Width() int {
+	return l.BitLenTime + l.BitLenMachineID + l.BitLenServiceID + l.BitLenSequence
+}
+
+// JSSafe reports whether every ID of layout l is an exact JavaScript
+// number.
+func (l Layout) JSSafe() bool {
+	return l.Width() <= JSSafeBits
+}
+
+// Fits reports whether id is a valid ID of layout l: it is not negative
+// and sets no bit beyond the width of l. An ID of a 63-bit layout read
+// with a narrower one does not fit.
+func (l Layout) Fits(id ID) bool {
+	return id >= 0 && int64(id)>>uint(l.Width()) == 0
+}
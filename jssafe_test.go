@@ -0,0 +1,47 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJSSafe(t *testing.T) {
+	var st Settings
+	st.InitJSSafe(31, 3)
+	st.StartTime = time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	if !df.Layout().JSSafe() || df.Layout().Width() != JSSafeBits || DefaultLayout.JSSafe() {
+		t.Errorf("unexpected layout: %+v", df.Layout())
+	}
+
+	for i := 0; i < 1000; i++ {
+		id, err := df.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id >= 1<<JSSafeBits {
+			t.Fatalf("ID %d is not an exact JavaScript number", id)
+		}
+		if p := df.DecomposeParts(id); p.MachineID != 31 || p.ServiceID != 3 {
+			t.Fatalf("unexpected parts: %+v", p)
+		}
+	}
+
+	// the largest ID of the layout still fits
+	max := JSSafeLayout.MaxIDAt(JSSafeLayout.TickTime(1<<38 - 1))
+	if max != 1<<JSSafeBits-1 || !JSSafeLayout.Fits(max) || JSSafeLayout.Fits(max+1) {
+		t.Errorf("largest ID %d", max)
+	}
+	if err := Validate(1<<JSSafeBits, st); err != ErrOutOfRange {
+		t.Errorf("Validate() of a 63-bit ID = %v", err)
+	}
+
+	st.BitsSequence++
+	if _, err := NewDxyflakeWithError(st); err != ErrInvalidLayout {
+		t.Errorf("54-bit layout: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// These are the environment variables the Kubernetes providers read, set
+// from the Downward API:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	- name: POD_NAMESPACE
+//	  valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+const (
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+)
+
+// ErrNoPodOrdinal is returned by PodOrdinal and MachineIDFromPodOrdinal for
+// pod names that do not end in a StatefulSet ordinal, such as "ids-3".
+var ErrNoPodOrdinal = errors.New("pod name has no ordinal")
+
+// PodName returns the name of the pod: EnvPodName if it is set, the host
+// name otherwise, which Kubernetes sets to the pod name.
+func PodName() (string, error) {
+	if name := os.Getenv(EnvPodName); name != "" {
+		return name, nil
+	}
+	return os.Hostname()
+}
+
+// PodOrdinal returns the ordinal a StatefulSet pod name ends in, e.g. 3 for
+// "ids-3".
+func PodOrdinal(name string) (int, error) {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrNoPodOrdinal, name)
+	}
+	n, err := strconv.Atoi(name[i+1:])
+	if err != nil || n < 0 || strconv.Itoa(n) != name[i+1:] {
+		return 0, fmt.Errorf("%w: %s", ErrNoPodOrdinal, name)
+	}
+	return n, nil
+}
+
+// MachineIDFromPodOrdinal returns a Settings.MachineID taking the machine
+// ID from the ordinal of the StatefulSet pod, see PodName and PodOrdinal.
+// As the ordinals of a StatefulSet are unique, so are the machine IDs, as
+// long as it has no more replicas than machine IDs, 32 with BitLenMachineID
+// bits or 1<<Settings.BitsMachineID; NewDxyflakeWithError fails with
+// ErrInvalidMachineID for the pods of larger ones.
+func MachineIDFromPodOrdinal() func() (uint16, error) {
+	return func() (uint16, error) {
+		name, err := PodName()
+		if err != nil {
+			return 0, err
+		}
+		n, err := PodOrdinal(name)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxUint16 {
+			return 0, fmt.Errorf("%w: ordinal %d of %s", ErrInvalidMachineID, n, name)
+		}
+		return uint16(n), nil
+	}
+}
+
+// MachineIDFromPod returns a Settings.MachineID hashing the namespace and
+// name of the pod, from EnvPodNamespace and PodName, into the machine ID,
+// for pods without ordinals. Like MachineIDFromHostname, distinct pods may
+// collide.
+func MachineIDFromPod() func() (uint16, error) {
+	return MachineIDFromPodBits(BitLenMachineID)
+}
+
+// MachineIDFromPodBits is like MachineIDFromPod for machine IDs of bits
+// bits, see Settings.BitsMachineID.
+func MachineIDFromPodBits(bits int) func() (uint16, error) {
+	return func() (uint16, error) {
+		name, err := PodName()
+		if err != nil {
+			return 0, err
+		}
+		return hashMachineID(os.Getenv(EnvPodNamespace)+"/"+name, bits), nil
+	}
+}
+
+// ServiceIDFromEnv returns a Settings.ServiceID reading the service ID in
+// decimal from the environment variable name, e.g. set per StatefulSet.
+func ServiceIDFromEnv(name string) func() (uint16, error) {
+	return func() (uint16, error) {
+		v, err := strconv.ParseUint(os.Getenv(name), 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+		return uint16(v), nil
+	}
+}
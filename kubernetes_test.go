@@ -0,0 +1,58 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPodOrdinal(t *testing.T) {
+	for name, want := range map[string]int{"ids-0": 0, "ids-31": 31, "my-ids-120": 120} {
+		if n, err := PodOrdinal(name); err != nil || n != want {
+			t.Errorf("PodOrdinal(%q) = %d, %v", name, n, err)
+		}
+	}
+	for _, name := range []string{"ids", "ids-", "ids-01", "ids-x1", "ids-7f9c6d5b4-x2lqp"} {
+		if _, err := PodOrdinal(name); !errors.Is(err, ErrNoPodOrdinal) {
+			t.Errorf("PodOrdinal(%q) error = %v", name, err)
+		}
+	}
+}
+
+func TestKubernetesProviders(t *testing.T) {
+	t.Setenv(EnvPodName, "ids-7")
+	t.Setenv(EnvPodNamespace, "orders")
+	t.Setenv("TEST_DXYFLAKE_SERVICE_ID", "3")
+
+	var st Settings
+	st.MachineID = MachineIDFromPodOrdinal()
+	st.ServiceID = ServiceIDFromEnv("TEST_DXYFLAKE_SERVICE_ID")
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if df.machineID != 7 || df.serviceID != 3 {
+		t.Errorf("machine id %d, service id %d", df.machineID, df.serviceID)
+	}
+
+	t.Setenv(EnvPodName, "ids-32")
+	if _, err := NewDxyflakeWithError(st); !errors.Is(err, ErrInvalidMachineID) {
+		t.Errorf("ordinal 32: %v", err)
+	}
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 41, 10, 2, 10
+	if df, err := NewDxyflakeWithError(st); err != nil || df.machineID != 32 {
+		t.Errorf("ordinal 32 of 10 machine ID bits: %v", err)
+	}
+	t.Setenv(EnvPodName, "ids-65536")
+	if _, err := NewDxyflakeWithError(st); !errors.Is(err, ErrInvalidMachineID) {
+		t.Errorf("ordinal 65536: %v", err)
+	}
+	t.Setenv(EnvPodName, "ids-32")
+	t.Setenv("TEST_DXYFLAKE_SERVICE_ID", "")
+	if _, err := st.ServiceID(); err == nil {
+		t.Error("empty service id accepted")
+	}
+
+	if id, err := MachineIDFromPod()(); err != nil || id != hashMachineID("orders/ids-32", BitLenMachineID) {
+		t.Errorf("MachineIDFromPod() = %d, %v", id, err)
+	}
+}
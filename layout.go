@@ -0,0 +1,207 @@
+package dxyflake
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidLayout is returned by Layout.Validate for layouts that do
+	// not describe a usable ID.
+	ErrInvalidLayout = errors.New("invalid layout")
+	// ErrFutureTimestamp is returned by Layout.Compose for times after the
+	// time unit the system clock is in.
+	ErrFutureTimestamp = errors.New("timestamp in the future")
+)
+
+// Layout describes how an ID is made of its parts: the bit lengths of the
+// parts and the epoch and unit of the time part. Code that handles IDs of
+// more than one layout takes a Layout rather than using the package
+// constants, which describe DefaultLayout.
+//
+// If Epoch is 0, DefaultStartTime is used. Unit must divide a second or be
+// a multiple of one.
+//
+// BitLenRegionID splits the machine ID into a region ID, its upper
+// BitLenRegionID bits, and a node ID, the rest, so that generators of
+// different regions or datacenters never share a machine ID whatever
+// their node IDs. It does not move any part and is 0 in DefaultLayout.
+type Layout struct {
+	Epoch           time.Time
+	Unit            time.Duration
+	BitLenTime      int
+	BitLenMachineID int
+	BitLenServiceID int
+	BitLenSequence  int
+	BitLenRegionID  int
+}
+
+// DefaultLayout is the layout of dxyflake IDs.
+var DefaultLayout = Layout{
+	Epoch:           DefaultStartTime,
+	Unit:            dxyflakeTimeUnit,
+	BitLenTime:      BitLenTime,
+	BitLenMachineID: BitLenMachineID,
+	BitLenServiceID: BitLenServiceID,
+	BitLenSequence:  BitLenSequence,
+}
+
+// Validate returns ErrInvalidLayout if a bit length is negative, the time
+// part is empty, the parts do not fit 63 bits, the region ID does not fit
+// the machine ID or Unit is not usable.
+func (l Layout) Validate() error {
+	if l.BitLenTime <= 0 || l.BitLenMachineID < 0 || l.BitLenServiceID < 0 || l.BitLenSequence < 0 ||
+		l.BitLenTime+l.BitLenMachineID+l.BitLenServiceID+l.BitLenSequence > 63 ||
+		l.BitLenRegionID < 0 || l.BitLenRegionID > l.BitLenMachineID {
+		return ErrInvalidLayout
+	}
+	if l.Unit <= 0 || (time.Second%l.Unit != 0 && l.Unit%time.Second != 0) {
+		return ErrInvalidLayout
+	}
+	return nil
+}
+
+func (l Layout) epoch() time.Time {
+	if l.Epoch.IsZero() {
+		return DefaultStartTime
+	}
+	return l.Epoch
+}
+
+func (l Layout) shiftTime() uint {
+	return uint(l.BitLenMachineID + l.BitLenServiceID + l.BitLenSequence)
+}
+
+func (l Layout) shiftMachineID() uint {
+	return uint(l.BitLenServiceID + l.BitLenSequence)
+}
+
+// units returns the number of whole units between the Unix epoch and t.
+func (l Layout) units(t time.Time) int64 {
+	sec, nsec := t.Unix(), int64(t.Nanosecond())
+	if l.Unit >= time.Second {
+		s := int64(l.Unit / time.Second)
+		return floorDiv(sec, s)
+	}
+	return sec*int64(time.Second/l.Unit) + nsec/int64(l.Unit)
+}
+
+// fromUnits is the inverse of units.
+func (l Layout) fromUnits(n int64) time.Time {
+	if l.Unit >= time.Second {
+		return time.Unix(n*int64(l.Unit/time.Second), 0).UTC()
+	}
+	perSec := int64(time.Second / l.Unit)
+	sec := floorDiv(n, perSec)
+	return time.Unix(sec, (n-sec*perSec)*int64(l.Unit)).UTC()
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// Tick returns the time part of an ID issued at t: the number of units
+// elapsed since the epoch. It is negative before the epoch and is not
+// clamped to BitLenTime.
+func (l Layout) Tick(t time.Time) int64 {
+	return l.units(t) - l.units(l.epoch())
+}
+
+// TickTime returns the time at which the time unit tick begins.
+func (l Layout) TickTime(tick int64) time.Time {
+	return l.fromUnits(l.units(l.epoch()) + tick)
+}
+
+// TimeOf returns the time at which the time unit of id begins.
+func (l Layout) TimeOf(id ID) time.Time {
+	return l.TickTime(int64(id) >> l.shiftTime())
+}
+
+// Decompose returns a set of the parts of id, like the package level
+// Decompose does for DefaultLayout, with "region-id" and "node-id" in
+// layouts with region bits.
+func (l Layout) Decompose(id ID) map[string]int64 {
+	n := int64(id)
+	parts := map[string]int64{
+		"id":         n,
+		"msb":        n >> 63,
+		"time":       n >> l.shiftTime(),
+		"machine-id": n >> l.shiftMachineID() & (1<<uint(l.BitLenMachineID) - 1),
+		"service-id": n >> uint(l.BitLenSequence) & (1<<uint(l.BitLenServiceID) - 1),
+		"sequence":   n & (1<<uint(l.BitLenSequence) - 1),
+	}
+	if l.BitLenRegionID > 0 {
+		parts["region-id"] = int64(l.RegionID(id))
+		parts["node-id"] = int64(l.NodeID(id))
+	}
+	return parts
+}
+
+// Compose returns the ID issued at t with the given parts. It returns
+// ErrOutOfRange if t is before the epoch or beyond the time limit, or if a
+// part does not fit its bit length, and ErrFutureTimestamp if t is after the
+// current time unit of the system clock: IDs from the future sort after
+// the IDs issued until then, against the creation order. ComposeFuture
+// allows such times.
+func (l Layout) Compose(t time.Time, machineID, serviceID, sequence uint16) (ID, error) {
+	id, err := l.ComposeFuture(t, machineID, serviceID, sequence)
+	if err != nil {
+		return 0, err
+	}
+	if l.Tick(t) > l.Tick(time.Now()) {
+		return 0, ErrFutureTimestamp
+	}
+	return id, nil
+}
+
+// ComposeFuture is like Compose, but allows times in the future, e.g. for
+// test vectors or for IDs of scheduled events.
+func (l Layout) ComposeFuture(t time.Time, machineID, serviceID, sequence uint16) (ID, error) {
+	tick := l.Tick(t)
+	if tick < 0 || tick >= 1<<uint(l.BitLenTime) ||
+		int64(machineID) >= 1<<uint(l.BitLenMachineID) ||
+		int64(serviceID) >= 1<<uint(l.BitLenServiceID) ||
+		int64(sequence) >= 1<<uint(l.BitLenSequence) {
+		return 0, ErrOutOfRange
+	}
+	return l.compose(tick, machineID, serviceID, sequence), nil
+}
+
+func (l Layout) compose(tick int64, machineID, serviceID, sequence uint16) ID {
+	return ID(tick<<l.shiftTime() |
+		int64(machineID)<<l.shiftMachineID() |
+		int64(serviceID)<<uint(l.BitLenSequence) |
+		int64(sequence))
+}
+
+// clampedTick returns Tick(t) clamped to the range the time part can hold.
+func (l Layout) clampedTick(t time.Time) int64 {
+	tick := l.Tick(t)
+	if tick < 0 {
+		return 0
+	}
+	if max := int64(1)<<uint(l.BitLenTime) - 1; tick > max {
+		return max
+	}
+	return tick
+}
+
+// MinIDAt returns the smallest ID that can be issued at time t.
+func (l Layout) MinIDAt(t time.Time) ID {
+	return ID(l.clampedTick(t) << l.shiftTime())
+}
+
+// MaxIDAt returns the largest ID that can be issued at time t.
+func (l Layout) MaxIDAt(t time.Time) ID {
+	return ID(l.clampedTick(t)<<l.shiftTime() | (1<<l.shiftTime() - 1))
+}
+
+// Capacity returns the number of IDs per second a generator of the layout
+// can issue at most.
+func (l Layout) Capacity() int64 {
+	return int64(1) << l.BitLenSequence * int64(time.Second) / int64(l.Unit)
+}
@@ -0,0 +1,128 @@
+package dxyflake
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLayoutValidate(t *testing.T) {
+	if err := DefaultLayout.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []Layout{
+		{Unit: time.Millisecond, BitLenTime: 41, BitLenMachineID: 10, BitLenSequence: 13},
+		{Unit: time.Millisecond, BitLenTime: 0, BitLenSequence: 12},
+		{Unit: 0, BitLenTime: 41},
+		{Unit: 3 * time.Millisecond, BitLenTime: 41},
+		{Unit: time.Millisecond, BitLenTime: 41, BitLenSequence: -1},
+	} {
+		if err := l.Validate(); err != ErrInvalidLayout {
+			t.Errorf("%+v: got %v", l, err)
+		}
+	}
+	if err := (Layout{Unit: 2 * time.Second, BitLenTime: 32}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLayoutDefault(t *testing.T) {
+	for _, id := range []ID{0, 475370495148032, 9223372036854775807, 1<<22 | 3<<17 | 5<<12 | 7} {
+		if got, want := DefaultLayout.Decompose(id), Decompose(id); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: got %v, want %v", id, got, want)
+		}
+	}
+
+	at := DefaultStartTime.Add(1234*time.Hour + 5678*time.Millisecond)
+	id, err := DefaultLayout.Compose(at, 3, 5, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := Decompose(id)
+	if parts["time"] != DefaultLayout.Tick(at) || parts["machine-id"] != 3 || parts["service-id"] != 5 || parts["sequence"] != 7 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+	if got, want := DefaultLayout.TimeOf(id), at.Truncate(10*time.Millisecond); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if DefaultLayout.MinIDAt(at) != MinIDAt(at, time.Time{}) || DefaultLayout.MaxIDAt(at) != MaxIDAt(at, time.Time{}) {
+		t.Error("range helpers differ from the package functions")
+	}
+
+	for _, args := range [][3]uint16{{32, 0, 0}, {0, 32, 0}, {0, 0, 4096}} {
+		if _, err := DefaultLayout.Compose(at, args[0], args[1], args[2]); err != ErrOutOfRange {
+			t.Errorf("%v: got %v", args, err)
+		}
+	}
+	if _, err := DefaultLayout.Compose(DefaultStartTime.Add(-time.Second), 0, 0, 0); err != ErrOutOfRange {
+		t.Errorf("time before epoch: got %v", err)
+	}
+
+	future := time.Now().Add(time.Second)
+	if _, err := DefaultLayout.Compose(future, 0, 0, 0); err != ErrFutureTimestamp {
+		t.Errorf("time in the future: got %v", err)
+	}
+	if id, err := DefaultLayout.ComposeFuture(future, 0, 0, 0); err != nil || id != DefaultLayout.MinIDAt(future) {
+		t.Errorf("ComposeFuture() = %d, %v", id, err)
+	}
+	if _, err := DefaultLayout.Compose(time.Now(), 0, 0, 0); err != nil {
+		t.Errorf("time now: got %v", err)
+	}
+}
+
+func TestLayoutCustom(t *testing.T) {
+	// a Sonyflake-like layout: 39 bits of 10 msec, 8 bits of sequence and
+	// 16 bits of machine ID
+	l := Layout{
+		Epoch:           time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC),
+		Unit:            10 * time.Millisecond,
+		BitLenTime:      39,
+		BitLenMachineID: 16,
+		BitLenSequence:  8,
+	}
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	id, err := l.Compose(at, 0xbeef, 0, 0xaa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := l.Decompose(id)
+	if parts["machine-id"] != 0xbeef || parts["service-id"] != 0 || parts["sequence"] != 0xaa {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+	if !l.TimeOf(id).Equal(at) {
+		t.Errorf("got %v, want %v", l.TimeOf(id), at)
+	}
+
+	coarse := Layout{Unit: 2 * time.Second, BitLenTime: 40}
+	if tick := coarse.Tick(DefaultStartTime.Add(5 * time.Second)); tick != 2 {
+		t.Errorf("got tick %d, want 2", tick)
+	}
+	if got, want := coarse.TickTime(2), DefaultStartTime.Add(4*time.Second); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if tick := coarse.Tick(DefaultStartTime.Add(-time.Second)); tick != -1 {
+		t.Errorf("got tick %d before the epoch, want -1", tick)
+	}
+}
+
+func TestDxyflakeLayout(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	st.StartTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	df := NewDxyflake(st)
+	l := df.Layout()
+	if !l.Epoch.Equal(st.StartTime) || l.BitLenTime != BitLenTime || l.Unit != 10*time.Millisecond {
+		t.Fatalf("unexpected layout: %+v", l)
+	}
+
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Decompose(id)["time"] != Decompose(id)["time"] {
+		t.Error("layout does not describe the IDs")
+	}
+	if d := time.Since(l.TimeOf(id)); d < 0 || d > time.Second {
+		t.Errorf("ID time is %v off", d)
+	}
+}
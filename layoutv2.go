@@ -0,0 +1,118 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrBeforeCutover is returned by Versions.CheckClock while the clock is
+// before the cutover to LayoutV2.
+var ErrBeforeCutover = errors.New("clock is before the cutover to layout v2")
+
+// LayoutV2 is the layout of version 2 dxyflake IDs, for fleets beyond the
+// 32 machine IDs of DefaultLayout: 256 machine IDs and 4 service IDs. The
+// time part and the sequence are those of DefaultLayout, so V1 and V2 IDs
+// of the same time sort together and share TimeOf.
+var LayoutV2 = Layout{
+	Epoch:           DefaultStartTime,
+	Unit:            dxyflakeTimeUnit,
+	BitLenTime:      BitLenTime,
+	BitLenMachineID: 8,
+	BitLenServiceID: 2,
+	BitLenSequence:  BitLenSequence,
+}
+
+// InitV2 sets the MachineID and ServiceID like Init, and the bit lengths
+// of LayoutV2.
+func (s *Settings) InitV2(mID, sID uint16) {
+	if s != nil {
+		s.Init(mID, sID)
+		s.BitsTime, s.BitsMachineID = LayoutV2.BitLenTime, LayoutV2.BitLenMachineID
+		s.BitsServiceID, s.BitsSequence = LayoutV2.BitLenServiceID, LayoutV2.BitLenSequence
+	}
+}
+
+// Versions tells V1 IDs of DefaultLayout and V2 IDs of LayoutV2 apart. As
+// both are plain 63-bit integers, they are told apart by time: V1
+// generators are stopped before Cutover and V2 generators started after
+// it, so every ID of a time unit before Cutover is V1 and every later one
+// V2. Pass CheckClock as Settings.CheckClock of the V2 generators.
+//
+// The time between stopping the last V1 generator and Cutover must exceed
+// the clock skew of the fleet. IDs that leave the process can also carry
+// their version explicitly as envelopes, see Layout.Envelope.
+type Versions struct {
+	Cutover time.Time
+}
+
+// IsV1 reports whether id was issued before the cutover.
+func (v Versions) IsV1(id ID) bool {
+	return id < DefaultLayout.MinIDAt(v.Cutover)
+}
+
+// Layout returns the layout id was issued with.
+func (v Versions) Layout(id ID) Layout {
+	if v.IsV1(id) {
+		return DefaultLayout
+	}
+	return LayoutV2
+}
+
+// DecomposeParts returns the parts of id, read with its layout.
+func (v Versions) DecomposeParts(id ID) Parts {
+	return v.Layout(id).DecomposeParts(id)
+}
+
+// CheckClock returns ErrBeforeCutover if the system clock is before the
+// cutover.
+func (v Versions) CheckClock() error {
+	if now := time.Now(); now.Before(v.Cutover) {
+		return fmt.Errorf("%w: %v to go", ErrBeforeCutover, v.Cutover.Sub(now))
+	}
+	return nil
+}
+
+// Convert returns the ID of layout to with the parts of id of layout l,
+// issued in the same time unit. It returns ErrOutOfRange if a part does
+// not fit layout to, e.g. a V1 service ID above 3 in LayoutV2, or if the
+// time is outside of the range of layout to.
+func (l Layout) Convert(id ID, to Layout) (ID, error) {
+	if id < 0 {
+		return 0, ErrOutOfRange
+	}
+	p := l.DecomposeParts(id)
+	return to.ComposeFuture(p.Timestamp, p.MachineID, p.ServiceID, p.Sequence)
+}
+
+// ConvertV1ToV2 converts an ID of DefaultLayout into one of LayoutV2. See
+// Layout.Convert for its errors.
+func ConvertV1ToV2(id ID) (ID, error) {
+	return DefaultLayout.Convert(id, LayoutV2)
+}
+
+// ConvertV2ToV1 converts an ID of LayoutV2 into one of DefaultLayout. See
+// Layout.Convert for its errors.
+func ConvertV2ToV1(id ID) (ID, error) {
+	return LayoutV2.Convert(id, DefaultLayout)
+}
+
+// Envelope returns id of layout l as an envelope of the version of l, so
+// that it is read with the right layout whatever version is current. It
+// returns ErrUnknownVersion if l is not the layout of an envelope version.
+func (l Layout) Envelope(id ID) (string, error) {
+	for version, vl := range envelopeLayouts {
+		if vl.equal(l) {
+			return envelopePrefix + strconv.Itoa(version) + "_" + id.Base62(), nil
+		}
+	}
+	return "", ErrUnknownVersion
+}
+
+// equal reports whether IDs of l and o are read the same way.
+func (l Layout) equal(o Layout) bool {
+	return l.epoch().Equal(o.epoch()) && l.Unit == o.Unit &&
+		l.BitLenTime == o.BitLenTime && l.BitLenMachineID == o.BitLenMachineID &&
+		l.BitLenServiceID == o.BitLenServiceID && l.BitLenSequence == o.BitLenSequence
+}
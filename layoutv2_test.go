@@ -0,0 +1,89 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLayoutV2(t *testing.T) {
+	if err := LayoutV2.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var st Settings
+	st.InitV2(200, 3)
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := LayoutV2.DecomposeParts(id); p.MachineID != 200 || p.ServiceID != 3 {
+		t.Errorf("unexpected parts %+v", p)
+	}
+	st.InitV2(0, 4)
+	if _, err := NewDxyflakeWithError(st); !errors.Is(err, ErrInvalidServiceID) {
+		t.Errorf("service id 4 in layout v2: %v", err)
+	}
+
+	v1, err := ConvertV2ToV1(id)
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("machine id 200 converted to v1: %d, %v", v1, err)
+	}
+	at := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	v1, _ = Compose(at, 31, 3, 7)
+	v2, err := ConvertV1ToV2(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := LayoutV2.DecomposeParts(v2); p.MachineID != 31 || p.ServiceID != 3 || p.Sequence != 7 || !p.Timestamp.Equal(at) {
+		t.Errorf("unexpected converted parts %+v", p)
+	}
+	if back, err := ConvertV2ToV1(v2); err != nil || back != v1 {
+		t.Errorf("round trip: %d, %v", back, err)
+	}
+	v1, _ = Compose(at, 0, 4, 0)
+	if _, err := ConvertV1ToV2(v1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("service id 4 converted to v2: %v", err)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	cutover := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := Versions{Cutover: cutover}
+	before, _ := Compose(cutover.Add(-dxyflakeTimeUnit), 31, 31, 4095)
+	after, _ := LayoutV2.Compose(cutover, 255, 0, 0)
+	if !v.IsV1(before) || v.IsV1(after) {
+		t.Errorf("IsV1() = %v, %v", v.IsV1(before), v.IsV1(after))
+	}
+	if p := v.DecomposeParts(before); p.MachineID != 31 || p.ServiceID != 31 {
+		t.Errorf("v1 parts %+v", p)
+	}
+	if p := v.DecomposeParts(after); p.MachineID != 255 {
+		t.Errorf("v2 parts %+v", p)
+	}
+	if err := v.CheckClock(); err != nil {
+		t.Error(err)
+	}
+	if err := (Versions{Cutover: time.Now().Add(time.Hour)}).CheckClock(); !errors.Is(err, ErrBeforeCutover) {
+		t.Errorf("CheckClock() before the cutover = %v", err)
+	}
+
+	s, err := LayoutV2.Envelope(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, l, err := ParseEnvelope(s); err != nil || id != after || !l.equal(LayoutV2) || s[:5] != "dxy2_" {
+		t.Errorf("ParseEnvelope(%q) = %d, %+v, %v", s, id, l, err)
+	}
+	if s, err := DefaultLayout.Envelope(before); err != nil || s != before.Envelope() {
+		t.Errorf("v1 envelope %q, %v", s, err)
+	}
+	other := LayoutV2
+	other.BitLenMachineID, other.BitLenServiceID = 9, 1
+	if _, err := other.Envelope(after); err != ErrUnknownVersion {
+		t.Errorf("envelope of an unknown layout: %v", err)
+	}
+}
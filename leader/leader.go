@@ -0,0 +1,264 @@
+// Package leader runs a single active generator among several instances.
+//
+// The instances elect a leader with a lease on one key. The leader issues
+// IDs with the machine ID all instances share; the standbys forward their
+// NextID calls to it over HTTP, so every instance serves requests while
+// only one writes. When the leader steps down or dies, a standby takes the
+// lease over and waits until the high-water mark of the old leader, plus
+// a margin for clock skew, has passed before it issues its first ID:
+//
+//	e, err := leader.New(backend, st, leader.Options{Address: "http://10.0.0.5:8080", Store: store})
+//	...
+//	go e.Run(ctx)
+//	http.ListenAndServe(":8080", server.New(e, server.Options{}))
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/client"
+	"github.com/GiterLab/dxyflake/handoff"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+// These errors are returned by an Elector.
+var (
+	ErrNoLeader    = errors.New("no leader elected")
+	ErrNoAddress   = errors.New("no address to advertise")
+	ErrNoGenerator = errors.New("cannot create generator")
+)
+
+// DefaultMargin covers the clock skew of NTP-synchronized instances.
+const (
+	DefaultKey    = "dxyflake/leader"
+	DefaultMargin = 100 * time.Millisecond
+)
+
+// Backend is a lease backend that can tell who holds a lease, such as
+// lease.MemoryBackend, sqlite.Store or natsid.KVBackend.
+type Backend interface {
+	lease.Backend
+	lease.Inspector
+}
+
+// Options configures an Elector:
+//
+// Address is the base URL of the ID server of this instance, which the
+// standbys forward to while it leads. It is required.
+//
+// Key is the lease key of the election.
+// If Key is empty, DefaultKey is used.
+//
+// Lease configures the lease of the leader. Its TTL bounds how long the
+// instances are without a leader when the leader dies.
+//
+// Store keeps the high-water mark of a leader stepping down for its
+// successor. If Store is nil, successors only wait for Margin.
+//
+// Margin covers the clock skew between the instances.
+// If Margin is 0, DefaultMargin is used.
+//
+// Client configures the forwarding to the leader.
+//
+// OnError, if not nil, is called with the errors of the election, such as
+// a lost lease or a failing backend.
+type Options struct {
+	Address string
+	Key     string
+	Lease   lease.Options
+	Store   handoff.Store
+	Margin  time.Duration
+	Client  client.Options
+	OnError func(err error)
+}
+
+// Elector is a dxyflake.Generator issuing IDs while it leads and
+// forwarding to the leader otherwise.
+type Elector struct {
+	backend Backend
+	st      dxyflake.Settings
+	opts    Options
+
+	mutex   sync.Mutex
+	lease   *lease.Lease
+	gen     dxyflake.Generator // while leading
+	clients map[string]*client.Client
+}
+
+// New returns an Elector issuing IDs with st once it leads. Call Run to
+// take part in the election.
+func New(b Backend, st dxyflake.Settings, opts Options) (*Elector, error) {
+	if opts.Address == "" {
+		return nil, ErrNoAddress
+	}
+	if opts.Key == "" {
+		opts.Key = DefaultKey
+	}
+	if opts.Margin == 0 {
+		opts.Margin = DefaultMargin
+	}
+	if opts.Lease.TTL == 0 {
+		opts.Lease.TTL = lease.DefaultTTL
+	}
+	return &Elector{backend: b, st: st, opts: opts, clients: make(map[string]*client.Client)}, nil
+}
+
+// Run takes part in the election until ctx is done, then steps down if it
+// leads. A standby tries to take over every third of the lease TTL.
+func (e *Elector) Run(ctx context.Context) error {
+	retry := time.NewTicker(e.opts.Lease.TTL / 3)
+	defer retry.Stop()
+
+	for {
+		l, err := lease.Acquire(ctx, e.backend, e.opts.Key, e.opts.Address, e.opts.Lease)
+		if err == nil {
+			err = e.lead(ctx, l)
+			if ctx.Err() != nil {
+				return err
+			}
+		}
+		if err != nil && !errors.Is(err, lease.ErrHeld) && e.opts.OnError != nil {
+			e.opts.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-retry.C:
+		}
+	}
+}
+
+// lead issues IDs while l is held and steps down when ctx is done.
+func (e *Elector) lead(ctx context.Context, l *lease.Lease) error {
+	machineID, err := e.st.MachineID()
+	if err != nil {
+		l.Release(context.Background())
+		return err
+	}
+	serviceID, err := e.st.ServiceID()
+	if err != nil {
+		l.Release(context.Background())
+		return err
+	}
+	// A dead leader stopped issuing when its lease expired locally, before
+	// this one was granted; the margin covers the skew of the two clocks.
+	// A leader stepping down saved its mark, which may lie further ahead.
+	if e.opts.Store != nil {
+		err = handoff.Acquire(ctx, e.opts.Store, machineID, serviceID, e.opts.Margin)
+	}
+	if err == nil {
+		err = sleep(ctx, e.opts.Margin)
+	}
+	if err != nil {
+		l.Release(context.Background())
+		return err
+	}
+
+	st := e.st
+	check := st.CheckMachineID
+	st.CheckMachineID = func(id uint16) bool {
+		return l.Valid() && (check == nil || check(id))
+	}
+	gen := dxyflake.NewDxyflake(st)
+	if gen == nil {
+		l.Release(context.Background())
+		return ErrNoGenerator
+	}
+
+	e.mutex.Lock()
+	e.lease, e.gen = l, gen
+	e.mutex.Unlock()
+
+	select {
+	case <-l.Done():
+		err = l.Err()
+	case <-ctx.Done():
+	}
+
+	e.mutex.Lock()
+	e.lease, e.gen = nil, nil
+	e.mutex.Unlock()
+
+	if ctx.Err() != nil && e.opts.Store != nil {
+		_, err = handoff.Release(context.Background(), gen, e.opts.Store, func() error {
+			return l.Release(context.Background())
+		})
+		return err
+	}
+	l.Release(context.Background())
+	return err
+}
+
+// Leading reports whether this instance leads.
+func (e *Elector) Leading() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.gen != nil && e.lease.Valid()
+}
+
+// Leader returns the address of the leader.
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	holder, ok, err := e.backend.Holder(ctx, e.opts.Key)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrNoLeader
+	}
+	return holder, nil
+}
+
+// NextID issues an ID while this instance leads, and fetches one from the
+// leader otherwise.
+func (e *Elector) NextID() (dxyflake.ID, error) {
+	e.mutex.Lock()
+	gen, l := e.gen, e.lease
+	e.mutex.Unlock()
+	if gen != nil && l.Valid() {
+		return gen.NextID()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.Lease.TTL)
+	defer cancel()
+	addr, err := e.Leader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if addr == e.opts.Address {
+		// elected, but still waiting for the old leader's mark
+		return 0, ErrNoLeader
+	}
+	return e.client(addr).NextID()
+}
+
+// client returns the client forwarding to the leader at addr.
+func (e *Elector) client(addr string) *client.Client {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	c, ok := e.clients[addr]
+	if !ok {
+		c = client.New(addr, e.opts.Client)
+		e.clients[addr] = c
+	}
+	return c
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,120 @@
+package leader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/client"
+	"github.com/GiterLab/dxyflake/handoff"
+	"github.com/GiterLab/dxyflake/lease"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// instance is an Elector behind an ID server.
+type instance struct {
+	*Elector
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func start(t *testing.T, b Backend, store handoff.Store) *instance {
+	var h http.Handler
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	var st dxyflake.Settings
+	st.Init(7, 1)
+	e, err := New(b, st, Options{
+		Address: ts.URL,
+		Lease:   lease.Options{TTL: 60 * time.Millisecond},
+		Store:   store,
+		Margin:  10 * time.Millisecond,
+		Client:  client.Options{MaxWait: -1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = server.New(e, server.Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := &instance{Elector: e, cancel: cancel, done: make(chan error, 1)}
+	go func() { in.done <- e.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-in.done
+	})
+	return in
+}
+
+func waitLeading(t *testing.T, e *Elector) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !e.Leading() {
+		if time.Now().After(deadline) {
+			t.Fatal("not elected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	b := lease.NewMemoryBackend()
+	store := handoff.NewMemoryStore()
+
+	a := start(t, b, store)
+	waitLeading(t, a.Elector)
+	standby := start(t, b, store)
+
+	// the standby forwards to the leader
+	first, err := a.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarded, err := standby.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forwarded <= first {
+		t.Errorf("forwarded ID %d not after %d", forwarded, first)
+	}
+	if addr, err := standby.Leader(context.Background()); err != nil || addr != a.opts.Address {
+		t.Errorf("Leader() = %q, %v", addr, err)
+	}
+	if standby.Leading() {
+		t.Error("two leaders")
+	}
+
+	// the leader steps down and the standby takes over after its mark
+	last, err := a.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.cancel()
+	if err := <-a.done; err != nil {
+		t.Fatal(err)
+	}
+	a.done <- nil // for the cleanup
+	waitLeading(t, standby.Elector)
+	next, err := standby.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next <= last {
+		t.Errorf("ID %d of the new leader not after %d", next, last)
+	}
+	mark, ok, _ := store.LoadMark(context.Background(), 7, 1)
+	if !ok || dxyflake.DefaultLayout.TimeOf(next).Before(mark.Next) {
+		t.Errorf("ID of the new leader at %v before the mark %v", dxyflake.DefaultLayout.TimeOf(next), mark.Next)
+	}
+}
+
+func TestNoAddress(t *testing.T) {
+	if _, err := New(lease.NewMemoryBackend(), dxyflake.Settings{}, Options{}); err != ErrNoAddress {
+		t.Errorf("New() error = %v, want %v", err, ErrNoAddress)
+	}
+}
@@ -0,0 +1,12 @@
+-- KEYS[1]: lease key
+-- ARGV[1]: holder, ARGV[2]: TTL in milliseconds
+-- Returns 1 if holder holds the key for the TTL from now, 0 if another
+-- holder does.
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return 1
+end
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+return 0
@@ -0,0 +1,222 @@
+package lease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrEtcd is returned for error replies of etcd.
+var ErrEtcd = errors.New("etcd error")
+
+// EtcdBackend keeps leases as etcd keys holding the holder name and
+// attached to an etcd lease, through the JSON gateway of the etcd v3 API,
+// so that it needs no etcd client. Keys are only changed in transactions
+// comparing the holder.
+//
+// etcd leases keep the TTL they were granted with, in whole seconds: Renew
+// refreshes the lease to that TTL whatever TTL it is passed.
+type EtcdBackend struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewEtcdBackend returns an EtcdBackend talking to the etcd server at
+// endpoint, such as "http://etcd:2379", with client. If client is nil,
+// http.DefaultClient is used.
+func NewEtcdBackend(endpoint string, client *http.Client) *EtcdBackend {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &EtcdBackend{endpoint: strings.TrimSuffix(endpoint, "/"), client: client}
+}
+
+// etcdInt is an int64 of the JSON gateway, written as a string.
+type etcdInt int64
+
+func (n etcdInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(n), 10))
+}
+
+func (n *etcdInt) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*n = etcdInt(v)
+	return nil
+}
+
+type etcdKV struct {
+	Key   []byte  `json:"key"`
+	Value []byte  `json:"value"`
+	Lease etcdInt `json:"lease"`
+}
+
+type etcdLease struct {
+	ID  etcdInt `json:"ID"`
+	TTL etcdInt `json:"TTL"`
+}
+
+type etcdCompare struct {
+	Key            []byte  `json:"key"`
+	Result         string  `json:"result"`
+	Target         string  `json:"target"`
+	CreateRevision etcdInt `json:"create_revision,omitempty"`
+	Value          []byte  `json:"value,omitempty"`
+}
+
+type etcdPut struct {
+	Key   []byte  `json:"key"`
+	Value []byte  `json:"value"`
+	Lease etcdInt `json:"lease"`
+}
+
+type etcdOp struct {
+	RequestPut *etcdPut `json:"request_put,omitempty"`
+}
+
+type etcdTxn struct {
+	Compare []etcdCompare `json:"compare"`
+	Success []etcdOp      `json:"success"`
+}
+
+// call posts req to the API path and decodes the reply into resp.
+func (b *EtcdBackend) call(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	res, err := b.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(res.Body).Decode(&e)
+		return fmt.Errorf("%w: %s %s: %s", ErrEtcd, path, res.Status, e.Message)
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// get returns the key-value of key, or nil if there is none.
+func (b *EtcdBackend) get(ctx context.Context, key string) (*etcdKV, error) {
+	var resp struct {
+		KVs []etcdKV `json:"kvs"`
+	}
+	if err := b.call(ctx, "/v3/kv/range", map[string][]byte{"key": []byte(key)}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.KVs) == 0 {
+		return nil, nil
+	}
+	return &resp.KVs[0], nil
+}
+
+// put puts holder on key with a new lease of ttl if cmp holds, and revokes
+// the lease if not.
+func (b *EtcdBackend) put(ctx context.Context, key, holder string, ttl time.Duration, cmp etcdCompare) (bool, error) {
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	var grant etcdLease
+	if err := b.call(ctx, "/v3/lease/grant", etcdLease{TTL: etcdInt(secs)}, &grant); err != nil {
+		return false, err
+	}
+	txn := etcdTxn{
+		Compare: []etcdCompare{cmp},
+		Success: []etcdOp{{RequestPut: &etcdPut{Key: []byte(key), Value: []byte(holder), Lease: grant.ID}}},
+	}
+	var resp struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	err := b.call(ctx, "/v3/kv/txn", txn, &resp)
+	if err != nil || !resp.Succeeded {
+		b.revoke(ctx, grant.ID)
+	}
+	return resp.Succeeded, err
+}
+
+func (b *EtcdBackend) revoke(ctx context.Context, id etcdInt) error {
+	var resp struct{}
+	return b.call(ctx, "/v3/lease/revoke", etcdLease{ID: id}, &resp)
+}
+
+// Acquire implements Backend.
+func (b *EtcdBackend) Acquire(ctx context.Context, key, holder string, ttl time.Duration) error {
+	ok, err := b.put(ctx, key, holder, ttl, etcdCompare{Key: []byte(key), Result: "EQUAL", Target: "CREATE"})
+	if err != nil || ok {
+		return err
+	}
+	// taken again by the same holder, e.g. after a restart
+	kv, err := b.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if kv == nil || string(kv.Value) != holder {
+		return ErrHeld
+	}
+	ok, err = b.put(ctx, key, holder, ttl, etcdCompare{Key: []byte(key), Result: "EQUAL", Target: "VALUE", Value: []byte(holder)})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrHeld
+	}
+	if kv.Lease != 0 {
+		b.revoke(ctx, kv.Lease)
+	}
+	return nil
+}
+
+// Renew implements Backend.
+func (b *EtcdBackend) Renew(ctx context.Context, key, holder string, ttl time.Duration) error {
+	kv, err := b.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if kv == nil || string(kv.Value) != holder {
+		return ErrNotHeld
+	}
+	var resp struct {
+		Result etcdLease `json:"result"`
+	}
+	if err := b.call(ctx, "/v3/lease/keepalive", etcdLease{ID: kv.Lease}, &resp); err != nil {
+		return err
+	}
+	if resp.Result.TTL <= 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Release implements Backend.
+func (b *EtcdBackend) Release(ctx context.Context, key, holder string) error {
+	kv, err := b.get(ctx, key)
+	if err != nil || kv == nil || string(kv.Value) != holder {
+		return err
+	}
+	return b.revoke(ctx, kv.Lease)
+}
+
+// Holder implements Inspector.
+func (b *EtcdBackend) Holder(ctx context.Context, key string) (string, bool, error) {
+	kv, err := b.get(ctx, key)
+	if err != nil || kv == nil {
+		return "", false, err
+	}
+	return string(kv.Value), true, nil
+}
@@ -0,0 +1,141 @@
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEtcd serves the part of the etcd v3 JSON gateway EtcdBackend uses.
+// Leases never expire on their own; expire revokes them.
+type testEtcd struct {
+	mu     sync.Mutex
+	nextID int64
+	leases map[int64]int64 // TTL by lease ID
+	kvs    map[string]etcdKV
+	ttls   []int64
+}
+
+func newTestEtcd() *testEtcd {
+	return &testEtcd{leases: make(map[int64]int64), kvs: make(map[string]etcdKV)}
+}
+
+func (e *testEtcd) expire(id int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.revoke(id)
+}
+
+func (e *testEtcd) revoke(id int64) {
+	delete(e.leases, id)
+	for k, kv := range e.kvs {
+		if int64(kv.Lease) == id {
+			delete(e.kvs, k)
+		}
+	}
+}
+
+func (e *testEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var resp interface{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		var req etcdLease
+		json.NewDecoder(r.Body).Decode(&req)
+		e.nextID++
+		e.leases[e.nextID] = int64(req.TTL)
+		e.ttls = append(e.ttls, int64(req.TTL))
+		resp = map[string]string{"ID": strconv.FormatInt(e.nextID, 10), "TTL": strconv.FormatInt(int64(req.TTL), 10)}
+	case "/v3/lease/keepalive":
+		var req etcdLease
+		json.NewDecoder(r.Body).Decode(&req)
+		result := map[string]string{"ID": strconv.FormatInt(int64(req.ID), 10)}
+		if ttl, ok := e.leases[int64(req.ID)]; ok {
+			result["TTL"] = strconv.FormatInt(ttl, 10)
+		}
+		resp = map[string]interface{}{"result": result}
+	case "/v3/lease/revoke":
+		var req etcdLease
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := e.leases[int64(req.ID)]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			resp = map[string]string{"message": "etcdserver: requested lease not found"}
+			break
+		}
+		e.revoke(int64(req.ID))
+		resp = struct{}{}
+	case "/v3/kv/range":
+		var req etcdKV
+		json.NewDecoder(r.Body).Decode(&req)
+		kvs := []etcdKV{}
+		if kv, ok := e.kvs[string(req.Key)]; ok {
+			kvs = append(kvs, kv)
+		}
+		resp = map[string]interface{}{"kvs": kvs, "count": strconv.Itoa(len(kvs))}
+	case "/v3/kv/txn":
+		var req etcdTxn
+		json.NewDecoder(r.Body).Decode(&req)
+		ok := true
+		for _, c := range req.Compare {
+			kv, found := e.kvs[string(c.Key)]
+			switch c.Target {
+			case "CREATE":
+				ok = ok && !found && c.CreateRevision == 0
+			case "VALUE":
+				ok = ok && found && string(kv.Value) == string(c.Value)
+			}
+		}
+		if ok {
+			for _, op := range req.Success {
+				p := op.RequestPut
+				e.kvs[string(p.Key)] = etcdKV{Key: p.Key, Value: p.Value, Lease: p.Lease}
+			}
+		}
+		resp = map[string]bool{"succeeded": ok}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestEtcdBackend(t *testing.T) {
+	e := newTestEtcd()
+	s := httptest.NewServer(e)
+	defer s.Close()
+	b := NewEtcdBackend(s.URL+"/", nil)
+	checkBackend(t, b)
+
+	ctx := context.Background()
+	if err := b.Acquire(ctx, "short", "a", 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := e.ttls[len(e.ttls)-1]; ttl != 2 {
+		t.Errorf("TTL of 1.5s granted as %ds", ttl)
+	}
+	e.mu.Lock()
+	if n := len(e.leases); n != 2 {
+		t.Errorf("%d leases left, want 2", n)
+	}
+	id := int64(e.kvs["short"].Lease)
+	e.mu.Unlock()
+
+	e.expire(id)
+	if err := b.Renew(ctx, "short", "a", time.Minute); err != ErrNotHeld {
+		t.Errorf("Renew() of expired lease error = %v, want %v", err, ErrNotHeld)
+	}
+	if err := b.Acquire(ctx, "short", "b", time.Minute); err != nil {
+		t.Errorf("Acquire() of expired lease error = %v", err)
+	}
+	if err := NewEtcdBackend(s.URL+"/missing", nil).Release(ctx, "k", "a"); !errors.Is(err, ErrEtcd) {
+		t.Errorf("Release() error = %v, want %v", err, ErrEtcd)
+	}
+}
@@ -0,0 +1,301 @@
+// Package lease keeps time-limited claims on keys in a shared backend.
+//
+// A backend only has to implement the three operations of Backend; the
+// Lease type takes care of jittered background renewal, of a local deadline
+// that errs on the safe side, and of telling the owner when the lease is
+// lost. Machine ID coordinators build on it so that a generator stops
+// issuing IDs before another process can claim the same machine ID.
+//
+// MemoryBackend keeps leases in the process, RedisBackend in Redis and
+// EtcdBackend in etcd.
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake/supervise"
+)
+
+// These errors are returned by backends and leases.
+var (
+	ErrHeld     = errors.New("lease is held by another holder")
+	ErrNotHeld  = errors.New("lease is not held by this holder")
+	ErrExpired  = errors.New("lease expired")
+	ErrReleased = errors.New("lease released")
+)
+
+// A lease lives for 10s by default, renewed every 3.3s ±20%.
+const (
+	DefaultTTL    = 10 * time.Second
+	DefaultJitter = 0.2
+)
+
+// Backend stores leases. Implementations must be safe for concurrent use.
+type Backend interface {
+	// Acquire claims key for holder for ttl. It returns ErrHeld if another
+	// holder has a lease on key that has not expired.
+	Acquire(ctx context.Context, key, holder string, ttl time.Duration) error
+	// Renew extends the lease of holder on key to ttl from now. It returns
+	// ErrNotHeld if holder no longer holds key.
+	Renew(ctx context.Context, key, holder string, ttl time.Duration) error
+	// Release gives up the lease of holder on key. Releasing a lease that is
+	// not held by holder is not an error.
+	Release(ctx context.Context, key, holder string) error
+}
+
+// Inspector is implemented by backends that can tell who holds a key, so
+// that others can find the holder, e.g. the leader of an election.
+type Inspector interface {
+	// Holder returns the holder of the unexpired lease on key. ok is false
+	// if key is not held.
+	Holder(ctx context.Context, key string) (holder string, ok bool, err error)
+}
+
+// Options configures a Lease:
+//
+// TTL is the lifetime of the lease in the backend.
+// If TTL is 0, DefaultTTL is used.
+//
+// RenewInterval is the mean time between renewals.
+// If RenewInterval is 0, a third of TTL is used.
+//
+// Jitter spreads renewals by this fraction of RenewInterval, so that many
+// holders do not hit the backend at once.
+// If Jitter is 0, DefaultJitter is used. Negative values disable jitter.
+//
+// OnExpired is called once, from the renewal goroutine, when the lease is
+// lost: either the backend reported that it is held by someone else, or no
+// renewal succeeded before the local deadline.
+//
+// Supervisor, if set, runs the renewal as its task "lease <key>" instead of
+// a goroutine of its own. A lost lease is then a fatal failure of the
+// supervisor, with ErrExpired, and the lease is released when the
+// supervisor stops.
+type Options struct {
+	TTL           time.Duration
+	RenewInterval time.Duration
+	Jitter        float64
+	OnExpired     func(key string)
+	Supervisor    *supervise.Supervisor
+}
+
+func (o *Options) setDefaults() {
+	if o.TTL <= 0 {
+		o.TTL = DefaultTTL
+	}
+	if o.RenewInterval <= 0 {
+		o.RenewInterval = o.TTL / 3
+	}
+	if o.Jitter == 0 {
+		o.Jitter = DefaultJitter
+	}
+	if o.Jitter < 0 {
+		o.Jitter = 0
+	}
+}
+
+// DefaultHolder returns a holder name made of the host name and process ID.
+func DefaultHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Lease is a claim on a key that is renewed in the background.
+type Lease struct {
+	backend Backend
+	key     string
+	holder  string
+	opts    Options
+
+	mutex    sync.Mutex
+	deadline time.Time
+	err      error
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Acquire claims key for holder and starts renewing the lease until it is
+// released or lost.
+func Acquire(ctx context.Context, b Backend, key, holder string, opts Options) (*Lease, error) {
+	opts.setDefaults()
+
+	start := time.Now()
+	if err := b.Acquire(ctx, key, holder, opts.TTL); err != nil {
+		return nil, err
+	}
+
+	l := &Lease{
+		backend:  b,
+		key:      key,
+		holder:   holder,
+		opts:     opts,
+		deadline: start.Add(opts.TTL),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if opts.Supervisor == nil {
+		go l.renew(context.Background())
+		return l, nil
+	}
+	err := opts.Supervisor.Go(supervise.Spec{Name: "lease " + key, Run: func(ctx context.Context) error {
+		l.renew(ctx)
+		if err := l.Err(); err == ErrExpired {
+			return err
+		}
+		return nil
+	}})
+	if err != nil {
+		b.Release(ctx, key, holder)
+		return nil, err
+	}
+	return l, nil
+}
+
+// Key returns the leased key.
+func (l *Lease) Key() string {
+	return l.key
+}
+
+// Holder returns the holder of the lease.
+func (l *Lease) Holder() string {
+	return l.holder
+}
+
+// Deadline returns the time until which the lease is known to be held. It
+// is measured from the start of the last successful call to the backend, so
+// it is never later than the expiry the backend sees.
+func (l *Lease) Deadline() time.Time {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.deadline
+}
+
+// Valid reports whether the lease is still held.
+func (l *Lease) Valid() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.err == nil && time.Now().Before(l.deadline)
+}
+
+// Done returns a channel that is closed once the lease is lost or released.
+func (l *Lease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns nil while the lease is held, ErrExpired after it was lost and
+// ErrReleased after Release.
+func (l *Lease) Err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.err
+}
+
+// Release stops renewing the lease and releases it in the backend.
+func (l *Lease) Release(ctx context.Context) error {
+	l.mutex.Lock()
+	if l.err != nil {
+		err := l.err
+		l.mutex.Unlock()
+		if err == ErrReleased {
+			return nil
+		}
+		return err
+	}
+	l.err = ErrReleased
+	l.mutex.Unlock()
+
+	close(l.stop)
+	<-l.done
+	return l.backend.Release(ctx, l.key, l.holder)
+}
+
+func (l *Lease) interval() time.Duration {
+	d := float64(l.opts.RenewInterval)
+	d += d * l.opts.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(d)
+}
+
+// renew renews the lease until it is released or lost, or until ctx is
+// done, which releases it.
+func (l *Lease) renew(ctx context.Context) {
+	defer close(l.done)
+
+	timer := time.NewTimer(l.interval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ctx.Done():
+			l.abandon()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		deadline := l.Deadline()
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		err := l.backend.Renew(ctx, l.key, l.holder, l.opts.TTL)
+		cancel()
+
+		switch {
+		case err == nil:
+			l.mutex.Lock()
+			l.deadline = start.Add(l.opts.TTL)
+			l.mutex.Unlock()
+			timer.Reset(l.interval())
+		case errors.Is(err, ErrNotHeld) || !time.Now().Before(deadline):
+			l.expire()
+			return
+		default:
+			// retry quickly, but never past the deadline
+			retry := l.interval() / 4
+			if left := time.Until(deadline); retry > left {
+				retry = left
+			}
+			timer.Reset(retry)
+		}
+	}
+}
+
+// abandon releases the lease from the renewal goroutine.
+func (l *Lease) abandon() {
+	l.mutex.Lock()
+	if l.err != nil {
+		l.mutex.Unlock()
+		return
+	}
+	l.err = ErrReleased
+	l.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.opts.RenewInterval)
+	defer cancel()
+	l.backend.Release(ctx, l.key, l.holder)
+}
+
+func (l *Lease) expire() {
+	l.mutex.Lock()
+	if l.err != nil {
+		l.mutex.Unlock()
+		return
+	}
+	l.err = ErrExpired
+	l.mutex.Unlock()
+
+	if l.opts.OnExpired != nil {
+		l.opts.OnExpired(l.key)
+	}
+}
@@ -0,0 +1,199 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake/supervise"
+)
+
+type entry struct {
+	holder  string
+	expires time.Time
+}
+
+// testBackend is a minimal Backend that can be made to fail.
+type testBackend struct {
+	mutex   sync.Mutex
+	entries map[string]entry
+	fail    error
+	renews  int
+}
+
+func newTestBackend() *testBackend {
+	return &testBackend{entries: make(map[string]entry)}
+}
+
+func (b *testBackend) Acquire(ctx context.Context, key, holder string, ttl time.Duration) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e, ok := b.entries[key]; ok && e.holder != holder && time.Now().Before(e.expires) {
+		return ErrHeld
+	}
+	b.entries[key] = entry{holder, time.Now().Add(ttl)}
+	return nil
+}
+
+func (b *testBackend) Renew(ctx context.Context, key, holder string, ttl time.Duration) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.renews++
+	if b.fail != nil {
+		return b.fail
+	}
+	if e, ok := b.entries[key]; !ok || e.holder != holder {
+		return ErrNotHeld
+	}
+	b.entries[key] = entry{holder, time.Now().Add(ttl)}
+	return nil
+}
+
+func (b *testBackend) Release(ctx context.Context, key, holder string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e, ok := b.entries[key]; ok && e.holder == holder {
+		delete(b.entries, key)
+	}
+	return nil
+}
+
+func (b *testBackend) set(f func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	f()
+}
+
+func TestAcquireRenewRelease(t *testing.T) {
+	b := newTestBackend()
+	ctx := context.Background()
+
+	l, err := Acquire(ctx, b, "machine-1", "a", Options{TTL: 60 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Acquire(ctx, b, "machine-1", "b", Options{}); err != ErrHeld {
+		t.Errorf("Acquire() error = %v, want %v", err, ErrHeld)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if !l.Valid() {
+		t.Fatalf("lease not renewed: %v", l.Err())
+	}
+
+	if err := l.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if l.Valid() || l.Err() != ErrReleased {
+		t.Errorf("lease valid after release: %v", l.Err())
+	}
+	if err := l.Release(ctx); err != nil {
+		t.Errorf("second Release() error = %v", err)
+	}
+	if _, err := Acquire(ctx, b, "machine-1", "b", Options{}); err != nil {
+		t.Errorf("Acquire() after release error = %v", err)
+	}
+}
+
+func TestLeaseStolen(t *testing.T) {
+	b := newTestBackend()
+	expired := make(chan string, 1)
+	l, err := Acquire(context.Background(), b, "machine-2", "a", Options{
+		TTL:       30 * time.Millisecond,
+		OnExpired: func(key string) { expired <- key },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.set(func() { b.entries["machine-2"] = entry{"b", time.Now().Add(time.Hour)} })
+
+	select {
+	case key := <-expired:
+		if key != "machine-2" {
+			t.Errorf("unexpected key: %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnExpired not called")
+	}
+	<-l.Done()
+	if l.Valid() || l.Err() != ErrExpired {
+		t.Errorf("unexpected lease state: %v", l.Err())
+	}
+}
+
+func TestLeaseBackendDown(t *testing.T) {
+	b := newTestBackend()
+	expired := make(chan string, 1)
+	l, err := Acquire(context.Background(), b, "machine-3", "a", Options{
+		TTL:       60 * time.Millisecond,
+		OnExpired: func(key string) { expired <- key },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	b.set(func() { b.fail = errors.New("backend down") })
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("OnExpired not called")
+	}
+	if time.Since(start) > 200*time.Millisecond {
+		t.Errorf("lease expired late: %s", time.Since(start))
+	}
+	if !time.Now().After(l.Deadline()) {
+		t.Error("lease expired before its deadline")
+	}
+
+	b.set(func() {
+		if b.renews < 2 {
+			t.Errorf("renewal not retried: %d", b.renews)
+		}
+	})
+}
+
+func TestSupervised(t *testing.T) {
+	b := newTestBackend()
+	sup := supervise.New(context.Background(), supervise.Options{})
+	l, err := Acquire(context.Background(), b, "machine-4", "a", Options{TTL: 30 * time.Millisecond, Supervisor: sup})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a lost lease stops the supervisor
+	b.set(func() { b.entries["machine-4"] = entry{"b", time.Now().Add(time.Hour)} })
+	if err := sup.Wait(); !errors.Is(err, ErrExpired) {
+		t.Errorf("unexpected supervisor error: %v", err)
+	}
+	if l.Err() != ErrExpired {
+		t.Errorf("unexpected lease state: %v", l.Err())
+	}
+
+	// stopping the supervisor releases the lease
+	sup = supervise.New(context.Background(), supervise.Options{})
+	l, err = Acquire(context.Background(), b, "machine-5", "a", Options{Supervisor: sup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sup.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if l.Err() != ErrReleased {
+		t.Errorf("unexpected lease state: %v", l.Err())
+	}
+	if _, err := Acquire(context.Background(), b, "machine-5", "b", Options{}); err != nil {
+		t.Errorf("lease not released: %v", err)
+	}
+	if _, err := Acquire(context.Background(), b, "machine-6", "a", Options{Supervisor: sup}); err != supervise.ErrStopped {
+		t.Errorf("acquired under a stopped supervisor: %v", err)
+	}
+}
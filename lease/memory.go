@@ -0,0 +1,89 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend is a Backend keeping leases in memory, for tests and for
+// local development without a datastore. It behaves like the networked
+// backends: leases expire after their TTL and cannot be renewed once
+// expired.
+type MemoryBackend struct {
+	mutex   sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	holder  string
+	expires time.Time
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{entries: make(map[string]memoryEntry)}
+}
+
+// get returns the unexpired entry of key.
+func (b *MemoryBackend) get(key string) (memoryEntry, bool) {
+	e, ok := b.entries[key]
+	if ok && !time.Now().Before(e.expires) {
+		delete(b.entries, key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+// Acquire implements Backend.
+func (b *MemoryBackend) Acquire(ctx context.Context, key, holder string, ttl time.Duration) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e, ok := b.get(key); ok && e.holder != holder {
+		return ErrHeld
+	}
+	b.entries[key] = memoryEntry{holder, time.Now().Add(ttl)}
+	return nil
+}
+
+// Renew implements Backend.
+func (b *MemoryBackend) Renew(ctx context.Context, key, holder string, ttl time.Duration) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e, ok := b.get(key); !ok || e.holder != holder {
+		return ErrNotHeld
+	}
+	b.entries[key] = memoryEntry{holder, time.Now().Add(ttl)}
+	return nil
+}
+
+// Release implements Backend.
+func (b *MemoryBackend) Release(ctx context.Context, key, holder string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e, ok := b.get(key); ok && e.holder == holder {
+		delete(b.entries, key)
+	}
+	return nil
+}
+
+// Holder implements Inspector.
+func (b *MemoryBackend) Holder(ctx context.Context, key string) (string, bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	e, ok := b.get(key)
+	return e.holder, ok, nil
+}
+
+// Revoke drops the lease on key, as when a datastore loses it, so that
+// tests can exercise lease loss.
+func (b *MemoryBackend) Revoke(key string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.entries, key)
+}
@@ -0,0 +1,113 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	checkBackend(t, NewMemoryBackend())
+
+	b := NewMemoryBackend()
+	ctx := context.Background()
+
+	if err := b.Acquire(ctx, "k", "a", 30*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(ctx, "k", "b", time.Minute); err != ErrHeld {
+		t.Errorf("Acquire() error = %v, want %v", err, ErrHeld)
+	}
+	if err := b.Renew(ctx, "k", "b", time.Minute); err != ErrNotHeld {
+		t.Errorf("Renew() error = %v, want %v", err, ErrNotHeld)
+	}
+	if holder, ok, _ := b.Holder(ctx, "k"); !ok || holder != "a" {
+		t.Errorf("Holder() = %q, %v", holder, ok)
+	}
+
+	// an expired lease can be taken over but not renewed
+	time.Sleep(50 * time.Millisecond)
+	if err := b.Renew(ctx, "k", "a", time.Minute); err != ErrNotHeld {
+		t.Errorf("Renew() of expired lease error = %v, want %v", err, ErrNotHeld)
+	}
+	if err := b.Acquire(ctx, "k", "b", time.Minute); err != nil {
+		t.Errorf("Acquire() of expired lease error = %v", err)
+	}
+
+	if err := b.Release(ctx, "k", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if holder, _, _ := b.Holder(ctx, "k"); holder != "b" {
+		t.Errorf("released by another holder: %q", holder)
+	}
+	b.Revoke("k")
+	if _, ok, _ := b.Holder(ctx, "k"); ok {
+		t.Error("lease not revoked")
+	}
+}
+
+func TestMemoryBackendLeaseLost(t *testing.T) {
+	b := NewMemoryBackend()
+	l, err := Acquire(context.Background(), b, "k", "a", Options{TTL: 30 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Revoke("k")
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("revoked lease still held")
+	}
+	if l.Err() != ErrExpired {
+		t.Errorf("unexpected lease state: %v", l.Err())
+	}
+}
+
+// checkBackend checks the behaviour every backend shares.
+func checkBackend(t *testing.T, b interface {
+	Backend
+	Inspector
+}) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, ok, err := b.Holder(ctx, "k"); err != nil || ok {
+		t.Errorf("Holder() of free key = %v, %v", ok, err)
+	}
+	if err := b.Acquire(ctx, "k", "a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(ctx, "k", "a", time.Minute); err != nil {
+		t.Errorf("Acquire() by the holder error = %v", err)
+	}
+	if err := b.Acquire(ctx, "k", "b", time.Minute); err != ErrHeld {
+		t.Errorf("Acquire() error = %v, want %v", err, ErrHeld)
+	}
+	if err := b.Renew(ctx, "k", "a", time.Minute); err != nil {
+		t.Errorf("Renew() error = %v", err)
+	}
+	if err := b.Renew(ctx, "k", "b", time.Minute); err != ErrNotHeld {
+		t.Errorf("Renew() error = %v, want %v", err, ErrNotHeld)
+	}
+	if holder, ok, _ := b.Holder(ctx, "k"); !ok || holder != "a" {
+		t.Errorf("Holder() = %q, %v", holder, ok)
+	}
+	if err := b.Release(ctx, "k", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if holder, _, _ := b.Holder(ctx, "k"); holder != "a" {
+		t.Errorf("released by another holder: %q", holder)
+	}
+	if err := b.Release(ctx, "k", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Holder(ctx, "k"); ok {
+		t.Error("lease not released")
+	}
+	if err := b.Renew(ctx, "k", "a", time.Minute); err != ErrNotHeld {
+		t.Errorf("Renew() of released lease error = %v, want %v", err, ErrNotHeld)
+	}
+	if err := b.Acquire(ctx, "k", "b", time.Minute); err != nil {
+		t.Errorf("Acquire() of released lease error = %v", err)
+	}
+}
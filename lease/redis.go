@@ -0,0 +1,103 @@
+package lease
+
+import (
+	"context"
+	_ "embed"
+	"strconv"
+	"time"
+
+	"github.com/GiterLab/dxyflake/redisid"
+)
+
+var (
+	//go:embed acquire.lua
+	acquireLua string
+	//go:embed renew.lua
+	renewLua string
+	//go:embed release.lua
+	releaseLua string
+)
+
+// These are the scripts of RedisBackend, see the .lua files for their keys
+// and arguments.
+var (
+	AcquireScript = redisid.NewScript(acquireLua)
+	RenewScript   = redisid.NewScript(renewLua)
+	ReleaseScript = redisid.NewScript(releaseLua)
+	holderScript  = redisid.NewScript("return redis.call('GET', KEYS[1]) or ''")
+)
+
+// RedisBackend keeps leases as Redis keys holding the holder name and
+// expiring with the lease, changed only by Lua scripts so that checking
+// the holder and changing the key are atomic. Like package redisid, it
+// does not depend on a Redis client, see redisid.Evaler.
+type RedisBackend struct {
+	r redisid.Evaler
+}
+
+// NewRedisBackend returns a RedisBackend running its scripts with r.
+func NewRedisBackend(r redisid.Evaler) *RedisBackend {
+	return &RedisBackend{r: r}
+}
+
+func (b *RedisBackend) run(ctx context.Context, s *redisid.Script, key string, args ...interface{}) (int64, error) {
+	v, err := s.Run(ctx, b.r, []string{key}, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return 0, redisid.ErrUnexpectedReply
+	}
+	return n, nil
+}
+
+func ms(d time.Duration) string {
+	if d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
+
+// Acquire implements Backend.
+func (b *RedisBackend) Acquire(ctx context.Context, key, holder string, ttl time.Duration) error {
+	n, err := b.run(ctx, AcquireScript, key, holder, ms(ttl))
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrHeld
+	}
+	return nil
+}
+
+// Renew implements Backend.
+func (b *RedisBackend) Renew(ctx context.Context, key, holder string, ttl time.Duration) error {
+	n, err := b.run(ctx, RenewScript, key, holder, ms(ttl))
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Release implements Backend.
+func (b *RedisBackend) Release(ctx context.Context, key, holder string) error {
+	_, err := b.run(ctx, ReleaseScript, key, holder)
+	return err
+}
+
+// Holder implements Inspector.
+func (b *RedisBackend) Holder(ctx context.Context, key string) (string, bool, error) {
+	v, err := holderScript.Run(ctx, b.r, []string{key})
+	if err != nil {
+		return "", false, err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", false, redisid.ErrUnexpectedReply
+	}
+	return s, s != "", nil
+}
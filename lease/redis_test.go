@@ -0,0 +1,113 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake/redisid"
+)
+
+// testRedis runs the scripts of RedisBackend as Go code on an in-memory
+// key space.
+type testRedis struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	ttls    []string
+}
+
+func newTestRedis() *testRedis {
+	return &testRedis{values: make(map[string]string), expires: make(map[string]time.Time)}
+}
+
+func (r *testRedis) get(key string) (string, bool) {
+	if time.Now().After(r.expires[key]) {
+		delete(r.values, key)
+	}
+	v, ok := r.values[key]
+	return v, ok
+}
+
+func (r *testRedis) set(key, ttl string) {
+	n, _ := strconv.Atoi(ttl)
+	r.expires[key] = time.Now().Add(time.Duration(n) * time.Millisecond)
+	r.ttls = append(r.ttls, ttl)
+}
+
+func (r *testRedis) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := keys[0]
+	v, ok := r.get(key)
+	switch sha1 {
+	case AcquireScript.SHA1:
+		if !ok {
+			r.values[key] = args[0].(string)
+		} else if v != args[0] {
+			return int64(0), nil
+		}
+		r.set(key, args[1].(string))
+		return int64(1), nil
+	case RenewScript.SHA1:
+		if !ok || v != args[0] {
+			return int64(0), nil
+		}
+		r.set(key, args[1].(string))
+		return int64(1), nil
+	case ReleaseScript.SHA1:
+		if ok && v == args[0] {
+			delete(r.values, key)
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case holderScript.SHA1:
+		return v, nil
+	}
+	return nil, errors.New("NOSCRIPT No matching script. Please use EVAL.")
+}
+
+func (r *testRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.EvalSha(ctx, redisid.NewScript(script).SHA1, keys, args...)
+}
+
+func TestRedisBackend(t *testing.T) {
+	r := newTestRedis()
+	b := NewRedisBackend(r)
+	checkBackend(t, b)
+
+	ctx := context.Background()
+	if err := b.Acquire(ctx, "short", "a", time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := r.ttls[len(r.ttls)-1]; ttl != "1" {
+		t.Errorf("TTL below a millisecond sent as %q", ttl)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := b.Renew(ctx, "short", "a", time.Minute); err != ErrNotHeld {
+		t.Errorf("Renew() of expired lease error = %v, want %v", err, ErrNotHeld)
+	}
+	if err := b.Acquire(ctx, "short", "b", time.Minute); err != nil {
+		t.Errorf("Acquire() of expired lease error = %v", err)
+	}
+}
+
+func TestRedisBackendReply(t *testing.T) {
+	b := NewRedisBackend(replyEvaler{"OK"})
+	if err := b.Acquire(context.Background(), "k", "a", time.Minute); !errors.Is(err, redisid.ErrUnexpectedReply) {
+		t.Errorf("Acquire() error = %v, want %v", err, redisid.ErrUnexpectedReply)
+	}
+}
+
+type replyEvaler struct{ v interface{} }
+
+func (e replyEvaler) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	return e.v, nil
+}
+
+func (e replyEvaler) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return e.v, nil
+}
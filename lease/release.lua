@@ -0,0 +1,8 @@
+-- KEYS[1]: lease key
+-- ARGV[1]: holder
+-- Deletes the key if holder holds it, and returns the number of keys
+-- deleted.
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
@@ -0,0 +1,9 @@
+-- KEYS[1]: lease key
+-- ARGV[1]: holder, ARGV[2]: TTL in milliseconds
+-- Returns 1 if the lease of holder was extended, 0 if holder no longer
+-- holds the key.
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+return 0
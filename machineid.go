@@ -0,0 +1,159 @@
+package dxyflake
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+)
+
+// ErrNoPrivateIP is returned by PrivateIPv4 and MachineIDFromPrivateIP when
+// no network interface that is up has a private IPv4 address.
+var ErrNoPrivateIP = errors.New("no private ip address")
+
+// ErrNoIPv4 is returned by InterfaceIPv4 and MachineIDFromInterface when
+// the interface has no IPv4 address.
+var ErrNoIPv4 = errors.New("no ipv4 address")
+
+// PrivateIPv4 returns the first private IPv4 address, in 10.0.0.0/8,
+// 172.16.0.0/12 or 192.168.0.0/16, of the network interfaces that are up,
+// skipping loopback interfaces.
+func PrivateIPv4() (net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		if ip := firstIPv4(addrs, isPrivateIPv4); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, ErrNoPrivateIP
+}
+
+// InterfaceIPv4 returns the first IPv4 address of the network interface
+// named name, such as "eth0".
+func InterfaceIPv4(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	if ip := firstIPv4(addrs, nil); ip != nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoIPv4, name)
+}
+
+// firstIPv4 returns the first IPv4 address of addrs accepted by ok, or by
+// any if ok is nil.
+func firstIPv4(addrs []net.Addr, ok func(net.IP) bool) net.IP {
+	for _, a := range addrs {
+		var ip net.IP
+		switch v := a.(type) {
+		case *net.IPNet:
+			ip = v.IP
+		case *net.IPAddr:
+			ip = v.IP
+		}
+		if ip = ip.To4(); ip != nil && (ok == nil || ok(ip)) {
+			return ip
+		}
+	}
+	return nil
+}
+
+func isPrivateIPv4(ip net.IP) bool {
+	return ip[0] == 10 ||
+		ip[0] == 172 && ip[1]&0xf0 == 16 ||
+		ip[0] == 192 && ip[1] == 168
+}
+
+// ipMachineID returns the lowest bits bits of ip.
+func ipMachineID(ip net.IP, bits int) uint16 {
+	return uint16(binary.BigEndian.Uint32(ip.To4()) & (1<<uint(bits) - 1))
+}
+
+// MachineIDFromPrivateIP returns a Settings.MachineID taking the machine ID
+// from the lowest bits of the private IPv4 address of the host, see
+// PrivateIPv4. Hosts only get distinct machine IDs if their addresses fall
+// in one block of 32 addresses, as in a small dedicated subnet; otherwise
+// pair it with Settings.CheckMachineID or a coordinator.
+func MachineIDFromPrivateIP() func() (uint16, error) {
+	return MachineIDFromPrivateIPBits(BitLenMachineID)
+}
+
+// MachineIDFromPrivateIPBits is like MachineIDFromPrivateIP for machine IDs
+// of bits bits, see Settings.BitsMachineID: addresses in one block of
+// 1<<bits addresses get distinct machine IDs.
+func MachineIDFromPrivateIPBits(bits int) func() (uint16, error) {
+	return func() (uint16, error) {
+		ip, err := PrivateIPv4()
+		if err != nil {
+			return 0, err
+		}
+		return ipMachineID(ip, bits), nil
+	}
+}
+
+// MachineIDFromInterface returns a Settings.MachineID taking the machine ID
+// from the lowest bits of the IPv4 address of the network interface named
+// name, see InterfaceIPv4 and MachineIDFromPrivateIP.
+func MachineIDFromInterface(name string) func() (uint16, error) {
+	return MachineIDFromInterfaceBits(name, BitLenMachineID)
+}
+
+// MachineIDFromInterfaceBits is like MachineIDFromInterface for machine
+// IDs of bits bits, see Settings.BitsMachineID.
+func MachineIDFromInterfaceBits(name string, bits int) func() (uint16, error) {
+	return func() (uint16, error) {
+		ip, err := InterfaceIPv4(name)
+		if err != nil {
+			return 0, err
+		}
+		return ipMachineID(ip, bits), nil
+	}
+}
+
+// MachineIDFromHostname returns a Settings.MachineID hashing the host name,
+// which is the pod name under Kubernetes, into the machine ID. Distinct
+// hosts collide with a chance of 1 in 32 per pair, so pair it with
+// Settings.CheckMachineID or a coordinator beyond a few hosts.
+func MachineIDFromHostname() func() (uint16, error) {
+	return MachineIDFromHostnameBits(BitLenMachineID)
+}
+
+// MachineIDFromHostnameBits is like MachineIDFromHostname for machine IDs
+// of bits bits, see Settings.BitsMachineID. Distinct hosts collide with a
+// chance of 1 in 1<<bits per pair.
+func MachineIDFromHostnameBits(bits int) func() (uint16, error) {
+	return func() (uint16, error) {
+		name, err := os.Hostname()
+		if err != nil {
+			return 0, err
+		}
+		return hashMachineID(name, bits), nil
+	}
+}
+
+// hashMachineID folds the FNV-1a hash of name into a machine ID of bits
+// bits, as the hostname provider of package config does.
+func hashMachineID(name string, bits int) uint16 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := h.Sum32()
+	sum ^= sum >> 16
+	return uint16(sum & (1<<uint(bits) - 1))
+}
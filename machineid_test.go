@@ -0,0 +1,59 @@
+package dxyflake
+
+import (
+	"net"
+	"os"
+	"testing"
+)
+
+func TestFirstIPv4(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.ParseIP("fe80::1")},
+		&net.IPNet{IP: net.ParseIP("8.8.8.8")},
+		&net.IPAddr{IP: net.ParseIP("172.31.4.77")},
+		&net.IPNet{IP: net.ParseIP("10.0.0.3")},
+	}
+	if ip := firstIPv4(addrs, isPrivateIPv4); !ip.Equal(net.ParseIP("172.31.4.77")) {
+		t.Errorf("first private address = %v", ip)
+	}
+	if ip := firstIPv4(addrs, nil); !ip.Equal(net.ParseIP("8.8.8.8")) {
+		t.Errorf("first address = %v", ip)
+	}
+	if ip := firstIPv4(addrs[:2], isPrivateIPv4); ip != nil {
+		t.Errorf("public address taken as private: %v", ip)
+	}
+
+	for s, private := range map[string]bool{
+		"10.1.2.3": true, "172.16.0.1": true, "172.32.0.1": false,
+		"192.168.1.1": true, "192.169.1.1": false, "100.64.0.1": false,
+	} {
+		if got := isPrivateIPv4(net.ParseIP(s).To4()); got != private {
+			t.Errorf("isPrivateIPv4(%s) = %v", s, got)
+		}
+	}
+	if id := ipMachineID(net.ParseIP("10.0.1.77"), BitLenMachineID); id != 77&31 {
+		t.Errorf("ipMachineID() = %d", id)
+	}
+	if id := ipMachineID(net.ParseIP("10.0.1.77"), 10); id != 256+77 {
+		t.Errorf("10-bit ipMachineID() = %d", id)
+	}
+}
+
+func TestMachineIDProviders(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	if id, err := MachineIDFromHostname()(); err != nil || id != hashMachineID(host, BitLenMachineID) || id >= 1<<BitLenMachineID {
+		t.Errorf("MachineIDFromHostname() = %d, %v", id, err)
+	}
+	if id, err := MachineIDFromHostnameBits(10)(); err != nil || id != hashMachineID(host, 10) || id&31 != hashMachineID(host, BitLenMachineID)&31 {
+		t.Errorf("MachineIDFromHostnameBits(10)() = %d, %v", id, err)
+	}
+	if _, err := MachineIDFromInterface("no-such-interface0")(); err == nil {
+		t.Error("machine ID of a missing interface")
+	}
+	if id, err := MachineIDFromPrivateIP()(); err == nil && id >= 1<<BitLenMachineID {
+		t.Errorf("MachineIDFromPrivateIP() = %d", id)
+	}
+}
@@ -0,0 +1,213 @@
+package dxyflake
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxRate is the number of IDs one dxyflake of DefaultLayout can issue per
+// second: every sequence number of every time unit. See Manager.MaxRate
+// for other layouts.
+const MaxRate = (1 << BitLenSequence) * (1e9 / dxyflakeTimeUnit)
+
+// ErrInvalidWeight is returned by Manager.Service for weights that are not
+// positive.
+var ErrInvalidWeight = errors.New("invalid weight")
+
+// Manager shares one generator, and so one sequence space, between
+// logical services.
+//
+// Every service gets a token bucket holding its weighted share of the
+// sequence numbers of a time unit, refilled at the start of every time
+// unit. A burst of one service therefore cannot use up the sequence
+// numbers of a time unit and make the others wait for the next one. The
+// share of a service is reserved for it even while it is idle.
+type Manager struct {
+	gen      Generator
+	layout   Layout
+	clock    Clock
+	mutex    sync.Mutex
+	services map[string]*Service
+	total    float64 // sum of the weights
+}
+
+// NewManager returns a Manager sharing gen. It shares the sequence numbers
+// and time units of the layout of gen by the clock of gen, if gen has
+// Layout and Clock methods like dxyflakes do, and those of DefaultLayout by
+// SystemClock otherwise.
+func NewManager(gen Generator) *Manager {
+	m := &Manager{gen: gen, layout: DefaultLayout, clock: SystemClock, services: make(map[string]*Service)}
+	if g, ok := gen.(interface{ Layout() Layout }); ok {
+		m.layout = g.Layout()
+	}
+	if g, ok := gen.(interface{ Clock() Clock }); ok {
+		m.clock = g.Clock()
+	}
+	return m
+}
+
+// MaxRate returns the number of IDs the generator of the Manager can issue
+// per second, shared by its services.
+func (m *Manager) MaxRate() float64 {
+	return float64(m.layout.Capacity())
+}
+
+// Service returns the generator of the named service, registering it with
+// weight on first use. Registering a service, or changing its weight,
+// rebalances the shares of all services.
+func (m *Manager) Service(name string, weight float64) (*Service, error) {
+	if weight <= 0 {
+		return nil, ErrInvalidWeight
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s, ok := m.services[name]
+	if !ok {
+		s = &Service{name: name, manager: m}
+		m.services[name] = s
+	}
+	s.mutex.Lock()
+	m.total += weight - s.weight
+	s.weight = weight
+	s.mutex.Unlock()
+	for _, s := range m.services {
+		s.rebalance(m.total)
+	}
+	return s, nil
+}
+
+// Stats returns the statistics of every service, sorted by name.
+func (m *Manager) Stats() []ServiceStats {
+	m.mutex.Lock()
+	services := make([]*Service, 0, len(m.services))
+	for _, s := range m.services {
+		services = append(services, s)
+	}
+	m.mutex.Unlock()
+
+	stats := make([]ServiceStats, len(services))
+	var issued uint64
+	for i, s := range services {
+		stats[i] = s.Stats()
+		issued += stats[i].Issued
+	}
+	for i := range stats {
+		if issued > 0 {
+			stats[i].Utilization = float64(stats[i].Issued) / float64(issued)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// ServiceStats are the statistics of one service of a Manager.
+//
+// Rate is the number of IDs per second reserved for the service.
+// Throttled counts the IDs the service had to wait for, and Waited is the
+// total time it waited. Utilization is the share of all IDs issued by the
+// Manager that went to the service.
+type ServiceStats struct {
+	Name        string
+	Weight      float64
+	Rate        float64
+	Issued      uint64
+	Throttled   uint64
+	Waited      time.Duration
+	Utilization float64
+}
+
+// Service is a Generator for one service of a Manager.
+type Service struct {
+	name    string
+	manager *Manager
+
+	mutex     sync.Mutex
+	weight    float64
+	quota     int   // sequence numbers per time unit
+	tick      int64 // time unit the quota was last refilled at
+	used      int
+	issued    uint64
+	throttled uint64
+	waited    time.Duration
+}
+
+// rebalance sets the quota of s. It is called with the Manager locked.
+func (s *Service) rebalance(total float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.quota = int(float64(int64(1)<<uint(s.manager.layout.BitLenSequence)) * s.weight / total)
+	if s.quota < 1 {
+		s.quota = 1
+	}
+}
+
+// take takes a sequence number from the quota of the current time unit.
+// If the quota is used up, it returns how long to wait for the next one.
+func (s *Service) take() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l := s.manager.layout
+	t := s.manager.clock.Now().Add(clockJump())
+	if tick := l.units(t); tick != s.tick {
+		s.tick = tick
+		s.used = 0
+	}
+	if s.used < s.quota {
+		s.used++
+		return 0
+	}
+	return l.fromUnits(s.tick + 1).Sub(t)
+}
+
+// NextID waits for the share of the service and issues an ID.
+func (s *Service) NextID() (ID, error) {
+	var waited time.Duration
+	for {
+		wait := s.take()
+		if wait <= 0 {
+			break
+		}
+		s.manager.clock.Sleep(wait)
+		waited += wait
+	}
+
+	id, err := s.manager.gen.NextID()
+
+	s.mutex.Lock()
+	if err == nil {
+		s.issued++
+	}
+	if waited > 0 {
+		s.throttled++
+		s.waited += waited
+	}
+	s.mutex.Unlock()
+	return id, err
+}
+
+// Name returns the name of the service.
+func (s *Service) Name() string {
+	return s.name
+}
+
+// Stats returns the statistics of the service. Utilization is only set by
+// Manager.Stats.
+func (s *Service) Stats() ServiceStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return ServiceStats{
+		Name:      s.name,
+		Weight:    s.weight,
+		Rate:      float64(s.quota) * float64(time.Second) / float64(s.manager.layout.Unit),
+		Issued:    s.issued,
+		Throttled: s.throttled,
+		Waited:    s.waited,
+	}
+}
@@ -0,0 +1,111 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManagerShares(t *testing.T) {
+	start := time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC)
+	clock := &steppedClock{now: start.Add(time.Hour)}
+	var st Settings
+	st.Init(5, 5)
+	st.StartTime = start
+	st.Clock = clock
+	m := NewManager(NewDxyflake(st))
+
+	notify, err := m.Service("notify", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit, err := m.Service("audit", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Service("bad", 0); err != ErrInvalidWeight {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	stats := m.Stats()
+	if len(stats) != 2 || stats[0].Name != "audit" || stats[0].Rate != MaxRate/4 || stats[1].Rate != MaxRate*3/4 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// notify bursts through three time units while audit issues at a low
+	// rate: audit never waits for the share notify used up
+	at := clock.Now()
+	for i := 0; i < 3*3072; i++ {
+		if _, err := notify.NextID(); err != nil {
+			t.Fatal(err)
+		}
+		if i%300 == 0 {
+			if _, err := audit.NextID(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if d := clock.Now().Sub(at); d != 20*time.Millisecond {
+		t.Errorf("three shares of notify after %v", d)
+	}
+
+	stats = m.Stats()
+	if stats[0].Issued != 31 || stats[0].Throttled != 0 {
+		t.Errorf("unexpected audit stats: %+v", stats[0])
+	}
+	if stats[1].Throttled != 2 || stats[1].Utilization < 0.99 {
+		t.Errorf("unexpected notify stats: %+v", stats[1])
+	}
+}
+
+func TestManagerRebalance(t *testing.T) {
+	var st Settings
+	st.Init(6, 6)
+	m := NewManager(NewDxyflake(st))
+
+	a, _ := m.Service("a", 1)
+	if a.Stats().Rate != MaxRate {
+		t.Errorf("unexpected rate: %f", a.Stats().Rate)
+	}
+	m.Service("b", 1)
+	if a.Stats().Rate != MaxRate/2 {
+		t.Errorf("unexpected rate: %f", a.Stats().Rate)
+	}
+	m.Service("b", 3)
+	if a.Stats().Rate != MaxRate/4 {
+		t.Errorf("unexpected rate: %f", a.Stats().Rate)
+	}
+}
+
+func TestManagerLayout(t *testing.T) {
+	start := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	clock := &steppedClock{now: start.Add(time.Hour)}
+	var st Settings
+	st.Init(7, 7)
+	st.StartTime = start
+	st.Clock = clock
+	st.TimeUnit = time.Second
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 43, 5, 5, 10
+	m := NewManager(NewDxyflake(st))
+	if m.MaxRate() != 1024 {
+		t.Errorf("MaxRate() = %f", m.MaxRate())
+	}
+
+	a, _ := m.Service("a", 1)
+	m.Service("b", 3)
+	if r := a.Stats().Rate; r != 256 {
+		t.Errorf("unexpected rate: %f", r)
+	}
+	// the share of a lasts the time unit of the generator
+	at := clock.Now()
+	for i := 0; i < 257; i++ {
+		if _, err := a.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := clock.Now().Sub(at); d != time.Second {
+		t.Errorf("257th ID of a share of 256 per second after %v", d)
+	}
+	if s := a.Stats(); s.Throttled != 1 || s.Waited != time.Second {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,313 @@
+// Package manifest describes reserved ID ranges for batch jobs.
+//
+// A Manifest names a range of IDs of one node, the Count IDs issued in
+// order from Start on, with the issuer that reserved it and the lease that
+// proves the issuer owned the node. A coordinator seals the manifest and
+// hands it to workers, which verify it before issuing IDs from it. The
+// checksum covers every other field, so a manifest is tamper-evident and
+// can be stored and looked up by its checksum. A worker that stops resumes
+// with Resume from the last ID it used.
+package manifest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+var (
+	// ErrInvalidManifest is returned for manifests whose fields do not
+	// describe a range of IDs.
+	ErrInvalidManifest = errors.New("invalid manifest")
+	// ErrChecksum is returned by Verify for manifests changed after they
+	// were sealed or sealed with another key.
+	ErrChecksum = errors.New("manifest checksum mismatch")
+	// ErrOutOfRange is returned for IDs and indexes outside a manifest.
+	ErrOutOfRange = errors.New("out of manifest range")
+)
+
+// Version is the version of the manifest format.
+const Version = 1
+
+// These are the prefixes of the checksums of sealed manifests.
+const (
+	SHA256     = "sha256:"
+	HMACSHA256 = "hmac-sha256:"
+)
+
+// LeaseProof is the lease the issuer held on the node of a range when it
+// reserved it.
+type LeaseProof struct {
+	Key      string    `json:"key"`
+	Holder   string    `json:"holder"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// ProofOf returns the LeaseProof of l.
+func ProofOf(l *lease.Lease) *LeaseProof {
+	return &LeaseProof{Key: l.Key(), Holder: l.Holder(), Deadline: l.Deadline().UTC()}
+}
+
+// Manifest describes the Count IDs issued in order from Start on by the
+// node of Start: the sequence counts up and wraps to the next time unit.
+//
+// Layout describes the IDs of the range. Parent is the checksum of the
+// manifest a manifest was split or resumed from. Checksum is set by Seal.
+type Manifest struct {
+	Version  int                  `json:"version"`
+	Layout   dxyflake.Description `json:"layout"`
+	Start    dxyflake.ID          `json:"start"`
+	Count    int64                `json:"count"`
+	Issuer   string               `json:"issuer"`
+	Lease    *LeaseProof          `json:"lease,omitempty"`
+	Parent   string               `json:"parent,omitempty"`
+	Created  time.Time            `json:"created"`
+	Checksum string               `json:"checksum,omitempty"`
+}
+
+// New returns an unsealed manifest of the count IDs of layout l from start
+// on, reserved by issuer. It returns ErrInvalidManifest if the range is
+// empty or does not fit the layout.
+func New(l dxyflake.Layout, start dxyflake.ID, count int64, issuer string) (*Manifest, error) {
+	m := &Manifest{
+		Version: Version,
+		Layout:  l.Describe(),
+		Start:   start,
+		Count:   count,
+		Issuer:  issuer,
+		Created: time.Now().UTC(),
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// layout returns the Layout of the manifest.
+func (m *Manifest) layout() (dxyflake.Layout, error) {
+	unit, err := time.ParseDuration(m.Layout.Unit)
+	if err != nil {
+		return dxyflake.Layout{}, ErrInvalidManifest
+	}
+	l := dxyflake.Layout{
+		Epoch:           m.Layout.Epoch,
+		Unit:            unit,
+		BitLenTime:      m.Layout.BitLenTime,
+		BitLenMachineID: m.Layout.BitLenMachineID,
+		BitLenServiceID: m.Layout.BitLenServiceID,
+		BitLenSequence:  m.Layout.BitLenSequence,
+	}
+	if l.Validate() != nil {
+		return dxyflake.Layout{}, ErrInvalidManifest
+	}
+	return l, nil
+}
+
+// Validate returns ErrInvalidManifest if the fields of the manifest do not
+// describe a range of IDs. It does not check the checksum.
+func (m *Manifest) Validate() error {
+	if m.Version != Version || m.Count <= 0 || m.Start < 0 {
+		return ErrInvalidManifest
+	}
+	l, err := m.layout()
+	if err != nil {
+		return err
+	}
+	if tick(l, m.Start)>>uint(l.BitLenTime) != 0 {
+		return ErrInvalidManifest
+	}
+	seq := int64(m.Start) & seqMask(l)
+	if m.Count > 1<<62 || tick(l, m.Start)+(seq+m.Count-1)>>uint(l.BitLenSequence) >= 1<<uint(l.BitLenTime) {
+		return ErrInvalidManifest
+	}
+	return nil
+}
+
+func timeShift(l dxyflake.Layout) uint {
+	return uint(l.BitLenMachineID + l.BitLenServiceID + l.BitLenSequence)
+}
+
+func tick(l dxyflake.Layout, id dxyflake.ID) int64 {
+	return int64(id) >> timeShift(l)
+}
+
+func seqMask(l dxyflake.Layout) int64 {
+	return 1<<uint(l.BitLenSequence) - 1
+}
+
+// node returns the machine ID and service ID bits of id.
+func node(l dxyflake.Layout, id dxyflake.ID) int64 {
+	return int64(id) & (1<<timeShift(l) - 1) &^ seqMask(l)
+}
+
+// sum returns the checksum of the manifest with key, a plain SHA-256 if
+// key is empty.
+func (m *Manifest) sum(key []byte) string {
+	c := *m
+	c.Checksum = ""
+	b, _ := json.Marshal(&c)
+	if len(key) == 0 {
+		s := sha256.Sum256(b)
+		return SHA256 + hex.EncodeToString(s[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return HMACSHA256 + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Seal validates the manifest and sets its checksum: an HMAC-SHA256 with
+// key, which workers sharing the key can trust, or a plain SHA-256 if key
+// is empty, which only detects accidental changes.
+func (m *Manifest) Seal(key []byte) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	m.Checksum = m.sum(key)
+	return nil
+}
+
+// Verify validates the manifest and returns ErrChecksum if it was not
+// sealed with key as it is. A manifest sealed with a key does not verify
+// without one.
+func (m *Manifest) Verify(key []byte) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(m.Checksum), []byte(m.sum(key))) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// Digest returns the hex checksum of a sealed manifest without its prefix,
+// to name the manifest in content-addressed storage.
+func (m *Manifest) Digest() string {
+	if i := strings.IndexByte(m.Checksum, ':'); i >= 0 {
+		return m.Checksum[i+1:]
+	}
+	return m.Checksum
+}
+
+// At returns the i-th ID of the range, from 0.
+func (m *Manifest) At(i int64) (dxyflake.ID, error) {
+	l, err := m.layout()
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i >= m.Count {
+		return 0, ErrOutOfRange
+	}
+	n := int64(m.Start)&seqMask(l) + i
+	t := tick(l, m.Start) + n>>uint(l.BitLenSequence)
+	return dxyflake.ID(t<<timeShift(l) | node(l, m.Start) | n&seqMask(l)), nil
+}
+
+// Index returns the index of id in the range, or ErrOutOfRange if id is
+// not part of it.
+func (m *Manifest) Index(id dxyflake.ID) (int64, error) {
+	l, err := m.layout()
+	if err != nil {
+		return 0, err
+	}
+	if node(l, id) != node(l, m.Start) || id < m.Start {
+		return 0, ErrOutOfRange
+	}
+	i := (tick(l, id)-tick(l, m.Start))<<uint(l.BitLenSequence) + int64(id)&seqMask(l) - int64(m.Start)&seqMask(l)
+	if i >= m.Count {
+		return 0, ErrOutOfRange
+	}
+	return i, nil
+}
+
+// Contains reports whether id is part of the range.
+func (m *Manifest) Contains(id dxyflake.ID) bool {
+	_, err := m.Index(id)
+	return err == nil
+}
+
+// Last returns the last ID of the range.
+func (m *Manifest) Last() (dxyflake.ID, error) {
+	return m.At(m.Count - 1)
+}
+
+// child returns an unsealed manifest of the count IDs from the i-th one on.
+func (m *Manifest) child(i, count int64) (*Manifest, error) {
+	start, err := m.At(i)
+	if err != nil {
+		return nil, err
+	}
+	c := *m
+	c.Start, c.Count = start, count
+	c.Parent, c.Checksum = m.Checksum, ""
+	c.Created = time.Now().UTC()
+	return &c, nil
+}
+
+// Split returns n unsealed manifests of consecutive parts of the range, one
+// per worker, or fewer if the range has fewer than n IDs.
+func (m *Manifest) Split(n int) ([]*Manifest, error) {
+	if n < 1 {
+		n = 1
+	}
+	if int64(n) > m.Count {
+		n = int(m.Count)
+	}
+	parts := make([]*Manifest, 0, n)
+	var i int64
+	for k := 0; k < n; k++ {
+		count := m.Count / int64(n)
+		if int64(k) < m.Count%int64(n) {
+			count++
+		}
+		c, err := m.child(i, count)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, c)
+		i += count
+	}
+	return parts, nil
+}
+
+// Resume returns an unsealed manifest of the IDs of the range after done,
+// the last ID a worker used. It returns ErrOutOfRange if done is not part
+// of the range, and io.EOF if done is its last ID.
+func (m *Manifest) Resume(done dxyflake.ID) (*Manifest, error) {
+	i, err := m.Index(done)
+	if err != nil {
+		return nil, err
+	}
+	if i == m.Count-1 {
+		return nil, io.EOF
+	}
+	return m.child(i+1, m.Count-i-1)
+}
+
+// Write writes the manifest as JSON to w.
+func (m *Manifest) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Read reads a manifest as JSON from r and verifies it with key.
+func Read(r io.Reader, key []byte) (*Manifest, error) {
+	var m Manifest
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	if err := m.Verify(key); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
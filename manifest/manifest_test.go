@@ -0,0 +1,155 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+func testManifest(t *testing.T, seq uint16, count int64) *Manifest {
+	start, err := dxyflake.DefaultLayout.Compose(time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC), 3, 4, seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(dxyflake.DefaultLayout, start, count, "batch-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRange(t *testing.T) {
+	m := testManifest(t, 4090, 10)
+	prev := dxyflake.ID(-1)
+	for i := int64(0); i < m.Count; i++ {
+		id, err := m.At(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := dxyflake.Decompose(id)
+		if id <= prev || parts["machine-id"] != 3 || parts["service-id"] != 4 {
+			t.Fatalf("unexpected id %d: %v", i, parts)
+		}
+		if n, err := m.Index(id); err != nil || n != i {
+			t.Errorf("Index(At(%d)) = %d, %v", i, n, err)
+		}
+		prev = id
+	}
+	last, _ := m.Last()
+	if parts := dxyflake.Decompose(last); parts["sequence"] != 3 || parts["time"] != dxyflake.Decompose(m.Start)["time"]+1 {
+		t.Errorf("sequence did not wrap: %v", parts)
+	}
+	if _, err := m.At(m.Count); err != ErrOutOfRange {
+		t.Errorf("At(Count) error = %v", err)
+	}
+	if m.Contains(last+1) || m.Contains(m.Start-1) || m.Contains(m.Start+1<<dxyflake.BitLenSequence) {
+		t.Error("range contains foreign ids")
+	}
+
+	if _, err := New(dxyflake.DefaultLayout, m.Start, 0, "batch-1"); err != ErrInvalidManifest {
+		t.Errorf("empty range accepted: %v", err)
+	}
+	if _, err := New(dxyflake.DefaultLayout, dxyflake.DefaultLayout.MaxIDAt(time.Now().AddDate(1000, 0, 0)), 2, "batch-1"); err != ErrInvalidManifest {
+		t.Errorf("range beyond the time limit accepted: %v", err)
+	}
+}
+
+func TestSealVerify(t *testing.T) {
+	b := lease.NewMemoryBackend()
+	l, err := lease.Acquire(context.Background(), b, "dxyflake/machine-id/3", "batch-1", lease.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Release(context.Background())
+
+	key := []byte("secret")
+	m := testManifest(t, 0, 100)
+	m.Lease = ProofOf(l)
+	if err := m.Seal(key); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(bytes.NewReader(buf.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Digest() != m.Digest() || got.Lease.Holder != "batch-1" {
+		t.Errorf("unexpected manifest: %+v", got)
+	}
+	if _, err := Read(bytes.NewReader(buf.Bytes()), nil); err != ErrChecksum {
+		t.Errorf("verified without the key: %v", err)
+	}
+
+	tampered := bytes.Replace(buf.Bytes(), []byte(`"count": 100`), []byte(`"count": 1000`), 1)
+	if _, err := Read(bytes.NewReader(tampered), key); err != ErrChecksum {
+		t.Errorf("tampered manifest verified: %v", err)
+	}
+
+	if err := m.Seal(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(nil); err != nil {
+		t.Errorf("unkeyed manifest not verified: %v", err)
+	}
+}
+
+func TestSplitResume(t *testing.T) {
+	m := testManifest(t, 4000, 1000)
+	if err := m.Seal(nil); err != nil {
+		t.Fatal(err)
+	}
+	parts, err := m.Split(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := m.Start
+	var total int64
+	for _, p := range parts {
+		if p.Start != next || p.Parent != m.Checksum {
+			t.Errorf("unexpected part: %+v", p)
+		}
+		if err := p.Seal(nil); err != nil {
+			t.Fatal(err)
+		}
+		last, _ := p.Last()
+		if n, err := m.Index(last); err != nil || n != total+p.Count-1 {
+			t.Errorf("part ends at %d, %v", n, err)
+		}
+		total += p.Count
+		if i, err := m.Index(last); err == nil && i+1 < m.Count {
+			next, _ = m.At(i + 1)
+		}
+	}
+	if total != m.Count {
+		t.Errorf("parts cover %d ids, want %d", total, m.Count)
+	}
+
+	done, _ := m.At(499)
+	rest, err := m.Resume(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Count != 500 || rest.Parent != m.Checksum {
+		t.Errorf("unexpected rest: %+v", rest)
+	}
+	if first, _ := m.At(500); rest.Start != first {
+		t.Errorf("resumed at %d, want %d", rest.Start, first)
+	}
+	last, _ := m.Last()
+	if _, err := m.Resume(last); err != io.EOF {
+		t.Errorf("Resume(Last) error = %v", err)
+	}
+	if _, err := m.Resume(last + 1); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Resume outside the range error = %v", err)
+	}
+}
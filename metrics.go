@@ -0,0 +1,24 @@
+package dxyflake
+
+import "time"
+
+// Metrics is notified of the work of a dxyflake as it happens, e.g. to
+// feed Prometheus collectors or expvar variables, see Settings.Metrics.
+// Its methods are called with the dxyflake locked and must return quickly;
+// the shards of a Sharded dxyflake call them concurrently.
+type Metrics interface {
+	// Issued is called for every ID issued, with its sequence number.
+	Issued(sequence uint16)
+	// Waited is called after every wait of the dxyflake, for the next
+	// time unit or for a clock that moved back, with its duration.
+	Waited(d time.Duration)
+}
+
+// waited records a wait that began at start.
+func (df *dxyflake) waited(start time.Time) {
+	d := df.clock.Now().Sub(start)
+	df.waitTime += d
+	if df.metrics != nil {
+		df.metrics.Waited(d)
+	}
+}
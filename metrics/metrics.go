@@ -0,0 +1,83 @@
+// Package metrics exposes the statistics of a dxyflake generator to
+// monitoring systems: in the Prometheus text exposition format, without
+// depending on the Prometheus client, and as an expvar variable.
+//
+// Both read the generator's Stats when scraped. For collectors updated as
+// IDs are issued, implement dxyflake.Metrics and set Settings.Metrics.
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Source is a generator reporting its statistics, such as a dxyflake or a
+// dxyflake.Sharded.
+type Source interface {
+	Stats() dxyflake.Stats
+}
+
+// metric is one metric of the exposition.
+type metric struct {
+	name, typ, help string
+	value           func(dxyflake.Stats) float64
+}
+
+var exposition = []metric{
+	{"dxyflake_ids_issued_total", "counter", "IDs issued by the generator.",
+		func(st dxyflake.Stats) float64 { return float64(st.Issued) }},
+	{"dxyflake_sequence_waits_total", "counter", "Waits for the next time unit after using up the sequence numbers of one.",
+		func(st dxyflake.Stats) float64 { return float64(st.Waits) }},
+	{"dxyflake_sequence_wait_seconds_total", "counter", "Time spent waiting for the next time unit.",
+		func(st dxyflake.Stats) float64 { return st.WaitTime.Seconds() }},
+	{"dxyflake_sequence_pressure", "gauge", "Recent share of the sequence numbers used per time unit.",
+		func(st dxyflake.Stats) float64 { return st.Pressure }},
+	{"dxyflake_sequence_high_water", "gauge", "Highest sequence number issued in a time unit.",
+		func(st dxyflake.Stats) float64 { return float64(st.HighWater) }},
+	{"dxyflake_time_remaining_seconds", "gauge", "Time left until the time part of the IDs overflows.",
+		func(st dxyflake.Stats) float64 { return st.Remaining.Seconds() }},
+	{"dxyflake_rate_limited_total", "counter", "Calls delayed or refused by the rate limit of the generator.",
+		func(st dxyflake.Stats) float64 { return float64(st.RateLimited) }},
+}
+
+// Write writes the statistics of src to w in the Prometheus text
+// exposition format.
+func Write(w io.Writer, src Source) error {
+	st := src.Stats()
+	for _, m := range exposition {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.typ, m.name, m.value(st)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns a handler serving the statistics of src to Prometheus.
+func Handler(src Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w, src)
+	})
+}
+
+// Publish publishes the statistics of src as the expvar variable name,
+// shown by the /debug/vars handler of package expvar. Like expvar.Publish,
+// it panics if name is already in use.
+func Publish(name string, src Source) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		st := src.Stats()
+		return map[string]interface{}{
+			"issued":            st.Issued,
+			"waits":             st.Waits,
+			"wait-seconds":      st.WaitTime.Seconds(),
+			"pressure":          st.Pressure,
+			"high-water":        st.HighWater,
+			"remaining-seconds": st.Remaining.Seconds(),
+			"rate-limited":      st.RateLimited,
+		}
+	}))
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+type testSource dxyflake.Stats
+
+func (s testSource) Stats() dxyflake.Stats { return dxyflake.Stats(s) }
+
+var stats = testSource{Issued: 8193, Waits: 2, WaitTime: 15 * time.Millisecond, Pressure: 0.5, HighWater: 4095, Remaining: time.Hour, RateLimited: 3}
+
+func TestHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler(stats).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE dxyflake_ids_issued_total counter\ndxyflake_ids_issued_total 8193\n",
+		"dxyflake_sequence_waits_total 2\n",
+		"dxyflake_sequence_wait_seconds_total 0.015\n",
+		"dxyflake_sequence_pressure 0.5\n",
+		"# TYPE dxyflake_sequence_high_water gauge\ndxyflake_sequence_high_water 4095\n",
+		"dxyflake_time_remaining_seconds 3600\n",
+		"dxyflake_rate_limited_total 3\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("%q missing from\n%s", line, body)
+		}
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	Publish("dxyflake-test", stats)
+	var v struct {
+		Issued    uint64 `json:"issued"`
+		HighWater uint16 `json:"high-water"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("dxyflake-test").String()), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Issued != 8193 || v.HighWater != 4095 {
+		t.Errorf("unexpected variable %+v", v)
+	}
+}
@@ -0,0 +1,49 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+type testMetrics struct {
+	issued    int
+	highWater uint16
+	waited    time.Duration
+}
+
+func (m *testMetrics) Issued(sequence uint16) {
+	m.issued++
+	if sequence > m.highWater {
+		m.highWater = sequence
+	}
+}
+
+func (m *testMetrics) Waited(d time.Duration) { m.waited += d }
+
+func TestMetrics(t *testing.T) {
+	m := new(testMetrics)
+	var st Settings
+	st.Init(1, 2)
+	c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 3e6, time.UTC)}
+	st.Clock = c
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+	st.Metrics = m
+	gen := NewDxyflake(st)
+
+	for i := 0; i < 1<<BitLenSequence+1; i++ {
+		if _, err := gen.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.issued != 1<<BitLenSequence+1 || m.highWater != 1<<BitLenSequence-1 || m.waited != 7*time.Millisecond {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+	stats := gen.Stats()
+	if stats.Issued != uint64(m.issued) || stats.HighWater != m.highWater || stats.WaitTime != m.waited || stats.Waits != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	limit := DefaultLayout.TickTime(1 << BitLenTime)
+	if want := limit.Sub(c.now); stats.Remaining != want {
+		t.Errorf("Remaining = %v, want %v", stats.Remaining, want)
+	}
+}
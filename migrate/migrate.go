@@ -0,0 +1,183 @@
+// Package migrate assigns dxyflake IDs to rows that are keyed by legacy
+// auto-increment IDs.
+//
+// A Migrator reads legacy IDs from a Source in ascending order, hands them to
+// a pool of workers in batches, and records every legacy <-> dxyflake pair in
+// a Store. The Store also keeps a checkpoint, so an interrupted migration
+// resumes after the last batch window that was fully stored.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These are the defaults used when Migrator fields are left zero.
+const (
+	DefaultBatchSize = 1000
+	DefaultWorkers   = 4
+)
+
+// ErrConflict is returned by a Store when a legacy ID or dxyflake ID is
+// already mapped to a different counterpart.
+var ErrConflict = errors.New("conflicting mapping")
+
+// ErrNoGenerator is returned by Run when Migrator.Generator is nil.
+var ErrNoGenerator = errors.New("no generator")
+
+// ErrNoStore is returned by Run when Migrator.Store is nil.
+var ErrNoStore = errors.New("no store")
+
+// ErrNoSource is returned by Run when Migrator.Source is nil.
+var ErrNoSource = errors.New("no source")
+
+// Source returns up to limit legacy IDs strictly greater than after, in
+// ascending order. An empty result means there is nothing left to migrate.
+type Source func(ctx context.Context, after int64, limit int) ([]int64, error)
+
+// Stats reports the work done by a single Run.
+type Stats struct {
+	Batches  int   // number of batches stored
+	Assigned int   // number of newly assigned IDs
+	Skipped  int   // number of legacy IDs that were already mapped
+	Resumed  int64 // checkpoint the run started from
+	Last     int64 // checkpoint the run finished at
+}
+
+// Migrator assigns dxyflake IDs to legacy IDs.
+//
+// BatchSize is the number of legacy IDs handed to a worker at once.
+// If BatchSize is 0, DefaultBatchSize is used.
+//
+// Workers is the number of batches processed concurrently.
+// If Workers is 0, DefaultWorkers is used.
+type Migrator struct {
+	Generator dxyflake.Generator
+	Store     Store
+	Source    Source
+	BatchSize int
+	Workers   int
+}
+
+// Run migrates every legacy ID returned by Source, starting after the Store's
+// checkpoint. The checkpoint is advanced only after a whole window of
+// batches has been stored, so Run can be called again after any failure.
+func (m *Migrator) Run(ctx context.Context) (Stats, error) {
+	var stats Stats
+
+	switch {
+	case m.Generator == nil:
+		return stats, ErrNoGenerator
+	case m.Store == nil:
+		return stats, ErrNoStore
+	case m.Source == nil:
+		return stats, ErrNoSource
+	}
+
+	batchSize := m.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	workers := m.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	after, err := m.Store.Checkpoint()
+	if err != nil {
+		return stats, err
+	}
+	stats.Resumed = after
+	stats.Last = after
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		legacy, err := m.Source(ctx, after, batchSize*workers)
+		if err != nil {
+			return stats, err
+		}
+		if len(legacy) == 0 {
+			return stats, nil
+		}
+
+		results := make([]batchResult, 0, workers)
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		for start := 0; start < len(legacy); start += batchSize {
+			end := start + batchSize
+			if end > len(legacy) {
+				end = len(legacy)
+			}
+			wg.Add(1)
+			go func(batch []int64) {
+				defer wg.Done()
+				r := m.migrateBatch(batch)
+				mutex.Lock()
+				results = append(results, r)
+				mutex.Unlock()
+			}(legacy[start:end])
+		}
+		wg.Wait()
+
+		for _, r := range results {
+			if r.err != nil {
+				return stats, r.err
+			}
+		}
+		for _, r := range results {
+			stats.Batches++
+			stats.Assigned += r.assigned
+			stats.Skipped += r.skipped
+		}
+
+		after = legacy[len(legacy)-1]
+		if err := m.Store.SetCheckpoint(after); err != nil {
+			return stats, err
+		}
+		stats.Last = after
+	}
+}
+
+type batchResult struct {
+	assigned int
+	skipped  int
+	err      error
+}
+
+func (m *Migrator) migrateBatch(batch []int64) batchResult {
+	var r batchResult
+
+	mappings := make([]Mapping, 0, len(batch))
+	for _, legacy := range batch {
+		_, ok, err := m.Store.Lookup(legacy)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		if ok {
+			r.skipped++
+			continue
+		}
+		id, err := m.Generator.NextID()
+		if err != nil {
+			r.err = err
+			return r
+		}
+		mappings = append(mappings, Mapping{Legacy: legacy, ID: id})
+	}
+
+	if len(mappings) > 0 {
+		if err := m.Store.Put(mappings); err != nil {
+			r.err = err
+			return r
+		}
+	}
+	r.assigned = len(mappings)
+	return r
+}
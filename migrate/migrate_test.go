@@ -0,0 +1,142 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func newGenerator(t *testing.T, serviceID uint16) dxyflake.Generator {
+	var st dxyflake.Settings
+	st.Init(1, serviceID)
+	df := dxyflake.NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	return df
+}
+
+func sliceSource(legacy []int64) Source {
+	return func(ctx context.Context, after int64, limit int) ([]int64, error) {
+		i := sort.Search(len(legacy), func(i int) bool { return legacy[i] > after })
+		end := i + limit
+		if end > len(legacy) {
+			end = len(legacy)
+		}
+		return legacy[i:end], nil
+	}
+}
+
+func TestMigratorRun(t *testing.T) {
+	legacy := make([]int64, 0, 2500)
+	for i := int64(1); i <= 5000; i += 2 {
+		legacy = append(legacy, i)
+	}
+
+	store := NewMemoryStore()
+	m := Migrator{
+		Generator: newGenerator(t, 1),
+		Store:     store,
+		Source:    sliceSource(legacy),
+		BatchSize: 100,
+		Workers:   3,
+	}
+	stats, err := m.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Assigned != len(legacy) {
+		t.Errorf("unexpected assigned: %d", stats.Assigned)
+	}
+	if stats.Last != legacy[len(legacy)-1] {
+		t.Errorf("unexpected checkpoint: %d", stats.Last)
+	}
+	if store.Len() != len(legacy) {
+		t.Errorf("unexpected store size: %d", store.Len())
+	}
+
+	for _, l := range legacy {
+		id, ok, _ := store.Lookup(l)
+		if !ok {
+			t.Fatalf("legacy id %d not mapped", l)
+		}
+		back, ok, _ := store.Reverse(id)
+		if !ok || back != l {
+			t.Fatalf("reverse of %d is %d, want %d", id, back, l)
+		}
+	}
+}
+
+type failingGenerator struct {
+	mutex sync.Mutex
+	gen   dxyflake.Generator
+	limit int
+	n     int
+}
+
+func (f *failingGenerator) NextID() (dxyflake.ID, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.n++
+	if f.n > f.limit {
+		return 0, errors.New("generator failure")
+	}
+	return f.gen.NextID()
+}
+
+func TestMigratorResume(t *testing.T) {
+	legacy := make([]int64, 1000)
+	for i := range legacy {
+		legacy[i] = int64(i + 1)
+	}
+
+	store := NewMemoryStore()
+	m := Migrator{
+		Generator: &failingGenerator{gen: newGenerator(t, 2), limit: 450},
+		Store:     store,
+		Source:    sliceSource(legacy),
+		BatchSize: 100,
+		Workers:   2,
+	}
+	if _, err := m.Run(context.Background()); err == nil {
+		t.Fatal("expected generator failure")
+	}
+	checkpoint, _ := store.Checkpoint()
+	if checkpoint != 400 {
+		t.Errorf("unexpected checkpoint: %d", checkpoint)
+	}
+
+	// a second generator in the same process must not share the service ID
+	m.Generator = newGenerator(t, 3)
+	stats, err := m.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Resumed != 400 {
+		t.Errorf("unexpected resume point: %d", stats.Resumed)
+	}
+	if store.Len() != len(legacy) {
+		t.Errorf("unexpected store size: %d", store.Len())
+	}
+}
+
+func TestMemoryStoreConflict(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Put([]Mapping{{Legacy: 1, ID: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put([]Mapping{{Legacy: 1, ID: 100}}); err != nil {
+		t.Errorf("idempotent put failed: %s", err)
+	}
+	if err := store.Put([]Mapping{{Legacy: 1, ID: 101}}); err != ErrConflict {
+		t.Errorf("expected conflict, got %v", err)
+	}
+	if err := store.Put([]Mapping{{Legacy: 2, ID: 100}}); err != ErrConflict {
+		t.Errorf("expected conflict, got %v", err)
+	}
+}
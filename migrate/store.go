@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Mapping pairs a legacy auto-increment ID with its dxyflake ID.
+type Mapping struct {
+	Legacy int64
+	ID     dxyflake.ID
+}
+
+// Store persists the bidirectional legacy <-> dxyflake mapping together with
+// the migration checkpoint.
+//
+// Put must be idempotent for pairs that are already stored, and must reject
+// (with ErrConflict) a legacy ID that is already mapped to a different ID.
+type Store interface {
+	// Lookup returns the dxyflake ID assigned to a legacy ID.
+	Lookup(legacy int64) (dxyflake.ID, bool, error)
+	// Reverse returns the legacy ID a dxyflake ID was assigned to.
+	Reverse(id dxyflake.ID) (int64, bool, error)
+	// Put stores a batch of mappings.
+	Put(pairs []Mapping) error
+	// Checkpoint returns the highest legacy ID whose batch is fully stored.
+	Checkpoint() (int64, error)
+	// SetCheckpoint records the highest legacy ID whose batch is fully stored.
+	SetCheckpoint(legacy int64) error
+}
+
+// MemoryStore is an in-memory Store, useful for tests and dry runs.
+type MemoryStore struct {
+	mutex      sync.RWMutex
+	forward    map[int64]dxyflake.ID
+	reverse    map[dxyflake.ID]int64
+	checkpoint int64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		forward: make(map[int64]dxyflake.ID),
+		reverse: make(map[dxyflake.ID]int64),
+	}
+}
+
+// Lookup returns the dxyflake ID assigned to a legacy ID.
+func (s *MemoryStore) Lookup(legacy int64) (dxyflake.ID, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	id, ok := s.forward[legacy]
+	return id, ok, nil
+}
+
+// Reverse returns the legacy ID a dxyflake ID was assigned to.
+func (s *MemoryStore) Reverse(id dxyflake.ID) (int64, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	legacy, ok := s.reverse[id]
+	return legacy, ok, nil
+}
+
+// Put stores a batch of mappings.
+func (s *MemoryStore) Put(pairs []Mapping) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, p := range pairs {
+		if id, ok := s.forward[p.Legacy]; ok && id != p.ID {
+			return ErrConflict
+		}
+		if legacy, ok := s.reverse[p.ID]; ok && legacy != p.Legacy {
+			return ErrConflict
+		}
+	}
+	for _, p := range pairs {
+		s.forward[p.Legacy] = p.ID
+		s.reverse[p.ID] = p.Legacy
+	}
+	return nil
+}
+
+// Checkpoint returns the highest legacy ID whose batch is fully stored.
+func (s *MemoryStore) Checkpoint() (int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.checkpoint, nil
+}
+
+// SetCheckpoint records the highest legacy ID whose batch is fully stored.
+func (s *MemoryStore) SetCheckpoint(legacy int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.checkpoint = legacy
+	return nil
+}
+
+// Len returns the number of stored mappings.
+func (s *MemoryStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.forward)
+}
@@ -0,0 +1,53 @@
+package dxyflake
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceNames maps service IDs to human-readable names, such as the
+// tenants of config.Policy, for output read by people rather than code.
+type ServiceNames map[uint16]string
+
+// Name returns the name of the service ID, or "" if it has none.
+func (n ServiceNames) Name(id uint16) string {
+	return n[id]
+}
+
+// DebugString returns a one-line description of id for logs and support
+// tooling, with the name of its service ID if names has one:
+//
+//	id=484514077880320 time=2021-10-14T08:52:51.58Z machine-id=0 service-id=3 namespace=orders sequence=0
+func (l Layout) DebugString(id ID, names ServiceNames) string {
+	parts := l.Decompose(id)
+	var b strings.Builder
+	b.WriteString("id=")
+	b.WriteString(id.String())
+	b.WriteString(" time=")
+	b.WriteString(l.TimeOf(id).Format(time.RFC3339Nano))
+	b.WriteString(" machine-id=")
+	b.WriteString(strconv.FormatInt(parts["machine-id"], 10))
+	b.WriteString(" service-id=")
+	b.WriteString(strconv.FormatInt(parts["service-id"], 10))
+	if name := names.Name(uint16(parts["service-id"])); name != "" {
+		b.WriteString(" namespace=")
+		b.WriteString(name)
+	}
+	b.WriteString(" sequence=")
+	b.WriteString(strconv.FormatInt(parts["sequence"], 10))
+	return b.String()
+}
+
+// Namespace returns the human-readable name of the dxyflake's service, set
+// by Settings.Namespace or resolved through Settings.ServiceNames, or "" if
+// it has none. It is meant for metrics labels and logs.
+func (df *dxyflake) Namespace() string {
+	return df.names.Name(df.serviceID)
+}
+
+// DebugString is like Layout.DebugString, with the service names of the
+// dxyflake.
+func (df *dxyflake) DebugString(id ID) string {
+	return df.layout.DebugString(id, df.names)
+}
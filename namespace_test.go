@@ -0,0 +1,47 @@
+package dxyflake
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugString(t *testing.T) {
+	at := time.Date(2021, 10, 14, 8, 52, 51, 580000000, time.UTC)
+	id, err := DefaultLayout.Compose(at, 0, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id=484514077880320 time=2021-10-14T08:52:51.58Z machine-id=0 service-id=3 namespace=orders sequence=0"
+	if got := DefaultLayout.DebugString(id, ServiceNames{3: "orders"}); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := DefaultLayout.DebugString(id, nil); strings.Contains(got, "namespace") {
+		t.Errorf("unnamed service has a namespace: %q", got)
+	}
+}
+
+func TestDxyflakeNamespace(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	st.ServiceNames = ServiceNames{2: "billing", 4: "orders"}
+	if ns := NewDxyflake(st).Namespace(); ns != "billing" {
+		t.Errorf("got namespace %q, want billing", ns)
+	}
+
+	st.Namespace = "payments"
+	df := NewDxyflake(st)
+	if ns := df.Namespace(); ns != "payments" {
+		t.Errorf("got namespace %q, want payments", ns)
+	}
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := df.DebugString(id); !strings.Contains(s, " service-id=2 namespace=payments ") {
+		t.Errorf("unexpected debug string %q", s)
+	}
+	if st.ServiceNames[2] != "billing" {
+		t.Error("Settings.ServiceNames modified")
+	}
+}
@@ -0,0 +1,147 @@
+// Package natsid carries dxyflake machine ID claims and ID requests over
+// NATS, for deployments whose infrastructure is NATS first.
+//
+// KVBackend is a lease.Backend on a JetStream key-value bucket, so a
+// coordinator.Coordinator can claim machine IDs from it. Serve answers ID
+// requests on a subject and Client sends them, in place of the HTTP server
+// and client.
+//
+// The package does not depend on a NATS client library: KeyValue and Conn
+// are the few operations it needs, for a thin adapter over the nats.go
+// types. Create the bucket with a TTL of at least the lease TTL, so that
+// the claims of crashed holders are removed; whether a claim is held is
+// decided by the expiry stored in it.
+package natsid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+// These errors are returned by KeyValue implementations.
+var (
+	ErrKeyNotFound   = errors.New("key not found")
+	ErrWrongRevision = errors.New("wrong last revision")
+)
+
+// KeyValue is a JetStream key-value bucket. Adapters map nats.ErrKeyNotFound
+// to ErrKeyNotFound, and the wrong last sequence and key exists errors of
+// Create and Update to ErrWrongRevision.
+type KeyValue interface {
+	// Get returns the value of key and its revision.
+	Get(ctx context.Context, key string) (value []byte, revision uint64, err error)
+	// Create sets key if it has no value.
+	Create(ctx context.Context, key string, value []byte) (revision uint64, err error)
+	// Update sets key if its revision is still last.
+	Update(ctx context.Context, key string, value []byte, last uint64) (revision uint64, err error)
+}
+
+// claim is the value of a leased key. An empty holder marks a released
+// lease.
+type claim struct {
+	Holder  string `json:"holder"`
+	Expires int64  `json:"expires"` // Unix nanoseconds
+}
+
+// KVBackend is a lease.Backend on a KeyValue. Every change is a
+// compare-and-set on the revision of the key, so of two holders racing for
+// a key only one wins.
+type KVBackend struct {
+	kv KeyValue
+}
+
+// NewKVBackend returns a KVBackend on kv.
+func NewKVBackend(kv KeyValue) *KVBackend {
+	return &KVBackend{kv: kv}
+}
+
+// get returns the claim on key and its revision. ok is false if key has no
+// value.
+func (b *KVBackend) get(ctx context.Context, key string) (c claim, revision uint64, ok bool, err error) {
+	value, revision, err := b.kv.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return c, 0, false, nil
+	}
+	if err != nil {
+		return c, 0, false, err
+	}
+	if err := json.Unmarshal(value, &c); err != nil {
+		return c, 0, false, err
+	}
+	return c, revision, true, nil
+}
+
+// set writes c to key if its revision is still revision, or creates key if
+// ok is false.
+func (b *KVBackend) set(ctx context.Context, key string, c claim, revision uint64, ok bool) error {
+	value, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		_, err = b.kv.Create(ctx, key, value)
+	} else {
+		_, err = b.kv.Update(ctx, key, value, revision)
+	}
+	return err
+}
+
+// Acquire implements lease.Backend.
+func (b *KVBackend) Acquire(ctx context.Context, key, holder string, ttl time.Duration) error {
+	now := time.Now()
+	c, revision, ok, err := b.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if ok && c.Holder != "" && c.Holder != holder && now.UnixNano() < c.Expires {
+		return lease.ErrHeld
+	}
+	err = b.set(ctx, key, claim{holder, now.Add(ttl).UnixNano()}, revision, ok)
+	if errors.Is(err, ErrWrongRevision) {
+		return lease.ErrHeld
+	}
+	return err
+}
+
+// Renew implements lease.Backend.
+func (b *KVBackend) Renew(ctx context.Context, key, holder string, ttl time.Duration) error {
+	now := time.Now()
+	c, revision, ok, err := b.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !ok || c.Holder != holder || now.UnixNano() >= c.Expires {
+		return lease.ErrNotHeld
+	}
+	err = b.set(ctx, key, claim{holder, now.Add(ttl).UnixNano()}, revision, true)
+	if errors.Is(err, ErrWrongRevision) {
+		return lease.ErrNotHeld
+	}
+	return err
+}
+
+// Holder implements lease.Inspector.
+func (b *KVBackend) Holder(ctx context.Context, key string) (string, bool, error) {
+	c, _, ok, err := b.get(ctx, key)
+	if err != nil || !ok || c.Holder == "" || time.Now().UnixNano() >= c.Expires {
+		return "", false, err
+	}
+	return c.Holder, true, nil
+}
+
+// Release implements lease.Backend.
+func (b *KVBackend) Release(ctx context.Context, key, holder string) error {
+	c, revision, ok, err := b.get(ctx, key)
+	if err != nil || !ok || c.Holder != holder {
+		return err
+	}
+	err = b.set(ctx, key, claim{}, revision, true)
+	if errors.Is(err, ErrWrongRevision) {
+		return nil
+	}
+	return err
+}
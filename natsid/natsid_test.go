@@ -0,0 +1,205 @@
+package natsid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/coordinator"
+	"github.com/GiterLab/dxyflake/lease"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// testKV is a KeyValue in memory.
+type testKV struct {
+	mutex    sync.Mutex
+	values   map[string][]byte
+	revs     map[string]uint64
+	revision uint64
+}
+
+func newTestKV() *testKV {
+	return &testKV{values: make(map[string][]byte), revs: make(map[string]uint64)}
+}
+
+func (kv *testKV) Get(ctx context.Context, key string) ([]byte, uint64, error) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	value, ok := kv.values[key]
+	if !ok {
+		return nil, 0, ErrKeyNotFound
+	}
+	return value, kv.revs[key], nil
+}
+
+func (kv *testKV) Create(ctx context.Context, key string, value []byte) (uint64, error) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	if _, ok := kv.values[key]; ok {
+		return 0, ErrWrongRevision
+	}
+	return kv.put(key, value), nil
+}
+
+func (kv *testKV) Update(ctx context.Context, key string, value []byte, last uint64) (uint64, error) {
+	kv.mutex.Lock()
+	defer kv.mutex.Unlock()
+
+	if kv.revs[key] != last {
+		return 0, ErrWrongRevision
+	}
+	return kv.put(key, value), nil
+}
+
+func (kv *testKV) put(key string, value []byte) uint64 {
+	kv.revision++
+	kv.values[key] = value
+	kv.revs[key] = kv.revision
+	return kv.revision
+}
+
+func TestKVBackend(t *testing.T) {
+	b := NewKVBackend(newTestKV())
+	ctx := context.Background()
+
+	if err := b.Acquire(ctx, "k", "a", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(ctx, "k", "b", time.Minute); err != lease.ErrHeld {
+		t.Errorf("Acquire() error = %v, want %v", err, lease.ErrHeld)
+	}
+	if err := b.Renew(ctx, "k", "b", time.Minute); err != lease.ErrNotHeld {
+		t.Errorf("Renew() error = %v, want %v", err, lease.ErrNotHeld)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Renew(ctx, "k", "a", time.Minute); err != lease.ErrNotHeld {
+		t.Errorf("Renew() of expired lease error = %v, want %v", err, lease.ErrNotHeld)
+	}
+	if err := b.Acquire(ctx, "k", "b", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if holder, ok, err := b.Holder(ctx, "k"); err != nil || !ok || holder != "b" {
+		t.Errorf("Holder() = %q, %v, %v", holder, ok, err)
+	}
+	if err := b.Release(ctx, "k", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(ctx, "k", "a", time.Minute); err != lease.ErrHeld {
+		t.Errorf("released by another holder: %v", err)
+	}
+	if err := b.Release(ctx, "k", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Holder(ctx, "k"); ok {
+		t.Error("released lease still held")
+	}
+	if err := b.Acquire(ctx, "k", "a", time.Minute); err != nil {
+		t.Errorf("Acquire() after release error = %v", err)
+	}
+}
+
+func TestKVBackendRace(t *testing.T) {
+	b := NewKVBackend(newTestKV())
+
+	var wg sync.WaitGroup
+	won := make(chan uint16, 8)
+	for i := 0; i < cap(won); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			claim, err := coordinator.New(b, coordinator.Options{
+				Holder:     fmt.Sprint("holder-", i),
+				MachineIDs: []uint16{1},
+			}).Claim(context.Background())
+			if err == nil {
+				won <- claim.MachineID()
+			} else if err != coordinator.ErrNoFreeMachineID {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(won)
+	if n := len(won); n != 1 {
+		t.Errorf("%d holders claimed machine id 1", n)
+	}
+}
+
+// testConn is a Conn delivering requests in memory.
+type testConn struct {
+	mutex    sync.Mutex
+	handlers map[string]func([]byte) []byte
+}
+
+func (c *testConn) Request(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	c.mutex.Lock()
+	handle, ok := c.handlers[subject]
+	c.mutex.Unlock()
+	if !ok {
+		return nil, errors.New("no responders")
+	}
+	return handle(data), nil
+}
+
+func (c *testConn) QueueSubscribe(subject, queue string, handle func([]byte) []byte) (func() error, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.handlers[subject] = handle
+	return func() error {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		delete(c.handlers, subject)
+		return nil
+	}, nil
+}
+
+func TestTransport(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(2, 3)
+	conn := &testConn{handlers: make(map[string]func([]byte) []byte)}
+	unsubscribe, err := Serve(conn, "ids.orders", dxyflake.NewDxyflake(st), Options{MaxBatch: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(conn, "ids.orders", Options{})
+
+	id, err := c.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts := dxyflake.Decompose(id); parts["machine-id"] != 2 || parts["service-id"] != 3 {
+		t.Errorf("unexpected parts: %v", parts)
+	}
+	ids, err := c.NextIDs(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range ids {
+		if ids[i] <= id {
+			t.Fatalf("IDs not increasing: %v", ids)
+		}
+		id = ids[i]
+	}
+
+	var e *server.Error
+	if _, err := c.NextIDs(context.Background(), 11); !errors.As(err, &e) || e.Code != server.CodeBatchTooLarge {
+		t.Errorf("NextIDs() error = %v, want %s", err, server.CodeBatchTooLarge)
+	}
+	if reply := conn.handlers["ids.orders"](nil); len(reply) == 0 {
+		t.Error("empty request not answered")
+	}
+
+	if err := unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NextID(); err == nil {
+		t.Error("request answered after unsubscribe")
+	}
+}
@@ -0,0 +1,168 @@
+package natsid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/client"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+// DefaultMaxBatch is that of the HTTP server, so that a client may switch
+// transports without splitting its requests.
+const (
+	DefaultQueue    = "dxyflake"
+	DefaultMaxBatch = server.DefaultMaxBatch
+	DefaultTimeout  = 2 * time.Second
+)
+
+// Conn is a NATS connection.
+type Conn interface {
+	// Request sends data to subject and returns the reply.
+	Request(ctx context.Context, subject string, data []byte) ([]byte, error)
+	// QueueSubscribe calls handle for the requests on subject, once per
+	// queue group, and replies with its result.
+	QueueSubscribe(subject, queue string, handle func(data []byte) []byte) (unsubscribe func() error, err error)
+}
+
+// Request is the body of an ID request. An empty body asks for one ID.
+// The reply is a server.IDsResponse with the IDs in decimal, or a
+// server.Error.
+type Request struct {
+	Count int `json:"count"`
+}
+
+// Options configures Serve and Client:
+//
+// Queue is the queue group of the servers of a subject, which share its
+// requests. If Queue is empty, DefaultQueue is used.
+//
+// MaxBatch is the largest number of IDs a request may ask for.
+// If MaxBatch is 0, DefaultMaxBatch is used.
+//
+// Timeout bounds the requests of Client.NextID.
+// If Timeout is 0, DefaultTimeout is used.
+type Options struct {
+	Queue    string
+	MaxBatch int
+	Timeout  time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Queue == "" {
+		o.Queue = DefaultQueue
+	}
+	if o.MaxBatch <= 0 {
+		o.MaxBatch = DefaultMaxBatch
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	return o
+}
+
+// Serve answers ID requests on subject with IDs of gen until unsubscribe
+// is called.
+func Serve(conn Conn, subject string, gen dxyflake.Generator, opts Options) (unsubscribe func() error, err error) {
+	opts = opts.withDefaults()
+	return conn.QueueSubscribe(subject, opts.Queue, func(data []byte) []byte {
+		reply, e := handle(data, gen, opts)
+		if e != nil {
+			reply, _ = json.Marshal(e)
+		}
+		return reply
+	})
+}
+
+func handle(data []byte, gen dxyflake.Generator, opts Options) ([]byte, *server.Error) {
+	req := Request{Count: 1}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &req); err != nil {
+			return nil, server.NewError(server.CodeInvalidRequest, err.Error())
+		}
+	}
+	if req.Count <= 0 {
+		return nil, server.NewError(server.CodeInvalidRequest, "count must be a positive integer")
+	}
+	if req.Count > opts.MaxBatch {
+		return nil, server.NewError(server.CodeBatchTooLarge,
+			fmt.Sprintf("%d IDs exceed the batch limit of %d", req.Count, opts.MaxBatch))
+	}
+
+	resp := server.IDsResponse{IDs: make([]string, req.Count)}
+	for i := range resp.IDs {
+		id, err := gen.NextID()
+		if err != nil {
+			return nil, server.Classify(err)
+		}
+		resp.IDs[i] = id.String()
+	}
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		return nil, server.Classify(err)
+	}
+	return reply, nil
+}
+
+// Client is a dxyflake.Generator requesting IDs on a subject.
+type Client struct {
+	conn    Conn
+	subject string
+	opts    Options
+}
+
+// NewClient returns a Client requesting IDs on subject.
+func NewClient(conn Conn, subject string, opts Options) *Client {
+	return &Client{conn: conn, subject: subject, opts: opts.withDefaults()}
+}
+
+// NextID requests one ID.
+func (c *Client) NextID() (dxyflake.ID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+	defer cancel()
+
+	ids, err := c.NextIDs(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// NextIDs requests n IDs with a single request. Error replies are returned
+// as *server.Error, as by the HTTP client.
+func (c *Client) NextIDs(ctx context.Context, n int) ([]dxyflake.ID, error) {
+	data, err := json.Marshal(Request{Count: n})
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.conn.Request(ctx, c.subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	e := new(server.Error)
+	if err := json.Unmarshal(reply, e); err != nil {
+		return nil, err
+	}
+	if e.Code != "" {
+		return nil, e
+	}
+	var resp server.IDsResponse
+	if err := json.Unmarshal(reply, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.IDs) < n {
+		return nil, client.ErrShortBatch
+	}
+
+	ids := make([]dxyflake.ID, n)
+	for i := range ids {
+		if ids[i], err = dxyflake.ParseString(resp.IDs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
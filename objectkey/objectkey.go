@@ -0,0 +1,174 @@
+// Package objectkey names objects, such as S3 objects, after dxyflake IDs.
+//
+// A key is made of a prefix, the date of the ID as path segments, a shard
+// and the encoded ID:
+//
+//	invoices/2024/06/03/9c1e-ybndrfg8ejkm.pdf
+//
+// The date segments let lifecycle rules and listings work by day; the
+// shard, derived from the ID, spreads consecutive IDs over the key space,
+// so that stores partitioning by key prefix do not send all writes of a
+// moment to one partition. Parse reads the ID back and checks the key
+// against it.
+package objectkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/analyze"
+)
+
+// ErrInvalidKey is returned by Parse for keys not built by Build with the
+// same options.
+var ErrInvalidKey = errors.New("invalid object key")
+
+// DefaultShard is the number of hex digits of the shard of a key.
+const DefaultShard = 4
+
+// Bucket is the time granularity of the date segments of a key.
+type Bucket int
+
+// These are the buckets.
+const (
+	Day Bucket = iota
+	Hour
+	Month
+	Year
+)
+
+// layouts are the time formats of the date segments by bucket.
+var layouts = map[Bucket]string{
+	Year:  "2006",
+	Month: "2006/01",
+	Day:   "2006/01/02",
+	Hour:  "2006/01/02/15",
+}
+
+// These are the encodings of key-safe ID strings.
+var (
+	Base32  = encoding("base32")
+	Base36  = encoding("base36")
+	Base62  = encoding("base62")
+	Decimal = encoding("decimal")
+)
+
+func encoding(name string) analyze.Encoding {
+	for _, e := range analyze.Encodings {
+		if e.Name == name {
+			return e
+		}
+	}
+	panic("objectkey: unknown encoding " + name)
+}
+
+type options struct {
+	layout    dxyflake.Layout
+	bucket    Bucket
+	shard     int
+	encoding  analyze.Encoding
+	extension string
+}
+
+// Option configures Build and Parse. Keys must be parsed with the options
+// they were built with.
+type Option func(*options)
+
+// WithLayout reads the time of IDs with l instead of DefaultLayout.
+func WithLayout(l dxyflake.Layout) Option {
+	return func(o *options) { o.layout = l }
+}
+
+// WithBucket sets the granularity of the date segments, Day by default.
+func WithBucket(b Bucket) Option {
+	return func(o *options) { o.bucket = b }
+}
+
+// WithShard sets the number of hex digits of the shard, at most 16. Zero
+// leaves the shard out, so that the keys of a day sort by ID if the
+// encoding is of fixed width.
+func WithShard(digits int) Option {
+	return func(o *options) { o.shard = digits }
+}
+
+// WithEncoding sets the encoding of the ID, Base32 by default.
+func WithEncoding(e analyze.Encoding) Option {
+	return func(o *options) { o.encoding = e }
+}
+
+// WithExtension appends ext, e.g. ".json", to the keys.
+func WithExtension(ext string) Option {
+	return func(o *options) { o.extension = ext }
+}
+
+func newOptions(opts []Option) options {
+	o := options{layout: dxyflake.DefaultLayout, shard: DefaultShard, encoding: Base32}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.shard > 16 {
+		o.shard = 16
+	}
+	if _, ok := layouts[o.bucket]; !ok {
+		o.bucket = Day
+	}
+	return o
+}
+
+// shard returns the first digits hex digits of a hash of id.
+func shard(id dxyflake.ID, digits int) string {
+	h := uint64(id) * 0x9E3779B97F4A7C15
+	return fmt.Sprintf("%016x", h)[:digits]
+}
+
+// name returns the last segment of the key of id.
+func (o options) name(id dxyflake.ID) string {
+	name := o.encoding.Format(id) + o.extension
+	if o.shard > 0 {
+		name = shard(id, o.shard) + "-" + name
+	}
+	return name
+}
+
+// Build returns the object key of id under prefix. The date segments are
+// in UTC. An empty prefix starts the key with the date.
+func Build(prefix string, id dxyflake.ID, opts ...Option) string {
+	o := newOptions(opts)
+	key := o.layout.TimeOf(id).UTC().Format(layouts[o.bucket]) + "/" + o.name(id)
+	if prefix != "" {
+		key = strings.TrimSuffix(prefix, "/") + "/" + key
+	}
+	return key
+}
+
+// Parse returns the ID of a key built by Build with the same options. It
+// returns ErrInvalidKey if the ID cannot be decoded or if the key does not
+// end in the date segments and shard of the ID, such as a key of another
+// ID that was renamed.
+func Parse(key string, opts ...Option) (dxyflake.ID, error) {
+	o := newOptions(opts)
+	i := strings.LastIndexByte(key, '/')
+	name := key[i+1:]
+	if !strings.HasSuffix(name, o.extension) {
+		return 0, ErrInvalidKey
+	}
+	s := strings.TrimSuffix(name, o.extension)
+	if o.shard > 0 {
+		if len(s) <= o.shard || s[o.shard] != '-' {
+			return 0, ErrInvalidKey
+		}
+		s = s[o.shard+1:]
+	}
+	id, err := o.encoding.Parse(s)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	suffix := Build("", id, opts...)
+	if !strings.HasSuffix(key, suffix) || len(key) > len(suffix) && key[len(key)-len(suffix)-1] != '/' {
+		return 0, fmt.Errorf("%w: %q does not match id %d", ErrInvalidKey, key, id)
+	}
+	return id, nil
+}
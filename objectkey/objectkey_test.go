@@ -0,0 +1,77 @@
+package objectkey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func testID(t *testing.T, at time.Time, seq uint16) dxyflake.ID {
+	id, err := dxyflake.DefaultLayout.Compose(at, 1, 2, seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestBuild(t *testing.T) {
+	id := testID(t, time.Date(2024, 6, 3, 14, 5, 0, 0, time.UTC), 7)
+	sh := shard(id, DefaultShard)
+
+	tests := []struct {
+		prefix string
+		opts   []Option
+		want   string
+	}{
+		{"invoices", nil, "invoices/2024/06/03/" + sh + "-" + id.Base32()},
+		{"invoices/", []Option{WithExtension(".pdf")}, "invoices/2024/06/03/" + sh + "-" + id.Base32() + ".pdf"},
+		{"", []Option{WithBucket(Hour), WithShard(0), WithEncoding(Decimal)}, "2024/06/03/14/" + id.String()},
+		{"a/b", []Option{WithBucket(Month), WithShard(2), WithEncoding(Base62)}, "a/b/2024/06/" + sh[:2] + "-" + id.Base62()},
+		{"logs", []Option{WithBucket(Year)}, "logs/2024/" + sh + "-" + id.Base32()},
+	}
+	for _, tt := range tests {
+		key := Build(tt.prefix, id, tt.opts...)
+		if key != tt.want {
+			t.Errorf("Build(%q) = %q, want %q", tt.prefix, key, tt.want)
+		}
+		got, err := Parse(key, tt.opts...)
+		if err != nil || got != id {
+			t.Errorf("Parse(%q) = %d, %v, want %d", key, got, err, id)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	at := time.Date(2024, 6, 3, 14, 5, 0, 0, time.UTC)
+	id := testID(t, at, 7)
+	key := Build("invoices", id, WithExtension(".pdf"))
+	other := Build("invoices", testID(t, at, 8), WithExtension(".pdf"))
+	i := strings.LastIndexByte(key, '-')
+
+	for _, k := range []string{
+		"",
+		"invoices/2024/06/03/garbage",
+		strings.TrimSuffix(key, ".pdf"),
+		strings.Replace(key, "2024/06/03", "2024/06/04", 1),
+		other[:strings.LastIndexByte(other, '-')] + key[i:],
+		strings.Replace(key, "invoices/", "invoices", 1),
+	} {
+		if _, err := Parse(k, WithExtension(".pdf")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Parse(%q) error = %v, want %v", k, err, ErrInvalidKey)
+		}
+	}
+}
+
+func TestShardSpread(t *testing.T) {
+	at := time.Date(2024, 6, 3, 14, 5, 0, 0, time.UTC)
+	shards := make(map[string]bool)
+	for seq := uint16(0); seq < 256; seq++ {
+		shards[shard(testID(t, at, seq), 2)] = true
+	}
+	if len(shards) < 128 {
+		t.Errorf("256 consecutive IDs fell into %d of 256 shards", len(shards))
+	}
+}
@@ -0,0 +1,191 @@
+package dxyflake
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Parts are the parts of an ID, as Decompose returns them but without
+// allocating a map. MSB is the unused sign bit, 0 for valid IDs. Time is
+// the time part in time units of the layout, and Timestamp the time at
+// which that time unit begins. RegionID and NodeID split MachineID in
+// layouts with region bits, see Layout.BitLenRegionID; in other layouts,
+// RegionID is 0 and NodeID is MachineID.
+//
+// Parts are written as JSON documents of PartsJSONSchema, the same fields
+// the decompose endpoint of package server answers with.
+type Parts struct {
+	ID        ID
+	MSB       int64
+	Time      int64
+	Timestamp time.Time
+	MachineID uint16
+	ServiceID uint16
+	Sequence  uint16
+	RegionID  uint16
+	NodeID    uint16
+}
+
+// PartsJSONSchema is the JSON schema of the JSON documents of Parts. The
+// ID is a decimal string, as JSON numbers lose precision in many decoders,
+// "time" is the RFC 3339 timestamp, "tick" the time part, and "msb" is
+// left out while 0. "region-id" and "node-id" are left out while the
+// region ID is 0 and the node ID is the machine ID.
+const PartsJSONSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/GiterLab/dxyflake/parts.schema.json",
+  "title": "dxyflake ID parts",
+  "type": "object",
+  "properties": {
+    "id": {"type": "string", "pattern": "^-?[0-9]+$"},
+    "time": {"type": "string", "format": "date-time"},
+    "tick": {"type": "integer", "minimum": 0},
+    "machine-id": {"type": "integer", "minimum": 0, "maximum": 65535},
+    "service-id": {"type": "integer", "minimum": 0, "maximum": 65535},
+    "sequence": {"type": "integer", "minimum": 0, "maximum": 65535},
+    "region-id": {"type": "integer", "minimum": 0, "maximum": 65535},
+    "node-id": {"type": "integer", "minimum": 0, "maximum": 65535},
+    "msb": {"type": "integer", "enum": [0, 1]}
+  },
+  "required": ["id", "time", "tick", "machine-id", "service-id", "sequence"]
+}`
+
+// partsJSON is the JSON document of Parts.
+type partsJSON struct {
+	ID        string  `json:"id"`
+	Time      string  `json:"time"`
+	Tick      int64   `json:"tick"`
+	MachineID uint16  `json:"machine-id"`
+	ServiceID uint16  `json:"service-id"`
+	Sequence  uint16  `json:"sequence"`
+	RegionID  *uint16 `json:"region-id,omitempty"`
+	NodeID    *uint16 `json:"node-id,omitempty"`
+	MSB       int64   `json:"msb,omitempty"`
+}
+
+// MarshalJSON returns the JSON document of p, see PartsJSONSchema.
+func (p Parts) MarshalJSON() ([]byte, error) {
+	v := partsJSON{
+		ID:        p.ID.String(),
+		Time:      p.Timestamp.UTC().Format(time.RFC3339Nano),
+		Tick:      p.Time,
+		MachineID: p.MachineID,
+		ServiceID: p.ServiceID,
+		Sequence:  p.Sequence,
+		MSB:       p.MSB,
+	}
+	if p.RegionID != 0 || p.NodeID != p.MachineID {
+		v.RegionID, v.NodeID = &p.RegionID, &p.NodeID
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads a JSON document of PartsJSONSchema.
+func (p *Parts) UnmarshalJSON(b []byte) error {
+	var v partsJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(v.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+	at, err := time.Parse(time.RFC3339Nano, v.Time)
+	if err != nil {
+		return err
+	}
+	*p = Parts{
+		ID:        ID(id),
+		MSB:       v.MSB,
+		Time:      v.Tick,
+		Timestamp: at,
+		MachineID: v.MachineID,
+		ServiceID: v.ServiceID,
+		Sequence:  v.Sequence,
+		NodeID:    v.MachineID,
+	}
+	if v.RegionID != nil {
+		p.RegionID = *v.RegionID
+	}
+	if v.NodeID != nil {
+		p.NodeID = *v.NodeID
+	}
+	return nil
+}
+
+// DecomposeParts returns the parts of id.
+func (l Layout) DecomposeParts(id ID) Parts {
+	n := int64(id)
+	tick := n >> l.shiftTime() & (1<<uint(l.BitLenTime) - 1)
+	return Parts{
+		ID:        id,
+		MSB:       n >> 63 & 1,
+		Time:      tick,
+		Timestamp: l.TickTime(tick),
+		MachineID: uint16(n >> l.shiftMachineID() & (1<<uint(l.BitLenMachineID) - 1)),
+		ServiceID: uint16(n >> uint(l.BitLenSequence) & (1<<uint(l.BitLenServiceID) - 1)),
+		Sequence:  uint16(n & (1<<uint(l.BitLenSequence) - 1)),
+		RegionID:  l.RegionID(id),
+		NodeID:    l.NodeID(id),
+	}
+}
+
+// DecomposeParts returns the parts of an ID of DefaultLayout.
+func DecomposeParts(id ID) Parts {
+	return DefaultLayout.DecomposeParts(id)
+}
+
+// DecomposeParts returns the parts of an ID of the dxyflake's layout.
+func (df *dxyflake) DecomposeParts(id ID) Parts {
+	return df.layout.DecomposeParts(id)
+}
+
+// DecomposeJSON returns the JSON document of the parts of id, see
+// PartsJSONSchema.
+func (l Layout) DecomposeJSON(id ID) ([]byte, error) {
+	return json.Marshal(l.DecomposeParts(id))
+}
+
+// DecomposeJSON returns the JSON document of the parts of an ID of
+// DefaultLayout, see PartsJSONSchema.
+func DecomposeJSON(id ID) ([]byte, error) {
+	return DefaultLayout.DecomposeJSON(id)
+}
+
+// DecomposeJSONFor returns the JSON document of the parts of an ID of the
+// dxyflakes created with st, whose "time" counts from st.StartTime. It
+// returns ErrInvalidLayout if the bit lengths or the time unit of st are
+// invalid.
+func DecomposeJSONFor(id ID, st Settings) ([]byte, error) {
+	l, ok := st.bits()
+	if !ok {
+		return nil, ErrInvalidLayout
+	}
+	return l.DecomposeJSON(id)
+}
+
+// DecomposeJSON returns the JSON document of the parts of an ID of the
+// dxyflake's layout, counted from its start time.
+func (df *dxyflake) DecomposeJSON(id ID) ([]byte, error) {
+	return df.layout.DecomposeJSON(id)
+}
+
+// TimeOf returns the time at which the time unit of an ID of the dxyflake
+// begins, counted from its start time.
+func (df *dxyflake) TimeOf(id ID) time.Time {
+	return df.layout.TimeOf(id)
+}
+
+// Compose returns the ID of DefaultLayout issued at t with the given parts,
+// the inverse of Decompose. See Layout.Compose for its errors.
+func Compose(t time.Time, machineID, serviceID, sequence uint16) (ID, error) {
+	return DefaultLayout.Compose(t, machineID, serviceID, sequence)
+}
+
+// Compose returns the ID of the dxyflake's layout issued at t with the
+// given parts, counted from its start time. See Layout.Compose for its
+// errors.
+func (df *dxyflake) Compose(t time.Time, machineID, serviceID, sequence uint16) (ID, error) {
+	return df.layout.Compose(t, machineID, serviceID, sequence)
+}
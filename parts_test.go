@@ -0,0 +1,139 @@
+package dxyflake
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecomposeParts(t *testing.T) {
+	id := ID(9223372036854775807)
+	want := Parts{ID: id, Time: 1<<BitLenTime - 1, Timestamp: DefaultLayout.TimeOf(id), MachineID: 31, ServiceID: 31, Sequence: 4095, NodeID: 31}
+	if p := DecomposeParts(id); p != want {
+		t.Errorf("DecomposeParts() = %+v, want %+v", p, want)
+	}
+	if p := DecomposeParts(-1); p.MSB != 1 || p.Time != 1<<BitLenTime-1 {
+		t.Errorf("DecomposeParts(-1) = %+v", p)
+	}
+
+	var st Settings
+	st.Init(200, 3)
+	st.StartTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 39, 8, 4, 12
+	df := NewDxyflake(st)
+	before := time.Now().Truncate(dxyflakeTimeUnit)
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := df.DecomposeParts(id)
+	if p.MachineID != 200 || p.ServiceID != 3 || p.Sequence != 0 {
+		t.Errorf("unexpected parts %+v", p)
+	}
+	if m := df.Decompose(id); m["time"] != p.Time {
+		t.Errorf("time %d != %d", m["time"], p.Time)
+	}
+	if at := df.TimeOf(id); at.Before(before) || at.After(time.Now()) {
+		t.Errorf("TimeOf() = %v, issued after %v", at, before)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	at := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	id, err := Compose(at, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Parts{ID: id, Time: DefaultLayout.Tick(at), MachineID: 1, ServiceID: 2, Sequence: 3, NodeID: 1}
+	if p := DecomposeParts(id); !p.Timestamp.Equal(at) {
+		t.Errorf("DecomposeParts() timestamp = %v, want %v", p.Timestamp, at)
+	} else if p.Timestamp = (time.Time{}); p != want {
+		t.Errorf("DecomposeParts() = %+v, want %+v", p, want)
+	}
+	for _, args := range [][3]uint16{{32, 0, 0}, {0, 32, 0}, {0, 0, 4096}} {
+		if _, err := Compose(at, args[0], args[1], args[2]); err != ErrOutOfRange {
+			t.Errorf("Compose(%v) error = %v", args, err)
+		}
+	}
+
+	var st Settings
+	st.StartTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 39, 8, 4, 12
+	df := NewDxyflake(st)
+	id, err = df.Compose(at, 200, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := df.DecomposeParts(id); p.MachineID != 200 || !df.TimeOf(id).Equal(at) {
+		t.Errorf("unexpected parts %+v", p)
+	}
+	if _, err := df.Compose(at, 0, 16, 0); err != ErrOutOfRange {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPartsJSON(t *testing.T) {
+	at := time.Date(2023, 5, 6, 7, 8, 9, 10e6, time.UTC)
+	id, err := Compose(at, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := DecomposeParts(id)
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"` + id.String() + `","time":"2023-05-06T07:08:09.01Z","tick":` +
+		strconv.FormatInt(p.Time, 10) + `,"machine-id":1,"service-id":2,"sequence":3}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+	var back Parts
+	if err := json.Unmarshal(b, &back); err != nil || back.ID != id || !back.Timestamp.Equal(at) || back.Sequence != 3 {
+		t.Errorf("round trip: %+v, %v", back, err)
+	}
+
+	if b, _ := json.Marshal(DecomposeParts(-1)); !strings.Contains(string(b), `"msb":1`) {
+		t.Errorf("msb left out: %s", b)
+	}
+	if b, err := DecomposeJSON(id); err != nil || string(b) != want {
+		t.Errorf("DecomposeJSON() = %s, %v", b, err)
+	}
+	var st Settings
+	st.StartTime = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.TimeUnit = time.Second
+	l := layoutAt(st.StartTime)
+	l.Unit = time.Second
+	id, _ = l.Compose(at, 1, 2, 3)
+	b, err = DecomposeJSONFor(id, st)
+	if err != nil || !strings.Contains(string(b), `"time":"2023-05-06T07:08:09Z"`) {
+		t.Errorf("DecomposeJSONFor() = %s, %v", b, err)
+	}
+	st.BitsTime = 1
+	if _, err := DecomposeJSONFor(id, st); err != ErrInvalidLayout {
+		t.Errorf("invalid settings: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(PartsJSONSchema), &schema); err != nil {
+		t.Errorf("invalid schema: %v", err)
+	}
+}
+
+func BenchmarkDecompose(b *testing.B) {
+	id := ID(29829153948057600)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Decompose(id)
+	}
+}
+
+func BenchmarkDecomposeParts(b *testing.B) {
+	id := ID(29829153948057600)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = DecomposeParts(id)
+	}
+}
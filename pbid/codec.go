@@ -0,0 +1,65 @@
+package pbid
+
+import (
+	"errors"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrNoCodec is returned by Codec for messages other than IDs when it has
+// no Next codec.
+var ErrNoCodec = errors.New("no codec for message")
+
+// BaseCodec is the codec interface of gRPC, encoding.Codec.
+type BaseCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	Name() string
+}
+
+// Codec is a gRPC codec sending dxyflake.ID and *dxyflake.ID messages as
+// DxyflakeID, e.g. for raw calls with conn.Invoke, and every other message
+// with Next, the codec it wraps. Register it in place of the proto codec:
+//
+//	encoding.RegisterCodec(pbid.Codec{Next: encoding.GetCodec(proto.Name)})
+type Codec struct {
+	Next BaseCodec
+}
+
+// Marshal encodes v.
+func (c Codec) Marshal(v interface{}) ([]byte, error) {
+	switch id := v.(type) {
+	case dxyflake.ID:
+		return Marshal(id), nil
+	case *dxyflake.ID:
+		return Marshal(*id), nil
+	}
+	if c.Next == nil {
+		return nil, ErrNoCodec
+	}
+	return c.Next.Marshal(v)
+}
+
+// Unmarshal decodes data into v, validating IDs like Unmarshal.
+func (c Codec) Unmarshal(data []byte, v interface{}) error {
+	if p, ok := v.(*dxyflake.ID); ok {
+		id, err := Unmarshal(data)
+		if err != nil {
+			return err
+		}
+		*p = id
+		return nil
+	}
+	if c.Next == nil {
+		return ErrNoCodec
+	}
+	return c.Next.Unmarshal(data, v)
+}
+
+// Name returns the name of Next, which Codec replaces, or "proto".
+func (c Codec) Name() string {
+	if c.Next == nil {
+		return "proto"
+	}
+	return c.Next.Name()
+}
@@ -0,0 +1,156 @@
+// Package pbid converts dxyflake IDs to and from DxyflakeID, the protobuf
+// message of dxyflake_id.proto, so that services passing IDs across gRPC
+// boundaries share one field convention and one validation.
+//
+// The package does not depend on the protobuf runtime. FromMessage reads
+// the Go types generated from dxyflake_id.proto by their getters, and
+// Marshal and Unmarshal encode the message by hand, e.g. for raw gRPC
+// calls with Codec, Kafka payloads or cache entries.
+package pbid
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+var (
+	// ErrInvalid is returned for messages that are not a valid DxyflakeID:
+	// malformed, negative or beyond 63 bits, or with a decimal that does
+	// not parse.
+	ErrInvalid = errors.New("invalid dxyflake id message")
+	// ErrMissing is returned for DxyflakeID messages without an ID.
+	ErrMissing = errors.New("dxyflake id not set")
+)
+
+// The field numbers and wire types of DxyflakeID.
+const (
+	fieldValue   = 1
+	fieldDecimal = 2
+
+	wireI64   = 1
+	wireBytes = 2
+)
+
+// Message is implemented by the Go types protoc-gen-go generates from
+// DxyflakeID.
+type Message interface {
+	GetValue() uint64
+	GetDecimal() string
+}
+
+// FromMessage returns the ID of m. It returns ErrMissing if m is nil or
+// carries no ID, which, as getters do not tell an unset value from 0,
+// includes the ID 0, and ErrInvalid for invalid IDs.
+func FromMessage(m Message) (dxyflake.ID, error) {
+	if m == nil {
+		return 0, ErrMissing
+	}
+	if d := m.GetDecimal(); d != "" {
+		return parseDecimal(d)
+	}
+	v := m.GetValue()
+	if v == 0 {
+		return 0, ErrMissing
+	}
+	return fromValue(v)
+}
+
+// FromMessageValidated is like FromMessage, but also checks that the ID
+// could have been issued by a dxyflake created with st, see
+// dxyflake.Validate, for IDs from untrusted clients.
+func FromMessageValidated(m Message, st dxyflake.Settings) (dxyflake.ID, error) {
+	id, err := FromMessage(m)
+	if err != nil {
+		return 0, err
+	}
+	if err := dxyflake.Validate(id, st); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Value returns id as the value field of DxyflakeID, for setting the
+// field of a generated message.
+func Value(id dxyflake.ID) uint64 {
+	return uint64(id)
+}
+
+// Marshal returns the DxyflakeID message of id, with the value field set.
+func Marshal(id dxyflake.ID) []byte {
+	return AppendMarshal(nil, id)
+}
+
+// AppendMarshal appends the DxyflakeID message of id to b.
+func AppendMarshal(b []byte, id dxyflake.ID) []byte {
+	b = append(b, fieldValue<<3|wireI64)
+	var v [8]byte
+	binary.LittleEndian.PutUint64(v[:], uint64(id))
+	return append(b, v[:]...)
+}
+
+// Unmarshal returns the ID of the DxyflakeID message b, with either field
+// set; if both are, the last one wins, as in protobuf decoders. Unknown
+// fields are skipped. It returns ErrMissing if b carries no ID and
+// ErrInvalid for malformed messages and invalid IDs.
+func Unmarshal(b []byte) (dxyflake.ID, error) {
+	var id dxyflake.ID
+	err := ErrMissing
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, ErrInvalid
+		}
+		b = b[n:]
+		field, typ := key>>3, key&7
+		switch typ {
+		case 0: // varint
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return 0, ErrInvalid
+			}
+			b = b[n:]
+		case wireI64, 5: // fixed64, fixed32
+			size := 8
+			if typ == 5 {
+				size = 4
+			}
+			if len(b) < size {
+				return 0, ErrInvalid
+			}
+			if field == fieldValue && typ == wireI64 {
+				id, err = fromValue(binary.LittleEndian.Uint64(b))
+			}
+			b = b[size:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return 0, ErrInvalid
+			}
+			if field == fieldDecimal {
+				id, err = parseDecimal(string(b[n : n+int(l)]))
+			}
+			b = b[n+int(l):]
+		default:
+			return 0, ErrInvalid
+		}
+	}
+	return id, err
+}
+
+func fromValue(v uint64) (dxyflake.ID, error) {
+	if v >= 1<<63 {
+		return 0, fmt.Errorf("%w: %d beyond 63 bits", ErrInvalid, v)
+	}
+	return dxyflake.ID(v), nil
+}
+
+func parseDecimal(s string) (dxyflake.ID, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: decimal %q", ErrInvalid, s)
+	}
+	return dxyflake.ID(n), nil
+}
@@ -0,0 +1,136 @@
+package pbid
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// message is what protoc-gen-go generates for DxyflakeID, reduced to its
+// getters.
+type message struct {
+	value   uint64
+	decimal string
+}
+
+func (m *message) GetValue() uint64 {
+	if m == nil {
+		return 0
+	}
+	return m.value
+}
+
+func (m *message) GetDecimal() string {
+	if m == nil {
+		return ""
+	}
+	return m.decimal
+}
+
+func TestMessage(t *testing.T) {
+	const id = dxyflake.ID(29829153948057600)
+	for _, c := range []struct {
+		m    Message
+		want dxyflake.ID
+		err  error
+	}{
+		{&message{value: Value(id)}, id, nil},
+		{&message{decimal: id.String()}, id, nil},
+		{&message{value: 1 << 63}, 0, ErrInvalid},
+		{&message{decimal: "-5"}, 0, ErrInvalid},
+		{&message{decimal: "12a"}, 0, ErrInvalid},
+		{&message{}, 0, ErrMissing},
+		{(*message)(nil), 0, ErrMissing},
+		{nil, 0, ErrMissing},
+	} {
+		got, err := FromMessage(c.m)
+		if got != c.want || !errors.Is(err, c.err) || (c.err == nil) != (err == nil) {
+			t.Errorf("FromMessage(%+v) = %d, %v, want %d, %v", c.m, got, err, c.want, c.err)
+		}
+	}
+
+	var st dxyflake.Settings
+	st.Clock = clockAt(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if got, err := FromMessageValidated(&message{value: Value(id)}, st); err != nil || got != id {
+		t.Errorf("FromMessageValidated() = %d, %v", got, err)
+	}
+	st.Clock = clockAt(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if _, err := FromMessageValidated(&message{value: Value(id)}, st); !errors.Is(err, dxyflake.ErrFutureID) {
+		t.Errorf("FromMessageValidated() of a future ID = %v", err)
+	}
+}
+
+type clockAt time.Time
+
+func (c clockAt) Now() time.Time        { return time.Time(c) }
+func (c clockAt) Sleep(d time.Duration) {}
+
+func TestWire(t *testing.T) {
+	const id = dxyflake.ID(29829153948057600)
+	b := Marshal(id)
+	// field 1, fixed64, little endian, as protoc encodes it
+	if want := "\x09\x00\x40\x06\x1d\x75\xf9\x69\x00"; string(b) != want {
+		t.Errorf("Marshal() = %x, want %x", b, want)
+	}
+	if got, err := Unmarshal(b); err != nil || got != id {
+		t.Errorf("Unmarshal() = %d, %v", got, err)
+	}
+	if got, err := Unmarshal(Marshal(0)); err != nil || got != 0 {
+		t.Errorf("Unmarshal() of ID 0 = %d, %v", got, err)
+	}
+
+	decimal := append([]byte{0x12, 17}, id.String()...)
+	unknown := []byte{0x18, 0x96, 0x01, 0x22, 0x01, 'x'}
+	if got, err := Unmarshal(append(append(b[:len(b):len(b)], unknown...), decimal...)); err != nil || got != id {
+		t.Errorf("Unmarshal() of the decimal = %d, %v", got, err)
+	}
+	for _, c := range []struct {
+		b   []byte
+		err error
+	}{
+		{nil, ErrMissing},
+		{unknown, ErrMissing},
+		{b[:5], ErrInvalid},
+		{[]byte{0x09, 0, 0, 0, 0, 0, 0, 0, 0x80}, ErrInvalid},
+		{[]byte{0x12, 2, '-', '1'}, ErrInvalid},
+		{[]byte{0x12, 5, '1'}, ErrInvalid},
+		{[]byte{0x0b}, ErrInvalid},
+	} {
+		if _, err := Unmarshal(c.b); !errors.Is(err, c.err) {
+			t.Errorf("Unmarshal(%x) error = %v, want %v", c.b, err, c.err)
+		}
+	}
+}
+
+type fallback struct{ calls int }
+
+func (f *fallback) Marshal(v interface{}) ([]byte, error)      { f.calls++; return nil, nil }
+func (f *fallback) Unmarshal(data []byte, v interface{}) error { f.calls++; return nil }
+func (f *fallback) Name() string                               { return "proto" }
+
+func TestCodec(t *testing.T) {
+	next := new(fallback)
+	c := Codec{Next: next}
+	id := dxyflake.ID(42)
+	b, err := c.Marshal(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back dxyflake.ID
+	if err := c.Unmarshal(b, &back); err != nil || back != id {
+		t.Errorf("Unmarshal() = %d, %v", back, err)
+	}
+	if err := c.Unmarshal([]byte{0x09, 1}, &back); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Unmarshal() of a truncated ID = %v", err)
+	}
+	c.Marshal("other")
+	c.Unmarshal(nil, new(string))
+	if next.calls != 2 || c.Name() != "proto" {
+		t.Errorf("Next called %d times, name %q", next.calls, c.Name())
+	}
+	if _, err := (Codec{}).Marshal("other"); err != ErrNoCodec {
+		t.Errorf("Marshal() without Next = %v", err)
+	}
+}
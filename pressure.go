@@ -0,0 +1,89 @@
+package dxyflake
+
+import (
+	"math"
+	"time"
+)
+
+// pressureWeight is the weight of the last time unit in Pressure: about
+// the last dozen time units make up most of it.
+const pressureWeight = 0.25
+
+// used returns the share of the sequence numbers of the current time unit
+// that have been used. It is called with df.mutex held.
+func (df *dxyflake) used() float64 {
+	return (float64(df.sequence-df.startSeq) + 1) / (float64(df.lastSeq-df.startSeq) + 1)
+}
+
+// endTick adds a time unit of which the share used of the sequence
+// numbers was used, followed by empty time units without IDs, to the
+// pressure of the dxyflake. It is called with df.mutex held.
+func (df *dxyflake) endTick(used float64, empty int64) {
+	if df.issued == 0 {
+		return
+	}
+	df.pressure = decayPressure(df.pressure, used, empty)
+}
+
+func decayPressure(pressure, used float64, empty int64) float64 {
+	pressure += (used - pressure) * pressureWeight
+	if empty > 0 {
+		pressure *= math.Pow(1-pressureWeight, float64(empty))
+	}
+	return pressure
+}
+
+// Pressure returns how close the dxyflake recently came to using up the
+// sequence numbers of its time units, from 0 for idle to 1 for using up
+// every time unit, so that admission control can shed load before NextID
+// starts waiting for the next time unit. It is a moving average of the
+// share of sequence numbers used per time unit, raised to the share used
+// of the current time unit so far, and 1 while IDs are issued ahead of the
+// clock.
+func (df *dxyflake) Pressure() float64 {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return df.currentPressure()
+}
+
+func (df *dxyflake) currentPressure() float64 {
+	if df.issued == 0 {
+		return 0
+	}
+	current := df.elapsed(df.now())
+	switch {
+	case df.elapsedTime > current:
+		return 1
+	case df.elapsedTime == current:
+		return math.Max(df.pressure, df.used())
+	default:
+		return decayPressure(df.pressure, df.used(), current-df.elapsedTime-1)
+	}
+}
+
+// Stats are the statistics of a dxyflake.
+type Stats struct {
+	Issued      uint64        // IDs issued
+	Waits       uint64        // waits for the next time unit, see Settings.Waiter
+	WaitTime    time.Duration // total time of the waits
+	Pressure    float64       // see Pressure
+	HighWater   uint16        // highest sequence number issued in a time unit
+	Remaining   time.Duration // time left until the time part overflows
+	RateLimited uint64        // calls delayed or refused by Settings.RateLimit
+}
+
+// Stats returns the statistics of the dxyflake. Remaining is capped at the
+// largest time.Duration, about 292 years.
+func (df *dxyflake) Stats() Stats {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+	return Stats{
+		Issued:      df.issued,
+		Waits:       df.waits,
+		WaitTime:    df.waitTime,
+		Pressure:    df.currentPressure(),
+		HighWater:   df.highWater,
+		Remaining:   df.TimeRemaining(),
+		RateLimited: df.limiter.count(),
+	}
+}
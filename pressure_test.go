@@ -0,0 +1,53 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPressure(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	clock := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.Clock = clock
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+	gen := NewDxyflake(st)
+	if p := gen.Pressure(); p != 0 {
+		t.Errorf("idle pressure %v", p)
+	}
+
+	// half of a time unit
+	for i := 0; i < 1<<(BitLenSequence-1); i++ {
+		gen.NextID()
+	}
+	if p := gen.Pressure(); p != 0.5 {
+		t.Errorf("pressure %v, want 0.5", p)
+	}
+
+	// every sequence number of several time units
+	var last float64
+	for tick := 0; tick < 8; tick++ {
+		for i := 0; i < 1<<BitLenSequence; i++ {
+			gen.NextID()
+		}
+		p := gen.Pressure()
+		if p < last {
+			t.Errorf("pressure fell under load: %v < %v", p, last)
+		}
+		last = p
+	}
+	if last < 0.85 {
+		t.Errorf("pressure %v under full load", last)
+	}
+
+	// idle time units decay it
+	clock.Sleep(20 * dxyflakeTimeUnit)
+	if p := gen.Pressure(); p > 0.01 {
+		t.Errorf("pressure %v after idling", p)
+	}
+
+	s := gen.Stats()
+	if s.Issued != 1<<(BitLenSequence-1)+8<<BitLenSequence || s.Waits != 8 || s.Pressure > 0.01 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,85 @@
+package dxyflake
+
+import (
+	"context"
+	"sync"
+)
+
+// Producer issues IDs in a goroutine into a buffered channel, so that hot
+// paths receive an ID with a channel read instead of taking the lock of the
+// generator. Unlike an Iterator with prefetch, it is read from many
+// goroutines at once. The timestamps of buffered IDs are those of the time
+// they were issued, not received.
+type Producer struct {
+	// C delivers the IDs. It is closed when the context of the producer is
+	// done, Stop is called or the generator fails; Err tells which.
+	C <-chan ID
+
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+	err    error
+}
+
+// NewProducer returns a Producer issuing IDs with gen into a channel of
+// bufSize IDs until ctx is done.
+func NewProducer(ctx context.Context, gen Generator, bufSize int) *Producer {
+	ctx, cancel := context.WithCancel(ctx)
+	ch := make(chan ID, bufSize)
+	p := &Producer{C: ch, cancel: cancel}
+	go p.run(ctx, gen, ch)
+	return p
+}
+
+// Stream returns a Producer issuing IDs of the dxyflake, see NewProducer.
+func (df *dxyflake) Stream(ctx context.Context, bufSize int) *Producer {
+	return NewProducer(ctx, df, bufSize)
+}
+
+func (p *Producer) run(ctx context.Context, gen Generator, ch chan<- ID) {
+	defer close(ch)
+	next := gen.NextID
+	if c, ok := gen.(interface {
+		NextIDContext(ctx context.Context) (ID, error)
+	}); ok {
+		next = func() (ID, error) { return c.NextIDContext(ctx) }
+	}
+	for {
+		id, err := next()
+		if err == nil {
+			select {
+			case ch <- id:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		p.mutex.Lock()
+		p.err = err
+		p.mutex.Unlock()
+		return
+	}
+}
+
+// Next receives an ID, or returns Err once C is closed.
+func (p *Producer) Next() (ID, error) {
+	if id, ok := <-p.C; ok {
+		return id, nil
+	}
+	return 0, p.Err()
+}
+
+// Err returns why C was closed: the error of the generator, or the error of
+// the context. It returns nil while C is open.
+func (p *Producer) Err() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.err
+}
+
+// Stop stops the producer. IDs buffered in C are still received; they are
+// unique but never used if they are not.
+func (p *Producer) Stop() {
+	p.cancel()
+}
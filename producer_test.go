@@ -0,0 +1,69 @@
+package dxyflake
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestProducer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newIterTestDxyflake(t).Stream(ctx, 64)
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		seen  = make(map[ID]bool)
+	)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				id, err := p.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mutex.Lock()
+				if seen[id] {
+					t.Errorf("duplicate %d", id)
+				}
+				seen[id] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := p.Err(); err != nil {
+		t.Errorf("Err() = %v while running", err)
+	}
+
+	cancel()
+	for range p.C {
+	}
+	if _, err := p.Next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Next() after cancel: %v", err)
+	}
+
+	p = NewProducer(context.Background(), &failAfter{gen: newIterTestDxyflake(t), n: 3}, 10)
+	n := 0
+	for range p.C {
+		n++
+	}
+	if n != 3 || !errors.Is(p.Err(), errIterTest) {
+		t.Errorf("got %d IDs and %v", n, p.Err())
+	}
+}
+
+func BenchmarkProducer(b *testing.B) {
+	var st Settings
+	st.Init(1, 2)
+	p := NewDxyflake(st).Stream(context.Background(), 1024)
+	defer p.Stop()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		<-p.C
+	}
+}
@@ -0,0 +1,43 @@
+package dxyflake
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDifferentNodes is returned by EstimateRate for IDs of different
+// machine IDs or service IDs.
+var ErrDifferentNodes = errors.New("ids are from different nodes")
+
+// EstimateRate estimates the rate a node issued IDs at from two of its IDs,
+// a dxyflake started at epoch issued them: about ids IDs per per. The
+// order of a and b does not matter. If epoch is 0, DefaultStartTime is
+// used. It returns ErrDifferentNodes if the IDs are from different nodes
+// and ErrOutOfRange if either is from the future.
+//
+// IDs of the same time unit give an exact count: b - a IDs within one time
+// unit. The sequence restarts every time unit, so IDs of different time
+// units only tell how busy their own units were: a node issuing n IDs per
+// unit hands out the sequence numbers 0 to n-1, and a random ID of the
+// unit has the sequence number (n-1)/2 on average. The estimate is thus
+// seqA+seqB+1 IDs per unit, which assumes the node was as busy in every
+// unit in between and overestimates the rate of nodes idle in between.
+func EstimateRate(a, b ID, epoch time.Time) (ids float64, per time.Duration, err error) {
+	l := layoutAt(epoch)
+	if a > b {
+		a, b = b, a
+	}
+	if max := l.MaxIDAt(time.Now().Add(24 * time.Hour)); a < 0 || b > max {
+		return 0, 0, ErrOutOfRange
+	}
+
+	pa, pb := l.Decompose(a), l.Decompose(b)
+	if pa["machine-id"] != pb["machine-id"] || pa["service-id"] != pb["service-id"] {
+		return 0, 0, ErrDifferentNodes
+	}
+	if pa["time"] == pb["time"] {
+		return float64(pb["sequence"] - pa["sequence"]), l.Unit, nil
+	}
+	ticks := pb["time"] - pa["time"]
+	return float64(pa["sequence"]+pb["sequence"]+1) * float64(ticks), time.Duration(ticks) * l.Unit, nil
+}
@@ -0,0 +1,37 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateRate(t *testing.T) {
+	at := time.Now().Add(-time.Minute)
+	compose := func(d time.Duration, m, s, seq uint16) ID {
+		id, err := DefaultLayout.Compose(at.Add(d), m, s, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	a := compose(0, 1, 2, 100)
+	ids, per, err := EstimateRate(a, a+50, time.Time{})
+	if err != nil || ids != 50 || per != 10*time.Millisecond {
+		t.Errorf("same unit: got %v per %v, %v", ids, per, err)
+	}
+
+	// 100 units of 250 IDs each
+	b := compose(time.Second, 1, 2, 149)
+	ids, per, err = EstimateRate(b, a, time.Time{})
+	if err != nil || ids != 25000 || per != time.Second {
+		t.Errorf("different units: got %v per %v, %v", ids, per, err)
+	}
+
+	if _, _, err := EstimateRate(a, compose(time.Second, 1, 3, 0), time.Time{}); err != ErrDifferentNodes {
+		t.Errorf("different nodes: got %v", err)
+	}
+	if _, _, err := EstimateRate(a, MaxIDAt(time.Now().Add(48*time.Hour), time.Time{}), time.Time{}); err != ErrOutOfRange {
+		t.Errorf("future id: got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package dxyflake
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by NextID under RateLimitError, and by
+// TryNextID, when issuing IDs would exceed Settings.RateLimit.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitPolicy is what NextID and NextIDContext do when issuing an ID
+// would exceed Settings.RateLimit.
+type RateLimitPolicy int
+
+// These are the rate limit policies.
+const (
+	RateLimitWait  RateLimitPolicy = iota // wait with Settings.Waiter until the ID is due, the default
+	RateLimitError                        // return ErrRateLimited
+)
+
+// limiter is a token bucket refilled at rate tokens per second, holding at
+// most burst. A Sharded dxyflake has one for all of its shards.
+type limiter struct {
+	mutex   sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	policy  RateLimitPolicy
+	limited uint64 // reservations delayed or refused
+}
+
+// newLimiter returns the limiter of st, or nil if st.RateLimit is not
+// positive. The bucket starts full.
+func newLimiter(st Settings, now time.Time) *limiter {
+	if !(st.RateLimit > 0) {
+		return nil
+	}
+	burst := float64(st.RateBurst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(st.RateLimit))
+	}
+	return &limiter{rate: st.RateLimit, burst: burst, tokens: burst, last: now, policy: st.RateLimited}
+}
+
+// reserve takes n tokens and returns how long to wait until they are due.
+// If wait is false, it takes nothing and returns ErrRateLimited unless n
+// tokens are there.
+func (l *limiter) reserve(n int, now time.Time, wait bool) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if d := now.Sub(l.last); d > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+d.Seconds()*l.rate)
+		l.last = now
+	}
+	if l.tokens >= float64(n) {
+		l.tokens -= float64(n)
+		return 0, nil
+	}
+	l.limited++
+	if !wait {
+		return 0, ErrRateLimited
+	}
+	l.tokens -= float64(n)
+	return time.Duration(-l.tokens / l.rate * float64(time.Second)), nil
+}
+
+// count returns the number of reservations delayed or refused, 0 for a
+// nil limiter.
+func (l *limiter) count() uint64 {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limited
+}
+
+// cancel gives back n tokens taken by reserve.
+func (l *limiter) cancel(n int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+float64(n))
+}
+
+// refund gives back the n tokens of a call that failed with err after
+// reserving them, if it failed before taking a sequence number. Calls
+// failing later, e.g. in Settings.Transform, keep them, as their sequence
+// numbers are used up.
+func (l *limiter) refund(n int, err error) {
+	if l != nil && err != nil && beforeSequence(err) {
+		l.cancel(n)
+	}
+}
+
+// beforeSequence reports whether err fails a call before it takes a
+// sequence number.
+func beforeSequence(err error) bool {
+	return errors.Is(err, ErrSequenceExhausted) || errors.Is(err, ErrClockMovedBack) ||
+		errors.Is(err, ErrClockCheck) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// throttle waits until n IDs are due under limiter l, or returns
+// ErrRateLimited if wait is false or the policy is RateLimitError. It
+// returns ctx.Err() if ctx is done first, without taking the IDs from the
+// limit.
+func (df *dxyflake) throttle(ctx context.Context, l *limiter, n int, wait bool) error {
+	if l == nil {
+		return nil
+	}
+	d, err := l.reserve(n, df.now(), wait && l.policy == RateLimitWait)
+	if err != nil || d == 0 {
+		return err
+	}
+	if err := df.sleep(ctx, d); err != nil {
+		l.cancel(n)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package dxyflake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	clock := &steppedClock{now: start.Add(time.Hour)}
+	var st Settings
+	st.Init(17, 2)
+	st.StartTime = start
+	st.Clock = clock
+	st.RateLimit, st.RateBurst = 100, 2
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	at := clock.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := df.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := clock.Now().Sub(at); d != 10*time.Millisecond {
+		t.Errorf("third ID of a burst of 2 at 100/s after %v", d)
+	}
+	if _, err := df.TryNextID(); err != ErrRateLimited {
+		t.Errorf("TryNextID() = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := df.NextIDContext(ctx); err != context.Canceled {
+		t.Errorf("NextIDContext() of a done context = %v", err)
+	}
+	at = clock.Now()
+	if ids, err := df.NextIDs(3); err != nil || len(ids) != 3 {
+		t.Fatalf("NextIDs() = %v, %v", ids, err)
+	}
+	if d := clock.Now().Sub(at); d != 30*time.Millisecond {
+		t.Errorf("3 IDs at 100/s after %v", d)
+	}
+	if s := df.Stats(); s.RateLimited != 4 || s.Waits != 0 || s.Issued != 6 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+
+	st.Init(17, 3)
+	st.RateLimited = RateLimitError
+	st.RateBurst = 0
+	st.RateLimit = 0.5
+	df, err = NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+	if _, err := df.NextID(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := df.NextID(); err != ErrRateLimited {
+		t.Errorf("second ID of a burst of 1: %v", err)
+	}
+	clock.Sleep(2 * time.Second)
+	if _, err := df.NextID(); err != nil {
+		t.Errorf("ID due after 2s at 0.5/s: %v", err)
+	}
+}
+
+func TestRateLimitRefund(t *testing.T) {
+	start := time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC)
+	clock := &steppedClock{now: start.Add(time.Hour)}
+	var st Settings
+	st.Init(7, 5)
+	st.StartTime = start
+	st.Clock = clock
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 53, 4, 4, 2
+	st.RateLimit, st.RateBurst, st.RateLimited = 1, 9, RateLimitError
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 4; i++ {
+			if _, err := df.TryNextID(); err != nil {
+				t.Fatalf("round %d: %v", round, err)
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := df.TryNextID(); err != ErrSequenceExhausted {
+				t.Fatalf("round %d: TryNextID() = %v", round, err)
+			}
+		}
+		clock.Sleep(10 * time.Millisecond)
+	}
+	if s := df.Stats(); s.RateLimited != 0 || s.Issued != 8 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestRateLimitTransform(t *testing.T) {
+	start := time.Date(2022, 10, 4, 0, 0, 0, 0, time.UTC)
+	var st Settings
+	st.Init(7, 6)
+	st.StartTime = start
+	st.Clock = &steppedClock{now: start.Add(time.Hour)}
+	st.RateLimit, st.RateBurst, st.RateLimited = 1, 2, RateLimitError
+	errTransform := errors.New("transform failed")
+	st.Transform = func(ID) (ID, error) { return 0, errTransform }
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := df.NextID(); err != errTransform {
+			t.Fatalf("NextID() = %v", err)
+		}
+	}
+	if _, err := df.NextID(); err != ErrRateLimited {
+		t.Errorf("third ID of a burst of 2 after failed transforms: %v", err)
+	}
+}
+
+func TestRateLimitSharded(t *testing.T) {
+	start := time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC)
+	var st Settings
+	st.Init(17, 4)
+	st.StartTime = start
+	st.Clock = &steppedClock{now: start.Add(time.Hour)}
+	st.RateLimit, st.RateLimited = 10, RateLimitError
+	s, err := NewShardedDxyflake(st, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// the shards share the limit
+	var issued int
+	for i := 0; i < 20; i++ {
+		if _, err := s.NextID(); err == nil {
+			issued++
+		} else if err != ErrRateLimited {
+			t.Fatal(err)
+		}
+	}
+	if issued != 10 || s.Stats().RateLimited != 10 {
+		t.Errorf("%d of 20 IDs issued at 10/s, stats %+v", issued, s.Stats())
+	}
+}
@@ -0,0 +1,16 @@
+-- dedup.lua records an ID in the set of its time bucket and returns 1 if
+-- it was not there yet, 0 if it is a duplicate.
+--
+-- KEYS[1] is the key prefix; the set of a bucket is KEYS[1]:<bucket>, so
+-- in Redis Cluster the prefix must carry a hash tag. ARGV[10] is the
+-- length of a bucket and ARGV[11] how long a set is kept after its bucket
+-- ends, both in milliseconds.
+
+local size = tonumber(ARGV[10])
+local bucket = math.floor(ms / size)
+local key = KEYS[1] .. ':' .. string.format('%d', bucket)
+local added = redis.call('SADD', key, ARGV[1])
+if added == 1 then
+  redis.call('PEXPIREAT', key, string.format('%d', (bucket + 1) * size + tonumber(ARGV[11])))
+end
+return added
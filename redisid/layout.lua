@@ -0,0 +1,51 @@
+-- layout.lua is prepended to the scripts of package redisid. It reads an
+-- ID and its parts without losing precision: Lua numbers are doubles, so
+-- the ID is passed as a decimal string and split into 32-bit halves.
+--
+-- ARGV[1] is the ID, ARGV[2] to ARGV[9] describe the layout: the shifts
+-- and bit lengths of the time part, the machine ID and the service ID, the
+-- epoch in milliseconds since the Unix epoch and the time unit in
+-- milliseconds.
+
+local function split(s)
+  local hi, lo = 0, 0
+  for i = 1, #s do
+    local d = string.byte(s, i) - 48
+    if d < 0 or d > 9 then
+      return redis.error_reply('invalid id ' .. s)
+    end
+    lo = lo * 10 + d
+    local carry = math.floor(lo / 4294967296)
+    lo = lo % 4294967296
+    hi = hi * 10 + carry
+    if hi >= 2147483648 then
+      return redis.error_reply('invalid id ' .. s)
+    end
+  end
+  return hi, lo
+end
+
+-- field returns (id >> shift) & (1<<bits - 1) for bits <= 53.
+local function field(hi, lo, shift, bits)
+  local m = 2 ^ bits
+  if shift >= 32 then
+    return math.floor(hi / 2 ^ (shift - 32)) % m
+  end
+  local h = 0
+  local hb = bits - (32 - shift)
+  if hb > 0 then
+    h = (hi % 2 ^ hb) * 2 ^ (32 - shift)
+  end
+  return (h + math.floor(lo / 2 ^ shift)) % m
+end
+
+local hi, lo = split(ARGV[1])
+if type(hi) == 'table' then
+  return hi
+end
+local tick = field(hi, lo, tonumber(ARGV[2]), tonumber(ARGV[3]))
+local machine_id = field(hi, lo, tonumber(ARGV[4]), tonumber(ARGV[5]))
+local service_id = field(hi, lo, tonumber(ARGV[6]), tonumber(ARGV[7]))
+-- ms is the time in milliseconds since the Unix epoch at which the time
+-- unit of the ID begins.
+local ms = tonumber(ARGV[8]) + tick * tonumber(ARGV[9])
@@ -0,0 +1,17 @@
+-- rate.lua counts an ID in the window of its machine ID and service ID and
+-- returns the machine ID, the service ID and the count of the window.
+--
+-- KEYS[1] is the key prefix; the counter of a window is
+-- KEYS[1]:<machine-id>:<service-id>:<window>, so in Redis Cluster the
+-- prefix must carry a hash tag. ARGV[10] is the length of a window and
+-- ARGV[11] how long a counter is kept after its window ends, both in
+-- milliseconds.
+
+local size = tonumber(ARGV[10])
+local window = math.floor(ms / size)
+local key = KEYS[1] .. ':' .. string.format('%d:%d:%d', machine_id, service_id, window)
+local count = redis.call('INCR', key)
+if count == 1 then
+  redis.call('PEXPIREAT', key, string.format('%d', (window + 1) * size + tonumber(ARGV[11])))
+end
+return {machine_id, service_id, count}
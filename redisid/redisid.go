@@ -0,0 +1,155 @@
+// Package redisid runs checks on IDs inside Redis with Lua scripts: dedup
+// by ID in sets per time bucket, and rate counting per machine ID and
+// service ID. The bit math that reads the parts of an ID lives in one
+// script, layout.lua, shared by the others.
+//
+// The package does not depend on a Redis client; Evaler adapts one, e.g.
+// for go-redis:
+//
+//	type evaler struct{ c *redis.Client }
+//
+//	func (e evaler) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+//		return e.c.EvalSha(ctx, sha1, keys, args...).Result()
+//	}
+//
+//	func (e evaler) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return e.c.Eval(ctx, script, keys, args...).Result()
+//	}
+package redisid
+
+import (
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrUnexpectedReply is returned for script replies of the wrong type.
+var ErrUnexpectedReply = errors.New("unexpected reply")
+
+// Evaler runs Lua scripts on a Redis server.
+type Evaler interface {
+	EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error)
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// Script is a Lua script with its SHA-1 digest.
+type Script struct {
+	Source string
+	SHA1   string
+}
+
+// NewScript returns the Script of src.
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{Source: src, SHA1: hex.EncodeToString(sum[:])}
+}
+
+// Run runs the script by its digest, and by its source if the server does
+// not have it cached yet.
+func (s *Script) Run(ctx context.Context, r Evaler, keys []string, args ...interface{}) (interface{}, error) {
+	v, err := r.EvalSha(ctx, s.SHA1, keys, args...)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		return r.Eval(ctx, s.Source, keys, args...)
+	}
+	return v, err
+}
+
+var (
+	//go:embed layout.lua
+	layoutLua string
+	//go:embed dedup.lua
+	dedupLua string
+	//go:embed rate.lua
+	rateLua string
+)
+
+// These are the scripts of the package, see the .lua files for their keys
+// and arguments.
+var (
+	DedupScript = NewScript(layoutLua + dedupLua)
+	RateScript  = NewScript(layoutLua + rateLua)
+)
+
+// Checker runs the scripts of the package for IDs of one layout.
+type Checker struct {
+	r      Evaler
+	layout []interface{} // ARGV[2] to ARGV[9]
+}
+
+// New returns a Checker of the IDs of layout l. It returns
+// dxyflake.ErrInvalidLayout if l is not valid or its time part is longer
+// than the 53 bits a Lua number holds.
+func New(r Evaler, l dxyflake.Layout) (*Checker, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	if l.BitLenTime > 53 {
+		return nil, dxyflake.ErrInvalidLayout
+	}
+	m, d := l.Masks(), l.Describe()
+	return &Checker{r: r, layout: []interface{}{
+		m.ShiftTime, l.BitLenTime,
+		m.ShiftMachineID, l.BitLenMachineID,
+		m.ShiftServiceID, l.BitLenServiceID,
+		d.Epoch.UnixNano() / int64(time.Millisecond),
+		ms(l.Unit),
+	}}, nil
+}
+
+// ms formats d in milliseconds for a script.
+func ms(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
+
+func (c *Checker) args(id dxyflake.ID, size, ttl time.Duration) []interface{} {
+	args := make([]interface{}, 0, 11)
+	args = append(args, id.String())
+	args = append(args, c.layout...)
+	return append(args, ms(size), ms(ttl))
+}
+
+// Dedup records id under prefix in the set of its time bucket of length
+// bucket, kept for ttl after the bucket ends, and reports whether id was
+// seen before. IDs from buckets that ended more than ttl ago are not
+// recorded and never reported as seen. In Redis Cluster, prefix must
+// carry a hash tag, e.g. "{dedup}".
+func (c *Checker) Dedup(ctx context.Context, prefix string, id dxyflake.ID, bucket, ttl time.Duration) (seen bool, err error) {
+	v, err := DedupScript.Run(ctx, c.r, []string{prefix}, c.args(id, bucket, ttl)...)
+	if err != nil {
+		return false, err
+	}
+	added, ok := v.(int64)
+	if !ok {
+		return false, ErrUnexpectedReply
+	}
+	return added == 0, nil
+}
+
+// Count counts id under prefix in the window of length window of its
+// machine ID and service ID, kept for ttl after the window ends, and
+// returns the count of the window so far. In Redis Cluster, prefix must
+// carry a hash tag, e.g. "{rate}".
+func (c *Checker) Count(ctx context.Context, prefix string, id dxyflake.ID, window, ttl time.Duration) (machineID, serviceID uint16, count int64, err error) {
+	v, err := RateScript.Run(ctx, c.r, []string{prefix}, c.args(id, window, ttl)...)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	reply, ok := v.([]interface{})
+	if !ok || len(reply) != 3 {
+		return 0, 0, 0, ErrUnexpectedReply
+	}
+	var n [3]int64
+	for i := range n {
+		if n[i], ok = reply[i].(int64); !ok {
+			return 0, 0, 0, ErrUnexpectedReply
+		}
+	}
+	return uint16(n[0]), uint16(n[1]), n[2], nil
+}
@@ -0,0 +1,75 @@
+package redisid
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// testEvaler replies to scripts with canned values and caches scripts like
+// Redis does.
+type testEvaler struct {
+	cached map[string]bool
+	reply  interface{}
+	args   []interface{}
+}
+
+func (e *testEvaler) EvalSha(ctx context.Context, sha1 string, keys []string, args ...interface{}) (interface{}, error) {
+	if !e.cached[sha1] {
+		return nil, errors.New("NOSCRIPT No matching script. Please use EVAL.")
+	}
+	e.args = args
+	return e.reply, nil
+}
+
+func (e *testEvaler) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	e.cached[NewScript(script).SHA1] = true
+	e.args = args
+	return e.reply, nil
+}
+
+func TestScripts(t *testing.T) {
+	if !strings.HasPrefix(DedupScript.Source, layoutLua) || !strings.HasPrefix(RateScript.Source, layoutLua) {
+		t.Error("scripts do not start with layout.lua")
+	}
+
+	e := &testEvaler{cached: make(map[string]bool), reply: int64(1)}
+	c, err := New(e, dxyflake.DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id := dxyflake.ID(66740215716663303)
+	if seen, err := c.Dedup(ctx, "{dedup}", id, time.Hour, 90*time.Second); err != nil || seen {
+		t.Errorf("Dedup() = %v, %v", seen, err)
+	}
+	if !e.cached[DedupScript.SHA1] {
+		t.Error("script not run by source after NOSCRIPT")
+	}
+	want := []interface{}{"66740215716663303", uint(22), 41, uint(17), 5, uint(12), 5, int64(1633046400000), "10", "3600000", "90000"}
+	if len(e.args) != len(want) {
+		t.Fatalf("unexpected args %v", e.args)
+	}
+	for i := range want {
+		if e.args[i] != want[i] {
+			t.Errorf("ARGV[%d] = %#v, want %#v", i+1, e.args[i], want[i])
+		}
+	}
+	e.reply = int64(0)
+	if seen, err := c.Dedup(ctx, "{dedup}", id, time.Hour, 0); err != nil || !seen {
+		t.Errorf("Dedup() = %v, %v", seen, err)
+	}
+
+	e.reply = []interface{}{int64(3), int64(5), int64(42)}
+	if m, s, n, err := c.Count(ctx, "{rate}", id, time.Minute, time.Hour); err != nil || m != 3 || s != 5 || n != 42 {
+		t.Errorf("Count() = %d, %d, %d, %v", m, s, n, err)
+	}
+	e.reply = "OK"
+	if _, _, _, err := c.Count(ctx, "{rate}", id, time.Minute, time.Hour); err != ErrUnexpectedReply {
+		t.Errorf("Count() error = %v", err)
+	}
+}
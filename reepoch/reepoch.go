@@ -0,0 +1,150 @@
+// Package reepoch moves stored IDs from one epoch to a later one, to extend
+// the lifetime of a deployment without a flag-day cutover.
+//
+// An ID keeps its time, machine ID, service ID and sequence; only its time
+// part is counted from the new epoch. IDs from before the new epoch cannot
+// be represented and get a fresh ID from a generator instead, recorded in a
+// mapping so that references to them can be updated. Rewritten IDs of one
+// node keep their order, which the Rewriter verifies as it goes.
+package reepoch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These errors are returned by the Rewriter.
+var (
+	ErrEpochNotLater    = errors.New("new epoch is not later than the old one")
+	ErrNotRepresentable = errors.New("id is not representable under the new epoch")
+	ErrOrderBroken      = errors.New("rewritten ids of a node are out of order")
+)
+
+// Report summarizes a Run.
+type Report struct {
+	Total     int `json:"total"`
+	Rewritten int `json:"rewritten"`
+	Mapped    int `json:"mapped"`
+}
+
+// Rewriter rewrites IDs from an old epoch to a new one. It is not safe for
+// concurrent use.
+type Rewriter struct {
+	from, to dxyflake.Layout
+	gen      dxyflake.Generator
+	nodes    map[int64]pair
+}
+
+// pair is the last ID of a node before and after rewriting.
+type pair struct {
+	old, new dxyflake.ID
+}
+
+// New returns a Rewriter from the epoch from to the later epoch to. gen
+// issues the IDs for those that are not representable under the new
+// epoch; if it is nil, Rewrite returns ErrNotRepresentable for them.
+func New(from, to time.Time, gen dxyflake.Generator) (*Rewriter, error) {
+	if !to.After(from) {
+		return nil, ErrEpochNotLater
+	}
+	r := &Rewriter{
+		from:  dxyflake.DefaultLayout,
+		to:    dxyflake.DefaultLayout,
+		gen:   gen,
+		nodes: make(map[int64]pair),
+	}
+	r.from.Epoch, r.to.Epoch = from, to
+	if err := r.to.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Rewrite returns the ID under the new epoch. mapped is true if id is not
+// representable and the result was issued by the generator. It returns
+// ErrOrderBroken if a rewritten ID does not compare to the last rewritten
+// one of its node as id does, which means the epochs do not fit the IDs.
+func (r *Rewriter) Rewrite(id dxyflake.ID) (result dxyflake.ID, mapped bool, err error) {
+	if id < 0 {
+		return 0, false, dxyflake.ErrOutOfRange
+	}
+	p := r.from.Decompose(id)
+	result, err = r.to.Compose(r.from.TimeOf(id),
+		uint16(p["machine-id"]), uint16(p["service-id"]), uint16(p["sequence"]))
+	if err != nil {
+		if r.gen == nil {
+			return 0, false, ErrNotRepresentable
+		}
+		result, err = r.gen.NextID()
+		return result, err == nil, err
+	}
+
+	node := p["machine-id"]<<16 | p["service-id"]
+	if last, ok := r.nodes[node]; ok && compare(id, last.old) != compare(result, last.new) {
+		return 0, false, fmt.Errorf("%w: %d after %d", ErrOrderBroken, id, last.old)
+	}
+	r.nodes[node] = pair{id, result}
+	return result, false, nil
+}
+
+func compare(a, b dxyflake.ID) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Run rewrites the decimal IDs read from src, one per line, and writes the
+// results to dst in the same order. The IDs that had to be mapped are also
+// written to mapping as CSV lines of the old and the new ID, after an
+// "old,new" header. Empty lines are copied.
+func (r *Rewriter) Run(src io.Reader, dst, mapping io.Writer) (*Report, error) {
+	rep := new(Report)
+	out := bufio.NewWriter(dst)
+	mapOut := bufio.NewWriter(mapping)
+	if _, err := mapOut.WriteString("old,new\n"); err != nil {
+		return rep, err
+	}
+
+	scanner := bufio.NewScanner(src)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			out.WriteByte('\n')
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return rep, fmt.Errorf("line %d: %w", line, err)
+		}
+		rep.Total++
+		result, mapped, err := r.Rewrite(dxyflake.ID(id))
+		if err != nil {
+			return rep, fmt.Errorf("line %d: %w", line, err)
+		}
+		if mapped {
+			rep.Mapped++
+			fmt.Fprintf(mapOut, "%d,%d\n", id, result)
+		} else {
+			rep.Rewritten++
+		}
+		fmt.Fprintf(out, "%d\n", result)
+	}
+	if err := scanner.Err(); err != nil {
+		return rep, err
+	}
+	if err := out.Flush(); err != nil {
+		return rep, err
+	}
+	return rep, mapOut.Flush()
+}
@@ -0,0 +1,122 @@
+package reepoch
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+var (
+	oldEpoch = dxyflake.DefaultStartTime
+	newEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func compose(t *testing.T, at time.Time, m, s, seq uint16) dxyflake.ID {
+	l := dxyflake.DefaultLayout
+	l.Epoch = oldEpoch
+	id, err := l.Compose(at, m, s, seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestRewrite(t *testing.T) {
+	r, err := New(oldEpoch, newEpoch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	id := compose(t, at, 3, 4, 5)
+	got, mapped, err := r.Rewrite(id)
+	if err != nil || mapped {
+		t.Fatal(mapped, err)
+	}
+	l := dxyflake.DefaultLayout
+	l.Epoch = newEpoch
+	if !l.TimeOf(got).Equal(at) || got >= id {
+		t.Errorf("unexpected rewritten id %d: %v", got, l.TimeOf(got))
+	}
+	p := l.Decompose(got)
+	if p["machine-id"] != 3 || p["service-id"] != 4 || p["sequence"] != 5 {
+		t.Errorf("unexpected parts: %v", p)
+	}
+
+	if _, _, err := r.Rewrite(compose(t, newEpoch.Add(-time.Hour), 3, 4, 0)); err != ErrNotRepresentable {
+		t.Errorf("id before the new epoch: got %v", err)
+	}
+	if _, err := New(newEpoch, oldEpoch, nil); err != ErrEpochNotLater {
+		t.Errorf("earlier epoch: got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(9, 9)
+	st.StartTimeSet(newEpoch)
+	r, err := New(oldEpoch, newEpoch, dxyflake.NewDxyflake(st))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	ids := []dxyflake.ID{
+		compose(t, at, 1, 1, 0),
+		compose(t, at, 1, 1, 1),
+		compose(t, at.Add(time.Second), 1, 1, 0),
+		compose(t, at, 2, 1, 0),
+		compose(t, newEpoch.Add(-time.Hour), 1, 1, 0),
+	}
+	var src strings.Builder
+	for _, id := range ids {
+		src.WriteString(id.String() + "\n")
+	}
+	var dst, mapping bytes.Buffer
+	rep, err := r.Run(strings.NewReader(src.String()), &dst, &mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *rep != (Report{Total: 5, Rewritten: 4, Mapped: 1}) {
+		t.Errorf("unexpected report: %+v", rep)
+	}
+	lines := strings.Split(strings.TrimSpace(dst.String()), "\n")
+	if len(lines) != len(ids) {
+		t.Fatalf("unexpected output: %q", dst.String())
+	}
+	want := "old,new\n" + ids[4].String() + "," + lines[4] + "\n"
+	if mapping.String() != want {
+		t.Errorf("unexpected mapping: %q, want %q", mapping.String(), want)
+	}
+
+	if _, err := r.Run(strings.NewReader("12x\n"), &dst, &mapping); err == nil {
+		t.Error("no error for an invalid id")
+	}
+}
+
+func TestRewriteOrder(t *testing.T) {
+	// epochs off the unit grid still shift every tick by the same amount
+	r, err := New(oldEpoch, newEpoch.Add(3*time.Millisecond), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var last dxyflake.ID
+	for i := 0; i < 100; i++ {
+		got, _, err := r.Rewrite(compose(t, at.Add(time.Duration(i)*10*time.Millisecond), 1, 1, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got <= last {
+			t.Fatalf("order not preserved: %d after %d", got, last)
+		}
+		last = got
+	}
+	if _, _, err := r.Rewrite(compose(t, at, 1, 1, 0)); errors.Is(err, ErrOrderBroken) {
+		t.Errorf("earlier id flagged: %v", err)
+	}
+}
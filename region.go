@@ -0,0 +1,58 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRegionID is returned by NewDxyflakeWithError for region IDs
+// that do not fit Settings.BitsRegion.
+var ErrInvalidRegionID = errors.New("region id out of range")
+
+// RegionID returns the region ID of id: the upper BitLenRegionID bits of
+// its machine ID. It is 0 in layouts without region bits.
+func (l Layout) RegionID(id ID) uint16 {
+	return uint16(int64(id) >> (l.shiftMachineID() + l.nodeBits()) & (1<<uint(l.BitLenRegionID) - 1))
+}
+
+// NodeID returns the node ID of id: the bits of its machine ID below the
+// region ID, the whole machine ID in layouts without region bits.
+func (l Layout) NodeID(id ID) uint16 {
+	return uint16(int64(id) >> l.shiftMachineID() & (1<<l.nodeBits() - 1))
+}
+
+// nodeBits returns the bit length of the node IDs of the layout.
+func (l Layout) nodeBits() uint {
+	return uint(l.BitLenMachineID - l.BitLenRegionID)
+}
+
+// InitRegion sets the RegionID, MachineID and ServiceID of a layout with
+// region bits, see Settings.BitsRegion: nID is the node ID within the
+// region.
+func (s *Settings) InitRegion(rID, nID, sID uint16) {
+	if s != nil {
+		s.Init(nID, sID)
+		s.RegionID = func() (uint16, error) {
+			return rID, nil
+		}
+	}
+}
+
+// regionMachineID returns the machine ID of node nID in the region of
+// regionID, or region 0 if regionID is nil.
+func (l Layout) regionMachineID(regionID func() (uint16, error), nID uint16) (uint16, error) {
+	var rID uint16
+	if regionID != nil {
+		var err error
+		if rID, err = regionID(); err != nil {
+			return 0, fmt.Errorf("region id: %w", err)
+		}
+	}
+	if int(rID) >= 1<<uint(l.BitLenRegionID) {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidRegionID, rID)
+	}
+	if int(nID) >= 1<<l.nodeBits() {
+		return 0, fmt.Errorf("%w: node %d", ErrInvalidMachineID, nID)
+	}
+	return rID<<l.nodeBits() | nID, nil
+}
@@ -0,0 +1,64 @@
+package dxyflake
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegion(t *testing.T) {
+	var st Settings
+	st.InitRegion(2, 5, 1)
+	st.BitsRegion = 2
+	st.StartTime = time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer df.Close()
+
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := df.DecomposeParts(id)
+	if p.RegionID != 2 || p.NodeID != 5 || p.MachineID != 2<<3|5 || p.ServiceID != 1 {
+		t.Errorf("unexpected parts: %+v", p)
+	}
+	if parts := df.Decompose(id); parts["region-id"] != 2 || parts["node-id"] != 5 {
+		t.Errorf("unexpected decomposition: %v", parts)
+	}
+	if d := df.Describe(); d.BitLenRegionID != 2 || d.Compatible(DefaultLayout.Describe()) == nil {
+		t.Errorf("unexpected description: %+v", d)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Parts
+	if err := json.Unmarshal(b, &back); err != nil || back.RegionID != 2 || back.NodeID != 5 {
+		t.Errorf("round trip of %s: %+v, %v", b, back, err)
+	}
+	if p := DecomposeParts(id); p.RegionID != 0 || p.NodeID != p.MachineID {
+		t.Errorf("parts without region bits: %+v", p)
+	}
+
+	for _, c := range []struct {
+		region, node uint16
+		bits         int
+		err          error
+	}{
+		{4, 0, 2, ErrInvalidRegionID},
+		{0, 8, 2, ErrInvalidMachineID},
+		{1, 0, 0, ErrInvalidRegionID},
+		{0, 0, 6, ErrInvalidLayout},
+	} {
+		st.InitRegion(c.region, c.node, 3)
+		st.BitsRegion = c.bits
+		if _, err := NewDxyflakeWithError(st); !errors.Is(err, c.err) {
+			t.Errorf("region %d, node %d of %d region bits: %v, want %v", c.region, c.node, c.bits, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownService is returned by Registry.NextID for service names that
+// are not in the ServiceNames of its Settings.
+var ErrUnknownService = errors.New("unknown service")
+
+// Registry holds one dxyflake per service ID on a shared machine ID and
+// start time, for processes hosting many logical services. The dxyflakes
+// are created on first use; the Settings of the registry configure all of
+// them but their service ID.
+type Registry struct {
+	st       Settings
+	services map[string]uint16
+
+	mutex  sync.RWMutex
+	gens   map[uint16]*dxyflake
+	closed bool
+}
+
+// NewRegistry returns a Registry of dxyflakes configured with st. The
+// machine ID is taken from st.MachineID once, so that all dxyflakes share
+// it; st.ServiceID and st.Namespace are ignored, and st.ServiceNames name
+// the services of NextID.
+func NewRegistry(st Settings) (*Registry, error) {
+	var machineID uint16
+	if st.MachineID != nil {
+		var err error
+		if machineID, err = st.MachineID(); err != nil {
+			return nil, err
+		}
+	}
+	st.MachineID = func() (uint16, error) { return machineID, nil }
+	st.Namespace = ""
+
+	r := &Registry{st: st, services: make(map[string]uint16), gens: make(map[uint16]*dxyflake)}
+	for id, name := range st.ServiceNames {
+		r.services[name] = id
+	}
+	return r, nil
+}
+
+// For returns the dxyflake of serviceID, creating it on first use. It
+// returns the error of NewDxyflakeWithError if it cannot be created, and
+// tries again on the next call. Once the registry is closed, it returns
+// ErrClosed instead of creating a dxyflake.
+func (r *Registry) For(serviceID uint16) (*dxyflake, error) {
+	r.mutex.RLock()
+	df := r.gens[serviceID]
+	r.mutex.RUnlock()
+	if df != nil {
+		return df, nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if df := r.gens[serviceID]; df != nil {
+		return df, nil
+	}
+	if r.closed {
+		return nil, ErrClosed
+	}
+	st := r.st
+	st.ServiceID = func() (uint16, error) { return serviceID, nil }
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		return nil, fmt.Errorf("service id %d: %w", serviceID, err)
+	}
+	r.gens[serviceID] = df
+	return df, nil
+}
+
+// NextID issues an ID of the named service.
+func (r *Registry) NextID(service string) (ID, error) {
+	serviceID, ok := r.services[service]
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownService, service)
+	}
+	df, err := r.For(serviceID)
+	if err != nil {
+		return 0, err
+	}
+	return df.NextID()
+}
+
+// Drain drains the dxyflakes created so far, e.g. on shutdown, and returns
+// their marks by service ID.
+func (r *Registry) Drain() map[uint16]Mark {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	marks := make(map[uint16]Mark, len(r.gens))
+	for id, df := range r.gens {
+		marks[id] = df.Drain()
+	}
+	return marks
+}
+
+// Close closes the dxyflakes created so far. Every later NextID returns
+// ErrClosed, for the services created so far and those still to come.
+func (r *Registry) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+	for _, df := range r.gens {
+		df.Close()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package dxyflake
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	var st Settings
+	st.Init(7, 0)
+	st.StartTime = time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	st.ServiceNames = ServiceNames{1: "orders", 2: "payments"}
+	r, err := NewRegistry(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	gens := make([]*dxyflake, 8)
+	for i := range gens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			gens[i], _ = r.For(3)
+		}(i)
+	}
+	wg.Wait()
+	for _, df := range gens {
+		if df == nil || df != gens[0] {
+			t.Fatal("For() created several dxyflakes of a service ID")
+		}
+	}
+
+	for name, serviceID := range map[string]uint16{"orders": 1, "payments": 2} {
+		id, err := r.NextID(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		df, _ := r.For(serviceID)
+		parts := df.DecomposeParts(id)
+		if parts.MachineID != 7 || parts.ServiceID != serviceID {
+			t.Errorf("%s: %+v", name, parts)
+		}
+		if df.Namespace() != name {
+			t.Errorf("namespace %q, want %q", df.Namespace(), name)
+		}
+	}
+	if _, err := r.NextID("shipping"); !errors.Is(err, ErrUnknownService) {
+		t.Errorf("unknown service: %v", err)
+	}
+	if _, err := r.For(1 << BitLenServiceID); !errors.Is(err, ErrInvalidServiceID) {
+		t.Errorf("service ID out of range: %v", err)
+	}
+	if marks := r.Drain(); len(marks) != 3 {
+		t.Errorf("Drain() = %v", marks)
+	}
+	r.Close()
+	if _, err := r.NextID("orders"); err != ErrClosed {
+		t.Errorf("NextID() after Close = %v", err)
+	}
+	if _, err := r.For(4); err != ErrClosed {
+		t.Errorf("For() after Close = %v", err)
+	}
+}
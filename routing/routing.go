@@ -0,0 +1,151 @@
+// Package routing builds routing tables that map dxyflake ID sub-spaces to
+// the deployments, shards and regions that issued them.
+//
+// The machine ID and service ID of an ID together form its node:
+//
+//	node = id >> BitLenSequence & (1<<(BitLenMachineID+BitLenServiceID) - 1)
+//
+// A Table is a sorted list of node ranges, so a gateway only needs a shift,
+// a mask and a binary search to route a request by ID.
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These are the layout values gateways need to compute the node of an ID.
+const (
+	NodeShift = dxyflake.BitLenSequence
+	NodeBits  = dxyflake.BitLenMachineID + dxyflake.BitLenServiceID
+	NodeMask  = 1<<NodeBits - 1
+)
+
+// These errors are returned by Build for inconsistent fleets.
+var (
+	ErrOverlap    = errors.New("node is claimed by more than one deployment")
+	ErrOutOfRange = errors.New("id does not fit its field")
+	ErrNoName     = errors.New("deployment without a name")
+)
+
+// Deployment is a group of generators sharing a route target.
+// An empty ServiceIDs list claims every service ID of its machine IDs.
+type Deployment struct {
+	Name       string   `json:"name" yaml:"name"`
+	Region     string   `json:"region,omitempty" yaml:"region"`
+	Shard      string   `json:"shard,omitempty" yaml:"shard"`
+	MachineIDs []uint16 `json:"machine-ids" yaml:"machine-ids"`
+	ServiceIDs []uint16 `json:"service-ids,omitempty" yaml:"service-ids"`
+}
+
+// Fleet describes every deployment issuing IDs.
+type Fleet struct {
+	Deployments []Deployment `json:"deployments" yaml:"deployments"`
+}
+
+// Route maps the nodes NodeMin to NodeMax, inclusive, to a deployment.
+type Route struct {
+	NodeMin    int    `json:"node-min"`
+	NodeMax    int    `json:"node-max"`
+	MachineID  uint16 `json:"machine-id"`
+	ServiceMin uint16 `json:"service-id-min"`
+	ServiceMax uint16 `json:"service-id-max"`
+	Deployment string `json:"deployment"`
+	Region     string `json:"region,omitempty"`
+	Shard      string `json:"shard,omitempty"`
+}
+
+// Table is a routing table sorted by node.
+type Table struct {
+	NodeShift int     `json:"node-shift"`
+	NodeMask  int     `json:"node-mask"`
+	Routes    []Route `json:"routes"`
+}
+
+func node(machineID, serviceID uint16) int {
+	return int(machineID)<<dxyflake.BitLenServiceID | int(serviceID)
+}
+
+// Build returns the routing table of a fleet. Nodes no deployment claims
+// are left out of the table.
+func Build(f Fleet) (*Table, error) {
+	owners := make([]int, 1<<NodeBits)
+	for i := range owners {
+		owners[i] = -1
+	}
+
+	for i, d := range f.Deployments {
+		if d.Name == "" {
+			return nil, ErrNoName
+		}
+		services := d.ServiceIDs
+		if len(services) == 0 {
+			services = make([]uint16, 1<<dxyflake.BitLenServiceID)
+			for s := range services {
+				services[s] = uint16(s)
+			}
+		}
+		for _, m := range d.MachineIDs {
+			if m >= 1<<dxyflake.BitLenMachineID {
+				return nil, fmt.Errorf("%s: machine id %d: %w", d.Name, m, ErrOutOfRange)
+			}
+			for _, s := range services {
+				if s >= 1<<dxyflake.BitLenServiceID {
+					return nil, fmt.Errorf("%s: service id %d: %w", d.Name, s, ErrOutOfRange)
+				}
+				n := node(m, s)
+				if owners[n] >= 0 && owners[n] != i {
+					return nil, fmt.Errorf("machine id %d, service id %d: %s and %s: %w",
+						m, s, f.Deployments[owners[n]].Name, d.Name, ErrOverlap)
+				}
+				owners[n] = i
+			}
+		}
+	}
+
+	t := &Table{NodeShift: NodeShift, NodeMask: NodeMask}
+	for n := 0; n < len(owners); n++ {
+		if owners[n] < 0 {
+			continue
+		}
+		// extend the route while the owner and machine ID stay the same
+		end := n
+		for end+1 < len(owners) && owners[end+1] == owners[n] &&
+			(end+1)>>dxyflake.BitLenServiceID == n>>dxyflake.BitLenServiceID {
+			end++
+		}
+
+		d := f.Deployments[owners[n]]
+		t.Routes = append(t.Routes, Route{
+			NodeMin:    n,
+			NodeMax:    end,
+			MachineID:  uint16(n >> dxyflake.BitLenServiceID),
+			ServiceMin: uint16(n & (1<<dxyflake.BitLenServiceID - 1)),
+			ServiceMax: uint16(end & (1<<dxyflake.BitLenServiceID - 1)),
+			Deployment: d.Name,
+			Region:     d.Region,
+			Shard:      d.Shard,
+		})
+		n = end
+	}
+	return t, nil
+}
+
+// Lookup returns the route of the node that issued id.
+func (t *Table) Lookup(id dxyflake.ID) (Route, bool) {
+	n := int(int64(id)>>NodeShift) & NodeMask
+	i := sort.Search(len(t.Routes), func(i int) bool { return t.Routes[i].NodeMax >= n })
+	if i < len(t.Routes) && t.Routes[i].NodeMin <= n {
+		return t.Routes[i], true
+	}
+	return Route{}, false
+}
+
+// JSON returns the table as indented JSON for gateways.
+func (t *Table) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
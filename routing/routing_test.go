@@ -0,0 +1,104 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+var fleet = Fleet{Deployments: []Deployment{
+	{Name: "orders-eu", Region: "eu", Shard: "s1", MachineIDs: []uint16{0, 1}},
+	{Name: "orders-us", Region: "us", Shard: "s2", MachineIDs: []uint16{2}, ServiceIDs: []uint16{0, 1, 2, 5}},
+	{Name: "billing-us", Region: "us", Shard: "s3", MachineIDs: []uint16{2}, ServiceIDs: []uint16{3, 4}},
+}}
+
+func TestBuild(t *testing.T) {
+	table, err := Build(fleet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Route{
+		{0, 31, 0, 0, 31, "orders-eu", "eu", "s1"},
+		{32, 63, 1, 0, 31, "orders-eu", "eu", "s1"},
+		{64, 66, 2, 0, 2, "orders-us", "us", "s2"},
+		{67, 68, 2, 3, 4, "billing-us", "us", "s3"},
+		{69, 69, 2, 5, 5, "orders-us", "us", "s2"},
+	}
+	if len(table.Routes) != len(want) {
+		t.Fatalf("unexpected routes: %+v", table.Routes)
+	}
+	for i := range want {
+		if table.Routes[i] != want[i] {
+			t.Errorf("route %d = %+v, want %+v", i, table.Routes[i], want[i])
+		}
+	}
+
+	b, err := table.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Table
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.NodeShift != 12 || decoded.NodeMask != 1023 || len(decoded.Routes) != len(want) {
+		t.Errorf("unexpected decoded table: %+v", decoded)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	table, err := Build(fleet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		machineID, serviceID uint16
+		want                 string
+	}{
+		{1, 17, "orders-eu"},
+		{2, 4, "billing-us"},
+		{2, 5, "orders-us"},
+		{2, 6, ""},
+		{31, 31, ""},
+	}
+	for _, tt := range tests {
+		var st dxyflake.Settings
+		st.Init(tt.machineID, tt.serviceID)
+		id, err := dxyflake.NewDxyflake(st).NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, ok := table.Lookup(id)
+		if ok != (tt.want != "") || r.Deployment != tt.want {
+			t.Errorf("Lookup(%d/%d) = %q, %v, want %q", tt.machineID, tt.serviceID, r.Deployment, ok, tt.want)
+		}
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		fleet Fleet
+		want  error
+	}{
+		{"overlap", Fleet{Deployments: []Deployment{
+			{Name: "a", MachineIDs: []uint16{1}},
+			{Name: "b", MachineIDs: []uint16{1}, ServiceIDs: []uint16{3}},
+		}}, ErrOverlap},
+		{"machine id", Fleet{Deployments: []Deployment{{Name: "a", MachineIDs: []uint16{32}}}}, ErrOutOfRange},
+		{"service id", Fleet{Deployments: []Deployment{{Name: "a", MachineIDs: []uint16{1}, ServiceIDs: []uint16{32}}}}, ErrOutOfRange},
+		{"no name", Fleet{Deployments: []Deployment{{MachineIDs: []uint16{1}}}}, ErrNoName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Build(tt.fleet); !errors.Is(err, tt.want) {
+				t.Errorf("Build() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
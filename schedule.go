@@ -0,0 +1,28 @@
+package dxyflake
+
+import (
+	"context"
+	"time"
+)
+
+// NextIDAfter waits until the dxyflake's clock reaches the time unit of t
+// and issues an ID, so that the timestamp of the ID is not earlier than t
+// truncated to the time unit, e.g. an external commit time. It returns
+// ctx.Err() if ctx is done first.
+func (df *dxyflake) NextIDAfter(ctx context.Context, t time.Time) (ID, error) {
+	tick := df.layout.Tick(t)
+	for {
+		now := df.now()
+		current := df.layout.Tick(now)
+		if current >= tick {
+			break
+		}
+		if err := sleepContext(ctx, df.clock, df.layout.TickTime(tick).Sub(now)); err != nil {
+			return 0, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return df.NextID()
+}
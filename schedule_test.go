@@ -0,0 +1,40 @@
+package dxyflake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNextIDAfter(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	gen := NewDxyflake(st)
+	l := gen.Layout()
+
+	at := time.Now().Add(50 * time.Millisecond)
+	id, err := gen.NextIDAfter(context.Background(), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tick := Decompose(id)["time"]; tick < l.Tick(at) {
+		t.Errorf("got tick %d before %d", tick, l.Tick(at))
+	}
+	if time.Now().Before(at.Truncate(10 * time.Millisecond)) {
+		t.Error("returned before the time unit of t")
+	}
+
+	start := time.Now()
+	if _, err := gen.NextIDAfter(context.Background(), start.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("waited %v for a past time", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := gen.NextIDAfter(ctx, time.Now().Add(time.Hour)); err != context.DeadlineExceeded {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// These errors are returned by SelfTest.
+var (
+	ErrNotIncreasing = errors.New("ids are not increasing")
+	ErrClockFrozen   = errors.New("clock does not advance")
+)
+
+// selfTestBurst is the number of IDs SelfTest issues.
+const selfTestBurst = 1000
+
+// SelfTestReport is the outcome of SelfTest. Rate is the number of IDs per
+// second the burst was issued at.
+type SelfTestReport struct {
+	IDs      int
+	Duration time.Duration
+	Rate     float64
+}
+
+// SelfTest issues a burst of IDs, checks that they increase and carry the
+// machine ID and service ID of the dxyflake, measures the rate they were
+// issued at and checks that the clock advances over two time units. It is
+// meant to run at startup, before a service reports ready. The IDs are
+// discarded.
+func (df *dxyflake) SelfTest() (SelfTestReport, error) {
+	var r SelfTestReport
+	ids := make([]ID, selfTestBurst)
+	before := df.clock.Now()
+
+	start := time.Now()
+	for i := range ids {
+		id, err := df.NextID()
+		if err != nil {
+			return r, err
+		}
+		ids[i] = id
+	}
+	r.IDs = len(ids)
+	r.Duration = time.Since(start)
+	if r.Duration > 0 {
+		r.Rate = float64(r.IDs) / r.Duration.Seconds()
+	}
+
+	for i, id := range ids {
+		if i > 0 && id <= ids[i-1] {
+			return r, fmt.Errorf("%w: %d after %d", ErrNotIncreasing, id, ids[i-1])
+		}
+		parts := df.layout.Decompose(id)
+		if parts["machine-id"] != int64(df.machineID) || parts["service-id"] != int64(df.serviceID) {
+			return r, fmt.Errorf("id %d has machine id %d and service id %d, want %d and %d",
+				id, parts["machine-id"], parts["service-id"], df.machineID, df.serviceID)
+		}
+	}
+
+	df.clock.Sleep(2 * df.layout.Unit)
+	if after := df.clock.Now(); df.layout.Tick(after) <= df.layout.Tick(before) {
+		return r, fmt.Errorf("%w: %s, then %s", ErrClockFrozen, before.Format(time.RFC3339Nano), after.Format(time.RFC3339Nano))
+	}
+	return r, nil
+}
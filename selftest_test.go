@@ -0,0 +1,36 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type frozenClock struct {
+	now time.Time
+}
+
+func (c frozenClock) Now() time.Time        { return c.now }
+func (c frozenClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func TestSelfTest(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	r, err := NewDxyflake(st).SelfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.IDs != selfTestBurst || r.Duration <= 0 || r.Rate <= 0 {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	st.Clock = &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if _, err := NewDxyflake(st).SelfTest(); err != nil {
+		t.Errorf("stepped clock: %v", err)
+	}
+
+	st.Clock = frozenClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if _, err := NewDxyflake(st).SelfTest(); !errors.Is(err, ErrClockFrozen) {
+		t.Errorf("frozen clock: got %v", err)
+	}
+}
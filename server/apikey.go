@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnauthorized classifies requests without a valid API key.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// APIKeyHeader is the request header carrying an API key, as an
+// alternative to "Authorization: Bearer <key>".
+const APIKeyHeader = "X-API-Key"
+
+// APIKey is a client of the server, known by the hash of its key, see
+// HashAPIKey. The key itself is never stored.
+//
+// Name identifies the client, in Access.Identity and in audit records.
+//
+// Tenants and ServiceIDs restrict the tenants and service IDs the key may
+// ask for IDs of, "" being the default tenant. Empty lists allow all.
+// ServiceIDs and Quota only apply to requests issuing IDs, not to
+// decompose lookups.
+//
+// Quota caps the IDs per second the key may ask for, allowing bursts of
+// up to a second's worth. If Quota is 0, the key is not limited.
+type APIKey struct {
+	Name       string
+	Hash       string
+	Tenants    []string
+	ServiceIDs []uint16
+	Quota      int
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of key. API keys are
+// random and long, so a fast hash is as good as a password hash for them.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKey returns a random API key and its hash.
+func NewAPIKey() (key, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = "dxy_" + hex.EncodeToString(b)
+	return key, HashAPIKey(key), nil
+}
+
+// APIKeyAuth authenticates requests by API key and authorizes them by the
+// tenants, service IDs and quota of the key.
+//
+// The keys are looked up on every request, so a function backed by a
+// reloading configuration rotates keys without a restart, see
+// config.Reloader.APIKeys.
+type APIKeyAuth struct {
+	keys func() []APIKey
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewAPIKeyAuth returns an APIKeyAuth with the keys returned by keys.
+func NewAPIKeyAuth(keys func() []APIKey) *APIKeyAuth {
+	return &APIKeyAuth{keys: keys, buckets: make(map[string]*bucket)}
+}
+
+// lookup returns the key with the hash of key. Every stored hash is
+// compared in constant time, so the time taken tells nothing about how
+// close key came to any of them.
+func (a *APIKeyAuth) lookup(key string) (APIKey, bool) {
+	hash := []byte(HashAPIKey(key))
+	var found APIKey
+	ok := false
+	for _, k := range a.keys() {
+		if subtle.ConstantTimeCompare(hash, []byte(strings.ToLower(k.Hash))) == 1 {
+			found, ok = k, true
+		}
+	}
+	return found, ok
+}
+
+// requestKey returns the API key of r.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// Middleware refuses requests without a valid API key and passes the
+// others on with the name of their key as identity, see Identify.
+func (a *APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k, ok := a.lookup(requestKey(r))
+		if !ok {
+			e := NewError(CodeUnauthorized, "missing or unknown API key")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dxyflake"`)
+			writeJSON(w, e.Status(), e)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), k.Name)))
+	})
+}
+
+// Authorize implements Authorizer for requests that passed Middleware.
+func (a *APIKeyAuth) Authorize(ctx context.Context, acc Access) error {
+	var k APIKey
+	ok := false
+	for _, key := range a.keys() {
+		if key.Name == acc.Identity {
+			k, ok = key, true
+			break
+		}
+	}
+	if !ok {
+		return NewError(CodeUnauthorized, "unknown API key "+acc.Identity)
+	}
+	if len(k.Tenants) > 0 && !containsString(k.Tenants, acc.Tenant) {
+		return fmt.Errorf("API key %s may not use tenant %q", k.Name, acc.Tenant)
+	}
+	if len(k.ServiceIDs) > 0 && acc.Issue && (acc.ServiceID == nil || !containsUint16(k.ServiceIDs, *acc.ServiceID)) {
+		return fmt.Errorf("API key %s may not use this service", k.Name)
+	}
+	if k.Quota > 0 && acc.Issue && !a.take(k, acc.Count) {
+		return fmt.Errorf("API key %s: %w of %d IDs per second", k.Name, ErrQuotaExceeded, k.Quota)
+	}
+	return nil
+}
+
+// take takes n IDs from the quota of k.
+func (a *APIKeyAuth) take(k APIKey, n int) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	b, ok := a.buckets[k.Name]
+	if !ok || b.rate != float64(k.Quota) {
+		// new or changed by a reload
+		b = &bucket{rate: float64(k.Quota), tokens: float64(k.Quota), last: time.Now()}
+		a.buckets[k.Name] = b
+	}
+	return b.take(float64(n), time.Now())
+}
+
+// bucket is a token bucket refilled at rate tokens per second, holding at
+// most a second's worth.
+type bucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) take(n float64, now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	if n > b.tokens {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint16(list []uint16, n uint16) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
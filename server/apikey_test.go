@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	shop, shopHash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(shop, "dxy_") || shopHash != HashAPIKey(shop) {
+		t.Fatalf("unexpected key %q, hash %q", shop, shopHash)
+	}
+
+	var mutex sync.Mutex
+	keys := []APIKey{
+		{Name: "shop", Hash: shopHash, Quota: 5},
+		{Name: "billing", Hash: strings.ToUpper(HashAPIKey("billing-key")), ServiceIDs: []uint16{3}},
+		{Name: "legacy", Hash: HashAPIKey("legacy-key"), Tenants: []string{"legacy"}},
+	}
+	auth := NewAPIKeyAuth(func() []APIKey {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return keys
+	})
+	h := auth.Middleware(newTestServer(t, Options{Authorizer: auth}))
+
+	request := func(target string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, tt := range []struct {
+		name   string
+		target string
+		header []string
+		status int
+	}{
+		{"no key", "/v1/id", nil, http.StatusUnauthorized},
+		{"unknown key", "/v1/id", []string{APIKeyHeader, "guess"}, http.StatusUnauthorized},
+		{"bearer", "/v1/id", []string{"Authorization", "Bearer " + shop}, http.StatusOK},
+		{"decompose", "/v1/decompose/29829153948057600", []string{APIKeyHeader, shop}, http.StatusOK},
+		{"decompose again", "/decompose/29829153948057600", []string{APIKeyHeader, shop}, http.StatusOK},
+		{"header", "/v1/ids?count=4", []string{APIKeyHeader, shop}, http.StatusOK},
+		{"quota", "/v1/ids?count=4", []string{APIKeyHeader, shop}, http.StatusTooManyRequests},
+		{"service", "/v1/ids?count=10", []string{APIKeyHeader, "billing-key"}, http.StatusOK},
+		{"tenant", "/v1/id", []string{APIKeyHeader, "legacy-key"}, http.StatusForbidden},
+	} {
+		w := request(tt.target, tt.header...)
+		if w.Code != tt.status {
+			t.Errorf("%s: %d %s, want %d", tt.name, w.Code, w.Body, tt.status)
+		}
+		if w.Code == http.StatusUnauthorized {
+			var e Error
+			if json.Unmarshal(w.Body.Bytes(), &e) != nil || e.Code != CodeUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("%s: unexpected response %s", tt.name, w.Body)
+			}
+		}
+	}
+
+	// the quota refills, and rotated keys take effect at once
+	time.Sleep(500 * time.Millisecond)
+	if w := request("/v1/ids?count=2", APIKeyHeader, shop); w.Code != http.StatusOK {
+		t.Errorf("quota not refilled: %d %s", w.Code, w.Body)
+	}
+	mutex.Lock()
+	keys = []APIKey{{Name: "shop", Hash: HashAPIKey("rotated")}}
+	mutex.Unlock()
+	if w := request("/v1/id", APIKeyHeader, shop); w.Code != http.StatusUnauthorized {
+		t.Errorf("old key accepted after rotation: %d", w.Code)
+	}
+	if w := request("/v1/id", APIKeyHeader, "rotated"); w.Code != http.StatusOK {
+		t.Errorf("new key refused: %d %s", w.Code, w.Body)
+	}
+}
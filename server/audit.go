@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// AuditRecord records one call of an issuing endpoint: who asked for how
+// many IDs, when, and which range was issued. First and Last are zero if
+// the call failed; Code is then the class of the error.
+type AuditRecord struct {
+	Time     time.Time   `json:"time"`
+	Identity string      `json:"identity,omitempty"`
+	Remote   string      `json:"remote"`
+	Tenant   string      `json:"tenant,omitempty"`
+	Endpoint string      `json:"endpoint"`
+	Count    int         `json:"count"`
+	First    dxyflake.ID `json:"first,omitempty"`
+	Last     dxyflake.ID `json:"last,omitempty"`
+	Status   int         `json:"status"`
+	Code     Code        `json:"code,omitempty"`
+}
+
+// AuditSink stores audit records. It is called synchronously, before the
+// response is written, so that no issued range goes unrecorded while the
+// sink keeps up.
+type AuditSink func(ctx context.Context, rec AuditRecord) error
+
+// JSONAuditSink returns an AuditSink writing one JSON object per line to w.
+func JSONAuditSink(w io.Writer) AuditSink {
+	var mutex sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(ctx context.Context, rec AuditRecord) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return enc.Encode(rec)
+	}
+}
+
+// Audit configures the audit log of the issuing endpoints, /v1/id and
+// /v1/ids:
+//
+// Sink receives the records. If Sink is nil, calls are not audited.
+//
+// Sample is the fraction of calls recorded.
+// If Sample is 0, every call is recorded.
+//
+// Always is the count from which calls are recorded regardless of Sample,
+// so that batch reservations are never sampled out.
+// If Always is 0, sampling applies to every call.
+//
+// OnError is called when the sink fails.
+// If OnError is nil, the error is logged.
+type Audit struct {
+	Sink    AuditSink
+	Sample  float64
+	Always  int
+	OnError func(err error)
+}
+
+// audit records a call of an issuing endpoint for count IDs that issued
+// first to last, or failed with e.
+func (s *Server) audit(r *http.Request, count int, first, last dxyflake.ID, e *Error) {
+	a := s.opts.Audit
+	if a.Sink == nil {
+		return
+	}
+	if a.Sample > 0 && a.Sample < 1 && (a.Always <= 0 || count < a.Always) && rand.Float64() >= a.Sample {
+		return
+	}
+
+	rec := AuditRecord{
+		Time:     time.Now().UTC(),
+		Identity: Identify(r),
+		Remote:   r.RemoteAddr,
+		Tenant:   tenant(r).name,
+		Endpoint: r.URL.Path,
+		Count:    count,
+		First:    first,
+		Last:     last,
+		Status:   http.StatusOK,
+	}
+	if e != nil {
+		rec.Status, rec.Code = e.Status(), e.Code
+	}
+	if err := a.Sink(r.Context(), rec); err != nil {
+		if a.OnError != nil {
+			a.OnError(err)
+		} else {
+			log.Printf("dxyflake: audit: %v", err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTestServer(t, Options{Audit: Audit{Sink: JSONAuditSink(&buf)}})
+
+	var many IDsResponse
+	w := get(s, "/v1/ids?count=3")
+	if err := json.Unmarshal(w.Body.Bytes(), &many); err != nil {
+		t.Fatal(err)
+	}
+	get(s, "/v1/id")
+	get(s, "/v1/meta")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d records: %s", len(lines), buf.String())
+	}
+	var rec AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Endpoint != "/v1/ids" || rec.Count != 3 || rec.Status != http.StatusOK || rec.Time.IsZero() {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+	if rec.First.String() != many.IDs[0] || rec.Last.String() != many.IDs[2] {
+		t.Errorf("unexpected range %v-%v, want %v", rec.First, rec.Last, many.IDs)
+	}
+}
+
+func TestAuditFailures(t *testing.T) {
+	var recs []AuditRecord
+	var sinkErr error
+	s := New(failingGenerator{ErrQuotaExceeded}, Options{
+		Audit: Audit{
+			Sink: func(ctx context.Context, rec AuditRecord) error {
+				recs = append(recs, rec)
+				return errors.New("sink down")
+			},
+			OnError: func(err error) { sinkErr = err },
+		},
+		Authorizer: AuthorizerFunc(func(ctx context.Context, a Access) error {
+			if a.Identity != "billing" {
+				return errors.New("billing only")
+			}
+			return nil
+		}),
+	})
+
+	get(s, "/v1/ids?count=2")
+	r := httptest.NewRequest(http.MethodGet, "/v1/id", nil)
+	s.ServeHTTP(httptest.NewRecorder(), r.WithContext(WithIdentity(r.Context(), "billing")))
+
+	if len(recs) != 2 {
+		t.Fatalf("%d records", len(recs))
+	}
+	if recs[0].Code != CodeForbidden || recs[0].Count != 2 || recs[0].First != 0 {
+		t.Errorf("unexpected record of refused call: %+v", recs[0])
+	}
+	if recs[1].Code != CodeQuotaExceeded || recs[1].Identity != "billing" || recs[1].Status != http.StatusTooManyRequests {
+		t.Errorf("unexpected record of failed call: %+v", recs[1])
+	}
+	if sinkErr == nil {
+		t.Error("sink error not reported")
+	}
+}
+
+func TestAuditSampling(t *testing.T) {
+	n := 0
+	s := newTestServer(t, Options{Audit: Audit{
+		Sink:   func(ctx context.Context, rec AuditRecord) error { n++; return nil },
+		Sample: 1e-9,
+		Always: 5,
+	}})
+	for i := 0; i < 20; i++ {
+		get(s, "/v1/id")
+	}
+	if n != 0 {
+		t.Errorf("%d of 20 single calls recorded", n)
+	}
+	get(s, "/v1/ids?count=5")
+	if n != 1 {
+		t.Error("batch call sampled out")
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrForbidden classifies requests an Authorizer refuses.
+var ErrForbidden = errors.New("forbidden")
+
+// Access describes a request to an Authorizer.
+//
+// Identity names the client, see Identify. Tenant is the tenant the request
+// was routed to, "" for the default one. Endpoint is the path of the
+// request, e.g. "/v1/ids". Count is the number of IDs asked for or given,
+// 0 for /v1/meta. Issue is whether the request issues IDs, false for
+// decompose lookups and /v1/meta. ServiceID and Service are the service ID of the
+// generator of the tenant and its name, if the generator describes itself.
+type Access struct {
+	Identity  string
+	Tenant    string
+	Endpoint  string
+	Count     int
+	Issue     bool
+	ServiceID *uint16
+	Service   string
+	Request   *http.Request
+}
+
+// Authorizer decides whether a request may proceed. It is called once per
+// request, after the request is parsed and before any ID is issued. An
+// error refuses the request: an *Error or an error wrapping one of the
+// errors of this package, such as ErrQuotaExceeded, is served as by
+// Classify, any other error as CodeForbidden with its message.
+type Authorizer interface {
+	Authorize(ctx context.Context, a Access) error
+}
+
+// AuthorizerFunc is an Authorizer function.
+type AuthorizerFunc func(ctx context.Context, a Access) error
+
+// Authorize implements Authorizer.
+func (f AuthorizerFunc) Authorize(ctx context.Context, a Access) error {
+	return f(ctx, a)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx naming the client identity, for
+// authentication middleware in front of a Server.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// Identify returns the identity of the client of r: the one set with
+// WithIdentity, or else the common name of its verified TLS client
+// certificate, or else "".
+func Identify(r *http.Request) string {
+	if identity, ok := r.Context().Value(identityKey{}).(string); ok {
+		return identity
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	return ""
+}
+
+// authorize asks the Authorizer of s whether r may ask for count IDs, to
+// issue them or not, and returns the error to respond with if not.
+func (s *Server) authorize(r *http.Request, count int, issue bool) *Error {
+	if s.opts.Authorizer == nil {
+		return nil
+	}
+	t := tenant(r)
+	a := Access{
+		Identity: Identify(r),
+		Tenant:   t.name,
+		Endpoint: r.URL.Path,
+		Count:    count,
+		Issue:    issue,
+		Request:  r,
+	}
+	if g, ok := t.Generator.(describer); ok {
+		if a.ServiceID = g.Describe().ServiceID; a.ServiceID != nil {
+			a.Service = t.ServiceNames.Name(*a.ServiceID)
+		}
+	}
+
+	err := s.opts.Authorizer.Authorize(r.Context(), a)
+	if err == nil {
+		return nil
+	}
+	if e := Classify(err); e.Code != CodeInternal {
+		return e
+	}
+	return NewError(CodeForbidden, err.Error())
+}
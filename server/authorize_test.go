@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestAuthorizer(t *testing.T) {
+	var got []Access
+	auth := AuthorizerFunc(func(ctx context.Context, a Access) error {
+		got = append(got, a)
+		if a.Count > 1 && a.Identity != "billing" {
+			return fmt.Errorf("%s may not reserve batches", a.Identity)
+		}
+		if a.Identity == "" {
+			return NewError(CodeInvalidRequest, "who are you")
+		}
+		return nil
+	})
+	s := newTestServer(t, Options{Authorizer: auth, ServiceNames: dxyflake.ServiceNames{3: "orders"}})
+
+	request := func(target, identity string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if identity != "" {
+			r = r.WithContext(WithIdentity(r.Context(), identity))
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := request("/v1/id", "shop"); w.Code != http.StatusOK {
+		t.Errorf("single ID refused: %d %s", w.Code, w.Body)
+	}
+	if w := request("/v1/ids?count=5", "shop"); w.Code != http.StatusForbidden {
+		t.Errorf("batch allowed: %d %s", w.Code, w.Body)
+	}
+	if w := request("/v1/ids?count=5", "billing"); w.Code != http.StatusOK {
+		t.Errorf("batch refused: %d %s", w.Code, w.Body)
+	}
+	if w := request("/v1/meta", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("error of the authorizer not served: %d %s", w.Code, w.Body)
+	}
+
+	a := got[1]
+	if a.Identity != "shop" || a.Endpoint != "/v1/ids" || a.Count != 5 || a.Tenant != "" {
+		t.Errorf("unexpected access: %+v", a)
+	}
+	if a.ServiceID == nil || *a.ServiceID != 3 || a.Service != "orders" {
+		t.Errorf("unexpected service: %v %q", a.ServiceID, a.Service)
+	}
+	if got[3].Count != 0 {
+		t.Errorf("unexpected meta count: %d", got[3].Count)
+	}
+	if n := s.Errors()[CodeForbidden]; n != 1 {
+		t.Errorf("%d forbidden responses counted", n)
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake/analyze"
+)
+
+// maxBodyPerID bounds the request body: no encoding of an ID, quoted and
+// separated, takes more bytes.
+const maxBodyPerID = 72
+
+// DecomposeRequest is the body of POST /v1/decompose. IDs may be JSON
+// strings in any encoding or JSON numbers. If Encoding is "", it is
+// detected per ID as by analyze.Parse, which prefers base62 for strings
+// valid in several encodings.
+//
+// A text/plain body is a list of IDs separated by white space, so that
+// lists can be pasted as they are; the encoding is then taken from the
+// "encoding" query parameter.
+type DecomposeRequest struct {
+	IDs      []Input `json:"ids"`
+	Encoding string  `json:"encoding,omitempty"`
+}
+
+// Input is an ID as given in a request: a JSON string or number.
+type Input string
+
+// UnmarshalJSON accepts a JSON string or number.
+func (in *Input) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, (*string)(in))
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*in = Input(n)
+	return nil
+}
+
+// Parts are the decomposed parts of one ID of a DecomposeRequest: the
+// fields of the JSON documents of dxyflake.Parts, see
+// dxyflake.PartsJSONSchema, with the input, its encoding and the namespace
+// of the service ID. If the input is not a valid ID, Error is set and the
+// parts are zero.
+type Parts struct {
+	Input     string `json:"input"`
+	Error     string `json:"error,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Encoding  string `json:"encoding,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Tick      int64  `json:"tick"`
+	MachineID int64  `json:"machine-id"`
+	ServiceID int64  `json:"service-id"`
+	Namespace string `json:"namespace,omitempty"`
+	Sequence  int64  `json:"sequence"`
+}
+
+// DecomposeResponse is the response to POST /v1/decompose, with the parts
+// in the order of the request.
+type DecomposeResponse struct {
+	Results []Parts `json:"results"`
+}
+
+func (s *Server) decompose(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	limit := int64(s.opts.MaxBatch)*maxBodyPerID + 1024
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		s.writeError(w, NewError(CodeInvalidRequest, err.Error()))
+		return
+	}
+	if int64(len(body)) > limit {
+		s.writeError(w, NewError(CodeBatchTooLarge, "request body too large"))
+		return
+	}
+
+	var req DecomposeRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/plain" {
+		for _, f := range strings.Fields(string(body)) {
+			req.IDs = append(req.IDs, Input(f))
+		}
+		req.Encoding = r.URL.Query().Get("encoding")
+	} else {
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.writeError(w, NewError(CodeInvalidRequest, "invalid request: "+err.Error()))
+			return
+		}
+	}
+	if len(req.IDs) > s.opts.MaxBatch {
+		s.writeError(w, NewError(CodeBatchTooLarge,
+			fmt.Sprintf("%d IDs exceed the batch limit of %d", len(req.IDs), s.opts.MaxBatch)))
+		return
+	}
+	if req.Encoding != "" && !knownEncoding(req.Encoding) {
+		s.writeError(w, NewError(CodeInvalidRequest, fmt.Sprintf("unknown encoding %q", req.Encoding)))
+		return
+	}
+	if e := s.authorize(r, len(req.IDs), false); e != nil {
+		s.writeError(w, e)
+		return
+	}
+
+	resp := DecomposeResponse{Results: make([]Parts, len(req.IDs))}
+	for i, in := range req.IDs {
+		resp.Results[i] = parts(tenant(r), string(in), req.Encoding)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decomposeID serves GET /v1/decompose/<id>, answering with the Parts of
+// one ID. The encoding query parameter works like Encoding of a
+// DecomposeRequest.
+func (s *Server) decomposeID(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	in := r.URL.Path[strings.LastIndexByte(r.URL.Path, '/')+1:]
+	encoding := r.URL.Query().Get("encoding")
+	if encoding != "" && !knownEncoding(encoding) {
+		s.writeError(w, NewError(CodeInvalidRequest, fmt.Sprintf("unknown encoding %q", encoding)))
+		return
+	}
+	if e := s.authorize(r, 1, false); e != nil {
+		s.writeError(w, e)
+		return
+	}
+	p := parts(tenant(r), in, encoding)
+	if p.Error != "" {
+		s.writeError(w, NewError(CodeInvalidRequest, p.Error))
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func knownEncoding(name string) bool {
+	for _, e := range analyze.Encodings {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parts(t Tenant, in, encoding string) Parts {
+	l := t.Layout
+	id, enc, err := analyze.ParseLayout(in, encoding, l)
+	if err != nil {
+		return Parts{Input: in, Error: err.Error()}
+	}
+	p := l.DecomposeParts(id)
+	return Parts{
+		Input:     in,
+		ID:        id.String(),
+		Encoding:  enc,
+		Time:      p.Timestamp.UTC().Format(time.RFC3339Nano),
+		Tick:      p.Time,
+		MachineID: int64(p.MachineID),
+		ServiceID: int64(p.ServiceID),
+		Namespace: t.ServiceNames.Name(p.ServiceID),
+		Sequence:  int64(p.Sequence),
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func newTestServer(t *testing.T, opts Options) *Server {
+	var st dxyflake.Settings
+	st.Init(1, 3)
+	gen := dxyflake.NewDxyflake(st)
+	if gen == nil {
+		t.Fatal("dxyflake not created")
+	}
+	return New(gen, opts)
+}
+
+func post(s *Server, contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/v1/decompose", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestDecompose(t *testing.T) {
+	s := newTestServer(t, Options{ServiceNames: dxyflake.ServiceNames{3: "orders"}})
+	at := time.Date(2021, 10, 14, 8, 52, 51, 580000000, time.UTC)
+	id, err := dxyflake.DefaultLayout.Compose(at, 1, 3, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"ids": ["` + id.Base62() + `", ` + id.String() + `, "not an id"]}`
+	w := post(s, "application/json", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	var resp DecomposeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("got %d results", len(resp.Results))
+	}
+	want := Parts{
+		Input:     id.Base62(),
+		ID:        id.String(),
+		Encoding:  "base62",
+		Time:      "2021-10-14T08:52:51.58Z",
+		Tick:      dxyflake.DefaultLayout.Tick(at),
+		MachineID: 1,
+		ServiceID: 3,
+		Namespace: "orders",
+		Sequence:  7,
+	}
+	if resp.Results[0] != want {
+		t.Errorf("got %+v, want %+v", resp.Results[0], want)
+	}
+	var lib map[string]interface{}
+	b, _ := json.Marshal(dxyflake.DecomposeParts(id))
+	json.Unmarshal(b, &lib)
+	var got map[string]interface{}
+	b, _ = json.Marshal(resp.Results[0])
+	json.Unmarshal(b, &got)
+	for k, v := range lib {
+		if got[k] != v {
+			t.Errorf("%s = %v, dxyflake.Parts has %v", k, got[k], v)
+		}
+	}
+	if r := resp.Results[1]; r.ID != id.String() || r.Encoding != "decimal" {
+		t.Errorf("unexpected number result %+v", r)
+	}
+	if r := resp.Results[2]; r.Error == "" || r.ID != "" {
+		t.Errorf("invalid input not reported: %+v", r)
+	}
+
+	// base58 strings are valid base62 too, so ask for base58
+	w = post(s, "application/json", `{"ids": ["`+id.Base58()+`"], "encoding": "base58"}`)
+	resp = DecomposeResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if r := resp.Results[0]; r.ID != id.String() || r.Encoding != "base58" {
+		t.Errorf("unexpected base58 result %+v", r)
+	}
+}
+
+func TestDecomposeText(t *testing.T) {
+	s := newTestServer(t, Options{})
+	w := post(s, "text/plain; charset=utf-8", "484514077880320\n 475370495148032\r\n")
+	var resp DecomposeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].ServiceID != 3 || resp.Results[1].Time != "2021-10-14T02:49:31.58Z" {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestDecomposeErrors(t *testing.T) {
+	s := newTestServer(t, Options{MaxBatch: 2})
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/decompose", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET: got status %d", w.Code)
+	}
+
+	for body, status := range map[string]int{
+		`{"ids": [1, 2, 3]}`:                             http.StatusRequestEntityTooLarge,
+		`{"ids": [1], "encoding": "base99"}`:             http.StatusBadRequest,
+		`{"ids": [true]}`:                                http.StatusBadRequest,
+		`{"ids": [1`:                                     http.StatusBadRequest,
+		`{"ids": ["` + strings.Repeat("1", 5000) + `"]}`: http.StatusRequestEntityTooLarge,
+	} {
+		w := post(s, "application/json", body)
+		if w.Code != status {
+			t.Errorf("%.40s: got status %d, want %d", body, w.Code, status)
+		}
+		var e Error
+		if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil || e.Message == "" || e.Status() != status {
+			t.Errorf("%.40s: no error body: %s", body, w.Body)
+		}
+	}
+}
+
+func TestDecomposeID(t *testing.T) {
+	s := newTestServer(t, Options{ServiceNames: dxyflake.ServiceNames{3: "orders"}})
+	id, err := dxyflake.DefaultLayout.Compose(time.Date(2021, 10, 14, 8, 52, 51, 580000000, time.UTC), 1, 3, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/v1/decompose/" + id.String(), "/decompose/" + id.Base62() + "?encoding=base62"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: got status %d: %s", path, w.Code, w.Body)
+		}
+		var p Parts
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != id.String() || p.Namespace != "orders" || p.Sequence != 7 {
+			t.Errorf("%s: unexpected parts %+v", path, p)
+		}
+	}
+
+	for path, code := range map[string]int{
+		"/decompose/not-an-id":               http.StatusBadRequest,
+		"/decompose/1?encoding=base99":       http.StatusBadRequest,
+		"/v1/decompose/" + id.String() + "/": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("%s: got status %d, want %d", path, w.Code, code)
+		}
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/decompose/1", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: got status %d", w.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/handoff"
+)
+
+// ErrDraining is returned for ID requests to a draining server.
+var ErrDraining = errors.New("server is draining")
+
+// DrainOptions configures Drain:
+//
+// Store persists the high-water marks of the drained generators, so that
+// their successors wait for them, see package handoff. If Store is nil,
+// the marks are only returned.
+//
+// Release gives the machine IDs back, e.g. by releasing their leases. It is
+// called once all marks are saved, and not at all if saving one fails.
+type DrainOptions struct {
+	Store   handoff.Store
+	Release func() error
+}
+
+// begin registers an ID request, unless the server is draining.
+func (s *Server) begin() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.draining {
+		return false
+	}
+	s.inflight++
+	return true
+}
+
+// end unregisters an ID request.
+func (s *Server) end() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.inflight--
+	if s.draining && s.inflight == 0 {
+		close(s.idle)
+	}
+}
+
+// Drain stops the server for a handoff, in this order: new ID requests
+// are refused with CodeUnavailable, the ID requests in flight are let
+// finish, the generators of all tenants that can be drained are drained
+// and their high-water marks saved, and finally the machine IDs are
+// released. No ID can thus be issued past a saved mark nor after the
+// machine ID was given back. Decompose and meta requests are still served.
+//
+// Drain returns the marks by machine ID and service ID. If ctx is done
+// before the requests in flight finish, Drain returns its error without
+// draining the generators; calling it again resumes.
+func (s *Server) Drain(ctx context.Context, opts DrainOptions) ([]dxyflake.Mark, error) {
+	s.mutex.Lock()
+	if !s.draining {
+		s.draining = true
+		if s.inflight == 0 {
+			close(s.idle)
+		}
+	}
+	s.mutex.Unlock()
+
+	select {
+	case <-s.idle:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var marks []dxyflake.Mark
+	for _, t := range s.tenants {
+		if d, ok := t.Generator.(handoff.Drainer); ok {
+			marks = append(marks, d.Drain())
+		}
+	}
+	sort.Slice(marks, func(i, j int) bool {
+		if marks[i].MachineID != marks[j].MachineID {
+			return marks[i].MachineID < marks[j].MachineID
+		}
+		return marks[i].ServiceID < marks[j].ServiceID
+	})
+	if opts.Store != nil {
+		for _, mark := range marks {
+			if err := opts.Store.SaveMark(ctx, mark); err != nil {
+				return marks, err
+			}
+		}
+	}
+	if opts.Release != nil {
+		if err := opts.Release(); err != nil {
+			return marks, err
+		}
+	}
+	return marks, nil
+}
+
+// DrainOnSignal waits for SIGTERM or SIGINT, as sent by Kubernetes on a
+// rollout, then drains s and shuts srv down. It returns the error of
+// Drain or of the shutdown; ctx limits both. If ctx is done before a
+// signal arrives, it returns the error of ctx without draining.
+func (s *Server) DrainOnSignal(ctx context.Context, srv *http.Server, opts DrainOptions) ([]dxyflake.Mark, error) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sig)
+
+	select {
+	case <-sig:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	marks, err := s.Drain(ctx, opts)
+	if err != nil {
+		return marks, err
+	}
+	return marks, srv.Shutdown(ctx)
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/handoff"
+)
+
+// gatedGenerator blocks NextID until the gate is opened.
+type gatedGenerator struct {
+	gen interface {
+		NextID() (dxyflake.ID, error)
+		Drain() dxyflake.Mark
+	}
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (g *gatedGenerator) NextID() (dxyflake.ID, error) {
+	g.entered <- struct{}{}
+	<-g.gate
+	return g.gen.NextID()
+}
+
+func (g *gatedGenerator) Drain() dxyflake.Mark {
+	return g.gen.Drain()
+}
+
+func TestDrain(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(4, 5)
+	gen := &gatedGenerator{gen: dxyflake.NewDxyflake(st), entered: make(chan struct{}), gate: make(chan struct{})}
+	s := New(gen, Options{})
+
+	inflight := make(chan int)
+	go func() { inflight <- get(s, "/v1/id").Code }()
+	<-gen.entered
+
+	store := handoff.NewMemoryStore()
+	released := make(chan bool, 1)
+	drained := make(chan []dxyflake.Mark)
+	go func() {
+		marks, err := s.Drain(context.Background(), DrainOptions{Store: store, Release: func() error {
+			_, saved, _ := store.LoadMark(context.Background(), 4, 5)
+			released <- saved
+			return nil
+		}})
+		if err != nil {
+			t.Error(err)
+		}
+		drained <- marks
+	}()
+
+	// new requests are refused while the one in flight is let finish
+	for draining := false; !draining; {
+		time.Sleep(time.Millisecond)
+		s.mutex.Lock()
+		draining = s.draining
+		s.mutex.Unlock()
+	}
+	if w := get(s, "/v1/id"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("request accepted while draining: %d", w.Code)
+	}
+	select {
+	case <-drained:
+		t.Fatal("drained with a request in flight")
+	default:
+	}
+	close(gen.gate)
+	if code := <-inflight; code != http.StatusOK {
+		t.Errorf("request in flight failed: %d", code)
+	}
+
+	marks := <-drained
+	if len(marks) != 1 || marks[0].MachineID != 4 || marks[0].ServiceID != 5 {
+		t.Errorf("unexpected marks: %+v", marks)
+	}
+	if !<-released {
+		t.Error("released before the mark was saved")
+	}
+	if w := get(s, "/v1/meta"); w.Code != http.StatusOK {
+		t.Errorf("meta refused while draining: %d", w.Code)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(4, 6)
+	gen := &gatedGenerator{gen: dxyflake.NewDxyflake(st), entered: make(chan struct{}), gate: make(chan struct{})}
+	s := New(gen, Options{})
+	go get(s, "/v1/id")
+	<-gen.entered
+	defer close(gen.gate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.Drain(ctx, DrainOptions{}); err != context.DeadlineExceeded {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
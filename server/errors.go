@@ -0,0 +1,149 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+// These errors classify failures of ID issuance. Generators and wrappers
+// around them return errors wrapping them, so that the server reports the
+// matching Code.
+var (
+	ErrQuotaExceeded     = errors.New("quota exceeded")
+	ErrSequenceExhausted = errors.New("sequence exhausted")
+	ErrLeaseLost         = errors.New("machine id lease lost")
+	ErrClockAnomaly      = errors.New("clock anomaly")
+)
+
+// Code is the machine-readable class of an error response.
+type Code string
+
+// These are the error codes, with the status code they are served with.
+const (
+	CodeInvalidRequest    Code = "invalid-request"    // 400
+	CodeUnauthorized      Code = "unauthorized"       // 401
+	CodeForbidden         Code = "forbidden"          // 403
+	CodeUnknownTenant     Code = "unknown-tenant"     // 404
+	CodeMethodNotAllowed  Code = "method-not-allowed" // 405
+	CodeBatchTooLarge     Code = "batch-too-large"    // 413
+	CodeQuotaExceeded     Code = "quota-exceeded"     // 429, retry after a second
+	CodeSequenceExhausted Code = "sequence-exhausted" // 503, retry after a time unit
+	CodeLeaseLost         Code = "lease-lost"         // 503, retry at another replica
+	CodeClockAnomaly      Code = "clock-anomaly"      // 503, retry after a second
+	CodeUnavailable       Code = "unavailable"        // 503, e.g. draining; retry at another replica
+	CodeTimeExhausted     Code = "time-exhausted"     // 500, the time part is used up
+	CodeInternal          Code = "internal"           // 500
+)
+
+type class struct {
+	status     int
+	retryable  bool
+	retryAfter time.Duration
+}
+
+var classes = map[Code]class{
+	CodeInvalidRequest:    {http.StatusBadRequest, false, 0},
+	CodeUnauthorized:      {http.StatusUnauthorized, false, 0},
+	CodeForbidden:         {http.StatusForbidden, false, 0},
+	CodeUnknownTenant:     {http.StatusNotFound, false, 0},
+	CodeMethodNotAllowed:  {http.StatusMethodNotAllowed, false, 0},
+	CodeBatchTooLarge:     {http.StatusRequestEntityTooLarge, false, 0},
+	CodeQuotaExceeded:     {http.StatusTooManyRequests, true, time.Second},
+	CodeSequenceExhausted: {http.StatusServiceUnavailable, true, 10 * time.Millisecond},
+	CodeLeaseLost:         {http.StatusServiceUnavailable, true, 0},
+	CodeClockAnomaly:      {http.StatusServiceUnavailable, true, time.Second},
+	CodeUnavailable:       {http.StatusServiceUnavailable, true, 0},
+	CodeTimeExhausted:     {http.StatusInternalServerError, false, 0},
+	CodeInternal:          {http.StatusInternalServerError, false, 0},
+}
+
+// Error is the body of every error response. Clients retry retryable
+// errors after RetryAfterMS milliseconds, with their own backoff if it is
+// 0; the Retry-After header carries the same delay rounded up to seconds.
+type Error struct {
+	Code         Code   `json:"code"`
+	Message      string `json:"error"`
+	Retryable    bool   `json:"retryable"`
+	RetryAfterMS int64  `json:"retry-after-ms,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// Status returns the HTTP status code of the error.
+func (e *Error) Status() int {
+	if c, ok := classes[e.Code]; ok {
+		return c.status
+	}
+	return http.StatusInternalServerError
+}
+
+// NewError returns the Error of code with message.
+func NewError(code Code, message string) *Error {
+	c := classes[code]
+	return &Error{
+		Code:         code,
+		Message:      message,
+		Retryable:    c.retryable,
+		RetryAfterMS: c.retryAfter.Milliseconds(),
+	}
+}
+
+// Classify returns the Error of an issuance error.
+func Classify(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, ErrUnauthorized):
+		return NewError(CodeUnauthorized, err.Error())
+	case errors.Is(err, ErrForbidden):
+		return NewError(CodeForbidden, err.Error())
+	case errors.Is(err, ErrQuotaExceeded), errors.Is(err, dxyflake.ErrRateLimited):
+		return NewError(CodeQuotaExceeded, err.Error())
+	case errors.Is(err, ErrSequenceExhausted):
+		return NewError(CodeSequenceExhausted, err.Error())
+	case errors.Is(err, ErrLeaseLost), errors.Is(err, lease.ErrExpired),
+		errors.Is(err, lease.ErrNotHeld), errors.Is(err, lease.ErrReleased):
+		return NewError(CodeLeaseLost, err.Error())
+	case errors.Is(err, ErrClockAnomaly):
+		return NewError(CodeClockAnomaly, err.Error())
+	case errors.Is(err, dxyflake.ErrDrained), errors.Is(err, dxyflake.ErrClosed),
+		errors.Is(err, ErrDraining):
+		return NewError(CodeUnavailable, err.Error())
+	case errors.Is(err, dxyflake.ErrOverTimeLimit):
+		return NewError(CodeTimeExhausted, err.Error())
+	}
+	return NewError(CodeInternal, err.Error())
+}
+
+// writeError writes e and counts it in the error statistics of s.
+func (s *Server) writeError(w http.ResponseWriter, e *Error) {
+	s.mutex.Lock()
+	s.errors[e.Code]++
+	s.mutex.Unlock()
+
+	if e.RetryAfterMS > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt((e.RetryAfterMS+999)/1000, 10))
+	}
+	writeJSON(w, e.Status(), e)
+}
+
+// Errors returns the number of error responses by code since the server
+// was created, to watch how the service degrades.
+func (s *Server) Errors() map[Code]uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	m := make(map[Code]uint64, len(s.errors))
+	for code, n := range s.errors {
+		m[code] = n
+	}
+	return m
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+type failingGenerator struct {
+	err error
+}
+
+func (g failingGenerator) NextID() (dxyflake.ID, error) {
+	return 0, g.err
+}
+
+func TestClassify(t *testing.T) {
+	for err, code := range map[error]Code{
+		fmt.Errorf("tenant orders: %w", ErrQuotaExceeded): CodeQuotaExceeded,
+		ErrSequenceExhausted:                              CodeSequenceExhausted,
+		fmt.Errorf("billing only: %w", ErrForbidden):      CodeForbidden,
+		lease.ErrExpired:                                  CodeLeaseLost,
+		fmt.Errorf("renew: %w", lease.ErrNotHeld):         CodeLeaseLost,
+		ErrClockAnomaly:                                   CodeClockAnomaly,
+		dxyflake.ErrDrained:                               CodeUnavailable,
+		dxyflake.ErrRateLimited:                           CodeQuotaExceeded,
+		dxyflake.ErrOverTimeLimit:                         CodeTimeExhausted,
+		errors.New("disk on fire"):                        CodeInternal,
+		NewError(CodeBatchTooLarge, "too many"):           CodeBatchTooLarge,
+	} {
+		if e := Classify(err); e.Code != code || e.Message == "" {
+			t.Errorf("%v: got %+v, want %s", err, e, code)
+		}
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	for _, c := range []struct {
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{ErrQuotaExceeded, http.StatusTooManyRequests, "1"},
+		{ErrSequenceExhausted, http.StatusServiceUnavailable, "1"},
+		{ErrLeaseLost, http.StatusServiceUnavailable, ""},
+		{dxyflake.ErrOverTimeLimit, http.StatusInternalServerError, ""},
+	} {
+		s := New(failingGenerator{c.err}, Options{})
+		for _, target := range []string{"/v1/id", "/v1/ids?count=3"} {
+			w := get(s, target)
+			if w.Code != c.status || w.Header().Get("Retry-After") != c.retryAfter {
+				t.Errorf("%v %s: got status %d, Retry-After %q", c.err, target, w.Code, w.Header().Get("Retry-After"))
+			}
+			var e Error
+			if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil || e.Code != Classify(c.err).Code {
+				t.Errorf("%v %s: unexpected body %s", c.err, target, w.Body)
+			}
+		}
+		if n := s.Errors()[Classify(c.err).Code]; n != 2 {
+			t.Errorf("%v: counted %d errors", c.err, n)
+		}
+	}
+}
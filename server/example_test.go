@@ -0,0 +1,33 @@
+package server_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/server"
+)
+
+func ExampleNew() {
+	var st dxyflake.Settings
+	st.Init(1, 2)
+	gen := dxyflake.NewDxyflake(st)
+
+	// In production: http.ListenAndServe(":8080", s)
+	s := server.New(gen, server.Options{ServiceNames: dxyflake.ServiceNames{4: "orders"}})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/decompose", "text/plain", strings.NewReader("29829153948057600"))
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	fmt.Print(string(b))
+	// Output:
+	// {"results":[{"input":"29829153948057600","id":"29829153948057600","encoding":"decimal","time":"2024-01-02T03:04:05Z","tick":7111824500,"machine-id":3,"service-id":4,"namespace":"orders","sequence":0}]}
+}
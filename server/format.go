@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These are the content types of ID responses, chosen by the Accept header
+// of the request. The first supported type listed in the header is used,
+// regardless of quality values; JSON is the default.
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeText    = "text/plain; charset=utf-8"
+	ContentTypeMsgpack = "application/msgpack"
+)
+
+// formats are the values of the format query parameter of ID responses.
+// The default is decimal.
+var formats = map[string]func(dxyflake.ID) string{
+	"decimal":     dxyflake.ID.String,
+	"base2":       dxyflake.ID.Base2,
+	"base32":      dxyflake.ID.Base32,
+	"crockford32": dxyflake.ID.Crockford32,
+	"base36":      dxyflake.ID.Base36,
+	"base58":      dxyflake.ID.Base58,
+	"base62":      dxyflake.ID.Base62,
+	"base64":      dxyflake.ID.Base64,
+	"hex":         dxyflake.ID.Hex,
+	"sortable":    dxyflake.ID.SortableString,
+	"envelope":    dxyflake.ID.Envelope,
+}
+
+// formatter returns the function formatting IDs as asked for by the format
+// query parameter of r.
+func (s *Server) formatter(r *http.Request) (func(dxyflake.ID) string, *Error) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		return dxyflake.ID.String, nil
+	}
+	if name == "signed-token" {
+		if len(s.opts.TokenKey) == 0 {
+			return nil, NewError(CodeInvalidRequest, "signed tokens are not enabled")
+		}
+		key := s.opts.TokenKey
+		return func(id dxyflake.ID) string { return id.SignedToken(key) }, nil
+	}
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	return nil, NewError(CodeInvalidRequest, fmt.Sprintf("unknown format %q", name))
+}
+
+// contentType returns the content type of the response to r.
+func contentType(r *http.Request) string {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json", "*/*", "application/*":
+			return ContentTypeJSON
+		case "text/plain", "text/*":
+			return ContentTypeText
+		case "application/msgpack", "application/x-msgpack":
+			return ContentTypeMsgpack
+		}
+	}
+	return ContentTypeJSON
+}
+
+// writeIDs writes the formatted IDs in the content type r asks for: as the
+// field key of a JSON object or a MessagePack map, holding a string or, if
+// many is true, an array of strings; or as plain text, one per line.
+func writeIDs(w http.ResponseWriter, r *http.Request, key string, ids []string, many bool) {
+	ct := contentType(r)
+	w.Header().Set("Content-Type", ct)
+	w.Header().Add("Vary", "Accept")
+
+	var b []byte
+	switch ct {
+	case ContentTypeText:
+		b = []byte(strings.Join(ids, "\n") + "\n")
+	case ContentTypeMsgpack:
+		b = append(b, 0x81) // map of one entry
+		b = appendMsgpackString(b, key)
+		if many {
+			b = appendMsgpackArrayHeader(b, len(ids))
+			for _, id := range ids {
+				b = appendMsgpackString(b, id)
+			}
+		} else {
+			b = appendMsgpackString(b, ids[0])
+		}
+	default:
+		var v interface{} = IDResponse{ID: ids[0]}
+		if many {
+			v = IDsResponse{IDs: ids}
+		}
+		b, _ = json.Marshal(v)
+		b = append(b, '\n')
+	}
+	w.Write(b)
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n < 1<<8:
+		b = append(b, 0xd9, byte(n))
+	default:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n < 1<<16:
+		return append(b, 0xdc, byte(n>>8), byte(n))
+	}
+	return append(b, 0xdd, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
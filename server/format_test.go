@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func getAccept(s *Server, target, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestFormats(t *testing.T) {
+	key := []byte("secret")
+	s := newTestServer(t, Options{TokenKey: key})
+
+	parse := map[string]func(string) (dxyflake.ID, error){
+		"decimal": dxyflake.ParseString,
+		"base62":  func(v string) (dxyflake.ID, error) { return dxyflake.ParseBase62([]byte(v)) },
+		"hex":     dxyflake.ParseHex,
+		"envelope": func(v string) (dxyflake.ID, error) {
+			id, _, err := dxyflake.ParseEnvelope(v)
+			return id, err
+		},
+		"signed-token": func(v string) (dxyflake.ID, error) { return dxyflake.ParseSignedToken(v, key) },
+	}
+	for format, parse := range parse {
+		var resp IDResponse
+		w := get(s, "/v1/id?format="+format)
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v: %s", format, err, w.Body)
+		}
+		id, err := parse(resp.ID)
+		if err != nil || dxyflake.Decompose(id)["service-id"] != 3 {
+			t.Errorf("%s: %q parses to %d, %v", format, resp.ID, id, err)
+		}
+	}
+
+	if w := get(s, "/v1/id?format=base99"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: got status %d", w.Code)
+	}
+	if w := get(newTestServer(t, Options{}), "/v1/id?format=signed-token"); w.Code != http.StatusBadRequest {
+		t.Errorf("signed token without key: got status %d", w.Code)
+	}
+}
+
+func TestContentTypes(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	w := getAccept(s, "/v1/ids?count=3&format=hex", "text/html, text/plain;q=0.9")
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeText {
+		t.Errorf("got content type %q", ct)
+	}
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 3 || len(lines[0]) != 16 {
+		t.Errorf("unexpected text body %q", w.Body)
+	}
+
+	w = getAccept(s, "/v1/id?format=base62", "*/*")
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeJSON {
+		t.Errorf("got content type %q", ct)
+	}
+
+	w = getAccept(s, "/v1/id?format=hex", "application/msgpack")
+	b := w.Body.Bytes()
+	if ct := w.Header().Get("Content-Type"); ct != ContentTypeMsgpack {
+		t.Errorf("got content type %q", ct)
+	}
+	// {"id": "<16 hex digits>"}
+	if len(b) != 1+3+1+16 || !bytes.HasPrefix(b, []byte{0x81, 0xa2, 'i', 'd', 0xa0 | 16}) {
+		t.Errorf("unexpected msgpack body %x", b)
+	}
+
+	w = getAccept(s, "/v1/ids?count=20", "application/x-msgpack")
+	b = w.Body.Bytes()
+	// {"ids": [20 strings]}
+	if !bytes.HasPrefix(b, []byte{0x81, 0xa3, 'i', 'd', 's', 0xdc, 0, 20}) {
+		t.Errorf("unexpected msgpack body %x", b)
+	}
+}
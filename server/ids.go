@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// IDResponse is the JSON response to GET /v1/id. The ID is a string in the
+// format of the format query parameter, decimal by default, as JSON numbers
+// lose precision in many decoders. See writeIDs for the other content
+// types.
+type IDResponse struct {
+	ID string `json:"id"`
+}
+
+// IDsResponse is the response to GET /v1/ids, with the IDs in the order
+// they were issued.
+type IDsResponse struct {
+	IDs []string `json:"ids"`
+}
+
+func (s *Server) id(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if !s.begin() {
+		s.writeError(w, Classify(ErrDraining))
+		return
+	}
+	defer s.end()
+	format, e := s.formatter(r)
+	if e != nil {
+		s.writeError(w, e)
+		return
+	}
+	if e := s.authorize(r, 1, true); e != nil {
+		s.refuse(w, r, 1, e)
+		return
+	}
+	id, err := tenant(r).Generator.NextID()
+	if err != nil {
+		s.refuse(w, r, 1, Classify(err))
+		return
+	}
+	s.audit(r, 1, id, id, nil)
+	writeIDs(w, r, "id", []string{format(id)}, false)
+}
+
+func (s *Server) ids(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if !s.begin() {
+		s.writeError(w, Classify(ErrDraining))
+		return
+	}
+	defer s.end()
+	format, e := s.formatter(r)
+	if e != nil {
+		s.writeError(w, e)
+		return
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count <= 0 {
+		s.writeError(w, NewError(CodeInvalidRequest, "count must be a positive integer"))
+		return
+	}
+	if count > s.opts.MaxBatch {
+		s.writeError(w, NewError(CodeBatchTooLarge,
+			fmt.Sprintf("%d IDs exceed the batch limit of %d", count, s.opts.MaxBatch)))
+		return
+	}
+	if e := s.authorize(r, count, true); e != nil {
+		s.refuse(w, r, count, e)
+		return
+	}
+
+	issued, err := nextIDs(tenant(r).Generator, count)
+	if err != nil {
+		s.refuse(w, r, count, Classify(err))
+		return
+	}
+	ids := make([]string, count)
+	for i, id := range issued {
+		ids[i] = format(id)
+	}
+	s.audit(r, count, issued[0], issued[count-1], nil)
+	writeIDs(w, r, "ids", ids, true)
+}
+
+// nextIDs issues n IDs of gen, in one call if gen issues batches.
+func nextIDs(gen dxyflake.Generator, n int) ([]dxyflake.ID, error) {
+	if b, ok := gen.(interface {
+		NextIDs(n int) ([]dxyflake.ID, error)
+	}); ok {
+		return b.NextIDs(n)
+	}
+	ids := make([]dxyflake.ID, n)
+	for i := range ids {
+		id, err := gen.NextID()
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// refuse audits and writes the error response of an issuing endpoint.
+func (s *Server) refuse(w http.ResponseWriter, r *http.Request, count int, e *Error) {
+	s.audit(r, count, 0, 0, e)
+	s.writeError(w, e)
+}
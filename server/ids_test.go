@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func get(s *Server, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestIDs(t *testing.T) {
+	s := newTestServer(t, Options{MaxBatch: 10})
+
+	var one IDResponse
+	if w := get(s, "/v1/id"); w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &one) != nil || one.ID == "" {
+		t.Fatalf("GET /v1/id: %d %s", w.Code, w.Body)
+	}
+
+	var many IDsResponse
+	if w := get(s, "/v1/ids?count=10"); w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &many) != nil {
+		t.Fatalf("GET /v1/ids: %d %s", w.Code, w.Body)
+	}
+	if len(many.IDs) != 10 {
+		t.Fatalf("got %d IDs", len(many.IDs))
+	}
+	prev := one.ID
+	for _, id := range many.IDs {
+		if len(id) < len(prev) || len(id) == len(prev) && id <= prev {
+			t.Errorf("%s not after %s", id, prev)
+		}
+		prev = id
+	}
+
+	for target, status := range map[string]int{
+		"/v1/ids":          http.StatusBadRequest,
+		"/v1/ids?count=0":  http.StatusBadRequest,
+		"/v1/ids?count=x":  http.StatusBadRequest,
+		"/v1/ids?count=11": http.StatusRequestEntityTooLarge,
+	} {
+		if w := get(s, target); w.Code != status {
+			t.Errorf("%s: got status %d, want %d", target, w.Code, status)
+		}
+	}
+
+	// the unversioned paths of sidecars
+	if w := get(s, "/id"); w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &one) != nil || one.ID <= prev {
+		t.Errorf("GET /id: %d %s", w.Code, w.Body)
+	}
+	if w := get(s, "/ids?count=2"); w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &many) != nil || len(many.IDs) != 2 {
+		t.Errorf("GET /ids: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// TimeHeader carries the time of the server, in RFC 3339 with nanoseconds,
+// on responses to GET /v1/meta, so that peers can compare their clocks
+// with it more precisely than with the Date header.
+const TimeHeader = "X-Dxyflake-Time"
+
+// describer is implemented by generators that describe themselves, such
+// as those of NewDxyflake.
+type describer interface {
+	Describe() dxyflake.Description
+}
+
+// meta serves the dxyflake.Description of the generator of the tenant, or
+// of its layout if the generator does not describe itself, with the time
+// of the server in TimeHeader.
+func (s *Server) meta(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	if e := s.authorize(r, 0, false); e != nil {
+		s.writeError(w, e)
+		return
+	}
+	t := tenant(r)
+	d := t.Layout.Describe()
+	if g, ok := t.Generator.(describer); ok {
+		d = g.Describe()
+	}
+	w.Header().Set(TimeHeader, time.Now().UTC().Format(time.RFC3339Nano))
+	writeJSON(w, http.StatusOK, d)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestMeta(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	var d dxyflake.Description
+	w := get(s, "/v1/meta")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &d) != nil {
+		t.Fatalf("GET /v1/meta: %d %s", w.Code, w.Body)
+	}
+	if d.MachineID == nil || *d.MachineID != 1 || d.ServiceID == nil || *d.ServiceID != 3 ||
+		d.Version == "" || d.GoVersion != dxyflake.Build().GoVersion {
+		t.Errorf("unexpected description: %s", w.Body)
+	}
+	if err := d.Compatible(dxyflake.DefaultLayout.Describe()); err != nil {
+		t.Error(err)
+	}
+	if at, err := time.Parse(time.RFC3339Nano, w.Header().Get(TimeHeader)); err != nil || time.Since(at) > time.Minute {
+		t.Errorf("unexpected %s: %q", TimeHeader, w.Header().Get(TimeHeader))
+	}
+
+	// generators that do not describe themselves get the server layout
+	s = New(failingGenerator{}, Options{})
+	if w := get(s, "/v1/meta"); w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &d) != nil || d.Unit != "10ms" {
+		t.Errorf("GET /v1/meta: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,187 @@
+// Package server serves dxyflake IDs and ID introspection over HTTP.
+//
+// The endpoints are:
+//
+//	GET  /v1/id              issue an ID
+//	GET  /v1/ids             issue count IDs, e.g. /v1/ids?count=100
+//	POST /v1/decompose       decompose a batch of IDs in any encoding
+//	GET  /v1/decompose/<id>  decompose one ID, see Parts
+//	GET  /v1/meta            describe the generator, see dxyflake.Description
+//
+// For sidecars, /id, /ids and /decompose/<id> serve the same without the
+// version prefix.
+// The IDs endpoints take a format query parameter: decimal (the default),
+// base2, base32, crockford32, base36, base58, base62, base64, hex,
+// sortable, envelope or signed-token. They answer in JSON, plain text or MessagePack depending on
+// the Accept header.
+//
+// A server may host several tenants, generator groups with their own
+// epochs or layouts, such as a legacy Twitter-layout ID space next to a
+// dxyflake one. A request is routed to a tenant by a /t/<tenant> path
+// prefix, e.g. /t/legacy/v1/id, or by the X-Dxyflake-Tenant header, and to
+// the generator passed to New otherwise.
+//
+// An Authorizer may refuse requests by client identity, tenant, service
+// and count.
+//
+// Errors are returned as JSON objects, see Error.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// DefaultMaxBatch is the number of IDs a request may carry when
+// Options.MaxBatch is 0.
+const DefaultMaxBatch = 1000
+
+// Options configures a Server.
+//
+// Layout describes the IDs the server decomposes. If Layout.Unit is 0, the
+// layout of the generator is used if it has one, DefaultLayout otherwise.
+//
+// ServiceNames names the service IDs of decomposed IDs.
+//
+// MaxBatch is the largest number of IDs a request may carry or ask for.
+//
+// TokenKey is the key of the signed-token format, see ID.SignedToken.
+// If TokenKey is empty, the format is refused.
+//
+// Tenants are the tenants of the server by name, besides the default one
+// of the generator passed to New, Layout and ServiceNames.
+//
+// Authorizer, if not nil, decides on every request, see Authorizer.
+//
+// Audit configures the audit log of the issued IDs.
+type Options struct {
+	Layout       dxyflake.Layout
+	ServiceNames dxyflake.ServiceNames
+	MaxBatch     int
+	TokenKey     []byte
+	Tenants      map[string]Tenant
+	Authorizer   Authorizer
+	Audit        Audit
+}
+
+// TenantHeader is the request header naming the tenant of a request.
+const TenantHeader = "X-Dxyflake-Tenant"
+
+// Tenant is a generator with the layout of its IDs. If Layout.Unit is 0,
+// the layout of the generator is used if it has one, DefaultLayout
+// otherwise.
+type Tenant struct {
+	Generator    dxyflake.Generator
+	Layout       dxyflake.Layout
+	ServiceNames dxyflake.ServiceNames
+
+	name string
+}
+
+func (t Tenant) withDefaults() Tenant {
+	if t.Layout.Unit == 0 {
+		if l, ok := t.Generator.(interface{ Layout() dxyflake.Layout }); ok {
+			t.Layout = l.Layout()
+		} else {
+			t.Layout = dxyflake.DefaultLayout
+		}
+	}
+	return t
+}
+
+type tenantKey struct{}
+
+// Server is an http.Handler serving the endpoints of the package.
+type Server struct {
+	opts    Options
+	tenants map[string]Tenant // by name, "" for the default tenant
+	mux     *http.ServeMux
+
+	mutex    sync.Mutex
+	errors   map[Code]uint64
+	draining bool
+	inflight int
+	idle     chan struct{} // closed once draining and idle
+}
+
+// New returns a Server issuing IDs with gen, and with the generators of
+// opts.Tenants for their tenants. If gen is nil, requests must name a
+// tenant.
+func New(gen dxyflake.Generator, opts Options) *Server {
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = DefaultMaxBatch
+	}
+
+	s := &Server{
+		opts:    opts,
+		tenants: make(map[string]Tenant, len(opts.Tenants)+1),
+		mux:     http.NewServeMux(),
+		errors:  make(map[Code]uint64),
+		idle:    make(chan struct{}),
+	}
+	for name, t := range opts.Tenants {
+		t.name = name
+		s.tenants[name] = t.withDefaults()
+	}
+	if gen != nil {
+		s.tenants[""] = Tenant{Generator: gen, Layout: opts.Layout, ServiceNames: opts.ServiceNames}.withDefaults()
+	}
+	for _, prefix := range []string{"/v1", ""} {
+		s.mux.HandleFunc(prefix+"/id", s.id)
+		s.mux.HandleFunc(prefix+"/ids", s.ids)
+		s.mux.HandleFunc(prefix+"/decompose/", s.decomposeID)
+	}
+	s.mux.HandleFunc("/v1/decompose", s.decompose)
+	s.mux.HandleFunc("/v1/meta", s.meta)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(TenantHeader)
+	if rest := strings.TrimPrefix(r.URL.Path, "/t/"); rest != r.URL.Path {
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			i = len(rest)
+		}
+		name = rest[:i]
+		r = r.Clone(r.Context())
+		r.URL.Path, r.URL.RawPath = rest[i:], ""
+	}
+	t, ok := s.tenants[name]
+	if !ok {
+		msg := "no tenant given"
+		if name != "" {
+			msg = "unknown tenant " + name
+		}
+		s.writeError(w, NewError(CodeUnknownTenant, msg))
+		return
+	}
+	s.mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, t)))
+}
+
+// tenant returns the tenant a request was routed to.
+func tenant(r *http.Request) Tenant {
+	t, _ := r.Context().Value(tenantKey{}).(Tenant)
+	return t
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		s.writeError(w, NewError(CodeMethodNotAllowed, r.Method+" is not allowed"))
+		return false
+	}
+	return true
+}
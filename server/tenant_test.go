@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// twitterLayout is the layout of Twitter's Snowflake, with the 10 bit node
+// split in two.
+var twitterLayout = dxyflake.Layout{
+	Epoch:           time.Unix(1288834974, 657000000).UTC(),
+	Unit:            time.Millisecond,
+	BitLenTime:      41,
+	BitLenMachineID: 5,
+	BitLenServiceID: 5,
+	BitLenSequence:  12,
+}
+
+// legacyGenerator issues Twitter-layout IDs, one per millisecond.
+type legacyGenerator struct {
+	mutex sync.Mutex
+	last  time.Time
+}
+
+func (g *legacyGenerator) NextID() (dxyflake.ID, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	t := time.Now()
+	if !t.After(g.last.Add(time.Millisecond)) {
+		t = g.last.Add(time.Millisecond)
+	}
+	g.last = t
+	return twitterLayout.Compose(t, 7, 1, 0)
+}
+
+func TestTenants(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(1, 3)
+	s := New(dxyflake.NewDxyflake(st), Options{Tenants: map[string]Tenant{
+		"legacy": {Generator: new(legacyGenerator), Layout: twitterLayout},
+	}})
+
+	var one IDResponse
+	w := get(s, "/t/legacy/v1/id")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &one) != nil {
+		t.Fatalf("GET /t/legacy/v1/id: %d %s", w.Code, w.Body)
+	}
+
+	body := `{"ids": ["` + one.ID + `"]}`
+	r := httptest.NewRequest(http.MethodPost, "/v1/decompose", strings.NewReader(body))
+	r.Header.Set(TenantHeader, "legacy")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	var resp DecomposeResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil || len(resp.Results) != 1 {
+		t.Fatalf("POST /v1/decompose: %d %s", w.Code, w.Body)
+	}
+	if p := resp.Results[0]; p.Error != "" || p.MachineID != 7 || p.ServiceID != 1 {
+		t.Errorf("unexpected parts: %+v", p)
+	}
+	if ts, _ := time.Parse(time.RFC3339Nano, resp.Results[0].Time); time.Since(ts) > time.Minute {
+		t.Errorf("unexpected time: %s", resp.Results[0].Time)
+	}
+
+	// the default tenant reads IDs with its own layout
+	w = post(s, "application/json", body)
+	if json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Results[0].Error == "" {
+		t.Errorf("legacy id accepted by the default tenant: %s", w.Body)
+	}
+
+	var d dxyflake.Description
+	if w := get(s, "/t/legacy/v1/meta"); json.Unmarshal(w.Body.Bytes(), &d) != nil || d.Unit != "1ms" {
+		t.Errorf("GET /t/legacy/v1/meta: %d %s", w.Code, w.Body)
+	}
+	if w := get(s, "/t/other/v1/id"); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), string(CodeUnknownTenant)) {
+		t.Errorf("unknown tenant: %d %s", w.Code, w.Body)
+	}
+
+	s = New(nil, Options{Tenants: map[string]Tenant{"legacy": {Generator: new(legacyGenerator), Layout: twitterLayout}}})
+	if w := get(s, "/v1/id"); w.Code != http.StatusNotFound {
+		t.Errorf("no tenant: %d %s", w.Code, w.Body)
+	}
+}
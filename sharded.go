@@ -0,0 +1,196 @@
+package dxyflake
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrInvalidShards is returned by NewShardedDxyflake for shard counts below
+// 1 or above the number of sequence numbers of a time unit.
+var ErrInvalidShards = errors.New("invalid shard count")
+
+// Sharded is a dxyflake split into shards that each own a range of the
+// sequence numbers of every time unit, so that parallel callers lock
+// different shards instead of queuing on one mutex. Callers mostly stay on
+// the shard of their CPU.
+//
+// The shards share the ID space of one dxyflake, so they do not issue more
+// IDs per time unit; a caller whose shard has used up its range moves on
+// to the others. IDs stay unique and ordered by time unit, but within a
+// time unit the IDs of different shards are not issued in order.
+type Sharded struct {
+	shards  []*dxyflake
+	pool    sync.Pool // shards, to keep callers on the shard of their P
+	next    uint32    // atomic, the shard of callers the pool has none for
+	limiter *limiter  // Settings.RateLimit of all shards, nil if unlimited
+}
+
+// NewShardedDxyflake returns a dxyflake configured with the given Settings
+// and split into n shards. It returns the errors of NewDxyflakeWithError,
+// and ErrInvalidShards for invalid n.
+func NewShardedDxyflake(st Settings, n int) (*Sharded, error) {
+	if n < 1 {
+		return nil, ErrInvalidShards
+	}
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		return nil, err
+	}
+	size := int(df.lastSeq) + 1
+	if n > size {
+		df.Drain()
+		return nil, ErrInvalidShards
+	}
+
+	s := &Sharded{shards: make([]*dxyflake, n), limiter: df.limiter}
+	df.limiter = nil
+	for i := range s.shards {
+		shard := df
+		if i > 0 {
+			shard = df.shard()
+		}
+		shard.firstSeq = uint16(i * size / n)
+		shard.lastSeq = uint16((i+1)*size/n - 1)
+		shard.startSeq = shard.firstSeq
+		shard.sequence = shard.lastSeq
+		s.shards[i] = shard
+	}
+	return s, nil
+}
+
+// shard returns a dxyflake of the same ID space as df for a shard. Only the
+// first shard is registered, see Settings.Exclusive.
+func (df *dxyflake) shard() *dxyflake {
+	shard := &dxyflake{
+		mutex:      new(sync.Mutex),
+		startTime:  df.startTime,
+		machineID:  df.machineID,
+		serviceID:  df.serviceID,
+		clock:      df.clock,
+		waiter:     df.waiter,
+		rand:       df.rand,
+		metrics:    df.metrics,
+		clockBack:  df.clockBack,
+		clockSlack: df.clockSlack,
+		randomSeq:  df.randomSeq,
+		checker:    df.checker,
+		exhaustion: df.exhaustion,
+		maxBorrow:  df.maxBorrow,
+		transform:  df.transform,
+		layout:     df.layout,
+		names:      df.names,
+	}
+	shard.validators.Store(df.validators.Load())
+	return shard
+}
+
+func (s *Sharded) get() *dxyflake {
+	if df, ok := s.pool.Get().(*dxyflake); ok {
+		return df
+	}
+	return s.shards[atomic.AddUint32(&s.next, 1)%uint32(len(s.shards))]
+}
+
+// NextID generates a next unique ID with one of the shards.
+func (s *Sharded) NextID() (ID, error) {
+	return s.NextIDContext(context.Background())
+}
+
+// NextIDContext is like NextID, but stops waiting for the next time unit
+// once ctx is done, see the method of the same name of a dxyflake. It only
+// waits once the ranges of all shards are used up.
+func (s *Sharded) NextIDContext(ctx context.Context) (id ID, err error) {
+	df := s.get()
+	defer s.pool.Put(df)
+	if err := df.throttle(ctx, s.limiter, 1, true); err != nil {
+		return 0, err
+	}
+	defer func() { s.limiter.refund(1, err) }()
+	if id, err := s.try(df); err == nil {
+		return id, nil
+	}
+	return df.NextIDContext(ctx)
+}
+
+// TryNextID is like NextID, but returns ErrSequenceExhausted instead of
+// waiting once the ranges of all shards are used up, and ErrRateLimited
+// beyond Settings.RateLimit.
+func (s *Sharded) TryNextID() (id ID, err error) {
+	df := s.get()
+	defer s.pool.Put(df)
+	if err := df.throttle(context.Background(), s.limiter, 1, false); err != nil {
+		return 0, err
+	}
+	defer func() { s.limiter.refund(1, err) }()
+	return s.try(df)
+}
+
+// try issues an ID with df, or with the other shards once the range of df
+// is used up.
+func (s *Sharded) try(df *dxyflake) (ID, error) {
+	id, err := df.TryNextID()
+	for i := 0; err == ErrSequenceExhausted && i < len(s.shards); i++ {
+		if s.shards[i] != df {
+			id, err = s.shards[i].TryNextID()
+		}
+	}
+	return id, err
+}
+
+// Drain drains every shard and returns the high-water mark of the
+// dxyflake.
+func (s *Sharded) Drain() Mark {
+	var mark Mark
+	for _, df := range s.shards {
+		if m := df.Drain(); m.Next.After(mark.Next) {
+			mark = m
+		}
+	}
+	return mark
+}
+
+// Close closes every shard, see Close of a dxyflake.
+func (s *Sharded) Close() error {
+	for _, df := range s.shards {
+		df.Close()
+	}
+	return nil
+}
+
+// Layout returns the layout of the IDs of the dxyflake.
+func (s *Sharded) Layout() Layout {
+	return s.shards[0].layout
+}
+
+// Clock returns the time source of the dxyflake, see Settings.Clock.
+func (s *Sharded) Clock() Clock {
+	return s.shards[0].clock
+}
+
+// Decompose returns a set of the parts of an ID of the dxyflake's layout.
+func (s *Sharded) Decompose(id ID) map[string]int64 {
+	return s.shards[0].Decompose(id)
+}
+
+// Stats returns the statistics of the dxyflake: the sums of those of the
+// shards, with the pressure of the busiest shard and the highest sequence
+// number of all.
+func (s *Sharded) Stats() Stats {
+	var stats Stats
+	for _, df := range s.shards {
+		st := df.Stats()
+		stats.Issued += st.Issued
+		stats.Waits += st.Waits
+		stats.WaitTime += st.WaitTime
+		stats.Pressure = math.Max(stats.Pressure, st.Pressure)
+		if st.HighWater > stats.HighWater {
+			stats.HighWater = st.HighWater
+		}
+		stats.Remaining = st.Remaining
+	}
+	stats.RateLimited = s.limiter.count()
+	return stats
+}
@@ -0,0 +1,114 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSharded(t *testing.T) {
+	var st Settings
+	st.Init(1, 2)
+	st.StartTime = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC) // an ID space of its own
+	st.Exclusive = true
+	c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.Clock = c
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+	s, err := NewShardedDxyflake(st, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.shards[2].firstSeq; got != 2730 {
+		t.Errorf("third shard starts at %d", got)
+	}
+
+	seen := make(map[ID]bool)
+	var tick int64
+	for i := 0; i < 1<<BitLenSequence; i++ {
+		next := s.NextID
+		if i%2 == 0 {
+			next = s.TryNextID
+		}
+		id, err := next()
+		if err != nil {
+			t.Fatalf("ID %d: %v", i, err)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		seen[id] = true
+		parts := s.Decompose(id)
+		if i == 0 {
+			tick = parts["time"]
+		} else if parts["time"] != tick {
+			t.Fatalf("ID %d issued in time unit %d, want %d", i, parts["time"], tick)
+		}
+	}
+	if _, err := s.TryNextID(); err != ErrSequenceExhausted {
+		t.Errorf("TryNextID() error = %v, want %v", err, ErrSequenceExhausted)
+	}
+	id, err := s.NextID()
+	if err != nil || s.Decompose(id)["time"] != tick+1 {
+		t.Errorf("NextID() = %d, %v after the time unit was used up", id, err)
+	}
+	if stats := s.Stats(); stats.Issued != 1<<BitLenSequence+1 || stats.Waits != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	mark := s.Drain()
+	if !mark.Next.Equal(s.Layout().TickTime(tick + 2)) {
+		t.Errorf("unexpected mark %+v", mark)
+	}
+	if _, err := s.NextID(); err != ErrDrained {
+		t.Errorf("NextID() error = %v, want %v", err, ErrDrained)
+	}
+
+	for _, n := range []int{0, 1<<BitLenSequence + 1} {
+		if _, err := NewShardedDxyflake(st, n); err != ErrInvalidShards {
+			t.Errorf("NewShardedDxyflake(%d) error = %v, want %v", n, err, ErrInvalidShards)
+		}
+	}
+	// the refused dxyflake is not left registered
+	if _, err := NewShardedDxyflake(st, 1); err != nil {
+		t.Errorf("NewShardedDxyflake() error = %v", err)
+	}
+}
+
+// benchmarkSettings issue 65536 IDs per millisecond, so that the benchmarks
+// measure the locking rather than waits for the next time unit.
+func benchmarkSettings() Settings {
+	st := Settings{BitsTime: 39, BitsMachineID: 4, BitsServiceID: 4, BitsSequence: 16, TimeUnit: time.Millisecond}
+	st.Init(1, 2)
+	return st
+}
+
+func BenchmarkNextID(b *testing.B) {
+	st := benchmarkSettings()
+	df := NewDxyflake(st)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		df.NextID()
+	}
+}
+
+func BenchmarkNextIDParallel(b *testing.B) {
+	st := benchmarkSettings()
+	df := NewDxyflake(st)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			df.NextID()
+		}
+	})
+}
+
+func BenchmarkShardedParallel(b *testing.B) {
+	st := benchmarkSettings()
+	s, err := NewShardedDxyflake(st, 16)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.NextID()
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package dxyflake
+
+import (
+	"errors"
+	"os"
+)
+
+// These errors are returned by OpenShared. Errors matching
+// ErrSharedNotCreated wrap the error of NewDxyflakeWithError.
+var (
+	ErrNoFreeServiceID   = errors.New("every service id of the shared file is taken")
+	ErrSharedNotCreated  = errors.New("dxyflake not created")
+	ErrSharedUnsupported = errors.New("shared dxyflakes are not supported on this platform")
+)
+
+// Shared is a dxyflake whose service ID is claimed from a file shared by
+// the processes of a host, see OpenShared.
+type Shared struct {
+	*dxyflake
+	file *os.File
+	slot int
+}
+
+// sharedError is an error of ErrSharedNotCreated with its cause.
+type sharedError struct {
+	err error
+}
+
+func (e sharedError) Error() string {
+	return ErrSharedNotCreated.Error() + ": " + e.err.Error()
+}
+
+func (e sharedError) Is(target error) bool {
+	return target == ErrSharedNotCreated
+}
+
+func (e sharedError) Unwrap() error {
+	return e.err
+}
+
+// sharedRecordSize is the size of the record of a service ID in the shared
+// file: the time, in Unix nanoseconds, before which the service ID must
+// not issue IDs, or 0 while it is claimed.
+const sharedRecordSize = 8
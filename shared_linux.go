@@ -0,0 +1,118 @@
+//go:build linux
+// +build linux
+
+package dxyflake
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenShared returns a dxyflake with a service ID that no other process
+// holding the shared file at path uses, so that processes started from
+// the same binary on one host, such as a sidecar and its main process,
+// partition the service IDs of their machine ID instead of colliding.
+//
+// The service ID is the first one of the layout of st, allowed by
+// st.CheckServiceID and st.Environment, whose
+// record in the file is not locked by another open dxyflake; st.ServiceID
+// is ignored. The lock is released by Close or when the process exits.
+// Close records the high-water mark of the dxyflake, and the next process
+// claiming the service ID waits for it; after a crash, it waits one time
+// unit, which covers a sequence overflow but not a clock stepping back.
+//
+// The records are locked with open file description locks, so two
+// dxyflakes of one process get different service IDs too.
+func OpenShared(path string, st Settings) (*Shared, error) {
+	l, ok := st.bits()
+	if !ok {
+		return nil, sharedError{ErrInvalidLayout}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	slot := -1
+	for i := 0; i < 1<<uint(l.BitLenServiceID) && slot < 0; i++ {
+		if st.CheckServiceID != nil && !st.CheckServiceID(uint16(i)) ||
+			st.Environment == EnvProduction && uint16(i) == l.TestServiceID() {
+			continue
+		}
+		err := lockSharedRecord(f, i, unix.F_WRLCK)
+		if err == unix.EAGAIN || err == unix.EACCES {
+			continue
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		slot = i
+	}
+	if slot < 0 {
+		f.Close()
+		return nil, ErrNoFreeServiceID
+	}
+
+	var rec [sharedRecordSize]byte
+	if _, err := f.ReadAt(rec[:], int64(slot*sharedRecordSize)); err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	// mark the record as claimed, so a crash is detected
+	if _, err := f.WriteAt(make([]byte, sharedRecordSize), int64(slot*sharedRecordSize)); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	st.ServiceID = func() (uint16, error) {
+		return uint16(slot), nil
+	}
+	df, err := NewDxyflakeWithError(st)
+	if err != nil {
+		f.Close()
+		return nil, sharedError{err}
+	}
+
+	next := df.now().Add(df.layout.Unit)
+	if mark := int64(binary.BigEndian.Uint64(rec[:])); mark != 0 {
+		next = time.Unix(0, mark)
+	}
+	if d := next.Sub(df.now()); d > 0 {
+		df.clock.Sleep(d)
+	}
+	return &Shared{dxyflake: df, file: f, slot: slot}, nil
+}
+
+func lockSharedRecord(f *os.File, slot int, typ int16) error {
+	lk := unix.Flock_t{
+		Type:   typ,
+		Whence: io.SeekStart,
+		Start:  int64(slot * sharedRecordSize),
+		Len:    sharedRecordSize,
+	}
+	return unix.FcntlFlock(f.Fd(), unix.F_OFD_SETLK, &lk)
+}
+
+// Close drains the dxyflake, records its high-water mark and releases its
+// service ID.
+func (s *Shared) Close() error {
+	mark := s.Drain()
+	var rec [sharedRecordSize]byte
+	binary.BigEndian.PutUint64(rec[:], uint64(mark.Next.UnixNano()))
+	_, err := s.file.WriteAt(rec[:], int64(s.slot*sharedRecordSize))
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if uerr := lockSharedRecord(s.file, s.slot, unix.F_UNLCK); err == nil {
+		err = uerr
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
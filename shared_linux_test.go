@@ -0,0 +1,101 @@
+package dxyflake
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dxyflake.shared")
+	var st Settings
+	st.Init(4, 0)
+
+	var shared []*Shared
+	for i := 0; i < 3; i++ {
+		s, err := OpenShared(path, st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if s.serviceID != uint16(i) {
+			t.Errorf("got service id %d, want %d", s.serviceID, i)
+		}
+		shared = append(shared, s)
+	}
+
+	last, err := shared[1].NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shared[1].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shared[1].NextID(); err != ErrDrained {
+		t.Errorf("closed dxyflake issued an ID: %v", err)
+	}
+
+	// the released service ID is claimed again, past the mark
+	s, err := OpenShared(path, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	id, err := s.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := Decompose(id); p["service-id"] != 1 || p["machine-id"] != 4 || p["time"] <= Decompose(last)["time"] {
+		t.Errorf("unexpected parts %v after %v", p, Decompose(last))
+	}
+
+	st.CheckServiceID = func(id uint16) bool { return id == 2 || id == 7 }
+	s7, err := OpenShared(path, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s7.Close()
+	if s7.serviceID != 7 {
+		t.Errorf("got service id %d, want 7", s7.serviceID)
+	}
+	if _, err := OpenShared(path, st); err != ErrNoFreeServiceID {
+		t.Errorf("got %v with every allowed service id taken", err)
+	}
+}
+
+func TestOpenSharedLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dxyflake.shared")
+	var st Settings
+	st.Init(5, 0)
+	st.BitsTime, st.BitsMachineID, st.BitsServiceID, st.BitsSequence = 43, 5, 3, 12
+	st.CheckServiceID = func(id uint16) bool { return id >= 7 }
+	s, err := OpenShared(path, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.serviceID != 7 {
+		t.Errorf("got service id %d, want 7", s.serviceID)
+	}
+	if _, err := OpenShared(path, st); err != ErrNoFreeServiceID {
+		t.Errorf("got %v with every service id of 3 bits taken", err)
+	}
+
+	st.BitsTime, st.BitsServiceID, st.BitsSequence = 40, 8, 10
+	st.CheckServiceID = func(id uint16) bool { return id == 200 }
+	s200, err := OpenShared(filepath.Join(t.TempDir(), "dxyflake.shared"), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s200.Close()
+	if s200.serviceID != 200 {
+		t.Errorf("got service id %d, want 200", s200.serviceID)
+	}
+
+	st.Init(40, 0)
+	st.CheckServiceID = nil
+	_, err = OpenShared(path, st)
+	if !errors.Is(err, ErrSharedNotCreated) || !errors.Is(err, ErrInvalidMachineID) {
+		t.Errorf("machine id out of range: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package dxyflake
+
+// OpenShared returns ErrSharedUnsupported; shared dxyflakes are only
+// supported on Linux.
+func OpenShared(path string, st Settings) (*Shared, error) {
+	return nil, ErrSharedUnsupported
+}
+
+// Close does nothing.
+func (s *Shared) Close() error {
+	return nil
+}
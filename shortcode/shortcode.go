@@ -0,0 +1,164 @@
+// Package shortcode mints short, human friendly codes, such as referral
+// codes, on top of dxyflake IDs.
+//
+// A code is a keyed hash of a fresh ID, truncated to Length characters of
+// Alphabet. Without the key, codes cannot be predicted from IDs or from
+// each other. Truncation makes collisions possible, so every code is
+// claimed in a Store, and a code that is taken is replaced by the code of
+// the next ID.
+package shortcode
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Alphabet is the default code alphabet: digits and upper case letters
+// without 0, 1, I, L, O and U, which are easily confused or misread.
+const Alphabet = "23456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// These are the defaults used when Minter fields are left zero.
+const (
+	DefaultLength      = 8
+	DefaultMaxAttempts = 8
+)
+
+// These are the limits of Minter.Length.
+const (
+	MinLength = 6
+	MaxLength = 8
+)
+
+// These errors are returned by Minter and Normalize.
+var (
+	ErrTaken         = errors.New("code is taken")
+	ErrExhausted     = errors.New("no free code found")
+	ErrNoGenerator   = errors.New("no generator")
+	ErrNoStore       = errors.New("no store")
+	ErrNoKey         = errors.New("no key")
+	ErrInvalidLength = errors.New("code length out of range")
+	ErrInvalidCode   = errors.New("invalid code")
+)
+
+// Minter mints codes.
+//
+// Key is the secret the codes are derived with. It must not be empty.
+//
+// Length is the number of characters of a code, from MinLength to
+// MaxLength. If Length is 0, DefaultLength is used.
+//
+// Alphabet is the set of characters codes are made of. It should only hold
+// digits and upper case letters, as Resolve upper cases codes.
+// If Alphabet is "", the package Alphabet is used.
+//
+// MaxAttempts is the number of IDs tried before Mint gives up with
+// ErrExhausted. If MaxAttempts is 0, DefaultMaxAttempts is used.
+type Minter struct {
+	Generator   dxyflake.Generator
+	Store       Store
+	Key         []byte
+	Length      int
+	Alphabet    string
+	MaxAttempts int
+}
+
+func (m *Minter) length() int {
+	if m.Length == 0 {
+		return DefaultLength
+	}
+	return m.Length
+}
+
+func (m *Minter) alphabet() string {
+	if m.Alphabet == "" {
+		return Alphabet
+	}
+	return m.Alphabet
+}
+
+// Code returns the code of id, whether or not it was minted.
+func (m *Minter) Code(id dxyflake.ID) string {
+	mac := hmac.New(sha256.New, m.Key)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id))
+	mac.Write(b[:])
+	h := binary.BigEndian.Uint64(mac.Sum(nil))
+
+	alphabet := m.alphabet()
+	base := uint64(len(alphabet))
+	code := make([]byte, m.length())
+	for i := range code {
+		code[i] = alphabet[h%base]
+		h /= base
+	}
+	return string(code)
+}
+
+// Mint issues an ID, claims its code in the Store and returns both.
+func (m *Minter) Mint(ctx context.Context) (string, dxyflake.ID, error) {
+	switch {
+	case m.Generator == nil:
+		return "", 0, ErrNoGenerator
+	case m.Store == nil:
+		return "", 0, ErrNoStore
+	case len(m.Key) == 0:
+		return "", 0, ErrNoKey
+	case m.length() < MinLength || m.length() > MaxLength:
+		return "", 0, ErrInvalidLength
+	}
+
+	attempts := m.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+	for i := 0; i < attempts; i++ {
+		id, err := m.Generator.NextID()
+		if err != nil {
+			return "", 0, err
+		}
+		code := m.Code(id)
+		switch err := m.Store.Claim(ctx, code, id); {
+		case err == nil:
+			return code, id, nil
+		case !errors.Is(err, ErrTaken):
+			return "", 0, err
+		}
+	}
+	return "", 0, ErrExhausted
+}
+
+// Resolve returns the ID a code was minted for. The code is normalized
+// first, so codes typed by people are found as well.
+func (m *Minter) Resolve(ctx context.Context, code string) (dxyflake.ID, bool, error) {
+	if m.Store == nil {
+		return 0, false, ErrNoStore
+	}
+	code, err := Normalize(code, m.alphabet(), m.length())
+	if err != nil {
+		return 0, false, err
+	}
+	return m.Store.Lookup(ctx, code)
+}
+
+// Normalize upper cases code, drops spaces and dashes, and checks that the
+// result has length characters of alphabet. It returns ErrInvalidCode
+// otherwise.
+func Normalize(code, alphabet string, length int) (string, error) {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	if len(code) != length {
+		return "", ErrInvalidCode
+	}
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(alphabet, code[i]) < 0 {
+			return "", ErrInvalidCode
+		}
+	}
+	return code, nil
+}
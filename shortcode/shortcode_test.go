@@ -0,0 +1,113 @@
+package shortcode
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func newMinter(t *testing.T) *Minter {
+	var st dxyflake.Settings
+	st.Init(3, 4)
+	return &Minter{
+		Generator: dxyflake.NewDxyflake(st),
+		Store:     NewMemoryStore(),
+		Key:       []byte("secret"),
+	}
+}
+
+func TestMint(t *testing.T) {
+	m := newMinter(t)
+	ctx := context.Background()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, id, err := m.Mint(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != DefaultLength || seen[code] {
+			t.Fatalf("unexpected code: %s", code)
+		}
+		seen[code] = true
+		if m.Code(id) != code {
+			t.Fatalf("code of %d is not %s", id, code)
+		}
+	}
+
+	for code := range seen {
+		lower := strings.ToLower(code[:4]) + "-" + code[4:]
+		if _, ok, err := m.Resolve(ctx, lower); !ok || err != nil {
+			t.Fatalf("Resolve(%s) = %v, %v", lower, ok, err)
+		}
+		break
+	}
+}
+
+func TestMintCollisions(t *testing.T) {
+	// six characters of two letters leave only 64 codes
+	m := newMinter(t)
+	m.Length, m.Alphabet = 6, "AB"
+	ctx := context.Background()
+
+	minted := 0
+	for i := 0; i < 100; i++ {
+		_, _, err := m.Mint(ctx)
+		if errors.Is(err, ErrExhausted) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		minted++
+	}
+	if n := m.Store.(*MemoryStore).Len(); minted != n || n > 64 {
+		t.Errorf("minted %d codes, stored %d", minted, n)
+	}
+}
+
+func TestMintErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		modify func(m *Minter)
+		want   error
+	}{
+		{"generator", func(m *Minter) { m.Generator = nil }, ErrNoGenerator},
+		{"store", func(m *Minter) { m.Store = nil }, ErrNoStore},
+		{"key", func(m *Minter) { m.Key = nil }, ErrNoKey},
+		{"length", func(m *Minter) { m.Length = 12 }, ErrInvalidLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMinter(t)
+			tt.modify(m)
+			if _, _, err := m.Mint(ctx); err != tt.want {
+				t.Errorf("Mint() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		err  error
+	}{
+		{"abcd-efgi", "", ErrInvalidCode},
+		{"abcd-2345", "ABCD2345", nil},
+		{"ab cd 23 45", "ABCD2345", nil},
+		{"ABCD234", "", ErrInvalidCode},
+		{"ABCD234O", "", ErrInvalidCode},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.code, Alphabet, 8)
+		if err != tt.err || (err == nil && got != tt.want) {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.code, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package shortcode
+
+import (
+	"context"
+	"sync"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// Store records which ID each code was minted for. Codes are truncated
+// hashes, so two IDs can map to the same code; the Store is what makes
+// codes unique.
+type Store interface {
+	// Claim records code for id. It returns ErrTaken if code is already
+	// claimed, by any ID.
+	Claim(ctx context.Context, code string, id dxyflake.ID) error
+	// Lookup returns the ID code was minted for.
+	Lookup(ctx context.Context, code string) (dxyflake.ID, bool, error)
+}
+
+// MemoryStore is an in-memory Store, useful for tests.
+type MemoryStore struct {
+	mutex sync.RWMutex
+	codes map[string]dxyflake.ID
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{codes: make(map[string]dxyflake.ID)}
+}
+
+// Claim records code for id.
+func (s *MemoryStore) Claim(ctx context.Context, code string, id dxyflake.ID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.codes[code]; ok {
+		return ErrTaken
+	}
+	s.codes[code] = id
+	return nil
+}
+
+// Lookup returns the ID code was minted for.
+func (s *MemoryStore) Lookup(ctx context.Context, code string) (dxyflake.ID, bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	id, ok := s.codes[code]
+	return id, ok, nil
+}
+
+// Len returns the number of claimed codes.
+func (s *MemoryStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.codes)
+}
@@ -0,0 +1,225 @@
+// Package simulate models dxyflake deployments to help choose safe settings.
+//
+// With only 5 bits for the machine ID, two nodes picking the same ID by
+// accident is far more likely than intuition suggests. Collisions estimates
+// how often that happens for a given machine ID assignment strategy.
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// DefaultSubnetSize is the number of addresses the IP based strategies draw
+// node addresses from when Config.SubnetSize is 0 (a /24 network).
+const DefaultSubnetSize = 256
+
+// ErrUnknownStrategy is returned by StrategyByName for unregistered names.
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// ErrInvalidConfig is returned by Collisions when Config cannot be simulated.
+var ErrInvalidConfig = errors.New("invalid simulation config")
+
+// Strategy assigns machine IDs in [0, space) to nodes, one per node.
+// subnet is the number of addresses IP based strategies draw from.
+type Strategy func(r *rand.Rand, nodes, space, subnet int) []uint16
+
+// Random picks every machine ID uniformly at random.
+func Random(r *rand.Rand, nodes, space, subnet int) []uint16 {
+	ids := make([]uint16, nodes)
+	for i := range ids {
+		ids[i] = uint16(r.Intn(space))
+	}
+	return ids
+}
+
+// Sequential hands out machine IDs by node ordinal, e.g. StatefulSet ordinals.
+func Sequential(r *rand.Rand, nodes, space, subnet int) []uint16 {
+	ids := make([]uint16, nodes)
+	for i := range ids {
+		ids[i] = uint16(i % space)
+	}
+	return ids
+}
+
+// IPLowBits uses the lowest bits of the node's address, with addresses
+// drawn without replacement from a subnet as DHCP or a CNI would.
+func IPLowBits(r *rand.Rand, nodes, space, subnet int) []uint16 {
+	addrs := addresses(r, nodes, subnet)
+	ids := make([]uint16, nodes)
+	for i, a := range addrs {
+		ids[i] = uint16(a % uint32(space))
+	}
+	return ids
+}
+
+// IPHash hashes the node's address, with addresses drawn as in IPLowBits.
+func IPHash(r *rand.Rand, nodes, space, subnet int) []uint16 {
+	addrs := addresses(r, nodes, subnet)
+	ids := make([]uint16, nodes)
+	for i, a := range addrs {
+		h := fnv.New32a()
+		h.Write([]byte{10, byte(a >> 16), byte(a >> 8), byte(a)})
+		ids[i] = uint16(fold(h.Sum32()) % uint32(space))
+	}
+	return ids
+}
+
+// HostnameHash hashes a random, container style hostname.
+func HostnameHash(r *rand.Rand, nodes, space, subnet int) []uint16 {
+	ids := make([]uint16, nodes)
+	for i := range ids {
+		h := fnv.New32a()
+		fmt.Fprintf(h, "app-%08x-%05x", r.Uint32(), r.Intn(1<<20))
+		ids[i] = uint16(fold(h.Sum32()) % uint32(space))
+	}
+	return ids
+}
+
+// fold runs the murmur3 finalizer over an FNV hash. The low bits of FNV-1a
+// only depend on the low bits of the input, so taking them directly turns a
+// hash of an address into a mere permutation of its low bits.
+func fold(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// addresses draws nodes distinct host numbers from a subnet. If there are
+// more nodes than addresses, the subnet is reused as a second network would.
+func addresses(r *rand.Rand, nodes, subnet int) []uint32 {
+	if subnet <= 0 {
+		subnet = DefaultSubnetSize
+	}
+	addrs := make([]uint32, 0, nodes)
+	for len(addrs) < nodes {
+		for _, a := range r.Perm(subnet) {
+			if len(addrs) == nodes {
+				break
+			}
+			addrs = append(addrs, uint32(a))
+		}
+	}
+	return addrs
+}
+
+var strategies = map[string]Strategy{
+	"random":        Random,
+	"sequential":    Sequential,
+	"ip-low-bits":   IPLowBits,
+	"ip-hash":       IPHash,
+	"hostname-hash": HostnameHash,
+}
+
+// StrategyNames returns the names of the registered strategies in order.
+func StrategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StrategyByName returns a registered strategy.
+func StrategyByName(name string) (Strategy, error) {
+	s, ok := strategies[name]
+	if !ok {
+		return nil, ErrUnknownStrategy
+	}
+	return s, nil
+}
+
+// Config configures a collision simulation:
+//
+// Nodes is the number of generators sharing the machine ID space.
+//
+// Trials is the number of simulated deployments.
+//
+// Bits is the bit length of the machine ID.
+// If Bits is 0, dxyflake.BitLenMachineID is used.
+//
+// SubnetSize is the number of addresses IP based strategies draw from.
+// If SubnetSize is 0, DefaultSubnetSize is used.
+//
+// Seed seeds the random source, so a simulation can be repeated.
+type Config struct {
+	Nodes      int
+	Trials     int
+	Bits       uint
+	SubnetSize int
+	Seed       int64
+}
+
+// Result is the outcome of a collision simulation.
+type Result struct {
+	Nodes           int
+	Trials          int
+	CollidingTrials int     // trials with at least one shared machine ID
+	Probability     float64 // CollidingTrials / Trials
+	MeanColliding   float64 // mean number of nodes sharing their machine ID per trial
+}
+
+// Collisions simulates deployments using strategy and reports how often two
+// or more nodes end up with the same machine ID.
+func Collisions(strategy Strategy, c Config) (Result, error) {
+	if c.Bits == 0 {
+		c.Bits = dxyflake.BitLenMachineID
+	}
+	if c.SubnetSize == 0 {
+		c.SubnetSize = DefaultSubnetSize
+	}
+	if strategy == nil || c.Nodes <= 0 || c.Trials <= 0 || c.Bits > 16 || c.SubnetSize < 0 {
+		return Result{}, ErrInvalidConfig
+	}
+
+	space := 1 << c.Bits
+	r := rand.New(rand.NewSource(c.Seed))
+	res := Result{Nodes: c.Nodes, Trials: c.Trials}
+	counts := make([]int, space)
+	var colliding int
+	for i := 0; i < c.Trials; i++ {
+		for j := range counts {
+			counts[j] = 0
+		}
+		for _, id := range strategy(r, c.Nodes, space, c.SubnetSize) {
+			counts[id]++
+		}
+
+		n := 0
+		for _, cnt := range counts {
+			if cnt > 1 {
+				n += cnt
+			}
+		}
+		if n > 0 {
+			res.CollidingTrials++
+		}
+		colliding += n
+	}
+
+	res.Probability = float64(res.CollidingTrials) / float64(res.Trials)
+	res.MeanColliding = float64(colliding) / float64(res.Trials)
+	return res, nil
+}
+
+// BirthdayProbability returns the exact probability that at least two of
+// nodes uniformly random machine IDs in a space of the given size collide.
+func BirthdayProbability(nodes, space int) float64 {
+	if nodes > space {
+		return 1
+	}
+	p := 1.0
+	for i := 0; i < nodes; i++ {
+		p *= float64(space-i) / float64(space)
+	}
+	return 1 - p
+}
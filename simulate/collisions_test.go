@@ -0,0 +1,76 @@
+package simulate
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCollisionsSequential(t *testing.T) {
+	res, err := Collisions(Sequential, Config{Nodes: 32, Trials: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CollidingTrials != 0 {
+		t.Errorf("unexpected collisions: %d", res.CollidingTrials)
+	}
+
+	res, err = Collisions(Sequential, Config{Nodes: 33, Trials: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Probability != 1 || res.MeanColliding != 2 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestCollisionsRandom(t *testing.T) {
+	const nodes = 8
+	res, err := Collisions(Random, Config{Nodes: nodes, Trials: 20000, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := BirthdayProbability(nodes, 32)
+	if math.Abs(res.Probability-want) > 0.02 {
+		t.Errorf("probability %f, want about %f", res.Probability, want)
+	}
+}
+
+func TestCollisionsIPLowBits(t *testing.T) {
+	// a /27 has exactly as many addresses as machine ids
+	res, err := Collisions(IPLowBits, Config{Nodes: 32, Trials: 100, SubnetSize: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CollidingTrials != 0 {
+		t.Errorf("unexpected collisions: %d", res.CollidingTrials)
+	}
+}
+
+func TestStrategyByName(t *testing.T) {
+	for _, name := range StrategyNames() {
+		s, err := StrategyByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Collisions(s, Config{Nodes: 4, Trials: 10}); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+
+	if _, err := StrategyByName("dice"); err != ErrUnknownStrategy {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBirthdayProbability(t *testing.T) {
+	if p := BirthdayProbability(1, 32); p != 0 {
+		t.Errorf("unexpected probability: %f", p)
+	}
+	if p := BirthdayProbability(33, 32); p != 1 {
+		t.Errorf("unexpected probability: %f", p)
+	}
+	if p := BirthdayProbability(7, 32); p < 0.5 || p > 0.52 {
+		t.Errorf("unexpected probability: %f", p)
+	}
+}
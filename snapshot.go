@@ -0,0 +1,86 @@
+package dxyflake
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+)
+
+// These errors are returned by Restore.
+var (
+	ErrInvalidSnapshot  = errors.New("invalid snapshot")
+	ErrSnapshotMismatch = errors.New("snapshot of another id space")
+)
+
+// snapshotVersion is the first byte of snapshots.
+const snapshotVersion = 1
+
+// snapshotSize is the size of a snapshot: the version, machine ID and
+// service ID, the epoch and unit, the bit lengths, the time unit and
+// sequence number, and a CRC-32 of all of it.
+const snapshotSize = 1 + 2 + 2 + 8 + 8 + 4 + 8 + 2 + 4
+
+// Save returns a snapshot of the position of the dxyflake: the time unit
+// and sequence number of its last ID, with its machine ID, service ID and
+// layout. Written out on shutdown and passed to Restore of the dxyflake
+// that replaces it, it keeps the new dxyflake from issuing the same IDs
+// again, even with the clock stepped back across the restart.
+func (df *dxyflake) Save() ([]byte, error) {
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	b := make([]byte, snapshotSize)
+	b[0] = snapshotVersion
+	binary.BigEndian.PutUint16(b[1:], df.machineID)
+	binary.BigEndian.PutUint16(b[3:], df.serviceID)
+	binary.BigEndian.PutUint64(b[5:], uint64(df.layout.epoch().UnixNano()))
+	binary.BigEndian.PutUint64(b[13:], uint64(df.layout.Unit))
+	b[21] = byte(df.layout.BitLenTime)
+	b[22] = byte(df.layout.BitLenMachineID)
+	b[23] = byte(df.layout.BitLenServiceID)
+	b[24] = byte(df.layout.BitLenSequence)
+	binary.BigEndian.PutUint64(b[25:], uint64(df.elapsedTime))
+	binary.BigEndian.PutUint16(b[33:], df.sequence)
+	binary.BigEndian.PutUint32(b[35:], crc32.ChecksumIEEE(b[:35]))
+	return b, nil
+}
+
+// Restore moves the dxyflake to the position of a snapshot made by Save,
+// so that its next ID comes after the last ID of the saved dxyflake. The
+// snapshot must be of the same machine ID, service ID and layout, or
+// Restore returns ErrSnapshotMismatch. A dxyflake is never moved back.
+//
+// While the clock is behind the restored time unit, the dxyflake treats
+// it as a clock moved back, see Settings.ClockBack.
+func (df *dxyflake) Restore(b []byte) error {
+	if len(b) != snapshotSize || b[0] != snapshotVersion ||
+		binary.BigEndian.Uint32(b[35:]) != crc32.ChecksumIEEE(b[:35]) {
+		return ErrInvalidSnapshot
+	}
+	l := Layout{
+		Epoch:           time.Unix(0, int64(binary.BigEndian.Uint64(b[5:]))).UTC(),
+		Unit:            time.Duration(binary.BigEndian.Uint64(b[13:])),
+		BitLenTime:      int(b[21]),
+		BitLenMachineID: int(b[22]),
+		BitLenServiceID: int(b[23]),
+		BitLenSequence:  int(b[24]),
+	}
+	tick := int64(binary.BigEndian.Uint64(b[25:]))
+	sequence := binary.BigEndian.Uint16(b[33:])
+
+	df.mutex.Lock()
+	defer df.mutex.Unlock()
+
+	if binary.BigEndian.Uint16(b[1:]) != df.machineID ||
+		binary.BigEndian.Uint16(b[3:]) != df.serviceID || !l.equal(df.layout) {
+		return ErrSnapshotMismatch
+	}
+	if tick > df.elapsedTime || (tick == df.elapsedTime && sequence > df.sequence) {
+		df.elapsedTime, df.sequence, df.startSeq = tick, sequence, df.firstSeq
+	}
+	if tick > df.lastTick {
+		df.lastTick = tick
+	}
+	return nil
+}
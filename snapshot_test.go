@@ -0,0 +1,66 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	c := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newDxyflake := func(mID uint16, policy ClockBackPolicy) *dxyflake {
+		var st Settings
+		st.Init(mID, 2)
+		st.Clock = c
+		st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { c.Sleep(d) })
+		st.ClockBack = policy
+		df, err := NewDxyflakeWithError(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return df
+	}
+
+	df := newDxyflake(1, ClockBackContinue)
+	var last ID
+	for i := 0; i < 3; i++ {
+		last, _ = df.NextID()
+	}
+	b, err := df.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// restarted with the clock stepped back
+	c.now = c.now.Add(-time.Minute)
+	restarted := newDxyflake(1, ClockBackContinue)
+	if err := restarted.Restore(b); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := restarted.NextID(); id <= last {
+		t.Errorf("restored dxyflake issued %d, not after %d", id, last)
+	}
+	if err := restarted.Restore(b); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := restarted.NextID(); id <= last+1 {
+		t.Errorf("dxyflake moved back by Restore: %d", id)
+	}
+
+	failing := newDxyflake(1, ClockBackError)
+	failing.Restore(b)
+	if _, err := failing.NextID(); !errors.Is(err, ErrClockMovedBack) {
+		t.Errorf("NextID() behind the snapshot error = %v, want %v", err, ErrClockMovedBack)
+	}
+
+	if err := newDxyflake(3, ClockBackContinue).Restore(b); err != ErrSnapshotMismatch {
+		t.Errorf("Restore() of another machine ID error = %v, want %v", err, ErrSnapshotMismatch)
+	}
+	b[30] ^= 1
+	if err := newDxyflake(1, ClockBackContinue).Restore(b); err != ErrInvalidSnapshot {
+		t.Errorf("Restore() of corrupt snapshot error = %v, want %v", err, ErrInvalidSnapshot)
+	}
+	if err := newDxyflake(1, ClockBackContinue).Restore(b[:10]); err != ErrInvalidSnapshot {
+		t.Errorf("Restore() of short snapshot error = %v, want %v", err, ErrInvalidSnapshot)
+	}
+}
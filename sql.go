@@ -0,0 +1,44 @@
+package dxyflake
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrNullID is returned by ID.Scan for NULL values. Nullable columns are
+// scanned into a *ID or a sql.NullInt64 instead.
+var ErrNullID = errors.New("cannot scan NULL into ID")
+
+// Value implements driver.Valuer, storing the ID as a BIGINT.
+func (f ID) Value() (driver.Value, error) {
+	return int64(f), nil
+}
+
+// Scan implements sql.Scanner, reading the ID from a BIGINT column, or from
+// a text column holding it in decimal.
+func (f *ID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*f = ID(v)
+	case []byte:
+		return f.scanString(string(v))
+	case string:
+		return f.scanString(v)
+	case nil:
+		return ErrNullID
+	default:
+		return fmt.Errorf("cannot scan %T into ID", src)
+	}
+	return nil
+}
+
+func (f *ID) scanString(s string) error {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*f = ID(i)
+	return nil
+}
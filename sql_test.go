@@ -0,0 +1,31 @@
+package dxyflake
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	_ driver.Valuer = ID(0)
+	_ sql.Scanner   = (*ID)(nil)
+)
+
+func TestScan(t *testing.T) {
+	const want = ID(475370495148032)
+	for _, src := range []interface{}{int64(want), "475370495148032", []byte("475370495148032")} {
+		var id ID
+		if err := id.Scan(src); err != nil || id != want {
+			t.Errorf("Scan(%#v) = %d, %v", src, id, err)
+		}
+	}
+	for _, src := range []interface{}{nil, "x1", 1.5} {
+		var id ID
+		if err := id.Scan(src); err == nil {
+			t.Errorf("Scan(%#v) succeeded", src)
+		}
+	}
+	if v, err := want.Value(); err != nil || v != int64(want) {
+		t.Errorf("Value() = %v, %v", v, err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package sqlexpr writes SQL expressions that decompose IDs stored in a
+// database column, so that analysts read IDs in the warehouse the way
+// dxyflake.Layout.Decompose and TimeOf do.
+package sqlexpr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrUnknownDialect is returned for dialects the package does not know.
+var ErrUnknownDialect = errors.New("unknown sql dialect")
+
+// Dialect is a SQL dialect.
+type Dialect string
+
+// These are the supported dialects.
+const (
+	MySQL      Dialect = "mysql"
+	PostgreSQL Dialect = "postgresql"
+	ClickHouse Dialect = "clickhouse"
+	BigQuery   Dialect = "bigquery"
+)
+
+// Dialects are the supported dialects.
+var Dialects = []Dialect{MySQL, PostgreSQL, ClickHouse, BigQuery}
+
+// Exprs are the expressions of the parts of the IDs of a column. Time is
+// the time part, Timestamp the time at which its time unit begins: a
+// DATETIME(6) in the session time zone for MySQL, a timestamptz for
+// PostgreSQL, a DateTime64 for ClickHouse and a TIMESTAMP for BigQuery.
+type Exprs struct {
+	Time      string
+	Timestamp string
+	MachineID string
+	ServiceID string
+	Sequence  string
+}
+
+// New returns the expressions decomposing the IDs of layout l stored in
+// column as a signed 64-bit integer. column is copied as is, so it may be
+// qualified or quoted.
+func New(d Dialect, column string, l dxyflake.Layout) (Exprs, error) {
+	if err := l.Validate(); err != nil {
+		return Exprs{}, err
+	}
+	m := l.Masks()
+	var shift func(x string, n uint) string
+	var and func(x string, n int64) string
+	switch d {
+	case MySQL, PostgreSQL, BigQuery:
+		shift = func(x string, n uint) string { return fmt.Sprintf("(%s >> %d)", x, n) }
+		and = func(x string, n int64) string { return fmt.Sprintf("(%s & %d)", x, n) }
+	case ClickHouse:
+		shift = func(x string, n uint) string { return fmt.Sprintf("bitShiftRight(%s, %d)", x, n) }
+		and = func(x string, n int64) string { return fmt.Sprintf("bitAnd(%s, %d)", x, n) }
+	default:
+		return Exprs{}, fmt.Errorf("%w: %s", ErrUnknownDialect, d)
+	}
+
+	e := Exprs{
+		Time:      and(shift(column, m.ShiftTime), m.MaskTime),
+		MachineID: and(shift(column, m.ShiftMachineID), m.MaskMachineID),
+		ServiceID: and(shift(column, m.ShiftServiceID), m.MaskServiceID),
+		Sequence:  and(column, m.MaskSequence),
+	}
+
+	// The timestamp is counted in the largest of milliseconds,
+	// microseconds and nanoseconds the unit is a whole number of.
+	epoch := l.Describe().Epoch
+	scale, suffix := time.Millisecond, "Milli"
+	if l.Unit%time.Microsecond != 0 {
+		scale, suffix = time.Nanosecond, "Nano"
+	} else if l.Unit%time.Millisecond != 0 {
+		scale, suffix = time.Microsecond, "Micro"
+	}
+	units := fmt.Sprintf("(%d + %s * %d)", epoch.UnixNano()/int64(scale), e.Time, int64(l.Unit/scale))
+	per := int64(time.Second / scale)
+	switch d {
+	case MySQL:
+		e.Timestamp = fmt.Sprintf("FROM_UNIXTIME(%s / %d)", units, per)
+	case PostgreSQL:
+		e.Timestamp = fmt.Sprintf("to_timestamp(%s / %d.0)", units, per)
+	case ClickHouse:
+		e.Timestamp = fmt.Sprintf("fromUnixTimestamp64%s(toInt64%s, 'UTC')", suffix, units)
+	case BigQuery:
+		// BigQuery timestamps have microsecond precision
+		switch scale {
+		case time.Millisecond:
+			e.Timestamp = fmt.Sprintf("TIMESTAMP_MILLIS%s", units)
+		case time.Microsecond:
+			e.Timestamp = fmt.Sprintf("TIMESTAMP_MICROS%s", units)
+		default:
+			e.Timestamp = fmt.Sprintf("TIMESTAMP_MICROS(DIV(%s, 1000))", units)
+		}
+	}
+	return e, nil
+}
+
+// Select returns the expressions as a SELECT list, each named after its
+// part as in dxyflake.Decompose, e.g. "machine_id".
+func (e Exprs) Select() string {
+	return strings.Join([]string{
+		e.Timestamp + " AS timestamp",
+		e.Time + " AS time",
+		e.MachineID + " AS machine_id",
+		e.ServiceID + " AS service_id",
+		e.Sequence + " AS sequence",
+	}, ",\n")
+}
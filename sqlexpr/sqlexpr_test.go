@@ -0,0 +1,50 @@
+package sqlexpr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		dialect   Dialect
+		time      string
+		timestamp string
+	}{
+		{MySQL, "((id >> 22) & 2199023255551)", "FROM_UNIXTIME((1633046400000 + ((id >> 22) & 2199023255551) * 10) / 1000)"},
+		{PostgreSQL, "((id >> 22) & 2199023255551)", "to_timestamp((1633046400000 + ((id >> 22) & 2199023255551) * 10) / 1000.0)"},
+		{ClickHouse, "bitAnd(bitShiftRight(id, 22), 2199023255551)", "fromUnixTimestamp64Milli(toInt64(1633046400000 + bitAnd(bitShiftRight(id, 22), 2199023255551) * 10), 'UTC')"},
+		{BigQuery, "((id >> 22) & 2199023255551)", "TIMESTAMP_MILLIS(1633046400000 + ((id >> 22) & 2199023255551) * 10)"},
+	}
+	for _, tt := range tests {
+		e, err := New(tt.dialect, "id", dxyflake.DefaultLayout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Time != tt.time || e.Timestamp != tt.timestamp {
+			t.Errorf("%s: unexpected expressions %+v", tt.dialect, e)
+		}
+	}
+
+	e, _ := New(PostgreSQL, "t.id", dxyflake.DefaultLayout)
+	if e.MachineID != "((t.id >> 17) & 31)" || e.ServiceID != "((t.id >> 12) & 31)" || e.Sequence != "(t.id & 4095)" {
+		t.Errorf("unexpected expressions %+v", e)
+	}
+
+	l := dxyflake.Layout{Epoch: time.Unix(1, 0), Unit: 100 * time.Microsecond, BitLenTime: 41, BitLenSequence: 12}
+	if e, _ := New(ClickHouse, "id", l); e.Timestamp != "fromUnixTimestamp64Micro(toInt64(1000000 + bitAnd(bitShiftRight(id, 12), 2199023255551) * 100), 'UTC')" {
+		t.Errorf("unexpected timestamp %s", e.Timestamp)
+	}
+
+	l.Unit = 500 * time.Nanosecond
+	if e, _ := New(BigQuery, "id", l); e.Timestamp != "TIMESTAMP_MICROS(DIV((1000000000 + ((id >> 12) & 2199023255551) * 500), 1000))" {
+		t.Errorf("unexpected timestamp %s", e.Timestamp)
+	}
+
+	if _, err := New("oracle", "id", dxyflake.DefaultLayout); !errors.Is(err, ErrUnknownDialect) {
+		t.Errorf("unknown dialect accepted: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package sqlexpr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// ErrNoUDF is returned by UDFs for dialects it writes no functions for.
+var ErrNoUDF = errors.New("no udf support for sql dialect")
+
+// UDFs returns the statements creating, or replacing, one function per
+// part of the IDs of layout l, so that warehouse functions are generated
+// from the layout rather than written by hand. The functions take the ID
+// as a signed 64-bit integer and are named after name and their part,
+// e.g. dxyflake_machine_id for name "dxyflake". For BigQuery, name may be
+// qualified with a dataset, e.g. "analytics.dxyflake". Only ClickHouse and
+// BigQuery are supported.
+func UDFs(d Dialect, name string, l dxyflake.Layout) (string, error) {
+	var def func(fn, expr string) string
+	switch d {
+	case ClickHouse:
+		def = func(fn, expr string) string {
+			return fmt.Sprintf("CREATE OR REPLACE FUNCTION %s AS (id) -> %s;", fn, expr)
+		}
+	case BigQuery:
+		def = func(fn, expr string) string {
+			return fmt.Sprintf("CREATE OR REPLACE FUNCTION %s(id INT64) AS (%s);", fn, expr)
+		}
+	default:
+		return "", fmt.Errorf("%w: %s", ErrNoUDF, d)
+	}
+
+	e, err := New(d, "id", l)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		def(name+"_timestamp", e.Timestamp),
+		def(name+"_time", e.Time),
+		def(name+"_machine_id", e.MachineID),
+		def(name+"_service_id", e.ServiceID),
+		def(name+"_sequence", e.Sequence),
+	}, "\n") + "\n", nil
+}
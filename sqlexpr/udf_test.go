@@ -0,0 +1,39 @@
+package sqlexpr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestUDFs(t *testing.T) {
+	s, err := UDFs(ClickHouse, "dxyflake", dxyflake.DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "CREATE OR REPLACE FUNCTION dxyflake_machine_id AS (id) -> bitAnd(bitShiftRight(id, 17), 31);\n"; !strings.Contains(s, want) {
+		t.Errorf("missing %q in\n%s", want, s)
+	}
+
+	s, err = UDFs(BigQuery, "analytics.dxyflake", dxyflake.DefaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"CREATE OR REPLACE FUNCTION analytics.dxyflake_timestamp(id INT64) AS (TIMESTAMP_MILLIS(1633046400000 + ((id >> 22) & 2199023255551) * 10));\n",
+		"CREATE OR REPLACE FUNCTION analytics.dxyflake_sequence(id INT64) AS ((id & 4095));\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %q in\n%s", want, s)
+		}
+	}
+	if n := strings.Count(s, "CREATE"); n != 5 {
+		t.Errorf("%d functions, want 5", n)
+	}
+
+	if _, err := UDFs(MySQL, "dxyflake", dxyflake.DefaultLayout); !errors.Is(err, ErrNoUDF) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package sqlite keeps machine ID leases and high-water marks in a SQLite
+// database, for edge deployments without a network datastore.
+//
+// A Store implements lease.Backend and lease.Inspector, so a coordinator.Coordinator can claim
+// machine IDs from it, and handoff.Store, so drained generators can record
+// their marks in the same file. The package works on a *sql.DB and leaves
+// the choice of driver to the caller:
+//
+//	import _ "modernc.org/sqlite"
+//
+//	db, err := sql.Open("sqlite", "/var/lib/dxyflake/state.db")
+//	...
+//	store, err := sqlite.New(ctx, db, sqlite.Options{})
+//
+// New switches the database to WAL mode, so that readers do not block the
+// writer. Operations that find the database locked by another process are
+// retried until Options.BusyTimeout has passed.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+// DefaultBusyTimeout is used when Options.BusyTimeout is 0.
+const DefaultBusyTimeout = 5 * time.Second
+
+// Options configures a Store:
+//
+// BusyTimeout is how long an operation waits for a database locked by
+// another connection or process.
+// If BusyTimeout is 0, DefaultBusyTimeout is used.
+type Options struct {
+	BusyTimeout time.Duration
+}
+
+// Store is a lease.Backend and handoff.Store on a SQLite database.
+type Store struct {
+	db   *sql.DB
+	opts Options
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS dxyflake_leases (
+	key     TEXT PRIMARY KEY,
+	holder  TEXT NOT NULL,
+	expires INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS dxyflake_marks (
+	machine_id INTEGER NOT NULL,
+	service_id INTEGER NOT NULL,
+	next       INTEGER NOT NULL,
+	PRIMARY KEY (machine_id, service_id)
+);`
+
+// New returns a Store on db. It enables WAL mode and creates the tables of
+// the store if they do not exist.
+func New(ctx context.Context, db *sql.DB, opts Options) (*Store, error) {
+	if opts.BusyTimeout == 0 {
+		opts.BusyTimeout = DefaultBusyTimeout
+	}
+	s := &Store{db: db, opts: opts}
+
+	err := s.retry(ctx, func() error {
+		var mode string
+		if err := db.QueryRowContext(ctx, "PRAGMA journal_mode=WAL").Scan(&mode); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, "PRAGMA busy_timeout="+strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+		_, err := db.ExecContext(ctx, schema)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// isBusy reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED.
+// The drivers do not share an error type, but all of them keep SQLite's
+// messages.
+func isBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "SQLITE_BUSY")
+}
+
+// retry calls f until it succeeds, fails with an error other than a busy
+// database, or BusyTimeout has passed.
+func (s *Store) retry(ctx context.Context, f func() error) error {
+	deadline := time.Now().Add(s.opts.BusyTimeout)
+	backoff := time.Millisecond
+	for {
+		err := f()
+		if !isBusy(err) || time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if backoff < 50*time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
+// exec runs query and returns the number of rows it changed.
+func (s *Store) exec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	var n int64
+	err := s.retry(ctx, func() error {
+		res, err := s.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return n, err
+}
+
+// Acquire implements lease.Backend.
+func (s *Store) Acquire(ctx context.Context, key, holder string, ttl time.Duration) error {
+	now := time.Now()
+	n, err := s.exec(ctx, `
+INSERT INTO dxyflake_leases (key, holder, expires) VALUES (?, ?, ?)
+ON CONFLICT (key) DO UPDATE SET holder = excluded.holder, expires = excluded.expires
+WHERE dxyflake_leases.holder = excluded.holder OR dxyflake_leases.expires <= ?`,
+		key, holder, now.Add(ttl).UnixNano(), now.UnixNano())
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return lease.ErrHeld
+	}
+	return nil
+}
+
+// Renew implements lease.Backend.
+func (s *Store) Renew(ctx context.Context, key, holder string, ttl time.Duration) error {
+	now := time.Now()
+	n, err := s.exec(ctx, `
+UPDATE dxyflake_leases SET expires = ?
+WHERE key = ? AND holder = ? AND expires > ?`,
+		now.Add(ttl).UnixNano(), key, holder, now.UnixNano())
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return lease.ErrNotHeld
+	}
+	return nil
+}
+
+// Release implements lease.Backend.
+func (s *Store) Release(ctx context.Context, key, holder string) error {
+	_, err := s.exec(ctx, "DELETE FROM dxyflake_leases WHERE key = ? AND holder = ?", key, holder)
+	return err
+}
+
+// Holder implements lease.Inspector.
+func (s *Store) Holder(ctx context.Context, key string) (string, bool, error) {
+	var holder string
+	err := s.retry(ctx, func() error {
+		return s.db.QueryRowContext(ctx,
+			"SELECT holder FROM dxyflake_leases WHERE key = ? AND expires > ?",
+			key, time.Now().UnixNano()).Scan(&holder)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return holder, true, nil
+}
+
+// SaveMark implements handoff.Store. It keeps the later mark if a mark is
+// already stored.
+func (s *Store) SaveMark(ctx context.Context, mark dxyflake.Mark) error {
+	_, err := s.exec(ctx, `
+INSERT INTO dxyflake_marks (machine_id, service_id, next) VALUES (?, ?, ?)
+ON CONFLICT (machine_id, service_id) DO UPDATE SET next = excluded.next
+WHERE excluded.next > dxyflake_marks.next`,
+		mark.MachineID, mark.ServiceID, mark.Next.UnixNano())
+	return err
+}
+
+// LoadMark implements handoff.Store.
+func (s *Store) LoadMark(ctx context.Context, machineID, serviceID uint16) (dxyflake.Mark, bool, error) {
+	mark := dxyflake.Mark{MachineID: machineID, ServiceID: serviceID}
+	var next int64
+	err := s.retry(ctx, func() error {
+		return s.db.QueryRowContext(ctx,
+			"SELECT next FROM dxyflake_marks WHERE machine_id = ? AND service_id = ?",
+			machineID, serviceID).Scan(&next)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return mark, false, nil
+	}
+	if err != nil {
+		return mark, false, err
+	}
+	mark.Next = time.Unix(0, next)
+	return mark, true, nil
+}
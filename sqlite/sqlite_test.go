@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+	"github.com/GiterLab/dxyflake/lease"
+)
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("database is locked"), true},
+		{errors.New("database is locked (5) (SQLITE_BUSY)"), true},
+		{errors.New("database table is locked: dxyflake_leases"), true},
+		{errors.New("no such table: dxyflake_leases"), false},
+	}
+	for _, tt := range tests {
+		if got := isBusy(tt.err); got != tt.want {
+			t.Errorf("isBusy(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s := &Store{opts: Options{BusyTimeout: 50 * time.Millisecond}}
+	ctx := context.Background()
+	busy := errors.New("database is locked")
+
+	calls := 0
+	err := s.retry(ctx, func() error {
+		if calls++; calls < 3 {
+			return busy
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retry() = %v after %d calls", err, calls)
+	}
+
+	start := time.Now()
+	if err := s.retry(ctx, func() error { return busy }); err != busy {
+		t.Errorf("retry() error = %v, want %v", err, busy)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("busy timeout not applied: %s", elapsed)
+	}
+
+	calls = 0
+	other := errors.New("disk I/O error")
+	if err := s.retry(ctx, func() error { calls++; return other }); err != other || calls != 1 {
+		t.Errorf("retry() = %v after %d calls", err, calls)
+	}
+}
+
+// TestStore runs against a SQLite driver registered as "sqlite" or
+// "sqlite3", if the test binary is built with one.
+func TestStore(t *testing.T) {
+	var driver string
+	for _, name := range sql.Drivers() {
+		if name == "sqlite" || name == "sqlite3" {
+			driver = name
+		}
+	}
+	if driver == "" {
+		t.Skip("no sqlite driver registered")
+	}
+	db, err := sql.Open(driver, filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s, err := New(ctx, db, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mode string
+	if err := db.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal mode = %q, %v", mode, err)
+	}
+
+	if err := s.Acquire(ctx, "k", "a", 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Acquire(ctx, "k", "b", time.Minute); err != lease.ErrHeld {
+		t.Errorf("Acquire() error = %v, want %v", err, lease.ErrHeld)
+	}
+	if err := s.Renew(ctx, "k", "b", time.Minute); err != lease.ErrNotHeld {
+		t.Errorf("Renew() error = %v, want %v", err, lease.ErrNotHeld)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := s.Renew(ctx, "k", "a", time.Minute); err != lease.ErrNotHeld {
+		t.Errorf("Renew() of expired lease error = %v, want %v", err, lease.ErrNotHeld)
+	}
+	if err := s.Acquire(ctx, "k", "b", time.Minute); err != nil {
+		t.Errorf("Acquire() of expired lease error = %v", err)
+	}
+	if holder, ok, err := s.Holder(ctx, "k"); err != nil || !ok || holder != "b" {
+		t.Errorf("Holder() = %q, %v, %v", holder, ok, err)
+	}
+	if err := s.Release(ctx, "k", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Acquire(ctx, "k", "a", time.Minute); err != nil {
+		t.Errorf("Acquire() after release error = %v", err)
+	}
+
+	next := time.Now()
+	for _, mark := range []dxyflake.Mark{
+		{MachineID: 1, ServiceID: 2, Next: next},
+		{MachineID: 1, ServiceID: 2, Next: next.Add(-time.Hour)},
+	} {
+		if err := s.SaveMark(ctx, mark); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mark, ok, err := s.LoadMark(ctx, 1, 2); err != nil || !ok || !mark.Next.Equal(next) {
+		t.Errorf("LoadMark() = %+v, %v, %v", mark, ok, err)
+	}
+	if _, ok, err := s.LoadMark(ctx, 1, 3); err != nil || ok {
+		t.Errorf("LoadMark() of missing mark = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,60 @@
+package dxyflake
+
+import "time"
+
+// staleBefore returns the smallest ID not older than d: IDs below it were
+// issued in time units that ended more than d ago.
+func (l Layout) staleBefore(d time.Duration) ID {
+	return l.MinIDAt(time.Now().Add(-d))
+}
+
+// OlderThan reports whether id was certainly issued more than d ago: the
+// time unit of id ended more than d ago. Cache layers use it to decide
+// whether data derived from an entity is old enough to revalidate.
+func (l Layout) OlderThan(id ID, d time.Duration) bool {
+	return id < l.staleBefore(d)
+}
+
+// FilterOlderThan returns the IDs of ids that are OlderThan d, in order.
+func (l Layout) FilterOlderThan(ids []ID, d time.Duration) []ID {
+	before := l.staleBefore(d)
+	var older []ID
+	for _, id := range ids {
+		if id < before {
+			older = append(older, id)
+		}
+	}
+	return older
+}
+
+// SplitOlderThan splits ids, in order, into those that are OlderThan d and
+// the rest.
+func (l Layout) SplitOlderThan(ids []ID, d time.Duration) (older, newer []ID) {
+	before := l.staleBefore(d)
+	for _, id := range ids {
+		if id < before {
+			older = append(older, id)
+		} else {
+			newer = append(newer, id)
+		}
+	}
+	return older, newer
+}
+
+// OlderThan is Layout.OlderThan for IDs of a dxyflake started at epoch.
+// If epoch is 0, DefaultStartTime is used.
+func OlderThan(id ID, d time.Duration, epoch time.Time) bool {
+	return layoutAt(epoch).OlderThan(id, d)
+}
+
+// FilterOlderThan is Layout.FilterOlderThan for IDs of a dxyflake started
+// at epoch. If epoch is 0, DefaultStartTime is used.
+func FilterOlderThan(ids []ID, d time.Duration, epoch time.Time) []ID {
+	return layoutAt(epoch).FilterOlderThan(ids, d)
+}
+
+// SplitOlderThan is Layout.SplitOlderThan for IDs of a dxyflake started at
+// epoch. If epoch is 0, DefaultStartTime is used.
+func SplitOlderThan(ids []ID, d time.Duration, epoch time.Time) (older, newer []ID) {
+	return layoutAt(epoch).SplitOlderThan(ids, d)
+}
@@ -0,0 +1,40 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOlderThan(t *testing.T) {
+	now := time.Now()
+	compose := func(t *testing.T, at time.Time) ID {
+		id, err := DefaultLayout.Compose(at, 1, 2, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	old := compose(t, now.Add(-2*time.Hour))
+	recent := compose(t, now.Add(-time.Minute))
+	// the time unit of edge ends within the hour
+	edge := compose(t, now.Add(-time.Hour+dxyflakeTimeUnit/2))
+
+	if !OlderThan(old, time.Hour, time.Time{}) || OlderThan(recent, time.Hour, time.Time{}) ||
+		OlderThan(edge, time.Hour, time.Time{}) {
+		t.Error("unexpected OlderThan")
+	}
+
+	ids := []ID{recent, old, edge, old + 1}
+	if got := FilterOlderThan(ids, time.Hour, time.Time{}); len(got) != 2 || got[0] != old || got[1] != old+1 {
+		t.Errorf("FilterOlderThan() = %v", got)
+	}
+	older, newer := SplitOlderThan(ids, time.Hour, time.Time{})
+	if len(older) != 2 || len(newer) != 2 || newer[0] != recent || newer[1] != edge {
+		t.Errorf("SplitOlderThan() = %v, %v", older, newer)
+	}
+
+	// IDs of a later epoch are younger
+	if !OlderThan(old, 90*time.Minute, time.Time{}) || OlderThan(old, 90*time.Minute, DefaultStartTime.Add(time.Hour)) {
+		t.Error("epoch ignored")
+	}
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileStore keeps the slot in a file, written with a system call per save.
+type FileStore struct {
+	file *os.File
+	opts Options
+
+	mutex sync.Mutex
+	dirty bool
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// OpenFile opens or creates the state file at path.
+func OpenFile(path string, opts Options) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{file: f, opts: opts}
+	if opts.Sync == SyncInterval {
+		s.stop, s.done = make(chan struct{}), make(chan struct{})
+		go syncEvery(opts.Interval, s.stop, s.done, s.flush)
+	}
+	return s, nil
+}
+
+// Load implements Store.
+func (s *FileStore) Load() (int64, bool, error) {
+	var b [SlotSize]byte
+	if _, err := s.file.ReadAt(b[:], 0); err == io.EOF {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return decode(binary.LittleEndian.Uint64(b[:]))
+}
+
+// Save implements Store.
+func (s *FileStore) Save(tick int64) error {
+	var b [SlotSize]byte
+	binary.LittleEndian.PutUint64(b[:], encode(tick))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err := s.file.WriteAt(b[:], 0); err != nil {
+		return err
+	}
+	if s.opts.Sync == SyncAlways {
+		return s.file.Sync()
+	}
+	s.dirty = true
+	return nil
+}
+
+func (s *FileStore) flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	s.dirty = false
+	return s.file.Sync()
+}
+
+// Close implements Store.
+func (s *FileStore) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	err := s.flush()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// syncEvery calls sync every interval until stop is closed.
+func syncEvery(interval time.Duration, stop <-chan struct{}, done chan<- struct{}, sync func() error) {
+	defer close(done)
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sync()
+		}
+	}
+}
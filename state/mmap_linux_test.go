@@ -0,0 +1,11 @@
+//go:build linux
+// +build linux
+
+package state
+
+import "testing"
+
+func TestMmapStore(t *testing.T) {
+	testStore(t, func(path string, opts Options) (Store, error) { return OpenMmap(path, opts) })
+}
+
@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package state
+
+// MmapStore is not supported on this platform, see OpenMmap.
+type MmapStore struct{}
+
+// OpenMmap returns ErrUnsupported; memory-mapped state is only supported
+// on Linux.
+func OpenMmap(path string, opts Options) (*MmapStore, error) {
+	return nil, ErrUnsupported
+}
+
+// Load implements Store.
+func (s *MmapStore) Load() (int64, bool, error) {
+	return 0, false, ErrUnsupported
+}
+
+// Save implements Store.
+func (s *MmapStore) Save(tick int64) error {
+	return ErrUnsupported
+}
+
+// Close implements Store.
+func (s *MmapStore) Close() error {
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package state persists the last time unit a dxyflake issued IDs from,
+// so that after a restart, even one with the clock stepped back, the
+// generator waits until that time unit has passed instead of issuing IDs
+// again.
+//
+// A Store keeps one 8-byte slot: the tick in the high 48 bits and a
+// checksum of it in the low 16 bits, written in one store so a crash
+// never leaves a torn value behind. FileStore writes the slot with a
+// system call per tick; MmapStore, on Linux, stores it in a memory-mapped
+// file and syncs it to disk by a SyncPolicy.
+package state
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+var (
+	// ErrCorrupt is returned by Store.Load for slots whose checksum does
+	// not match.
+	ErrCorrupt = errors.New("corrupt state slot")
+	// ErrNotCreated is returned by Open when the dxyflake is not created.
+	ErrNotCreated = errors.New("dxyflake not created")
+	// ErrUnsupported is returned by OpenMmap on platforms without it.
+	ErrUnsupported = errors.New("memory-mapped state is not supported on this platform")
+)
+
+// SlotSize is the size of the state slot at the start of a state file.
+const SlotSize = 8
+
+// SyncPolicy tells a store when to flush a saved slot to disk. Without a
+// sync, a saved slot survives a crash of the process but not of the host.
+type SyncPolicy int
+
+// These are the sync policies.
+const (
+	SyncAlways   SyncPolicy = iota // sync on every save
+	SyncInterval                   // sync in the background every Options.Interval
+	SyncNever                      // leave syncing to the operating system
+)
+
+// DefaultInterval is the sync interval used when Options.Interval is 0.
+const DefaultInterval = 100 * time.Millisecond
+
+// Options configures a store.
+//
+// Sync is when saved slots are flushed to disk.
+//
+// Interval is the time between syncs of SyncInterval.
+// If Interval is 0, DefaultInterval is used.
+type Options struct {
+	Sync     SyncPolicy
+	Interval time.Duration
+}
+
+// Store persists the last tick a generator issued IDs from.
+type Store interface {
+	// Load returns the saved tick, and false if none was saved.
+	Load() (tick int64, ok bool, err error)
+	// Save saves tick. Saved ticks only increase.
+	Save(tick int64) error
+	// Close syncs and closes the store.
+	Close() error
+}
+
+// encode returns the slot of tick, which must fit 48 bits.
+func encode(tick int64) uint64 {
+	return uint64(tick)<<16 | uint64(checksum(tick))
+}
+
+// decode returns the tick of a slot, and false for an empty slot.
+func decode(slot uint64) (int64, bool, error) {
+	if slot == 0 {
+		return 0, false, nil
+	}
+	tick := int64(slot >> 16)
+	if uint16(slot) != checksum(tick) {
+		return 0, false, ErrCorrupt
+	}
+	return tick, true, nil
+}
+
+func checksum(tick int64) uint16 {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(tick))
+	return uint16(crc32.Checksum(b[:], castagnoli))
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Generator is a dxyflake that saves the tick of its IDs to a Store
+// before returning them. It saves once per tick, not per ID.
+type Generator struct {
+	gen   dxyflake.Generator
+	masks dxyflake.Masks
+	store Store
+
+	last   int64 // atomic, the last saved tick
+	mutex  sync.Mutex
+	closed bool
+}
+
+// Open waits until the tick saved in store has passed and returns a
+// Generator of a dxyflake created with st. It returns ErrNotCreated, with
+// the reason, if the dxyflake is not created.
+func Open(ctx context.Context, store Store, st dxyflake.Settings) (*Generator, error) {
+	last, ok, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	df, err := dxyflake.NewDxyflakeWithError(st)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotCreated, err)
+	}
+	if ok {
+		clock := st.Clock
+		if clock == nil {
+			clock = dxyflake.SystemClock
+		}
+		if wait := df.TickTime(last + 1).Sub(clock.Now()); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return &Generator{gen: df, masks: df.Layout().Masks(), store: store, last: last}, nil
+}
+
+// NextID issues an ID, saving its tick first if it is later than the last
+// saved one. It returns the error of the store, without an ID, if saving
+// fails.
+func (g *Generator) NextID() (dxyflake.ID, error) {
+	id, err := g.gen.NextID()
+	if err != nil {
+		return 0, err
+	}
+	tick := g.masks.TimeBits(id)
+	if tick <= atomic.LoadInt64(&g.last) {
+		return id, nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.closed {
+		return 0, dxyflake.ErrClosed
+	}
+	if tick > g.last {
+		if err := g.store.Save(tick); err != nil {
+			return 0, err
+		}
+		atomic.StoreInt64(&g.last, tick)
+	}
+	return id, nil
+}
+
+// Close closes the dxyflake, waits for a tick being saved, and syncs and
+// closes the store. Every later NextID returns dxyflake.ErrClosed, so that
+// no ID is issued past the last saved tick.
+func (g *Generator) Close() error {
+	if c, ok := g.gen.(interface{ Close() error }); ok {
+		c.Close()
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	return g.store.Close()
+}
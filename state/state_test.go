@@ -0,0 +1,130 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func TestSlot(t *testing.T) {
+	for _, tick := range []int64{0, 1, 1<<41 - 1, 1<<48 - 1} {
+		got, ok, err := decode(encode(tick))
+		if err != nil || !ok || got != tick {
+			t.Errorf("decode(encode(%d)) = %d, %v, %v", tick, got, ok, err)
+		}
+	}
+	if _, ok, err := decode(0); ok || err != nil {
+		t.Errorf("empty slot: %v, %v", ok, err)
+	}
+	if _, _, err := decode(encode(12345) ^ 1<<20); err != ErrCorrupt {
+		t.Errorf("corrupt slot: %v", err)
+	}
+}
+
+// testStore checks the stores opened by open.
+func testStore(t *testing.T, open func(path string, opts Options) (Store, error)) {
+	path := filepath.Join(t.TempDir(), "dxyflake.state")
+	for _, sync := range []SyncPolicy{SyncAlways, SyncInterval, SyncNever} {
+		os.Remove(path)
+		s, err := open(path, Options{Sync: sync, Interval: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok, err := s.Load(); ok || err != nil {
+			t.Errorf("new store: %v, %v", ok, err)
+		}
+		for _, tick := range []int64{7, 8, 1 << 40} {
+			if err := s.Save(tick); err != nil {
+				t.Fatal(err)
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err = open(path, Options{Sync: sync})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tick, ok, err := s.Load(); !ok || err != nil || tick != 1<<40 {
+			t.Errorf("reopened store: %d, %v, %v", tick, ok, err)
+		}
+		s.Close()
+	}
+
+	if err := os.WriteFile(path, []byte{1, 2, 3, 4, 5, 6, 7, 8}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, _, err := s.Load(); err != ErrCorrupt {
+		t.Errorf("corrupt file: %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, func(path string, opts Options) (Store, error) { return OpenFile(path, opts) })
+}
+
+func TestGenerator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dxyflake.state")
+	s, err := OpenFile(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st dxyflake.Settings
+	st.Init(1, 2)
+	g, err := Open(context.Background(), s, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := g.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tick, ok, _ := s.Load(); !ok || tick != dxyflake.TimeBits(id) {
+		t.Errorf("saved tick %d, want %d", tick, dxyflake.TimeBits(id))
+	}
+
+	// a generator restarted with its clock behind waits for the saved tick
+	ahead := dxyflake.TimeBits(id) + 5
+	if err := s.Save(ahead); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if g, err = Open(context.Background(), s, st); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Errorf("did not wait for the saved tick: %v", time.Since(start))
+	}
+	if id, err := g.NextID(); err != nil || dxyflake.TimeBits(id) <= ahead {
+		t.Errorf("issued %d at tick %d, saved %d: %v", id, dxyflake.TimeBits(id), ahead, err)
+	}
+
+	if err := s.Save(dxyflake.TimeBits(id) + 1000); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Open(ctx, s, st); err != context.DeadlineExceeded {
+		t.Errorf("Open() error = %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := g.NextID(); err != dxyflake.ErrClosed {
+		t.Errorf("NextID() after Close = %v", err)
+	}
+	if err := g.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package supervise runs background tasks under a supervisor that restarts
+// them by policy and reports their failures in one place.
+//
+// Components that run goroutines, such as lease renewal or event sending,
+// take a Supervisor in their options and run them as tasks of it. A task
+// that fails beyond its restart policy stops the whole supervisor, and
+// Wait returns its error, so that failures are not lost in a goroutine and
+// shutdown stops everything at once:
+//
+//	sup := supervise.New(ctx, supervise.Options{})
+//	l, err := lease.Acquire(ctx, backend, key, holder, lease.Options{Supervisor: sup})
+//	...
+//	err = sup.Wait() // the first fatal failure, such as a lost lease
+//
+// Supervisors nest: a task may run a child supervisor and return its Wait.
+package supervise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// These are the defaults used when Spec fields are left zero.
+const (
+	DefaultBackoff    = 100 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// ErrStopped is returned by Go after the supervisor has stopped.
+var ErrStopped = errors.New("supervisor stopped")
+
+// Policy tells when a task is restarted.
+type Policy int
+
+// These are the restart policies.
+const (
+	Never     Policy = iota // a failure is fatal, a return ends the task
+	OnFailure               // restart after a failure, a return ends the task
+	Always                  // restart after a failure or a return
+)
+
+// Task is a background task. It must return soon after ctx is done; its
+// error is then ignored.
+type Task func(ctx context.Context) error
+
+// Spec describes a supervised task:
+//
+// Name identifies the task in errors and in Status.
+//
+// Restart is the restart policy.
+//
+// MaxRestarts is the number of restarts after which a failure is fatal.
+// If MaxRestarts is 0, the task is restarted indefinitely.
+//
+// Backoff is the wait before the first restart, doubled for every further
+// one up to MaxBackoff, and reset once the task has run for MaxBackoff.
+// If Backoff or MaxBackoff is 0, DefaultBackoff or DefaultMaxBackoff is used.
+type Spec struct {
+	Name        string
+	Run         Task
+	Restart     Policy
+	MaxRestarts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Options configures a Supervisor:
+//
+// OnError is called with every failure of a task, restarted or not.
+type Options struct {
+	OnError func(name string, err error)
+}
+
+// TaskError is a failure of a task.
+type TaskError struct {
+	Name string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Status is the state of a task.
+type Status struct {
+	Name     string
+	Running  bool
+	Restarts int
+	Err      error // last failure
+}
+
+// Supervisor runs tasks until its context is done, Stop is called or a
+// task fails fatally.
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   Options
+	wg     sync.WaitGroup
+
+	mutex  sync.Mutex
+	err    error
+	status map[string]*Status
+}
+
+// New returns a Supervisor whose tasks run until ctx is done.
+func New(ctx context.Context, opts Options) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Supervisor{ctx: ctx, cancel: cancel, opts: opts, status: make(map[string]*Status)}
+}
+
+// Context returns the context of the tasks, done once the supervisor stops.
+func (s *Supervisor) Context() context.Context {
+	return s.ctx
+}
+
+// Go starts a task. It returns ErrStopped if the supervisor has stopped.
+func (s *Supervisor) Go(spec Spec) error {
+	if spec.Backoff <= 0 {
+		spec.Backoff = DefaultBackoff
+	}
+	if spec.MaxBackoff <= 0 {
+		spec.MaxBackoff = DefaultMaxBackoff
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ctx.Err() != nil {
+		return ErrStopped
+	}
+	st := &Status{Name: spec.Name, Running: true}
+	s.status[spec.Name] = st
+	s.wg.Add(1)
+	go s.supervise(spec, st)
+	return nil
+}
+
+func (s *Supervisor) supervise(spec Spec, st *Status) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		st.Running = false
+		s.mutex.Unlock()
+	}()
+
+	backoff := spec.Backoff
+	for {
+		start := time.Now()
+		err := run(s.ctx, spec.Run)
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err == nil && spec.Restart != Always {
+			return
+		}
+		if err != nil {
+			s.mutex.Lock()
+			st.Err = err
+			s.mutex.Unlock()
+			if s.opts.OnError != nil {
+				s.opts.OnError(spec.Name, err)
+			}
+			if spec.Restart == Never || spec.MaxRestarts > 0 && st.Restarts >= spec.MaxRestarts {
+				s.fail(&TaskError{Name: spec.Name, Err: err})
+				return
+			}
+		}
+
+		if time.Since(start) >= spec.MaxBackoff {
+			backoff = spec.Backoff
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > spec.MaxBackoff {
+			backoff = spec.MaxBackoff
+		}
+		s.mutex.Lock()
+		st.Restarts++
+		s.mutex.Unlock()
+	}
+}
+
+// run runs task, turning a panic into an error.
+func run(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return task(ctx)
+}
+
+func (s *Supervisor) fail(err error) {
+	s.mutex.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mutex.Unlock()
+	s.cancel()
+}
+
+// Stop stops all tasks and waits for them to return. It returns the first
+// fatal failure, if any.
+func (s *Supervisor) Stop() error {
+	s.cancel()
+	return s.Wait()
+}
+
+// Wait waits until the supervisor stops and all tasks have returned. It
+// returns the first fatal failure, a *TaskError, or nil if the supervisor
+// was stopped by its context or Stop. Without a fatal failure, Wait only
+// returns once the context is done or Stop is called.
+func (s *Supervisor) Wait() error {
+	<-s.ctx.Done()
+	s.mutex.Lock() // let a Go in progress add its task
+	s.mutex.Unlock()
+	s.wg.Wait()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Status returns the state of every task started, sorted by name.
+func (s *Supervisor) Status() []Status {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := make([]Status, 0, len(s.status))
+	for _, st := range s.status {
+		status = append(status, *st)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
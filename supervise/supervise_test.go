@@ -0,0 +1,75 @@
+package supervise
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRestart(t *testing.T) {
+	var failures []string
+	s := New(context.Background(), Options{OnError: func(name string, err error) {
+		failures = append(failures, name)
+	}})
+
+	var runs int32
+	errFlaky := errors.New("flaky")
+	s.Go(Spec{Name: "flaky", Restart: OnFailure, MaxRestarts: 2, Backoff: time.Millisecond, Run: func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return errFlaky
+	}})
+	s.Go(Spec{Name: "idle", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	err := s.Wait()
+	var te *TaskError
+	if !errors.As(err, &te) || te.Name != "flaky" || !errors.Is(err, errFlaky) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runs != 3 || len(failures) != 3 {
+		t.Errorf("unexpected runs: %d, failures: %v", runs, failures)
+	}
+	for _, st := range s.Status() {
+		if st.Running {
+			t.Errorf("task still running: %+v", st)
+		}
+	}
+	if err := s.Go(Spec{Name: "late", Run: func(context.Context) error { return nil }}); err != ErrStopped {
+		t.Errorf("task started after stop: %v", err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	s := New(context.Background(), Options{})
+	s.Go(Spec{Name: "panics", Run: func(ctx context.Context) error {
+		panic("boom")
+	}})
+	if err := s.Wait(); err == nil || err.Error() != "panics: panic: boom" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	s := New(context.Background(), Options{})
+	var done int32
+	for _, name := range []string{"a", "b"} {
+		s.Go(Spec{Name: name, Restart: Always, Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			atomic.AddInt32(&done, 1)
+			return nil
+		}})
+	}
+	s.Go(Spec{Name: "once", Run: func(ctx context.Context) error { return nil }})
+
+	time.Sleep(10 * time.Millisecond)
+	if st := s.Status(); len(st) != 3 || !st[0].Running || st[2].Running {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	if err := s.Stop(); err != nil || done != 2 {
+		t.Errorf("unexpected stop: %v, %d tasks returned", err, done)
+	}
+}
@@ -0,0 +1,50 @@
+package dxyflake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidToken is returned by ParseSignedToken for tokens that are
+// malformed or whose signature does not verify.
+var ErrInvalidToken = errors.New("invalid token")
+
+// tokenMACSize is the length of the truncated HMAC-SHA256 of a token.
+const tokenMACSize = 12
+
+// SignedToken returns the dxyflake ID in base62 followed by a dot and a
+// truncated HMAC-SHA256 of the ID with key, so that a public API can hand
+// out IDs and reject forged or mistyped ones without a lookup. The ID is
+// not hidden; see Anonymize for that.
+func (f ID) SignedToken(key []byte) string {
+	return f.Base62() + "." + base64.RawURLEncoding.EncodeToString(tokenMAC(f, key))
+}
+
+// ParseSignedToken verifies a token of SignedToken with key and returns its
+// dxyflake ID.
+func ParseSignedToken(token string, key []byte) (ID, error) {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return -1, ErrInvalidToken
+	}
+	id, err := ParseBase62([]byte(token[:i]))
+	if err != nil || id < 0 || id.Base62() != token[:i] {
+		return -1, ErrInvalidToken
+	}
+	// the decoder skips newlines, so compare the written form as well
+	mac, err := base64.RawURLEncoding.Strict().DecodeString(token[i+1:])
+	if err != nil || base64.RawURLEncoding.EncodeToString(mac) != token[i+1:] || !hmac.Equal(mac, tokenMAC(id, key)) {
+		return -1, ErrInvalidToken
+	}
+	return id, nil
+}
+
+func tokenMAC(id ID, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	b := id.IntBytes()
+	mac.Write(b[:])
+	return mac.Sum(nil)[:tokenMACSize]
+}
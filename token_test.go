@@ -0,0 +1,30 @@
+package dxyflake
+
+import "testing"
+
+func TestSignedToken(t *testing.T) {
+	key := []byte("secret")
+	id := ID(475370495148032)
+	token := id.SignedToken(key)
+
+	got, err := ParseSignedToken(token, key)
+	if err != nil || got != id {
+		t.Fatalf("ParseSignedToken(%q) = %d, %v", token, got, err)
+	}
+
+	forged := (id + 1).Base62() + token[len(id.Base62()):]
+	for _, s := range []string{
+		forged,
+		id.Base62(),
+		"." + token[len(id.Base62())+1:],
+		token + "A",
+		"0" + token,
+	} {
+		if _, err := ParseSignedToken(s, key); err != ErrInvalidToken {
+			t.Errorf("ParseSignedToken(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseSignedToken(token, []byte("other")); err != ErrInvalidToken {
+		t.Errorf("token verifies with another key: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package dxyflake
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrCheckDigit is returned by StripCheckDigit for IDs whose check digit
+// does not match.
+var ErrCheckDigit = errors.New("check digit mismatch")
+
+// Transform post-processes the IDs of a dxyflake before they are returned,
+// e.g. to add a check digit, obfuscate them or tag them with a tenant, see
+// Settings.Transform.
+type Transform func(ID) (ID, error)
+
+// Chain returns a Transform running ts in order, each on the result of the
+// one before, and stopping at the first error. Nil transforms are skipped.
+func Chain(ts ...Transform) Transform {
+	return func(id ID) (ID, error) {
+		var err error
+		for _, t := range ts {
+			if t == nil {
+				continue
+			}
+			if id, err = t(id); err != nil {
+				return 0, err
+			}
+		}
+		return id, nil
+	}
+}
+
+// AppendCheckDigit is a Transform appending a Luhn check digit to the
+// decimal string of id: it returns id*10 plus the digit, so that its
+// String, and every encoding of it, carries the check. The IDs keep their
+// order but no longer decompose; StripCheckDigit returns the original ID.
+// It returns ErrOutOfRange if id*10 does not fit 63 bits.
+func AppendCheckDigit(id ID) (ID, error) {
+	if id < 0 || id > math.MaxInt64/10-1 {
+		return 0, ErrOutOfRange
+	}
+	return id*10 + ID(luhnDigit(int64(id))), nil
+}
+
+// StripCheckDigit removes the check digit of an ID of AppendCheckDigit and
+// returns the original ID. It returns ErrCheckDigit if the check digit does
+// not match, e.g. for a mistyped ID.
+func StripCheckDigit(id ID) (ID, error) {
+	if id < 0 {
+		return 0, ErrCheckDigit
+	}
+	orig := id / 10
+	if int64(id%10) != luhnDigit(int64(orig)) {
+		return 0, ErrCheckDigit
+	}
+	return orig, nil
+}
+
+// luhnDigit returns the Luhn check digit of the decimal digits of n.
+func luhnDigit(n int64) int64 {
+	var sum int64
+	for double := true; n > 0; n /= 10 {
+		d := n % 10
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,68 @@
+package dxyflake
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTransform(t *testing.T) {
+	for _, c := range []struct {
+		id, want ID
+	}{
+		{0, 0},
+		{7992739871, 79927398713}, // the Luhn example of ISO/IEC 7812
+		{1, 18},
+	} {
+		if got, err := AppendCheckDigit(c.id); err != nil || got != c.want {
+			t.Errorf("AppendCheckDigit(%d) = %d, %v, want %d", c.id, got, err, c.want)
+		}
+		if got, err := StripCheckDigit(c.want); err != nil || got != c.id {
+			t.Errorf("StripCheckDigit(%d) = %d, %v", c.want, got, err)
+		}
+	}
+	// single mistyped digits are caught
+	for pos := 0; pos < 11; pos++ {
+		for d := byte('0'); d <= '9'; d++ {
+			b := []byte("79927398713")
+			if b[pos] == d {
+				continue
+			}
+			b[pos] = d
+			n, _ := strconv.ParseInt(string(b), 10, 64)
+			if _, err := StripCheckDigit(ID(n)); err != ErrCheckDigit {
+				t.Errorf("mistyped %s: %v", b, err)
+			}
+		}
+	}
+	if _, err := AppendCheckDigit(1 << 62); err != ErrOutOfRange {
+		t.Errorf("overflow: %v", err)
+	}
+
+	errTenant := errors.New("no tenant")
+	var calls []ID
+	var st Settings
+	st.Init(5, 6)
+	st.StartTime = time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	st.Transform = Chain(func(id ID) (ID, error) {
+		calls = append(calls, id)
+		return id, nil
+	}, nil, AppendCheckDigit)
+	df := NewDxyflake(st)
+	defer df.Close()
+	ids, err := df.NextIDs(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range ids {
+		if orig, err := StripCheckDigit(id); err != nil || orig != calls[i] {
+			t.Errorf("ID %d: %d, %v, want %d", i, orig, err, calls[i])
+		}
+	}
+
+	df.transform = func(ID) (ID, error) { return 0, errTenant }
+	if _, err := df.NextID(); err != errTenant {
+		t.Errorf("NextID() error = %v, want %v", err, errTenant)
+	}
+}
@@ -0,0 +1,159 @@
+package dxyflake
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidUUID is returned when parsing a malformed UUID string.
+var ErrInvalidUUID = errors.New("invalid uuid")
+
+// ErrForeignUUID is returned when extracting the ID of a UUID that does not
+// embed a dxyflake ID.
+var ErrForeignUUID = errors.New("uuid does not embed a dxyflake id")
+
+// UUID is an RFC 9562 UUID embedding a dxyflake ID, for systems that
+// require UUID columns. Both versions keep the order of the IDs they embed
+// and give the ID back, see FromUUID.
+//
+// A version 7 UUID carries the Unix time in milliseconds of the time part,
+// so that readers of UUIDv7 see the issuing time, followed by the whole ID.
+// A version 8 UUID carries the ID in its most significant bits and needs no
+// layout to be read. The rest of either is a fixed tag telling them from
+// other UUIDs.
+type UUID [16]byte
+
+// uuidTag marks the bits of a UUID not taken by the ID.
+const uuidTag = 0x5d7 // 11 bits
+
+// UUIDv7 returns the version 7 UUID of id of the layout.
+func (l Layout) UUIDv7(id ID) UUID {
+	var u UUID
+	ms := uint64(l.TickTime(l.Masks().TimeBits(id)).UnixNano() / 1e6)
+	binary.BigEndian.PutUint64(u[:8], ms<<16|7<<12|uint64(id)>>51)
+	binary.BigEndian.PutUint64(u[8:], 2<<62|uuidTag<<51|uint64(id)&(1<<51-1))
+	return u
+}
+
+// UUIDv7 returns the version 7 UUID of an ID of DefaultLayout.
+func (f ID) UUIDv7() UUID {
+	return DefaultLayout.UUIDv7(f)
+}
+
+// UUIDv8 returns the version 8 UUID of the ID.
+func (f ID) UUIDv8() UUID {
+	var u UUID
+	binary.BigEndian.PutUint64(u[:8], uint64(f)>>15<<16|8<<12|uint64(f)>>3&0xfff)
+	binary.BigEndian.PutUint64(u[8:], 2<<62|uint64(f)&7<<59|uuidTag<<48)
+	return u
+}
+
+// Version returns the version of the UUID.
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+// FromUUID returns the ID embedded in u by UUIDv7 or UUIDv8, or
+// ErrForeignUUID. A version 7 UUID is only taken if its time matches the
+// time part of the ID in the layout.
+func (l Layout) FromUUID(u UUID) (ID, error) {
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	if lo>>62 != 2 {
+		return 0, ErrForeignUUID
+	}
+	switch u.Version() {
+	case 7:
+		id := ID(hi&0xfff<<51 | lo&(1<<51-1))
+		if lo>>51&(1<<11-1) != uuidTag || l.UUIDv7(id) != u {
+			return 0, ErrForeignUUID
+		}
+		return id, nil
+	case 8:
+		if lo&(1<<59-1) != uuidTag<<48 {
+			return 0, ErrForeignUUID
+		}
+		return ID(hi>>16<<15 | hi&0xfff<<3 | lo>>59&7), nil
+	}
+	return 0, ErrForeignUUID
+}
+
+// FromUUID returns the ID of DefaultLayout embedded in u, see
+// Layout.FromUUID.
+func FromUUID(u UUID) (ID, error) {
+	return DefaultLayout.FromUUID(u)
+}
+
+// String returns the UUID in its canonical form,
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func (u UUID) String() string {
+	b, _ := u.MarshalText()
+	return string(b)
+}
+
+// ParseUUID parses a UUID in its canonical form.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	err := u.UnmarshalText([]byte(s))
+	return u, err
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the canonical
+// form of the UUID.
+func (u UUID) MarshalText() ([]byte, error) {
+	b := make([]byte, 36)
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return b, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, reading the canonical
+// form of a UUID in either case.
+func (u *UUID) UnmarshalText(b []byte) error {
+	if len(b) != 36 || b[8] != '-' || b[13] != '-' || b[18] != '-' || b[23] != '-' {
+		return ErrInvalidUUID
+	}
+	var digits [32]byte
+	copy(digits[0:], b[0:8])
+	copy(digits[8:], b[9:13])
+	copy(digits[12:], b[14:18])
+	copy(digits[16:], b[19:23])
+	copy(digits[20:], b[24:])
+	var v UUID
+	if _, err := hex.Decode(v[:], digits[:]); err != nil {
+		return ErrInvalidUUID
+	}
+	*u = v
+	return nil
+}
+
+// Value implements driver.Valuer, storing the UUID in its canonical form,
+// which UUID columns accept.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Scan implements sql.Scanner, reading the UUID from its canonical form or
+// from its 16 bytes.
+func (u *UUID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return u.UnmarshalText([]byte(v))
+	case []byte:
+		if len(v) == len(u) {
+			copy(u[:], v)
+			return nil
+		}
+		return u.UnmarshalText(v)
+	}
+	return fmt.Errorf("cannot scan %T into UUID", src)
+}
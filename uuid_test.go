@@ -0,0 +1,74 @@
+package dxyflake
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestUUID(t *testing.T) {
+	at := time.Date(2024, 6, 3, 10, 20, 30, 450000000, time.UTC)
+	var ids []ID
+	for _, seq := range []uint16{0, 1, 4095} {
+		id, err := DefaultLayout.Compose(at, 31, 5, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	later, _ := DefaultLayout.Compose(at.Add(10*time.Millisecond), 0, 0, 0)
+	ids = append(ids, later, MaxIDAt(at, time.Time{}))
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var v7s, v8s []string
+	for _, id := range ids {
+		v7, v8 := id.UUIDv7(), id.UUIDv8()
+		if v7.Version() != 7 || v8.Version() != 8 {
+			t.Errorf("%d: versions %d, %d", id, v7.Version(), v8.Version())
+		}
+		if v7[8]>>6 != 2 || v8[8]>>6 != 2 {
+			t.Errorf("%d: variant not RFC 9562", id)
+		}
+		for _, u := range []UUID{v7, v8} {
+			got, err := FromUUID(u)
+			if err != nil || got != id {
+				t.Errorf("FromUUID(%s) = %d, %v, want %d", u, got, err, id)
+			}
+			parsed, err := ParseUUID(u.String())
+			if err != nil || parsed != u {
+				t.Errorf("ParseUUID(%s) = %s, %v", u, parsed, err)
+			}
+		}
+		v7s, v8s = append(v7s, v7.String()), append(v8s, v8.String())
+	}
+	if !sort.StringsAreSorted(v7s) || !sort.StringsAreSorted(v8s) {
+		t.Errorf("UUIDs out of order: %v %v", v7s, v8s)
+	}
+
+	u := ids[0].UUIDv7()
+	if ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(u[2])<<24 | int64(u[3])<<16 | int64(u[4])<<8 | int64(u[5]); ms != at.UnixNano()/1e6 {
+		t.Errorf("UUIDv7 time %d, want %d", ms, at.UnixNano()/1e6)
+	}
+	other := DefaultLayout
+	other.Epoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := other.FromUUID(u); !errors.Is(err, ErrForeignUUID) {
+		t.Errorf("UUIDv7 of another epoch: %v", err)
+	}
+	random, _ := ParseUUID("0190e0f8-7c2a-7b3e-9f5d-3c1a2b4d5e6f")
+	if _, err := FromUUID(random); !errors.Is(err, ErrForeignUUID) {
+		t.Errorf("random UUIDv7: %v", err)
+	}
+	var scanned UUID
+	if err := scanned.Scan(u[:]); err != nil || scanned != u {
+		t.Errorf("Scan(bytes) = %s, %v", scanned, err)
+	}
+	if v, _ := u.Value(); scanned.Scan(v) != nil || scanned != u {
+		t.Errorf("Scan(%v) = %s", v, scanned)
+	}
+	for _, s := range []string{"", "0190e0f87c2a7b3e9f5d3c1a2b4d5e6f", "0190e0f8-7c2a-7b3e-9f5d-3c1a2b4d5e6g"} {
+		if _, err := ParseUUID(s); !errors.Is(err, ErrInvalidUUID) {
+			t.Errorf("ParseUUID(%q): %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package dxyflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// These errors are returned by Validate for IDs no dxyflake issued.
+var (
+	ErrSignBit  = errors.New("id has the sign bit set")
+	ErrFutureID = errors.New("id time is in the future")
+)
+
+// MaxClockSkew is how far the time of an ID may be ahead of the clock of
+// Validate, as the clocks of the issuing machines are never exactly in
+// step with it.
+const MaxClockSkew = time.Minute
+
+// Validate checks that id, e.g. taken from an untrusted client, could have
+// been issued by a dxyflake created with st. It returns:
+//
+//   - ErrInvalidLayout if the bit lengths or the time unit of st are invalid.
+//   - ErrSignBit if the most significant bit of id is set.
+//   - ErrOutOfRange if id sets bits beyond the width of the layout of st,
+//     e.g. a 63-bit ID checked against JSSafeLayout.
+//   - ErrFutureID if the time of id is more than MaxClockSkew ahead of
+//     st.Clock, SystemClock if nil.
+//   - ErrInvalidMachineID or ErrInvalidServiceID if st.CheckMachineID or
+//     st.CheckServiceID refuse the machine ID or service ID of id, or if
+//     its service ID does not match st.Environment.
+//
+// The time part counts from st.StartTime, so no ID is from before it.
+// Validate cannot tell a forged ID from a real one if it fits all of the
+// above; sign IDs handed to clients for that, see ID.SignedToken.
+func Validate(id ID, st Settings) error {
+	l, ok := st.bits()
+	if !ok {
+		return ErrInvalidLayout
+	}
+	if id < 0 {
+		return ErrSignBit
+	}
+	if !l.Fits(id) {
+		return ErrOutOfRange
+	}
+	p := l.DecomposeParts(id)
+	clock := st.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	if ahead := p.Timestamp.Sub(clock.Now()); ahead > MaxClockSkew {
+		return fmt.Errorf("%w: %v ahead", ErrFutureID, ahead)
+	}
+	if st.CheckMachineID != nil && !st.CheckMachineID(p.MachineID) {
+		return fmt.Errorf("%w: %d", ErrInvalidMachineID, p.MachineID)
+	}
+	test := p.ServiceID == l.TestServiceID()
+	if (st.CheckServiceID != nil && !st.CheckServiceID(p.ServiceID)) ||
+		(st.Environment == EnvProduction && test) || (st.Environment == EnvTest && !test) {
+		return fmt.Errorf("%w: %d", ErrInvalidServiceID, p.ServiceID)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package dxyflake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	var st Settings
+	st.Clock = frozenClock{now}
+	st.CheckMachineID = func(id uint16) bool { return id < 8 }
+	st.Environment = EnvProduction
+
+	compose := func(at time.Time, machineID, serviceID uint16) ID {
+		id, err := DefaultLayout.Compose(at, machineID, serviceID, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	tests := []struct {
+		name string
+		id   ID
+		want error
+	}{
+		{"valid", compose(now.Add(-time.Hour), 3, 4), nil},
+		{"within skew", compose(now.Add(MaxClockSkew), 3, 4), nil},
+		{"sign bit", -compose(now, 3, 4), ErrSignBit},
+		{"future", compose(now.Add(MaxClockSkew+time.Second), 3, 4), ErrFutureID},
+		{"machine ID", compose(now, 9, 4), ErrInvalidMachineID},
+		{"test ID", compose(now, 3, TestServiceID), ErrInvalidServiceID},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.id, st); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	st.Environment = EnvTest
+	if err := Validate(compose(now, 3, 4), st); !errors.Is(err, ErrInvalidServiceID) {
+		t.Errorf("production ID in EnvTest: %v", err)
+	}
+	st.BitsTime = 1
+	if err := Validate(1, st); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("invalid layout: %v", err)
+	}
+}
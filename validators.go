@@ -0,0 +1,42 @@
+package dxyflake
+
+import "fmt"
+
+// validators are the CheckMachineID and CheckServiceID of a dxyflake.
+type validators struct {
+	machineID func(uint16) bool
+	serviceID func(uint16) bool
+}
+
+// SetValidators replaces the CheckMachineID and CheckServiceID of the
+// settings of the live dxyflake, e.g. when an allowlist changes, without
+// blocking NextID. As on creation, the validators must accept the machine
+// ID and service ID of the dxyflake: otherwise SetValidators returns
+// ErrInvalidMachineID or ErrInvalidServiceID and keeps the old ones. A nil
+// validator accepts every ID.
+func (df *dxyflake) SetValidators(checkMachineID, checkServiceID func(uint16) bool) error {
+	if checkMachineID != nil && !checkMachineID(df.machineID) {
+		return fmt.Errorf("%w: %d", ErrInvalidMachineID, df.machineID)
+	}
+	if checkServiceID != nil && !checkServiceID(df.serviceID) {
+		return fmt.Errorf("%w: %d", ErrInvalidServiceID, df.serviceID)
+	}
+	df.validators.Store(validators{checkMachineID, checkServiceID})
+	return nil
+}
+
+// CheckID returns ErrInvalidMachineID or ErrInvalidServiceID if the
+// machine ID or service ID of id, an ID of the dxyflake's layout, is not
+// accepted by the current validators, see SetValidators. Services use it
+// to refuse IDs of nodes missing from their allowlist.
+func (df *dxyflake) CheckID(id ID) error {
+	p := df.layout.DecomposeParts(id)
+	v, _ := df.validators.Load().(validators)
+	if v.machineID != nil && !v.machineID(p.MachineID) {
+		return fmt.Errorf("%w: %d", ErrInvalidMachineID, p.MachineID)
+	}
+	if v.serviceID != nil && !v.serviceID(p.ServiceID) {
+		return fmt.Errorf("%w: %d", ErrInvalidServiceID, p.ServiceID)
+	}
+	return nil
+}
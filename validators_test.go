@@ -0,0 +1,58 @@
+package dxyflake
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSetValidators(t *testing.T) {
+	allow := func(ids ...uint16) func(uint16) bool {
+		return func(id uint16) bool {
+			for _, a := range ids {
+				if id == a {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	var st Settings
+	st.Init(1, 2)
+	st.CheckMachineID = allow(1, 2)
+	df := NewDxyflake(st)
+
+	other, _ := Compose(DefaultStartTime, 3, 2, 0)
+	if err := df.CheckID(other); !errors.Is(err, ErrInvalidMachineID) {
+		t.Errorf("machine id 3 accepted: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if _, err := df.NextID(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	if err := df.SetValidators(allow(1, 2, 3), nil); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if err := df.CheckID(other); err != nil {
+		t.Errorf("machine id 3 refused after the allowlist changed: %v", err)
+	}
+
+	if err := df.SetValidators(allow(3), nil); !errors.Is(err, ErrInvalidMachineID) {
+		t.Errorf("validators refusing the dxyflake: %v", err)
+	}
+	if err := df.SetValidators(nil, allow(3)); !errors.Is(err, ErrInvalidServiceID) {
+		t.Errorf("validators refusing the dxyflake: %v", err)
+	}
+	if err := df.CheckID(other); err != nil {
+		t.Errorf("refused validators were kept: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package dxyflake
+
+//go:generate go run ./internal/vectors
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testVectors struct {
+	Layout struct {
+		BitLenTime      int    `json:"bit-len-time"`
+		BitLenMachineID int    `json:"bit-len-machine-id"`
+		BitLenServiceID int    `json:"bit-len-service-id"`
+		BitLenSequence  int    `json:"bit-len-sequence"`
+		TimeUnitNanos   int64  `json:"time-unit-ns"`
+		StartTime       string `json:"start-time"`
+	} `json:"layout"`
+	Vectors []struct {
+		ID        string            `json:"id"`
+		Parts     map[string]int64  `json:"parts"`
+		Timestamp string            `json:"timestamp"`
+		Encodings map[string]string `json:"encodings"`
+	} `json:"vectors"`
+}
+
+func TestVectors(t *testing.T) {
+	b, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tv testVectors
+	if err := json.Unmarshal(b, &tv); err != nil {
+		t.Fatal(err)
+	}
+
+	if tv.Layout.BitLenTime != BitLenTime ||
+		tv.Layout.BitLenMachineID != BitLenMachineID ||
+		tv.Layout.BitLenServiceID != BitLenServiceID ||
+		tv.Layout.BitLenSequence != BitLenSequence ||
+		tv.Layout.TimeUnitNanos != dxyflakeTimeUnit ||
+		tv.Layout.StartTime != DefaultStartTime.Format(time.RFC3339) {
+		t.Fatalf("layout of test vectors does not match: %+v", tv.Layout)
+	}
+	if len(tv.Vectors) == 0 {
+		t.Fatal("no test vectors")
+	}
+
+	for _, v := range tv.Vectors {
+		id, err := ParseString(v.ID)
+		if err != nil {
+			t.Fatalf("error parsing, %s", err)
+		}
+
+		if parts := Decompose(id); !reflect.DeepEqual(parts, v.Parts) {
+			t.Errorf("%s: parts %v, want %v", v.ID, parts, v.Parts)
+		}
+
+		ticks := v.Parts["time"]
+		ts := time.Unix(DefaultStartTime.Unix()+ticks/100, ticks%100*dxyflakeTimeUnit).UTC()
+		if ts.Format(time.RFC3339Nano) != v.Timestamp {
+			t.Errorf("%s: timestamp %s, want %s", v.ID, ts.Format(time.RFC3339Nano), v.Timestamp)
+		}
+
+		intBytes := id.IntBytes()
+		encodings := map[string]string{
+			"decimal":     id.String(),
+			"base2":       id.Base2(),
+			"base32":      id.Base32(),
+			"crockford32": id.Crockford32(),
+			"sortable":    id.SortableString(),
+			"checked":     id.StringChecked(),
+			"base36":      id.Base36(),
+			"base58":      id.Base58(),
+			"base62":      id.Base62(),
+			"base64":      id.Base64(),
+			"hex":         id.Hex(),
+			"int-bytes":   hex.EncodeToString(intBytes[:]),
+		}
+		if !reflect.DeepEqual(encodings, v.Encodings) {
+			t.Errorf("%s: encodings %v, want %v", v.ID, encodings, v.Encodings)
+		}
+
+		parsers := map[string]func(string) (ID, error){
+			"decimal":     ParseString,
+			"base2":       ParseBase2,
+			"base32":      func(s string) (ID, error) { return ParseBase32([]byte(s)) },
+			"crockford32": ParseCrockford32,
+			"sortable":    ParseSortableString,
+			"checked":     ParseChecked,
+			"base36":      ParseBase36,
+			"base58":      func(s string) (ID, error) { return ParseBase58([]byte(s)) },
+			"base62":      func(s string) (ID, error) { return ParseBase62([]byte(s)) },
+			"base64":      ParseBase64,
+			"hex":         ParseHex,
+			"int-bytes": func(s string) (ID, error) {
+				var b [8]byte
+				_, err := hex.Decode(b[:], []byte(s))
+				return ParseIntBytes(b), err
+			},
+		}
+		for name, parse := range parsers {
+			pID, err := parse(v.Encodings[name])
+			if err != nil {
+				t.Errorf("%s: error parsing %s, %s", v.ID, name, err)
+				continue
+			}
+			if pID != id {
+				t.Errorf("%s: parsed %s %v != %v", v.ID, name, pID, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package verify
+
+import (
+	"github.com/GiterLab/dxyflake"
+)
+
+// A page covers every sequence number of one (time, machine ID, service ID).
+const (
+	pageBits  = dxyflake.BitLenSequence
+	pageWords = 1 << pageBits / 64
+)
+
+type page [pageWords]uint64
+
+// Bitset is a sparse set of IDs. Memory is allocated in pages of 4096 IDs
+// that share the same time, machine ID and service ID, which is exactly how
+// a generator issues them, so the set stays small for real traffic.
+type Bitset struct {
+	pages map[int64]*page
+}
+
+// NewBitset returns an empty Bitset.
+func NewBitset() *Bitset {
+	return &Bitset{pages: make(map[int64]*page)}
+}
+
+// TestAndSet adds id to the set and reports whether it was already present.
+func (b *Bitset) TestAndSet(id dxyflake.ID) bool {
+	key := int64(id) >> pageBits
+	p, ok := b.pages[key]
+	if !ok {
+		p = new(page)
+		b.pages[key] = p
+	}
+
+	bit := uint64(id) & (1<<pageBits - 1)
+	mask := uint64(1) << (bit % 64)
+	if p[bit/64]&mask != 0 {
+		return true
+	}
+	p[bit/64] |= mask
+	return false
+}
+
+// Test reports whether id is in the set.
+func (b *Bitset) Test(id dxyflake.ID) bool {
+	p, ok := b.pages[int64(id)>>pageBits]
+	if !ok {
+		return false
+	}
+	bit := uint64(id) & (1<<pageBits - 1)
+	return p[bit/64]&(uint64(1)<<(bit%64)) != 0
+}
+
+// EvictBefore drops every page whose IDs are all smaller than id.
+func (b *Bitset) EvictBefore(id dxyflake.ID) {
+	limit := int64(id) >> pageBits
+	for key := range b.pages {
+		if key < limit {
+			delete(b.pages, key)
+		}
+	}
+}
+
+// Pages returns the number of allocated pages.
+func (b *Bitset) Pages() int {
+	return len(b.pages)
+}
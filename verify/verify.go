@@ -0,0 +1,120 @@
+// Package verify checks streams of dxyflake IDs for the invariants every
+// generator must keep: IDs are unique, and IDs from one (machine ID,
+// service ID) pair strictly increase.
+package verify
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+// These errors are returned by Verifier.Add for IDs that break an invariant.
+var (
+	ErrDuplicate    = errors.New("duplicated id")
+	ErrNotMonotonic = errors.New("id is not greater than the previous id of its node")
+	ErrTooOld       = errors.New("id is older than the verification window")
+)
+
+// evictEvery is the number of IDs added between two evictions.
+const evictEvery = 1 << 16
+
+const shiftTime = dxyflake.BitLenMachineID + dxyflake.BitLenServiceID + dxyflake.BitLenSequence
+
+// Stats counts the IDs seen by a Verifier.
+type Stats struct {
+	Total        uint64
+	Duplicates   uint64
+	NonMonotonic uint64
+	TooOld       uint64
+	Nodes        int // distinct (machine ID, service ID) pairs seen
+	Pages        int // pages currently held by the bitset
+}
+
+// Violations returns the number of IDs that broke an invariant.
+func (s Stats) Violations() uint64 {
+	return s.Duplicates + s.NonMonotonic + s.TooOld
+}
+
+// Verifier checks IDs for uniqueness and per-node monotonicity.
+//
+// Only IDs within window of the slowest node are kept in memory, so a
+// Verifier can run for days. An ID older than that can no longer be checked
+// for uniqueness and is reported with ErrTooOld.
+type Verifier struct {
+	mutex   sync.Mutex
+	bits    *Bitset
+	last    map[int64]dxyflake.ID
+	window  int64 // in dxyflake time units
+	horizon dxyflake.ID
+	stats   Stats
+}
+
+// NewVerifier returns a Verifier remembering IDs within window of the
+// slowest node. If window is 0, IDs are never forgotten.
+func NewVerifier(window time.Duration) *Verifier {
+	return &Verifier{
+		bits:   NewBitset(),
+		last:   make(map[int64]dxyflake.ID),
+		window: int64(window / (10 * time.Millisecond)),
+	}
+}
+
+// Add checks id against every ID added before and records it.
+func (v *Verifier) Add(id dxyflake.ID) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.stats.Total++
+	if v.stats.Total%evictEvery == 0 {
+		v.evict()
+	}
+
+	if id < v.horizon {
+		v.stats.TooOld++
+		return ErrTooOld
+	}
+	if v.bits.TestAndSet(id) {
+		v.stats.Duplicates++
+		return ErrDuplicate
+	}
+
+	node := int64(id) >> dxyflake.BitLenSequence & (1<<(dxyflake.BitLenMachineID+dxyflake.BitLenServiceID) - 1)
+	last, ok := v.last[node]
+	if ok && id <= last {
+		v.stats.NonMonotonic++
+		return ErrNotMonotonic
+	}
+	v.last[node] = id
+	return nil
+}
+
+func (v *Verifier) evict() {
+	if v.window == 0 || len(v.last) == 0 {
+		return
+	}
+
+	min := int64(-1)
+	for _, id := range v.last {
+		if t := int64(id) >> shiftTime; min < 0 || t < min {
+			min = t
+		}
+	}
+	if min -= v.window; min > 0 {
+		v.horizon = dxyflake.ID(min << shiftTime)
+		v.bits.EvictBefore(v.horizon)
+	}
+}
+
+// Stats returns the counters of the Verifier.
+func (v *Verifier) Stats() Stats {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	s := v.stats
+	s.Nodes = len(v.last)
+	s.Pages = v.bits.Pages()
+	return s
+}
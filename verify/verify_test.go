@@ -0,0 +1,108 @@
+package verify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GiterLab/dxyflake"
+)
+
+func compose(elapsed, machineID, serviceID, sequence int64) dxyflake.ID {
+	return dxyflake.ID(elapsed<<shiftTime |
+		machineID<<(dxyflake.BitLenServiceID+dxyflake.BitLenSequence) |
+		serviceID<<dxyflake.BitLenSequence |
+		sequence)
+}
+
+func TestBitset(t *testing.T) {
+	b := NewBitset()
+	ids := []dxyflake.ID{0, 1, 63, 64, 4095, 4096, compose(1<<40, 31, 31, 4095)}
+	for _, id := range ids {
+		if b.TestAndSet(id) {
+			t.Errorf("%d reported present before being set", id)
+		}
+	}
+	for _, id := range ids {
+		if !b.Test(id) || !b.TestAndSet(id) {
+			t.Errorf("%d reported missing after being set", id)
+		}
+	}
+	if b.Test(2) {
+		t.Error("2 reported present")
+	}
+
+	b.EvictBefore(4096)
+	if b.Test(0) || !b.Test(4096) {
+		t.Error("unexpected eviction")
+	}
+	if b.Pages() != 2 {
+		t.Errorf("unexpected pages: %d", b.Pages())
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	v := NewVerifier(0)
+
+	tests := []struct {
+		id   dxyflake.ID
+		want error
+	}{
+		{compose(1, 0, 0, 0), nil},
+		{compose(1, 0, 0, 1), nil},
+		{compose(1, 1, 0, 0), nil},
+		{compose(1, 0, 0, 1), ErrDuplicate},
+		{compose(0, 1, 0, 5), ErrNotMonotonic},
+		{compose(2, 1, 0, 0), nil},
+	}
+	for _, tt := range tests {
+		if err := v.Add(tt.id); err != tt.want {
+			t.Errorf("Add(%d) = %v, want %v", tt.id, err, tt.want)
+		}
+	}
+
+	s := v.Stats()
+	if s.Total != 6 || s.Duplicates != 1 || s.NonMonotonic != 1 || s.Nodes != 2 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+	if s.Violations() != 2 {
+		t.Errorf("unexpected violations: %d", s.Violations())
+	}
+}
+
+func TestVerifierWindow(t *testing.T) {
+	v := NewVerifier(time.Second)
+
+	var tick int64
+	for i := 0; i < 2*evictEvery; i++ {
+		if i%1000 == 0 {
+			tick++
+		}
+		if err := v.Add(compose(tick, 0, 0, int64(i%1000))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := v.Add(compose(1, 1, 0, 0)); err != ErrTooOld {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if pages := v.Stats().Pages; pages > 101 {
+		t.Errorf("pages not evicted: %d", pages)
+	}
+}
+
+func TestVerifierGenerator(t *testing.T) {
+	var st dxyflake.Settings
+	st.Init(3, 4)
+	df := dxyflake.NewDxyflake(st)
+
+	v := NewVerifier(time.Minute)
+	for i := 0; i < 100000; i++ {
+		id, err := df.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Add(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
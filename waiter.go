@@ -0,0 +1,65 @@
+package dxyflake
+
+import (
+	"runtime"
+	"time"
+)
+
+// Waiter waits for d on clock c when a dxyflake has used up the sequence
+// numbers of a time unit and must wait for the next one. NextID holds the
+// lock of the dxyflake while it waits.
+type Waiter interface {
+	Wait(c Clock, d time.Duration)
+}
+
+// WaiterFunc is a function used as a Waiter.
+type WaiterFunc func(c Clock, d time.Duration)
+
+// Wait calls f(c, d).
+func (f WaiterFunc) Wait(c Clock, d time.Duration) {
+	f(c, d)
+}
+
+type sleepWaiter struct{}
+
+func (sleepWaiter) Wait(c Clock, d time.Duration) {
+	c.Sleep(d)
+}
+
+// SleepWaiter sleeps on the clock, used when Settings.Waiter is nil.
+var SleepWaiter Waiter = sleepWaiter{}
+
+// SpinWaiter busy-waits on the clock for the last Spin of a wait and
+// sleeps before that. Waits end within microseconds of the time unit
+// instead of at the mercy of the scheduler, at the cost of a busy CPU; it
+// suits latency sensitive users whose waits are rare.
+type SpinWaiter struct {
+	Spin time.Duration
+}
+
+// Wait sleeps for d minus Spin, then spins until d has passed.
+func (w SpinWaiter) Wait(c Clock, d time.Duration) {
+	deadline := c.Now().Add(d)
+	if d > w.Spin {
+		c.Sleep(d - w.Spin)
+	}
+	for c.Now().Before(deadline) {
+		runtime.Gosched()
+	}
+}
+
+// ChanWaiter waits on the channel returned by After, such as time.After
+// or the timer of an event loop or a simulated clock. If After is nil,
+// time.After is used.
+type ChanWaiter struct {
+	After func(d time.Duration) <-chan time.Time
+}
+
+// Wait waits for a value on After(d).
+func (w ChanWaiter) Wait(c Clock, d time.Duration) {
+	after := w.After
+	if after == nil {
+		after = time.After
+	}
+	<-after(d)
+}
@@ -0,0 +1,146 @@
+package dxyflake
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// steppedClock only advances when slept on.
+type steppedClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *steppedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *steppedClock) Sleep(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWaiter(t *testing.T) {
+	var waits []time.Duration
+	var st Settings
+	st.Init(1, 2)
+	st.Clock = &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 5e6, time.UTC)}
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) {
+		waits = append(waits, d)
+		c.Sleep(d)
+	})
+	gen := NewDxyflake(st)
+
+	for i := 0; i < 2*4096+1; i++ {
+		if _, err := gen.NextID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(waits) != 2 || waits[0] != 5*time.Millisecond || waits[1] != 10*time.Millisecond {
+		t.Errorf("unexpected waits %v", waits)
+	}
+}
+
+func TestSpinWaiter(t *testing.T) {
+	for _, w := range []SpinWaiter{{}, {Spin: time.Millisecond}, {Spin: time.Hour}} {
+		start := time.Now()
+		w.Wait(SystemClock, 3*time.Millisecond)
+		if d := time.Since(start); d < 3*time.Millisecond {
+			t.Errorf("%+v: returned after %v", w, d)
+		}
+	}
+}
+
+func TestChanWaiter(t *testing.T) {
+	var got time.Duration
+	w := ChanWaiter{After: func(d time.Duration) <-chan time.Time {
+		got = d
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}}
+	w.Wait(SystemClock, 7*time.Millisecond)
+	if got != 7*time.Millisecond {
+		t.Errorf("got %v", got)
+	}
+
+	start := time.Now()
+	ChanWaiter{}.Wait(SystemClock, 2*time.Millisecond)
+	if d := time.Since(start); d < 2*time.Millisecond {
+		t.Errorf("returned after %v", d)
+	}
+}
+
+func TestNextIDContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var waits int
+	var st Settings
+	st.Init(1, 2)
+	st.Clock = frozenClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) {
+		waits++
+		cancel()
+	})
+	gen := NewDxyflake(st)
+
+	var last ID
+	for i := 0; i < 1<<BitLenSequence; i++ {
+		id, err := gen.NextIDContext(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = id
+	}
+	if _, err := gen.NextIDContext(ctx); err != context.Canceled || waits != 1 {
+		t.Fatalf("got %v after %d waits", err, waits)
+	}
+	if _, err := gen.NextIDContext(ctx); err != context.Canceled || waits != 1 {
+		t.Errorf("done context waited: %v after %d waits", err, waits)
+	}
+
+	// the cancelled wait issued no ID, the next call waits again
+	id, err := gen.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waits != 2 || TimeBits(id) != TimeBits(last)+1 || SequenceBits(id) != 0 {
+		t.Errorf("NextID() = %v after %v, %d waits", Decompose(id), Decompose(last), waits)
+	}
+}
+
+func TestTryNextID(t *testing.T) {
+	var waits int
+	var st Settings
+	st.Init(1, 2)
+	clock := &steppedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.Clock = clock
+	st.Waiter = WaiterFunc(func(c Clock, d time.Duration) { waits++ })
+	gen := NewDxyflake(st)
+
+	var last ID
+	for i := 0; i < 1<<BitLenSequence; i++ {
+		id, err := gen.TryNextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = id
+	}
+	if _, err := gen.TryNextID(); err != ErrSequenceExhausted || waits != 0 {
+		t.Fatalf("got %v after %d waits", err, waits)
+	}
+
+	clock.Sleep(dxyflakeTimeUnit)
+	id, err := gen.TryNextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TimeBits(id) != TimeBits(last)+1 || SequenceBits(id) != 0 {
+		t.Errorf("TryNextID() = %v after %v", Decompose(id), Decompose(last))
+	}
+}
@@ -0,0 +1,153 @@
+package dxyflake
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidWindow is returned by Windows.Err when the iteration parameters
+// are invalid.
+var ErrInvalidWindow = errors.New("invalid window")
+
+// layoutAt returns DefaultLayout with the given epoch.
+func layoutAt(epoch time.Time) Layout {
+	l := DefaultLayout
+	if !epoch.IsZero() {
+		l.Epoch = epoch
+	}
+	return l
+}
+
+// MinIDAt returns the smallest ID a dxyflake started at epoch can issue at
+// time t. If epoch is 0, DefaultStartTime is used.
+func MinIDAt(t, epoch time.Time) ID {
+	return layoutAt(epoch).MinIDAt(t)
+}
+
+// MaxIDAt returns the largest ID a dxyflake started at epoch can issue at
+// time t. If epoch is 0, DefaultStartTime is used.
+func MaxIDAt(t, epoch time.Time) ID {
+	return layoutAt(epoch).MaxIDAt(t)
+}
+
+// IDRange returns the smallest and the largest ID any generator of the
+// layout can issue from start, inclusive, to end, exclusive, for queries
+// like "WHERE id BETWEEN ? AND ?" on an ID column instead of an index on a
+// creation time. IDs only carry the time unit they were issued in, so the
+// range covers the whole time units of start and end - 1. If no ID can be
+// issued in the window, min is greater than max.
+func (l Layout) IDRange(start, end time.Time) (min, max ID) {
+	first, last := l.Tick(start), l.Tick(end.Add(-1))
+	if first < 0 {
+		first = 0
+	}
+	if limit := int64(1)<<uint(l.BitLenTime) - 1; last > limit {
+		last = limit
+	}
+	if !end.After(start) || last < first {
+		return 0, -1
+	}
+	return l.MinIDAt(l.TickTime(first)), l.MaxIDAt(l.TickTime(last))
+}
+
+// IDRangeForTime returns the IDRange of the layout of the dxyflakes created
+// with st, whatever their machine ID and service ID. If the bit lengths of
+// st are invalid, the range is empty.
+func IDRangeForTime(start, end time.Time, st Settings) (min, max ID) {
+	l, ok := st.bits()
+	if !ok {
+		return 0, -1
+	}
+	return l.IDRange(start, end)
+}
+
+// IDRange returns the IDRange of the layout of the dxyflake.
+func (df *dxyflake) IDRange(start, end time.Time) (min, max ID) {
+	return df.layout.IDRange(start, end)
+}
+
+// Window is a time window and the range of IDs issued within it.
+// Start is inclusive and End exclusive; Min and Max are both inclusive, so
+// they can be used directly in "WHERE id BETWEEN ? AND ?".
+type Window struct {
+	Start time.Time
+	End   time.Time
+	Min   ID
+	Max   ID
+}
+
+// Windows iterates over consecutive time windows, see IterateWindows.
+type Windows struct {
+	next   time.Time
+	to     time.Time
+	step   time.Duration
+	epoch  time.Time
+	window Window
+	err    error
+}
+
+// IterateWindows returns an iterator over the windows of length step that
+// cover [from, to), with the ID range of each window for a dxyflake started
+// at epoch. If epoch is 0, DefaultStartTime is used. The ID ranges of
+// consecutive windows neither overlap nor leave gaps.
+//
+//	w := dxyflake.IterateWindows(from, to, time.Hour, time.Time{})
+//	for w.Next() {
+//		backfill(w.Window().Min, w.Window().Max)
+//	}
+//	if err := w.Err(); err != nil {
+//		...
+//	}
+func IterateWindows(from, to time.Time, step time.Duration, epoch time.Time) *Windows {
+	w := &Windows{next: from, to: to, step: step, epoch: epoch}
+	if step <= 0 || to.Before(from) {
+		w.err = ErrInvalidWindow
+	}
+	return w
+}
+
+// Next advances to the next window. It returns false when the windows are
+// exhausted or the parameters are invalid.
+func (w *Windows) Next() bool {
+	for w.err == nil && w.next.Before(w.to) {
+		start := w.next
+		end := start.Add(w.step)
+		if end.After(w.to) {
+			end = w.to
+		}
+		w.next = end
+
+		l := layoutAt(w.epoch)
+		first := l.Tick(start)
+		last := l.Tick(end) - 1
+		if first < 0 {
+			first = 0
+		}
+		if last >= 1<<BitLenTime {
+			last = 1<<BitLenTime - 1
+		}
+		if last < first {
+			// the window is before epoch, beyond the time limit or within
+			// a time unit the previous window already covered
+			continue
+		}
+		w.window = Window{
+			Start: start,
+			End:   end,
+			Min:   l.compose(first, 0, 0, 0),
+			Max:   l.compose(last, 1<<BitLenMachineID-1, 1<<BitLenServiceID-1, 1<<BitLenSequence-1),
+		}
+		return true
+	}
+	return false
+}
+
+// Window returns the current window.
+func (w *Windows) Window() Window {
+	return w.window
+}
+
+// Err returns ErrInvalidWindow if the parameters were invalid.
+func (w *Windows) Err() error {
+	return w.err
+}
@@ -0,0 +1,135 @@
+package dxyflake
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMinMaxIDAt(t *testing.T) {
+	epoch := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := epoch.Add(1234 * time.Millisecond)
+
+	min, max := MinIDAt(at, epoch), MaxIDAt(at, epoch)
+	if parts := Decompose(min); parts["time"] != 123 || parts["sequence"] != 0 || parts["machine-id"] != 0 {
+		t.Errorf("unexpected min: %v", parts)
+	}
+	if parts := Decompose(max); parts["time"] != 123 || parts["sequence"] != 4095 || parts["service-id"] != 31 {
+		t.Errorf("unexpected max: %v", parts)
+	}
+
+	if MinIDAt(epoch.Add(-time.Hour), epoch) != 0 {
+		t.Error("time before epoch not clamped")
+	}
+	if MaxIDAt(at, time.Time{}) <= MaxIDAt(at, epoch) {
+		t.Error("zero epoch does not use the default start time")
+	}
+}
+
+func TestIterateWindows(t *testing.T) {
+	epoch := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	from := epoch.Add(-25 * time.Millisecond)
+	to := epoch.Add(95 * time.Millisecond)
+
+	w := IterateWindows(from, to, 30*time.Millisecond, epoch)
+	var windows []Window
+	for w.Next() {
+		windows = append(windows, w.Window())
+	}
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first window is entirely before the epoch
+	if len(windows) != 3 {
+		t.Fatalf("unexpected windows: %+v", windows)
+	}
+	if windows[0].Min != 0 {
+		t.Errorf("unexpected first min: %d", windows[0].Min)
+	}
+	for i := 1; i < len(windows); i++ {
+		if windows[i].Min != windows[i-1].Max+1 {
+			t.Errorf("window %d does not follow window %d: %d, %d", i, i-1, windows[i].Min, windows[i-1].Max)
+		}
+	}
+	if last := windows[len(windows)-1]; Decompose(last.Max)["time"] != 8 || !last.End.Equal(to) {
+		t.Errorf("unexpected last window: %+v", last)
+	}
+
+	var st Settings
+	st.Init(5, 6)
+	st.StartTime = epoch
+	id, err := NewDxyflake(st).NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	w = IterateWindows(now.Add(-time.Hour), now.Add(time.Hour), time.Minute, epoch)
+	found := 0
+	for w.Next() {
+		if win := w.Window(); id >= win.Min && id <= win.Max {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Errorf("id found in %d windows", found)
+	}
+}
+
+func TestIterateWindowsInvalid(t *testing.T) {
+	now := time.Now()
+	for _, w := range []*Windows{
+		IterateWindows(now, now.Add(time.Hour), 0, time.Time{}),
+		IterateWindows(now, now.Add(-time.Hour), time.Minute, time.Time{}),
+	} {
+		if w.Next() {
+			t.Error("invalid windows iterated")
+		}
+		if w.Err() != ErrInvalidWindow {
+			t.Errorf("unexpected error: %v", w.Err())
+		}
+	}
+}
+
+func TestIDRange(t *testing.T) {
+	epoch := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var st Settings
+	st.Init(5, 6)
+	st.StartTime = epoch
+	start, end := epoch.Add(1234*time.Millisecond), epoch.Add(2*time.Second)
+
+	min, max := IDRangeForTime(start, end, st)
+	if min != MinIDAt(start, epoch) || max != MaxIDAt(end.Add(-time.Millisecond), epoch) {
+		t.Errorf("IDRangeForTime() = %d, %d", min, max)
+	}
+	if parts := Decompose(max); parts["time"] != 199 {
+		t.Errorf("end not exclusive: %v", parts)
+	}
+	df := NewDxyflake(st)
+	if df == nil {
+		t.Fatal("dxyflake not created")
+	}
+	now := time.Now()
+	id, err := df.NextID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if min, max := df.IDRange(now.Add(-time.Second), time.Now().Add(time.Second)); id < min || id > max {
+		t.Errorf("%d not in [%d, %d]", id, min, max)
+	}
+
+	for _, w := range [][2]time.Time{
+		{end, start},
+		{epoch.Add(-time.Hour), epoch},
+	} {
+		if min, max := IDRangeForTime(w[0], w[1], st); min <= max {
+			t.Errorf("window %v: range %d, %d not empty", w, min, max)
+		}
+	}
+	if min, _ := IDRangeForTime(epoch.Add(-time.Hour), start, st); min != 0 {
+		t.Errorf("time before epoch not clamped: %d", min)
+	}
+	st.BitsTime = 1
+	if min, max := IDRangeForTime(start, end, st); min <= max {
+		t.Error("invalid settings gave a range")
+	}
+}